@@ -69,7 +69,7 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 
 	objstm, cached = parser.objstms[sobjNumber]
 	if !cached {
-		soi, err := parser.LookupByNumber(sobjNumber)
+		soi, err := parser.lookupObject(sobjNumber)
 		if err != nil {
 			common.Log.Debug("Missing object stream with number %d", sobjNumber)
 			return nil, err
@@ -106,6 +106,9 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 		}
 
 		common.Log.Trace("type: %s number of objects: %d", name, *N)
+		if err := parser.resolveFilterParams(sod); err != nil {
+			return nil, err
+		}
 		ds, err := DecodeStream(so)
 		if err != nil {
 			return nil, err
@@ -154,6 +157,7 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 
 		objstm = objectStream{N: int(*N), ds: ds, offsets: offsets}
 		parser.objstms[sobjNumber] = objstm
+		parser.growCachedObject(sobjNumber, int64(len(ds)))
 	} else {
 		// Temporarily change the reader object to this decoded buffer.
 		// Point back afterwards.
@@ -187,12 +191,20 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 	io := PdfIndirectObject{}
 	io.ObjectNumber = int64(objNum)
 	io.PdfObject = val
+	io.parser = parser
 
 	return &io, nil
 }
 
 // LookupByNumber looks up a PdfObject by object number.  Returns an error on failure.
 func (parser *PdfParser) LookupByNumber(objNumber int) (PdfObject, error) {
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	return parser.lookupObject(objNumber)
+}
+
+// lookupObject looks up a PdfObject by object number, for use when the lookups are locked.
+func (parser *PdfParser) lookupObject(objNumber int) (PdfObject, error) {
 	// Outside interface for lookupByNumberWrapper.  Default attempts repairs of bad xref tables.
 	obj, _, err := parser.lookupByNumberWrapper(objNumber, true)
 	return obj, err
@@ -208,6 +220,11 @@ func (parser *PdfParser) lookupByNumberWrapper(objNumber int, attemptRepairs boo
 	// If encrypted, decrypt it prior to returning.
 	// Do not attempt to decrypt objects within object streams.
 	if !inObjStream && parser.crypter != nil && !parser.crypter.isDecrypted(obj) {
+		if stream, ok := obj.(*PdfObjectStream); ok {
+			if err := parser.resolveFilterParams(stream.PdfObjectDictionary); err != nil {
+				return nil, inObjStream, err
+			}
+		}
 		err := parser.crypter.Decrypt(obj, 0, 0)
 		if err != nil {
 			return nil, inObjStream, err
@@ -232,7 +249,10 @@ func getObjectNumber(obj PdfObject) (int64, int64, error) {
 // lookupByNumber is used by LookupByNumber.
 // attemptRepairs signals whether to attempt repair if broken.
 func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (PdfObject, bool, error) {
-	obj, ok := parser.ObjCache[objNumber]
+	if obj, ok := parser.repairObjects[objNumber]; ok {
+		return obj, false, nil
+	}
+	obj, ok := parser.cachedObject(objNumber)
 	if ok {
 		common.Log.Trace("Returning cached object %d", objNumber)
 		return obj, false, nil
@@ -268,7 +288,7 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 					common.Log.Debug("ERROR Failed repair (%s)", err)
 					return nil, false, err
 				}
-				parser.xrefs = *xrefTable
+				parser.setRebuiltXrefs(*xrefTable)
 				return parser.lookupByNumber(objNumber, false)
 			}
 			return nil, false, err
@@ -286,14 +306,14 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 					return nil, false, err
 				}
 				// Empty the cache.
-				parser.ObjCache = objectCache{}
+				parser.clearCache()
 				// Try looking up again and return.
 				return parser.lookupByNumberWrapper(objNumber, false)
 			}
 		}
 
 		common.Log.Trace("Returning obj")
-		parser.ObjCache[objNumber] = obj
+		parser.cacheObject(objNumber, obj)
 		return obj, false, nil
 	} else if xref.XType == XrefTypeObjectStream {
 		common.Log.Trace("xref from object stream!")
@@ -312,7 +332,7 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 				return nil, true, err
 			}
 			common.Log.Trace("<Loaded via OS")
-			parser.ObjCache[objNumber] = optr
+			parser.cacheObject(objNumber, optr)
 			if parser.crypter != nil {
 				// Mark as decrypted (inside object stream) for caching.
 				// and avoid decrypting decrypted object.
@@ -335,6 +355,17 @@ func (parser *PdfParser) LookupByReference(ref PdfObjectReference) (PdfObject, e
 
 // Resolve resolves a PdfObject to direct object, looking up and resolving references as needed (unlike TraceToDirect).
 func (parser *PdfParser) Resolve(obj PdfObject) (PdfObject, error) {
+	if _, isRef := obj.(*PdfObjectReference); !isRef {
+		// Direct object already.
+		return obj, nil
+	}
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	return parser.resolve(obj)
+}
+
+// resolve is used by Resolve, for use when the lookups are locked.
+func (parser *PdfParser) resolve(obj PdfObject) (PdfObject, error) {
 	ref, isRef := obj.(*PdfObjectReference)
 	if !isRef {
 		// Direct object already.
@@ -344,7 +375,7 @@ func (parser *PdfParser) Resolve(obj PdfObject) (PdfObject, error) {
 	bakOffset := parser.GetFileOffset()
 	defer func() { parser.SetFileOffset(bakOffset) }()
 
-	o, err := parser.LookupByReference(*ref)
+	o, err := parser.lookupObject(int(ref.ObjectNumber))
 	if err != nil {
 		return nil, err
 	}
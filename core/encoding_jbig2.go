@@ -326,7 +326,13 @@ func newJBIG2DecoderFromStream(streamObj *PdfObjectStream, decodeParams *PdfObje
 		common.Log.Debug("ERROR: %v", err)
 		return nil, err
 	}
-	encoder.Globals, err = jbig2.DecodeGlobals(globalsStream.Stream)
+	// The globals stream may be filtered, or parsed with lazy stream data.
+	data, err := DecodeStream(globalsStream)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode jbig2.Globals stream: %v", err)
+		return nil, err
+	}
+	encoder.Globals, err = jbig2.DecodeGlobals(data)
 	if err != nil {
 		err = errors.Wrap(err, processName, "corrupted jbig2 encoded data")
 		common.Log.Debug("ERROR: %v", err)
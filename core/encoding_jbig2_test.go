@@ -1,6 +1,8 @@
 package core
 
 import (
+	"bytes"
+	"fmt"
 	"image"
 	"image/color"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/moolekkari/unipdf/internal/jbig2/bitmap"
+	"github.com/moolekkari/unipdf/internal/pdftest"
 )
 
 // TestImageToJBIG2Image tests conversion of image.Image to JBIG2Image
@@ -45,3 +48,54 @@ func TestImageToJBIG2Image(t *testing.T) {
 		assert.Equal(t, jb2.Data, bm.Data)
 	})
 }
+
+// TestJBIG2LazyGlobals tests decoding a JBIG2 image with JBIG2Globals parsed with lazy stream data.
+func TestJBIG2LazyGlobals(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		img.SetGray(x, x, color.Gray{Y: 255})
+	}
+	jbImg, err := GoImageToJBIG2(img, JB2ImageAutoThreshold)
+	require.NoError(t, err)
+	encoded, err := NewJBIG2Encoder().EncodeJBIG2Image(jbImg)
+	require.NoError(t, err)
+	// A global extension segment: number 0, type 62, page association 0 and 4 bytes of data.
+	globals := []byte{0, 0, 0, 0, 62, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0}
+
+	data := pdftest.Build(
+		"<< /Type /Catalog >>",
+		fmt.Sprintf("<< /Filter /JBIG2Decode /DecodeParms << /JBIG2Globals 3 0 R >> /Width 16 /Height 16 "+
+			"/BitsPerComponent 1 /Length %d >>\nstream\n%s\nendstream", len(encoded), encoded),
+		pdftest.Stream(string(globals)),
+	)
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+	parser.SetLazyStreams(true)
+
+	obj, err := parser.LookupByNumber(2)
+	require.NoError(t, err)
+	stream, ok := GetStream(obj)
+	require.True(t, ok)
+	decodeParms, ok := GetDict(stream.Get("DecodeParms"))
+	require.True(t, ok)
+	globalsObj, err := parser.LookupByNumber(3)
+	require.NoError(t, err)
+	globalsStream, ok := GetStream(globalsObj)
+	require.True(t, ok)
+	require.Nil(t, globalsStream.Stream)
+	decodeParms.Set("JBIG2Globals", globalsStream)
+
+	encoder, err := NewEncoderFromStream(stream)
+	require.NoError(t, err)
+	jbEncoder, ok := encoder.(*JBIG2Encoder)
+	require.True(t, ok)
+	require.Len(t, jbEncoder.Globals, 1)
+
+	decoded, err := jbEncoder.DecodeStream(stream)
+	require.NoError(t, err)
+	// The decoded data uses the PDF convention: 0 is black.
+	require.Len(t, decoded, len(jbImg.Data))
+	for i, b := range jbImg.Data {
+		require.Equal(t, ^b, decoded[i])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"io"
+	"math"
 
 	"github.com/moolekkari/unipdf/common"
 )
@@ -40,9 +41,8 @@ func (r *offsetReader) Seek(offset int64, whence int) (int64, error) {
 		return n, err
 	}
 
-	if whence == io.SeekCurrent {
-		n -= r.offset
-	}
+	// The position is relative to the offset, whatever the reference point.
+	n -= r.offset
 	if n < 0 {
 		return 0, errors.New("core.offsetReader.Seek: negative position")
 	}
@@ -86,8 +86,41 @@ func (parser *PdfParser) SetFileOffset(offset int64) {
 	parser.reader = bufio.NewReader(parser.rs)
 }
 
+// newReaderAt returns an io.ReaderAt for reading `rs` without changing its reading position, or nil
+// if `rs` does not support it.
+func newReaderAt(rs io.ReadSeeker) io.ReaderAt {
+	switch t := rs.(type) {
+	case *offsetReader:
+		if ra, ok := t.reader.(io.ReaderAt); ok {
+			return io.NewSectionReader(ra, t.offset, math.MaxInt64-t.offset)
+		}
+	case io.ReaderAt:
+		return t
+	}
+	return nil
+}
+
 // ReadBytesAt reads byte content at specific offset and length within the PDF.
+// Safe for concurrent use with the lookups of objects.
 func (parser *PdfParser) ReadBytesAt(offset, len int64) ([]byte, error) {
+	if parser.ra == nil {
+		parser.mu.Lock()
+		defer parser.mu.Unlock()
+	}
+	return parser.readBytesAt(offset, len)
+}
+
+// readBytesAt implements ReadBytesAt, for use when the lookups are locked.
+func (parser *PdfParser) readBytesAt(offset, len int64) ([]byte, error) {
+	if parser.ra != nil {
+		bb := make([]byte, len)
+		n, err := parser.ra.ReadAt(bb, offset)
+		if err != nil && !(err == io.EOF && int64(n) == len) {
+			return nil, err
+		}
+		return bb, nil
+	}
+
 	curPos := parser.GetFileOffset()
 
 	_, err := parser.rs.Seek(offset, io.SeekStart)
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOffsetReaderSeek checks that the positions returned by offsetReader.Seek are relative to
+// the offset for every reference point, so that e.g. seeking to the end gives the size of the data
+// after the offset.
+func TestOffsetReaderSeek(t *testing.T) {
+	data := []byte("junk%PDF-1.4 data")
+	r, err := newOffsetReader(bytes.NewReader(data), 4)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		offset   int64
+		whence   int
+		expected int64
+	}{
+		{3, io.SeekStart, 3},
+		{2, io.SeekCurrent, 5},
+		{0, io.SeekEnd, int64(len(data)) - 4},
+		{-4, io.SeekEnd, int64(len(data)) - 8},
+	}
+	for _, tcase := range testcases {
+		n, err := r.Seek(tcase.offset, tcase.whence)
+		require.NoError(t, err)
+		require.Equal(t, tcase.expected, n, "offset=%d whence=%d", tcase.offset, tcase.whence)
+	}
+
+	// Reading continues at the position relative to the offset.
+	_, err = r.Seek(1, io.SeekStart)
+	require.NoError(t, err)
+	p := make([]byte, 3)
+	_, err = io.ReadFull(r, p)
+	require.NoError(t, err)
+	require.Equal(t, "PDF", string(p))
+
+	_, err = r.Seek(-1, io.SeekStart)
+	require.Error(t, err)
+}
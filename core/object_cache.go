@@ -0,0 +1,305 @@
+package core
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+
+	"github.com/moolekkari/unipdf/common"
+)
+
+// objectLRU tracks the use of the objects of the parser object cache, for evicting the least
+// recently used objects when the size of the cache exceeds its limit.
+type objectLRU struct {
+	limit   int64 // The maximum size of the cached objects in bytes.
+	size    int64 // The estimated size of the cached objects in bytes.
+	order   *list.List
+	entries map[int]*list.Element
+}
+
+// lruEntry is an entry of objectLRU.
+type lruEntry struct {
+	objNum int
+	size   int64
+}
+
+// newObjectLRU returns a new objectLRU with a limit of `limit` bytes.
+func newObjectLRU(limit int64) *objectLRU {
+	return &objectLRU{
+		limit:   limit,
+		order:   list.New(),
+		entries: map[int]*list.Element{},
+	}
+}
+
+// SetCacheLimit limits the size of the objects cached by the parser to about `limit` bytes. When
+// the limit is exceeded, the least recently used objects are evicted from the cache, and parsed
+// again when looked up. The size of the objects is estimated from their content. A limit of 0
+// removes the limit.
+func (parser *PdfParser) SetCacheLimit(limit int64) {
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	if limit <= 0 {
+		parser.lru = nil
+		return
+	}
+	parser.lru = newObjectLRU(limit)
+	for objNum, obj := range parser.ObjCache {
+		parser.lru.add(objNum, estimateObjectSize(obj, true))
+	}
+	parser.evictObjects()
+}
+
+// SetLazyStreams sets whether the data of the streams is read when the stream objects are parsed
+// or when the data is needed, i.e. by DecodeStream or PdfObjectStream.LoadData. Lazy stream data is
+// not supported for encrypted documents, as the data is decrypted when the objects are parsed, and
+// requires the input of the parser to implement io.ReaderAt, e.g. *os.File or *bytes.Reader.
+func (parser *PdfParser) SetLazyStreams(lazy bool) {
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	parser.lazyStreams = lazy
+}
+
+// ReleaseObject removes the object with number `objNum` from the cache of the parser, so that it
+// is parsed again when looked up.
+func (parser *PdfParser) ReleaseObject(objNum int) {
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	parser.releaseObject(objNum)
+}
+
+// releaseObject is used by ReleaseObject, for use when the lookups are locked.
+func (parser *PdfParser) releaseObject(objNum int) {
+	delete(parser.ObjCache, objNum)
+	delete(parser.objstms, objNum)
+	if parser.lru != nil {
+		parser.lru.remove(objNum)
+	}
+}
+
+// IsCached returns true if the object with number `objNum` is in the cache of the parser.
+func (parser *PdfParser) IsCached(objNum int) bool {
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	_, ok := parser.ObjCache[objNum]
+	return ok
+}
+
+// cachedObject returns the cached object with number `objNum`, marking it as recently used.
+func (parser *PdfParser) cachedObject(objNum int) (PdfObject, bool) {
+	obj, ok := parser.ObjCache[objNum]
+	if ok && parser.lru != nil {
+		parser.lru.touch(objNum)
+	}
+	return obj, ok
+}
+
+// cacheObject adds `obj` with number `objNum` to the cache of the parser.
+func (parser *PdfParser) cacheObject(objNum int, obj PdfObject) {
+	parser.ObjCache[objNum] = obj
+	if parser.lru != nil {
+		parser.lru.add(objNum, estimateObjectSize(obj, true))
+		parser.evictObjects()
+	}
+}
+
+// growCachedObject adds `size` bytes to the size of the cached object with number `objNum`, e.g.
+// for the decoded data of object streams. The object is tracked if it was evicted meanwhile.
+func (parser *PdfParser) growCachedObject(objNum int, size int64) {
+	if parser.lru == nil {
+		return
+	}
+	parser.lru.grow(objNum, size)
+	parser.evictObjects()
+}
+
+// clearCache removes all the objects from the cache of the parser.
+func (parser *PdfParser) clearCache() {
+	parser.ObjCache = objectCache{}
+	if parser.lru != nil {
+		parser.lru = newObjectLRU(parser.lru.limit)
+	}
+}
+
+// evictObjects evicts the least recently used objects until the size of the cache is within its
+// limit. The most recently used object is kept.
+func (parser *PdfParser) evictObjects() {
+	lru := parser.lru
+	for lru.size > lru.limit && lru.order.Len() > 1 {
+		entry := lru.order.Back().Value.(*lruEntry)
+		common.Log.Trace("Evicting object %d (%d bytes)", entry.objNum, entry.size)
+		parser.releaseObject(entry.objNum)
+	}
+}
+
+// add adds the object with number `objNum` and size `size` as the most recently used object.
+func (lru *objectLRU) add(objNum int, size int64) {
+	lru.remove(objNum)
+	lru.entries[objNum] = lru.order.PushFront(&lruEntry{objNum: objNum, size: size})
+	lru.size += size
+}
+
+// touch marks the object with number `objNum` as the most recently used object.
+func (lru *objectLRU) touch(objNum int) {
+	if elem, ok := lru.entries[objNum]; ok {
+		lru.order.MoveToFront(elem)
+	}
+}
+
+// grow adds `size` bytes to the size of the object with number `objNum`, adding the object if
+// missing.
+func (lru *objectLRU) grow(objNum int, size int64) {
+	elem, ok := lru.entries[objNum]
+	if !ok {
+		lru.add(objNum, size)
+		return
+	}
+	elem.Value.(*lruEntry).size += size
+	lru.size += size
+}
+
+// remove removes the object with number `objNum`.
+func (lru *objectLRU) remove(objNum int) {
+	elem, ok := lru.entries[objNum]
+	if !ok {
+		return
+	}
+	lru.size -= elem.Value.(*lruEntry).size
+	lru.order.Remove(elem)
+	delete(lru.entries, objNum)
+}
+
+// estimateObjectSize returns an estimate of the memory used by `obj` in bytes. The indirect and
+// stream objects contained in `obj` are only counted when `obj` is a top level object.
+func estimateObjectSize(obj PdfObject, top bool) int64 {
+	switch t := obj.(type) {
+	case *PdfIndirectObject:
+		if !top {
+			return 8
+		}
+		return 32 + estimateObjectSize(t.PdfObject, false)
+	case *PdfObjectStream:
+		if !top {
+			return 8
+		}
+		// The data of lazy streams is counted as loaded.
+		size := int64(len(t.Stream))
+		if t.source != nil {
+			size = t.source.length
+		}
+		return 64 + size + estimateObjectSize(t.PdfObjectDictionary, false)
+	case *PdfObjectDictionary:
+		size := int64(64)
+		for _, key := range t.keys {
+			size += 32 + int64(len(key)) + estimateObjectSize(t.dict[key], false)
+		}
+		return size
+	case *PdfObjectArray:
+		size := int64(24)
+		for _, elem := range t.vec {
+			size += 16 + estimateObjectSize(elem, false)
+		}
+		return size
+	case *PdfObjectString:
+		return 32 + int64(len(t.val))
+	case *PdfObjectName:
+		return 16 + int64(len(*t))
+	}
+	return 16
+}
+
+// streamSource is the location of the data of a stream whose data is loaded lazily.
+type streamSource struct {
+	mu     sync.Mutex // Guards the data of the stream.
+	parser *PdfParser
+	offset int64
+	length int64
+}
+
+// LoadData reads the data of a stream parsed with lazy stream data (see PdfParser.SetLazyStreams).
+// It does nothing if the data is already loaded.
+// Safe for concurrent use.
+func (stream *PdfObjectStream) LoadData() error {
+	src := stream.source
+	if src == nil {
+		return nil
+	}
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if stream.Stream != nil {
+		return nil
+	}
+	data, err := src.parser.ReadBytesAt(src.offset, src.length)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to load stream data of object %d: %v", stream.ObjectNumber, err)
+		return err
+	}
+	stream.Stream = data
+	return nil
+}
+
+// ReleaseData releases the data of a stream parsed with lazy stream data, which is read again when
+// needed. Returns an error if the data cannot be read again, e.g. because it was modified.
+func (stream *PdfObjectStream) ReleaseData() error {
+	if stream.source == nil {
+		return errors.New("stream data not loaded lazily")
+	}
+	stream.source.mu.Lock()
+	defer stream.source.mu.Unlock()
+	if stream.Stream != nil && int64(len(stream.Stream)) != stream.source.length {
+		return errors.New("stream data modified")
+	}
+	stream.Stream = nil
+	return nil
+}
+
+// resolveFilterParams replaces the references in the Filter and DecodeParms entries of the stream
+// dictionary `dict` by the objects they refer to, so that the stream can be decoded while the
+// lookups are locked.
+func (parser *PdfParser) resolveFilterParams(dict *PdfObjectDictionary) error {
+	for _, key := range []PdfObjectName{"Filter", "DecodeParms"} {
+		obj := dict.Get(key)
+		if obj == nil {
+			continue
+		}
+		resolved, err := parser.resolveDirect(obj, 0)
+		if err != nil {
+			return err
+		}
+		dict.Set(key, resolved)
+	}
+	return nil
+}
+
+// resolveDirect returns `obj` with the references it contains replaced by the direct objects they
+// refer to, for use when the lookups are locked.
+func (parser *PdfParser) resolveDirect(obj PdfObject, depth int) (PdfObject, error) {
+	if depth > traceMaxDepth {
+		return nil, errors.New("trace depth level beyond 10 - error")
+	}
+	switch t := obj.(type) {
+	case *PdfObjectReference:
+		resolved, err := parser.resolve(t)
+		if err != nil {
+			return nil, err
+		}
+		return parser.resolveDirect(resolved, depth+1)
+	case *PdfObjectArray:
+		for i, elem := range t.Elements() {
+			resolved, err := parser.resolveDirect(elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			t.Set(i, resolved)
+		}
+	case *PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			resolved, err := parser.resolveDirect(t.Get(key), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			t.Set(key, resolved)
+		}
+	}
+	return obj, nil
+}
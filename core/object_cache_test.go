@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/internal/pdftest"
+)
+
+// makeCacheTestFile returns a PDF file with a catalog followed by `numStreams` streams of `size`
+// bytes each.
+func makeCacheTestFile(numStreams, size int) []byte {
+	objects := []string{"<< /Type /Catalog >>"}
+	for i := 0; i < numStreams; i++ {
+		objects = append(objects, pdftest.Stream(strings.Repeat(string(rune('a'+i%26)), size)))
+	}
+	return pdftest.Build(objects...)
+}
+
+func TestParserCacheLimit(t *testing.T) {
+	const numStreams = 10
+	parser, err := NewParser(bytes.NewReader(makeCacheTestFile(numStreams, 1000)))
+	require.NoError(t, err)
+	parser.SetCacheLimit(3000)
+
+	for i := 0; i < numStreams; i++ {
+		obj, err := parser.LookupByNumber(i + 2)
+		require.NoError(t, err)
+		stream, ok := GetStream(obj)
+		require.True(t, ok)
+		assert.Equal(t, 1000, len(stream.Stream))
+		assert.True(t, parser.IsCached(i+2))
+	}
+	// Only the most recently used streams are kept.
+	assert.LessOrEqual(t, parser.lru.size, int64(3000))
+	assert.False(t, parser.IsCached(2))
+	assert.True(t, parser.IsCached(numStreams+1))
+
+	// Evicted objects are parsed again.
+	obj, err := parser.LookupByNumber(2)
+	require.NoError(t, err)
+	stream, ok := GetStream(obj)
+	require.True(t, ok)
+	assert.Equal(t, strings.Repeat("a", 1000), string(stream.Stream))
+
+	parser.ReleaseObject(2)
+	assert.False(t, parser.IsCached(2))
+
+	// Without a limit, the objects are kept.
+	parser.SetCacheLimit(0)
+	for i := 0; i < numStreams; i++ {
+		_, err := parser.LookupByNumber(i + 2)
+		require.NoError(t, err)
+	}
+	for i := 0; i < numStreams; i++ {
+		assert.True(t, parser.IsCached(i+2))
+	}
+}
+
+func TestParserLazyStreams(t *testing.T) {
+	parser, err := NewParser(bytes.NewReader(makeCacheTestFile(3, 100)))
+	require.NoError(t, err)
+	parser.SetLazyStreams(true)
+
+	obj, err := parser.LookupByNumber(3)
+	require.NoError(t, err)
+	stream, ok := GetStream(obj)
+	require.True(t, ok)
+	assert.Nil(t, stream.Stream)
+
+	data, err := DecodeStream(stream)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("b", 100), string(data))
+	assert.Len(t, stream.Stream, 100)
+
+	require.NoError(t, stream.ReleaseData())
+	assert.Nil(t, stream.Stream)
+	require.NoError(t, stream.LoadData())
+	assert.Equal(t, strings.Repeat("b", 100), string(stream.Stream))
+
+	stream.Stream = []byte("modified")
+	assert.Error(t, stream.ReleaseData())
+	assert.Error(t, (&PdfObjectStream{}).ReleaseData())
+}
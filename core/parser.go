@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/moolekkari/unipdf/common"
 	"github.com/moolekkari/unipdf/core/security"
@@ -30,21 +31,40 @@ var reXrefSubsection = regexp.MustCompile(`(\d+)\s+(\d+)\s*$`)
 var reXrefEntry = regexp.MustCompile(`(\d+)\s+(\d+)\s+([nf])\s*$`)
 
 // PdfParser parses a PDF file and provides access to the object structure of the PDF.
+//
+// The lookups of objects, i.e. LookupByNumber, LookupByReference, Resolve and the resolution of
+// references with PdfObjectReference.Resolve, are safe for concurrent use once the parser is
+// created and, for encrypted documents, authenticated. The objects returned are shared: they must
+// not be modified while used by other goroutines.
 type PdfParser struct {
 	version Version
 
+	// Guards the reading position and the state changed by the lookups of objects, including
+	// the object caches.
+	mu sync.Mutex
+
 	rs               io.ReadSeeker
+	ra               io.ReaderAt // Reads without changing the reading position of rs, if supported.
 	reader           *bufio.Reader
 	fileSize         int64
 	xrefs            XrefTable
 	xrefOffset       int64     // Offset of first xref object.
 	xrefType         *xrefType // Type of first xref object.
+	xrefSections     []int64   // Offsets of the loaded xref sections, from the latest.
 	objstms          objectStreams
 	trailer          *PdfObjectDictionary
 	crypter          *PdfCrypt
 	repairsAttempted bool // Avoid multiple attempts for repair.
 
+	// Set in recovery mode, see NewParserWithRecovery.
+	repairLog     *RepairLog
+	repairObjects map[int]PdfObject // Objects changed or created by the repairs.
+
 	ObjCache objectCache
+	lru      *objectLRU // Limits the size of ObjCache when set.
+
+	// Read the data of the streams when needed rather than when parsing the stream objects.
+	lazyStreams bool
 
 	// Tracker for reference lookups when looking up Length entry of stream objects.
 	// The Length entries of stream objects are a special case, as they can require recursive parsing, i.e. look up
@@ -1255,6 +1275,7 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 	if err != nil {
 		return nil, err
 	}
+	parser.xrefSections = []int64{offsetXref}
 
 	// Check the XrefStm object also from the trailer.
 	xx := trailerDict.Get("XRefStm")
@@ -1305,6 +1326,7 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 			common.Log.Debug("Attempting to continue by ignoring it")
 			break
 		}
+		parser.xrefSections = append(parser.xrefSections, int64(off))
 
 		xx = ptrailerDict.Get("Prev")
 		if xx != nil {
@@ -1380,7 +1402,7 @@ func (parser *PdfParser) traceStreamLength(lengthObj PdfObject) (PdfObject, erro
 		parser.streamLengthReferenceLookupInProgress[lengthRef.ObjectNumber] = true
 	}
 
-	slo, err := parser.Resolve(lengthObj)
+	slo, err := parser.resolve(lengthObj)
 	if err != nil {
 		return nil, err
 	}
@@ -1394,6 +1416,46 @@ func (parser *PdfParser) traceStreamLength(lengthObj PdfObject) (PdfObject, erro
 	return slo, nil
 }
 
+// checkStreamLength returns the length of the data of the stream with dictionary `dict`, starting
+// at `streamStartOffset`.
+func (parser *PdfParser) checkStreamLength(dict *PdfObjectDictionary, streamStartOffset int64) (PdfObjectInteger, error) {
+	// Special stream length tracing function used to avoid endless recursive looping.
+	slo, err := parser.traceStreamLength(dict.Get("Length"))
+	if err != nil {
+		common.Log.Debug("Fail to trace stream length: %v", err)
+		return 0, err
+	}
+	common.Log.Trace("Stream length? %s", slo)
+
+	pstreamLength, ok := slo.(*PdfObjectInteger)
+	if !ok {
+		return 0, errors.New("stream length needs to be an integer")
+	}
+	streamLength := *pstreamLength
+	if streamLength < 0 {
+		return 0, errors.New("stream needs to be longer than 0")
+	}
+
+	// Validate the stream length based on the cross references.
+	// Find next object with closest offset to current object and calculate
+	// the expected stream length based on that.
+	nextObjectOffset := parser.xrefNextObjectOffset(streamStartOffset)
+	if streamStartOffset+int64(streamLength) > nextObjectOffset && nextObjectOffset > streamStartOffset {
+		common.Log.Debug("Expected ending at %d", streamStartOffset+int64(streamLength))
+		common.Log.Debug("Next object starting at %d", nextObjectOffset)
+		// endstream + "\n" endobj + "\n" (17)
+		newLength := nextObjectOffset - streamStartOffset - 17
+		if newLength < 0 {
+			return 0, errors.New("invalid stream length, going past boundaries")
+		}
+
+		common.Log.Debug("Attempting a length correction to %d...", newLength)
+		streamLength = PdfObjectInteger(newLength)
+		dict.Set("Length", MakeInteger(newLength))
+	}
+	return streamLength, nil
+}
+
 // ParseIndirectObject parses an indirect object from the input stream. Can also be an object stream.
 // Returns the indirect object (*PdfIndirectObject) or the stream object (*PdfObjectStream).
 func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
@@ -1511,40 +1573,13 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					}
 					common.Log.Trace("Stream dict %s", dict)
 
-					// Special stream length tracing function used to avoid endless recursive looping.
-					slo, err := parser.traceStreamLength(dict.Get("Length"))
-					if err != nil {
-						common.Log.Debug("Fail to trace stream length: %v", err)
-						return nil, err
-					}
-					common.Log.Trace("Stream length? %s", slo)
-
-					pstreamLength, ok := slo.(*PdfObjectInteger)
-					if !ok {
-						return nil, errors.New("stream length needs to be an integer")
-					}
-					streamLength := *pstreamLength
-					if streamLength < 0 {
-						return nil, errors.New("stream needs to be longer than 0")
-					}
-
-					// Validate the stream length based on the cross references.
-					// Find next object with closest offset to current object and calculate
-					// the expected stream length based on that.
 					streamStartOffset := parser.GetFileOffset()
-					nextObjectOffset := parser.xrefNextObjectOffset(streamStartOffset)
-					if streamStartOffset+int64(streamLength) > nextObjectOffset && nextObjectOffset > streamStartOffset {
-						common.Log.Debug("Expected ending at %d", streamStartOffset+int64(streamLength))
-						common.Log.Debug("Next object starting at %d", nextObjectOffset)
-						// endstream + "\n" endobj + "\n" (17)
-						newLength := nextObjectOffset - streamStartOffset - 17
-						if newLength < 0 {
-							return nil, errors.New("invalid stream length, going past boundaries")
-						}
-
-						common.Log.Debug("Attempting a length correction to %d...", newLength)
-						streamLength = PdfObjectInteger(newLength)
-						dict.Set("Length", MakeInteger(newLength))
+					var streamLength PdfObjectInteger
+					if parser.repairLog != nil {
+						// In recovery mode, the stream ends at the endstream keyword.
+						streamLength = parser.repairStreamLength(indirect.ObjectNumber, dict, streamStartOffset)
+					} else if streamLength, err = parser.checkStreamLength(dict, streamStartOffset); err != nil {
+						return nil, err
 					}
 
 					// Make sure is less than actual file size.
@@ -1553,16 +1588,20 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 						return nil, errors.New("invalid stream length, larger than file size")
 					}
 
-					stream := make([]byte, streamLength)
-					_, err = parser.ReadAtLeast(stream, int(streamLength))
-					if err != nil {
-						common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
-						common.Log.Debug("ERROR: %v", err)
-						return nil, err
-					}
-
 					streamobj := PdfObjectStream{}
-					streamobj.Stream = stream
+					if parser.lazyStreams && parser.crypter == nil && parser.ra != nil {
+						streamobj.source = &streamSource{parser: parser, offset: streamStartOffset, length: int64(streamLength)}
+						parser.SetFileOffset(streamStartOffset + int64(streamLength))
+					} else {
+						stream := make([]byte, streamLength)
+						_, err = parser.ReadAtLeast(stream, int(streamLength))
+						if err != nil {
+							common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
+							common.Log.Debug("ERROR: %v", err)
+							return nil, err
+						}
+						streamobj.Stream = stream
+					}
 					streamobj.PdfObjectDictionary = indirect.PdfObject.(*PdfObjectDictionary)
 					streamobj.ObjectNumber = indirect.ObjectNumber
 					streamobj.GenerationNumber = indirect.GenerationNumber
@@ -1598,6 +1637,7 @@ func NewParserFromString(txt string) *PdfParser {
 	parser := &PdfParser{
 		ObjCache:                              objectCache{},
 		rs:                                    bufReader,
+		ra:                                    bufReader,
 		reader:                                bufio.NewReader(bufReader),
 		fileSize:                              int64(len(txt)),
 		streamLengthReferenceLookupInProgress: map[int64]bool{},
@@ -1624,6 +1664,7 @@ func NewParser(rs io.ReadSeeker) (*PdfParser, error) {
 	}
 	parser.version.Major = majorVersion
 	parser.version.Minor = minorVersion
+	parser.ra = newReaderAt(parser.rs)
 
 	// Start by reading the xrefs (from bottom).
 	if parser.trailer, err = parser.loadXrefs(); err != nil {
@@ -1641,15 +1682,17 @@ func NewParser(rs io.ReadSeeker) (*PdfParser, error) {
 
 // Resolves a reference, returning the object and indicates whether or not it was cached.
 func (parser *PdfParser) resolveReference(ref *PdfObjectReference) (PdfObject, bool, error) {
-	cachedObj, isCached := parser.ObjCache[int(ref.ObjectNumber)]
+	parser.mu.Lock()
+	defer parser.mu.Unlock()
+	cachedObj, isCached := parser.cachedObject(int(ref.ObjectNumber))
 	if isCached {
 		return cachedObj, true, nil
 	}
-	obj, err := parser.LookupByReference(*ref)
+	obj, err := parser.lookupObject(int(ref.ObjectNumber))
 	if err != nil {
 		return nil, false, err
 	}
-	parser.ObjCache[int(ref.ObjectNumber)] = obj
+	parser.cacheObject(int(ref.ObjectNumber), obj)
 	return obj, false, nil
 }
 
@@ -76,6 +76,9 @@ type PdfObjectStream struct {
 	PdfObjectReference
 	*PdfObjectDictionary
 	Stream []byte
+
+	// The location of the stream data in the file, when loaded lazily.
+	source *streamSource
 }
 
 // PdfObjectStreams represents the primitive PDF object streams.
@@ -786,7 +789,9 @@ func (ind *PdfIndirectObject) String() string {
 func (ind *PdfIndirectObject) WriteString() string {
 	var b strings.Builder
 	b.WriteString(strconv.FormatInt(ind.ObjectNumber, 10))
-	b.WriteString(" 0 R")
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(ind.GenerationNumber, 10))
+	b.WriteString(" R")
 	return b.String()
 }
 
@@ -799,7 +804,9 @@ func (stream *PdfObjectStream) String() string {
 func (stream *PdfObjectStream) WriteString() string {
 	var b strings.Builder
 	b.WriteString(strconv.FormatInt(stream.ObjectNumber, 10))
-	b.WriteString(" 0 R")
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(stream.GenerationNumber, 10))
+	b.WriteString(" R")
 	return b.String()
 }
 
@@ -0,0 +1,750 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/moolekkari/unipdf/common"
+)
+
+// RepairEntry represents a repair made when recovering a damaged document.
+type RepairEntry struct {
+	// ObjectNumber is the number of the repaired object, 0 for the document structure.
+	ObjectNumber int64  `json:"object"`
+	Message      string `json:"message"`
+}
+
+// String returns a description of the repair.
+func (e RepairEntry) String() string {
+	return fmt.Sprintf("object %d: %s", e.ObjectNumber, e.Message)
+}
+
+// RepairLog records the repairs made when recovering a damaged document. Repairs are made when
+// the document is opened and when objects are read, e.g. the stream lengths. Safe for concurrent
+// use.
+type RepairLog struct {
+	mu      sync.Mutex
+	entries []RepairEntry
+}
+
+// add records a repair of object `objNum`.
+func (l *RepairLog) add(objNum int64, format string, args ...interface{}) {
+	entry := RepairEntry{ObjectNumber: objNum, Message: fmt.Sprintf(format, args...)}
+	common.Log.Debug("Repair: %s", entry)
+	l.mu.Lock()
+	l.entries = append(l.entries, entry)
+	l.mu.Unlock()
+}
+
+// Entries returns the repairs made so far.
+func (l *RepairLog) Entries() []RepairEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]RepairEntry(nil), l.entries...)
+}
+
+// NewParserWithRecovery creates a new parser for a damaged PDF document `rs`. Unlike NewParser,
+// which repairs only the cross-reference table when objects cannot be found at their offsets, the
+// recovery mode rebuilds the cross-reference table by scanning the document for objects, including
+// the objects of the object streams, and drops the objects which cannot be read. The lengths of
+// the streams are determined from their endstream keywords, also for truncated streams and streams
+// without endstream keyword. The trailer and the document catalog are reconstructed if missing, and
+// the page tree is rebuilt from the Page objects of the document if broken.
+// The repairs made are recorded in the log returned by GetRepairLog. All the objects of the
+// document are loaded into memory. The document structure of encrypted documents is not repaired.
+func NewParserWithRecovery(rs io.ReadSeeker) (*PdfParser, error) {
+	parser := &PdfParser{
+		rs:                                    rs,
+		ObjCache:                              make(objectCache),
+		streamLengthReferenceLookupInProgress: map[int64]bool{},
+		repairLog:                             &RepairLog{},
+		repairObjects:                         map[int]PdfObject{},
+		// The recovery replaces the repairs made when looking up objects.
+		repairsAttempted: true,
+	}
+
+	majorVersion, minorVersion, err := parser.parsePdfVersion()
+	if err != nil {
+		parser.repairLog.add(0, "PDF header not found, assuming version 1.7")
+		majorVersion, minorVersion = 1, 7
+		parser.reader = bufio.NewReader(parser.rs)
+	}
+	parser.version.Major = majorVersion
+	parser.version.Minor = minorVersion
+	parser.ra = newReaderAt(parser.rs)
+	if parser.fileSize, err = parser.rs.Seek(0, io.SeekEnd); err != nil {
+		return nil, err
+	}
+
+	trailer, err := parser.loadXrefs()
+	if err != nil {
+		parser.repairLog.add(0, "unable to load the cross-reference table: %v", err)
+		trailer = nil
+	}
+	parser.trailer = trailer
+	if err := parser.recover(err == nil); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// GetRepairLog returns the log of the repairs made by a parser created by
+// NewParserWithRecovery, or nil for other parsers.
+func (parser *PdfParser) GetRepairLog() *RepairLog {
+	return parser.repairLog
+}
+
+// recover repairs the document structure. `xrefsLoaded` indicates whether the cross-reference
+// table was loaded.
+func (parser *PdfParser) recover(xrefsLoaded bool) error {
+	if !xrefsLoaded || !parser.xrefEntriesValid() {
+		if err := parser.rebuildXrefsByScan(); err != nil {
+			return err
+		}
+	}
+	if len(parser.xrefs.ObjectMap) == 0 {
+		return errors.New("no objects found")
+	}
+	if parser.trailer != nil && parser.trailer.Get("Encrypt") != nil {
+		parser.repairLog.add(0, "encrypted document: document structure not repaired")
+		return nil
+	}
+
+	parser.removeUnreadableObjects()
+	catalog := parser.repairCatalog()
+	parser.repairPageTree(catalog)
+
+	maxObjNum := 0
+	for objNum := range parser.xrefs.ObjectMap {
+		if objNum > maxObjNum {
+			maxObjNum = objNum
+		}
+	}
+	for objNum := range parser.repairObjects {
+		if objNum > maxObjNum {
+			maxObjNum = objNum
+		}
+	}
+	if size, ok := GetIntVal(parser.trailer.Get("Size")); !ok || size <= maxObjNum {
+		parser.trailer.Set("Size", MakeInteger(int64(maxObjNum+1)))
+	}
+	return nil
+}
+
+// xrefEntriesValid returns true if the objects of the cross-reference table entries are at their
+// offsets.
+func (parser *PdfParser) xrefEntriesValid() bool {
+	for objNum, xref := range parser.xrefs.ObjectMap {
+		if xref.XType != XrefTypeTableEntry {
+			continue
+		}
+		if xref.Offset < 0 || xref.Offset >= parser.fileSize {
+			parser.repairLog.add(int64(objNum), "cross-reference entry offset %d outside of file", xref.Offset)
+			return false
+		}
+		bb, err := parser.ReadBytesAt(xref.Offset, minInt64(32, parser.fileSize-xref.Offset))
+		if err != nil {
+			return false
+		}
+		m := reObjectHeader.FindSubmatch(bb)
+		if m == nil || string(m[1]) != strconv.Itoa(objNum) {
+			parser.repairLog.add(int64(objNum), "no object at cross-reference entry offset %d", xref.Offset)
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	reObjectScan        = regexp.MustCompile(`(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj`)
+	reEndobjScan        = regexp.MustCompile(`endobj`)
+	reEndstreamScan     = regexp.MustCompile(`endstream`)
+	reStreamScan        = regexp.MustCompile(`(end)?stream`)
+	reStreamDataEndScan = regexp.MustCompile(`endstream|endobj`)
+)
+
+// The document is scanned in chunks when recovering it, so that it is not read into memory at
+// once. The chunks grow from scanMinChunk to scanMaxChunk bytes, as the searched keywords are
+// usually close. Consecutive chunks overlap by scanOverlap bytes, so that the keywords and object
+// headers across chunk boundaries are found.
+const (
+	scanMinChunk = 4 << 10
+	scanMaxChunk = 1 << 20
+	scanOverlap  = 256
+)
+
+// scanFind returns the submatch offsets of the first match of `re` in the document between offsets
+// `start` and `end`, or nil if there is none. Matches preceded by a byte `prev` for which `accept`
+// returns false are skipped, if `accept` is not nil. For use when the lookups are locked.
+func (parser *PdfParser) scanFind(re *regexp.Regexp, start, end int64, accept func(prev byte) bool) ([]int64, error) {
+	chunk := int64(scanMinChunk)
+	for pos := start; pos < end; {
+		// Read the byte preceding the chunk for `accept`.
+		from := pos
+		if from > 0 {
+			from--
+		}
+		to := minInt64(pos+chunk+scanOverlap, end)
+		data, err := parser.readBytesAt(from, to-from)
+		if err != nil {
+			return nil, err
+		}
+		for i := int(pos - from); i <= len(data); {
+			m := re.FindSubmatchIndex(data[i:])
+			if m == nil {
+				break
+			}
+			for k := range m {
+				if m[k] >= 0 {
+					m[k] += i
+				}
+			}
+			if to < end && from+int64(m[0]) >= pos+chunk {
+				// The match starts in the overlap and is found again in the next chunk.
+				break
+			}
+			i = m[1]
+			if accept != nil && m[0] > 0 && !accept(data[m[0]-1]) {
+				continue
+			}
+			loc := make([]int64, len(m))
+			for k := range m {
+				loc[k] = int64(m[k])
+				if m[k] >= 0 {
+					loc[k] += from
+				}
+			}
+			return loc, nil
+		}
+		if to == end {
+			break
+		}
+		pos += chunk
+		if chunk < scanMaxChunk {
+			chunk *= 2
+		}
+	}
+	return nil, nil
+}
+
+// rebuildXrefsByScan rebuilds the cross-reference table from the objects found in the document.
+// The later definitions of the objects take precedence, as for incremental updates.
+func (parser *PdfParser) rebuildXrefsByScan() error {
+	parser.mu.Lock()
+	objects, err := parser.scanObjects()
+	parser.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	parser.setRebuiltXrefs(XrefTable{ObjectMap: objects})
+	parser.objstms = make(objectStreams)
+	parser.clearCache()
+	parser.repairLog.add(0, "cross-reference table rebuilt with %d objects", len(objects))
+	parser.addObjectStreamObjects()
+	return nil
+}
+
+// scanObjects returns the cross-reference entries of the objects found in the document.
+func (parser *PdfParser) scanObjects() (map[int]XrefObject, error) {
+	isHeaderStart := func(prev byte) bool {
+		return IsWhiteSpace(prev) || IsDelimiter(prev)
+	}
+	objects := map[int]XrefObject{}
+	for pos := int64(0); pos < parser.fileSize; {
+		m, err := parser.scanFind(reObjectScan, pos, parser.fileSize, isHeaderStart)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			break
+		}
+		header, err := parser.readBytesAt(m[0], m[1]-m[0])
+		if err != nil {
+			return nil, err
+		}
+		objNum, _ := strconv.Atoi(string(header[m[2]-m[0] : m[3]-m[0]]))
+		gen, _ := strconv.Atoi(string(header[m[4]-m[0] : m[5]-m[0]]))
+		if xref, ok := objects[objNum]; !ok || gen >= xref.Generation {
+			objects[objNum] = XrefObject{XType: XrefTypeTableEntry, ObjectNumber: objNum, Generation: gen,
+				Offset: m[0]}
+		}
+		if pos, err = parser.skipStreamData(m[1]); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// skipStreamData returns the offset following the data of the stream of the object whose header
+// ends at `offset`, or `offset` if the object is not a stream.
+func (parser *PdfParser) skipStreamData(offset int64) (int64, error) {
+	// The stream keyword must be within the object.
+	limit := parser.fileSize
+	for _, re := range []*regexp.Regexp{reEndobjScan, reObjectScan} {
+		m, err := parser.scanFind(re, offset, limit, nil)
+		if err != nil {
+			return 0, err
+		}
+		if m != nil {
+			limit = m[0]
+		}
+	}
+	var start int64
+	for i := offset; ; {
+		m, err := parser.scanFind(reStreamScan, i, limit, nil)
+		if err != nil {
+			return 0, err
+		}
+		if m == nil {
+			return offset, nil
+		}
+		i = m[1]
+		if m[2] < 0 {
+			start = m[1]
+			break
+		}
+	}
+
+	// The data end with the endstream keyword, or the endobj keyword if missing.
+	m, err := parser.scanFind(reStreamDataEndScan, start, parser.fileSize, nil)
+	if err != nil || m == nil {
+		return parser.fileSize, err
+	}
+	return m[0], nil
+}
+
+// addObjectStreamObjects adds the objects of the object streams found in the document to the
+// cross-reference table. An object of an object stream takes precedence over a direct object
+// defined before the object stream.
+func (parser *PdfParser) addObjectStreamObjects() {
+	var streams []XrefObject
+	for _, xref := range parser.xrefs.ObjectMap {
+		streams = append(streams, xref)
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].Offset < streams[j].Offset })
+
+	for _, xref := range streams {
+		obj, err := parser.LookupByNumber(xref.ObjectNumber)
+		if err != nil {
+			continue
+		}
+		stream, ok := obj.(*PdfObjectStream)
+		if !ok {
+			continue
+		}
+		if name, ok := GetName(stream.Get("Type")); !ok || *name != "ObjStm" {
+			continue
+		}
+		objNums, err := parser.objectStreamObjects(stream)
+		if err != nil {
+			parser.repairLog.add(int64(xref.ObjectNumber), "broken object stream: %v", err)
+			continue
+		}
+		for i, objNum := range objNums {
+			if objNum == xref.ObjectNumber {
+				continue
+			}
+			// The object streams are processed in order, so only a later direct object takes
+			// precedence.
+			if direct, ok := parser.xrefs.ObjectMap[objNum]; ok &&
+				direct.XType == XrefTypeTableEntry && direct.Offset > xref.Offset {
+				continue
+			}
+			parser.xrefs.ObjectMap[objNum] = XrefObject{XType: XrefTypeObjectStream, ObjectNumber: objNum,
+				OsObjNumber: xref.ObjectNumber, OsObjIndex: i}
+			// The replaced direct object may be cached.
+			parser.releaseObject(objNum)
+		}
+	}
+}
+
+// objectStreamObjects returns the numbers of the objects of the object stream `stream`.
+func (parser *PdfParser) objectStreamObjects(stream *PdfObjectStream) ([]int, error) {
+	n, ok := GetIntVal(stream.Get("N"))
+	if !ok || n < 0 {
+		return nil, errors.New("invalid N")
+	}
+	first, ok := GetIntVal(stream.Get("First"))
+	if !ok || first < 0 {
+		return nil, errors.New("invalid First")
+	}
+	data, err := DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	if first > len(data) {
+		return nil, errors.New("First outside of stream data")
+	}
+	p := NewParserFromString(string(data[:first]))
+	var objNums []int
+	for i := 0; i < n; i++ {
+		p.skipSpaces()
+		objNum, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if _, err := p.parseNumber(); err != nil {
+			return nil, err
+		}
+		num, ok := GetIntVal(objNum)
+		if !ok {
+			return nil, errors.New("invalid object number")
+		}
+		objNums = append(objNums, num)
+	}
+	return objNums, nil
+}
+
+// removeUnreadableObjects removes the objects which cannot be read from the cross-reference
+// table.
+func (parser *PdfParser) removeUnreadableObjects() {
+	for _, objNum := range parser.GetObjectNums() {
+		if _, err := parser.LookupByNumber(objNum); err != nil {
+			parser.repairLog.add(int64(objNum), "unreadable object removed: %v", err)
+			delete(parser.xrefs.ObjectMap, objNum)
+		}
+	}
+	parser.xrefs.sortedObjects = nil
+}
+
+// newRepairObject adds the new object `obj` to the document and returns a reference to it.
+func (parser *PdfParser) newRepairObject(obj PdfObject) *PdfObjectReference {
+	objNum := 1
+	for num := range parser.xrefs.ObjectMap {
+		if num >= objNum {
+			objNum = num + 1
+		}
+	}
+	for num := range parser.repairObjects {
+		if num >= objNum {
+			objNum = num + 1
+		}
+	}
+	ind := MakeIndirectObject(obj)
+	ind.ObjectNumber = int64(objNum)
+	ind.parser = parser
+	parser.repairObjects[objNum] = ind
+	return &PdfObjectReference{ObjectNumber: int64(objNum), parser: parser}
+}
+
+// repairedObject records that object `objNum` `obj` was changed by the repairs.
+func (parser *PdfParser) repairedObject(objNum int64, obj PdfObject) {
+	parser.repairObjects[int(objNum)] = obj
+}
+
+// indirectDict returns the dictionary of the indirect object referred to by `obj`.
+func (parser *PdfParser) indirectDict(obj PdfObject) (*PdfIndirectObject, *PdfObjectDictionary, bool) {
+	ref, ok := obj.(*PdfObjectReference)
+	if !ok {
+		return nil, nil, false
+	}
+	obj, err := parser.LookupByNumber(int(ref.ObjectNumber))
+	if err != nil {
+		return nil, nil, false
+	}
+	ind, ok := obj.(*PdfIndirectObject)
+	if !ok {
+		return nil, nil, false
+	}
+	dict, ok := ind.PdfObject.(*PdfObjectDictionary)
+	return ind, dict, ok
+}
+
+// documentDicts returns the dictionaries of type `typ` of the document, in the order of the
+// document.
+func (parser *PdfParser) documentDicts(typ PdfObjectName) []*PdfIndirectObject {
+	type position struct {
+		offset int64
+		index  int
+	}
+	positions := map[int]position{}
+	var dicts []*PdfIndirectObject
+	for _, objNum := range parser.GetObjectNums() {
+		obj, err := parser.LookupByNumber(objNum)
+		if err != nil {
+			continue
+		}
+		ind, ok := obj.(*PdfIndirectObject)
+		if !ok {
+			continue
+		}
+		dict, ok := ind.PdfObject.(*PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		if name, ok := GetName(dict.Get("Type")); !ok || *name != typ {
+			continue
+		}
+		xref := parser.xrefs.ObjectMap[objNum]
+		pos := position{offset: xref.Offset}
+		if xref.XType == XrefTypeObjectStream {
+			pos = position{offset: parser.xrefs.ObjectMap[xref.OsObjNumber].Offset, index: xref.OsObjIndex}
+		}
+		positions[objNum] = pos
+		dicts = append(dicts, ind)
+	}
+	sort.SliceStable(dicts, func(i, j int) bool {
+		pi, pj := positions[int(dicts[i].ObjectNumber)], positions[int(dicts[j].ObjectNumber)]
+		if pi.offset != pj.offset {
+			return pi.offset < pj.offset
+		}
+		return pi.index < pj.index
+	})
+	return dicts
+}
+
+// repairCatalog repairs the trailer and its Root entry. Returns the catalog.
+func (parser *PdfParser) repairCatalog() *PdfIndirectObject {
+	if parser.trailer == nil {
+		parser.trailer = MakeDict()
+		parser.repairLog.add(0, "trailer created")
+	}
+	if ind, dict, ok := parser.indirectDict(parser.trailer.Get("Root")); ok && dict.Get("Pages") != nil {
+		return ind
+	}
+
+	// Use the latest catalog of the document, preferably with a page tree.
+	var catalog *PdfIndirectObject
+	catalogs := parser.documentDicts("Catalog")
+	for i := len(catalogs) - 1; i >= 0; i-- {
+		if catalogs[i].PdfObject.(*PdfObjectDictionary).Get("Pages") != nil {
+			catalog = catalogs[i]
+			break
+		}
+	}
+	if catalog == nil && len(catalogs) > 0 {
+		catalog = catalogs[len(catalogs)-1]
+	}
+	if catalog != nil {
+		if ref, ok := parser.trailer.Get("Root").(*PdfObjectReference); !ok || ref.ObjectNumber != catalog.ObjectNumber {
+			parser.trailer.Set("Root", &PdfObjectReference{ObjectNumber: catalog.ObjectNumber, parser: parser})
+			parser.repairLog.add(catalog.ObjectNumber, "catalog used as trailer Root")
+		}
+		return catalog
+	}
+
+	dict := MakeDict()
+	dict.Set("Type", MakeName("Catalog"))
+	ref := parser.newRepairObject(dict)
+	parser.trailer.Set("Root", ref)
+	parser.repairLog.add(ref.ObjectNumber, "catalog created")
+	return parser.repairObjects[int(ref.ObjectNumber)].(*PdfIndirectObject)
+}
+
+// inheritablePageKeys are the inheritable entries of the pages.
+var inheritablePageKeys = []PdfObjectName{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// repairPageTree checks the page tree of `catalog` and rebuilds it from the Page objects of the
+// document if it is broken. The Count and Parent entries of the nodes are repaired otherwise.
+func (parser *PdfParser) repairPageTree(catalog *PdfIndirectObject) {
+	catalogDict := catalog.PdfObject.(*PdfObjectDictionary)
+	r := pageTreeRepair{parser: parser, visited: map[int64]struct{}{}}
+	if root, ok := catalogDict.Get("Pages").(*PdfObjectReference); ok {
+		r.check(root, nil)
+	} else {
+		r.broken = true
+	}
+	if !r.broken && len(r.pages) > 0 {
+		for _, fix := range r.fixes {
+			fix()
+		}
+		return
+	}
+
+	// Rebuild the page tree with the pages of the tree first, followed by the other pages of the
+	// document.
+	pages := r.pages
+	for _, page := range parser.documentDicts("Page") {
+		if _, ok := r.visited[page.ObjectNumber]; !ok {
+			pages = append(pages, page)
+		}
+	}
+	node := MakeDict()
+	nodeRef := parser.newRepairObject(node)
+	kids := MakeArray()
+	for _, page := range pages {
+		dict := page.PdfObject.(*PdfObjectDictionary)
+		// Keep the inherited entries, as the parent is replaced.
+		for _, key := range inheritablePageKeys {
+			if dict.Get(key) == nil {
+				if value := parser.inheritedPageEntry(dict, key); value != nil {
+					dict.Set(key, value)
+				}
+			}
+		}
+		dict.Set("Parent", nodeRef)
+		parser.repairedObject(page.ObjectNumber, page)
+		kids.Append(&PdfObjectReference{ObjectNumber: page.ObjectNumber, parser: parser})
+	}
+	node.Set("Type", MakeName("Pages"))
+	node.Set("Kids", kids)
+	node.Set("Count", MakeInteger(int64(len(pages))))
+	catalogDict.Set("Pages", nodeRef)
+	parser.repairedObject(catalog.ObjectNumber, catalog)
+	parser.repairLog.add(nodeRef.ObjectNumber, "page tree rebuilt with %d pages", len(pages))
+}
+
+// inheritedPageEntry returns the entry `key` inherited by the page `dict` from its ancestors.
+func (parser *PdfParser) inheritedPageEntry(dict *PdfObjectDictionary, key PdfObjectName) PdfObject {
+	visited := map[int64]struct{}{}
+	for {
+		ref, ok := dict.Get("Parent").(*PdfObjectReference)
+		if !ok {
+			return nil
+		}
+		if _, ok := visited[ref.ObjectNumber]; ok {
+			return nil
+		}
+		visited[ref.ObjectNumber] = struct{}{}
+		if _, dict, ok = parser.indirectDict(ref); !ok {
+			return nil
+		}
+		if value := dict.Get(key); value != nil {
+			return value
+		}
+	}
+}
+
+// pageTreeRepair checks a page tree for repair.
+type pageTreeRepair struct {
+	parser  *PdfParser
+	visited map[int64]struct{}
+	pages   []*PdfIndirectObject // The pages of the tree, in order.
+	fixes   []func()             // The repairs of the Count and Parent entries.
+	broken  bool                 // Whether the tree must be rebuilt.
+}
+
+// check checks the node referred to by `ref` with parent `parent`. Returns the number of pages of
+// the node.
+func (r *pageTreeRepair) check(ref *PdfObjectReference, parent *PdfIndirectObject) int {
+	if _, ok := r.visited[ref.ObjectNumber]; ok {
+		r.parser.repairLog.add(ref.ObjectNumber, "page tree node referred to more than once")
+		r.broken = true
+		return 0
+	}
+	r.visited[ref.ObjectNumber] = struct{}{}
+	ind, dict, ok := r.parser.indirectDict(ref)
+	if !ok {
+		r.parser.repairLog.add(ref.ObjectNumber, "invalid page tree node")
+		r.broken = true
+		return 0
+	}
+
+	if parent != nil {
+		if p, ok := dict.Get("Parent").(*PdfObjectReference); !ok || p.ObjectNumber != parent.ObjectNumber {
+			r.fixes = append(r.fixes, func() {
+				dict.Set("Parent", &PdfObjectReference{ObjectNumber: parent.ObjectNumber, parser: r.parser})
+				r.parser.repairedObject(ind.ObjectNumber, ind)
+				r.parser.repairLog.add(ind.ObjectNumber, "Parent set to %d", parent.ObjectNumber)
+			})
+		}
+	}
+
+	typ, _ := GetName(dict.Get("Type"))
+	if typ != nil && *typ == "Page" && parent == nil {
+		r.parser.repairLog.add(ind.ObjectNumber, "page as page tree root")
+		r.broken = true
+		return 0
+	}
+	if typ != nil && *typ == "Page" {
+		r.pages = append(r.pages, ind)
+		return 1
+	}
+	kids, ok := GetArray(TraceToDirectObject(dict.Get("Kids")))
+	if (typ != nil && *typ != "Pages") || !ok {
+		r.parser.repairLog.add(ind.ObjectNumber, "invalid page tree node")
+		r.broken = true
+		return 0
+	}
+	if typ == nil {
+		r.fixes = append(r.fixes, func() {
+			dict.Set("Type", MakeName("Pages"))
+			r.parser.repairedObject(ind.ObjectNumber, ind)
+			r.parser.repairLog.add(ind.ObjectNumber, "Type set to Pages")
+		})
+	}
+
+	count := 0
+	for _, kid := range kids.Elements() {
+		kidRef, ok := kid.(*PdfObjectReference)
+		if !ok {
+			r.parser.repairLog.add(ind.ObjectNumber, "invalid page tree node kid")
+			r.broken = true
+			continue
+		}
+		count += r.check(kidRef, ind)
+	}
+	if n, ok := GetIntVal(dict.Get("Count")); !ok || n != count {
+		r.fixes = append(r.fixes, func() {
+			dict.Set("Count", MakeInteger(int64(count)))
+			r.parser.repairedObject(ind.ObjectNumber, ind)
+			r.parser.repairLog.add(ind.ObjectNumber, "Count set to %d", count)
+		})
+	}
+	return count
+}
+
+// repairStreamLength returns the length of the data of the stream of object `objNum` with
+// dictionary `dict`, starting at `offset`. The data end with the endstream keyword, or the endobj
+// keyword or the next object if missing. The Length entry of `dict` is repaired if incorrect.
+func (parser *PdfParser) repairStreamLength(objNum int64, dict *PdfObjectDictionary, offset int64) PdfObjectInteger {
+	// Resolving the Length entry changes the reading position.
+	defer parser.SetFileOffset(offset)
+
+	declared := int64(-1)
+	if slo, err := parser.traceStreamLength(dict.Get("Length")); err == nil {
+		if length, ok := GetIntVal(slo); ok && length >= 0 {
+			declared = int64(length)
+		}
+	}
+
+	limit := parser.fileSize
+	if next := parser.xrefNextObjectOffset(offset); next > offset {
+		limit = next
+	}
+
+	const keyword = "endstream"
+	if declared >= 0 && declared < limit-offset {
+		// Allow for a few end-of-line markers before the keyword.
+		n := minInt64(int64(len(keyword))+16, limit-offset-declared)
+		if data, err := parser.readBytesAt(offset+declared, n); err == nil &&
+			bytes.HasPrefix(bytes.TrimLeft(data, "\r\n"), []byte(keyword)) {
+			return PdfObjectInteger(declared)
+		}
+	}
+
+	// Find the end of the data.
+	length := limit - offset
+	msg := "truncated stream"
+	if m, _ := parser.scanFind(reEndstreamScan, offset, limit, nil); m != nil {
+		length, msg = m[0]-offset, "invalid stream Length"
+	} else if m, _ := parser.scanFind(reEndobjScan, offset, limit, nil); m != nil {
+		length, msg = m[0]-offset, "stream without endstream"
+	}
+	if length > 0 {
+		// Strip the end-of-line marker preceding the keyword.
+		n := minInt64(2, length)
+		if eol, err := parser.readBytesAt(offset+length-n, n); err == nil {
+			if eol[n-1] == '\n' {
+				length--
+				n--
+			}
+			if n > 0 && eol[n-1] == '\r' {
+				length--
+			}
+		}
+	}
+
+	parser.repairLog.add(objNum, "%s: Length %d corrected to %d", msg, declared, length)
+	dict.Set("Length", MakeInteger(length))
+	return PdfObjectInteger(length)
+}
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/internal/pdftest"
+)
+
+// repairMessages returns the messages of the repairs of `log` by object number.
+func repairMessages(log *RepairLog) map[int64][]string {
+	messages := map[int64][]string{}
+	for _, entry := range log.Entries() {
+		messages[entry.ObjectNumber] = append(messages[entry.ObjectNumber], entry.Message)
+	}
+	return messages
+}
+
+// pageNumbers returns the object numbers of the pages of the page tree of the document parsed by
+// `parser`, which must have a single node.
+func pageNumbers(t *testing.T, parser *PdfParser) []int64 {
+	catalog, ok := GetDict(parser.GetTrailer().Get("Root"))
+	require.True(t, ok)
+	pages, ok := GetDict(catalog.Get("Pages"))
+	require.True(t, ok)
+	kids, ok := GetArray(pages.Get("Kids"))
+	require.True(t, ok)
+	var objNums []int64
+	for _, kid := range kids.Elements() {
+		objNums = append(objNums, kid.(*PdfObjectReference).ObjectNumber)
+	}
+	count, _ := GetIntVal(pages.Get("Count"))
+	require.Equal(t, len(objNums), count)
+	return objNums
+}
+
+func TestRecoveryValid(t *testing.T) {
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		pdftest.Stream(strings.Repeat("x", 20)),
+	)
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Empty(t, parser.GetRepairLog().Entries())
+	require.Equal(t, []int64{3}, pageNumbers(t, parser))
+
+	parser, err = NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Nil(t, parser.GetRepairLog())
+}
+
+func TestRecoveryStreams(t *testing.T) {
+	data := pdftest.File{NoXref: true, Objects: []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [4 0 R 5 0 R 6 0 R] >>",
+		"<< /Length 10 >>\nstream\n" + strings.Repeat("a", 20) + "\nendstream",
+		"<< >>\nstream\n" + strings.Repeat("b", 20),
+		"<< /Length 30 >>\nstream\r\n" + strings.Repeat("c", 20),
+	}}.Bytes()
+	// Truncate the last stream.
+	data = data[:len(data)-len("\nendobj\n")-5]
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	for objNum, expected := range map[int]string{
+		4: strings.Repeat("a", 20),
+		5: strings.Repeat("b", 20),
+		6: strings.Repeat("c", 15),
+	} {
+		obj, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+		stream, ok := obj.(*PdfObjectStream)
+		require.True(t, ok)
+		require.Equal(t, expected, string(stream.Stream))
+		length, _ := GetIntVal(stream.Get("Length"))
+		require.Equal(t, len(expected), length)
+	}
+
+	messages := repairMessages(parser.GetRepairLog())
+	require.Equal(t, []string{"invalid stream Length: Length 10 corrected to 20"}, messages[4])
+	require.Equal(t, []string{"stream without endstream: Length -1 corrected to 20"}, messages[5])
+	require.Equal(t, []string{"truncated stream: Length 30 corrected to 15"}, messages[6])
+	require.Equal(t, []string{
+		"unable to load the cross-reference table: EOF not found",
+		"cross-reference table rebuilt with 6 objects",
+		"trailer created",
+	}, messages[0])
+	require.Equal(t, []string{"catalog used as trailer Root"}, messages[1])
+	size, _ := GetIntVal(parser.GetTrailer().Get("Size"))
+	require.Equal(t, 7, size)
+}
+
+// TestRecoveryLargeStreams checks the repair of streams spanning several of the chunks in which
+// the document is scanned.
+func TestRecoveryLargeStreams(t *testing.T) {
+	data := pdftest.File{NoXref: true, Objects: []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [4 0 R 5 0 R] >>",
+		"<< /Length 10 >>\nstream\n" + strings.Repeat("a", 3*scanMaxChunk) + "\nendstream",
+		"<< >>\nstream\n" + strings.Repeat("b", scanMaxChunk+100),
+		"(six)",
+	}}.Bytes()
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	for objNum, expected := range map[int]int{4: 3 * scanMaxChunk, 5: scanMaxChunk + 100} {
+		obj, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+		stream, ok := obj.(*PdfObjectStream)
+		require.True(t, ok)
+		require.Len(t, stream.Stream, expected)
+	}
+	obj, err := parser.LookupByNumber(6)
+	require.NoError(t, err)
+	str, ok := GetString(obj.(*PdfIndirectObject).PdfObject)
+	require.True(t, ok)
+	require.Equal(t, "six", str.Str())
+
+	messages := repairMessages(parser.GetRepairLog())
+	require.Equal(t, []string{"invalid stream Length: Length 10 corrected to 3145728"}, messages[4])
+	require.Equal(t, []string{"stream without endstream: Length -1 corrected to 1048676"}, messages[5])
+	require.Contains(t, messages[0], "cross-reference table rebuilt with 6 objects")
+}
+
+func TestScanFind(t *testing.T) {
+	// Object headers at and across the boundaries of the chunks, which double in size.
+	offsets := []int64{0, scanMinChunk - 3, scanMinChunk, 3*scanMinChunk - 1, 5 * scanMinChunk, scanMaxChunk + 5}
+	for _, offset := range offsets {
+		data := []byte(strings.Repeat(" ", int(offset)) + "12 0 obj" + strings.Repeat(" ", scanMaxChunk))
+		parser := &PdfParser{ra: bytes.NewReader(data), fileSize: int64(len(data))}
+		m, err := parser.scanFind(reObjectScan, 0, parser.fileSize, nil)
+		require.NoError(t, err)
+		require.Equal(t, []int64{offset, offset + 8, offset, offset + 2, offset + 3, offset + 4}, m)
+	}
+
+	// Matches rejected by the preceding byte are skipped.
+	data := []byte("a12 0 obj 5 0 obj")
+	parser := &PdfParser{ra: bytes.NewReader(data), fileSize: int64(len(data))}
+	isSpace := func(prev byte) bool { return prev == ' ' }
+	m, err := parser.scanFind(reObjectScan, 0, parser.fileSize, isSpace)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), m[0])
+	m, err = parser.scanFind(reEndstreamScan, 0, parser.fileSize, nil)
+	require.NoError(t, err)
+	require.Nil(t, m)
+}
+
+func TestRecoveryPageTree(t *testing.T) {
+	// Broken Kids entry of the root node, Page 5 outside of the page tree, no Root in trailer.
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 9 0 R] /Count 3 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"(four)",
+		"<< /Type /Page /Parent 4 0 R >>",
+	)
+	data = bytes.Replace(data, []byte("/Root 1 0 R"), nil, 1)
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	require.Equal(t, []int64{3, 5}, pageNumbers(t, parser))
+	messages := repairMessages(parser.GetRepairLog())
+	require.Equal(t, []string{"catalog used as trailer Root"}, messages[1])
+	require.Equal(t, []string{"invalid page tree node"}, messages[9])
+	require.Equal(t, []string{"page tree rebuilt with 2 pages"}, messages[6])
+
+	// The pages have the new parent and the inherited entries of the previous parent.
+	for _, objNum := range []int{3, 5} {
+		obj, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+		dict := obj.(*PdfIndirectObject).PdfObject.(*PdfObjectDictionary)
+		require.Equal(t, int64(6), dict.Get("Parent").(*PdfObjectReference).ObjectNumber)
+		if objNum == 3 {
+			require.NotNil(t, dict.Get("MediaBox"))
+		}
+	}
+	size, _ := GetIntVal(parser.GetTrailer().Get("Size"))
+	require.Equal(t, 7, size)
+}
+
+func TestRecoveryPageTreeCounts(t *testing.T) {
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 3 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Type /Page >>",
+	)
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	require.Equal(t, []int64{3, 4}, pageNumbers(t, parser))
+	require.Equal(t, map[int64][]string{
+		2: {"Count set to 2"},
+		4: {"Parent set to 2"},
+	}, repairMessages(parser.GetRepairLog()))
+}
+
+func TestRecoveryCatalog(t *testing.T) {
+	// No catalog, broken xref offsets and a broken object stream.
+	data := pdftest.File{
+		Objects: []string{
+			"<< /Type /Page >>",
+			"<< /Type /Page /Contents 3 0 R >>",
+			"<< /Length 2 >>\nstream\nxx\nendstream",
+			"<< /Type /ObjStm /N 2 /First 20 /Length 3 >>\nstream\nbad\nendstream",
+		},
+		FixOffsets: func(offsets []int) {
+			offsets[0] += 3
+		},
+	}.Bytes()
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	require.Equal(t, []int64{1, 2}, pageNumbers(t, parser))
+	messages := repairMessages(parser.GetRepairLog())
+	require.Equal(t, []string{"no object at cross-reference entry offset 12"}, messages[1])
+	require.Equal(t, []string{"broken object stream: First outside of stream data"}, messages[4])
+	require.Equal(t, []string{"catalog created"}, messages[5])
+	require.Equal(t, []string{"page tree rebuilt with 2 pages"}, messages[6])
+}
+
+func TestRecoveryObjectStream(t *testing.T) {
+	pages := "<< /Type /Pages /Kids [3 0 R] /Count 1 >> "
+	objects := fmt.Sprintf("2 0 3 %d ", len(pages))
+	first := len(objects)
+	objects += pages + "<< /Type /Page /Parent 2 0 R >>"
+	data := pdftest.File{NoXref: true, Objects: []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"(replaced)",
+		"(replaced)",
+		fmt.Sprintf("<< /Type /ObjStm /N 2 /First %d /Length %d >>\nstream\n%s\nendstream",
+			first, len(objects), objects),
+	}}.Bytes()
+	parser, err := NewParserWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, []int64{3}, pageNumbers(t, parser))
+}
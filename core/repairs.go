@@ -41,6 +41,14 @@ func (parser *PdfParser) repairLocateXref() (int64, error) {
 	return xrefOffset, nil
 }
 
+// setRebuiltXrefs sets the cross-reference table of the parser to `xrefs`, rebuilt from the objects
+// of the document rather than loaded from its cross-reference sections. The sections are forgotten
+// so that the document is read as a single revision (see GetRevisions).
+func (parser *PdfParser) setRebuiltXrefs(xrefs XrefTable) {
+	parser.xrefs = xrefs
+	parser.xrefSections = nil
+}
+
 // Renumbers the xref table.
 // Useful when the cross reference is pointing to an object with the wrong number.
 // Update the table.
@@ -57,7 +65,7 @@ func (parser *PdfParser) rebuildXrefTable() error {
 				common.Log.Debug("ERROR: Failed xref rebuild repair (%s)", err)
 				return err
 			}
-			parser.xrefs = *xrefTable
+			parser.setRebuiltXrefs(*xrefTable)
 			common.Log.Debug("Repaired xref table built")
 			return nil
 		}
@@ -71,7 +79,7 @@ func (parser *PdfParser) rebuildXrefTable() error {
 		newXrefs.ObjectMap[int(actObjNum)] = xref
 	}
 
-	parser.xrefs = newXrefs
+	parser.setRebuiltXrefs(newXrefs)
 	common.Log.Debug("New xref table built")
 	printXrefTable(parser.xrefs)
 	return nil
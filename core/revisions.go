@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bytes"
+)
+
+// Revision represents a revision of a PDF document, i.e. the original document or an incremental
+// update appended to it.
+type Revision struct {
+	// XrefOffset is the offset of the cross-reference section of the revision, i.e. the startxref
+	// value of the revision.
+	XrefOffset int64
+
+	// Length is the length of the document up to the end of the revision, including its %%EOF
+	// marker. The first Length bytes of the document are the document at the revision.
+	Length int64
+}
+
+// GetRevisions returns the revisions of the document, from the original document to the latest
+// incremental update. The revisions are determined by following the chain of cross-reference
+// sections, from the startxref value of the document to the sections referred to by the Prev
+// entries of the trailers.
+func (parser *PdfParser) GetRevisions() ([]Revision, error) {
+	parser.mu.Lock()
+	sections := append([]int64(nil), parser.xrefSections...)
+	parser.mu.Unlock()
+
+	if len(sections) == 0 {
+		// The cross-reference table was rebuilt, thus the document is read as a single revision.
+		return []Revision{{XrefOffset: parser.xrefOffset, Length: parser.fileSize}}, nil
+	}
+
+	// The revision of a section ends with the first %%EOF marker after the section. A revision
+	// includes the revisions of the sections it refers to, e.g. the first page section of a
+	// linearized document refers to the main section written after it.
+	var revisions []Revision
+	var length int64
+	for i := len(sections) - 1; i >= 0; i-- {
+		end, err := parser.revisionEnd(sections[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions) > 0 && end <= length {
+			revisions[len(revisions)-1].XrefOffset = sections[i]
+			continue
+		}
+		length = end
+		revisions = append(revisions, Revision{XrefOffset: sections[i], Length: length})
+	}
+	return revisions, nil
+}
+
+// revisionEnd returns the offset following the first %%EOF marker after `offset`, including the
+// end-of-line marker following it. Returns the file size if there is no %%EOF marker.
+func (parser *PdfParser) revisionEnd(offset int64) (int64, error) {
+	const bufLen = 2048
+	marker := []byte("%%EOF")
+	for offset < parser.fileSize {
+		n := int64(bufLen)
+		if offset+n > parser.fileSize {
+			n = parser.fileSize - offset
+		}
+		bb, err := parser.ReadBytesAt(offset, n)
+		if err != nil {
+			return 0, err
+		}
+		if i := bytes.Index(bb, marker); i >= 0 {
+			end := offset + int64(i+len(marker))
+			eol, err := parser.ReadBytesAt(end, minInt64(2, parser.fileSize-end))
+			if err != nil {
+				return 0, err
+			}
+			switch {
+			case bytes.HasPrefix(eol, []byte("\r\n")):
+				end += 2
+			case len(eol) > 0 && (eol[0] == '\r' || eol[0] == '\n'):
+				end++
+			}
+			return end, nil
+		}
+		if offset+n >= parser.fileSize {
+			break
+		}
+		// Overlap the buffers in case the marker is split between them.
+		offset += n - int64(len(marker)-1)
+	}
+	return parser.fileSize, nil
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/internal/pdftest"
+)
+
+// appendTestUpdate returns `data` followed by an incremental update adding object `objNum` with
+// body `obj`.
+func appendTestUpdate(t *testing.T, data []byte, objNum int, obj string) []byte {
+	i := bytes.LastIndex(data, []byte("startxref\n"))
+	require.True(t, i >= 0)
+	fields := bytes.Fields(data[i+len("startxref\n"):])
+	require.NotEmpty(t, fields)
+	prev, err := strconv.Atoi(string(fields[0]))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	offset := buf.Len()
+	fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", objNum, obj)
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n%d 1\n%.10d 00000 n\r\n", objNum, offset)
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Prev %d >>\nstartxref\n%d\n%%%%EOF\n",
+		objNum+1, prev, xrefOffset)
+	return buf.Bytes()
+}
+
+func TestRevisions(t *testing.T) {
+	base := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"(three)",
+	)
+	data := appendTestUpdate(t, base, 4, "(four)")
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	revisions, err := parser.GetRevisions()
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, int64(len(base)), revisions[0].Length)
+	require.Equal(t, int64(len(data)), revisions[1].Length)
+}
+
+// TestRevisionsRepaired checks that a document whose cross-reference table was rebuilt when
+// repairing it is read as a single revision.
+func TestRevisionsRepaired(t *testing.T) {
+	base := pdftest.File{
+		Objects: []string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [] /Count 0 >>",
+			"(three)",
+		},
+		FixOffsets: func(offsets []int) {
+			// Point into the body of object 3.
+			offsets[2] += 9
+		},
+	}.Bytes()
+	data := appendTestUpdate(t, base, 4, "(four)")
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	obj, err := parser.LookupByNumber(3)
+	require.NoError(t, err)
+	str, ok := GetString(TraceToDirectObject(obj))
+	require.True(t, ok)
+	require.Equal(t, "three", str.Str())
+
+	revisions, err := parser.GetRevisions()
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, int64(len(data)), revisions[0].Length)
+}
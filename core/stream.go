@@ -7,7 +7,12 @@ import (
 )
 
 // NewEncoderFromStream creates a StreamEncoder based on the stream's dictionary.
+// The data of streams parsed with lazy stream data is loaded, as some encoders need it and the
+// stream is usually decoded with the returned encoder.
 func NewEncoderFromStream(streamObj *PdfObjectStream) (StreamEncoder, error) {
+	if err := streamObj.LoadData(); err != nil {
+		return nil, err
+	}
 	filterObj := TraceToDirectObject(streamObj.PdfObjectDictionary.Get("Filter"))
 	if filterObj == nil {
 		// No filter, return raw data back.
@@ -0,0 +1,389 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// ValidationSeverity represents the severity of a validation issue.
+type ValidationSeverity int
+
+const (
+	// ValidationWarning indicates a deviation from the specification which readers usually
+	// tolerate, e.g. a reference to an undefined object, which is treated as the null object.
+	ValidationWarning ValidationSeverity = iota
+
+	// ValidationError indicates a violation of the specification, which can prevent readers from
+	// processing the document.
+	ValidationError
+)
+
+// String returns the name of the severity.
+func (s ValidationSeverity) String() string {
+	if s == ValidationError {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText implements encoding.TextMarshaler, so that the severity is written by name in
+// machine-readable reports.
+func (s ValidationSeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Codes of the validation issues.
+const (
+	ValidationCodeTrailer         = "trailer"          // Invalid trailer entry.
+	ValidationCodeXrefOffset      = "xref-offset"      // No object at the offset of an xref entry.
+	ValidationCodeObjectNumber    = "object-number"    // Object number differs from the xref entry.
+	ValidationCodeGeneration      = "generation"       // Generation number differs from the xref entry.
+	ValidationCodeObjectStream    = "object-stream"    // Invalid object stream of a compressed object.
+	ValidationCodeParse           = "parse"            // Object cannot be parsed.
+	ValidationCodeStreamLength    = "stream-length"    // Incorrect stream Length.
+	ValidationCodeBrokenReference = "broken-reference" // Reference to an undefined object.
+	ValidationCodeMissingKey      = "missing-key"      // Required dictionary entry missing.
+	ValidationCodePageTree        = "page-tree"        // Invalid page tree node.
+)
+
+// ValidationIssue represents an issue found by the validation of a document.
+type ValidationIssue struct {
+	Severity ValidationSeverity `json:"severity"`
+	Code     string             `json:"code"`
+
+	// ObjectNumber is the number of the object with the issue, 0 for the trailer.
+	ObjectNumber int64  `json:"object"`
+	Message      string `json:"message"`
+}
+
+// String returns a description of the issue.
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: object %d: %s: %s", i.Severity, i.ObjectNumber, i.Code, i.Message)
+}
+
+// ValidationReport represents the result of the validation of a document.
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// Add adds an issue with severity `severity`, code `code` for object `objNum` to the report.
+func (r *ValidationReport) Add(severity ValidationSeverity, code string, objNum int64, format string,
+	args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity:     severity,
+		Code:         code,
+		ObjectNumber: objNum,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// IsValid returns true if the report has no errors.
+func (r *ValidationReport) IsValid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors returns the issues of the report with severity ValidationError.
+func (r *ValidationReport) Errors() []ValidationIssue {
+	var errs []ValidationIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == ValidationError {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+var reObjectHeader = regexp.MustCompile(`^\s*(\d+)\s+(\d+)\s+obj`)
+var reStreamKeyword = regexp.MustCompile(`^\s*stream(\r\n|\r|\n)?`)
+
+// Validate checks the syntax of the document against the rules of the PDF specification (ISO
+// 32000-1, section 7): the consistency of the cross-reference entries with the objects, the Length
+// entries of the streams, the references and the trailer. The issues found are returned in the
+// report; an error is returned only if the document cannot be read.
+func (parser *PdfParser) Validate() (*ValidationReport, error) {
+	report := &ValidationReport{}
+
+	parser.mu.Lock()
+	entries := make(map[int]XrefObject, len(parser.xrefs.ObjectMap))
+	for objNum, xref := range parser.xrefs.ObjectMap {
+		entries[objNum] = xref
+	}
+	parser.mu.Unlock()
+	var objNums []int
+	for objNum := range entries {
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+
+	parser.validateTrailer(report, objNums)
+
+	// Check the objects at the offsets of the entries before the lookups, as the cross-reference
+	// table can be repaired when looking up objects.
+	for _, objNum := range objNums {
+		if xref := entries[objNum]; xref.XType == XrefTypeTableEntry {
+			if err := parser.validateXrefEntry(report, xref); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, objNum := range objNums {
+		xref := entries[objNum]
+		if xref.XType == XrefTypeObjectStream {
+			parser.validateCompressedEntry(report, xref, entries)
+		}
+		obj, err := parser.LookupByNumber(objNum)
+		if err != nil {
+			report.Add(ValidationError, ValidationCodeParse, int64(objNum), "unable to read object: %v", err)
+			continue
+		}
+		validateReferences(report, int64(objNum), obj, entries)
+	}
+	return report, nil
+}
+
+// validateTrailer checks the entries of the trailer, for a document with objects `objNums`.
+func (parser *PdfParser) validateTrailer(report *ValidationReport, objNums []int) {
+	trailer := parser.GetTrailer()
+	if trailer == nil {
+		report.Add(ValidationError, ValidationCodeTrailer, 0, "missing trailer")
+		return
+	}
+	size, ok := GetIntVal(trailer.Get("Size"))
+	if !ok {
+		report.Add(ValidationError, ValidationCodeTrailer, 0, "missing or invalid Size")
+	} else if len(objNums) > 0 && objNums[len(objNums)-1] >= size {
+		report.Add(ValidationError, ValidationCodeTrailer, 0, "Size %d not greater than object number %d",
+			size, objNums[len(objNums)-1])
+	}
+	if _, ok := trailer.Get("Root").(*PdfObjectReference); !ok {
+		report.Add(ValidationError, ValidationCodeTrailer, 0, "missing or invalid Root")
+	}
+	if info := trailer.Get("Info"); info != nil {
+		if _, ok := info.(*PdfObjectReference); !ok {
+			report.Add(ValidationError, ValidationCodeTrailer, 0, "Info is not an indirect reference")
+		}
+	}
+}
+
+// validateXrefEntry checks that the object of the cross-reference table entry `xref` is at its
+// offset and the Length of the object if it is a stream.
+func (parser *PdfParser) validateXrefEntry(report *ValidationReport, xref XrefObject) error {
+	objNum := int64(xref.ObjectNumber)
+	if xref.Offset <= 0 || xref.Offset >= parser.fileSize {
+		report.Add(ValidationError, ValidationCodeXrefOffset, objNum, "offset %d outside of file", xref.Offset)
+		return nil
+	}
+
+	// Read enough data for the dictionary of the object, if any.
+	var bb []byte
+	var dict *PdfObjectDictionary
+	var dictEnd int
+	for n := int64(4096); ; n *= 4 {
+		if xref.Offset+n > parser.fileSize {
+			n = parser.fileSize - xref.Offset
+		}
+		var err error
+		if bb, err = parser.ReadBytesAt(xref.Offset, n); err != nil {
+			return err
+		}
+		var complete bool
+		dict, dictEnd, complete = parseObjectDict(bb)
+		if complete || xref.Offset+n >= parser.fileSize || n >= 1<<22 {
+			break
+		}
+	}
+
+	m := reObjectHeader.FindSubmatch(bb)
+	if m == nil {
+		report.Add(ValidationError, ValidationCodeXrefOffset, objNum, "no object at offset %d", xref.Offset)
+		return nil
+	}
+	var num, gen int
+	fmt.Sscan(string(m[1]), &num)
+	fmt.Sscan(string(m[2]), &gen)
+	if num != xref.ObjectNumber {
+		report.Add(ValidationError, ValidationCodeObjectNumber, objNum,
+			"object %d %d at offset %d", num, gen, xref.Offset)
+		return nil
+	}
+	if gen != xref.Generation {
+		report.Add(ValidationError, ValidationCodeGeneration, objNum,
+			"generation %d, xref entry generation %d", gen, xref.Generation)
+	}
+
+	if dict == nil {
+		return nil
+	}
+	m = reStreamKeyword.FindSubmatch(bb[dictEnd:])
+	if m == nil {
+		return nil
+	}
+	if eol := string(m[1]); eol != "\r\n" && eol != "\n" {
+		report.Add(ValidationWarning, ValidationCodeStreamLength, objNum,
+			"stream keyword not followed by CRLF or LF")
+	}
+	return parser.validateStreamLength(report, objNum, dict, xref.Offset+int64(dictEnd+len(m[0])))
+}
+
+// parseObjectDict parses the dictionary of the object starting `bb`. Returns the dictionary and
+// the offset following it in `bb`, or nil if the object is not a dictionary or stream. The last
+// return value is false if the dictionary cannot be parsed, e.g. when `bb` ends within it.
+func parseObjectDict(bb []byte) (*PdfObjectDictionary, int, bool) {
+	loc := reObjectHeader.FindIndex(bb)
+	if loc == nil {
+		return nil, 0, true
+	}
+	p := NewParserFromString(string(bb[loc[1]:]))
+	p.skipSpaces()
+	p.skipComments()
+	if peek, _ := p.reader.Peek(2); string(peek) != "<<" {
+		return nil, 0, true
+	}
+	dict, err := p.ParseDict()
+	if err != nil {
+		return nil, 0, false
+	}
+	return dict, loc[1] + int(p.GetFileOffset()), true
+}
+
+// validateStreamLength checks the Length entry of the stream of object `objNum` with dictionary
+// `dict`, whose data start at `offset`.
+func (parser *PdfParser) validateStreamLength(report *ValidationReport, objNum int64,
+	dict *PdfObjectDictionary, offset int64) error {
+	lengthObj := dict.Get("Length")
+	if ref, ok := lengthObj.(*PdfObjectReference); ok {
+		obj, err := parser.LookupByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			report.Add(ValidationError, ValidationCodeStreamLength, objNum, "unable to read Length: %v", err)
+			return nil
+		}
+		lengthObj = TraceToDirectObject(obj)
+	}
+	length, ok := GetIntVal(lengthObj)
+	if !ok || length < 0 {
+		report.Add(ValidationError, ValidationCodeMissingKey, objNum, "missing or invalid stream Length")
+		return nil
+	}
+
+	// The data are followed by an optional end-of-line marker and the endstream keyword.
+	const keyword = "endstream"
+	if end := offset + int64(length); end < parser.fileSize {
+		n := minInt64(int64(len(keyword)+2), parser.fileSize-end)
+		bb, err := parser.ReadBytesAt(end, n)
+		if err != nil {
+			return err
+		}
+		bb = bytes.TrimLeft(bb, "\r\n")
+		if bytes.HasPrefix(bb, []byte(keyword)) {
+			return nil
+		}
+	}
+
+	actual, err := parser.findKeyword(offset, []byte(keyword))
+	if err != nil {
+		return err
+	}
+	if actual < 0 {
+		report.Add(ValidationError, ValidationCodeStreamLength, objNum, "stream without endstream")
+		return nil
+	}
+	actualLength := actual - offset
+	if prev, err := parser.ReadBytesAt(actual-minInt64(2, actualLength), minInt64(2, actualLength)); err == nil {
+		switch {
+		case bytes.HasSuffix(prev, []byte("\r\n")):
+			actualLength -= 2
+		case bytes.HasSuffix(prev, []byte("\n")) || bytes.HasSuffix(prev, []byte("\r")):
+			actualLength--
+		}
+	}
+	report.Add(ValidationError, ValidationCodeStreamLength, objNum, "Length %d, stream data length %d",
+		length, actualLength)
+	return nil
+}
+
+// findKeyword returns the offset of the first occurrence of `keyword` after `offset`, or -1 if not
+// found.
+func (parser *PdfParser) findKeyword(offset int64, keyword []byte) (int64, error) {
+	const bufLen = 1 << 16
+	for offset < parser.fileSize {
+		n := minInt64(bufLen, parser.fileSize-offset)
+		bb, err := parser.ReadBytesAt(offset, n)
+		if err != nil {
+			return 0, err
+		}
+		if i := bytes.Index(bb, keyword); i >= 0 {
+			return offset + int64(i), nil
+		}
+		if offset+n >= parser.fileSize {
+			break
+		}
+		offset += n - int64(len(keyword)-1)
+	}
+	return -1, nil
+}
+
+// validateCompressedEntry checks the object stream of the compressed object of entry `xref`.
+func (parser *PdfParser) validateCompressedEntry(report *ValidationReport, xref XrefObject,
+	entries map[int]XrefObject) {
+	objNum := int64(xref.ObjectNumber)
+	osEntry, ok := entries[xref.OsObjNumber]
+	if !ok || osEntry.XType != XrefTypeTableEntry {
+		report.Add(ValidationError, ValidationCodeObjectStream, objNum,
+			"object stream %d not found", xref.OsObjNumber)
+		return
+	}
+	obj, err := parser.LookupByNumber(xref.OsObjNumber)
+	if err != nil {
+		report.Add(ValidationError, ValidationCodeObjectStream, objNum,
+			"unable to read object stream %d: %v", xref.OsObjNumber, err)
+		return
+	}
+	stream, ok := obj.(*PdfObjectStream)
+	if !ok {
+		report.Add(ValidationError, ValidationCodeObjectStream, objNum,
+			"object %d is not an object stream", xref.OsObjNumber)
+		return
+	}
+	if name, ok := GetName(stream.Get("Type")); !ok || *name != "ObjStm" {
+		report.Add(ValidationError, ValidationCodeObjectStream, objNum,
+			"object %d is not an object stream", xref.OsObjNumber)
+		return
+	}
+	if n, ok := GetIntVal(stream.Get("N")); ok && xref.OsObjIndex >= n {
+		report.Add(ValidationError, ValidationCodeObjectStream, objNum,
+			"index %d outside of object stream %d with %d objects", xref.OsObjIndex, xref.OsObjNumber, n)
+	}
+}
+
+// validateReferences checks the references of object `objNum` `obj` to the objects of the
+// cross-reference entries `entries`.
+func validateReferences(report *ValidationReport, objNum int64, obj PdfObject, entries map[int]XrefObject) {
+	switch t := obj.(type) {
+	case *PdfObjectReference:
+		xref, ok := entries[int(t.ObjectNumber)]
+		switch {
+		case !ok:
+			report.Add(ValidationWarning, ValidationCodeBrokenReference, objNum,
+				"reference to undefined object %d %d", t.ObjectNumber, t.GenerationNumber)
+		case xref.XType == XrefTypeTableEntry && int64(xref.Generation) != t.GenerationNumber:
+			report.Add(ValidationWarning, ValidationCodeBrokenReference, objNum,
+				"reference to object %d %d with generation %d", t.ObjectNumber, t.GenerationNumber,
+				xref.Generation)
+		}
+	case *PdfIndirectObject:
+		validateReferences(report, objNum, t.PdfObject, entries)
+	case *PdfObjectStream:
+		validateReferences(report, objNum, t.PdfObjectDictionary, entries)
+	case *PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			validateReferences(report, objNum, t.Get(key), entries)
+		}
+	case *PdfObjectArray:
+		for _, elem := range t.Elements() {
+			validateReferences(report, objNum, elem, entries)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/internal/pdftest"
+)
+
+// hasIssue returns true if `report` has an issue with `severity` and `code` for object `objNum`.
+func hasIssue(report *ValidationReport, severity ValidationSeverity, code string, objNum int64) bool {
+	for _, issue := range report.Issues {
+		if issue.Severity == severity && issue.Code == code && issue.ObjectNumber == objNum {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateValid(t *testing.T) {
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Length 4 0 R >>\nstream\n"+strings.Repeat("x", 20)+"\nendstream",
+		"20",
+	)
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	report, err := parser.Validate()
+	require.NoError(t, err)
+	require.Empty(t, report.Issues)
+	require.True(t, report.IsValid())
+}
+
+func TestValidateInvalid(t *testing.T) {
+	data := pdftest.File{
+		Objects: []string{
+			"<< /Type /Catalog /Pages 9 0 R >>",
+			"<< /Length 10 >>\nstream\n" + strings.Repeat("x", 20) + "\nendstream",
+			"(three)",
+			"(four)",
+		},
+		Size: 3,
+		FixOffsets: func(offsets []int) {
+			offsets[2] = offsets[3]
+		},
+	}.Bytes()
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	report, err := parser.Validate()
+	require.NoError(t, err)
+	require.False(t, report.IsValid())
+	require.True(t, hasIssue(report, ValidationError, ValidationCodeTrailer, 0))
+	require.True(t, hasIssue(report, ValidationWarning, ValidationCodeBrokenReference, 1))
+	require.True(t, hasIssue(report, ValidationError, ValidationCodeStreamLength, 2))
+	require.True(t, hasIssue(report, ValidationError, ValidationCodeObjectNumber, 3))
+	require.Len(t, report.Errors(), 3)
+
+	for _, issue := range report.Issues {
+		if issue.Code == ValidationCodeStreamLength {
+			require.Equal(t, "Length 10, stream data length 20", issue.Message)
+		}
+	}
+
+	out, err := json.Marshal(report.Issues[0])
+	require.NoError(t, err)
+	require.Equal(t, `{"severity":"error","code":"trailer","object":0,`+
+		`"message":"Size 3 not greater than object number 4"}`, string(out))
+}
@@ -446,7 +446,12 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 							useName = resourcesNextUnusedFontName(name.String(), obj, resources)
 						}
 
-						resources.SetFontByName(useName, obj)
+						// Keep the fonts set with SetFont, so that they are subset on writing.
+						if font, ok := resourcesToAdd.GetFont(*name); ok {
+							resources.SetFont(useName, font)
+						} else {
+							resources.SetFontByName(useName, obj)
+						}
 						fontMap[*name] = useName
 					}
 
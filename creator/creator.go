@@ -68,9 +68,19 @@ type Creator struct {
 
 	optimizer model.Optimizer
 
+	// Document metadata.
+	info *model.PdfDocumentInfo
+	xmp  *model.XMPMetadata
+
 	// Default fonts used by all components instantiated through the creator.
 	defaultFontRegular *model.PdfFont
 	defaultFontBold    *model.PdfFont
+
+	// Streaming output, set with EnableStreaming.
+	streamWriter  *model.PdfStreamWriter
+	streamErr     error // The first error of flushing pages when starting a new page.
+	streamedPages int   // The number of pages written.
+	flushing      bool
 }
 
 // SetForms adds an Acroform to a PDF file.  Sets the specified form for writing.
@@ -296,6 +306,9 @@ func (c *Creator) initContext() {
 
 // NewPage adds a new Page to the Creator and sets as the active Page.
 func (c *Creator) NewPage() *model.PdfPage {
+	if err := c.flushPages(true); err != nil && c.streamErr == nil {
+		c.streamErr = err
+	}
 	page := c.newPage()
 	c.pages = append(c.pages, page)
 	c.context.Page++
@@ -304,6 +317,9 @@ func (c *Creator) NewPage() *model.PdfPage {
 
 // AddPage adds the specified page to the creator.
 func (c *Creator) AddPage(page *model.PdfPage) error {
+	if err := c.flushPages(true); err != nil {
+		return err
+	}
 	mbox, err := page.GetMediaBox()
 	if err != nil {
 		common.Log.Debug("Failed to get page mediabox: %v", err)
@@ -465,27 +481,7 @@ func (c *Creator) Finalize() error {
 
 	// Account for the front page and the table of content pages.
 	if c.outline != nil && c.AddOutlines {
-		var adjustOutlineDest func(item *model.OutlineItem)
-		adjustOutlineDest = func(item *model.OutlineItem) {
-			item.Dest.Page += int64(genpages)
-
-			// Reverse the Y axis of the destination coordinates.
-			// The user passes in the annotation coordinates as if
-			// position 0, 0 is at the top left of the page.
-			// However, position 0, 0 in the PDF is at the bottom
-			// left of the page.
-			item.Dest.Y = c.pageHeight - item.Dest.Y
-
-			outlineItems := item.Items()
-			for _, outlineItem := range outlineItems {
-				adjustOutlineDest(outlineItem)
-			}
-		}
-
-		outlineItems := c.outline.Items()
-		for _, outlineItem := range outlineItems {
-			adjustOutlineDest(outlineItem)
-		}
+		c.adjustOutlineDests(int64(genpages))
 
 		// Add outline TOC item.
 		if c.AddTOC {
@@ -502,58 +498,93 @@ func (c *Creator) Finalize() error {
 	}
 
 	for idx, page := range c.pages {
-		c.setActivePage(page)
-
-		// Draw page header.
-		if c.drawHeaderFunc != nil {
-			// Prepare a block to draw on.
-			// Header is drawn on the top of the page. Has width of the page, but height limited to
-			// the page margin top height.
-			headerBlock := NewBlock(c.pageWidth, c.pageMargins.top)
-			args := HeaderFunctionArgs{
-				PageNum:    idx + 1,
-				TotalPages: totPages,
-			}
-			c.drawHeaderFunc(headerBlock, args)
-			headerBlock.SetPos(0, 0)
+		if err := c.finalizePage(page, idx+1, totPages); err != nil {
+			return err
+		}
+	}
 
-			if err := c.Draw(headerBlock); err != nil {
-				common.Log.Debug("ERROR: drawing header: %v", err)
-				return err
-			}
+	c.finalized = true
+	return nil
+}
+
+// adjustOutlineDests shifts the pages of the outline destinations by `pageOffset` for the
+// generated pages, and converts their Y coordinates from top to bottom origin.
+func (c *Creator) adjustOutlineDests(pageOffset int64) {
+	var adjustOutlineDest func(item *model.OutlineItem)
+	adjustOutlineDest = func(item *model.OutlineItem) {
+		item.Dest.Page += pageOffset
+
+		// Reverse the Y axis of the destination coordinates.
+		// The user passes in the annotation coordinates as if
+		// position 0, 0 is at the top left of the page.
+		// However, position 0, 0 in the PDF is at the bottom
+		// left of the page.
+		item.Dest.Y = c.pageHeight - item.Dest.Y
+
+		outlineItems := item.Items()
+		for _, outlineItem := range outlineItems {
+			adjustOutlineDest(outlineItem)
 		}
+	}
 
-		// Draw page footer.
-		if c.drawFooterFunc != nil {
-			// Prepare a block to draw on.
-			// Footer is drawn on the bottom of the page. Has width of the page, but height limited
-			// to the page margin bottom height.
-			footerBlock := NewBlock(c.pageWidth, c.pageMargins.bottom)
-			args := FooterFunctionArgs{
-				PageNum:    idx + 1,
-				TotalPages: totPages,
-			}
-			c.drawFooterFunc(footerBlock, args)
-			footerBlock.SetPos(0, c.pageHeight-footerBlock.height)
+	outlineItems := c.outline.Items()
+	for _, outlineItem := range outlineItems {
+		adjustOutlineDest(outlineItem)
+	}
+}
 
-			if err := c.Draw(footerBlock); err != nil {
-				common.Log.Debug("ERROR: drawing footer: %v", err)
-				return err
-			}
+// finalizePage draws the header, the footer and the blocks of `page`, which is page number
+// `pageNum` of `totPages` pages.
+func (c *Creator) finalizePage(page *model.PdfPage, pageNum, totPages int) error {
+	c.setActivePage(page)
+
+	// Draw page header.
+	if c.drawHeaderFunc != nil {
+		// Prepare a block to draw on.
+		// Header is drawn on the top of the page. Has width of the page, but height limited to
+		// the page margin top height.
+		headerBlock := NewBlock(c.pageWidth, c.pageMargins.top)
+		args := HeaderFunctionArgs{
+			PageNum:    pageNum,
+			TotalPages: totPages,
 		}
+		c.drawHeaderFunc(headerBlock, args)
+		headerBlock.SetPos(0, 0)
 
-		// Draw page blocks.
-		block, ok := c.pageBlocks[page]
-		if !ok {
-			continue
+		if err := c.Draw(headerBlock); err != nil {
+			common.Log.Debug("ERROR: drawing header: %v", err)
+			return err
+		}
+	}
+
+	// Draw page footer.
+	if c.drawFooterFunc != nil {
+		// Prepare a block to draw on.
+		// Footer is drawn on the bottom of the page. Has width of the page, but height limited
+		// to the page margin bottom height.
+		footerBlock := NewBlock(c.pageWidth, c.pageMargins.bottom)
+		args := FooterFunctionArgs{
+			PageNum:    pageNum,
+			TotalPages: totPages,
 		}
-		if err := block.drawToPage(page); err != nil {
-			common.Log.Debug("ERROR: drawing page %d blocks: %v", idx+1, err)
+		c.drawFooterFunc(footerBlock, args)
+		footerBlock.SetPos(0, c.pageHeight-footerBlock.height)
+
+		if err := c.Draw(footerBlock); err != nil {
+			common.Log.Debug("ERROR: drawing footer: %v", err)
 			return err
 		}
 	}
 
-	c.finalized = true
+	// Draw page blocks.
+	block, ok := c.pageBlocks[page]
+	if !ok {
+		return nil
+	}
+	if err := block.drawToPage(page); err != nil {
+		common.Log.Debug("ERROR: drawing page %d blocks: %v", pageNum, err)
+		return err
+	}
 	return nil
 }
 
@@ -628,12 +659,21 @@ func (c *Creator) Draw(d Drawable) error {
 
 // Write output of creator to io.Writer interface.
 func (c *Creator) Write(ws io.Writer) error {
+	if c.streamWriter != nil {
+		return errors.New("streaming enabled, the document is completed with Close")
+	}
 	if err := c.Finalize(); err != nil {
 		return err
 	}
 
 	pdfWriter := model.NewPdfWriter()
 	pdfWriter.SetOptimizer(c.optimizer)
+	if c.info != nil {
+		pdfWriter.SetDocumentInfo(c.info)
+	}
+	if c.xmp != nil {
+		pdfWriter.SetXMPMetadata(c.xmp)
+	}
 
 	// Form fields.
 	if c.acroForm != nil {
@@ -676,6 +716,103 @@ func (c *Creator) Write(ws io.Writer) error {
 	return nil
 }
 
+// EnableStreaming sets the creator to write the pages to `ws` as they are completed, instead of
+// keeping all of them in memory until Write, e.g. for generating documents with many thousands of
+// pages. A page is completed when the next page is started: its header and footer are drawn, with
+// TotalPages set to 0 as the number of pages is not known yet, and it is written with the
+// resources not written yet. Close writes the remaining page and completes the document.
+// Front pages, tables of contents and optimizers are not supported with streaming, and the PdfWriter
+// access function is not called.
+func (c *Creator) EnableStreaming(ws io.Writer) error {
+	if c.streamWriter != nil || c.finalized {
+		return errors.New("creator already streamed or finalized")
+	}
+	if c.genFrontPageFunc != nil || c.AddTOC {
+		return errors.New("front pages and tables of contents are not supported with streaming")
+	}
+	c.streamWriter = model.NewPdfStreamWriter(ws)
+	// The last page may still be drawn on.
+	return c.flushPages(false)
+}
+
+// flushPages writes the pages of the creator when streaming, except the last page if `all` is
+// false.
+func (c *Creator) flushPages(all bool) error {
+	if c.streamWriter == nil || c.flushing {
+		return nil
+	}
+	c.flushing = true
+	defer func() { c.flushing = false }()
+
+	pages := c.pages
+	if !all && len(pages) > 0 {
+		pages = pages[:len(pages)-1]
+	}
+	for _, page := range pages {
+		c.streamedPages++
+		if err := c.finalizePage(page, c.streamedPages, 0); err != nil {
+			return err
+		}
+		if err := c.streamWriter.AddPage(page); err != nil {
+			common.Log.Debug("ERROR: Failed to write page %d: %v", c.streamedPages, err)
+			return err
+		}
+		delete(c.pageBlocks, page)
+	}
+	c.pages = c.pages[len(pages):]
+	c.activePage = nil
+	return nil
+}
+
+// Close writes the remaining pages and completes the document written with streaming. See
+// EnableStreaming.
+func (c *Creator) Close() error {
+	if c.streamWriter == nil {
+		return errors.New("streaming not enabled")
+	}
+	if c.finalized {
+		return errors.New("creator already closed")
+	}
+	if c.streamErr != nil {
+		return c.streamErr
+	}
+	if err := c.flushPages(true); err != nil {
+		return err
+	}
+	c.finalized = true
+
+	s := c.streamWriter
+	if c.info != nil {
+		s.SetDocumentInfo(c.info)
+	}
+	if c.xmp != nil {
+		s.SetXMPMetadata(c.xmp)
+	}
+	if c.acroForm != nil {
+		if err := s.SetForms(c.acroForm); err != nil {
+			return err
+		}
+	}
+	if c.externalOutline != nil {
+		s.AddOutlineTree(c.externalOutline)
+	} else if c.outline != nil && c.AddOutlines {
+		c.adjustOutlineDests(0)
+		s.AddOutlineTree(&c.outline.ToPdfOutline().PdfOutlineTreeNode)
+	}
+	return s.Close()
+}
+
+// SetDocumentInfo sets the document information dictionary of the output PDF to `info`.
+func (c *Creator) SetDocumentInfo(info *model.PdfDocumentInfo) {
+	c.info = info
+}
+
+// SetXMPMetadata sets the XMP metadata of the output PDF. The properties corresponding to the
+// entries of the document information dictionary are set to the document information on writing.
+func (c *Creator) SetXMPMetadata(metadata *model.XMPMetadata) {
+	c.xmp = metadata
+}
+
 // SetPdfWriterAccessFunc sets a PdfWriter access function/hook.
 // Exposes the PdfWriter just prior to writing the PDF.  Can be used to encrypt the output PDF, etc.
 //
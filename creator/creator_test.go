@@ -706,6 +706,41 @@ func TestParagraphChinese(t *testing.T) {
 	t.Logf("output size: %d (%d MB)", st.Size(), st.Size()/1024/1024)
 }
 
+// Test that the composite fonts used by the creator are embedded as subsets.
+func TestCreatorFontSubsetting(t *testing.T) {
+	c := New()
+
+	font, err := model.NewCompositePdfFontFromTTFFile(testFreeSansTTFFile)
+	require.NoError(t, err)
+	ttfInfo, err := os.Stat(testFreeSansTTFFile)
+	require.NoError(t, err)
+
+	p := c.NewStyledParagraph()
+	p.Append("Subset ").Style.Font = font
+	p.Append("fonts").Style.Font = font
+	require.NoError(t, c.Draw(p))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	require.True(t, buf.Len() < int(ttfInfo.Size())/10, "%d bytes", buf.Len())
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	fontDict, ok := core.GetDict(page.Resources.Font)
+	require.True(t, ok)
+	subset := false
+	for _, name := range fontDict.Keys() {
+		pageFont, err := model.NewPdfFontFromPdfObject(fontDict.Get(name))
+		require.NoError(t, err)
+		if strings.HasSuffix(pageFont.BaseFont(), "+FreeSans") {
+			subset = pageFont.IsSubset()
+		}
+	}
+	require.True(t, subset)
+}
+
 // Test paragraph with composite font and various unicode characters.
 func TestParagraphUnicode(t *testing.T) {
 	creator := New()
@@ -3293,3 +3328,70 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+func TestCreatorDocumentInfo(t *testing.T) {
+	c := New()
+	c.NewPage()
+	info := &model.PdfDocumentInfo{Title: "Creator metadata", Author: "Jane Doe"}
+	require.NoError(t, info.SetCustom("Department", "Sales"))
+	c.SetDocumentInfo(info)
+	c.SetXMPMetadata(model.NewXMPMetadata())
+
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	read, err := reader.GetDocumentInfo()
+	require.NoError(t, err)
+	require.Equal(t, info, read)
+	xmp, err := reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.NotNil(t, xmp)
+	require.Equal(t, info, xmp.DocumentInfo())
+}
+
+func TestCreatorStreaming(t *testing.T) {
+	const numPages = 25
+	c := New()
+	c.DrawFooter(func(footer *Block, args FooterFunctionArgs) {
+		p := c.NewParagraph(fmt.Sprintf("Statement page %d", args.PageNum))
+		p.SetPos(50, 10)
+		footer.Draw(p)
+	})
+	c.SetDocumentInfo(&model.PdfDocumentInfo{Title: "Statements"})
+
+	var buf bytes.Buffer
+	require.NoError(t, c.EnableStreaming(&buf))
+	for i := 0; i < numPages; i++ {
+		c.NewPage()
+		require.NoError(t, c.Draw(c.NewParagraph(fmt.Sprintf("Account %d", i+1))))
+		// Only the page being drawn is kept.
+		require.Len(t, c.pages, 1)
+	}
+	require.Error(t, c.Write(&bytes.Buffer{}))
+	require.NoError(t, c.Close())
+	require.Error(t, c.Close())
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	n, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, numPages, n)
+	info, err := reader.GetDocumentInfo()
+	require.NoError(t, err)
+	require.Equal(t, "Statements", info.Title)
+
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		require.Contains(t, content, fmt.Sprintf("[(Account) -278 (%d)] TJ", i))
+		require.Contains(t, content, fmt.Sprintf("[(Statement) -278 (page) -278 (%d)] TJ", i))
+	}
+
+	// Tables of contents are not supported with streaming.
+	c = New()
+	c.AddTOC = true
+	require.Error(t, c.EnableStreaming(&buf))
+}
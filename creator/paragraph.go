@@ -340,7 +340,7 @@ func drawParagraphOnBlock(blk *Block, p *Paragraph, ctx DrawContext) (DrawContex
 	}
 
 	// Add to the Page resources.
-	err := blk.resources.SetFontByName(fontName, p.textFont.ToPdfObject())
+	err := blk.resources.SetFont(fontName, p.textFont)
 	if err != nil {
 		return ctx, err
 	}
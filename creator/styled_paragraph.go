@@ -184,6 +184,7 @@ func (p *StyledParagraph) GetMargins() (float64, float64, float64, float64) {
 
 // SetWidth sets the the Paragraph width. This is essentially the wrapping width,
 // i.e. the width the text can extend to prior to wrapping over to next line.
+// The wrapping width of vertical text is the height of its columns.
 func (p *StyledParagraph) SetWidth(width float64) {
 	p.wrapWidth = width
 	p.wrapText()
@@ -191,6 +192,9 @@ func (p *StyledParagraph) SetWidth(width float64) {
 
 // Width returns the width of the Paragraph.
 func (p *StyledParagraph) Width() float64 {
+	if p.isVertical() {
+		return p.verticalWidth()
+	}
 	if p.enableWrap && int(p.wrapWidth) > 0 {
 		return p.wrapWidth
 	}
@@ -201,6 +205,9 @@ func (p *StyledParagraph) Width() float64 {
 // Height returns the height of the Paragraph. The height is calculated based on the input text and how it is wrapped
 // within the container. Does not include Margins.
 func (p *StyledParagraph) Height() float64 {
+	if p.isVertical() {
+		return p.verticalHeight()
+	}
 	p.wrapText()
 
 	var height float64
@@ -266,27 +273,26 @@ func (p *StyledParagraph) getLineHeight(idx int) (capHeight, height float64) {
 // wrapping into account).
 func (p *StyledParagraph) getTextWidth() float64 {
 	var width float64
-	lenChunks := len(p.chunks)
+	chunks := splitFallbackChunks(p.chunks)
+	lenChunks := len(chunks)
 
-	for i, chunk := range p.chunks {
+	for i, chunk := range chunks {
 		style := &chunk.Style
-		lenRunes := len(chunk.Text)
+		runes := []rune(chunk.Text)
+		lenRunes := len(runes)
+		widths, err := runeWidths(style, runes)
+		if err != nil {
+			// FIXME: return error.
+			return -1
+		}
 
-		for j, r := range chunk.Text {
+		for j, r := range runes {
 			// Ignore newline for this. Handles as if all in one line.
 			if r == '\u000A' { // LF
 				continue
 			}
 
-			metrics, found := style.Font.GetRuneMetrics(r)
-			if !found {
-				common.Log.Debug("Rune char metrics not found! %v\n", r)
-
-				// FIXME: return error.
-				return -1
-			}
-
-			width += style.FontSize * metrics.Wx
+			width += style.FontSize * widths[j]
 
 			// Do not add character spacing for the last character of the line.
 			if r != ' ' && (i != lenChunks-1 || j != lenRunes-1) {
@@ -305,23 +311,21 @@ func (p *StyledParagraph) getTextLineWidth(line []*TextChunk) float64 {
 
 	for i, chunk := range line {
 		style := &chunk.Style
-		lenRunes := len(chunk.Text)
+		runes := []rune(chunk.Text)
+		lenRunes := len(runes)
+		widths, err := runeWidths(style, runes)
+		if err != nil {
+			// FIXME: return error.
+			return -1
+		}
 
-		for j, r := range chunk.Text {
+		for j, r := range runes {
 			// Ignore newline for this. Handles as if all in one line.
 			if r == '\u000A' { // LF
 				continue
 			}
 
-			metrics, found := style.Font.GetRuneMetrics(r)
-			if !found {
-				common.Log.Debug("Rune char metrics not found! %v\n", r)
-
-				// FIXME: return error.
-				return -1
-			}
-
-			width += style.FontSize * metrics.Wx
+			width += style.FontSize * widths[j]
 
 			// Do not add character spacing for the last character of the line.
 			if r != ' ' && (i != lenChunks-1 || j != lenRunes-1) {
@@ -353,7 +357,7 @@ func (p *StyledParagraph) getMaxLineWidth() float64 {
 // getTextHeight calculates the text height as if all in one line (not taking wrapping into account).
 func (p *StyledParagraph) getTextHeight() float64 {
 	var height float64
-	for _, chunk := range p.chunks {
+	for _, chunk := range splitFallbackChunks(p.chunks) {
 		h := chunk.Style.FontSize * p.lineHeight
 		if h > height {
 			height = h
@@ -367,8 +371,9 @@ func (p *StyledParagraph) getTextHeight() float64 {
 // fill the lines.
 // TODO: Consider the Knuth/Plass algorithm or an alternative.
 func (p *StyledParagraph) wrapText() error {
+	chunks := splitFallbackChunks(p.chunks)
 	if !p.enableWrap || int(p.wrapWidth) <= 0 {
-		p.lines = [][]*TextChunk{p.chunks}
+		p.lines = [][]*TextChunk{chunks}
 		return nil
 	}
 
@@ -392,7 +397,7 @@ func (p *StyledParagraph) wrapText() error {
 		return annotation
 	}
 
-	for _, chunk := range p.chunks {
+	for _, chunk := range chunks {
 		style := chunk.Style
 		annotation := chunk.annotation
 
@@ -401,7 +406,13 @@ func (p *StyledParagraph) wrapText() error {
 			widths []float64
 		)
 
-		for _, r := range chunk.Text {
+		runes := []rune(chunk.Text)
+		glyphWidths, err := runeWidths(&style, runes)
+		if err != nil {
+			return err
+		}
+
+		for i, r := range runes {
 			// newline wrapping.
 			if r == '\u000A' { // LF
 				// moves to next line.
@@ -419,13 +430,7 @@ func (p *StyledParagraph) wrapText() error {
 				continue
 			}
 			isSpace := r == ' '
-
-			metrics, found := style.Font.GetRuneMetrics(r)
-			if !found {
-				common.Log.Debug("Rune char metrics not found! %v\n", r)
-				return errors.New("glyph char metrics missing")
-			}
-			w := style.FontSize * metrics.Wx
+			w := style.FontSize * glyphWidths[i]
 
 			charWidth := w
 			if !isSpace {
@@ -516,8 +521,12 @@ func (p *StyledParagraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawCon
 		ctx.Width -= p.margins.left + p.margins.right
 		ctx.Height -= p.margins.top + p.margins.bottom
 
-		// Use available space.
-		p.SetWidth(ctx.Width)
+		// Use available space. The columns of vertical text extend to the available height.
+		if p.isVertical() {
+			p.SetWidth(ctx.Height)
+		} else {
+			p.SetWidth(ctx.Width)
+		}
 
 		if p.Height() > ctx.Height {
 			// Goes out of the bounds.  Write on a new template instead and create a new context at upper
@@ -570,6 +579,10 @@ func (p *StyledParagraph) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawCon
 
 // Draw block on specified location on Page, adding to the content stream.
 func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext) (DrawContext, error) {
+	if p.isVertical() {
+		return drawVerticalStyledParagraphOnBlock(blk, p, ctx)
+	}
+
 	// Find first free index for the font resources of the paragraph.
 	num := 1
 	fontName := core.PdfObjectName(fmt.Sprintf("Font%d", num))
@@ -579,7 +592,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 	}
 
 	// Add default font to the page resources.
-	err := blk.resources.SetFontByName(fontName, p.defaultStyle.Font.ToPdfObject())
+	err := blk.resources.SetFont(fontName, p.defaultStyle.Font)
 	if err != nil {
 		return ctx, err
 	}
@@ -591,11 +604,14 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 	// Wrap the text into lines.
 	p.wrapText()
 
+	// Lay out the text chunks of the lines in visual order.
+	lines := p.visualLines()
+
 	// Add the fonts of all chunks to the page resources.
 	var fonts [][]core.PdfObjectName
 
 	var yOffset float64
-	for i, line := range p.lines {
+	for i, line := range lines {
 		var fontLine []core.PdfObjectName
 
 		for _, chunk := range line {
@@ -606,7 +622,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 
 			fontName = core.PdfObjectName(fmt.Sprintf("Font%d", num))
 
-			err := blk.resources.SetFontByName(fontName, style.Font.ToPdfObject())
+			err := blk.resources.SetFont(fontName, style.Font)
 			if err != nil {
 				return ctx, err
 			}
@@ -632,7 +648,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 	cc.Add_BT()
 
 	currY := yPos
-	for idx, line := range p.lines {
+	for idx, line := range lines {
 		currX := ctx.X
 
 		if idx != 0 {
@@ -640,7 +656,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 			cc.Add_Tstar()
 		}
 
-		isLastLine := idx == len(p.lines)-1
+		isLastLine := idx == len(lines)-1
 
 		// Get width of the line (excluding spaces).
 		var (
@@ -663,10 +679,16 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 				return ctx, errors.New("the font does not have a space glyph")
 			}
 
+			runes := []rune(chunk.Text)
+			widths, err := runeWidths(style, runes)
+			if err != nil {
+				return ctx, errors.New("unsupported text glyph")
+			}
+
 			var chunkSpaces uint
 			var chunkWidth float64
-			lenChunk := len(chunk.Text)
-			for i, r := range chunk.Text {
+			lenChunk := len(runes)
+			for i, r := range runes {
 				if r == ' ' {
 					chunkSpaces++
 					continue
@@ -675,13 +697,7 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 					continue
 				}
 
-				metrics, found := style.Font.GetRuneMetrics(r)
-				if !found {
-					common.Log.Debug("Unsupported rune %v in font\n", r)
-					return ctx, errors.New("unsupported text glyph")
-				}
-
-				chunkWidth += style.FontSize * metrics.Wx
+				chunkWidth += style.FontSize * widths[i]
 
 				// Do not add character spacing for the last character of the line.
 				if i != lenChunk-1 {
@@ -752,77 +768,97 @@ func drawStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext)
 				fontSize = style.FontSize
 				spaceWidth = spaceMetrics.Wx
 			}
-			enc := style.Font.Encoder()
-
-			var encStr []byte
-			for _, rn := range chunk.Text {
-				if r == '\u000A' { // LF
-					continue
-				}
-				if rn == ' ' {
-					if len(encStr) > 0 {
+			runes := []rune(chunk.Text)
+			if glyphs, ok := shapeText(style, runes, chunk.rtl); ok {
+				// Draw the glyphs of the shaped text, marked with the text they represent.
+				addActualText(cc, chunk.Text)
+
+				var shaped []model.ShapedGlyph
+				flushShaped := func() {
+					groups := shapedTextGroups(style.Font, shaped, style.FontSize, style.CharSpacing)
+					for _, group := range groups {
 						cc.Add_rg(r, g, b).
 							Add_Tf(fonts[idx][k], style.FontSize).
-							Add_TL(style.FontSize * p.lineHeight).
-							Add_TJ([]core.PdfObject{core.MakeStringFromBytes(encStr)}...)
-
-						encStr = nil
+							Add_TL(style.FontSize * p.lineHeight)
+						if group.rise != 0 {
+							cc.Add_Ts(group.rise)
+						}
+						cc.Add_TJ(group.items...)
+						if group.rise != 0 {
+							cc.Add_Ts(0)
+						}
 					}
+					shaped = nil
+				}
 
-					cc.Add_Tf(fontName, fontSize).
-						Add_TL(fontSize * p.lineHeight).
-						Add_TJ([]core.PdfObject{core.MakeFloat(-spaceWidth)}...)
+				for _, glyph := range glyphs {
+					if glyph.Cluster < len(runes) && runes[glyph.Cluster] == ' ' {
+						flushShaped()
+						cc.Add_Tf(fontName, fontSize).
+							Add_TL(fontSize * p.lineHeight).
+							Add_TJ([]core.PdfObject{core.MakeFloat(-spaceWidth)}...)
 
-					chunkWidths[k] += spaceWidth * fontSize
-				} else {
-					if _, ok := enc.RuneToCharcode(rn); !ok {
-						common.Log.Debug("unsupported rune in text encoding: %#x (%c)", rn, rn)
+						chunkWidths[k] += spaceWidth * fontSize
 						continue
 					}
-					encStr = append(encStr, enc.Encode(string(rn))...)
+					shaped = append(shaped, glyph)
 				}
-			}
+				flushShaped()
+				cc.Add_EMC()
+			} else {
+				if chunk.rtl {
+					// Draw right-to-left text in visual order, marked with its logical order.
+					addActualText(cc, chunk.Text)
+					runes = mirrorReverse(runes)
+				}
+				enc := style.Font.Encoder()
 
-			if len(encStr) > 0 {
-				cc.Add_rg(r, g, b).
-					Add_Tf(fonts[idx][k], style.FontSize).
-					Add_TL(style.FontSize * p.lineHeight).
-					Add_TJ([]core.PdfObject{core.MakeStringFromBytes(encStr)}...)
+				var encStr []byte
+				for _, rn := range runes {
+					if r == '\u000A' { // LF
+						continue
+					}
+					if rn == ' ' {
+						if len(encStr) > 0 {
+							cc.Add_rg(r, g, b).
+								Add_Tf(fonts[idx][k], style.FontSize).
+								Add_TL(style.FontSize * p.lineHeight).
+								Add_TJ([]core.PdfObject{core.MakeStringFromBytes(encStr)}...)
+
+							encStr = nil
+						}
+
+						cc.Add_Tf(fontName, fontSize).
+							Add_TL(fontSize * p.lineHeight).
+							Add_TJ([]core.PdfObject{core.MakeFloat(-spaceWidth)}...)
+
+						chunkWidths[k] += spaceWidth * fontSize
+					} else {
+						if _, ok := enc.RuneToCharcode(rn); !ok {
+							common.Log.Debug("unsupported rune in text encoding: %#x (%c)", rn, rn)
+							continue
+						}
+						encStr = append(encStr, enc.Encode(string(rn))...)
+					}
+				}
+
+				if len(encStr) > 0 {
+					cc.Add_rg(r, g, b).
+						Add_Tf(fonts[idx][k], style.FontSize).
+						Add_TL(style.FontSize * p.lineHeight).
+						Add_TJ([]core.PdfObject{core.MakeStringFromBytes(encStr)}...)
+				}
+				if chunk.rtl {
+					cc.Add_EMC()
+				}
 			}
 
 			chunkWidth := chunkWidths[k] / 1000.0
 
 			// Add annotations.
 			if chunk.annotation != nil {
-				var annotRect *core.PdfObjectArray
-
 				// Process annotation.
-				if !chunk.annotationProcessed {
-					switch t := chunk.annotation.GetContext().(type) {
-					case *model.PdfAnnotationLink:
-						// Initialize annotation rectangle.
-						annotRect = core.MakeArray()
-						t.Rect = annotRect
-
-						// Reverse the Y axis of the destination coordinates.
-						// The user passes in the annotation coordinates as if
-						// position 0, 0 is at the top left of the page.
-						// However, position 0, 0 in the PDF is at the bottom
-						// left of the page.
-						annotDest, ok := t.Dest.(*core.PdfObjectArray)
-						if ok && annotDest.Len() == 5 {
-							t, ok := annotDest.Get(1).(*core.PdfObjectName)
-							if ok && t.String() == "XYZ" {
-								y, err := core.GetNumberAsFloat(annotDest.Get(3))
-								if err == nil {
-									annotDest.Set(3, core.MakeFloat(ctx.PageHeight-y))
-								}
-							}
-						}
-					}
-
-					chunk.annotationProcessed = true
-				}
+				annotRect := chunk.processAnnotation(ctx)
 
 				// Set the coordinates of the annotation.
 				if annotRect != nil {
@@ -880,3 +880,20 @@ func TestStyledParagraphCharacterSpaceWrapping(t *testing.T) {
 	// Write output file.
 	testWriteAndRender(t, c, "styled_paragraph_character_space_wrapping.pdf")
 }
+
+func TestStyledParagraphCharacterSpacingNonASCII(t *testing.T) {
+	c := New()
+	p := c.NewStyledParagraph()
+	p.SetEnableWrap(false)
+	chunk := p.Append("aéü")
+	chunk.Style.FontSize = 10
+	chunk.Style.CharSpacing = 2
+
+	// The character spacing is not added after the last character.
+	widths, err := runeWidths(&chunk.Style, []rune(chunk.Text))
+	require.NoError(t, err)
+	expected := (widths[0]+widths[1]+widths[2])*10/1000 + 2*2
+	require.InDelta(t, expected, p.Width(), 1e-6)
+	require.NoError(t, p.wrapText())
+	require.InDelta(t, expected, p.getMaxLineWidth()/1000, 1e-6)
+}
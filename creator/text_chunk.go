@@ -1,11 +1,9 @@
 package creator
 
 import (
-	"errors"
 	"strings"
 	"unicode"
 
-	"github.com/moolekkari/unipdf/common"
 	"github.com/moolekkari/unipdf/core"
 	"github.com/moolekkari/unipdf/model"
 )
@@ -24,6 +22,10 @@ type TextChunk struct {
 	// Internally used in order to skip processing the annotation
 	// if it has already been processed by the parent component.
 	annotationProcessed bool
+
+	// Internally used for the runs of right-to-left text of the lines
+	// of paragraphs, which are drawn in visual order.
+	rtl bool
 }
 
 // NewTextChunk returns a new text chunk instance.
@@ -53,8 +55,12 @@ func (tc *TextChunk) Wrap(width float64) ([]string, error) {
 
 	style := tc.Style
 	runes := []rune(tc.Text)
+	glyphWidths, err := fallbackRuneWidths(tc)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, r := range runes {
+	for i, r := range runes {
 		// Move to the next line due to newline wrapping (LF).
 		if r == '\u000A' {
 			lines = append(lines, strings.TrimRightFunc(string(line), unicode.IsSpace)+string(r))
@@ -64,16 +70,7 @@ func (tc *TextChunk) Wrap(width float64) ([]string, error) {
 			continue
 		}
 		isSpace := r == ' '
-
-		metrics, found := style.Font.GetRuneMetrics(r)
-		if !found {
-			common.Log.Debug("ERROR: Rune char metrics not found! rune=0x%04x=%c font=%s %#q",
-				r, r, style.Font.BaseFont(), style.Font.Subtype())
-			common.Log.Trace("Font: %#v", style.Font)
-			common.Log.Trace("Encoder: %#v", style.Font.Encoder())
-			return nil, errors.New("glyph char metrics missing")
-		}
-		w := style.FontSize * metrics.Wx
+		w := style.FontSize * glyphWidths[i]
 
 		charWidth := w
 		if !isSpace {
@@ -84,9 +81,9 @@ func (tc *TextChunk) Wrap(width float64) ([]string, error) {
 			// Goes out of bounds. Break on the character.
 			idx := -1
 			if !isSpace {
-				for i := len(line) - 1; i >= 0; i-- {
-					if line[i] == ' ' {
-						idx = i
+				for j := len(line) - 1; j >= 0; j-- {
+					if line[j] == ' ' {
+						idx = j
 						break
 					}
 				}
@@ -195,6 +192,41 @@ func newInternalLinkAnnotation(page int64, x, y, zoom float64) *model.PdfAnnotat
 	return annotation.PdfAnnotation
 }
 
+// processAnnotation prepares the annotation of the chunk for drawing it in the context `ctx`,
+// if it has not been processed yet. It returns the rectangle of the annotation, which is to be
+// set to the coordinates of the drawn chunk, or nil if the annotation has no rectangle to set.
+func (tc *TextChunk) processAnnotation(ctx DrawContext) *core.PdfObjectArray {
+	if tc.annotationProcessed {
+		return nil
+	}
+	tc.annotationProcessed = true
+
+	var annotRect *core.PdfObjectArray
+	switch t := tc.annotation.GetContext().(type) {
+	case *model.PdfAnnotationLink:
+		// Initialize annotation rectangle.
+		annotRect = core.MakeArray()
+		t.Rect = annotRect
+
+		// Reverse the Y axis of the destination coordinates.
+		// The user passes in the annotation coordinates as if
+		// position 0, 0 is at the top left of the page.
+		// However, position 0, 0 in the PDF is at the bottom
+		// left of the page.
+		annotDest, ok := t.Dest.(*core.PdfObjectArray)
+		if ok && annotDest.Len() == 5 {
+			t, ok := annotDest.Get(1).(*core.PdfObjectName)
+			if ok && t.String() == "XYZ" {
+				y, err := core.GetNumberAsFloat(annotDest.Get(3))
+				if err == nil {
+					annotDest.Set(3, core.MakeFloat(ctx.PageHeight-y))
+				}
+			}
+		}
+	}
+	return annotRect
+}
+
 // copyLinkAnnotation returns a new link annotation based on an existing one.
 func copyLinkAnnotation(link *model.PdfAnnotationLink) *model.PdfAnnotationLink {
 	if link == nil {
@@ -0,0 +1,123 @@
+package creator
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/adrg/sysfont"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// fontForRune returns the font of `style` used to draw rune `r`: the font of the style if it has
+// a glyph for `r`, otherwise the first of the fallback fonts of the style which has one. `current`
+// is the font of the preceding rune, which is used for runes common to all scripts, e.g. spaces
+// and punctuation, so that runs of text are not split by them.
+// The font of the style is returned if no font has a glyph for `r`.
+func (style *TextStyle) fontForRune(r rune, current *model.PdfFont) *model.PdfFont {
+	if current != nil && unicode.In(r, unicode.Common, unicode.Inherited) && current.HasRune(r) {
+		return current
+	}
+	if style.Font == nil || style.Font.HasRune(r) {
+		return style.Font
+	}
+	for _, font := range style.FallbackFonts {
+		if font != nil && font.HasRune(r) {
+			return font
+		}
+	}
+	return style.Font
+}
+
+// splitFallbackChunks returns the text chunks `chunks` split in runs of text drawn with the same
+// font of the fallback fonts of their styles (see TextStyle.FallbackFonts). The chunks are
+// returned unchanged if their styles have no fallback fonts.
+func splitFallbackChunks(chunks []*TextChunk) []*TextChunk {
+	var split []*TextChunk
+	for _, chunk := range chunks {
+		if len(chunk.Style.FallbackFonts) == 0 {
+			split = append(split, chunk)
+			continue
+		}
+
+		runes := []rune(chunk.Text)
+		start := 0
+		var font *model.PdfFont
+		for i, r := range runes {
+			f := chunk.Style.fontForRune(r, font)
+			if i > 0 && f != font {
+				split = append(split, fallbackChunk(chunk, string(runes[start:i]), font))
+				start = i
+			}
+			font = f
+		}
+		split = append(split, fallbackChunk(chunk, string(runes[start:]), font))
+	}
+	return split
+}
+
+// fallbackChunk returns a copy of `chunk` with text `text` drawn with font `font`.
+func fallbackChunk(chunk *TextChunk, text string, font *model.PdfFont) *TextChunk {
+	style := chunk.Style
+	style.Font = font
+	return &TextChunk{
+		Text:       text,
+		Style:      style,
+		annotation: chunk.annotation,
+	}
+}
+
+// fallbackRuneWidths returns the widths of the runes of `chunk`, in glyph space units, measured
+// with the fonts of the fallback fonts of its style which draw them.
+func fallbackRuneWidths(chunk *TextChunk) ([]float64, error) {
+	var widths []float64
+	for _, c := range splitFallbackChunks([]*TextChunk{chunk}) {
+		w, err := runeWidths(&c.Style, []rune(c.Text))
+		if err != nil {
+			return nil, err
+		}
+		widths = append(widths, w...)
+	}
+	return widths, nil
+}
+
+// NewSystemFallbackFonts returns composite fonts loaded from the installed TrueType and OpenType
+// fonts of the font families `families`, e.g. "Noto Sans CJK" or "Noto Color Emoji", in the same
+// order. They can be used as the fallback fonts of text styles (see TextStyle.FallbackFonts).
+// The font of each family which best matches its name is used. Families which are not installed
+// are skipped, rather than substituted with fonts of other families. An error is returned if none
+// of them is found.
+func NewSystemFallbackFonts(families ...string) ([]*model.PdfFont, error) {
+	finder := sysfont.NewFinder(&sysfont.FinderOpts{
+		Extensions: []string{".ttf", ".otf"},
+	})
+
+	var fonts []*model.PdfFont
+	loaded := map[string]bool{}
+	for _, family := range families {
+		// The finder returns a substitute if the family is not installed.
+		match := finder.Match(family)
+		if match == nil || match.Filename == "" || !strings.EqualFold(match.Family, family) {
+			common.Log.Debug("System font not found: %q", family)
+			continue
+		}
+		if loaded[match.Filename] {
+			continue
+		}
+		loaded[match.Filename] = true
+
+		font, err := model.NewCompositePdfFontFromTTFFile(match.Filename)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load system font %q (%s): %v", family,
+				match.Filename, err)
+			continue
+		}
+		fonts = append(fonts, font)
+	}
+	if len(fonts) == 0 {
+		return nil, errors.New("system fonts not found")
+	}
+	return fonts, nil
+}
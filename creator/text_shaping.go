@@ -0,0 +1,234 @@
+package creator
+
+import (
+	"errors"
+	"unicode"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/bidi"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// complexScripts are the scripts whose runes are not mapped to glyphs one-to-one: their glyphs
+// depend on the context (joining forms, conjuncts) and are reordered or positioned relative to
+// each other (marks).
+var complexScripts = []*unicode.RangeTable{
+	unicode.Arabic,
+	unicode.Hebrew,
+	unicode.Devanagari,
+	unicode.Thai,
+}
+
+// needsShaping returns true if `text` contains runes of complex scripts or right-to-left text.
+// Text without these runes is drawn with one glyph per rune, as shaping does not change its
+// layout significantly.
+func needsShaping(text []rune) bool {
+	for _, r := range text {
+		if r >= 0x0590 && unicode.In(r, complexScripts...) {
+			return true
+		}
+	}
+	return bidi.HasRTL(text)
+}
+
+// shapeText shapes `text` drawn with `style` if it needs shaping and the style's font supports
+// shaping (see model.PdfFont.ShapeText). The bool is false if the text is not shaped.
+func shapeText(style *TextStyle, text []rune, rtl bool) ([]model.ShapedGlyph, bool) {
+	if style.Font == nil || !needsShaping(text) {
+		return nil, false
+	}
+	return style.Font.ShapeText(string(text), rtl)
+}
+
+// runeWidths returns the widths of the runes of `text` drawn with `style`, in glyph space units.
+// Shaped text (see shapeText) is measured by the advances of its glyphs: the advance of each glyph
+// is attributed to the first rune it represents.
+// The widths of vertical text are the vertical advances of the runes.
+func runeWidths(style *TextStyle, text []rune) ([]float64, error) {
+	if style.Vertical {
+		return verticalRuneWidths(style, text)
+	}
+	widths := make([]float64, len(text))
+	if glyphs, ok := shapeText(style, text, false); ok {
+		for _, g := range glyphs {
+			if g.Cluster < len(widths) {
+				widths[g.Cluster] += g.XAdvance
+			}
+		}
+		return widths, nil
+	}
+
+	for i, r := range text {
+		if r == '\u000A' { // LF
+			continue
+		}
+		metrics, found := style.Font.GetRuneMetrics(r)
+		if !found {
+			common.Log.Debug("ERROR: Rune char metrics not found! rune=0x%04x=%c font=%s %#q",
+				r, r, style.Font.BaseFont(), style.Font.Subtype())
+			return nil, errors.New("glyph char metrics missing")
+		}
+		widths[i] = metrics.Wx
+	}
+	return widths, nil
+}
+
+// visualLines returns the lines of the paragraph in visual order, as determined by the Unicode
+// bidirectional algorithm. The text chunks of lines with right-to-left text are split in runs of
+// a single direction, which are ordered from left to right. Lines of paragraphs without
+// right-to-left text are returned unchanged.
+func (p *StyledParagraph) visualLines() [][]*TextChunk {
+	var text []rune
+	for _, chunk := range p.chunks {
+		text = append(text, []rune(chunk.Text)...)
+	}
+	if !bidi.HasRTL(text) {
+		return p.lines
+	}
+	level := bidi.NewParagraph(text, -1).Level()
+
+	lines := make([][]*TextChunk, len(p.lines))
+	for i, line := range p.lines {
+		lines[i] = visualLine(line, level)
+	}
+	return lines
+}
+
+// visualLine returns the text chunks of `line` split in runs of a single direction, in visual
+// order. `level` is the embedding level of the paragraph.
+func visualLine(line []*TextChunk, level int) []*TextChunk {
+	var text []rune
+	var chunkIdx []int
+	for k, chunk := range line {
+		for _, r := range chunk.Text {
+			text = append(text, r)
+			chunkIdx = append(chunkIdx, k)
+		}
+	}
+	if level == 0 && !bidi.HasRTL(text) {
+		return line
+	}
+	levels := bidi.NewParagraph(text, level).LineLevels(0, len(text))
+
+	// Runs of runes of the same chunk with the same level.
+	type textRun struct {
+		start, end int
+	}
+	var runs []textRun
+	var runLevels []int
+	for i := range text {
+		if n := len(runs); n > 0 && chunkIdx[i] == chunkIdx[runs[n-1].start] &&
+			levels[i] == runLevels[n-1] {
+			runs[n-1].end = i + 1
+			continue
+		}
+		runs = append(runs, textRun{start: i, end: i + 1})
+		runLevels = append(runLevels, levels[i])
+	}
+
+	visual := make([]*TextChunk, 0, len(runs))
+	annotated := make(map[int]bool)
+	for _, ri := range bidi.Reorder(runLevels) {
+		run := runs[ri]
+		k := chunkIdx[run.start]
+		chunk := &TextChunk{
+			Text:  string(text[run.start:run.end]),
+			Style: line[k].Style,
+			rtl:   runLevels[ri]&1 == 1,
+		}
+		// The annotation of a chunk split in several runs is set for the first run.
+		if !annotated[k] {
+			chunk.annotation = line[k].annotation
+			annotated[k] = true
+		}
+		visual = append(visual, chunk)
+	}
+	return visual
+}
+
+// mirrorReverse returns the runes of the right-to-left text `text` in visual order, with the
+// mirrored characters replaced (e.g. parentheses).
+func mirrorReverse(text []rune) []rune {
+	visual := make([]rune, len(text))
+	for i, r := range text {
+		if m, ok := bidi.Mirror(r); ok {
+			r = m
+		}
+		visual[len(text)-1-i] = r
+	}
+	return visual
+}
+
+// addActualText begins a marked-content sequence with the actual text `text`, for text which is
+// not mapped to its glyphs one-to-one, e.g. shaped or reordered text. The text extracted from the
+// glyphs of the sequence is replaced by `text` (section 14.9.4 "Replacement Text" PDF32000_2008).
+func addActualText(cc *contentstream.ContentCreator, text string) {
+	props := core.MakeDict()
+	props.Set("ActualText", core.MakeEncodedString(text, true))
+	cc.Add_BDC("Span", props)
+}
+
+// textRiseGroup is a part of shaped text drawn with the same text rise.
+type textRiseGroup struct {
+	rise  float64
+	items []core.PdfObject // The operands of the TJ operator.
+}
+
+// shapedTextGroups returns the operands of the TJ operators that draw the shaped glyphs `glyphs`
+// with font `font` of size `fontSize` and character spacing `charSpacing`, grouped by the text
+// rise of the glyphs. The glyphs are positioned with adjustments of the text position between
+// the glyphs.
+func shapedTextGroups(font *model.PdfFont, glyphs []model.ShapedGlyph, fontSize,
+	charSpacing float64) []textRiseGroup {
+	var groups []textRiseGroup
+	var str []byte
+	var adjust float64
+	flushStr := func() {
+		if len(str) > 0 {
+			g := &groups[len(groups)-1]
+			g.items = append(g.items, core.MakeStringFromBytes(str))
+			str = nil
+		}
+	}
+	flushAdjust := func() {
+		if adjust != 0 {
+			g := &groups[len(groups)-1]
+			g.items = append(g.items, core.MakeFloat(adjust))
+			adjust = 0
+		}
+	}
+
+	for _, g := range glyphs {
+		rise := g.YOffset / 1000.0 * fontSize
+		if len(groups) == 0 || rise != groups[len(groups)-1].rise {
+			if len(groups) > 0 {
+				flushStr()
+				flushAdjust()
+			}
+			groups = append(groups, textRiseGroup{rise: rise})
+		}
+
+		// TJ adjustments move the text position to the left by thousandths of the font size.
+		adjust -= g.XOffset
+		if adjust != 0 {
+			flushStr()
+			flushAdjust()
+		}
+		str = append(str, font.CharcodesToBytes([]textencoding.CharCode{g.Code})...)
+
+		// Move from the position after the glyph to the position of the next glyph.
+		adjust = g.Width + g.XOffset - g.XAdvance
+		if g.XAdvance == 0 && charSpacing != 0 && fontSize != 0 {
+			// Marks are not spaced.
+			adjust += charSpacing * 1000.0 / fontSize
+		}
+	}
+	if len(groups) > 0 {
+		flushStr()
+		flushAdjust()
+	}
+	return groups
+}
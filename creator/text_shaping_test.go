@@ -0,0 +1,142 @@
+package creator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/moolekkari/unipdf/extractor"
+	"github.com/moolekkari/unipdf/model"
+	"github.com/stretchr/testify/require"
+)
+
+// extractPageText returns the text of the first page of the PDF written by creator `c`.
+func extractPageText(t *testing.T, c *Creator) string {
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+
+	ex, err := extractor.New(page)
+	require.NoError(t, err)
+	text, err := ex.ExtractText()
+	require.NoError(t, err)
+	return text
+}
+
+func TestStyledParagraphShaping(t *testing.T) {
+	font, err := model.NewCompositePdfFontFromTTFFile(testFreeSansTTFFile)
+	require.NoError(t, err)
+
+	texts := []string{
+		"नमस्ते दुनिया",
+		"धर्म क्षत्रिय",
+		"שלום עולם",
+	}
+
+	c := New()
+	for _, text := range texts {
+		p := c.NewStyledParagraph()
+		chunk := p.Append(text)
+		chunk.Style.Font = font
+		chunk.Style.FontSize = 16
+		require.NoError(t, c.Draw(p))
+	}
+
+	text := extractPageText(t, c)
+	for _, expected := range texts {
+		require.Contains(t, text, expected)
+	}
+	testWriteAndRender(t, c, "styled_paragraph_shaping.pdf")
+}
+
+func TestStyledParagraphVertical(t *testing.T) {
+	font, err := model.NewCompositePdfFontFromTTFFile(testFreeSansTTFFile)
+	require.NoError(t, err)
+
+	c := New()
+	p := c.NewStyledParagraph()
+	chunk := p.Append("ABCDE FGHIJ KLM")
+	chunk.Style.Font = font
+	chunk.Style.FontSize = 20
+	chunk.Style.Vertical = true
+	p.SetWidth(100)
+	p.SetPos(100, 100)
+	require.NoError(t, p.wrapText())
+
+	// The text is wrapped in columns of 5 glyphs, drawn from right to left.
+	require.Len(t, p.lines, 3)
+	require.InDelta(t, 60, p.Width(), 1)
+	require.InDelta(t, 100, p.Height(), 1)
+	require.NoError(t, c.Draw(p))
+
+	text := extractPageText(t, c)
+	require.Equal(t, "ABCDE\nFGHIJ\nKLM", text)
+	testWriteAndRender(t, c, "styled_paragraph_vertical.pdf")
+}
+
+func TestStyledParagraphFallbackFonts(t *testing.T) {
+	font, err := model.NewCompositePdfFontFromTTFFile(testFreeSansTTFFile)
+	require.NoError(t, err)
+
+	c := New()
+	p := c.NewStyledParagraph()
+	chunk := p.Append("Hello שלום world")
+	chunk.Style.FallbackFonts = []*model.PdfFont{font}
+	require.NoError(t, p.wrapText())
+
+	// The Hebrew runes are drawn with the fallback font, the spaces with the font of the
+	// preceding runes.
+	require.Len(t, p.lines, 1)
+	line := p.lines[0]
+	require.Len(t, line, 3)
+	require.Equal(t, "Hello ", line[0].Text)
+	require.Equal(t, p.defaultStyle.Font, line[0].Style.Font)
+	require.Equal(t, "שלום ", line[1].Text)
+	require.Equal(t, font, line[1].Style.Font)
+	require.Equal(t, "world", line[2].Text)
+	require.Equal(t, p.defaultStyle.Font, line[2].Style.Font)
+
+	require.NoError(t, c.Draw(p))
+	require.Contains(t, extractPageText(t, c), "שלום")
+	testWriteAndRender(t, c, "styled_paragraph_fallback_fonts.pdf")
+}
+
+func TestStyledParagraphFallbackFontsWidth(t *testing.T) {
+	font, err := model.NewCompositePdfFontFromTTFFile(testFreeSansTTFFile)
+	require.NoError(t, err)
+
+	c := New()
+	newParagraph := func(text string) *StyledParagraph {
+		p := c.NewStyledParagraph()
+		p.SetEnableWrap(false)
+		chunk := p.Append(text)
+		chunk.Style.FallbackFonts = []*model.PdfFont{font}
+		return p
+	}
+
+	// The runes missing from the font of the style are measured with the fallback font.
+	p := newParagraph("ab ЖЗ cd")
+	runes := []rune("ЖЗ")
+	widths, err := runeWidths(&TextStyle{Font: font, FontSize: 10}, runes)
+	require.NoError(t, err)
+	expected := newParagraph("ab  cd").Width() + (widths[0]+widths[1])*10/1000
+	require.InDelta(t, expected, p.Width(), 1e-6)
+	require.NoError(t, p.wrapText())
+	require.InDelta(t, p.getMaxLineWidth()/1000, p.Width(), 1e-6)
+
+	chunk := NewTextChunk("ab ЖЗ cd", p.defaultStyle)
+	chunk.Style.FallbackFonts = []*model.PdfFont{font}
+	lines, err := chunk.Wrap(expected - 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ab ЖЗ", "cd"}, lines)
+}
+
+func TestNewSystemFallbackFontsMissing(t *testing.T) {
+	// Families which are not installed are not substituted.
+	fonts, err := NewSystemFallbackFonts("Unipdf Missing Family")
+	require.Error(t, err)
+	require.Empty(t, fonts)
+}
@@ -12,6 +12,12 @@ type TextStyle struct {
 	// The font the text will use.
 	Font *model.PdfFont
 
+	// FallbackFonts are the fonts used for the runes for which Font has no glyph, in order of
+	// preference. The text is split in runs drawn with the first font which has glyphs for their
+	// runes, e.g. for text mixing Latin, CJK and emoji characters. System fonts can be used as
+	// fallback fonts with NewSystemFallbackFonts.
+	FallbackFonts []*model.PdfFont
+
 	// The size of the font.
 	FontSize float64
 
@@ -20,6 +26,11 @@ type TextStyle struct {
 
 	// The rendering mode.
 	RenderingMode TextRenderingMode
+
+	// Vertical sets the vertical writing mode: the text is drawn from top to bottom, in columns
+	// laid out from right to left, with the vertical variant of the font (see
+	// model.PdfFont.VerticalFont). Paragraphs with vertical text chunks are laid out in columns.
+	Vertical bool
 }
 
 // newTextStyle creates a new text style object using the specified font.
@@ -0,0 +1,251 @@
+package creator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/contentstream/draw"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// verticalFont returns the font used for drawing text with the vertical style `style`.
+func verticalFont(style *TextStyle) (*model.PdfFont, error) {
+	if style.Font == nil {
+		return nil, errors.New("font not set")
+	}
+	return style.Font.VerticalFont()
+}
+
+// verticalRuneWidths returns the vertical advances of the runes of `text` drawn with the
+// vertical style `style`, in glyph space units.
+func verticalRuneWidths(style *TextStyle, text []rune) ([]float64, error) {
+	font, err := verticalFont(style)
+	if err != nil {
+		return nil, err
+	}
+	enc := font.Encoder()
+
+	widths := make([]float64, len(text))
+	for i, r := range text {
+		if r == '\u000A' { // LF
+			continue
+		}
+		code, ok := enc.RuneToCharcode(r)
+		if !ok {
+			common.Log.Debug("ERROR: Rune not in vertical font encoding: rune=0x%04x=%c font=%s",
+				r, r, font.BaseFont())
+			return nil, errors.New("glyph char metrics missing")
+		}
+		metrics, ok := font.GetVerticalMetrics(code)
+		if !ok {
+			common.Log.Debug("ERROR: Vertical metrics not found: rune=0x%04x=%c font=%s",
+				r, r, font.BaseFont())
+			return nil, errors.New("glyph char metrics missing")
+		}
+		widths[i] = -metrics.W1y
+	}
+	return widths, nil
+}
+
+// isVertical returns true if the paragraph is laid out in vertical writing mode, i.e. if the
+// style of any of its text chunks is vertical.
+func (p *StyledParagraph) isVertical() bool {
+	for _, chunk := range p.chunks {
+		if chunk.Style.Vertical {
+			return true
+		}
+	}
+	return false
+}
+
+// getColumnWidth returns the width of the column of vertical text `line`.
+func (p *StyledParagraph) getColumnWidth(line []*TextChunk) float64 {
+	var width float64
+	for _, chunk := range line {
+		if w := p.lineHeight * chunk.Style.FontSize; w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// verticalWidth returns the width of the columns of the vertical paragraph.
+func (p *StyledParagraph) verticalWidth() float64 {
+	p.wrapText()
+
+	var width float64
+	for _, line := range p.lines {
+		width += p.getColumnWidth(line)
+	}
+	return width
+}
+
+// verticalHeight returns the height of the vertical paragraph, which is the wrapping width, or the
+// length of the text if it is not wrapped.
+func (p *StyledParagraph) verticalHeight() float64 {
+	if p.enableWrap && int(p.wrapWidth) > 0 {
+		return p.wrapWidth
+	}
+	return p.getTextWidth() / 1000.0
+}
+
+// drawVerticalStyledParagraphOnBlock draws the vertical paragraph `p` on block `blk`. The
+// columns of text are drawn from the right edge of the paragraph to the left. The glyphs are
+// centred in the columns, the columns are aligned at the top, bottom or centre of the paragraph
+// by its left, right and center alignments.
+func drawVerticalStyledParagraphOnBlock(blk *Block, p *StyledParagraph, ctx DrawContext) (DrawContext, error) {
+	// Wrap the text into columns.
+	p.wrapText()
+
+	// Add the vertical fonts of all chunks to the page resources.
+	num := 1
+	var fonts [][]core.PdfObjectName
+	for _, line := range p.lines {
+		var fontLine []core.PdfObjectName
+		for _, chunk := range line {
+			font, err := verticalFont(&chunk.Style)
+			if err != nil {
+				return ctx, err
+			}
+
+			fontName := core.PdfObjectName(fmt.Sprintf("Font%d", num))
+			for blk.resources.HasFontByName(fontName) {
+				num++
+				fontName = core.PdfObjectName(fmt.Sprintf("Font%d", num))
+			}
+			if err := blk.resources.SetFont(fontName, font); err != nil {
+				return ctx, err
+			}
+
+			fontLine = append(fontLine, fontName)
+			num++
+		}
+		fonts = append(fonts, fontLine)
+	}
+
+	// Create the content stream.
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+
+	yPos := ctx.PageHeight - ctx.Y
+	cc.Translate(ctx.X, yPos)
+
+	if p.angle != 0 {
+		cc.RotateDeg(p.angle)
+	}
+
+	cc.Add_BT()
+
+	height := p.verticalHeight()
+	currX := p.verticalWidth()
+	for idx, line := range p.lines {
+		width := p.getColumnWidth(line)
+		length := p.getTextLineWidth(line) / 1000.0
+		currX -= width
+
+		// Offset of the column from the top of the paragraph.
+		var currY float64
+		switch p.alignment {
+		case TextAlignmentCenter:
+			currY = (height - length) / 2
+		case TextAlignmentRight:
+			currY = height - length
+		}
+
+		// The vertical origin of the glyphs is in the centre of the column.
+		cc.Add_Tm(1, 0, 0, 1, currX+width/2, -currY)
+
+		for k, chunk := range line {
+			style := &chunk.Style
+			font, err := verticalFont(style)
+			if err != nil {
+				return ctx, err
+			}
+			runes := []rune(chunk.Text)
+			widths, err := runeWidths(style, runes)
+			if err != nil {
+				return ctx, err
+			}
+
+			enc := font.Encoder()
+			var encStr []byte
+			var chunkLength float64
+			for i, r := range runes {
+				if r == '\u000A' { // LF
+					continue
+				}
+				if _, ok := enc.RuneToCharcode(r); !ok {
+					common.Log.Debug("unsupported rune in text encoding: %#x (%c)", r, r)
+					continue
+				}
+				encStr = append(encStr, enc.Encode(string(r))...)
+
+				chunkLength += style.FontSize * widths[i] / 1000.0
+				// Do not add character spacing for the last character of the column.
+				if i != len(runes)-1 {
+					chunkLength += style.CharSpacing
+				}
+			}
+
+			r, g, b := style.Color.ToRGB()
+			// Positive character spacing moves the text position upwards in vertical mode.
+			cc.Add_Tr(int64(style.RenderingMode)).
+				Add_Tc(-style.CharSpacing).
+				Add_rg(r, g, b).
+				Add_Tf(fonts[idx][k], style.FontSize).
+				Add_TJ([]core.PdfObject{core.MakeStringFromBytes(encStr)}...)
+
+			// Add annotations.
+			if chunk.annotation != nil {
+				// Set the coordinates of the annotation.
+				if annotRect := chunk.processAnnotation(ctx); annotRect != nil {
+					// Calculate rotated annotation position.
+					annotPos := draw.NewPoint(currX, -currY-chunkLength).Rotate(p.angle)
+					annotPos.X += ctx.X
+					annotPos.Y += yPos
+
+					// Calculate rotated annotation bounding box.
+					offX, offY, annotW, annotH := rotateRect(width, chunkLength, p.angle)
+					annotPos.X += offX
+					annotPos.Y += offY
+
+					annotRect.Clear()
+					annotRect.Append(core.MakeFloat(annotPos.X))
+					annotRect.Append(core.MakeFloat(annotPos.Y))
+					annotRect.Append(core.MakeFloat(annotPos.X + annotW))
+					annotRect.Append(core.MakeFloat(annotPos.Y + annotH))
+				}
+
+				blk.AddAnnotation(chunk.annotation)
+			}
+			currY += chunkLength
+		}
+
+		// Reset rendering mode and character spacing.
+		cc.Add_Tr(int64(TextRenderingModeFill))
+		cc.Add_Tc(0)
+	}
+	cc.Add_ET()
+	cc.Add_Q()
+
+	ops := cc.Operations()
+	ops.WrapIfNeeded()
+
+	blk.addContents(ops)
+
+	if p.positioning.isRelative() {
+		pHeight := p.Height() + p.margins.bottom
+		ctx.Y += pHeight
+		ctx.Height -= pHeight
+
+		// If the division is inline, calculate context new X coordinate.
+		if ctx.Inline {
+			ctx.X += p.Width() + p.margins.right
+		}
+	}
+
+	return ctx, nil
+}
@@ -0,0 +1,6 @@
+// Package editor provides an interface for editing the content of PDF pages.
+// The content stream of a page is parsed into editable objects (text runs, images and paths)
+// which can be deleted, moved and recolored. The text of text runs can be replaced, provided
+// that the font used for the text contains the glyphs of the new text. The content stream of the
+// page is then regenerated with the changes applied, leaving the unchanged content as it was.
+package editor
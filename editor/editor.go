@@ -0,0 +1,508 @@
+package editor
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// Editor is used for editing the content of a page.
+type Editor struct {
+	page      *model.PdfPage
+	resources *model.PdfPageResources
+
+	segments []*segment
+	objects  []Object
+
+	// Cache of the fonts loaded from the font objects of the page resources.
+	fonts contentutil.FontCache
+}
+
+// segment is a part of the content stream: either a single operation or an editable object.
+type segment struct {
+	op    *contentstream.ContentStreamOperation
+	obj   Object
+	block *textBlock // The text object started by a BT operation.
+}
+
+// textBlock represents a text object, i.e. the operations between BT and ET.
+type textBlock struct {
+	runs []*TextRun
+}
+
+// modified returns true if any of the text runs of `b` have been changed.
+func (b *textBlock) modified() bool {
+	for _, run := range b.runs {
+		if run.modified() {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns an Editor for the content of `page`.
+func New(page *model.PdfPage) (*Editor, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Editor{
+		page:      page,
+		resources: page.Resources,
+		fonts:     contentutil.FontCache{},
+	}
+	if err := e.parse(*ops); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Objects returns the editable objects of the page, in the order they are drawn.
+func (e *Editor) Objects() []Object {
+	return e.objects
+}
+
+// TextRuns returns the text runs of the page, in the order they are drawn.
+func (e *Editor) TextRuns() []*TextRun {
+	var runs []*TextRun
+	for _, obj := range e.objects {
+		if run, ok := obj.(*TextRun); ok {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// Images returns the images of the page, in the order they are drawn.
+func (e *Editor) Images() []*Image {
+	var images []*Image
+	for _, obj := range e.objects {
+		if img, ok := obj.(*Image); ok {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+// Paths returns the painted paths of the page, in the order they are drawn.
+func (e *Editor) Paths() []*Path {
+	var paths []*Path
+	for _, obj := range e.objects {
+		if path, ok := obj.(*Path); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// ReplaceText replaces all occurrences of `old` with `new` in the text runs of the page.
+// Only occurrences within a single text run are replaced.
+// Returns the number of text runs changed. If `new` cannot be drawn with the font of a run
+// containing `old`, ErrGlyphNotInFont is returned after replacing the text of the other runs.
+func (e *Editor) ReplaceText(old, new string) (int, error) {
+	if old == "" {
+		return 0, errors.New("empty text")
+	}
+	var firstErr error
+	count := 0
+	for _, run := range e.TextRuns() {
+		if run.deleted || !strings.Contains(run.Text(), old) {
+			continue
+		}
+		if err := run.SetText(strings.Replace(run.Text(), old, new, -1)); err != nil {
+			common.Log.Debug("ERROR: unable to replace text %q: %v", run.Text(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		count++
+	}
+	return count, firstErr
+}
+
+// ConvertTextToOutlines converts all text runs of the page to the outlines of their glyphs, so
+// that the page can be printed without its fonts. The fonts which are no longer used are removed
+// from the page resources by Apply.
+// Returns the number of text runs converted. If the font of a run has no glyph outlines,
+// ErrNoGlyphOutlines is returned after converting the other runs.
+func (e *Editor) ConvertTextToOutlines() (int, error) {
+	var firstErr error
+	count := 0
+	for _, run := range e.TextRuns() {
+		if run.deleted || run.outlined {
+			continue
+		}
+		if err := run.ConvertToOutlines(); err != nil {
+			common.Log.Debug("ERROR: unable to convert text %q to outlines: %v", run.Text(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		count++
+	}
+	return count, firstErr
+}
+
+// unusedFonts returns the resource names of the fonts which are only used by text runs converted
+// to outlines.
+func (e *Editor) unusedFonts() map[core.PdfObjectName]bool {
+	unused := map[core.PdfObjectName]bool{}
+	used := map[core.PdfObjectName]bool{}
+	for _, run := range e.TextRuns() {
+		switch {
+		case run.outlined:
+			unused[run.fontName] = true
+		case !run.deleted:
+			used[run.fontName] = true
+		}
+	}
+	for name := range used {
+		delete(unused, name)
+	}
+	return unused
+}
+
+// Operations returns the content stream operations of the page with the changes applied.
+func (e *Editor) Operations() *contentstream.ContentStreamOperations {
+	var out contentstream.ContentStreamOperations
+	unused := e.unusedFonts()
+	absolute := false
+	// Clipping paths of text runs converted to outlines, applied at the end of the text object.
+	var clip []*contentstream.ContentStreamOperation
+	for _, seg := range e.segments {
+		switch obj := seg.obj.(type) {
+		case *Path:
+			out = append(out, obj.operations()...)
+			continue
+		case *Image:
+			out = append(out, obj.operations()...)
+			continue
+		case *TextRun:
+			if absolute && obj.outlined {
+				ops, clipOps := obj.outlineOperations()
+				out = append(out, ops...)
+				clip = append(clip, clipOps...)
+			} else if absolute {
+				out = append(out, obj.operations()...)
+			} else if !obj.deleted {
+				out = append(out, obj.op)
+			}
+			continue
+		}
+
+		op := seg.op
+		switch op.Operand {
+		case "BT":
+			absolute = seg.block != nil && seg.block.modified()
+		case "ET":
+			absolute = false
+			if len(clip) > 0 {
+				out = append(out, op)
+				out = append(out, clip...)
+				out = append(out, &contentstream.ContentStreamOperation{Operand: "W"},
+					&contentstream.ContentStreamOperation{Operand: "n"})
+				clip = nil
+				continue
+			}
+		case "Tf":
+			if len(op.Params) == 2 {
+				if name, ok := core.GetName(op.Params[0]); ok && unused[*name] {
+					continue
+				}
+			}
+		case "Td", "T*", "Tm":
+			// In modified text objects, each text run is positioned explicitly.
+			if absolute {
+				continue
+			}
+		case "TD":
+			if absolute {
+				if len(op.Params) == 2 {
+					if ty, err := core.GetNumberAsFloat(op.Params[1]); err == nil {
+						out = append(out, &contentstream.ContentStreamOperation{
+							Operand: "TL",
+							Params:  []core.PdfObject{core.MakeFloat(-ty)},
+						})
+					}
+				}
+				continue
+			}
+		}
+		out = append(out, op)
+	}
+	return removeEmptyTextObjects(out)
+}
+
+// removeEmptyTextObjects returns `ops` without the text objects which contain no operations, left
+// by text runs converted to outlines.
+func removeEmptyTextObjects(ops contentstream.ContentStreamOperations) *contentstream.ContentStreamOperations {
+	var out contentstream.ContentStreamOperations
+	for i := 0; i < len(ops); i++ {
+		if ops[i].Operand == "BT" && i+1 < len(ops) && ops[i+1].Operand == "ET" {
+			i++
+			continue
+		}
+		out = append(out, ops[i])
+	}
+	return &out
+}
+
+// Bytes returns the content stream of the page with the changes applied.
+func (e *Editor) Bytes() []byte {
+	return e.Operations().Bytes()
+}
+
+// Apply replaces the content streams of the page with the content stream returned by Bytes and
+// removes the fonts only used by text converted to outlines from the page resources.
+func (e *Editor) Apply() error {
+	unused := e.unusedFonts()
+	if err := e.page.SetContentStreams([]string{string(e.Bytes())}, core.NewFlateEncoder()); err != nil {
+		return err
+	}
+	if len(unused) == 0 || e.resources == nil {
+		return nil
+	}
+	fontDict, ok := core.GetDict(e.resources.Font)
+	if !ok {
+		return nil
+	}
+	// The font dictionary may be shared with other pages, so it is replaced rather than changed.
+	fonts := core.MakeDict()
+	for _, name := range fontDict.Keys() {
+		if !unused[name] {
+			fonts.Set(name, fontDict.Get(name))
+		}
+	}
+	if len(fonts.Keys()) == 0 {
+		e.resources.Font = nil
+	} else {
+		e.resources.Font = fonts
+	}
+	return nil
+}
+
+// operations returns the operations drawing `t` with the changes applied, in a text object
+// where each text run is positioned explicitly.
+func (t *TextRun) operations() []*contentstream.ContentStreamOperation {
+	var ops []*contentstream.ContentStreamOperation
+	if t.op.Operand == "\"" && len(t.op.Params) == 3 {
+		// Keep the word and character spacing set by the operation.
+		ops = append(ops,
+			&contentstream.ContentStreamOperation{Operand: "Tw", Params: t.op.Params[:1]},
+			&contentstream.ContentStreamOperation{Operand: "Tc", Params: t.op.Params[1:2]})
+	}
+	if t.deleted {
+		return ops
+	}
+
+	if t.fillColor != nil {
+		ops = append(ops, t.fillColor)
+	}
+	if t.strokeColor != nil {
+		ops = append(ops, t.strokeColor)
+	}
+
+	tm := t.tm
+	if t.moved() {
+		tm = t.moveMatrix().Mult(t.tm)
+	}
+	ops = append(ops, &contentstream.ContentStreamOperation{
+		Operand: "Tm",
+		Params:  matrixParams(tm),
+	})
+
+	switch {
+	case t.replaced != nil:
+		ops = append(ops, &contentstream.ContentStreamOperation{
+			Operand: "Tj",
+			Params:  []core.PdfObject{contentutil.MakeString(t.font, t.replaced)},
+		})
+	case t.op.Operand == "'" || t.op.Operand == "\"":
+		ops = append(ops, &contentstream.ContentStreamOperation{
+			Operand: "Tj",
+			Params:  t.op.Params[len(t.op.Params)-1:],
+		})
+	default:
+		ops = append(ops, t.op)
+	}
+
+	if t.fillColor != nil {
+		ops = append(ops, restoreColorOps(t.fillOps, "g")...)
+	}
+	if t.strokeColor != nil {
+		ops = append(ops, restoreColorOps(t.strokeOps, "G")...)
+	}
+	return ops
+}
+
+// textPaintOperators are the path painting operators for the text rendering modes which paint
+// the glyphs.
+var textPaintOperators = map[int]string{0: "f", 1: "S", 2: "B", 4: "f", 5: "S", 6: "B"}
+
+// outlineOperations returns the operations drawing the glyph outlines of `t`, which replace the
+// text of the run in a text object where each text run is positioned explicitly, and the path
+// operations to be added to the clipping path at the end of the text object.
+func (t *TextRun) outlineOperations() ([]*contentstream.ContentStreamOperation, []*contentstream.ContentStreamOperation) {
+	var ops, clip []*contentstream.ContentStreamOperation
+	if t.op.Operand == "\"" && len(t.op.Params) == 3 {
+		// Keep the word and character spacing set by the operation.
+		ops = append(ops,
+			&contentstream.ContentStreamOperation{Operand: "Tw", Params: t.op.Params[:1]},
+			&contentstream.ContentStreamOperation{Operand: "Tc", Params: t.op.Params[1:2]})
+	}
+	if t.deleted {
+		return ops, nil
+	}
+
+	// The outlines are drawn outside the text object, in the same user space as the text.
+	tm := t.tm
+	if t.moved() {
+		tm = t.moveMatrix().Mult(t.tm)
+	}
+	paintOp, paint := textPaintOperators[t.state.Tr]
+	var paths []*contentstream.ContentStreamOperation
+	contentutil.LayoutGlyphs(t.state, tm, t.items(), func(g contentutil.Glyph, m transform.Matrix, advance float64) {
+		path, _ := t.font.GlyphOutline(g.Code)
+		pathOps := glyphPathOperations(path, m)
+		if len(pathOps) == 0 {
+			return
+		}
+		if paint {
+			paths = append(paths, pathOps...)
+			paths = append(paths, &contentstream.ContentStreamOperation{Operand: paintOp})
+		}
+		if t.state.Tr >= 4 {
+			clip = append(clip, pathOps...)
+		}
+	}, nil)
+
+	ops = append(ops, &contentstream.ContentStreamOperation{Operand: "ET"})
+	if len(paths) > 0 {
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "q"})
+		if t.fillColor != nil {
+			ops = append(ops, t.fillColor)
+		}
+		if t.strokeColor != nil {
+			ops = append(ops, t.strokeColor)
+		}
+		ops = append(ops, paths...)
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "Q"})
+	}
+	ops = append(ops, &contentstream.ContentStreamOperation{Operand: "BT"})
+	return ops, clip
+}
+
+// glyphPathOperations returns the path construction operations of the glyph outline `path`,
+// transformed by the matrix `m` mapping unscaled text space to user space.
+func glyphPathOperations(path model.GlyphPath, m transform.Matrix) []*contentstream.ContentStreamOperation {
+	var ops []*contentstream.ContentStreamOperation
+	for _, seg := range path {
+		op := &contentstream.ContentStreamOperation{Operand: seg.Op}
+		for _, p := range seg.Points {
+			x, y := m.TransformPoint(p[0]*contentutil.GlyphTextRatio, p[1]*contentutil.GlyphTextRatio)
+			op.Params = append(op.Params, makeCoordinate(x), makeCoordinate(y))
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// makeCoordinate returns a number object for the path coordinate `v`, rounded to 1/1000 of a
+// user space unit.
+func makeCoordinate(v float64) core.PdfObject {
+	return core.MakeFloat(math.Round(v*1000) / 1000)
+}
+
+// restoreColorOps returns the color operations `colorOps`, or the operation setting the default
+// black DeviceGray color with `grayOperand` if there are none.
+func restoreColorOps(colorOps []*contentstream.ContentStreamOperation, grayOperand string) []*contentstream.ContentStreamOperation {
+	if len(colorOps) > 0 {
+		return colorOps
+	}
+	return []*contentstream.ContentStreamOperation{
+		{Operand: grayOperand, Params: []core.PdfObject{core.MakeInteger(0)}},
+	}
+}
+
+// operations returns the operations drawing `p` with the changes applied.
+func (p *Path) operations() []*contentstream.ContentStreamOperation {
+	var ops []*contentstream.ContentStreamOperation
+	if !p.modified() {
+		ops = append(ops, p.ops...)
+		if p.clipOp != nil {
+			ops = append(ops, p.clipOp)
+		}
+		return append(ops, p.paintOp)
+	}
+
+	if !p.deleted {
+		ops = append(ops, p.object.begin()...)
+		ops = append(ops, p.ops...)
+		ops = append(ops, p.paintOp)
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "Q"})
+	}
+	if p.clipOp != nil {
+		// The clipping path is not affected by the changes to the painted path.
+		ops = append(ops, p.ops...)
+		ops = append(ops, p.clipOp, &contentstream.ContentStreamOperation{Operand: "n"})
+	}
+	return ops
+}
+
+// operations returns the operations drawing `img` with the changes applied.
+func (img *Image) operations() []*contentstream.ContentStreamOperation {
+	if !img.modified() {
+		return []*contentstream.ContentStreamOperation{img.op}
+	}
+	if img.deleted {
+		return nil
+	}
+	ops := img.object.begin()
+	ops = append(ops, img.op)
+	return append(ops, &contentstream.ContentStreamOperation{Operand: "Q"})
+}
+
+// begin returns the operations saving the graphics state and applying the color changes and the
+// move of `o`. They are to be followed by the operations drawing the object and a Q operation.
+func (o *object) begin() []*contentstream.ContentStreamOperation {
+	ops := []*contentstream.ContentStreamOperation{{Operand: "q"}}
+	if o.fillColor != nil {
+		ops = append(ops, o.fillColor)
+	}
+	if o.strokeColor != nil {
+		ops = append(ops, o.strokeColor)
+	}
+	if o.moved() {
+		ops = append(ops, &contentstream.ContentStreamOperation{
+			Operand: "cm",
+			Params:  matrixParams(o.moveMatrix()),
+		})
+	}
+	return ops
+}
+
+// matrixParams returns the parameters of an operation specifying the matrix `m`.
+func matrixParams(m transform.Matrix) []core.PdfObject {
+	return []core.PdfObject{
+		core.MakeFloat(m[0]), core.MakeFloat(m[1]),
+		core.MakeFloat(m[3]), core.MakeFloat(m[4]),
+		core.MakeFloat(m[6]), core.MakeFloat(m[7]),
+	}
+}
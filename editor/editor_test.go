@@ -0,0 +1,253 @@
+package editor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/extractor"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// makeTestPage returns a page with two lines of text, an image and two rectangles, the second of
+// which is drawn with a scaled CTM. The content is generated with a ContentCreator, as the editor
+// regenerates it.
+func makeTestPage(t *testing.T) *model.PdfPage {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+
+	ximg, err := model.NewXObjectImageFromImage(&model.Image{
+		Width:            4,
+		Height:           4,
+		BitsPerComponent: 8,
+		ColorComponents:  1,
+		Data:             bytes.Repeat([]byte{0xff}, 4*4),
+	}, model.NewPdfColorspaceDeviceGray(), core.NewFlateEncoder())
+	require.NoError(t, err)
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	require.NoError(t, page.Resources.SetXObjectImageByName("Im1", ximg))
+
+	cc := contentstream.NewContentCreator().
+		Add_BT().Add_Tf("F1", 12).Add_TL(14).Add_Td(50, 700).
+		Add_Tj(*core.MakeString("Hello world")).Add_Tstar().
+		Add_Tj(*core.MakeString("Second line")).Add_ET().
+		Add_q().Add_cm(100, 0, 0, 100, 300, 300).Add_Do("Im1").Add_Q().
+		Add_rg(0, 0, 1).Add_re(50, 100, 50, 50).Add_f().
+		Add_q().Add_cm(2, 0, 0, 2, 0, 0).Add_rg(1, 0, 0).Add_re(100, 50, 25, 25).Add_f().Add_Q()
+	require.NoError(t, page.SetContentStreams([]string{cc.String()}, nil))
+	return page
+}
+
+// extractText returns the text of `page` and its text marks.
+func extractText(t *testing.T, page *model.PdfPage) (string, []extractor.TextMark) {
+	ex, err := extractor.New(page)
+	require.NoError(t, err)
+	pageText, _, _, err := ex.ExtractPageText()
+	require.NoError(t, err)
+	return pageText.Text(), pageText.Marks().Elements()
+}
+
+// findMark returns the first text mark of `marks` with text `text`.
+func findMark(t *testing.T, marks []extractor.TextMark, text string) extractor.TextMark {
+	for _, mark := range marks {
+		if mark.Text == text {
+			return mark
+		}
+	}
+	require.Failf(t, "mark not found", "text=%q", text)
+	return extractor.TextMark{}
+}
+
+func TestEditorObjects(t *testing.T) {
+	page := makeTestPage(t)
+	e, err := New(page)
+	require.NoError(t, err)
+	require.Len(t, e.Objects(), 5)
+
+	runs := e.TextRuns()
+	require.Len(t, runs, 2)
+	assert.Equal(t, "Hello world", runs[0].Text())
+	assert.Equal(t, "Second line", runs[1].Text())
+	assert.Equal(t, 12.0, runs[0].FontSize())
+	require.NotNil(t, runs[0].Font())
+	assert.Equal(t, "Helvetica", runs[0].Font().BaseFont())
+	bbox := runs[1].BBox()
+	assert.InDelta(t, 50, bbox.Llx, 1e-6)
+	assert.InDelta(t, 686-0.2*12, bbox.Lly, 1)
+
+	images := e.Images()
+	require.Len(t, images, 1)
+	assert.Equal(t, core.PdfObjectName("Im1"), images[0].Name())
+	assert.False(t, images[0].IsInline())
+	assert.Equal(t, model.PdfRectangle{Llx: 300, Lly: 300, Urx: 400, Ury: 400}, images[0].BBox())
+
+	paths := e.Paths()
+	require.Len(t, paths, 2)
+	assert.Equal(t, "f", paths[0].Operator())
+	assert.Equal(t, model.PdfRectangle{Llx: 50, Lly: 100, Urx: 100, Ury: 150}, paths[0].BBox())
+	assert.Equal(t, model.PdfRectangle{Llx: 200, Lly: 100, Urx: 250, Ury: 150}, paths[1].BBox())
+
+	// Unchanged content is regenerated as it was.
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	orig, err := contentstream.NewContentStreamParser(contents).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, string(orig.Bytes()), string(e.Bytes()))
+}
+
+func TestEditorReplaceText(t *testing.T) {
+	page := makeTestPage(t)
+	e, err := New(page)
+	require.NoError(t, err)
+
+	n, err := e.ReplaceText("world", "there")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = e.ReplaceText("missing", "text")
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Helvetica has no glyph for CJK characters.
+	err = e.TextRuns()[1].SetText("第二")
+	assert.Equal(t, ErrGlyphNotInFont, err)
+	assert.Equal(t, "Second line", e.TextRuns()[1].Text())
+
+	require.NoError(t, e.Apply())
+	text, marks := extractText(t, page)
+	assert.Equal(t, "Hello there\nSecond line", text)
+	assert.InDelta(t, 686, findMark(t, marks, "S").BBox.Lly, 3)
+}
+
+func TestEditorModifyObjects(t *testing.T) {
+	page := makeTestPage(t)
+	e, err := New(page)
+	require.NoError(t, err)
+
+	runs := e.TextRuns()
+	runs[0].Delete()
+	runs[1].Move(100, -200)
+	require.NoError(t, runs[1].SetFillColor(model.NewPdfColorDeviceRGB(1, 0, 0)))
+
+	images := e.Images()
+	images[0].Move(-200, 0)
+
+	paths := e.Paths()
+	paths[0].Delete()
+	require.NoError(t, paths[1].SetFillColor(model.NewPdfColorDeviceGray(0.5)))
+	paths[1].Move(10, 20)
+
+	require.NoError(t, e.Apply())
+
+	text, marks := extractText(t, page)
+	assert.Equal(t, "Second line", text)
+	mark := findMark(t, marks, "S")
+	assert.InDelta(t, 150, mark.BBox.Llx, 1)
+	assert.InDelta(t, 486, mark.BBox.Lly, 3)
+
+	// Reparse the regenerated content and check the positions of the objects.
+	e, err = New(page)
+	require.NoError(t, err)
+	require.Len(t, e.TextRuns(), 1)
+	require.Len(t, e.Images(), 1)
+	assert.Equal(t, model.PdfRectangle{Llx: 100, Lly: 300, Urx: 200, Ury: 400}, e.Images()[0].BBox())
+	require.Len(t, e.Paths(), 1)
+	bbox := e.Paths()[0].BBox()
+	assert.InDelta(t, 210, bbox.Llx, 1e-6)
+	assert.InDelta(t, 120, bbox.Lly, 1e-6)
+	assert.InDelta(t, 260, bbox.Urx, 1e-6)
+	assert.InDelta(t, 170, bbox.Ury, 1e-6)
+	content := string(e.Bytes())
+	assert.Contains(t, content, "1 0 0 rg")
+	assert.Contains(t, content, "0.5 g")
+}
+
+func TestEditorConvertTextToOutlines(t *testing.T) {
+	page := makeTestPage(t)
+	font, err := model.NewPdfFontFromTTFFile("../model/testdata/font/OpenSans-Regular.ttf")
+	require.NoError(t, err)
+	require.NoError(t, page.Resources.SetFontByName("F2", font.ToPdfObject()))
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	contents += "\nBT /F2 20 Tf 2 Tr 0 1 0 RG 1 0 0 1 50 500 Tm 2 Tc (Outlined) Tj [(Te) 200 (xt)] TJ ET"
+	require.NoError(t, page.SetContentStreams([]string{contents}, nil))
+
+	e, err := New(page)
+	require.NoError(t, err)
+	runs := e.TextRuns()
+	require.Len(t, runs, 4)
+	bbox := rectUnion(runs[2].BBox(), runs[3].BBox())
+
+	// The standard 14 fonts are not embedded.
+	n, err := e.ConvertTextToOutlines()
+	assert.Equal(t, ErrNoGlyphOutlines, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, e.Apply())
+
+	text, _ := extractText(t, page)
+	assert.Equal(t, "Hello world\nSecond line", text)
+	_, ok := page.Resources.GetFontByName("F1")
+	assert.True(t, ok)
+	_, ok = page.Resources.GetFontByName("F2")
+	assert.False(t, ok)
+
+	e, err = New(page)
+	require.NoError(t, err)
+	require.Len(t, e.TextRuns(), 2)
+	content := string(e.Bytes())
+	assert.NotContains(t, content, "/F2")
+	assert.Contains(t, content, "0 1 0 RG")
+
+	// The glyphs are filled and stroked and have the extent of the text.
+	paths := e.Paths()
+	require.Len(t, paths, 14)
+	outlines := paths[2].BBox()
+	for _, path := range paths[2:] {
+		assert.Equal(t, "B", path.Operator())
+		outlines = rectUnion(outlines, path.BBox())
+	}
+	assert.InDelta(t, bbox.Llx, outlines.Llx, 3)
+	assert.InDelta(t, bbox.Urx, outlines.Urx, 3)
+	assert.InDelta(t, 500, outlines.Lly, 1)
+	assert.InDelta(t, 500+0.714*20, outlines.Ury, 1)
+}
+
+func TestEditorConvertRotatedTextToOutlines(t *testing.T) {
+	font, err := model.NewPdfFontFromTTFFile("../model/testdata/font/OpenSans-Regular.ttf")
+	require.NoError(t, err)
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	content := "BT /F1 20 Tf 0 1 -1 0 300 300 Tm (Rotated) Tj ET"
+	require.NoError(t, page.SetContentStreams([]string{content}, nil))
+
+	e, err := New(page)
+	require.NoError(t, err)
+	require.Len(t, e.TextRuns(), 1)
+	bbox := e.TextRuns()[0].BBox()
+	n, err := e.ConvertTextToOutlines()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.NoError(t, e.Apply())
+
+	// The outlines of the text rotated by 90 degrees run upwards from the origin of the text and
+	// lie within the extent of the text.
+	e, err = New(page)
+	require.NoError(t, err)
+	paths := e.Paths()
+	require.NotEmpty(t, paths)
+	outlines := paths[0].BBox()
+	for _, path := range paths {
+		outlines = rectUnion(outlines, path.BBox())
+	}
+	assert.GreaterOrEqual(t, outlines.Llx, bbox.Llx-1)
+	assert.LessOrEqual(t, outlines.Urx, bbox.Urx+1)
+	assert.InDelta(t, 300, outlines.Lly, 3)
+	assert.InDelta(t, bbox.Ury, outlines.Ury, 3)
+}
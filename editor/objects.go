@@ -0,0 +1,283 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// ErrGlyphNotInFont is returned when replacing text with text that cannot be drawn with the font
+// of a text run, e.g. because the glyphs are missing from an embedded font subset.
+var ErrGlyphNotInFont = errors.New("glyph not in font")
+
+// ErrNoGlyphOutlines is returned when converting text to outlines if the font of a text run has no
+// embedded glyph outlines, e.g. for fonts which are not embedded and Type 3 fonts.
+var ErrNoGlyphOutlines = errors.New("no glyph outlines in font")
+
+// Object is an editable object of the content of a page.
+type Object interface {
+	// BBox returns the bounding box of the object in page coordinates, before any move.
+	BBox() model.PdfRectangle
+
+	// Delete removes the object from the page content.
+	Delete()
+
+	// Move translates the object by `dx`,`dy` in page coordinates.
+	Move(dx, dy float64)
+
+	// SetFillColor sets the nonstroking color of the object. Only DeviceGray, DeviceRGB and
+	// DeviceCMYK colors are supported.
+	SetFillColor(color model.PdfColor) error
+
+	// SetStrokeColor sets the stroking color of the object. Only DeviceGray, DeviceRGB and
+	// DeviceCMYK colors are supported.
+	SetStrokeColor(color model.PdfColor) error
+}
+
+// object contains the state common to all editable objects.
+type object struct {
+	bbox model.PdfRectangle
+	ctm  transform.Matrix // The current transformation matrix of the object.
+
+	deleted     bool
+	dx, dy      float64
+	fillColor   *contentstream.ContentStreamOperation
+	strokeColor *contentstream.ContentStreamOperation
+}
+
+// BBox implements Object.
+func (o *object) BBox() model.PdfRectangle {
+	return o.bbox
+}
+
+// Delete implements Object.
+func (o *object) Delete() {
+	o.deleted = true
+}
+
+// Move implements Object.
+func (o *object) Move(dx, dy float64) {
+	o.dx += dx
+	o.dy += dy
+}
+
+// SetFillColor implements Object.
+func (o *object) SetFillColor(color model.PdfColor) error {
+	op, err := colorOperation(color, false)
+	if err != nil {
+		return err
+	}
+	o.fillColor = op
+	return nil
+}
+
+// SetStrokeColor implements Object.
+func (o *object) SetStrokeColor(color model.PdfColor) error {
+	op, err := colorOperation(color, true)
+	if err != nil {
+		return err
+	}
+	o.strokeColor = op
+	return nil
+}
+
+// moved returns true if the object has been moved.
+func (o *object) moved() bool {
+	return o.dx != 0 || o.dy != 0
+}
+
+// recolored returns true if the color of the object has been changed.
+func (o *object) recolored() bool {
+	return o.fillColor != nil || o.strokeColor != nil
+}
+
+// modified returns true if the object has been changed in any way.
+func (o *object) modified() bool {
+	return o.deleted || o.moved() || o.recolored()
+}
+
+// moveMatrix returns the matrix which, concatenated to the current transformation matrix of the
+// object, translates the object by its move in page coordinates.
+func (o *object) moveMatrix() transform.Matrix {
+	inv, ok := o.ctm.Inverse()
+	if !ok {
+		return transform.IdentityMatrix()
+	}
+	// M × CTM = CTM × T  =>  M = CTM × T × CTM⁻¹
+	return inv.Mult(transform.TranslationMatrix(o.dx, o.dy)).Mult(o.ctm)
+}
+
+// TextRun is the text drawn by a single text showing operation (Tj, TJ, ' or ").
+type TextRun struct {
+	object
+
+	text     string
+	font     *model.PdfFont
+	fontName core.PdfObjectName // The resource name of the font.
+	fontSize float64
+	state    contentutil.TextState // The text state at the start of the run.
+
+	op    *contentstream.ContentStreamOperation // The text showing operation.
+	block *textBlock                            // The text object (BT/ET) containing the operation.
+	tm    transform.Matrix                      // The text matrix at the start of the run.
+
+	// Color operations in effect for the run, used for restoring the colors after recoloring.
+	fillOps   []*contentstream.ContentStreamOperation
+	strokeOps []*contentstream.ContentStreamOperation
+
+	replaced []byte // Character codes of the replacement text, if the text was replaced.
+	outlined bool   // True if the run is drawn with the outlines of its glyphs.
+}
+
+// Text returns the text of the run.
+func (t *TextRun) Text() string {
+	return t.text
+}
+
+// Font returns the font used for drawing the run. It is nil if the font could not be loaded.
+func (t *TextRun) Font() *model.PdfFont {
+	return t.font
+}
+
+// FontSize returns the font size used for drawing the run.
+func (t *TextRun) FontSize() float64 {
+	return t.fontSize
+}
+
+// SetText replaces the text of the run with `text`. The text is drawn with the font of the run,
+// starting at the same position. Returns ErrGlyphNotInFont if the font does not contain the
+// glyphs needed for drawing `text`. For embedded font subsets, this is the case for glyphs that
+// were not included in the subset.
+func (t *TextRun) SetText(text string) error {
+	if t.font == nil {
+		return errors.New("font not loaded")
+	}
+	data, numMisses := t.font.StringToCharcodeBytes(text)
+	if numMisses > 0 {
+		return ErrGlyphNotInFont
+	}
+	if decoded, _, _ := t.font.CharcodeBytesToUnicode(data); decoded != text {
+		return ErrGlyphNotInFont
+	}
+	for _, code := range t.font.BytesToCharcodes(data) {
+		metrics, ok := t.font.GetCharMetrics(code)
+		if !ok {
+			return ErrGlyphNotInFont
+		}
+		// Font subsets only have widths for the glyphs in the subset.
+		if t.font.IsSubset() && metrics.Wx == 0 {
+			return ErrGlyphNotInFont
+		}
+	}
+	t.replaced = data
+	t.text = text
+	return nil
+}
+
+// ConvertToOutlines replaces the run with paths drawing the outlines of its glyphs, painted
+// according to the text rendering mode of the run. The outlines are read from the embedded font
+// program of the font of the run. Returns ErrNoGlyphOutlines if the font has no glyph outlines or
+// is used for vertical writing.
+func (t *TextRun) ConvertToOutlines() error {
+	if t.font == nil || t.font.IsVertical() {
+		return ErrNoGlyphOutlines
+	}
+	if t.block == nil {
+		return errors.New("text run outside text object")
+	}
+	var err error
+	contentutil.LayoutGlyphs(t.state, t.tm, t.items(), func(g contentutil.Glyph, m transform.Matrix, advance float64) {
+		if _, ok := t.font.GlyphOutline(g.Code); !ok {
+			err = ErrNoGlyphOutlines
+		}
+	}, nil)
+	if err != nil {
+		return err
+	}
+	t.outlined = true
+	return nil
+}
+
+// items returns the strings and TJ position adjustments drawn by the run.
+func (t *TextRun) items() []core.PdfObject {
+	if t.replaced != nil {
+		return []core.PdfObject{contentutil.MakeString(t.font, t.replaced)}
+	}
+	switch t.op.Operand {
+	case "TJ":
+		if arr, ok := core.GetArray(t.op.Params[0]); ok {
+			return arr.Elements()
+		}
+		return nil
+	case "\"":
+		return t.op.Params[2:]
+	}
+	return t.op.Params
+}
+
+// modified returns true if the run has been changed in any way.
+func (t *TextRun) modified() bool {
+	return t.object.modified() || t.replaced != nil || t.outlined
+}
+
+// Image is an image drawn by an image XObject or an inline image.
+type Image struct {
+	object
+
+	name core.PdfObjectName                    // The name of the image XObject, empty for inline images.
+	op   *contentstream.ContentStreamOperation // The Do or BI operation.
+}
+
+// Name returns the resource name of the image XObject, or an empty name for inline images.
+func (img *Image) Name() core.PdfObjectName {
+	return img.name
+}
+
+// IsInline returns true if the image is an inline image.
+func (img *Image) IsInline() bool {
+	return img.name == ""
+}
+
+// Path is a path painted by a path painting operation.
+type Path struct {
+	object
+
+	ops     []*contentstream.ContentStreamOperation // The path construction operations.
+	clipOp  *contentstream.ContentStreamOperation   // The W or W* operation if the path is used for clipping.
+	paintOp *contentstream.ContentStreamOperation   // The path painting operation.
+}
+
+// Operator returns the path painting operator, e.g. "f" for filled paths.
+func (p *Path) Operator() string {
+	return p.paintOp.Operand
+}
+
+// colorOperation returns the operation setting the nonstroking (stroking if `stroking` is true)
+// color to `color`.
+func colorOperation(color model.PdfColor, stroking bool) (*contentstream.ContentStreamOperation, error) {
+	var operand string
+	var vals []float64
+	switch c := color.(type) {
+	case *model.PdfColorDeviceGray:
+		operand, vals = "g", []float64{c.Val()}
+	case *model.PdfColorDeviceRGB:
+		operand, vals = "rg", []float64{c.R(), c.G(), c.B()}
+	case *model.PdfColorDeviceCMYK:
+		operand, vals = "k", []float64{c.C(), c.M(), c.Y(), c.K()}
+	default:
+		return nil, fmt.Errorf("unsupported color %T", color)
+	}
+	if stroking {
+		operand = map[string]string{"g": "G", "rg": "RG", "k": "K"}[operand]
+	}
+	op := &contentstream.ContentStreamOperation{Operand: operand}
+	for _, v := range vals {
+		op.Params = append(op.Params, core.MakeFloat(v))
+	}
+	return op, nil
+}
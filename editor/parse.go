@@ -0,0 +1,253 @@
+package editor
+
+import (
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// graphicsState is the part of the graphics state tracked while parsing the page content.
+type graphicsState struct {
+	ctm transform.Matrix
+
+	contentutil.TextState
+
+	// Operations setting the current nonstroking and stroking colors.
+	fillOps   []*contentstream.ContentStreamOperation
+	strokeOps []*contentstream.ContentStreamOperation
+}
+
+// parser splits the page content into segments.
+type parser struct {
+	e *Editor
+
+	gs    graphicsState
+	stack []graphicsState
+	text  contentutil.TextMatrices // The text matrices of the current text object.
+	block *textBlock               // The current text object.
+
+	path     *Path
+	pathBBox model.PdfRectangle
+}
+
+// parse splits the content stream operations `ops` into segments and editable objects.
+func (e *Editor) parse(ops contentstream.ContentStreamOperations) error {
+	p := &parser{
+		e:    e,
+		gs:   graphicsState{ctm: transform.IdentityMatrix(), TextState: contentutil.NewTextState()},
+		text: contentutil.NewTextMatrices(),
+	}
+	for _, op := range ops {
+		if err := p.process(op); err != nil {
+			return err
+		}
+	}
+	p.flushPath()
+	return nil
+}
+
+// add adds a segment for `op` or `obj`.
+func (p *parser) add(op *contentstream.ContentStreamOperation, obj Object) {
+	p.e.segments = append(p.e.segments, &segment{op: op, obj: obj})
+	if obj != nil {
+		p.e.objects = append(p.e.objects, obj)
+	}
+}
+
+// process processes the content stream operation `op`.
+func (p *parser) process(op *contentstream.ContentStreamOperation) error {
+	switch op.Operand {
+	case "m", "l", "c", "v", "y", "h", "re":
+		p.addPathOp(op)
+		return nil
+	case "W", "W*":
+		if p.path == nil {
+			break
+		}
+		p.path.clipOp = op
+		return nil
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+		if p.path == nil {
+			break
+		}
+		p.path.paintOp = op
+		p.path.bbox = p.pathBBox
+		p.add(nil, p.path)
+		p.path = nil
+		return nil
+	}
+
+	// Paths which are not painted (n operator or incomplete paths) are not editable objects.
+	p.flushPath()
+
+	switch op.Operand {
+	case "q":
+		p.stack = append(p.stack, p.gs)
+	case "Q":
+		if len(p.stack) > 0 {
+			p.gs = p.stack[len(p.stack)-1]
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+	case "cm":
+		m, err := contentutil.OperandMatrix(op)
+		if err != nil {
+			return err
+		}
+		p.gs.ctm = p.gs.ctm.Mult(m)
+	case "g", "rg", "k", "sc", "scn":
+		p.gs.fillOps = colorOps(p.gs.fillOps, op, "cs")
+	case "cs":
+		p.gs.fillOps = []*contentstream.ContentStreamOperation{op}
+	case "G", "RG", "K", "SC", "SCN":
+		p.gs.strokeOps = colorOps(p.gs.strokeOps, op, "CS")
+	case "CS":
+		p.gs.strokeOps = []*contentstream.ContentStreamOperation{op}
+	case "BT":
+		p.text = contentutil.NewTextMatrices()
+		p.block = &textBlock{}
+		p.e.segments = append(p.e.segments, &segment{op: op, block: p.block})
+		return nil
+	case "ET":
+		p.block = nil
+	case "Tj", "TJ", "'", "\"":
+		return p.showText(op)
+	case "BI":
+		p.add(nil, p.newImage(op, ""))
+		return nil
+	case "Do":
+		if len(op.Params) == 1 && p.e.resources != nil {
+			if name, ok := core.GetName(op.Params[0]); ok {
+				if _, xtype := p.e.resources.GetXObjectByName(*name); xtype == model.XObjectTypeImage {
+					p.add(nil, p.newImage(op, *name))
+					return nil
+				}
+			}
+		}
+	default:
+		if _, err := contentutil.ProcessTextOp(op, &p.gs.TextState, &p.text, p.e.resources, p.e.fonts); err != nil {
+			return err
+		}
+	}
+
+	p.add(op, nil)
+	return nil
+}
+
+// colorOps returns the color operations in effect after the color operation `op`, given the
+// color operations `current` in effect before it. `csOperand` is the operator which sets the
+// color space for the color operations of `op`.
+func colorOps(current []*contentstream.ContentStreamOperation, op *contentstream.ContentStreamOperation,
+	csOperand string) []*contentstream.ContentStreamOperation {
+	switch op.Operand {
+	case "sc", "scn", "SC", "SCN":
+		// The color space set by cs or CS remains in effect.
+		if len(current) > 0 && current[0].Operand == csOperand {
+			return []*contentstream.ContentStreamOperation{current[0], op}
+		}
+	}
+	return []*contentstream.ContentStreamOperation{op}
+}
+
+// addPathOp adds the path construction operation `op` to the current path.
+func (p *parser) addPathOp(op *contentstream.ContentStreamOperation) {
+	if p.path == nil {
+		p.path = &Path{object: object{ctm: p.gs.ctm}}
+		p.pathBBox = emptyRect()
+	}
+	p.path.ops = append(p.path.ops, op)
+
+	vals, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		common.Log.Debug("ERROR: invalid path operation %s: %v", op.Operand, err)
+		return
+	}
+	addPoint := func(x, y float64) {
+		x, y = p.gs.ctm.TransformPoint(x, y)
+		p.pathBBox = extendRect(p.pathBBox, x, y)
+	}
+	if op.Operand == "re" && len(vals) == 4 {
+		addPoint(vals[0], vals[1])
+		addPoint(vals[0]+vals[2], vals[1]+vals[3])
+		addPoint(vals[0]+vals[2], vals[1])
+		addPoint(vals[0], vals[1]+vals[3])
+		return
+	}
+	for i := 0; i+1 < len(vals); i += 2 {
+		addPoint(vals[i], vals[i+1])
+	}
+}
+
+// flushPath adds the operations of the current path, if any, as plain segments.
+func (p *parser) flushPath() {
+	if p.path == nil {
+		return
+	}
+	for _, op := range p.path.ops {
+		p.add(op, nil)
+	}
+	if p.path.clipOp != nil {
+		p.add(p.path.clipOp, nil)
+	}
+	p.path = nil
+}
+
+// newImage returns an Image for the image drawn by `op`.
+func (p *parser) newImage(op *contentstream.ContentStreamOperation, name core.PdfObjectName) *Image {
+	llx, lly, urx, ury := p.gs.ctm.TransformBBox(0, 0, 1, 1)
+	return &Image{
+		object: object{
+			ctm:  p.gs.ctm,
+			bbox: model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: ury},
+		},
+		name: name,
+		op:   op,
+	}
+}
+
+// showText adds a TextRun for the text showing operation `op`.
+func (p *parser) showText(op *contentstream.ContentStreamOperation) error {
+	items, err := contentutil.TextShowItems(op, &p.gs.TextState, &p.text)
+	if err != nil {
+		return err
+	}
+
+	run := &TextRun{
+		object:    object{ctm: p.gs.ctm, bbox: emptyRect()},
+		font:      p.gs.Font,
+		fontName:  p.gs.FontName,
+		fontSize:  p.gs.Tfs,
+		state:     p.gs.TextState,
+		op:        op,
+		block:     p.block,
+		tm:        p.text.Tm,
+		fillOps:   p.gs.fillOps,
+		strokeOps: p.gs.strokeOps,
+	}
+
+	ascent, descent := contentutil.FontExtent(p.gs.Font)
+	p.text.Tm = contentutil.LayoutGlyphs(p.gs.TextState, p.text.Tm, items,
+		func(g contentutil.Glyph, m transform.Matrix, advance float64) {
+			llx, lly, urx, ury := p.gs.ctm.Mult(m).TransformBBox(0, descent, g.Width, ascent)
+			run.bbox = rectUnion(run.bbox, model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: ury})
+		}, nil)
+
+	var text []byte
+	for _, item := range items {
+		if data, ok := core.GetStringBytes(item); ok {
+			text = append(text, data...)
+		}
+	}
+	run.text = contentutil.DecodeText(p.gs.Font, text)
+	if run.bbox.Llx > run.bbox.Urx {
+		// No glyphs.
+		run.bbox = model.PdfRectangle{}
+	}
+	if p.block != nil {
+		p.block.runs = append(p.block.runs, run)
+	}
+	p.add(nil, run)
+	return nil
+}
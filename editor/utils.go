@@ -0,0 +1,31 @@
+package editor
+
+import (
+	"math"
+
+	"github.com/moolekkari/unipdf/model"
+)
+
+// emptyRect returns a rectangle which contains no points, for use with extendRect and rectUnion.
+func emptyRect() model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Inf(1), Lly: math.Inf(1),
+		Urx: math.Inf(-1), Ury: math.Inf(-1),
+	}
+}
+
+// extendRect returns the smallest rectangle that contains `rect` and the point `x`,`y`.
+func extendRect(rect model.PdfRectangle, x, y float64) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(rect.Llx, x), Lly: math.Min(rect.Lly, y),
+		Urx: math.Max(rect.Urx, x), Ury: math.Max(rect.Ury, y),
+	}
+}
+
+// rectUnion returns the smallest rectangle that contains `a` and `b`.
+func rectUnion(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx), Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx), Ury: math.Max(a.Ury, b.Ury),
+	}
+}
@@ -0,0 +1,194 @@
+package extractor
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// DocumentExtractOptions contains options for controlling the extraction of
+// content from a range of pages of a PDF document.
+type DocumentExtractOptions struct {
+	// FirstPage and LastPage specify the (1-based, inclusive) range of pages
+	// to extract. Zero values default to the first and last page of the document.
+	FirstPage int
+	LastPage  int
+
+	// Workers is the maximum number of pages processed concurrently.
+	// Defaults to the number of CPUs if not positive.
+	Workers int
+
+	// ExtractText enables extraction of the page text and text marks.
+	ExtractText bool
+
+	// ExtractImages enables extraction of the page images, using ImageOptions.
+	ExtractImages bool
+	ImageOptions  *ImageExtractOptions
+}
+
+// PageResult represents the content extracted from a single page of a document.
+type PageResult struct {
+	// PageNum is the (1-based) number of the page.
+	PageNum int
+
+	// Text contains the page text and marks, if text extraction is enabled.
+	Text      *PageText
+	NumChars  int
+	NumMisses int
+
+	// Images contains the page images, if image extraction is enabled.
+	Images *PageImages
+
+	// Err is the error that occurred while processing the page, if any.
+	// The results of the other pages are not affected.
+	Err error
+}
+
+// ExtractDocument extracts the content of the pages of `reader`, according to
+// `options`. The pages are processed concurrently by a bounded pool of workers
+// and the results are sent on the returned channel as they complete, which is
+// not necessarily in page order. The channel is closed once all the pages have
+// been processed. Errors encountered while processing a page are returned in
+// its PageResult and do not abort the extraction of the other pages.
+// Fonts are cached across pages, so that fonts shared by multiple pages are
+// only loaded once. A nil `options` extracts the text of all pages.
+//
+// The pages are loaded from `reader` by the workers, see model.PdfReader for
+// the use of a reader by multiple goroutines.
+func ExtractDocument(reader *model.PdfReader, options *DocumentExtractOptions) (<-chan *PageResult, error) {
+	if reader == nil {
+		return nil, errors.New("reader not specified")
+	}
+	if options == nil {
+		options = &DocumentExtractOptions{ExtractText: true}
+	}
+
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := options.FirstPage, options.LastPage
+	if first <= 0 {
+		first = 1
+	}
+	if last <= 0 || last > numPages {
+		last = numPages
+	}
+	if first > last {
+		return nil, errors.New("invalid page range")
+	}
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if pages := last - first + 1; workers > pages {
+		workers = pages
+	}
+
+	fonts := newDocumentFontCache()
+	pageNums := make(chan int, workers)
+	results := make(chan *PageResult, workers)
+
+	go func() {
+		defer close(pageNums)
+		for pageNum := first; pageNum <= last; pageNum++ {
+			pageNums <- pageNum
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pageNum := range pageNums {
+				job, err := loadPageJob(reader, pageNum)
+				if err != nil {
+					results <- &PageResult{PageNum: pageNum, Err: err}
+					continue
+				}
+				job.extractor.docFonts = fonts
+				results <- job.process(options)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// pageJob represents a page loaded for extraction.
+type pageJob struct {
+	pageNum   int
+	extractor *Extractor
+}
+
+// loadPageJob loads page `pageNum` of `reader` for extraction.
+func loadPageJob(reader *model.PdfReader, pageNum int) (*pageJob, error) {
+	page, err := reader.GetPage(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := New(page)
+	if err != nil {
+		return nil, err
+	}
+	return &pageJob{pageNum: pageNum, extractor: e}, nil
+}
+
+// process extracts the content of the page, according to `options`.
+func (job *pageJob) process(options *DocumentExtractOptions) *PageResult {
+	result := &PageResult{PageNum: job.pageNum}
+	if options.ExtractText {
+		result.Text, result.NumChars, result.NumMisses, result.Err = job.extractor.ExtractPageText()
+		if result.Err != nil {
+			common.Log.Debug("ERROR: page %d text extraction failed. err=%v", job.pageNum, result.Err)
+			return result
+		}
+	}
+	if options.ExtractImages {
+		result.Images, result.Err = job.extractor.ExtractPageImages(options.ImageOptions)
+		if result.Err != nil {
+			common.Log.Debug("ERROR: page %d image extraction failed. err=%v", job.pageNum, result.Err)
+			return result
+		}
+	}
+	return result
+}
+
+// documentFontCache is a font cache which is shared by the page extractors of
+// a document. It is safe for concurrent use.
+type documentFontCache struct {
+	sync.RWMutex
+	fonts map[core.PdfObject]*model.PdfFont
+}
+
+func newDocumentFontCache() *documentFontCache {
+	return &documentFontCache{fonts: map[core.PdfObject]*model.PdfFont{}}
+}
+
+// get returns the font loaded from the font object `fontObj`, if cached.
+func (c *documentFontCache) get(fontObj core.PdfObject) (*model.PdfFont, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	font, ok := c.fonts[fontObj]
+	return font, ok
+}
+
+// set caches `font`, loaded from the font object `fontObj`.
+func (c *documentFontCache) set(fontObj core.PdfObject, font *model.PdfFont) {
+	c.Lock()
+	defer c.Unlock()
+	c.fonts[fontObj] = font
+}
@@ -0,0 +1,125 @@
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/creator"
+	"github.com/moolekkari/unipdf/model"
+	"github.com/moolekkari/unipdf/model/optimize"
+)
+
+// TestExtractDocument tests concurrent extraction of a page range of a document.
+func TestExtractDocument(t *testing.T) {
+	const numPages = 8
+
+	c := creator.New()
+	for i := 1; i <= numPages; i++ {
+		c.NewPage()
+		require.NoError(t, c.Draw(c.NewParagraph(fmt.Sprintf("Page %d", i))))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	results, err := ExtractDocument(reader, &DocumentExtractOptions{
+		FirstPage:     2,
+		LastPage:      7,
+		Workers:       3,
+		ExtractText:   true,
+		ExtractImages: true,
+	})
+	require.NoError(t, err)
+
+	var pages []*PageResult
+	for result := range results {
+		require.NoError(t, result.Err)
+		pages = append(pages, result)
+	}
+	require.Len(t, pages, 6)
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].PageNum < pages[j].PageNum
+	})
+	for i, page := range pages {
+		assert.Equal(t, i+2, page.PageNum)
+		assert.Equal(t, fmt.Sprintf("Page %d", i+2), page.Text.Text())
+		assert.NotEmpty(t, page.Text.Marks().Elements())
+		require.NotNil(t, page.Images)
+		assert.Empty(t, page.Images.Images)
+	}
+
+	// Invalid page range.
+	_, err = ExtractDocument(reader, &DocumentExtractOptions{FirstPage: 5, LastPage: 3})
+	assert.Error(t, err)
+}
+
+// TestExtractDocumentConcurrentReader tests the extraction of the pages of a
+// document from multiple goroutines sharing a lazy reader, with the objects in
+// object streams. Run with -race.
+func TestExtractDocumentConcurrentReader(t *testing.T) {
+	const numPages = 12
+
+	c := creator.New()
+	c.SetOptimizer(optimize.New(optimize.Options{UseObjectStreams: true}))
+	for i := 1; i <= numPages; i++ {
+		c.NewPage()
+		require.NoError(t, c.Draw(c.NewParagraph(fmt.Sprintf("Page %d", i))))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	require.Contains(t, buf.String(), "/ObjStm")
+
+	reader, err := model.NewPdfReaderLazyWithOptions(bytes.NewReader(buf.Bytes()),
+		&model.LazyReaderOptions{CacheSize: 4096, LazyStreams: true})
+	require.NoError(t, err)
+
+	results, err := ExtractDocument(reader, &DocumentExtractOptions{
+		Workers:     4,
+		ExtractText: true,
+	})
+	require.NoError(t, err)
+	texts := map[int]string{}
+	for result := range results {
+		require.NoError(t, result.Err)
+		texts[result.PageNum] = result.Text.Text()
+	}
+
+	// Pages extracted directly with Extractor from other goroutines.
+	var wg sync.WaitGroup
+	direct := make([]string, numPages)
+	errs := make([]error, numPages)
+	for i := 0; i < numPages; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			page, err := reader.GetPage(i + 1)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			e, err := New(page)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			direct[i], errs[i] = e.ExtractText()
+		}(i)
+	}
+	wg.Wait()
+
+	require.Len(t, texts, numPages)
+	for i := 1; i <= numPages; i++ {
+		require.NoError(t, errs[i-1])
+		assert.Equal(t, fmt.Sprintf("Page %d", i), texts[i])
+		assert.Equal(t, fmt.Sprintf("Page %d", i), direct[i-1])
+	}
+}
@@ -10,10 +10,17 @@ type Extractor struct {
 	contents  string
 	resources *model.PdfPageResources
 
+	// page the contents were loaded from, used to access the page annotations.
+	page *model.PdfPage
+
 	// fontCache is a simple LRU cache that is used to prevent redundant constructions of PdfFont's from
 	// PDF objects. NOTE: This is not a conventional glyph cache. It only caches PdfFont's.
 	fontCache map[string]fontEntry
 
+	// docFonts is the font cache shared by the pages of a document, when
+	// extracting through ExtractDocument. Fonts are cached by font object.
+	docFonts *documentFontCache
+
 	// text results from running extractXYText on forms within the page.
 	// TODO(peterwilliams): Cache this map accross all pages in a PDF to speed up processig.
 	formResults map[string]textResult
@@ -40,6 +47,7 @@ func New(page *model.PdfPage) (*Extractor, error) {
 	e := &Extractor{
 		contents:    contents,
 		resources:   page.Resources,
+		page:        page,
 		fontCache:   map[string]fontEntry{},
 		formResults: map[string]textResult{},
 	}
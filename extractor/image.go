@@ -4,6 +4,8 @@ import (
 	"github.com/moolekkari/unipdf/common"
 	"github.com/moolekkari/unipdf/contentstream"
 	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
 	"github.com/moolekkari/unipdf/model"
 )
 
@@ -11,6 +13,22 @@ import (
 // PDF pages.
 type ImageExtractOptions struct {
 	IncludeInlineStencilMasks bool
+
+	// IncludeEncoded sets the Encoded and Filter fields of the extracted image
+	// XObjects, so that the image data can be saved without re-encoding
+	// (e.g. writing DCT encoded data directly to a JPEG file).
+	IncludeEncoded bool
+
+	// ApplyMasks merges the soft mask (SMask) or mask (Mask) of the extracted
+	// images into their alpha channel.
+	ApplyMasks bool
+
+	// IncludePatterns extracts the images drawn by tiling patterns.
+	IncludePatterns bool
+
+	// IncludeAnnotations extracts the images contained in the normal
+	// appearance streams of the page annotations.
+	IncludeAnnotations bool
 }
 
 // ExtractPageImages returns the image contents of the page extractor, including data
@@ -23,11 +41,23 @@ func (e *Extractor) ExtractPageImages(options *ImageExtractOptions) (*PageImages
 		options: options,
 	}
 
-	err := ctx.extractContentStreamImages(e.contents, e.resources)
+	err := ctx.extractContentStreamImages(e.contents, e.resources, transform.IdentityMatrix())
 	if err != nil {
 		return nil, err
 	}
 
+	if ctx.options.IncludeAnnotations && e.page != nil {
+		annotations, err := e.page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		for _, annotation := range annotations {
+			if err := ctx.extractAnnotationImages(annotation, e.resources); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &PageImages{
 		Images: ctx.extractedImages,
 	}, nil
@@ -54,6 +84,29 @@ type ImageMark struct {
 
 	// Angle in degrees, if rotated.
 	Angle float64
+
+	// CTM is the full transformation matrix mapping the unit square of the
+	// image to PDF coordinates.
+	CTM transform.Matrix
+
+	// Ref is the reference of the image XObject, which identifies the image
+	// when it is drawn more than once in the document. It is nil for inline images.
+	Ref *core.PdfObjectReference
+
+	// Encoded contains the image data compressed with Filter, as stored in
+	// the PDF. Any general purpose filters applied on top of the image codec
+	// are decoded. Only set for image XObjects, if the IncludeEncoded
+	// extraction option is enabled.
+	Encoded []byte
+
+	// Filter is the name of the filter that Encoded is compressed with,
+	// e.g. core.StreamEncodingFilterNameDCT. It is empty if the image data
+	// is not compressed.
+	Filter string
+
+	// DecodeParms contains the decode parameters of Filter, if any.
+	// They are required for decoding CCITTFax and JBIG2 encoded data.
+	DecodeParms *core.PdfObjectDictionary
 }
 
 // Provide context for image extraction content stream processing.
@@ -66,6 +119,9 @@ type imageExtractContext struct {
 	// Cache to avoid processing same image many times.
 	cacheXObjectImages map[*core.PdfObjectStream]*cachedImage
 
+	// Tiling patterns which have already been processed.
+	processedPatterns map[core.PdfObject]struct{}
+
 	// Extract options.
 	options *ImageExtractOptions
 }
@@ -73,9 +129,16 @@ type imageExtractContext struct {
 type cachedImage struct {
 	image *model.Image
 	cs    model.PdfColorspace
+	alpha []byte
+
+	encoded     []byte
+	filter      string
+	decodeParms *core.PdfObjectDictionary
 }
 
-func (ctx *imageExtractContext) extractContentStreamImages(contents string, resources *model.PdfPageResources) error {
+// extractContentStreamImages extracts the images of the content stream `contents`.
+// `ctm` maps the initial coordinate system of the content stream to PDF coordinates.
+func (ctx *imageExtractContext) extractContentStreamImages(contents string, resources *model.PdfPageResources, ctm transform.Matrix) error {
 	cstreamParser := contentstream.NewContentStreamParser(contents)
 	operations, err := cstreamParser.Parse()
 	if err != nil {
@@ -85,6 +148,9 @@ func (ctx *imageExtractContext) extractContentStreamImages(contents string, reso
 	if ctx.cacheXObjectImages == nil {
 		ctx.cacheXObjectImages = map[*core.PdfObjectStream]*cachedImage{}
 	}
+	if ctx.processedPatterns == nil {
+		ctx.processedPatterns = map[core.PdfObject]struct{}{}
+	}
 	if ctx.options == nil {
 		ctx.options = &ImageExtractOptions{}
 	}
@@ -92,14 +158,18 @@ func (ctx *imageExtractContext) extractContentStreamImages(contents string, reso
 	processor := contentstream.NewContentStreamProcessor(*operations)
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
-			return ctx.processOperand(op, gs, resources)
+			// The processor starts each content stream with an identity CTM.
+			// Map it to PDF coordinates.
+			gs.CTM = ctm.Mult(gs.CTM)
+			return ctx.processOperand(op, gs, resources, ctm)
 		})
 
 	return processor.Process(resources)
 }
 
 // Process individual content stream operands for image extraction.
-func (ctx *imageExtractContext) processOperand(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+// `baseCTM` is the transformation matrix at the start of the content stream.
+func (ctx *imageExtractContext) processOperand(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources, baseCTM transform.Matrix) error {
 	if op.Operand == "BI" && len(op.Params) == 1 {
 		// BI: Inline image.
 		iimg, ok := op.Params[0].(*contentstream.ContentStreamInlineImage)
@@ -129,6 +199,16 @@ func (ctx *imageExtractContext) processOperand(op *contentstream.ContentStreamOp
 		case model.XObjectTypeForm:
 			return ctx.extractFormImages(name, gs, resources)
 		}
+	} else if (op.Operand == "scn" || op.Operand == "SCN") && len(op.Params) > 0 {
+		// scn/SCN: Pattern color.
+		if !ctx.options.IncludePatterns {
+			return nil
+		}
+		name, ok := core.GetName(op.Params[len(op.Params)-1])
+		if !ok {
+			return nil
+		}
+		return ctx.extractPatternImages(name, resources, baseCTM)
 	}
 	return nil
 }
@@ -153,14 +233,7 @@ func (ctx *imageExtractContext) extractInlineImage(iimg *contentstream.ContentSt
 		return err
 	}
 
-	imgMark := ImageMark{
-		Image:  &rgbImg,
-		Width:  gs.CTM.ScalingFactorX(),
-		Height: gs.CTM.ScalingFactorY(),
-		Angle:  gs.CTM.Angle(),
-	}
-	imgMark.X, imgMark.Y = gs.CTM.Translation()
-
+	imgMark := newImageMark(&rgbImg, gs.CTM)
 	ctx.extractedImages = append(ctx.extractedImages, imgMark)
 	ctx.inlineImages++
 	return nil
@@ -192,6 +265,18 @@ func (ctx *imageExtractContext) extractXObjectImage(name *core.PdfObjectName, gs
 			image: img,
 			cs:    ximg.ColorSpace,
 		}
+		if ctx.options.ApplyMasks {
+			cimg.alpha, err = imageMaskAlpha(ximg, img)
+			if err != nil {
+				return err
+			}
+		}
+		if ctx.options.IncludeEncoded {
+			cimg.encoded, cimg.filter, cimg.decodeParms, err = encodedImageData(stream)
+			if err != nil {
+				return err
+			}
+		}
 		ctx.cacheXObjectImages[stream] = cimg
 	}
 	img := cimg.image
@@ -201,15 +286,21 @@ func (ctx *imageExtractContext) extractXObjectImage(name *core.PdfObjectName, gs
 	if err != nil {
 		return err
 	}
+	if cimg.alpha != nil {
+		setImageAlpha(&rgbImg, cimg.alpha)
+	}
 
 	common.Log.Debug("@Do CTM: %s", gs.CTM.String())
-	imgMark := ImageMark{
-		Image:  &rgbImg,
-		Width:  gs.CTM.ScalingFactorX(),
-		Height: gs.CTM.ScalingFactorY(),
-		Angle:  gs.CTM.Angle(),
+	imgMark := newImageMark(&rgbImg, gs.CTM)
+	if stream.ObjectNumber != 0 {
+		imgMark.Ref = &core.PdfObjectReference{
+			ObjectNumber:     stream.ObjectNumber,
+			GenerationNumber: stream.GenerationNumber,
+		}
 	}
-	imgMark.X, imgMark.Y = gs.CTM.Translation()
+	imgMark.Encoded = cimg.encoded
+	imgMark.Filter = cimg.filter
+	imgMark.DecodeParms = cimg.decodeParms
 
 	ctx.extractedImages = append(ctx.extractedImages, imgMark)
 	ctx.xObjectImages++
@@ -237,11 +328,371 @@ func (ctx *imageExtractContext) extractFormImages(name *core.PdfObjectName, gs c
 		formResources = resources
 	}
 
+	// The form matrix maps form space to the user space at the time of painting.
+	ctm := gs.CTM.Mult(contentutil.FormMatrix(xform))
+
 	// Process the content stream in the Form object too:
-	err = ctx.extractContentStreamImages(string(formContent), formResources)
+	err = ctx.extractContentStreamImages(string(formContent), formResources, ctm)
 	if err != nil {
 		return err
 	}
 	ctx.xObjectForms++
 	return nil
 }
+
+// extractPatternImages extracts the images in the content stream of the tiling
+// pattern specified by `name`. Each pattern is processed once, positioning
+// the pattern cell at the origin of the pattern space.
+func (ctx *imageExtractContext) extractPatternImages(name *core.PdfObjectName, resources *model.PdfPageResources, baseCTM transform.Matrix) error {
+	if resources == nil {
+		return nil
+	}
+	pattern, found := resources.GetPatternByName(*name)
+	if !found || !pattern.IsTiling() {
+		return nil
+	}
+	container := pattern.GetContainingPdfObject()
+	if _, processed := ctx.processedPatterns[container]; processed {
+		return nil
+	}
+	ctx.processedPatterns[container] = struct{}{}
+
+	tiling := pattern.GetAsTilingPattern()
+	content, err := tiling.GetContentStream()
+	if err != nil {
+		return err
+	}
+
+	// The pattern matrix maps pattern space to the initial coordinate space
+	// of the content stream the pattern is used in.
+	ctm := baseCTM
+	if tiling.Matrix != nil {
+		m, err := tiling.Matrix.ToFloat64Array()
+		if err == nil && len(m) == 6 {
+			ctm = baseCTM.Mult(transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5]))
+		} else {
+			common.Log.Debug("ERROR: invalid pattern matrix: %v", tiling.Matrix)
+		}
+	}
+
+	patternResources := tiling.Resources
+	if patternResources == nil {
+		patternResources = resources
+	}
+	return ctx.extractContentStreamImages(string(content), patternResources, ctm)
+}
+
+// extractAnnotationImages extracts the images in the normal appearance stream of `annotation`.
+func (ctx *imageExtractContext) extractAnnotationImages(annotation *model.PdfAnnotation, resources *model.PdfPageResources) error {
+	apDict, ok := core.GetDict(annotation.AP)
+	if !ok {
+		return nil
+	}
+
+	// The normal appearance is either a form XObject or a dictionary of
+	// appearance states, selected by the appearance state (AS) entry.
+	appearance := core.TraceToDirectObject(apDict.Get("N"))
+	if states, ok := appearance.(*core.PdfObjectDictionary); ok {
+		state, ok := core.GetName(annotation.AS)
+		if !ok {
+			return nil
+		}
+		appearance = core.TraceToDirectObject(states.Get(*state))
+	}
+	stream, ok := appearance.(*core.PdfObjectStream)
+	if !ok {
+		return nil
+	}
+
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		return err
+	}
+	rect, ok := core.GetArray(annotation.Rect)
+	if !ok {
+		return nil
+	}
+	annotRect, err := model.NewPdfRectangle(*rect)
+	if err != nil {
+		return err
+	}
+	bboxArr, ok := core.GetArray(xform.BBox)
+	if !ok {
+		return nil
+	}
+	bbox, err := model.NewPdfRectangle(*bboxArr)
+	if err != nil {
+		return err
+	}
+
+	formContent, err := xform.GetContentStream()
+	if err != nil {
+		return err
+	}
+	formResources := xform.Resources
+	if formResources == nil {
+		formResources = resources
+	}
+
+	ctm := appearanceMatrix(contentutil.FormMatrix(xform), bbox, annotRect)
+	return ctx.extractContentStreamImages(string(formContent), formResources, ctm)
+}
+
+// newImageMark returns an image mark for `img` painted with transformation matrix `ctm`.
+func newImageMark(img *model.Image, ctm transform.Matrix) ImageMark {
+	imgMark := ImageMark{
+		Image:  img,
+		Width:  ctm.ScalingFactorX(),
+		Height: ctm.ScalingFactorY(),
+		Angle:  ctm.Angle(),
+		CTM:    ctm,
+	}
+	imgMark.X, imgMark.Y = ctm.Translation()
+	return imgMark
+}
+
+// appearanceMatrix returns the matrix mapping the appearance stream space to
+// PDF coordinates, such that the appearance bounding box `bbox`, transformed
+// by the form matrix `m`, fits the annotation rectangle `rect`
+// (section 12.5.5 "Appearance Streams" PDF32000_2008).
+func appearanceMatrix(m transform.Matrix, bbox, rect *model.PdfRectangle) transform.Matrix {
+	// Transformed bounding box of the appearance.
+	minX, minY, maxX, maxY := m.TransformBBox(bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury)
+
+	sx, sy := 1.0, 1.0
+	if maxX > minX {
+		sx = rect.Width() / (maxX - minX)
+	}
+	if maxY > minY {
+		sy = rect.Height() / (maxY - minY)
+	}
+	a := transform.NewMatrix(sx, 0, 0, sy, rect.Llx-minX*sx, rect.Lly-minY*sy)
+	return a.Mult(m)
+}
+
+// imageMaskAlpha returns the 8 bit alpha channel of the image XObject `ximg`,
+// based on its soft mask (SMask) or mask (Mask). `img` is the decoded image
+// data of `ximg`. Returns nil if the image is not masked.
+func imageMaskAlpha(ximg *model.XObjectImage, img *model.Image) ([]byte, error) {
+	width, height := int(img.Width), int(img.Height)
+
+	// Soft mask (section 11.6.5.3 "Soft-Mask Images" PDF32000_2008).
+	if stream, ok := core.GetStream(ximg.SMask); ok {
+		mask, err := loadMaskImage(stream)
+		if err != nil {
+			return nil, err
+		}
+		samples := imageSamples(mask)
+		maxVal := uint32(1)<<uint(mask.BitsPerComponent) - 1
+		alpha := scaleMask(samples, int(mask.Width), int(mask.Height), width, height)
+		for i, val := range alpha {
+			alpha[i] = byte(uint32(val) * 255 / maxVal)
+		}
+		if isDecodeInverted(stream.Get("Decode")) {
+			for i := range alpha {
+				alpha[i] = 255 - alpha[i]
+			}
+		}
+		return alpha, nil
+	}
+
+	switch t := core.TraceToDirectObject(ximg.Mask).(type) {
+	case *core.PdfObjectStream:
+		// Stencil mask (section 8.9.6.3 "Explicit Masking" PDF32000_2008).
+		// Sample values of 1 mark the masked out areas, unless inverted by Decode.
+		mask, err := loadMaskImage(t)
+		if err != nil {
+			return nil, err
+		}
+		samples := imageSamples(mask)
+		alpha := scaleMask(samples, int(mask.Width), int(mask.Height), width, height)
+		inverted := isDecodeInverted(t.Get("Decode"))
+		for i, val := range alpha {
+			if (val != 0) != inverted {
+				alpha[i] = 0
+			} else {
+				alpha[i] = 255
+			}
+		}
+		return alpha, nil
+	case *core.PdfObjectArray:
+		// Color key masking (section 8.9.6.4 "Colour Key Masking" PDF32000_2008).
+		ranges, err := core.GetNumbersAsFloat(t.Elements())
+		if err != nil {
+			return nil, err
+		}
+		numComponents := img.ColorComponents
+		if len(ranges) != 2*numComponents {
+			common.Log.Debug("ERROR: invalid color key mask: %v", t)
+			return nil, nil
+		}
+		samples := imageSamples(img)
+		alpha := make([]byte, width*height)
+		for i := range alpha {
+			alpha[i] = 255
+			if (i+1)*numComponents > len(samples) {
+				continue
+			}
+			masked := true
+			for j := 0; j < numComponents; j++ {
+				val := float64(samples[i*numComponents+j])
+				if val < ranges[2*j] || val > ranges[2*j+1] {
+					masked = false
+					break
+				}
+			}
+			if masked {
+				alpha[i] = 0
+			}
+		}
+		return alpha, nil
+	}
+	return nil, nil
+}
+
+// loadMaskImage loads the mask image from the image XObject `stream`.
+func loadMaskImage(stream *core.PdfObjectStream) (*model.Image, error) {
+	ximg, err := model.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	if ximg.BitsPerComponent == nil {
+		// Optional for image masks, which always have 1 bit per component.
+		bpc := int64(1)
+		ximg.BitsPerComponent = &bpc
+	}
+	// Masks are single component images, regardless of the colorspace entry.
+	ximg.ColorSpace = model.NewPdfColorspaceDeviceGray()
+	return ximg.ToImage()
+}
+
+// isDecodeInverted returns true if the Decode array `obj` of a single component image is [1 0].
+func isDecodeInverted(obj core.PdfObject) bool {
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return false
+	}
+	decode, err := arr.ToFloat64Array()
+	return err == nil && len(decode) == 2 && decode[0] > decode[1]
+}
+
+// imageSamples returns the color samples of `img`, taking into account that
+// each image row is padded to a byte boundary.
+func imageSamples(img *model.Image) []uint32 {
+	bpc := int(img.BitsPerComponent)
+	rowSamples := int(img.Width) * img.ColorComponents
+	rowBytes := (rowSamples*bpc + 7) / 8
+
+	samples := make([]uint32, 0, rowSamples*int(img.Height))
+	for y := 0; y < int(img.Height); y++ {
+		row := img.Data[minInt(y*rowBytes, len(img.Data)):minInt((y+1)*rowBytes, len(img.Data))]
+		for x := 0; x < rowSamples; x++ {
+			bit := x * bpc
+			var val uint32
+			for b := 0; b < bpc; b++ {
+				idx := (bit + b) / 8
+				if idx >= len(row) {
+					break
+				}
+				val = val<<1 | uint32(row[idx]>>uint(7-(bit+b)%8)&1)
+			}
+			samples = append(samples, val)
+		}
+	}
+	return samples
+}
+
+// scaleMask returns the single component `samples` of a `w`x`h` mask image,
+// scaled to `width`x`height` with nearest neighbor sampling.
+// The returned sample values are truncated to 8 bits.
+func scaleMask(samples []uint32, w, h, width, height int) []byte {
+	scaled := make([]byte, width*height)
+	if w <= 0 || h <= 0 {
+		return scaled
+	}
+	for y := 0; y < height; y++ {
+		sy := y * h / height
+		for x := 0; x < width; x++ {
+			sx := x * w / width
+			if idx := sy*w + sx; idx < len(samples) {
+				scaled[y*width+x] = byte(samples[idx])
+			}
+		}
+	}
+	return scaled
+}
+
+// setImageAlpha sets the 8 bit per pixel `alpha` channel of the RGB image `img`,
+// converting the alpha data to the bits per component of the image.
+func setImageAlpha(img *model.Image, alpha []byte) {
+	switch img.BitsPerComponent {
+	case 8:
+		img.SetAlpha(alpha)
+	case 16:
+		alpha16 := make([]byte, 2*len(alpha))
+		for i, val := range alpha {
+			alpha16[2*i], alpha16[2*i+1] = val, val
+		}
+		img.SetAlpha(alpha16)
+	default:
+		img.Resample(8)
+		img.SetAlpha(alpha)
+	}
+}
+
+// encodedImageData returns the data of the image XObject `stream` encoded
+// with its last filter only, along with the name and decode parameters of
+// that filter. Any preceding filters are decoded.
+func encodedImageData(stream *core.PdfObjectStream) ([]byte, string, *core.PdfObjectDictionary, error) {
+	if err := stream.LoadData(); err != nil {
+		return nil, "", nil, err
+	}
+	var filters []core.PdfObject
+	switch t := core.TraceToDirectObject(stream.Get("Filter")).(type) {
+	case *core.PdfObjectName:
+		filters = []core.PdfObject{t}
+	case *core.PdfObjectArray:
+		filters = t.Elements()
+	}
+	if len(filters) == 0 {
+		return stream.Stream, "", nil, nil
+	}
+
+	filter, ok := core.GetName(filters[len(filters)-1])
+	if !ok {
+		return nil, "", nil, errTypeCheck
+	}
+
+	var decodeParms *core.PdfObjectDictionary
+	var parms []core.PdfObject
+	switch t := core.TraceToDirectObject(stream.Get("DecodeParms")).(type) {
+	case *core.PdfObjectDictionary:
+		if len(filters) == 1 {
+			decodeParms = t
+		}
+	case *core.PdfObjectArray:
+		parms = t.Elements()
+		if len(parms) == len(filters) {
+			decodeParms, _ = core.GetDict(parms[len(parms)-1])
+		}
+	}
+
+	if len(filters) == 1 {
+		return stream.Stream, filter.String(), decodeParms, nil
+	}
+
+	// Decode the data with all filters but the last one.
+	dict := core.MakeDict()
+	dict.Set("Filter", core.MakeArray(filters[:len(filters)-1]...))
+	if len(parms) == len(filters) {
+		dict.Set("DecodeParms", core.MakeArray(parms[:len(parms)-1]...))
+	}
+	encoded, err := core.DecodeStream(&core.PdfObjectStream{
+		PdfObjectDictionary: dict,
+		Stream:              stream.Stream,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return encoded, filter.String(), decodeParms, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/transform"
 	"github.com/moolekkari/unipdf/model"
 )
 
@@ -81,6 +82,8 @@ func TestImageExtractionBasic(t *testing.T) {
 
 		for i, img := range pageImages.Images {
 			img.Image = nil // Discard image data.
+			img.CTM = transform.Matrix{}
+			img.Ref = nil
 			assert.Equalf(t, tcase.Expected[i], img, "i = %d", i)
 		}
 	}
@@ -178,6 +181,8 @@ func TestImageExtractionNestedCM(t *testing.T) {
 
 		for i, img := range pageImages.Images {
 			img.Image = nil // Discard image data.
+			img.CTM = transform.Matrix{}
+			img.Ref = nil
 			assert.Equalf(t, tcase.Expected[i], img, "i = %d", i)
 		}
 	}
@@ -245,6 +250,117 @@ func TestImageExtractionMulti(t *testing.T) {
 	}
 }
 
+// Test extraction of encoded data, masks and images nested in forms, patterns
+// and annotation appearances.
+func TestImageExtractionOptions(t *testing.T) {
+	makeImage := func(data []byte, w, h int64, cs string, encoder core.StreamEncoder) *core.PdfObjectStream {
+		stream, err := core.MakeStream(data, encoder)
+		require.NoError(t, err)
+		stream.Set("Type", core.MakeName("XObject"))
+		stream.Set("Subtype", core.MakeName("Image"))
+		stream.Set("Width", core.MakeInteger(w))
+		stream.Set("Height", core.MakeInteger(h))
+		stream.Set("ColorSpace", core.MakeName(cs))
+		stream.Set("BitsPerComponent", core.MakeInteger(8))
+		return stream
+	}
+	makeForm := func(content string, resources *model.PdfPageResources, matrix []float64) *core.PdfObjectStream {
+		xform := model.NewXObjectForm()
+		xform.Resources = resources
+		xform.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+		if matrix != nil {
+			xform.Matrix = core.MakeArrayFromFloats(matrix)
+		}
+		require.NoError(t, xform.SetContentStream([]byte(content), nil))
+		return xform.ToPdfObject().(*core.PdfObjectStream)
+	}
+
+	// 2x1 RGB image with a soft mask, encoded with ASCIIHex and Flate.
+	encoder := core.NewMultiEncoder()
+	encoder.AddEncoder(core.NewASCIIHexEncoder())
+	encoder.AddEncoder(core.NewFlateEncoder())
+	img := makeImage([]byte{255, 0, 0, 0, 0, 255}, 2, 1, "DeviceRGB", encoder)
+	img.ObjectNumber = 7
+	img.Set("SMask", makeImage([]byte{255, 0}, 2, 1, "DeviceGray", nil))
+
+	formResources := model.NewPdfPageResources()
+	require.NoError(t, formResources.SetXObjectByName("Im0", img))
+	form := makeForm("50 0 0 40 0 0 cm /Im0 Do", formResources, []float64{1, 0, 0, 1, 10, 20})
+
+	// Tiling pattern drawing the image.
+	pattern := makeForm("10 0 0 10 0 0 cm /Im0 Do", formResources, []float64{1, 0, 0, 1, 5, 5})
+	pattern.Set("Type", core.MakeName("Pattern"))
+	pattern.Set("PatternType", core.MakeInteger(1))
+	pattern.Set("PaintType", core.MakeInteger(1))
+	pattern.Set("TilingType", core.MakeInteger(1))
+	pattern.Set("XStep", core.MakeFloat(100))
+	pattern.Set("YStep", core.MakeFloat(100))
+
+	page := model.NewPdfPage()
+	require.NoError(t, page.Resources.SetXObjectByName("Fm0", form))
+	require.NoError(t, page.Resources.SetPatternByName("P0", pattern))
+	require.NoError(t, page.SetContentStreams([]string{
+		"q 2 0 0 2 0 0 cm /Fm0 Do Q /Pattern cs /P0 scn 0 0 100 100 re f",
+	}, nil))
+
+	// Annotation with an appearance drawing the image, scaled to fit the annotation rectangle.
+	annotation := model.NewPdfAnnotation()
+	annotation.Rect = core.MakeArrayFromFloats([]float64{300, 400, 350, 450})
+	apDict := core.MakeDict()
+	apDict.Set("N", makeForm("100 0 0 100 0 0 cm /Im0 Do", formResources, nil))
+	annotation.AP = apDict
+	page.AddAnnotation(annotation)
+
+	pageExtractor, err := New(page)
+	require.NoError(t, err)
+
+	// Default options.
+	pageImages, err := pageExtractor.ExtractPageImages(nil)
+	require.NoError(t, err)
+	require.Len(t, pageImages.Images, 1)
+
+	mark := pageImages.Images[0]
+	assert.Equal(t, ImageMark{Width: 100, Height: 80, X: 20, Y: 40}, ImageMark{
+		Width: mark.Width, Height: mark.Height, X: mark.X, Y: mark.Y,
+	})
+	assert.Equal(t, transform.NewMatrix(100, 0, 0, 80, 20, 40), mark.CTM)
+	require.NotNil(t, mark.Ref)
+	assert.Equal(t, int64(7), mark.Ref.ObjectNumber)
+	assert.Nil(t, mark.Encoded)
+	assert.False(t, mark.Image.HasAlpha())
+
+	// All options.
+	pageImages, err = pageExtractor.ExtractPageImages(&ImageExtractOptions{
+		IncludeEncoded:     true,
+		ApplyMasks:         true,
+		IncludePatterns:    true,
+		IncludeAnnotations: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, pageImages.Images, 3)
+
+	mark = pageImages.Images[0]
+	assert.Equal(t, core.StreamEncodingFilterNameFlate, mark.Filter)
+	decoded, err := core.NewFlateEncoder().DecodeBytes(mark.Encoded)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{255, 0, 0, 0, 0, 255}, decoded)
+
+	goImg, err := mark.Image.ToGoImage()
+	require.NoError(t, err)
+	_, _, _, a := goImg.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xffff), a)
+	_, _, _, a = goImg.At(1, 0).RGBA()
+	assert.Equal(t, uint32(0), a)
+
+	// Pattern image.
+	mark = pageImages.Images[1]
+	assert.Equal(t, transform.NewMatrix(10, 0, 0, 10, 5, 5), mark.CTM)
+
+	// Annotation image.
+	mark = pageImages.Images[2]
+	assert.Equal(t, transform.NewMatrix(50, 0, 0, 50, 300, 400), mark.CTM)
+}
+
 func TestImageExtractionRealWorld(t *testing.T) {
 	if len(corpusFolder) == 0 && !forceTest {
 		t.Log("Corpus folder not set - skipping")
@@ -323,6 +439,8 @@ func TestImageExtractionRealWorld(t *testing.T) {
 
 		for i, img := range pageImages.Images {
 			img.Image = nil // Discard image data.
+			img.CTM = transform.Matrix{}
+			img.Ref = nil
 			assert.Equalf(t, tcase.Expected[i], img, "i = %d", i)
 		}
 	}
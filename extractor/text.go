@@ -58,6 +58,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 	fontStack := fontStacker{}
 	to := newTextObject(e, resources, contentstream.GraphicsState{}, &state, &fontStack)
 	var inTextObj bool
+	var markedContent []markedContentSpan
 
 	cstreamParser := contentstream.NewContentStreamParser(contents)
 	operations, err := cstreamParser.Parse()
@@ -287,6 +288,34 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 				to.setHorizScaling(y)
 
+			case "BMC": // Begin marked-content sequence.
+				markedContent = append(markedContent, markedContentSpan{})
+			case "BDC": // Begin marked-content sequence with property list.
+				span := markedContentSpan{}
+				if len(op.Params) == 2 {
+					if props, ok := core.GetDict(op.Params[1]); ok {
+						if actualText, ok := core.GetString(props.Get("ActualText")); ok {
+							span = markedContentSpan{
+								actualText: actualText.Decoded(),
+								hasText:    true,
+								to:         to,
+								start:      len(to.marks),
+							}
+						}
+					}
+				}
+				markedContent = append(markedContent, span)
+			case "EMC": // End marked-content sequence.
+				if len(markedContent) == 0 {
+					common.Log.Debug("EMC called outside of a marked-content sequence")
+					break
+				}
+				span := markedContent[len(markedContent)-1]
+				markedContent = markedContent[:len(markedContent)-1]
+				// The replacement text is only applied to sequences within a text object.
+				if span.hasText && span.to == to && span.start <= len(to.marks) {
+					to.replaceText(span.start, span.actualText)
+				}
 			case "Do":
 				// Handle XObjects by recursing through form XObjects.
 				name := *op.Params[0].(*core.PdfObjectName)
@@ -335,6 +364,14 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 	return pageText, state.numChars, state.numMisses, err
 }
 
+// markedContentSpan is a marked-content sequence of a content stream.
+type markedContentSpan struct {
+	actualText string      // The replacement text of the sequence (ActualText property).
+	hasText    bool        // Whether the sequence has a replacement text.
+	to         *textObject // The text object the sequence begins in.
+	start      int         // The index of the first text mark of the sequence in `to`.
+}
+
 type textResult struct {
 	pageText  PageText
 	numChars  int
@@ -393,7 +430,7 @@ func (to *textObject) showText(charcodes []byte) error {
 
 // showTextAdjusted "TJ". Show text with adjustable spacing.
 func (to *textObject) showTextAdjusted(args *core.PdfObjectArray) error {
-	vertical := false
+	vertical := to.getCurrentFont().IsVertical()
 	for _, o := range args.Elements() {
 		switch o.(type) {
 		case *core.PdfObjectFloat, *core.PdfObjectInteger:
@@ -706,6 +743,9 @@ func (to *textObject) renderText(data []byte) error {
 
 	common.Log.Trace("renderText: %d codes=%+v runes=%q", len(charcodes), charcodes, runes)
 
+	// Text of vertical fonts advances downwards, the glyphs are centred below the text position.
+	vertical := font.IsVertical()
+
 	for i, r := range runes {
 		// TODO(peterwilliams97): Need to find and fix cases where this happens.
 		if r == '\x00' {
@@ -739,6 +779,15 @@ func (to *textObject) renderText(data []byte) error {
 		// t is the displacement of the text cursor when the character is rendered.
 		t0 := transform.Point{X: (c.X*tfs + w) * th}
 		t := transform.Point{X: (c.X*tfs + state.tc + w) * th}
+		if vertical {
+			// The mark runs down the right side of the glyph (see section 9.7.4.3 "Glyph
+			// Metrics in CIDFonts" PDF32000_2008).
+			vm, _ := font.GetVerticalMetrics(code)
+			side := transform.Point{X: vm.Vx * glyphTextRatio * tfs}
+			trm = to.gs.CTM.Mult(to.tm).Mult(translationMatrix(side)).Mult(stateMatrix)
+			t0 = transform.Point{X: side.X, Y: vm.W1y*glyphTextRatio*tfs + w}
+			t = transform.Point{Y: vm.W1y*glyphTextRatio*tfs + state.tc + w}
+		}
 
 		// td, td0 are t, t0 in matrix form.
 		// td0 is where this character ends. td is where the next character starts.
@@ -777,6 +826,27 @@ func (to *textObject) renderText(data []byte) error {
 	return nil
 }
 
+// replaceText replaces the text marks of `to` from index `start` with a single mark with the
+// replacement text `text` (section 14.9.4 "Replacement Text" PDF32000_2008). The replacement
+// text is used for text which is not mapped to its glyphs one-to-one, e.g. ligatures and shaped
+// text of complex scripts.
+func (to *textObject) replaceText(start int, text string) {
+	if start >= len(to.marks) {
+		return
+	}
+	marks := to.marks[start:]
+	mark := marks[0]
+	last := marks[len(marks)-1]
+	for _, m := range marks[1:] {
+		mark.bbox = rectUnion(mark.bbox, m.bbox)
+	}
+	mark.text = text
+	mark.original = text
+	mark.orientedEnd = last.orientedEnd
+	mark.end = last.end
+	to.marks = append(to.marks[:start], mark)
+}
+
 // glyphTextRatio converts Glyph metrics units to unscaled text space units.
 const glyphTextRatio = 1.0 / 1000.0
 
@@ -826,6 +896,10 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 	spaceWidth float64, font *model.PdfFont, charspacing float64) textMark {
 	to.e.textCount++
 	theta := trm.Angle()
+	if font != nil && font.IsVertical() {
+		// Vertical text runs downwards, as text rotated by 90°.
+		theta += 90
+	}
 	orient := nearestMultiple(theta, 10)
 	var height float64
 	if orient%180 != 90 {
@@ -1644,11 +1718,20 @@ func (to *textObject) getFontDirect(name string) (*model.PdfFont, error) {
 	if err != nil {
 		return nil, err
 	}
+	if docFonts := to.e.docFonts; docFonts != nil {
+		if font, ok := docFonts.get(fontObj); ok {
+			return font, nil
+		}
+	}
 	font, err := model.NewPdfFontFromPdfObject(fontObj)
 	if err != nil {
 		common.Log.Debug("getFontDirect: NewPdfFontFromPdfObject failed. name=%#q err=%v", name, err)
+		return font, err
 	}
-	return font, err
+	if docFonts := to.e.docFonts; docFonts != nil {
+		docFonts.set(fontObj, font)
+	}
+	return font, nil
 }
 
 // getFontDict returns the font dict with key `name` if it exists in the page's or form's Font
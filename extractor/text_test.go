@@ -109,6 +109,35 @@ func TestTextExtractionFragments(t *testing.T) {
         `,
 			text: "Hello World!\nDoink",
 		},
+		{
+			name: "Type3",
+			contents: `
+        BT
+        /UniDocType3 24 Tf
+        (\001\002\003)Tj
+        0 -10 Td
+        (\003\001)Tj
+        ET
+        `,
+			text: "Hi!\n!H",
+		},
+		{
+			name: "actual text",
+			contents: `
+        BT
+        /UniDocCourier 24 Tf
+        /Span <</ActualText (fi)>> BDC
+        (X)Tj
+        EMC
+        (nal)Tj
+        0 -10 Td
+        /Span <</ActualText (Doink)>> BDC
+        [(kn) 100 (ioD)]TJ
+        EMC
+        ET
+        `,
+			text: "final\nDoink",
+		},
 	}
 
 	// Setup mock resources.
@@ -118,6 +147,21 @@ func TestTextExtractionFragments(t *testing.T) {
 		helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
 		resources.SetFontByName("UniDocHelvetica", helvetica.ToPdfObject())
 		resources.SetFontByName("UniDocCourier", courier.ToPdfObject())
+
+		var glyphs []model.Type3Glyph
+		for _, r := range "Hi!" {
+			glyphs = append(glyphs, model.Type3Glyph{
+				Rune:    r,
+				Width:   500,
+				BBox:    model.PdfRectangle{Urx: 500, Ury: 700},
+				Content: []byte("0 0 500 700 re f"),
+			})
+		}
+		type3, err := model.NewPdfFontType3(glyphs, nil, nil)
+		if err != nil {
+			t.Fatalf("Error creating Type3 font: %v", err)
+		}
+		resources.SetFontByName("UniDocType3", type3.ToPdfObject())
 	}
 
 	for _, f := range fragmentTests {
@@ -73,3 +73,11 @@ func truncate(s string, n int) string {
 	}
 	return s[:n]
 }
+
+// minInt returns the lesser of `a` and `b`.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
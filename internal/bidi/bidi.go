@@ -0,0 +1,483 @@
+// Package bidi implements the Unicode Bidirectional Algorithm (UAX #9) for laying out paragraphs
+// of text with mixed left-to-right and right-to-left scripts.
+//
+// The implementation covers the resolution of weak and neutral types (including bracket pairs),
+// the resolution of implicit levels and the reordering of lines. Explicit directional embeddings,
+// overrides and isolates are not supported: the formatting characters are ignored and isolates
+// are treated as other neutral characters.
+package bidi
+
+import (
+	"golang.org/x/text/unicode/bidi"
+)
+
+// class is a bidirectional character type.
+type class = bidi.Class
+
+// Bidi character types used by the algorithm, see UAX #9, table 4.
+const (
+	classL   = bidi.L
+	classR   = bidi.R
+	classAL  = bidi.AL
+	classEN  = bidi.EN
+	classES  = bidi.ES
+	classET  = bidi.ET
+	classAN  = bidi.AN
+	classCS  = bidi.CS
+	classNSM = bidi.NSM
+	classBN  = bidi.BN
+	classB   = bidi.B
+	classS   = bidi.S
+	classWS  = bidi.WS
+	classON  = bidi.ON
+)
+
+// maxBracketPairs is the maximum depth of the bracket pair stack (BD16).
+const maxBracketPairs = 63
+
+// Paragraph is a paragraph of text with its resolved embedding levels.
+type Paragraph struct {
+	text    []rune
+	classes []class // The original bidi character types of `text`.
+	level   int     // The paragraph embedding level.
+	levels  []int   // The resolved embedding levels of `text`.
+}
+
+// NewParagraph resolves the embedding levels of the paragraph of text `text`.
+// The paragraph embedding level is `level`, either 0 (left-to-right) or 1 (right-to-left). If
+// `level` is negative the paragraph level is determined by the first strong character of `text`
+// (rules P2 and P3).
+func NewParagraph(text []rune, level int) *Paragraph {
+	classes := make([]class, len(text))
+	for i, r := range text {
+		classes[i] = lookupClass(r)
+	}
+	if level < 0 {
+		level = paragraphLevel(classes)
+	}
+	level &= 1
+	p := &Paragraph{
+		text:    text,
+		classes: classes,
+		level:   level,
+	}
+	p.resolve()
+	return p
+}
+
+// Level returns the paragraph embedding level, 0 for left-to-right and 1 for right-to-left
+// paragraphs.
+func (p *Paragraph) Level() int {
+	return p.level
+}
+
+// IsRTL returns true if the paragraph is a right-to-left paragraph.
+func (p *Paragraph) IsRTL() bool {
+	return p.level == 1
+}
+
+// Levels returns the resolved embedding levels of the runes of the paragraph, before the line
+// rules (L1) are applied. Odd levels are right-to-left.
+func (p *Paragraph) Levels() []int {
+	return p.levels
+}
+
+// HasRTL returns true if `text` contains right-to-left characters or Arabic numbers, i.e. if
+// the layout of `text` may depend on the algorithm.
+func HasRTL(text []rune) bool {
+	for _, r := range text {
+		switch lookupClass(r) {
+		case classR, classAL, classAN:
+			return true
+		}
+	}
+	return false
+}
+
+// LineLevels returns the embedding levels of the runes of the line text[start:end] of the
+// paragraph, after resetting the levels of trailing whitespace and separators to the paragraph
+// level (rule L1).
+func (p *Paragraph) LineLevels(start, end int) []int {
+	levels := make([]int, end-start)
+	copy(levels, p.levels[start:end])
+	trailing := true
+	for i := end - 1; i >= start; i-- {
+		switch p.classes[i] {
+		case classS, classB:
+			levels[i-start] = p.level
+			trailing = true
+		case classWS, classBN:
+			if trailing {
+				levels[i-start] = p.level
+			}
+		default:
+			if isIsolate(p.text[i]) {
+				if trailing {
+					levels[i-start] = p.level
+				}
+				continue
+			}
+			trailing = false
+		}
+	}
+	return levels
+}
+
+// LineOrder returns the indexes of the runes of the line text[start:end] of the paragraph in
+// visual order, from left to right (rules L1 and L2).
+func (p *Paragraph) LineOrder(start, end int) []int {
+	order := Reorder(p.LineLevels(start, end))
+	for i := range order {
+		order[i] += start
+	}
+	return order
+}
+
+// Reorder returns the visual order, from left to right, of runes with embedding levels `levels`
+// (rule L2). The returned slice holds indexes into `levels`.
+func Reorder(levels []int) []int {
+	order := make([]int, len(levels))
+	for i := range order {
+		order[i] = i
+	}
+	highest, lowestOdd := 0, -1
+	for _, l := range levels {
+		if l > highest {
+			highest = l
+		}
+		if l&1 == 1 && (lowestOdd < 0 || l < lowestOdd) {
+			lowestOdd = l
+		}
+	}
+	if lowestOdd < 0 {
+		return order
+	}
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(levels); {
+			if levels[order[i]] < level {
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(levels) && levels[order[j]] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
+
+// lookupClass returns the bidi character type of `r`. The explicit formatting characters are
+// mapped to BN and the isolate formatting characters to ON, as they are not supported.
+func lookupClass(r rune) class {
+	props, _ := bidi.LookupRune(r)
+	c := props.Class()
+	switch c {
+	case bidi.LRO, bidi.RLO, bidi.LRE, bidi.RLE, bidi.PDF:
+		return classBN
+	case bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+		return classON
+	case bidi.Control:
+		return classBN
+	}
+	return c
+}
+
+// isIsolate returns true if `r` is an isolate formatting character.
+func isIsolate(r rune) bool {
+	return r >= 0x2066 && r <= 0x2069
+}
+
+// paragraphLevel returns the paragraph embedding level of text with bidi character types
+// `classes`, which is determined by its first strong character (rules P2 and P3).
+func paragraphLevel(classes []class) int {
+	for _, c := range classes {
+		switch c {
+		case classL:
+			return 0
+		case classR, classAL:
+			return 1
+		}
+	}
+	return 0
+}
+
+// resolve resolves the embedding levels of the paragraph. As explicit embeddings are not
+// supported, the whole paragraph is a single isolating run sequence at the paragraph level.
+func (p *Paragraph) resolve() {
+	n := len(p.text)
+	p.levels = make([]int, n)
+
+	// Rule X9: the removed characters (BN) are skipped by all the following rules.
+	var idx []int
+	for i, c := range p.classes {
+		if c != classBN {
+			idx = append(idx, i)
+		}
+	}
+	types := make([]class, len(idx))
+	for k, i := range idx {
+		types[k] = p.classes[i]
+	}
+
+	e := classL
+	if p.level == 1 {
+		e = classR
+	}
+	sos, eos := e, e
+
+	resolveWeak(types, sos)
+	p.resolveBrackets(idx, types, sos, e)
+	resolveNeutral(types, sos, eos, e)
+
+	// Rules I1 and I2.
+	for k, i := range idx {
+		level := p.level
+		switch t := types[k]; {
+		case level&1 == 0 && t == classR:
+			level++
+		case level&1 == 0 && (t == classAN || t == classEN):
+			level += 2
+		case level&1 == 1 && (t == classL || t == classEN || t == classAN):
+			level++
+		}
+		p.levels[i] = level
+	}
+	// The removed characters get the level of the preceding character.
+	prev := p.level
+	for i, c := range p.classes {
+		if c == classBN {
+			p.levels[i] = prev
+		}
+		prev = p.levels[i]
+	}
+}
+
+// resolveWeak resolves the weak types `types` of an isolating run sequence starting with `sos`
+// (rules W1-W7).
+func resolveWeak(types []class, sos class) {
+	// W1: NSM takes the type of the previous character.
+	prev := sos
+	for k, t := range types {
+		if t == classNSM {
+			types[k] = prev
+		}
+		prev = types[k]
+	}
+	// W2: EN preceded by AL becomes AN. W3: AL becomes R.
+	lastStrong := sos
+	for k, t := range types {
+		switch t {
+		case classL, classR, classAL:
+			lastStrong = t
+		case classEN:
+			if lastStrong == classAL {
+				types[k] = classAN
+			}
+		}
+	}
+	for k, t := range types {
+		if t == classAL {
+			types[k] = classR
+		}
+	}
+	// W4: a single separator between two numbers of the same type.
+	for k := 1; k+1 < len(types); k++ {
+		before, after := types[k-1], types[k+1]
+		switch types[k] {
+		case classES:
+			if before == classEN && after == classEN {
+				types[k] = classEN
+			}
+		case classCS:
+			if before == after && (before == classEN || before == classAN) {
+				types[k] = before
+			}
+		}
+	}
+	// W5: a sequence of ETs adjacent to EN becomes EN.
+	for k := 0; k < len(types); {
+		if types[k] != classET {
+			k++
+			continue
+		}
+		j := k
+		for j < len(types) && types[j] == classET {
+			j++
+		}
+		if (k > 0 && types[k-1] == classEN) || (j < len(types) && types[j] == classEN) {
+			for m := k; m < j; m++ {
+				types[m] = classEN
+			}
+		}
+		k = j
+	}
+	// W6: the remaining separators and terminators become ON.
+	for k, t := range types {
+		switch t {
+		case classES, classET, classCS:
+			types[k] = classON
+		}
+	}
+	// W7: EN preceded by L becomes L.
+	lastStrong = sos
+	for k, t := range types {
+		switch t {
+		case classL, classR:
+			lastStrong = t
+		case classEN:
+			if lastStrong == classL {
+				types[k] = classL
+			}
+		}
+	}
+}
+
+// bracketPair is a pair of matching brackets, identified by their positions in an isolating run
+// sequence.
+type bracketPair struct {
+	open, close int
+}
+
+// resolveBrackets resolves the types of paired brackets (rule N0). `idx` maps the positions in
+// `types` to the runes of the paragraph. `e` is the embedding direction.
+func (p *Paragraph) resolveBrackets(idx []int, types []class, sos, e class) {
+	// BD16: identify the bracket pairs.
+	type opening struct {
+		pos   int
+		match rune
+	}
+	var stack []opening
+	var pairs []bracketPair
+	for k, i := range idx {
+		if types[k] != classON {
+			continue
+		}
+		r := p.text[i]
+		props, _ := bidi.LookupRune(r)
+		if !props.IsBracket() {
+			continue
+		}
+		if props.IsOpeningBracket() {
+			m, ok := Mirror(r)
+			if !ok {
+				continue
+			}
+			if len(stack) == maxBracketPairs {
+				break
+			}
+			stack = append(stack, opening{pos: k, match: m})
+			continue
+		}
+		for s := len(stack) - 1; s >= 0; s-- {
+			if stack[s].match == r {
+				pairs = append(pairs, bracketPair{open: stack[s].pos, close: k})
+				stack = stack[:s]
+				break
+			}
+		}
+	}
+	sortPairs(pairs)
+
+	strong := func(t class) class {
+		switch t {
+		case classEN, classAN:
+			return classR
+		}
+		return t
+	}
+	for _, pair := range pairs {
+		found := classON
+		for k := pair.open + 1; k < pair.close; k++ {
+			t := strong(types[k])
+			if t == e {
+				found = e
+				break
+			}
+			if t == classL || t == classR {
+				found = t
+			}
+		}
+		if found == classON {
+			continue
+		}
+		if found != e {
+			// The brackets enclose strong text of the opposite direction only: use the
+			// direction of the preceding context if it is also opposite.
+			context := sos
+			for k := pair.open - 1; k >= 0; k-- {
+				if t := strong(types[k]); t == classL || t == classR {
+					context = t
+					break
+				}
+			}
+			if context != found {
+				found = e
+			}
+		}
+		for _, k := range []int{pair.open, pair.close} {
+			types[k] = found
+			// NSMs following a bracket whose type changed take its type.
+			for m := k + 1; m < len(types) && p.classes[idx[m]] == classNSM; m++ {
+				types[m] = found
+			}
+		}
+	}
+}
+
+// sortPairs sorts bracket pairs by the positions of their opening brackets.
+func sortPairs(pairs []bracketPair) {
+	for i := 1; i < len(pairs); i++ {
+		for j := i; j > 0 && pairs[j].open < pairs[j-1].open; j-- {
+			pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+		}
+	}
+}
+
+// resolveNeutral resolves the neutral types `types` of an isolating run sequence (rules N1 and
+// N2).
+func resolveNeutral(types []class, sos, eos, e class) {
+	isNeutral := func(t class) bool {
+		switch t {
+		case classB, classS, classWS, classON:
+			return true
+		}
+		return false
+	}
+	strong := func(t class) class {
+		switch t {
+		case classEN, classAN:
+			return classR
+		}
+		return t
+	}
+	for k := 0; k < len(types); {
+		if !isNeutral(types[k]) {
+			k++
+			continue
+		}
+		j := k
+		for j < len(types) && isNeutral(types[j]) {
+			j++
+		}
+		before, after := sos, eos
+		if k > 0 {
+			before = strong(types[k-1])
+		}
+		if j < len(types) {
+			after = strong(types[j])
+		}
+		t := e
+		if before == after {
+			t = before
+		}
+		for m := k; m < j; m++ {
+			types[m] = t
+		}
+		k = j
+	}
+}
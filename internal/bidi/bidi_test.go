@@ -0,0 +1,76 @@
+package bidi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// visual returns `text` laid out as a single line in visual order, with mirrored characters in
+// right-to-left runs.
+func visual(text string, level int) string {
+	runes := []rune(text)
+	p := NewParagraph(runes, level)
+	levels := p.LineLevels(0, len(runes))
+	var out []rune
+	for _, i := range p.LineOrder(0, len(runes)) {
+		r := runes[i]
+		if levels[i]&1 == 1 {
+			if m, ok := Mirror(r); ok {
+				r = m
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func TestParagraphLevel(t *testing.T) {
+	assert.Equal(t, 0, NewParagraph([]rune("abc אבג"), -1).Level())
+	assert.Equal(t, 1, NewParagraph([]rune("123 אבג abc"), -1).Level())
+	assert.Equal(t, 1, NewParagraph([]rune("سلام"), -1).Level())
+	assert.Equal(t, 0, NewParagraph([]rune("123 ..."), -1).Level())
+	assert.Equal(t, 1, NewParagraph([]rune("abc"), 1).Level())
+}
+
+func TestLineOrder(t *testing.T) {
+	testCases := []struct {
+		text     string
+		level    int
+		expected string
+	}{
+		{"abc def", -1, "abc def"},
+		{"אבג דהו", -1, "והד גבא"},
+		{"abc אבג דהו ghi", -1, "abc והד גבא ghi"},
+		{"אבג abc def דהו", -1, "והד abc def גבא"},
+		// Numbers are laid out left to right in right-to-left text.
+		{"אבג 123 דהו", -1, "והד 123 גבא"},
+		{"שלום 1.5% עולם", -1, "םלוע 1.5% םולש"},
+		// Arabic digits following Arabic letters are Arabic numbers.
+		{"عدد 12", -1, "12 ددع"},
+		// Brackets are mirrored in right-to-left runs and paired.
+		{"אבג (דהו)", -1, "(והד) גבא"},
+		{"abc (אבג) def", 0, "abc (גבא) def"},
+		{"אבג (abc) דהו", -1, "והד (abc) גבא"},
+		// Trailing whitespace is at the paragraph level.
+		{"abc אבג  ", 0, "abc גבא  "},
+		// Marks are reversed with their base characters.
+		{"\u05e9\u05b8\u05c1\u05dc", -1, "\u05dc\u05c1\u05b8\u05e9"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, visual(tc.text, tc.level), "text=%q", tc.text)
+	}
+}
+
+func TestReorder(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 2}, Reorder([]int{0, 0, 0}))
+	assert.Equal(t, []int{2, 1, 0}, Reorder([]int{1, 1, 1}))
+	assert.Equal(t, []int{0, 3, 2, 1, 4}, Reorder([]int{0, 1, 1, 1, 0}))
+	assert.Equal(t, []int{4, 2, 3, 1, 0}, Reorder([]int{1, 1, 2, 2, 1}))
+}
+
+func TestHasRTL(t *testing.T) {
+	assert.False(t, HasRTL([]rune("Hello, world 123")))
+	assert.True(t, HasRTL([]rune("Hello שלום")))
+	assert.True(t, HasRTL([]rune("مرحبا")))
+}
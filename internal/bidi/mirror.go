@@ -0,0 +1,47 @@
+package bidi
+
+// mirrorPairs lists the pairs of characters with the Bidi_Mirrored property that have mirrored
+// glyphs (BidiMirroring.txt). The most frequently used pairs are listed.
+var mirrorPairs = [][2]rune{
+	{'(', ')'}, {'<', '>'}, {'[', ']'}, {'{', '}'}, {'«', '»'},
+	{0x0F3A, 0x0F3B}, {0x0F3C, 0x0F3D}, {0x169B, 0x169C},
+	{0x2039, 0x203A}, {0x2045, 0x2046}, {0x207D, 0x207E}, {0x208D, 0x208E},
+	{0x2208, 0x220B}, {0x2209, 0x220C}, {0x220A, 0x220D}, {0x2215, 0x29F5},
+	{0x223C, 0x223D}, {0x2243, 0x22CD}, {0x2264, 0x2265}, {0x2266, 0x2267},
+	{0x226A, 0x226B}, {0x226E, 0x226F}, {0x2270, 0x2271}, {0x2272, 0x2273},
+	{0x2276, 0x2277}, {0x227A, 0x227B}, {0x227C, 0x227D}, {0x2282, 0x2283},
+	{0x2286, 0x2287}, {0x228F, 0x2290}, {0x2291, 0x2292}, {0x22A2, 0x22A3},
+	{0x22B0, 0x22B1}, {0x22B2, 0x22B3}, {0x22B4, 0x22B5}, {0x22D0, 0x22D1},
+	{0x22D6, 0x22D7}, {0x22D8, 0x22D9}, {0x22DA, 0x22DB}, {0x22DE, 0x22DF},
+	{0x2308, 0x2309}, {0x230A, 0x230B}, {0x2329, 0x232A}, {0x2768, 0x2769},
+	{0x276A, 0x276B}, {0x276C, 0x276D}, {0x276E, 0x276F}, {0x2770, 0x2771},
+	{0x2772, 0x2773}, {0x2774, 0x2775}, {0x27C5, 0x27C6}, {0x27E6, 0x27E7},
+	{0x27E8, 0x27E9}, {0x27EA, 0x27EB}, {0x27EC, 0x27ED}, {0x27EE, 0x27EF},
+	{0x2983, 0x2984}, {0x2985, 0x2986}, {0x2987, 0x2988}, {0x2989, 0x298A},
+	{0x298B, 0x298C}, {0x298D, 0x2990}, {0x298F, 0x298E}, {0x2991, 0x2992},
+	{0x2993, 0x2994}, {0x2995, 0x2996}, {0x2997, 0x2998}, {0x29FC, 0x29FD},
+	{0x2E22, 0x2E23}, {0x2E24, 0x2E25}, {0x2E26, 0x2E27}, {0x2E28, 0x2E29},
+	{0x3008, 0x3009}, {0x300A, 0x300B}, {0x300C, 0x300D}, {0x300E, 0x300F},
+	{0x3010, 0x3011}, {0x3014, 0x3015}, {0x3016, 0x3017}, {0x3018, 0x3019},
+	{0x301A, 0x301B}, {0xFE59, 0xFE5A}, {0xFE5B, 0xFE5C}, {0xFE5D, 0xFE5E},
+	{0xFF08, 0xFF09}, {0xFF1C, 0xFF1E}, {0xFF3B, 0xFF3D}, {0xFF5B, 0xFF5D},
+	{0xFF5F, 0xFF60}, {0xFF62, 0xFF63},
+}
+
+// mirrors maps the characters of mirrorPairs to their mirrored characters.
+var mirrors = make(map[rune]rune, 2*len(mirrorPairs))
+
+func init() {
+	for _, p := range mirrorPairs {
+		mirrors[p[0]] = p[1]
+		mirrors[p[1]] = p[0]
+	}
+}
+
+// Mirror returns the character whose glyph is the mirror image of the glyph of `r`, which is
+// drawn instead of `r` in right-to-left text (rule L4). The bool is false if `r` has no
+// mirrored character.
+func Mirror(r rune) (rune, bool) {
+	m, ok := mirrors[r]
+	return m, ok
+}
@@ -301,6 +301,15 @@ func (cmap *CMap) CharcodeToUnicode(code CharCode) (rune, bool) {
 	return MissingCodeRune, false
 }
 
+// CharcodeToUnicodeMap returns a copy of the character code to unicode mappings of the CMap.
+func (cmap *CMap) CharcodeToUnicodeMap() map[CharCode]rune {
+	codeToUnicode := make(map[CharCode]rune, len(cmap.codeToUnicode))
+	for code, r := range cmap.codeToUnicode {
+		codeToUnicode[code] = r
+	}
+	return codeToUnicode
+}
+
 // RuneToCID maps the specified rune to a character identifier. If the provided
 // rune has no available mapping, the second return value is false.
 func (cmap *CMap) RuneToCID(r rune) (CharCode, bool) {
@@ -351,6 +360,28 @@ func (cmap *CMap) BytesToCharcodes(data []byte) ([]CharCode, bool) {
 	return charcodes, true
 }
 
+// SplitCharcodeBytes splits `data` into the byte sequences of the character codes in `data`.
+// The sequences correspond to the character codes returned by BytesToCharcodes.
+func (cmap *CMap) SplitCharcodeBytes(data []byte) ([][]byte, bool) {
+	var split [][]byte
+	if cmap.nbits == 8 {
+		for i := range data {
+			split = append(split, data[i:i+1])
+		}
+		return split, true
+	}
+	for i := 0; i < len(data); {
+		_, n, matched := cmap.matchCode(data[i:])
+		if !matched {
+			common.Log.Debug("ERROR: No code match at i=%d bytes=[% 02x]=%#q", i, data, string(data))
+			return split, false
+		}
+		split = append(split, data[i:i+n])
+		i += n
+	}
+	return split, true
+}
+
 // Name returns the name of the CMap.
 func (cmap *CMap) Name() string {
 	return cmap.name
@@ -443,11 +474,12 @@ func (cmap *CMap) toBfData() string {
 	}
 	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
 
-	// charRanges is a list of the contiguous character code ranges in `codes`.
+	// charRanges is a list of the ranges of contiguous character codes in `codes` that map to
+	// contiguous runes.
 	var charRanges []charRange
 	c0, c1 := codes[0], codes[0]+1
 	for _, c := range codes[1:] {
-		if c != c1 {
+		if c != c1 || cmap.codeToUnicode[c] != cmap.codeToUnicode[c0]+rune(c-c0) {
 			charRanges = append(charRanges, charRange{c0, c1})
 			c0 = c
 		}
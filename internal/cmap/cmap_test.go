@@ -610,6 +610,13 @@ var (
 		0x0316: '̖',
 		0x0317: '̗',
 	}
+	// codeToUnicode4 has contiguous codes that map to runes which are not contiguous.
+	codeToUnicode4 = map[CharCode]rune{
+		0x0001: 'A',
+		0x0002: '★',
+		0x0003: 'C',
+		0x0004: 'D',
+	}
 )
 
 const bfData1 = `
@@ -653,6 +660,7 @@ func TestCMapCreation(t *testing.T) {
 	checkCmapWriteRead(t, codeToUnicode1)
 	checkCmapWriteRead(t, codeToUnicode2)
 	checkCmapWriteRead(t, codeToUnicode3)
+	checkCmapWriteRead(t, codeToUnicode4)
 }
 
 // checkCmapWriteRead creates CMap data from `codeToUnicode` then parses it and checks that the
@@ -0,0 +1,47 @@
+// Package contentutil contains the parts of content stream processing shared by the packages that
+// edit page content: the operands of operations, form matrices, the text state and the glyphs
+// shown by text showing operations.
+package contentutil
+
+import (
+	"errors"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// OperandFloats returns the `n` numeric parameters of `op`.
+func OperandFloats(op *contentstream.ContentStreamOperation, n int) ([]float64, error) {
+	if len(op.Params) != n {
+		common.Log.Debug("ERROR: %s expects %d parameters, got %d", op.Operand, n, len(op.Params))
+		return nil, errors.New("invalid number of parameters")
+	}
+	return core.GetNumbersAsFloat(op.Params)
+}
+
+// OperandMatrix returns the matrix specified by the parameters of `op`.
+func OperandMatrix(op *contentstream.ContentStreamOperation) (transform.Matrix, error) {
+	v, err := OperandFloats(op, 6)
+	if err != nil {
+		return transform.IdentityMatrix(), err
+	}
+	return transform.NewMatrix(v[0], v[1], v[2], v[3], v[4], v[5]), nil
+}
+
+// FormMatrix returns the form matrix of `xform`. The identity matrix is returned if the matrix is
+// missing or invalid.
+func FormMatrix(xform *model.XObjectForm) transform.Matrix {
+	arr, ok := core.GetArray(xform.Matrix)
+	if !ok {
+		return transform.IdentityMatrix()
+	}
+	m, err := arr.ToFloat64Array()
+	if err != nil || len(m) != 6 {
+		common.Log.Debug("ERROR: invalid form matrix: %v", xform.Matrix)
+		return transform.IdentityMatrix()
+	}
+	return transform.NewMatrix(m[0], m[1], m[2], m[3], m[4], m[5])
+}
@@ -0,0 +1,298 @@
+package contentutil
+
+import (
+	"errors"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// Default glyph metrics used when the font metrics are not available, in unscaled text space units.
+const (
+	DefaultGlyphWidth = 0.5
+	DefaultAscent     = 0.8
+	DefaultDescent    = -0.2
+)
+
+// GlyphTextRatio converts glyph metrics units to unscaled text space units.
+const GlyphTextRatio = 1.0 / 1000.0
+
+// TextState holds the text state parameters used for laying out the glyphs of text showing
+// operations (section 9.3 "Text State Parameters and Operators" PDF32000_2008). The text state is
+// part of the graphics state.
+type TextState struct {
+	Tc    float64 // Character spacing.
+	Tw    float64 // Word spacing.
+	Th    float64 // Horizontal scaling (percent).
+	Tl    float64 // Leading.
+	Tfs   float64 // Font size.
+	Trise float64 // Text rise.
+	Tr    int     // Text rendering mode.
+
+	Font     *model.PdfFont     // The font selected by the Tf operator, nil if it was not loaded.
+	FontName core.PdfObjectName // The resource name of the font.
+}
+
+// NewTextState returns the initial text state.
+func NewTextState() TextState {
+	return TextState{Th: 100}
+}
+
+// TextMatrices holds the text matrix and the text line matrix of the current text object.
+type TextMatrices struct {
+	Tm  transform.Matrix // Text matrix.
+	Tlm transform.Matrix // Text line matrix.
+}
+
+// NewTextMatrices returns the text matrices at the start of a text object.
+func NewTextMatrices() TextMatrices {
+	return TextMatrices{Tm: transform.IdentityMatrix(), Tlm: transform.IdentityMatrix()}
+}
+
+// MoveTo moves the start of the line by `tx`,`ty` in unscaled text space units.
+func (t *TextMatrices) MoveTo(tx, ty float64) {
+	t.Tlm.Concat(transform.TranslationMatrix(tx, ty))
+	t.Tm = t.Tlm
+}
+
+// FontCache holds the fonts loaded from the font objects of page resources.
+type FontCache map[core.PdfObject]*model.PdfFont
+
+// LoadFont returns the font named `name` in `resources`, or nil if it cannot be loaded.
+func (c FontCache) LoadFont(resources *model.PdfPageResources, name core.PdfObjectName) *model.PdfFont {
+	if resources == nil {
+		return nil
+	}
+	obj, ok := resources.GetFontByName(name)
+	if !ok {
+		common.Log.Debug("ERROR: font %s not found in resources", name)
+		return nil
+	}
+	if font, ok := c[obj]; ok {
+		return font
+	}
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to load font %s: %v", name, err)
+		font = nil
+	}
+	c[obj] = font
+	return font
+}
+
+// ProcessTextOp updates the text state `ts` and the text matrices `tm` for the operation `op` if
+// it is a text object, text state or text positioning operation: BT, Tc, Tw, Tz, TL, Ts, Tr, Tf,
+// Td, TD, T* or Tm. The fonts selected by Tf are loaded from `resources` with `fonts`.
+// The bool is false if `op` is not one of these operations.
+func ProcessTextOp(op *contentstream.ContentStreamOperation, ts *TextState, tm *TextMatrices,
+	resources *model.PdfPageResources, fonts FontCache) (bool, error) {
+	switch op.Operand {
+	case "BT":
+		*tm = NewTextMatrices()
+	case "Tc", "Tw", "Tz", "TL", "Ts", "Tr":
+		v, err := OperandFloats(op, 1)
+		if err != nil {
+			return true, err
+		}
+		switch op.Operand {
+		case "Tc":
+			ts.Tc = v[0]
+		case "Tw":
+			ts.Tw = v[0]
+		case "Tz":
+			ts.Th = v[0]
+		case "TL":
+			ts.Tl = v[0]
+		case "Ts":
+			ts.Trise = v[0]
+		case "Tr":
+			ts.Tr = int(v[0])
+		}
+	case "Tf":
+		if len(op.Params) != 2 {
+			return true, errors.New("invalid number of parameters for Tf")
+		}
+		name, ok := core.GetName(op.Params[0])
+		if !ok {
+			return true, errors.New("invalid font name for Tf")
+		}
+		size, err := core.GetNumberAsFloat(op.Params[1])
+		if err != nil {
+			return true, err
+		}
+		ts.Font = fonts.LoadFont(resources, *name)
+		ts.FontName = *name
+		ts.Tfs = size
+	case "Td", "TD":
+		v, err := OperandFloats(op, 2)
+		if err != nil {
+			return true, err
+		}
+		if op.Operand == "TD" {
+			ts.Tl = -v[1]
+		}
+		tm.MoveTo(v[0], v[1])
+	case "T*":
+		tm.MoveTo(0, -ts.Tl)
+	case "Tm":
+		m, err := OperandMatrix(op)
+		if err != nil {
+			return true, err
+		}
+		tm.Tm = m
+		tm.Tlm = m
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// TextShowItems returns the strings and TJ position adjustments shown by the text showing
+// operation `op` (Tj, TJ, ' or "). The text state `ts` and the text matrices `tm` are updated for
+// the move to the next line and the spacing set by the ' and " operations.
+func TextShowItems(op *contentstream.ContentStreamOperation, ts *TextState, tm *TextMatrices) ([]core.PdfObject, error) {
+	switch op.Operand {
+	case "Tj", "'":
+		if len(op.Params) != 1 {
+			return nil, errors.New("invalid number of parameters for text showing operation")
+		}
+		if op.Operand == "'" {
+			tm.MoveTo(0, -ts.Tl)
+		}
+		return op.Params, nil
+	case "TJ":
+		if len(op.Params) != 1 {
+			return nil, errors.New("invalid number of parameters for TJ")
+		}
+		arr, ok := core.GetArray(op.Params[0])
+		if !ok {
+			return nil, errors.New("invalid TJ parameter")
+		}
+		return arr.Elements(), nil
+	case "\"":
+		if len(op.Params) != 3 {
+			return nil, errors.New("invalid number of parameters for \" operation")
+		}
+		v, err := core.GetNumbersAsFloat(op.Params[:2])
+		if err != nil {
+			return nil, err
+		}
+		ts.Tw, ts.Tc = v[0], v[1]
+		tm.MoveTo(0, -ts.Tl)
+		return op.Params[2:], nil
+	}
+	return nil, errors.New("not a text showing operation")
+}
+
+// Glyph is a glyph shown by a text showing operation.
+type Glyph struct {
+	Data  []byte                // The character code bytes.
+	Code  textencoding.CharCode // The character code.
+	Width float64               // The glyph width in unscaled text space units.
+}
+
+// Glyphs returns the glyphs shown by the string `data` drawn with `font`. The glyphs of a font
+// which was not loaded are the bytes of `data`, with the default glyph width.
+func Glyphs(font *model.PdfFont, data []byte) []Glyph {
+	if font == nil {
+		glyphs := make([]Glyph, len(data))
+		for i := range data {
+			glyphs[i] = Glyph{Data: data[i : i+1], Code: textencoding.CharCode(data[i]), Width: DefaultGlyphWidth}
+		}
+		return glyphs
+	}
+
+	split := font.SplitCharcodeBytes(data)
+	glyphs := make([]Glyph, len(split))
+	for i, b := range split {
+		glyphs[i].Data = b
+		codes := font.BytesToCharcodes(b)
+		if len(codes) == 0 {
+			continue
+		}
+		glyphs[i].Code = codes[0]
+		if m, ok := font.GetCharMetrics(codes[0]); ok {
+			glyphs[i].Width = m.Wx * GlyphTextRatio
+		}
+	}
+	return glyphs
+}
+
+// LayoutGlyphs lays out the glyphs shown by `items`, the strings and TJ position adjustments of a
+// text showing operation, drawn with the text state `ts` starting at text matrix `tm`. Returns
+// the text matrix after the glyphs.
+// `glyphFn` is called for each glyph with the matrix mapping the unscaled text space of the glyph
+// (glyph space scaled by GlyphTextRatio) to user space, and with the displacement of the glyph in
+// unscaled text space units before horizontal scaling. `adjustFn`, if not nil, is called for each
+// position adjustment.
+func LayoutGlyphs(ts TextState, tm transform.Matrix, items []core.PdfObject,
+	glyphFn func(g Glyph, m transform.Matrix, advance float64), adjustFn func(num float64)) transform.Matrix {
+	tfs := ts.Tfs
+	th := ts.Th / 100.0
+	stateMatrix := transform.NewMatrix(tfs*th, 0, 0, tfs, 0, ts.Trise)
+	for _, item := range items {
+		if data, ok := core.GetStringBytes(item); ok {
+			for _, g := range Glyphs(ts.Font, data) {
+				w := 0.0
+				if len(g.Data) == 1 && g.Data[0] == ' ' {
+					w = ts.Tw
+				}
+				advance := g.Width*tfs + ts.Tc + w
+				glyphFn(g, tm.Mult(stateMatrix), advance)
+				tm.Concat(transform.TranslationMatrix(advance*th, 0))
+			}
+			continue
+		}
+		num, err := core.GetNumberAsFloat(item)
+		if err != nil {
+			common.Log.Debug("ERROR: invalid TJ element %T", item)
+			continue
+		}
+		if adjustFn != nil {
+			adjustFn(num)
+		}
+		tm.Concat(transform.TranslationMatrix(-num*tfs*th/1000, 0))
+	}
+	return tm
+}
+
+// FontExtent returns the ascent and descent of `font` in unscaled text space units. The default
+// metrics are returned if `font` is nil or has no valid metrics.
+func FontExtent(font *model.PdfFont) (float64, float64) {
+	if font == nil {
+		return DefaultAscent, DefaultDescent
+	}
+	descriptor, err := font.GetFontDescriptor()
+	if err != nil || descriptor == nil {
+		return DefaultAscent, DefaultDescent
+	}
+	ascent, errA := descriptor.GetAscent()
+	descent, errD := descriptor.GetDescent()
+	if errA != nil || errD != nil || ascent <= descent {
+		return DefaultAscent, DefaultDescent
+	}
+	return ascent * GlyphTextRatio, descent * GlyphTextRatio
+}
+
+// DecodeText returns the text of the character code bytes `data` drawn with `font`. The bytes are
+// returned as is if `font` is nil.
+func DecodeText(font *model.PdfFont, data []byte) string {
+	if font == nil {
+		return string(data)
+	}
+	text, _, _ := font.CharcodeBytesToUnicode(data)
+	return text
+}
+
+// MakeString returns a string object for the character code bytes `data` drawn with `font`.
+// Strings of composite fonts are hexadecimal strings.
+func MakeString(font *model.PdfFont, data []byte) *core.PdfObjectString {
+	if font != nil && font.IsCID() {
+		return core.MakeHexString(string(data))
+	}
+	return core.MakeStringFromBytes(data)
+}
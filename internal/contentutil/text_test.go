@@ -0,0 +1,87 @@
+package contentutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/transform"
+)
+
+func TestProcessTextOp(t *testing.T) {
+	ops, err := contentstream.NewContentStreamParser(
+		"BT 2 Tc 3 Tw 50 Tz 14 TL 1 Ts 2 Tr /F1 10 Tf 100 200 Td 0 -20 TD T* q").Parse()
+	require.NoError(t, err)
+
+	ts := NewTextState()
+	var tm TextMatrices
+	for _, op := range (*ops)[:len(*ops)-1] {
+		ok, err := ProcessTextOp(op, &ts, &tm, nil, FontCache{})
+		require.NoError(t, err)
+		assert.True(t, ok, op.Operand)
+	}
+	ok, err := ProcessTextOp((*ops)[len(*ops)-1], &ts, &tm, nil, FontCache{})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The font is not loaded without resources.
+	assert.Equal(t, TextState{Tc: 2, Tw: 3, Th: 50, Tl: 20, Tfs: 10, Trise: 1, Tr: 2, FontName: "F1"}, ts)
+	x, y := tm.Tm.Translation()
+	assert.Equal(t, 100.0, x)
+	assert.Equal(t, 160.0, y)
+	assert.Equal(t, tm.Tm, tm.Tlm)
+}
+
+func TestTextShowItems(t *testing.T) {
+	ops, err := contentstream.NewContentStreamParser(`(a) Tj [(b) -10 (c)] TJ (d) ' 1 2 (e) "`).Parse()
+	require.NoError(t, err)
+
+	ts := NewTextState()
+	ts.Tl = 10
+	tm := NewTextMatrices()
+	var counts []int
+	for _, op := range *ops {
+		items, err := TextShowItems(op, &ts, &tm)
+		require.NoError(t, err)
+		counts = append(counts, len(items))
+	}
+	assert.Equal(t, []int{1, 3, 1, 1}, counts)
+	// ' and " move to the next line, " sets the word and character spacing.
+	_, y := tm.Tm.Translation()
+	assert.Equal(t, -20.0, y)
+	assert.Equal(t, 1.0, ts.Tw)
+	assert.Equal(t, 2.0, ts.Tc)
+
+	_, err = TextShowItems(&contentstream.ContentStreamOperation{Operand: "Tf"}, &ts, &tm)
+	assert.Error(t, err)
+}
+
+func TestLayoutGlyphs(t *testing.T) {
+	// Without a font, each byte is a glyph with the default width.
+	ts := NewTextState()
+	ts.Tfs = 10
+	ts.Tc = 1
+	ts.Tw = 2
+	items := []core.PdfObject{core.MakeString("a b"), core.MakeFloat(-100)}
+
+	var xs, advances []float64
+	var adjustments []float64
+	tm := LayoutGlyphs(ts, transform.IdentityMatrix(), items,
+		func(g Glyph, m transform.Matrix, advance float64) {
+			assert.Equal(t, DefaultGlyphWidth, g.Width)
+			x, _ := m.Translation()
+			xs = append(xs, x)
+			advances = append(advances, advance)
+		},
+		func(num float64) {
+			adjustments = append(adjustments, num)
+		})
+	assert.Equal(t, []float64{0, 6, 14}, xs)
+	assert.Equal(t, []float64{6, 8, 6}, advances)
+	assert.Equal(t, []float64{-100}, adjustments)
+	x, _ := tm.Translation()
+	assert.Equal(t, 21.0, x)
+}
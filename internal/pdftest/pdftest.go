@@ -0,0 +1,69 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package pdftest builds small PDF files from the bodies of their objects for use in tests. It
+// does not depend on the other unipdf packages so that it can be used by the tests of any of them.
+package pdftest
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// File describes a PDF file made of a sequence of indirect objects, numbered from 1, followed by a
+// cross-reference table and a trailer with the catalog at 1 0 R.
+type File struct {
+	// Objects are the bodies of the indirect objects.
+	Objects []string
+
+	// Size is the /Size entry of the trailer. If 0, len(Objects)+1 is used.
+	Size int
+
+	// NoXref omits the cross-reference table, the trailer and the startxref section, e.g. to test
+	// recovery by scanning.
+	NoXref bool
+
+	// FixOffsets, if set, is called with the offsets of the objects before they are written to the
+	// cross-reference table, e.g. to break some of them.
+	FixOffsets func(offsets []int)
+}
+
+// Build returns a PDF file with the indirect objects `objects` and a valid cross-reference table.
+func Build(objects ...string) []byte {
+	return File{Objects: objects}.Bytes()
+}
+
+// Bytes returns the contents of the PDF file described by `f`.
+func (f File) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	var offsets []int
+	for i, obj := range f.Objects {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	if f.NoXref {
+		return buf.Bytes()
+	}
+	if f.FixOffsets != nil {
+		f.FixOffsets(offsets)
+	}
+	size := f.Size
+	if size == 0 {
+		size = len(offsets) + 1
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f\r\n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%.10d 00000 n\r\n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, xrefOffset)
+	return buf.Bytes()
+}
+
+// Stream returns the body of a stream object with the data `data` and a correct /Length.
+func Stream(data string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(data), data)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/moolekkari/unipdf/common"
 	"github.com/moolekkari/unipdf/core"
@@ -20,6 +21,15 @@ type GID uint16
 // Corresponds to Identity-H CMap and Identity encoding.
 type TrueTypeFontEncoder struct {
 	runeToGIDMap map[rune]GID
+
+	// The runes encoded with the encoder. Shared by the copies of the encoder.
+	registered *registeredRunes
+}
+
+// registeredRunes is a set of runes that can be used concurrently.
+type registeredRunes struct {
+	mu    sync.Mutex
+	runes map[rune]struct{}
 }
 
 // NewTrueTypeFontEncoder creates a new text encoder for TTF fonts with a runeToGlyphIndexMap that
@@ -29,9 +39,25 @@ type TrueTypeFontEncoder struct {
 func NewTrueTypeFontEncoder(runeToGIDMap map[rune]GID) TrueTypeFontEncoder {
 	return TrueTypeFontEncoder{
 		runeToGIDMap: runeToGIDMap,
+		registered:   &registeredRunes{runes: make(map[rune]struct{})},
 	}
 }
 
+// RegisteredRunes returns the runes that have been encoded with `enc`, mapped to their glyph
+// indices. These are the glyphs needed for drawing the text encoded with `enc`.
+func (enc TrueTypeFontEncoder) RegisteredRunes() map[rune]GID {
+	runeToGID := make(map[rune]GID)
+	if enc.registered == nil {
+		return runeToGID
+	}
+	enc.registered.mu.Lock()
+	defer enc.registered.mu.Unlock()
+	for r := range enc.registered.runes {
+		runeToGID[r] = enc.runeToGIDMap[r]
+	}
+	return runeToGID
+}
+
 // ttEncoderMaxNumEntries is the maximum number of encoding entries shown in simpleEncoder.String().
 const ttEncoderMaxNumEntries = 10
 
@@ -92,6 +118,14 @@ func (enc TrueTypeFontEncoder) GlyphToCharcode(glyph GlyphName) (CharCode, bool)
 	return 0, false
 }
 
+// RuneToGID returns the glyph index of rune `r`. Unlike RuneToCharcode, `r` is not registered
+// (see RegisteredRunes).
+// The bool return flag is true if there was a match, and false otherwise.
+func (enc TrueTypeFontEncoder) RuneToGID(r rune) (GID, bool) {
+	gid, ok := enc.runeToGIDMap[r]
+	return gid, ok
+}
+
 // RuneToCharcode converts rune `r` to a PDF character code.
 // The bool return flag is true if there was a match, and false otherwise.
 func (enc TrueTypeFontEncoder) RuneToCharcode(r rune) (CharCode, bool) {
@@ -100,6 +134,11 @@ func (enc TrueTypeFontEncoder) RuneToCharcode(r rune) (CharCode, bool) {
 		common.Log.Debug("Missing rune %d (%+q) from encoding", r, r)
 		return 0, false
 	}
+	if enc.registered != nil {
+		enc.registered.mu.Lock()
+		enc.registered.runes[r] = struct{}{}
+		enc.registered.mu.Unlock()
+	}
 	// Identity : charcode <-> glyphIndex
 	// TODO(dennwc): Here charcode is probably the same as CID.
 	// TODO(dennwc): Find out what are the alternative mappings (enc.cmap?).
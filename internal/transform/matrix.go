@@ -124,6 +124,40 @@ func (m *Matrix) Transform(x, y float64) (float64, float64) {
 	return xp, yp
 }
 
+// TransformPoint returns coordinates `x`,`y` transformed by `m` as described in section 8.3.4
+// "Transformation Matrices" of PDF32000_2008, i.e. x' = a*x + c*y + tx and y' = b*x + d*y + ty.
+// Unlike Transform, the off-diagonal elements b and c are applied to y and x respectively.
+func (m Matrix) TransformPoint(x, y float64) (float64, float64) {
+	return x*m[0] + y*m[3] + m[6], x*m[1] + y*m[4] + m[7]
+}
+
+// TransformBBox returns the bounding box of the rectangle (`llx`,`lly`)-(`urx`,`ury`) transformed
+// by `m` with TransformPoint.
+func (m Matrix) TransformBBox(llx, lly, urx, ury float64) (float64, float64, float64, float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range [4][2]float64{{llx, lly}, {urx, lly}, {urx, ury}, {llx, ury}} {
+		x, y := m.TransformPoint(c[0], c[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// Inverse returns the inverse of `m`. The returned bool is false if `m` is not invertible, in which
+// case the identity matrix is returned.
+func (m Matrix) Inverse() (Matrix, bool) {
+	a, b, c, d, tx, ty := m[0], m[1], m[3], m[4], m[6], m[7]
+	det := a*d - b*c
+	if math.Abs(det) < 1e-12 {
+		return IdentityMatrix(), false
+	}
+	return NewMatrix(
+		d/det, -b/det,
+		-c/det, a/det,
+		(c*ty-d*tx)/det, (b*tx-a*ty)/det), true
+}
+
 // ScalingFactorX returns the X scaling of the affine transform.
 func (m *Matrix) ScalingFactorX() float64 {
 	return math.Hypot(m[0], m[1])
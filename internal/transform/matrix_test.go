@@ -56,3 +56,50 @@ func makeAngleCase(r, theta float64) angleCase {
 	d := a
 	return angleCase{params{a, b, c, d, 0, 0}, theta}
 }
+
+// TestTransformPoint tests that Matrix.TransformPoint() agrees with concatenating a translation
+// to the point.
+func TestTransformPoint(t *testing.T) {
+	const tol = 1.0e-10
+	m := NewMatrix(2, 1, -1, 3, 5, 7)
+	x, y := m.TransformPoint(1, 2)
+	if math.Abs(x-5) > tol || math.Abs(y-14) > tol {
+		t.Fatalf("Bad point: m=%s expected=(5,14) actual=(%g,%g)", m, x, y)
+	}
+	p := m.Mult(TranslationMatrix(1, 2))
+	if px, py := p.Translation(); math.Abs(x-px) > tol || math.Abs(y-py) > tol {
+		t.Fatalf("Bad point: m=%s expected=(%g,%g) actual=(%g,%g)", m, px, py, x, y)
+	}
+}
+
+// TestTransformBBox tests the Matrix.TransformBBox() function.
+func TestTransformBBox(t *testing.T) {
+	const tol = 1.0e-10
+	m := TranslationMatrix(10, 20).Mult(RotationMatrix(math.Pi / 2))
+	llx, lly, urx, ury := m.TransformBBox(0, 0, 2, 1)
+	expected := [4]float64{9, 20, 10, 22}
+	for i, v := range [4]float64{llx, lly, urx, ury} {
+		if math.Abs(v-expected[i]) > tol {
+			t.Fatalf("Bad bbox: m=%s expected=%v actual=%v", m, expected, [4]float64{llx, lly, urx, ury})
+		}
+	}
+}
+
+// TestInverse tests the Matrix.Inverse() function.
+func TestInverse(t *testing.T) {
+	const tol = 1.0e-10
+	m := NewMatrix(2, 1, -1, 3, 5, 7)
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("Matrix not invertible: m=%s", m)
+	}
+	id := IdentityMatrix()
+	for i, v := range inv.Mult(m) {
+		if math.Abs(v-id[i]) > tol {
+			t.Fatalf("Bad inverse: m=%s inv=%s", m, inv)
+		}
+	}
+	if _, ok := NewMatrix(1, 2, 2, 4, 0, 0).Inverse(); ok {
+		t.Fatalf("Singular matrix inverted")
+	}
+}
@@ -22,6 +22,10 @@ type PdfAppender struct {
 	pages    []*PdfPage
 	acroForm *PdfAcroForm
 
+	// Document metadata, nil if not changed.
+	info *PdfDocumentInfo
+	xmp  *XMPMetadata
+
 	xrefs          core.XrefTable
 	xrefOffset     int64
 	greatestObjNum int
@@ -242,7 +246,8 @@ func (a *PdfAppender) updateObjectsDeep(obj core.PdfObject, processed map[core.P
 			// Check if data has changed.
 			if streamObj, err := a.roReader.parser.LookupByReference(v.PdfObjectReference); err == nil {
 				var isNotChanged bool
-				if stream, ok := core.GetStream(streamObj); ok && bytes.Equal(stream.Stream, v.Stream) {
+				if stream, ok := core.GetStream(streamObj); ok && stream.LoadData() == nil && v.LoadData() == nil &&
+					bytes.Equal(stream.Stream, v.Stream) {
 					isNotChanged = true
 				}
 				if dict, ok := core.GetDict(streamObj); isNotChanged && ok {
@@ -536,6 +541,19 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
+// SetDocumentInfo sets the document information dictionary of the output PDF to `info`. The
+// metadata stream of the document, if any, is updated to be consistent with `info`.
+func (a *PdfAppender) SetDocumentInfo(info *PdfDocumentInfo) {
+	a.info = info
+}
+
+// SetXMPMetadata sets the XMP metadata of the output PDF, written as the metadata stream of the
+// document catalog. The properties corresponding to the entries of the document information
+// dictionary are set to the document information on writing.
+func (a *PdfAppender) SetXMPMetadata(metadata *XMPMetadata) {
+	a.xmp = metadata
+}
+
 // Write writes the Appender output to io.Writer.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
@@ -544,6 +562,20 @@ func (a *PdfAppender) Write(w io.Writer) error {
 	}
 
 	writer := NewPdfWriter()
+	if a.info != nil {
+		writer.SetDocumentInfo(a.info)
+	}
+	if a.xmp != nil {
+		writer.SetXMPMetadata(a.xmp)
+	} else if a.info != nil {
+		// Keep the metadata stream of the document consistent with the document information.
+		xmp, err := a.roReader.GetXMPMetadata()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to read XMP metadata: %v", err)
+		} else if xmp != nil {
+			writer.SetXMPMetadata(xmp)
+		}
+	}
 
 	pagesDict, ok := core.GetDict(writer.pages)
 	if !ok {
@@ -30,9 +30,13 @@ type pdfFont interface {
 // - Type0
 // - Type1
 // - TrueType
+// - Type3
 // etc.
 type PdfFont struct {
 	context pdfFont // The underlying font: Type0, Type1, Truetype, etc..
+
+	// obj is the PDF object of the font used by PdfPageResources.SetFont (see pdfObject).
+	obj core.PdfObject
 }
 
 // GetFontDescriptor returns the font descriptor for `font`.
@@ -54,6 +58,26 @@ func (font *PdfFont) BaseFont() string {
 	return font.baseFields().basefont
 }
 
+// IsSubset returns true if the font is an embedded font subset, i.e. if its BaseFont is prefixed
+// with a subset tag such as "EOODIA+" (section 9.6.4 "Font Subsets" PDF32000_2008).
+func (font *PdfFont) IsSubset() bool {
+	return isSubsetFontName(font.BaseFont())
+}
+
+// isSubsetFontName returns true if `name` starts with a font subset tag, which consists of six
+// uppercase letters followed by a plus sign.
+func isSubsetFontName(name string) bool {
+	if len(name) < 8 || name[6] != '+' {
+		return false
+	}
+	for i := 0; i < 6; i++ {
+		if name[i] < 'A' || name[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 // Subtype returns the font's "Subtype" field.
 func (font *PdfFont) Subtype() string {
 	subtype := font.baseFields().subtype
@@ -69,7 +93,8 @@ func (font *PdfFont) IsCID() bool {
 }
 
 // FontDescriptor returns font's PdfFontDescriptor. This may be a builtin descriptor for standard 14
-// fonts but must be an explicit descriptor for other fonts.
+// fonts but must be an explicit descriptor for other fonts. Type 3 fonts may have no descriptor, in
+// which case nil is returned.
 func (font *PdfFont) FontDescriptor() *PdfFontDescriptor {
 	if font.baseFields().fontDescriptor != nil {
 		return font.baseFields().fontDescriptor
@@ -77,6 +102,9 @@ func (font *PdfFont) FontDescriptor() *PdfFontDescriptor {
 	if d := font.context.getFontDescriptor(); d != nil {
 		return d
 	}
+	if _, ok := font.context.(*pdfFontType3); ok {
+		return nil
+	}
 	common.Log.Error("All fonts have a Descriptor. font=%s", font)
 	return nil
 }
@@ -290,7 +318,14 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 			return nil, err
 		}
 		font.context = type0font
-	case "Type1", "Type3", "MMType1", "TrueType":
+	case "Type3":
+		type3font, err := newPdfFontType3FromPdfObject(d, base)
+		if err != nil {
+			common.Log.Debug("ERROR: While loading Type3 font. font=%s err=%v", base, err)
+			return nil, err
+		}
+		font.context = type3font
+	case "Type1", "MMType1", "TrueType":
 		var simplefont *pdfFontSimple
 		fnt, builtin := fonts.NewStdFontByName(fonts.StdFontName(base.basefont))
 		if builtin {
@@ -335,6 +370,9 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 			common.Log.Error("simplefont=%s", simplefont)
 			common.Log.Error("fnt=%+v", fnt)
 		}
+		if len(simplefont.charWidths) == 0 {
+			simplefont.addFontFile3Widths()
+		}
 		if len(simplefont.charWidths) == 0 {
 			common.Log.Debug("ERROR: No widths. font=%s", simplefont)
 		}
@@ -391,6 +429,34 @@ func (font *PdfFont) BytesToCharcodes(data []byte) []textencoding.CharCode {
 	return charcodes
 }
 
+// SplitCharcodeBytes splits `data` into the byte sequences of the character codes in `data`.
+// The sequences correspond to the character codes returned by BytesToCharcodes.
+func (font *PdfFont) SplitCharcodeBytes(data []byte) [][]byte {
+	if type0, ok := font.context.(*pdfFontType0); ok && type0.codeToCID != nil {
+		if split, ok := type0.codeToCID.SplitCharcodeBytes(data); ok {
+			return split
+		}
+	}
+
+	var split [][]byte
+	if font.baseFields().isCIDFont() {
+		if len(data) == 1 {
+			data = []byte{0, data[0]}
+		}
+		if len(data)%2 != 0 {
+			data = append(data, 0)
+		}
+		for i := 0; i < len(data); i += 2 {
+			split = append(split, data[i:i+2])
+		}
+	} else {
+		for i := range data {
+			split = append(split, data[i:i+1])
+		}
+	}
+	return split
+}
+
 // CharcodesToUnicodeWithStats is identical to CharcodesToUnicode except returns more statistical
 // information about hits and misses from the reverse mapping process.
 func (font *PdfFont) CharcodesToUnicodeWithStats(charcodes []textencoding.CharCode) (runelist []rune, numHits, numMisses int) {
@@ -558,6 +624,38 @@ func (font *PdfFont) GetRuneMetrics(r rune) (CharMetrics, bool) {
 	return fonts.CharMetrics{}, false
 }
 
+// HasRune returns true if `font` has a glyph for rune `r`, i.e. `r` is encoded by the font's
+// encoding and the font has metrics for the glyph. Unlike GetRuneMetrics, the missing width of the
+// font is not used for runes without a glyph.
+func (font *PdfFont) HasRune(r rune) bool {
+	t := font.actualFont()
+	if t == nil {
+		return false
+	}
+	simple, isSimple := t.(*pdfFontSimple)
+	if isSimple && simple.fontMetrics != nil {
+		_, has := simple.fontMetrics[r]
+		return has
+	}
+	encoder := t.Encoder()
+	if encoder == nil {
+		return false
+	}
+	if ttEncoder, ok := encoder.(textencoding.TrueTypeFontEncoder); ok {
+		// The rune is not registered for subsetting. Glyph 0 is the .notdef glyph.
+		gid, ok := ttEncoder.RuneToGID(r)
+		return ok && gid != 0
+	}
+	code, ok := encoder.RuneToCharcode(r)
+	if !ok {
+		return false
+	}
+	if isSimple {
+		_, ok = simple.GetCharMetrics(code)
+	}
+	return ok
+}
+
 // GetCharMetrics returns the char metrics for character code `code`.
 // How it works:
 //  1) It calls the GetCharMetrics function for the underlying font, either a simple font or
@@ -594,6 +692,10 @@ func (font *PdfFont) GetCharMetrics(code textencoding.CharCode) (CharMetrics, bo
 		if m, ok := t.GetCharMetrics(code); ok {
 			return m, ok
 		}
+	case *pdfFontType3:
+		if m, ok := t.GetCharMetrics(code); ok {
+			return m, ok
+		}
 	default:
 		common.Log.Debug("ERROR: GetCharMetrics not implemented for font type=%T.", font.context)
 		return nometrics, false
@@ -753,13 +855,9 @@ func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDict
 		font.name = name
 	}
 
-	if subtype == "Type3" {
-		common.Log.Debug("ERROR: Type 3 font not supported. d=%s", d)
-		return d, font, ErrType3FontNotSupported
-	}
-
+	// BaseFont is not used by Type 3 fonts.
 	basefont, ok := core.GetNameVal(d.Get("BaseFont"))
-	if !ok {
+	if !ok && subtype != "Type3" {
 		common.Log.Debug("ERROR: Font Incompatibility. BaseFont (Required) missing")
 		return d, font, ErrRequiredAttributeMissing
 	}
@@ -864,6 +962,11 @@ type PdfFontDescriptor struct {
 	missingWidth float64
 	*fontFile
 	fontFile2 *fonts.TtfType
+	fontFile3 *fonts.CFFFont
+
+	// The glyph outlines of the TrueType font program, loaded on first use.
+	ttfOutlines       *fonts.TrueTypeOutlines
+	ttfOutlinesLoaded bool
 
 	// Additional entries for CIDFonts
 	Style  core.PdfObject
@@ -905,7 +1008,11 @@ func (desc *PdfFontDescriptor) String() string {
 	if desc.fontFile2 != nil {
 		parts = append(parts, desc.fontFile2.String())
 	}
-	parts = append(parts, fmt.Sprintf("FontFile3=%t", desc.FontFile3 != nil))
+	if desc.fontFile3 != nil {
+		parts = append(parts, desc.fontFile3.String())
+	} else {
+		parts = append(parts, fmt.Sprintf("FontFile3=%t", desc.FontFile3 != nil))
+	}
 
 	return fmt.Sprintf("FONT_DESCRIPTOR{%s}", strings.Join(parts, ", "))
 }
@@ -997,6 +1104,13 @@ func newPdfFontDescriptorFromPdfObject(obj core.PdfObject) (*PdfFontDescriptor,
 		common.Log.Trace("fontFile2=%s", fontFile2.String())
 		descriptor.fontFile2 = &fontFile2
 	}
+	if descriptor.FontFile3 != nil {
+		// The font can be used without its font program, so a font program that cannot be loaded
+		// is not an error.
+		if err := descriptor.loadFontFile3(); err != nil {
+			common.Log.Debug("ERROR: Unable to load FontFile3: %v", err)
+		}
+	}
 	return descriptor, nil
 }
 
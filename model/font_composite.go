@@ -101,6 +101,20 @@ type pdfFontType0 struct {
 	Encoding       core.PdfObject
 	DescendantFont *PdfFont // Can be either CIDFontType0 or CIDFontType2 font.
 	codeToCID      *cmap.CMap
+
+	// ttfSubset is set for fonts created from TrueType font files and is used for subsetting the
+	// embedded font program.
+	ttfSubset *trueTypeSubset
+	// shaping is set for fonts created from TrueType font files and is used for shaping text.
+	shaping *fontShaping
+
+	// vertical is true for fonts used for vertical writing (see PdfFont.IsVertical).
+	vertical bool
+	// verticalMetrics is set for vertical fonts created from TrueType font files and is used for
+	// the vertical metrics of the embedded subset.
+	verticalMetrics *ttfVerticalMetrics
+	// verticalFont is the vertical variant of the font (see PdfFont.VerticalFont).
+	verticalFont *PdfFont
 }
 
 // pdfFontType0FromSkeleton returns a pdfFontType0 with its common fields initalized.
@@ -207,6 +221,7 @@ func newPdfFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon)
 
 	font := pdfFontType0FromSkeleton(base)
 	font.DescendantFont = df
+	font.vertical = isVerticalEncoding(d.Get("Encoding"))
 
 	encoderName, ok := core.GetNameVal(d.Get("Encoding"))
 	if ok {
@@ -256,6 +271,10 @@ type pdfCIDFontType0 struct {
 
 	widths       map[textencoding.CharCode]float64
 	defaultWidth float64
+	vertical     *cidVerticalMetrics // The vertical metrics (DW2 and W2).
+
+	// Mapping between unicode runes to widths, for fonts created from OpenType font files.
+	runeToWidthMap map[rune]int
 }
 
 // pdfCIDFontType0FromSkeleton returns a pdfCIDFontType0 with its common fields initalized.
@@ -282,6 +301,9 @@ func (font pdfCIDFontType0) Encoder() textencoding.TextEncoder {
 // GetRuneMetrics returns the character metrics for the specified rune.
 // A bool flag is returned to indicate whether or not the entry was found.
 func (font pdfCIDFontType0) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
+	if w, ok := font.runeToWidthMap[r]; ok {
+		return fonts.CharMetrics{Wx: float64(w)}, true
+	}
 	return fonts.CharMetrics{Wx: font.defaultWidth}, true
 }
 
@@ -297,7 +319,29 @@ func (font pdfCIDFontType0) GetCharMetrics(code textencoding.CharCode) (fonts.Ch
 
 // ToPdfObject converts the pdfCIDFontType0 to a PDF representation.
 func (font *pdfCIDFontType0) ToPdfObject() core.PdfObject {
-	return core.MakeNull()
+	if font.container == nil {
+		font.container = &core.PdfIndirectObject{}
+	}
+	d := font.baseFields().asPdfObjectDictionary("CIDFontType0")
+	font.container.PdfObject = d
+
+	if font.CIDSystemInfo != nil {
+		d.Set("CIDSystemInfo", font.CIDSystemInfo)
+	}
+	if font.DW != nil {
+		d.Set("DW", font.DW)
+	}
+	if font.DW2 != nil {
+		d.Set("DW2", font.DW2)
+	}
+	if font.W != nil {
+		d.Set("W", font.W)
+	}
+	if font.W2 != nil {
+		d.Set("W2", font.W2)
+	}
+
+	return font.container
 }
 
 // newPdfCIDFontType0FromPdfObject creates a pdfCIDFontType0 object from a dictionary (either direct
@@ -323,6 +367,11 @@ func newPdfCIDFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	font.W = d.Get("W")
 	font.DW2 = d.Get("DW2")
 	font.W2 = d.Get("W2")
+	vertical, err := parseCIDFontVerticalMetrics(font.DW2, font.W2)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid vertical metrics: %v. font=%s", err, base)
+	}
+	font.vertical = vertical
 
 	// Get font default glyph width.
 	font.defaultWidth = 1000.0
@@ -370,6 +419,7 @@ type pdfCIDFontType2 struct {
 
 	widths       map[textencoding.CharCode]float64
 	defaultWidth float64
+	vertical     *cidVerticalMetrics // The vertical metrics (DW2 and W2).
 
 	// Mapping between unicode runes to widths.
 	// TODO(dennwc): it is used only in GetGlyphCharMetrics
@@ -480,6 +530,11 @@ func newPdfCIDFontType2FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	font.W = d.Get("W")
 	font.DW2 = d.Get("DW2")
 	font.W2 = d.Get("W2")
+	vertical, err := parseCIDFontVerticalMetrics(font.DW2, font.W2)
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid vertical metrics: %v. font=%s", err, base)
+	}
+	font.vertical = vertical
 	font.CIDToGIDMap = d.Get("CIDToGIDMap")
 
 	// Get font default glyph width.
@@ -562,11 +617,15 @@ func parseCIDFontWidthsArray(w core.PdfObject) (map[textencoding.CharCode]float6
 	return fontWidths, nil
 }
 
-// NewCompositePdfFontFromTTFFile loads a composite font from a TTF font file. Composite fonts can
-// be used to represent unicode fonts which can have multi-byte character codes, representing a wide
-// range of values.
-// It is represented by a Type0 Font with an underlying CIDFontType2 and an Identity-H encoding map.
-// TODO: May be extended in the future to support a larger variety of CMaps and vertical fonts.
+// NewCompositePdfFontFromTTFFile loads a composite font from a TTF or OTF font file. Composite
+// fonts can be used to represent unicode fonts which can have multi-byte character codes,
+// representing a wide range of values.
+// It is represented by a Type0 Font with an Identity-H encoding map and an underlying CIDFontType2
+// for fonts with TrueType outlines, or CIDFontType0 for OpenType fonts with CFF outlines. The
+// font program of OpenType fonts with CFF outlines is embedded as a FontFile3 stream with subtype
+// OpenType.
+// The variant of the font for vertical writing is returned by PdfFont.VerticalFont.
+// TODO: May be extended in the future to support a larger variety of CMaps.
 func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 	// Load the truetype font data.
 	ttfBytes, err := ioutil.ReadFile(filePath)
@@ -574,21 +633,26 @@ func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 		common.Log.Debug("ERROR: while reading ttf font: %v", err)
 		return nil, err
 	}
+	return newCompositePdfFontFromTTF(ttfBytes, false)
+}
+
+// newCompositePdfFontFromTTF loads a composite font from the TTF or OTF font data `ttfBytes`.
+// The font has the Identity-V encoding and the vertical forms of the glyphs if `vertical` is true
+// (see PdfFont.VerticalFont), otherwise the Identity-H encoding.
+func newCompositePdfFontFromTTF(ttfBytes []byte, vertical bool) (*PdfFont, error) {
 	ttf, err := fonts.TtfParse(bytes.NewReader(ttfBytes))
 	if err != nil {
 		common.Log.Debug("ERROR: while loading ttf font: %v", err)
 		return nil, err
 	}
-
-	// Prepare the inner descendant font (CIDFontType2).
-	cidfont := &pdfCIDFontType2{
-		fontCommon: fontCommon{
-			subtype: "CIDFontType2",
-		},
-
-		// Use identity character id (CID) to glyph id (GID) mapping.
-		// Code below relies on the fact that identity mapping is used.
-		CIDToGIDMap: core.MakeName("Identity"),
+	shaper, err := fonts.NewShaper(&ttf, ttfBytes)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to read font layout tables: %v", err)
+		return nil, err
+	}
+	if vertical {
+		// The runes are mapped to the vertical forms of their glyphs.
+		ttf.Chars = verticalChars(ttf.Chars, shaper.VerticalSubstitutions())
 	}
 
 	// 2-byte character codes ➞ runes
@@ -617,20 +681,14 @@ func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 		w := k * float64(ttf.Widths[gid])
 		runeToWidthMap[r] = int(w)
 	}
-	cidfont.runeToWidthMap = runeToWidthMap
-
-	// Default width.
-	cidfont.DW = core.MakeInteger(int64(missingWidth))
 
 	// Construct W array.  Stores character code to width mappings.
 	wArr := makeCIDWidthArr(runes, runeToWidthMap, ttf.Chars)
-	cidfont.W = core.MakeIndirectObject(wArr)
 
 	d := core.MakeDict()
 	d.Set("Ordering", core.MakeString("Identity"))
 	d.Set("Registry", core.MakeString("Adobe"))
 	d.Set("Supplement", core.MakeInteger(0))
-	cidfont.CIDSystemInfo = d
 
 	// Make the font descriptor.
 	descriptor := &PdfFontDescriptor{
@@ -648,14 +706,19 @@ func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 		MissingWidth: core.MakeFloat(k * float64(ttf.Widths[0])),
 	}
 
-	// Embed the TrueType font program.
+	// Embed the font program.
 	stream, err := core.MakeStream(ttfBytes, core.NewFlateEncoder())
 	if err != nil {
 		common.Log.Debug("ERROR: Unable to make stream: %v", err)
 		return nil, err
 	}
-	stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(ttfBytes))))
-	descriptor.FontFile2 = stream
+	if ttf.CFF {
+		stream.PdfObjectDictionary.Set("Subtype", core.MakeName("OpenType"))
+		descriptor.FontFile3 = stream
+	} else {
+		stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(ttfBytes))))
+		descriptor.FontFile2 = stream
+	}
 
 	if ttf.Bold {
 		descriptor.StemV = core.MakeInteger(120)
@@ -673,8 +736,69 @@ func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 	}
 	descriptor.Flags = core.MakeInteger(int64(flags))
 
-	cidfont.basefont = ttf.PostScriptName
-	cidfont.fontDescriptor = descriptor
+	// The metrics for vertical writing.
+	var (
+		verticalMetrics *ttfVerticalMetrics
+		dw2, w2         core.PdfObject
+		cidVertical     *cidVerticalMetrics
+	)
+	if vertical {
+		verticalMetrics = &ttfVerticalMetrics{
+			widths:  ttf.Widths,
+			heights: ttf.Heights,
+			scale:   k,
+			vy:      int(k * float64(ttf.TypoAscender)),
+		}
+		gids := make([]fonts.GID, 0, len(ttf.Chars))
+		for _, gid := range ttf.Chars {
+			gids = append(gids, gid)
+		}
+		w2Arr := verticalMetrics.makeW2(gids)
+		dw2, w2 = verticalMetrics.defaultMetrics(), core.MakeIndirectObject(w2Arr)
+		cidVertical, err = parseCIDFontVerticalMetrics(dw2, w2Arr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Prepare the inner descendant font.
+	var cidfont pdfFont
+	if ttf.CFF {
+		// The CFF font program is not CID-keyed, the CIDs are used as glyph indices.
+		cidfont = &pdfCIDFontType0{
+			fontCommon: fontCommon{
+				subtype:        "CIDFontType0",
+				basefont:       ttf.PostScriptName,
+				fontDescriptor: descriptor,
+			},
+			CIDSystemInfo:  d,
+			DW:             core.MakeInteger(int64(missingWidth)),
+			W:              core.MakeIndirectObject(wArr),
+			DW2:            dw2,
+			W2:             w2,
+			vertical:       cidVertical,
+			runeToWidthMap: runeToWidthMap,
+		}
+	} else {
+		cidfont = &pdfCIDFontType2{
+			fontCommon: fontCommon{
+				subtype:        "CIDFontType2",
+				basefont:       ttf.PostScriptName,
+				fontDescriptor: descriptor,
+			},
+			CIDSystemInfo: d,
+			DW:            core.MakeInteger(int64(missingWidth)),
+			W:             core.MakeIndirectObject(wArr),
+			DW2:           dw2,
+			W2:            w2,
+			vertical:      cidVertical,
+
+			// Use identity character id (CID) to glyph id (GID) mapping.
+			// Code below relies on the fact that identity mapping is used.
+			CIDToGIDMap:    core.MakeName("Identity"),
+			runeToWidthMap: runeToWidthMap,
+		}
+	}
 
 	// Make root Type0 font.
 	type0 := pdfFontType0{
@@ -688,8 +812,35 @@ func NewCompositePdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 		Encoding: core.MakeName("Identity-H"),
 		encoder:  ttf.NewEncoder(),
 	}
+	if vertical {
+		type0.Encoding = core.MakeName("Identity-V")
+		type0.vertical = true
+		type0.verticalMetrics = verticalMetrics
+	}
 
 	type0.toUnicodeCmap = ttf.MakeToUnicode()
+	// The ToUnicode stream is kept so that it can be updated when the font is subset.
+	toUnicode, err := core.MakeStream(type0.toUnicodeCmap.Bytes(), nil)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to make stream: %v", err)
+		return nil, err
+	}
+	type0.toUnicode = toUnicode
+	type0.ttfSubset = &trueTypeSubset{
+		data:           ttfBytes,
+		postScriptName: ttf.PostScriptName,
+		fontFile:       stream,
+		cff:            ttf.CFF,
+	}
+	if !vertical {
+		// Text is shaped in horizontal writing mode only.
+		type0.shaping = &fontShaping{
+			shaper: shaper,
+			widths: ttf.Widths,
+			scale:  k,
+			glyphs: make(map[fonts.GID]rune),
+		}
+	}
 
 	// Build Font.
 	font := PdfFont{
@@ -0,0 +1,475 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adrg/sysfont"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// FontFileFinder finds the TrueType and OpenType font files of fonts which are not embedded in
+// documents (see PdfReader.EmbedFonts).
+type FontFileFinder interface {
+	// FindFontFile returns the path of the font file of the font named `name`, the BaseFont of a
+	// font, e.g. "Arial,Bold", "TimesNewRomanPSMT" or "Helvetica". The bool is false if the font
+	// is not found.
+	FindFontFile(name string) (string, bool)
+}
+
+// std14FontFamilies are the families of fonts with the metrics of the families of the standard 14
+// fonts, which can substitute them.
+var std14FontFamilies = map[string][]string{
+	"helvetica": {"arial", "liberationsans", "freesans", "nimbussans"},
+	"times":     {"timesnewroman", "liberationserif", "freeserif", "nimbusroman"},
+	"courier":   {"couriernew", "liberationmono", "freemono", "nimbusmono"},
+}
+
+// fontNameStyles are the style suffixes of font names, with their normalized forms.
+var fontNameStyles = []struct{ suffix, style string }{
+	{"bolditalic", "bolditalic"},
+	{"boldoblique", "bolditalic"},
+	{"italic", "italic"},
+	{"oblique", "italic"},
+	{"bold", "bold"},
+	{"roman", ""},
+	{"regular", ""},
+}
+
+// normalizeFontName returns the normalized family and style of font name `name`: lower case
+// letters and digits, without subset tag and without the "MT" and "PS" suffixes of names of
+// PostScript fonts, e.g. "arial" and "bold" for both "Arial,Bold" and "Arial-BoldMT".
+func normalizeFontName(name string) (string, string) {
+	if isSubsetFontName(name) {
+		name = name[7:]
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	norm := strings.TrimSuffix(b.String(), "mt")
+
+	style := ""
+	for _, s := range fontNameStyles {
+		if s.suffix == "roman" && strings.HasSuffix(norm, "newroman") {
+			// Times New Roman.
+			continue
+		}
+		if strings.HasSuffix(norm, s.suffix) {
+			norm = strings.TrimSuffix(norm, s.suffix)
+			style = s.style
+			break
+		}
+	}
+	norm = strings.TrimSuffix(norm, "ps")
+	return norm, style
+}
+
+// fontNameCandidates returns the normalized names of the fonts which can be embedded for the font
+// named `name`: the font itself and, for the standard 14 fonts, fonts with the same metrics.
+func fontNameCandidates(name string) []string {
+	family, style := normalizeFontName(name)
+	candidates := []string{family + style}
+	for _, f := range std14FontFamilies[family] {
+		candidates = append(candidates, f+style)
+	}
+	return candidates
+}
+
+// fontDirectoryFinder is a FontFileFinder of the font files in a directory.
+type fontDirectoryFinder struct {
+	files map[string]string // Font file paths, by normalized font name.
+}
+
+// NewFontDirectoryFinder returns a FontFileFinder of the TrueType and OpenType font files in
+// directory `dir` and its subdirectories. The fonts are found by their PostScript names and file
+// names, ignoring case, punctuation and subset tags, e.g. "Arial,Bold" is found in the file of
+// the font named "Arial-BoldMT". The standard 14 fonts are substituted by fonts with the same
+// metrics, e.g. Arial or Liberation Sans for Helvetica.
+func NewFontDirectoryFinder(dir string) (FontFileFinder, error) {
+	finder := &fontDirectoryFinder{files: make(map[string]string)}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if info.IsDir() || ext != ".ttf" && ext != ".otf" {
+			return nil
+		}
+		finder.add(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path)
+		ttf, err := fonts.TtfParseFile(path)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to parse font file %s: %v", path, err)
+			return nil
+		}
+		finder.add(ttf.PostScriptName, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return finder, nil
+}
+
+// add adds the font file `path` of the font named `name`.
+func (f *fontDirectoryFinder) add(name, path string) {
+	family, style := normalizeFontName(name)
+	if _, ok := f.files[family+style]; !ok && family != "" {
+		f.files[family+style] = path
+	}
+}
+
+// FindFontFile implements FontFileFinder.
+func (f *fontDirectoryFinder) FindFontFile(name string) (string, bool) {
+	for _, candidate := range fontNameCandidates(name) {
+		if path, ok := f.files[candidate]; ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// systemFontFinder is a FontFileFinder of the fonts installed in the system.
+type systemFontFinder struct {
+	finder *sysfont.Finder
+}
+
+// NewSystemFontFinder returns a FontFileFinder of the TrueType and OpenType fonts installed in the
+// system. The fonts are matched by family and style, fonts which are not installed are
+// substituted by similar installed fonts, e.g. Arial or Liberation Sans for Helvetica.
+func NewSystemFontFinder() FontFileFinder {
+	return &systemFontFinder{
+		finder: sysfont.NewFinder(&sysfont.FinderOpts{
+			Extensions: []string{".ttf", ".otf"},
+		}),
+	}
+}
+
+// FindFontFile implements FontFileFinder.
+func (f *systemFontFinder) FindFontFile(name string) (string, bool) {
+	if isSubsetFontName(name) {
+		name = name[7:]
+	}
+	font := f.finder.Match(strings.Replace(name, ",", "-", -1))
+	if font == nil || font.Filename == "" {
+		return "", false
+	}
+	return font.Filename, true
+}
+
+// errFontNotEmbeddable is returned for fonts that cannot be embedded.
+var errFontNotEmbeddable = errors.New("font cannot be embedded")
+
+// EmbedFonts embeds the font programs of the fonts of the document which are not embedded, from
+// the font files found by `finder`. It returns the inventory entries of the embedded fonts
+// (see GetFontInventory).
+//
+// The fonts are replaced in place: the font dictionaries and their font descriptors are updated
+// with subsets of the font programs, containing the glyphs of the characters shown with the fonts,
+// and the documents written with the pages of the reader include the embedded fonts. The encodings
+// and the glyph widths of the fonts are preserved, so that the content streams, which are not
+// changed, draw the same text with the same layout. The widths of the standard 14 fonts, which
+// fonts do not need to specify, are added to the font dictionaries.
+//
+// Simple fonts are embedded as TrueType fonts, or as Type1 fonts with OpenType font programs with
+// CFF outlines. Composite fonts are embedded as CIDFontType2 fonts, with a CIDToGIDMap mapping
+// the CIDs to the glyphs of the runes they represent in the ToUnicode CMap; their font files must
+// be TrueType fonts. Type3 fonts, fonts whose files are not found and fonts with characters
+// missing in their font files are not embedded.
+func (r *PdfReader) EmbedFonts(finder FontFileFinder) ([]*FontInventoryEntry, error) {
+	inventory, err := r.GetFontInventory()
+	if err != nil {
+		return nil, err
+	}
+
+	var embedded []*FontInventoryEntry
+	for _, entry := range inventory {
+		if entry.Embedded {
+			continue
+		}
+		path, ok := finder.FindFontFile(entry.BaseFont)
+		if !ok {
+			common.Log.Debug("Font file not found: %s", entry.BaseFont)
+			continue
+		}
+		if err := entry.embed(path); err != nil {
+			common.Log.Debug("ERROR: Unable to embed font %s from %s: %v", entry.BaseFont, path, err)
+			continue
+		}
+		embedded = append(embedded, entry)
+	}
+	return embedded, nil
+}
+
+// embed embeds the subset of the font program of font file `path` in the font of `entry` and
+// updates `entry`.
+func (entry *FontInventoryEntry) embed(path string) error {
+	d, ok := core.GetDict(entry.Object)
+	if !ok {
+		return core.ErrTypeError
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ttf, err := fonts.TtfParse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	codes := entry.Charcodes
+	if len(codes) == 0 {
+		codes = []textencoding.CharCode{' '}
+	}
+
+	switch t := entry.Font.context.(type) {
+	case *pdfFontSimple:
+		err = embedSimpleFont(d, entry.Font, &ttf, data, codes)
+	case *pdfFontType0:
+		err = embedCompositeFont(d, entry.Font, t, &ttf, data, codes)
+	default:
+		err = errFontNotEmbeddable
+	}
+	if err != nil {
+		return err
+	}
+
+	font, err := NewPdfFontFromPdfObject(entry.Object)
+	if err != nil {
+		return err
+	}
+	embedded := newFontInventoryEntry(font, entry.Object)
+	entry.Font = font
+	entry.BaseFont = embedded.BaseFont
+	entry.Subtype = embedded.Subtype
+	entry.Encoding = embedded.Encoding
+	entry.Embedded = embedded.Embedded
+	entry.Subset = embedded.Subset
+	entry.FontFileType = embedded.FontFileType
+	entry.MissingGlyphs = nil
+	return nil
+}
+
+// embedSimpleFont embeds the subset of font program `data` of font `ttf` with the glyphs of the
+// character codes `codes` in the simple font `font` with font dictionary `d`.
+func embedSimpleFont(d *core.PdfObjectDictionary, font *PdfFont, ttf *fonts.TtfType, data []byte,
+	codes []textencoding.CharCode) error {
+	encoder := font.Encoder()
+	if encoder == nil {
+		return errFontNotEmbeddable
+	}
+	runeToGID := make(map[rune]fonts.GID)
+	for _, code := range codes {
+		r, ok := encoder.CharcodeToRune(code)
+		if !ok {
+			return errFontNotEmbeddable
+		}
+		gid, ok := ttf.Chars[r]
+		if !ok || gid == 0 {
+			common.Log.Debug("ERROR: Glyph of rune %+q not in font %s", r, ttf.PostScriptName)
+			return errFontNotEmbeddable
+		}
+		runeToGID[r] = gid
+	}
+	basefont, stream, err := embeddedSubset(ttf, data, runeToGID)
+	if err != nil {
+		return err
+	}
+
+	// The widths of the standard 14 fonts are given by the standard font metrics.
+	if d.Get("Widths") == nil {
+		first, last := codes[0], codes[len(codes)-1]
+		widths := make([]float64, 0, last-first+1)
+		for code := first; code <= last; code++ {
+			metrics, _ := font.GetCharMetrics(code)
+			widths = append(widths, metrics.Wx)
+		}
+		d.Set("FirstChar", core.MakeInteger(int64(first)))
+		d.Set("LastChar", core.MakeInteger(int64(last)))
+		d.Set("Widths", core.MakeArrayFromFloats(widths))
+	}
+	// The glyphs of the embedded program are mapped by the names of the glyphs of the encoding,
+	// which must be specified for nonsymbolic fonts.
+	if d.Get("Encoding") == nil {
+		d.Set("Encoding", differencesEncoding(encoder, codes))
+	}
+
+	subtype := "TrueType"
+	if ttf.CFF {
+		subtype = "Type1"
+	}
+	d.Set("Subtype", core.MakeName(subtype))
+	d.Set("BaseFont", core.MakeName(basefont))
+	embedFontDescriptor(d, ttf, basefont, stream, true)
+	return nil
+}
+
+// differencesEncoding returns an encoding dictionary of the encoding of `encoder` for the character
+// codes `codes`, as the differences from the WinAnsiEncoding.
+func differencesEncoding(encoder textencoding.TextEncoder, codes []textencoding.CharCode) core.PdfObject {
+	winAnsi := textencoding.NewWinAnsiEncoder()
+	differences := core.MakeArray()
+	for _, code := range codes {
+		r, ok := encoder.CharcodeToRune(code)
+		if !ok {
+			continue
+		}
+		if wr, ok := winAnsi.CharcodeToRune(code); ok && wr == r {
+			continue
+		}
+		if glyph, ok := textencoding.RuneToGlyph(r); ok {
+			differences.Append(core.MakeInteger(int64(code)), core.MakeName(string(glyph)))
+		}
+	}
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("Encoding"))
+	dict.Set("BaseEncoding", core.MakeName("WinAnsiEncoding"))
+	if differences.Len() > 0 {
+		dict.Set("Differences", differences)
+	}
+	return dict
+}
+
+// embedCompositeFont embeds the subset of font program `data` of font `ttf` with the glyphs of the
+// character codes `codes` in the composite font `font` with font dictionary `d`.
+func embedCompositeFont(d *core.PdfObjectDictionary, font *PdfFont, type0 *pdfFontType0,
+	ttf *fonts.TtfType, data []byte, codes []textencoding.CharCode) error {
+	descendants, ok := core.GetArray(d.Get("DescendantFonts"))
+	if !ok || descendants.Len() != 1 || ttf.CFF {
+		return errFontNotEmbeddable
+	}
+	dd, ok := core.GetDict(descendants.Get(0))
+	if !ok {
+		return errFontNotEmbeddable
+	}
+
+	runeToGID := make(map[rune]fonts.GID)
+	cidToGID := make(map[textencoding.CharCode]fonts.GID)
+	var maxCID textencoding.CharCode
+	for _, code := range codes {
+		cid := code
+		if type0.codeToCID != nil {
+			c, ok := type0.codeToCID.CharcodeToCID(cmap.CharCode(code))
+			if !ok {
+				return errFontNotEmbeddable
+			}
+			cid = textencoding.CharCode(c)
+		}
+		runes, _, numMisses := font.CharcodesToUnicodeWithStats([]textencoding.CharCode{code})
+		if numMisses > 0 || len(runes) != 1 {
+			common.Log.Debug("ERROR: No rune for code %d of font %s", code, font.BaseFont())
+			return errFontNotEmbeddable
+		}
+		gid, ok := ttf.Chars[runes[0]]
+		if !ok || gid == 0 {
+			common.Log.Debug("ERROR: Glyph of rune %+q not in font %s", runes[0], ttf.PostScriptName)
+			return errFontNotEmbeddable
+		}
+		runeToGID[runes[0]] = gid
+		cidToGID[cid] = gid
+		if cid > maxCID {
+			maxCID = cid
+		}
+	}
+	basefont, stream, err := embeddedSubset(ttf, data, runeToGID)
+	if err != nil {
+		return err
+	}
+
+	cidToGIDMap := make([]byte, 2*(int(maxCID)+1))
+	for cid, gid := range cidToGID {
+		cidToGIDMap[2*cid] = byte(gid >> 8)
+		cidToGIDMap[2*cid+1] = byte(gid)
+	}
+	cidToGIDStream, err := core.MakeStream(cidToGIDMap, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+
+	dd.Set("Subtype", core.MakeName("CIDFontType2"))
+	dd.Set("BaseFont", core.MakeName(basefont))
+	dd.Set("CIDToGIDMap", cidToGIDStream)
+	embedFontDescriptor(dd, ttf, basefont, stream, false)
+	if encoding, ok := core.GetNameVal(d.Get("Encoding")); ok {
+		basefont += "-" + encoding
+	}
+	d.Set("BaseFont", core.MakeName(basefont))
+	return nil
+}
+
+// embeddedSubset returns the subset of font program `data` of font `ttf` with the glyphs of
+// `runeToGID`, as a font file stream, and the font name with the subset tag.
+func embeddedSubset(ttf *fonts.TtfType, data []byte, runeToGID map[rune]fonts.GID) (string,
+	*core.PdfObjectStream, error) {
+	gidSet := make(map[fonts.GID]struct{}, len(runeToGID))
+	for _, gid := range runeToGID {
+		gidSet[gid] = struct{}{}
+	}
+	gids := make([]fonts.GID, 0, len(gidSet))
+	for gid := range gidSet {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	subsetFont := fonts.SubsetTrueType
+	if ttf.CFF {
+		subsetFont = fonts.SubsetOpenType
+	}
+	subset, err := subsetFont(data, gids, runeToGID)
+	if err != nil {
+		return "", nil, err
+	}
+	stream, err := core.MakeStream(subset, core.NewFlateEncoder())
+	if err != nil {
+		return "", nil, err
+	}
+	if ttf.CFF {
+		stream.Set("Subtype", core.MakeName("OpenType"))
+	} else {
+		stream.Set("Length1", core.MakeInteger(int64(len(subset))))
+	}
+	return subsetTag(gids) + "+" + ttf.PostScriptName, stream, nil
+}
+
+// embedFontDescriptor sets the font program `stream` of font `ttf` and the font name `basefont` in
+// the font descriptor of font dictionary `d`, which is a simple font if `simple` is true. A font
+// descriptor with the metrics of `ttf` is created for fonts without font descriptors, e.g. the
+// standard 14 fonts.
+func embedFontDescriptor(d *core.PdfObjectDictionary, ttf *fonts.TtfType, basefont string,
+	stream *core.PdfObjectStream, simple bool) {
+	desc, ok := core.GetDict(d.Get("FontDescriptor"))
+	if !ok {
+		obj := newTTFFontDescriptor(ttf).ToPdfObject()
+		d.Set("FontDescriptor", obj)
+		desc, _ = core.GetDict(obj)
+	}
+	desc.Set("FontName", core.MakeName(basefont))
+	if ttf.CFF {
+		desc.Set("FontFile3", stream)
+	} else {
+		desc.Set("FontFile2", stream)
+	}
+	// The glyph names of the CharSet and the CIDs of the CIDSet are those of the font that was not
+	// embedded.
+	desc.Remove("CharSet")
+	desc.Remove("CIDSet")
+
+	// The glyphs of simple fonts are mapped through their encodings, which requires nonsymbolic
+	// fonts.
+	if simple {
+		flags, _ := core.GetIntVal(desc.Get("Flags"))
+		flags = flags&^fontFlagSymbolic | fontFlagNonsymbolic
+		desc.Set("Flags", core.MakeInteger(int64(flags)))
+	}
+}
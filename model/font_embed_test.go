@@ -0,0 +1,156 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+)
+
+func TestNormalizeFontName(t *testing.T) {
+	testcases := []struct {
+		name, family, style string
+	}{
+		{"Arial", "arial", ""},
+		{"ArialMT", "arial", ""},
+		{"Arial,Bold", "arial", "bold"},
+		{"Arial-BoldMT", "arial", "bold"},
+		{"ABCDEF+Arial-BoldItalicMT", "arial", "bolditalic"},
+		{"Helvetica-Oblique", "helvetica", "italic"},
+		{"Times-Roman", "times", ""},
+		{"TimesNewRoman", "timesnewroman", ""},
+		{"TimesNewRomanPSMT", "timesnewroman", ""},
+		{"TimesNewRomanPS-BoldMT", "timesnewroman", "bold"},
+		{"OpenSans-Regular", "opensans", ""},
+	}
+	for _, tc := range testcases {
+		family, style := normalizeFontName(tc.name)
+		assert.Equal(t, tc.family, family, tc.name)
+		assert.Equal(t, tc.style, style, tc.name)
+	}
+}
+
+// removeFontFile removes the font program from the font descriptor of font dictionary `obj`.
+func removeFontFile(t *testing.T, obj core.PdfObject) {
+	d, ok := core.GetDict(obj)
+	require.True(t, ok)
+	if descendants, ok := core.GetArray(d.Get("DescendantFonts")); ok {
+		d, ok = core.GetDict(descendants.Get(0))
+		require.True(t, ok)
+	}
+	desc, ok := core.GetDict(d.Get("FontDescriptor"))
+	require.True(t, ok)
+	desc.Remove("FontFile2")
+	desc.Remove("FontFile3")
+}
+
+func TestEmbedFonts(t *testing.T) {
+	// The font directory of the finder.
+	dir, err := ioutil.TempDir("", "fonts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, path := range []string{subsetTestFont, shapingTestFont} {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filepath.Base(path)), data, 0644))
+	}
+	finder, err := NewFontDirectoryFinder(dir)
+	require.NoError(t, err)
+
+	// A page with Helvetica, and simple and composite fonts whose font programs are removed after
+	// the document is written.
+	simple, err := NewPdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+	composite, err := NewCompositePdfFontFromTTFFile(shapingTestFont)
+	require.NoError(t, err)
+	encoded := composite.Encoder().Encode("Ab")
+
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetFont("F1", NewStandard14FontMustCompile(HelveticaName)))
+	require.NoError(t, page.Resources.SetFont("F2", simple))
+	require.NoError(t, page.Resources.SetFont("F3", composite))
+	content := fmt.Sprintf("BT /F1 12 Tf 50 700 Td (Hello) Tj /F2 12 Tf (xyz) Tj /F3 12 Tf %s Tj ET",
+		core.MakeHexString(string(encoded)).WriteString())
+	require.NoError(t, page.SetContentStreams([]string{content}, nil))
+
+	writer := NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	// Embed the fonts and write the document again.
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	for _, name := range []core.PdfObjectName{"F2", "F3"} {
+		obj, ok := page.Resources.GetFontByName(name)
+		require.True(t, ok)
+		removeFontFile(t, obj)
+	}
+	// PdfWriter adds the font UF1 (Helvetica) to the pages.
+	inventory, err := reader.GetFontInventory()
+	require.NoError(t, err)
+	require.Len(t, inventory, 4)
+	for _, entry := range inventory {
+		require.False(t, entry.Embedded, entry.BaseFont)
+	}
+	embedded, err := reader.EmbedFonts(finder)
+	require.NoError(t, err)
+	require.Len(t, embedded, 4)
+
+	writer = NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	buf.Reset()
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	inventory, err = reader.GetFontInventory()
+	require.NoError(t, err)
+	require.Len(t, inventory, 4)
+	for _, entry := range inventory {
+		require.True(t, entry.Embedded, entry.BaseFont)
+	}
+
+	// Helvetica is substituted by FreeSans, with the widths of Helvetica.
+	entry := inventory[0]
+	assert.True(t, entry.Embedded)
+	assert.True(t, entry.Subset)
+	assert.Equal(t, "TrueType", entry.Subtype)
+	assert.Equal(t, "TrueType", entry.FontFileType)
+	assert.Equal(t, "FreeSans", entry.BaseFont[7:])
+	assert.Equal(t, []rune("Helo"), entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+	metrics, ok := entry.Font.GetCharMetrics('H')
+	require.True(t, ok)
+	assert.Equal(t, 722.0, metrics.Wx)
+
+	entry = inventory[1]
+	assert.True(t, entry.Embedded)
+	assert.True(t, entry.Subset)
+	assert.Equal(t, "WinAnsiEncoding", entry.Encoding)
+	assert.Equal(t, "OpenSans-Regular", entry.BaseFont[7:])
+	assert.Equal(t, []rune("xyz"), entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+
+	entry = inventory[2]
+	assert.True(t, entry.Embedded)
+	assert.True(t, entry.Subset)
+	assert.Equal(t, "Type0:CIDFontType2", entry.Subtype)
+	assert.Equal(t, "FreeSans-Identity-H", entry.BaseFont[7:])
+	assert.Equal(t, []rune("Ab"), entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+	codes := entry.Font.BytesToCharcodes(encoded)
+	assert.Equal(t, "Ab", string(entry.Font.CharcodesToUnicode(codes)))
+	assert.Equal(t, []textencoding.CharCode(codes), entry.Charcodes)
+}
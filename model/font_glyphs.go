@@ -0,0 +1,149 @@
+package model
+
+import (
+	"bytes"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// GlyphPath is the outline of a glyph: a sequence of closed subpaths with coordinates in glyph
+// space units, where 1000 units are 1 text space unit.
+type GlyphPath = fonts.GlyphPath
+
+// GlyphPathSegment is a segment of a GlyphPath. Op is the PDF path construction operator of the
+// segment ("m", "l", "c" or "h") and Points are the x,y coordinates of its operands.
+type GlyphPathSegment = fonts.PathSegment
+
+// GlyphOutline returns the outline of the glyph drawn for character code `code` by `font`, read
+// from its embedded TrueType, CFF or Type 1 font program. Codes without glyphs get the outline of
+// the .notdef glyph, as when the font is rendered.
+// The bool is false if `font` has no embedded font program with glyph outlines, e.g. for fonts
+// which are not embedded and Type 3 fonts, or the outline cannot be read.
+func (font *PdfFont) GlyphOutline(code textencoding.CharCode) (GlyphPath, bool) {
+	switch t := font.context.(type) {
+	case *pdfFontSimple:
+		return t.glyphOutline(code)
+	case *pdfFontType0:
+		if t.DescendantFont == nil {
+			return nil, false
+		}
+		cid := code
+		if t.codeToCID != nil {
+			c, ok := t.codeToCID.CharcodeToCID(cmap.CharCode(code))
+			if !ok {
+				c = 0
+			}
+			cid = textencoding.CharCode(c)
+		}
+		switch cidfont := t.DescendantFont.context.(type) {
+		case *pdfCIDFontType2:
+			return cidfont.glyphOutline(cid)
+		case *pdfCIDFontType0:
+			return cidfont.glyphOutline(cid)
+		}
+	}
+	return nil, false
+}
+
+// glyphOutline returns the outline of the glyph of character code `code` of `font`.
+func (font *pdfFontSimple) glyphOutline(code textencoding.CharCode) (GlyphPath, bool) {
+	desc := font.fontDescriptor
+	if desc == nil {
+		return nil, false
+	}
+	if outlines := desc.trueTypeOutlines(); outlines != nil {
+		gid, _ := font.trueTypeGID(code)
+		return checkGlyphOutline(outlines.Outline(gid))
+	}
+
+	var glyph textencoding.GlyphName
+	if encoder := font.Encoder(); encoder != nil {
+		if r, ok := encoder.CharcodeToRune(code); ok {
+			glyph, _ = textencoding.RuneToGlyph(r)
+		}
+	}
+	switch {
+	case desc.fontFile3 != nil:
+		gid, _ := desc.fontFile3.GIDByName(glyph)
+		return checkGlyphOutline(desc.fontFile3.Outline(gid))
+	case desc.fontFile != nil && desc.fontFile.glyphs != nil:
+		glyphs := desc.fontFile.glyphs
+		if !glyphs.HasGlyph(glyph) {
+			glyph = ".notdef"
+		}
+		return checkGlyphOutline(glyphs.Outline(glyph))
+	}
+	return nil, false
+}
+
+// glyphOutline returns the outline of the glyph of CID `cid` of `font`.
+func (font *pdfCIDFontType2) glyphOutline(cid textencoding.CharCode) (GlyphPath, bool) {
+	if font.fontDescriptor == nil {
+		return nil, false
+	}
+	outlines := font.fontDescriptor.trueTypeOutlines()
+	if outlines == nil {
+		return nil, false
+	}
+	gid, _ := font.cidToGID(cid)
+	return checkGlyphOutline(outlines.Outline(gid))
+}
+
+// glyphOutline returns the outline of the glyph of CID `cid` of `font`.
+func (font *pdfCIDFontType0) glyphOutline(cid textencoding.CharCode) (GlyphPath, bool) {
+	if font.fontDescriptor == nil || font.fontDescriptor.fontFile3 == nil {
+		return nil, false
+	}
+	gid, _ := font.cidToGID(cid)
+	return checkGlyphOutline(font.fontDescriptor.fontFile3.Outline(gid))
+}
+
+// checkGlyphOutline returns `path` and true if `err` is nil.
+func checkGlyphOutline(path GlyphPath, err error) (GlyphPath, bool) {
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to read glyph outline: %v", err)
+		return nil, false
+	}
+	return path, true
+}
+
+// trueTypeOutlines returns the glyph outlines of the TrueType font program of `desc`, or nil if it
+// has none. The outlines are loaded on first use.
+func (desc *PdfFontDescriptor) trueTypeOutlines() *fonts.TrueTypeOutlines {
+	if desc.ttfOutlinesLoaded {
+		return desc.ttfOutlines
+	}
+	desc.ttfOutlinesLoaded = true
+	if desc.fontFile2 == nil || desc.fontFile2.CFF {
+		return nil
+	}
+
+	// TrueType font programs are embedded in FontFile2 streams or, as OpenType font programs
+	// with TrueType outlines, in FontFile3 streams.
+	stream, ok := core.GetStream(desc.FontFile2)
+	if !ok {
+		stream, ok = core.GetStream(desc.FontFile3)
+	}
+	if !ok {
+		return nil
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode font program: %v", err)
+		return nil
+	}
+	if bytes.HasPrefix(data, []byte("OTTO")) {
+		return nil
+	}
+	outlines, err := fonts.ParseTrueTypeOutlines(data)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load TrueType glyph outlines: %v", err)
+		return nil
+	}
+	desc.ttfOutlines = outlines
+	return outlines
+}
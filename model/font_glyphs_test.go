@@ -0,0 +1,42 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+)
+
+func TestGlyphOutline(t *testing.T) {
+	simple, err := NewPdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+	composite, err := NewCompositePdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+
+	for _, font := range []*PdfFont{simple, composite} {
+		// Load the fonts from their objects, as for fonts read from a document.
+		font, err := NewPdfFontFromPdfObject(core.ResolveReference(font.ToPdfObject()))
+		require.NoError(t, err)
+		data, numMisses := font.StringToCharcodeBytes("A")
+		require.Equal(t, 0, numMisses)
+		codes := font.BytesToCharcodes(data)
+		require.Len(t, codes, 1)
+
+		path, ok := font.GlyphOutline(codes[0])
+		require.True(t, ok)
+		require.NotEmpty(t, path)
+		assert.Equal(t, "m", path[0].Op)
+		assert.Equal(t, "h", path[len(path)-1].Op)
+		for _, seg := range path {
+			for _, p := range seg.Points {
+				assert.True(t, p[0] >= 0 && p[0] <= 1000 && p[1] >= -300 && p[1] <= 1000, "point %v", p)
+			}
+		}
+	}
+
+	// The standard 14 fonts are not embedded.
+	_, ok := NewStandard14FontMustCompile(HelveticaName).GlyphOutline(65)
+	assert.False(t, ok)
+}
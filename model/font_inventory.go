@@ -0,0 +1,752 @@
+package model
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// FontInventoryEntry describes a font used by a document, as reported by
+// PdfReader.GetFontInventory.
+type FontInventoryEntry struct {
+	// Font is the loaded font.
+	Font *PdfFont
+	// Object is the font dictionary in the document, usually an indirect object.
+	Object core.PdfObject
+
+	// BaseFont is the BaseFont entry of the font, including the subset tag of subset fonts.
+	BaseFont string
+	// Subtype is the font subtype, e.g. "Type1", "TrueType" or "Type0:CIDFontType2" for composite
+	// fonts.
+	Subtype string
+	// Encoding is the name of the encoding of the font: the name of the base encoding of simple
+	// fonts, or of the CMap of composite fonts. It is empty if the font uses its built-in encoding.
+	Encoding string
+	// Embedded is true if the font program is embedded in the document. Type3 fonts, whose glyphs
+	// are defined by content streams in the document, are considered embedded.
+	Embedded bool
+	// Subset is true if the font is an embedded subset (see PdfFont.IsSubset).
+	Subset bool
+	// FontFileType is the type of the embedded font program: "Type1" (FontFile), "TrueType"
+	// (FontFile2), or the subtype of a FontFile3 stream, e.g. "Type1C", "CIDFontType0C" or
+	// "OpenType". It is empty for fonts that are not embedded.
+	FontFileType string
+
+	// Pages are the numbers of the pages which use the font, in increasing order: the pages with
+	// the font in their resources or in the resources of the form XObjects they draw and of the
+	// appearances of their annotations.
+	Pages []int
+	// Charcodes are the character codes shown with the font, in increasing order.
+	Charcodes []textencoding.CharCode
+	// Runes are the characters represented by Charcodes, in increasing order. Character codes
+	// without a Unicode mapping and the codes of MissingGlyphs are not represented.
+	Runes []rune
+	// MissingGlyphs are the character codes of Charcodes for which the font has no glyph: codes
+	// which are not mapped to a glyph of the embedded font program or, for fonts that are not
+	// embedded, for which the font has no metrics. The .notdef glyph is drawn for these codes.
+	MissingGlyphs []textencoding.CharCode
+
+	codes map[textencoding.CharCode]struct{}
+	pages map[int]struct{}
+}
+
+// GetFontInventory returns the fonts used in the document, in the order they are first used: the
+// fonts of the resources of the pages, of the form XObjects drawn by them and of the appearance
+// streams of their annotations. The content streams are scanned for the character codes shown
+// with each font.
+// Fonts of the resources which are not loaded by NewPdfFontFromPdfObject are skipped.
+func (r *PdfReader) GetFontInventory() ([]*FontInventoryEntry, error) {
+	inv := &fontInventory{entries: make(map[core.PdfObject]*FontInventoryEntry)}
+
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := r.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		inv.pageNum = pageNum
+		inv.visited = make(map[*core.PdfObjectStream]bool)
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return nil, err
+		}
+		inv.scan([]byte(contents), page.Resources, nil)
+
+		annotations, err := page.GetAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		for _, annot := range annotations {
+			for _, stream := range appearanceStreams(annot.AP) {
+				inv.scanForm(stream, nil)
+			}
+		}
+	}
+
+	for _, entry := range inv.order {
+		entry.finish()
+	}
+	return inv.order, nil
+}
+
+// fontInventory collects the fonts of a document for PdfReader.GetFontInventory.
+type fontInventory struct {
+	entries map[core.PdfObject]*FontInventoryEntry // The fonts, by their PDF objects.
+	order   []*FontInventoryEntry                  // The fonts in the order they are first used.
+	pageNum int                                    // The number of the page being scanned.
+	visited map[*core.PdfObjectStream]bool         // The forms scanned for the page.
+}
+
+// appearanceStreams returns the streams of appearance dictionary `ap`: the normal, rollover and
+// down appearances, which are either streams or subdictionaries of streams for each state.
+func appearanceStreams(ap core.PdfObject) []*core.PdfObjectStream {
+	apDict, ok := core.GetDict(ap)
+	if !ok {
+		return nil
+	}
+	var streams []*core.PdfObjectStream
+	for _, key := range []core.PdfObjectName{"N", "R", "D"} {
+		obj := apDict.Get(key)
+		if stream, ok := core.GetStream(obj); ok {
+			streams = append(streams, stream)
+			continue
+		}
+		if states, ok := core.GetDict(obj); ok {
+			for _, state := range states.Keys() {
+				if stream, ok := core.GetStream(states.Get(state)); ok {
+					streams = append(streams, stream)
+				}
+			}
+		}
+	}
+	return streams
+}
+
+// font returns the inventory entry of the font named `name` in `resources`, adding the font to
+// the inventory if it is not there yet. Nil is returned if the font cannot be loaded.
+func (inv *fontInventory) font(resources *PdfPageResources, name core.PdfObjectName) *FontInventoryEntry {
+	if resources == nil {
+		return nil
+	}
+	obj, ok := resources.GetFontByName(name)
+	if !ok {
+		common.Log.Debug("ERROR: Font %q not in resources", name)
+		return nil
+	}
+	entry, ok := inv.entries[obj]
+	if !ok {
+		font, err := NewPdfFontFromPdfObject(obj)
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to load font %q: %v", name, err)
+			inv.entries[obj] = nil
+			return nil
+		}
+		entry = newFontInventoryEntry(font, obj)
+		inv.entries[obj] = entry
+		inv.order = append(inv.order, entry)
+	}
+	if entry != nil {
+		entry.pages[inv.pageNum] = struct{}{}
+	}
+	return entry
+}
+
+// addResourceFonts adds the fonts of `resources` to the inventory, including the fonts which are
+// not used by the content stream.
+func (inv *fontInventory) addResourceFonts(resources *PdfPageResources) {
+	if resources == nil {
+		return
+	}
+	fontDict, ok := core.GetDict(resources.Font)
+	if !ok {
+		return
+	}
+	for _, name := range fontDict.Keys() {
+		inv.font(resources, name)
+	}
+}
+
+// scanForm scans the content stream of form XObject `stream`. `font` is the font selected when
+// the form is drawn.
+func (inv *fontInventory) scanForm(stream *core.PdfObjectStream, font *FontInventoryEntry) {
+	if inv.visited[stream] {
+		return
+	}
+	inv.visited[stream] = true
+
+	form, err := NewXObjectFormFromStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load form XObject: %v", err)
+		return
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode form XObject: %v", err)
+		return
+	}
+	inv.scan(data, form.Resources, font)
+}
+
+// scan scans content stream `data` with resources `resources` for the character codes shown with
+// each font. `font` is the font selected at the start of the content stream.
+func (inv *fontInventory) scan(data []byte, resources *PdfPageResources, font *FontInventoryEntry) {
+	inv.addResourceFonts(resources)
+
+	var fontStack []*FontInventoryEntry
+	s := newContentScanner(data)
+	for {
+		op, operands, ok := s.next()
+		if !ok {
+			return
+		}
+		switch op {
+		case "q":
+			fontStack = append(fontStack, font)
+		case "Q":
+			if n := len(fontStack); n > 0 {
+				font = fontStack[n-1]
+				fontStack = fontStack[:n-1]
+			}
+		case "Tf":
+			if len(operands) == 2 {
+				if name, ok := core.GetName(operands[0]); ok {
+					font = inv.font(resources, *name)
+				}
+			}
+		case "Tj", "'", "\"", "TJ":
+			if font == nil || len(operands) == 0 {
+				continue
+			}
+			obj := operands[len(operands)-1]
+			if arr, ok := core.GetArray(obj); ok {
+				for _, item := range arr.Elements() {
+					font.addString(item)
+				}
+			} else {
+				font.addString(obj)
+			}
+		case "Do":
+			if resources == nil || len(operands) != 1 {
+				continue
+			}
+			name, ok := core.GetName(operands[0])
+			if !ok {
+				continue
+			}
+			if stream, xtype := resources.GetXObjectByName(*name); xtype == XObjectTypeForm {
+				inv.scanForm(stream, font)
+			}
+		}
+	}
+}
+
+// newFontInventoryEntry returns the inventory entry of `font` with font dictionary `obj`.
+func newFontInventoryEntry(font *PdfFont, obj core.PdfObject) *FontInventoryEntry {
+	entry := &FontInventoryEntry{
+		Font:     font,
+		Object:   obj,
+		BaseFont: font.BaseFont(),
+		Subtype:  font.Subtype(),
+		Subset:   font.IsSubset(),
+		codes:    make(map[textencoding.CharCode]struct{}),
+		pages:    make(map[int]struct{}),
+	}
+	if d, ok := core.GetDict(obj); ok {
+		entry.Encoding = encodingName(d.Get("Encoding"))
+	}
+	if _, ok := font.context.(*pdfFontType3); ok {
+		entry.Embedded = true
+	}
+	if desc := inventoryDescriptor(font); desc != nil {
+		switch {
+		case desc.FontFile != nil:
+			entry.FontFileType = "Type1"
+		case desc.FontFile2 != nil:
+			entry.FontFileType = "TrueType"
+		case desc.FontFile3 != nil:
+			entry.FontFileType = "FontFile3"
+			if stream, ok := core.GetStream(desc.FontFile3); ok {
+				if subtype, ok := core.GetNameVal(stream.Get("Subtype")); ok {
+					entry.FontFileType = subtype
+				}
+			}
+		}
+		entry.Embedded = entry.FontFileType != ""
+	}
+	return entry
+}
+
+// encodingName returns the name of the Encoding entry `obj` of a font dictionary.
+func encodingName(obj core.PdfObject) string {
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectName:
+		return string(*t)
+	case *core.PdfObjectDictionary:
+		if name, ok := core.GetNameVal(t.Get("BaseEncoding")); ok {
+			return name
+		}
+		return "Differences"
+	case *core.PdfObjectStream:
+		if name, ok := core.GetNameVal(t.Get("CMapName")); ok {
+			return name
+		}
+		return "Embedded CMap"
+	}
+	return ""
+}
+
+// inventoryDescriptor returns the font descriptor of `font`, which is the descriptor of the
+// descendant font of composite fonts.
+func inventoryDescriptor(font *PdfFont) *PdfFontDescriptor {
+	if type0, ok := font.context.(*pdfFontType0); ok {
+		if type0.DescendantFont == nil {
+			return nil
+		}
+		return type0.DescendantFont.context.getFontDescriptor()
+	}
+	return font.context.getFontDescriptor()
+}
+
+// addString adds the character codes of PDF string `obj` shown with the font.
+func (entry *FontInventoryEntry) addString(obj core.PdfObject) {
+	str, ok := core.GetString(obj)
+	if !ok {
+		return
+	}
+	for _, code := range entry.Font.BytesToCharcodes(str.Bytes()) {
+		entry.codes[code] = struct{}{}
+	}
+}
+
+// finish sets the pages, character codes, runes and missing glyphs of `entry`.
+func (entry *FontInventoryEntry) finish() {
+	entry.Pages = make([]int, 0, len(entry.pages))
+	for pageNum := range entry.pages {
+		entry.Pages = append(entry.Pages, pageNum)
+	}
+	sort.Ints(entry.Pages)
+
+	entry.Charcodes = make([]textencoding.CharCode, 0, len(entry.codes))
+	for code := range entry.codes {
+		entry.Charcodes = append(entry.Charcodes, code)
+	}
+	sort.Slice(entry.Charcodes, func(i, j int) bool { return entry.Charcodes[i] < entry.Charcodes[j] })
+
+	runes := make(map[rune]struct{})
+	for _, code := range entry.Charcodes {
+		if !entry.Font.hasGlyph(code) {
+			entry.MissingGlyphs = append(entry.MissingGlyphs, code)
+			continue
+		}
+		decoded, _, numMisses := entry.Font.CharcodesToUnicodeWithStats([]textencoding.CharCode{code})
+		if numMisses == 0 {
+			for _, r := range decoded {
+				runes[r] = struct{}{}
+			}
+		}
+	}
+	entry.Runes = make([]rune, 0, len(runes))
+	for r := range runes {
+		entry.Runes = append(entry.Runes, r)
+	}
+	sort.Slice(entry.Runes, func(i, j int) bool { return entry.Runes[i] < entry.Runes[j] })
+}
+
+// hasGlyph returns true if `font` has a glyph for character code `code`. The glyphs of embedded
+// TrueType and CFF font programs are looked up in the font programs, fonts that are not embedded
+// have glyphs for the codes for which they have metrics. Codes of other fonts are assumed to have
+// glyphs.
+func (font *PdfFont) hasGlyph(code textencoding.CharCode) bool {
+	switch t := font.context.(type) {
+	case *pdfFontType0:
+		if t.DescendantFont == nil {
+			return false
+		}
+		cid := code
+		if t.codeToCID != nil {
+			c, ok := t.codeToCID.CharcodeToCID(cmap.CharCode(code))
+			if !ok {
+				return false
+			}
+			cid = textencoding.CharCode(c)
+		}
+		switch cidfont := t.DescendantFont.context.(type) {
+		case *pdfCIDFontType2:
+			return cidfont.hasGlyph(cid)
+		case *pdfCIDFontType0:
+			return cidfont.hasGlyph(cid)
+		}
+	case *pdfFontSimple:
+		return t.hasGlyph(code)
+	}
+	return true
+}
+
+// hasGlyph returns true if the embedded font program of `font` has a glyph for CID `cid`.
+func (font *pdfCIDFontType2) hasGlyph(cid textencoding.CharCode) bool {
+	desc := font.fontDescriptor
+	if desc == nil || desc.fontFile2 == nil {
+		return true
+	}
+	gid, ok := font.cidToGID(cid)
+	return ok && gid != 0 && int(gid) < len(desc.fontFile2.Widths)
+}
+
+// cidToGID returns the glyph index of CID `cid` given by the CIDToGIDMap of `font`. The bool is
+// false if the CIDToGIDMap has no entry for `cid`.
+func (font *pdfCIDFontType2) cidToGID(cid textencoding.CharCode) (fonts.GID, bool) {
+	stream, ok := core.GetStream(font.CIDToGIDMap)
+	if !ok {
+		return fonts.GID(cid), true
+	}
+	data, err := core.DecodeStream(stream)
+	i := 2 * int(cid)
+	if err != nil || i+1 >= len(data) {
+		return 0, false
+	}
+	return fonts.GID(data[i])<<8 | fonts.GID(data[i+1]), true
+}
+
+// hasGlyph returns true if the embedded font program of `font` has a glyph for CID `cid`.
+func (font *pdfCIDFontType0) hasGlyph(cid textencoding.CharCode) bool {
+	desc := font.fontDescriptor
+	if desc == nil || desc.fontFile3 == nil {
+		return true
+	}
+	gid, ok := font.cidToGID(cid)
+	return ok && gid != 0
+}
+
+// cidToGID returns the glyph index of CID `cid` in the embedded CFF font program of `font`. The
+// bool is false if the font program has no glyph for `cid`.
+func (font *pdfCIDFontType0) cidToGID(cid textencoding.CharCode) (fonts.GID, bool) {
+	cff := font.fontDescriptor.fontFile3
+	if !cff.IsCIDKeyed {
+		return fonts.GID(cid), int(cid) < len(cff.Widths)
+	}
+	if cid > 0xffff {
+		return 0, false
+	}
+	return cff.GIDByCID(uint16(cid))
+}
+
+// hasGlyph returns true if `font` has a glyph for character code `code`.
+func (font *pdfFontSimple) hasGlyph(code textencoding.CharCode) bool {
+	desc := font.fontDescriptor
+	embedded := desc != nil && (desc.FontFile != nil || desc.FontFile2 != nil || desc.FontFile3 != nil)
+	if !embedded {
+		if font.fontMetrics != nil {
+			encoder := font.Encoder()
+			if encoder == nil {
+				return false
+			}
+			r, ok := encoder.CharcodeToRune(code)
+			if !ok {
+				return false
+			}
+			_, ok = font.fontMetrics[r]
+			return ok
+		}
+		_, ok := font.charWidths[code]
+		return ok
+	}
+
+	encoder := font.Encoder()
+	if encoder == nil {
+		return true
+	}
+	if desc.fontFile2 != nil {
+		_, ok := font.trueTypeGID(code)
+		return ok
+	}
+	r, ok := encoder.CharcodeToRune(code)
+	if !ok {
+		return true
+	}
+	switch {
+	case desc.fontFile3 != nil && !desc.fontFile3.IsCIDKeyed:
+		glyph, ok := textencoding.RuneToGlyph(r)
+		if !ok {
+			return true
+		}
+		gid, ok := desc.fontFile3.GIDByName(glyph)
+		return ok && gid != 0
+	}
+	return true
+}
+
+// trueTypeGID returns the glyph index of character code `code` in the embedded TrueType font
+// program of `font`. The bool is false if the font program has no glyph for `code`.
+func (font *pdfFontSimple) trueTypeGID(code textencoding.CharCode) (fonts.GID, bool) {
+	var r rune
+	ok := false
+	if encoder := font.Encoder(); encoder != nil {
+		r, ok = encoder.CharcodeToRune(code)
+	}
+	// The codes of symbolic fonts are mapped to glyphs by the (3,0) cmap of the font program, in
+	// which they are offset by 0xF000, or by the (1,0) cmap (section 9.6.6.4 "Encodings for
+	// TrueType Fonts" PDF32000_2008).
+	for _, key := range []rune{r, 0xf000 + rune(code), rune(code)} {
+		if gid, has := font.fontDescriptor.fontFile2.Chars[key]; has && (ok || key != r) {
+			return gid, gid != 0
+		}
+	}
+	return 0, false
+}
+
+// contentScanner splits a content stream in operations. It is a minimal content stream parser for
+// collecting the text shown in content streams: only names, strings and arrays are kept as
+// operands, other objects are replaced by null objects.
+type contentScanner struct {
+	data []byte
+	pos  int
+}
+
+// newContentScanner returns a scanner of content stream `data`.
+func newContentScanner(data []byte) *contentScanner {
+	return &contentScanner{data: data}
+}
+
+// next returns the next operator of the content stream and its operands. The bool is false at
+// the end of the content stream.
+func (s *contentScanner) next() (string, []core.PdfObject, bool) {
+	var operands []core.PdfObject
+	for {
+		obj, op, ok := s.object()
+		if !ok {
+			return "", nil, false
+		}
+		if op == "" {
+			operands = append(operands, obj)
+			continue
+		}
+		if op == "BI" {
+			s.skipInlineImage()
+		}
+		return op, operands, true
+	}
+}
+
+// object returns the next object of the content stream, or the next operator.
+func (s *contentScanner) object() (core.PdfObject, string, bool) {
+	s.skipSpaces()
+	if s.pos >= len(s.data) {
+		return nil, "", false
+	}
+	switch c := s.data[s.pos]; {
+	case c == '/':
+		s.pos++
+		return core.MakeName(string(s.token())), "", true
+	case c == '(':
+		return core.MakeStringFromBytes(s.literalString()), "", true
+	case c == '<' && s.pos+1 < len(s.data) && s.data[s.pos+1] == '<':
+		s.skipDict()
+		return core.MakeNull(), "", true
+	case c == '<':
+		return core.MakeStringFromBytes(s.hexString()), "", true
+	case c == '[':
+		s.pos++
+		arr := core.MakeArray()
+		for {
+			s.skipSpaces()
+			if s.pos >= len(s.data) {
+				return arr, "", true
+			}
+			if s.data[s.pos] == ']' {
+				s.pos++
+				return arr, "", true
+			}
+			obj, op, ok := s.object()
+			if !ok {
+				return arr, "", true
+			}
+			if op == "" {
+				arr.Append(obj)
+			}
+		}
+	case c == ']' || c == ')' || c == '>' || c == '{' || c == '}':
+		s.pos++
+		return core.MakeNull(), "", true
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		s.token()
+		return core.MakeNull(), "", true
+	}
+	tok := s.token()
+	switch string(tok) {
+	case "true", "false", "null":
+		return core.MakeNull(), "", true
+	}
+	return nil, string(tok), true
+}
+
+// skipSpaces skips white space and comments.
+func (s *contentScanner) skipSpaces() {
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if c == '%' {
+			for s.pos < len(s.data) && s.data[s.pos] != '\n' && s.data[s.pos] != '\r' {
+				s.pos++
+			}
+			continue
+		}
+		if !core.IsWhiteSpace(c) {
+			return
+		}
+		s.pos++
+	}
+}
+
+// token returns the regular characters at the position of the scanner.
+func (s *contentScanner) token() []byte {
+	start := s.pos
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if core.IsWhiteSpace(c) || core.IsDelimiter(c) {
+			break
+		}
+		s.pos++
+	}
+	if s.pos == start {
+		// Skip an unexpected delimiter.
+		s.pos++
+	}
+	return s.data[start:s.pos]
+}
+
+// literalString returns the bytes of the literal string at the position of the scanner.
+func (s *contentScanner) literalString() []byte {
+	s.pos++ // (
+	var buf bytes.Buffer
+	depth := 1
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		s.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf.Bytes()
+			}
+		case '\\':
+			if s.pos >= len(s.data) {
+				return buf.Bytes()
+			}
+			c = s.data[s.pos]
+			s.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation.
+				if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+					s.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					val := int(c - '0')
+					for i := 0; i < 2 && s.pos < len(s.data); i++ {
+						d := s.data[s.pos]
+						if d < '0' || d > '7' {
+							break
+						}
+						val = val*8 + int(d-'0')
+						s.pos++
+					}
+					c = byte(val)
+				}
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.Bytes()
+}
+
+// hexString returns the bytes of the hexadecimal string at the position of the scanner.
+func (s *contentScanner) hexString() []byte {
+	s.pos++ // <
+	var digits []byte
+	for s.pos < len(s.data) && s.data[s.pos] != '>' {
+		c := s.data[s.pos]
+		s.pos++
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c-'0')
+		case c >= 'a' && c <= 'f':
+			digits = append(digits, c-'a'+10)
+		case c >= 'A' && c <= 'F':
+			digits = append(digits, c-'A'+10)
+		}
+	}
+	s.pos++ // >
+	if len(digits)%2 != 0 {
+		digits = append(digits, 0)
+	}
+	data := make([]byte, len(digits)/2)
+	for i := range data {
+		data[i] = digits[2*i]<<4 | digits[2*i+1]
+	}
+	return data
+}
+
+// skipDict skips the dictionary at the position of the scanner.
+func (s *contentScanner) skipDict() {
+	s.pos += 2 // <<
+	for {
+		s.skipSpaces()
+		if s.pos >= len(s.data) {
+			return
+		}
+		if bytes.HasPrefix(s.data[s.pos:], []byte(">>")) {
+			s.pos += 2
+			return
+		}
+		if _, _, ok := s.object(); !ok {
+			return
+		}
+	}
+}
+
+// skipInlineImage skips the parameters and the data of an inline image, up to the EI operator.
+func (s *contentScanner) skipInlineImage() {
+	id := bytes.Index(s.data[s.pos:], []byte("ID"))
+	if id < 0 {
+		s.pos = len(s.data)
+		return
+	}
+	s.pos += id + 2
+	for s.pos < len(s.data) {
+		ei := bytes.Index(s.data[s.pos:], []byte("EI"))
+		if ei < 0 {
+			s.pos = len(s.data)
+			return
+		}
+		s.pos += ei + 2
+		end := s.pos
+		if core.IsWhiteSpace(s.data[end-3]) && (end == len(s.data) || core.IsWhiteSpace(s.data[end]) ||
+			core.IsDelimiter(s.data[end])) {
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+)
+
+func TestGetFontInventory(t *testing.T) {
+	helvetica := NewStandard14FontMustCompile(HelveticaName)
+	composite, err := NewCompositePdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+	courier := NewStandard14FontMustCompile(CourierName)
+
+	// Page 1 shows text with the composite font and draws a form showing text with Helvetica.
+	// Glyph 0xffff is not in the composite font.
+	encoded := composite.Encoder().Encode("AB")
+	form := NewXObjectForm()
+	form.Resources = NewPdfPageResources()
+	require.NoError(t, form.Resources.SetFont("F2", helvetica))
+	require.NoError(t, form.SetContentStream([]byte("BT /F2 12 Tf (Hi) Tj (\\351) ' ET"), nil))
+
+	page1 := NewPdfPage()
+	page1.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page1.Resources.SetFont("F1", composite))
+	require.NoError(t, page1.Resources.SetXObjectFormByName("Fm1", form))
+	require.NoError(t, page1.SetContentStreams([]string{fmt.Sprintf(
+		"q BT /F1 12 Tf 50 700 Td [%s -20 <ffff>] TJ ET Q /Fm1 Do",
+		core.MakeHexString(string(encoded)).WriteString()),
+	}, nil))
+
+	// Page 2 has an annotation whose appearance shows text with Courier.
+	appearance := NewXObjectForm()
+	appearance.Resources = NewPdfPageResources()
+	require.NoError(t, appearance.Resources.SetFont("F3", courier))
+	require.NoError(t, appearance.SetContentStream([]byte("BT /F3 10 Tf <7a> Tj ET"), nil))
+	annot := NewPdfAnnotationSquare()
+	annot.Rect = core.MakeArrayFromFloats([]float64{10, 10, 100, 100})
+	ap := core.MakeDict()
+	ap.Set("N", appearance.ToPdfObject())
+	annot.AP = ap
+
+	page2 := NewPdfPage()
+	page2.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	page2.AddAnnotation(annot.PdfAnnotation)
+
+	writer := NewPdfWriter()
+	require.NoError(t, writer.AddPage(page1))
+	require.NoError(t, writer.AddPage(page2))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	inventory, err := reader.GetFontInventory()
+	require.NoError(t, err)
+	entries := make(map[string]*FontInventoryEntry)
+	for _, entry := range inventory {
+		if len(entry.Charcodes) > 0 {
+			entries[entry.BaseFont] = entry
+		}
+	}
+	require.Len(t, entries, 3)
+
+	entry := inventory[0]
+	assert.True(t, entry.Subset)
+	assert.Equal(t, "Type0:CIDFontType2", entry.Subtype)
+	assert.Equal(t, "Identity-H", entry.Encoding)
+	assert.True(t, entry.Embedded)
+	assert.Equal(t, "TrueType", entry.FontFileType)
+	assert.Equal(t, []int{1}, entry.Pages)
+	require.Len(t, entry.Charcodes, 3)
+	assert.Equal(t, []rune{'A', 'B'}, entry.Runes)
+	assert.Equal(t, []textencoding.CharCode{0xffff}, entry.MissingGlyphs)
+
+	entry = entries["Helvetica"]
+	require.NotNil(t, entry)
+	assert.Equal(t, "Type1", entry.Subtype)
+	assert.Equal(t, "WinAnsiEncoding", entry.Encoding)
+	assert.False(t, entry.Embedded)
+	assert.False(t, entry.Subset)
+	assert.Equal(t, "", entry.FontFileType)
+	assert.Equal(t, []int{1}, entry.Pages)
+	assert.Equal(t, []textencoding.CharCode{'H', 'i', 0xe9}, entry.Charcodes)
+	assert.Equal(t, []rune{'H', 'i', 'é'}, entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+
+	entry = entries["Courier"]
+	require.NotNil(t, entry)
+	assert.Equal(t, []int{2}, entry.Pages)
+	assert.Equal(t, []rune{'z'}, entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+}
+
+func TestContentScanner(t *testing.T) {
+	content := `% comment
+		/GS0 gs BT /F1 12 Tf (a\(b\)\\c\101\n) Tj [(x) -250 <4142 43>] TJ ET
+		/P <</MCID 0 /Alt (y)>> BDC EMC
+		BI /W 2 /H 1 /BPC 8 /CS /G ID ab EI Q`
+	s := newContentScanner([]byte(content))
+
+	var ops []string
+	var strs []string
+	for {
+		op, operands, ok := s.next()
+		if !ok {
+			break
+		}
+		ops = append(ops, op)
+		for _, obj := range operands {
+			if str, ok := core.GetString(obj); ok {
+				strs = append(strs, str.Str())
+			}
+			if arr, ok := core.GetArray(obj); ok {
+				for _, item := range arr.Elements() {
+					if str, ok := core.GetString(item); ok {
+						strs = append(strs, str.Str())
+					}
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{"gs", "BT", "Tf", "Tj", "TJ", "ET", "BDC", "EMC", "BI", "Q"}, ops)
+	assert.Equal(t, []string{"a(b)\\cA\n", "x", "ABC"}, strs)
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"sync"
+
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// ShapedGlyph is a glyph of text shaped with PdfFont.ShapeText.
+type ShapedGlyph struct {
+	// Code is the character code of the glyph.
+	Code textencoding.CharCode
+	// Cluster is the index of the first rune of the shaped text represented by the glyph. Glyphs
+	// representing the same runes, e.g. a conjunct and its marks, have the same cluster.
+	Cluster int
+	// Width is the width of the glyph in the widths of the font, by which PDF readers advance the
+	// text position after drawing the glyph.
+	Width float64
+	// XAdvance is the advance of the glyph in the shaped text. It differs from Width e.g. for
+	// kerned glyphs and for marks, which do not advance.
+	XAdvance float64
+	// XOffset and YOffset are the offsets of the glyph from its position in the shaped text.
+	XOffset, YOffset float64
+}
+
+// fontShaping holds the text shaper of a composite font created from a TrueType or OpenType font
+// file and the glyphs used by shaped text.
+type fontShaping struct {
+	shaper *fonts.Shaper
+	widths []uint16 // The glyph widths in font units, by glyph index.
+	scale  float64  // The scale from font units to glyph space units.
+
+	mu     sync.Mutex
+	glyphs map[fonts.GID]rune // The glyphs of shaped text, mapped to the first rune they represent.
+}
+
+// ShapeText shapes `text` with the OpenType layout tables of `font`: the runes are mapped to the
+// glyphs of their contextual forms, ligatures and conjuncts, which are reordered and positioned
+// as required by the script of the text, e.g. Arabic, Hebrew, Devanagari or Thai. `text` must be
+// a run of text with a single direction, `rtl` is true for right-to-left text.
+//
+// The glyphs are returned in visual order, from left to right. Their widths, advances and offsets
+// are in glyph space units (thousandths of text space units).
+// The glyphs are registered with the font, so that they are included in the embedded font subset
+// and mapped to the runes they represent in the ToUnicode CMap of the documents written
+// with PdfWriter.
+// As this mapping cannot represent reordered runes and ligatures, shaped text should be marked
+// with its actual text for text extraction.
+//
+// The bool is false if `font` does not support shaping. Only composite fonts created with
+// NewCompositePdfFontFromTTFFile support shaping.
+func (font *PdfFont) ShapeText(text string, rtl bool) ([]ShapedGlyph, bool) {
+	type0, ok := font.context.(*pdfFontType0)
+	if !ok || type0.shaping == nil {
+		return nil, false
+	}
+	return type0.shaping.shape([]rune(text), rtl), true
+}
+
+// CharcodesToBytes returns the bytes of the PDF string of the character codes `codes` of `font`.
+// Character codes of composite fonts with the Identity-H encoding are 2 bytes long, those of simple
+// fonts 1 byte long.
+func (font *PdfFont) CharcodesToBytes(codes []textencoding.CharCode) []byte {
+	if !font.baseFields().isCIDFont() {
+		data := make([]byte, len(codes))
+		for i, code := range codes {
+			data[i] = byte(code)
+		}
+		return data
+	}
+	data := make([]byte, 0, 2*len(codes))
+	for _, code := range codes {
+		data = append(data, byte(code>>8), byte(code))
+	}
+	return data
+}
+
+// shape shapes `text` and registers the glyphs.
+func (s *fontShaping) shape(text []rune, rtl bool) []ShapedGlyph {
+	shaped := s.shaper.Shape(text, rtl)
+	glyphs := make([]ShapedGlyph, len(shaped))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, g := range shaped {
+		if _, ok := s.glyphs[g.GID]; !ok && g.GID != 0 && g.Cluster < len(text) {
+			s.glyphs[g.GID] = text[g.Cluster]
+		}
+		glyphs[i] = ShapedGlyph{
+			Code:     textencoding.CharCode(g.GID),
+			Cluster:  g.Cluster,
+			Width:    float64(s.width(g.GID)),
+			XAdvance: s.scale * float64(g.XAdvance),
+			XOffset:  s.scale * float64(g.XOffset),
+			YOffset:  s.scale * float64(g.YOffset),
+		}
+	}
+	return glyphs
+}
+
+// width returns the width of glyph `gid` in the font's W array.
+func (s *fontShaping) width(gid fonts.GID) int {
+	if int(gid) >= len(s.widths) {
+		return 0
+	}
+	return int(s.scale * float64(s.widths[gid]))
+}
+
+// shapedGlyphs returns the glyphs of the shaped text, mapped to the first rune they represent.
+func (s *fontShaping) shapedGlyphs() map[fonts.GID]rune {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	glyphs := make(map[fonts.GID]rune, len(s.glyphs))
+	for gid, r := range s.glyphs {
+		glyphs[gid] = r
+	}
+	return glyphs
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+const shapingTestFont = "../creator/testdata/FreeSans.ttf"
+
+func TestShapeText(t *testing.T) {
+	ttf, err := fonts.TtfParseFile(shapingTestFont)
+	require.NoError(t, err)
+	font, err := NewCompositePdfFontFromTTFFile(shapingTestFont)
+	require.NoError(t, err)
+
+	// The conjunct KSSA is shaped to a single ligature glyph.
+	glyphs, ok := font.ShapeText("क्ष", false)
+	require.True(t, ok)
+	require.Len(t, glyphs, 1)
+	gid := fonts.GID(glyphs[0].Code)
+	assert.Equal(t, "uni0915_uni094D_uni0937.akhn", string(ttf.GlyphNames[gid]))
+	assert.Equal(t, 0, glyphs[0].Cluster)
+	assert.InDelta(t, glyphs[0].Width, glyphs[0].XAdvance, 1)
+
+	// Right-to-left text is returned in visual order.
+	glyphs, ok = font.ShapeText("של", true)
+	require.True(t, ok)
+	require.Len(t, glyphs, 2)
+	assert.Equal(t, 1, glyphs[0].Cluster)
+	assert.Equal(t, 0, glyphs[1].Cluster)
+
+	// The shaped glyphs are included in the subset, mapped to the first rune they represent.
+	loaded, _ := writeFontTestPDF(t, font, "abc")
+	code := textencoding.CharCode(gid)
+	decoded, _, numMisses := loaded.CharcodesToUnicodeWithStats([]textencoding.CharCode{code})
+	assert.Equal(t, "क", string(decoded))
+	assert.Equal(t, 0, numMisses)
+	metrics, ok := loaded.GetCharMetrics(code)
+	require.True(t, ok)
+	assert.Equal(t, float64(int(1000*float64(ttf.Widths[gid])/float64(ttf.UnitsPerEm))), metrics.Wx)
+
+	// Simple fonts are not shaped.
+	simple, err := NewPdfFontFromTTFFile(shapingTestFont)
+	require.NoError(t, err)
+	_, ok = simple.ShapeText("क्ष", false)
+	assert.False(t, ok)
+}
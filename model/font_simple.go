@@ -58,6 +58,10 @@ type pdfFontSimple struct {
 
 	// Standard 14 fonts metrics
 	fontMetrics map[rune]fonts.CharMetrics
+
+	// ttfSubset is set for fonts created from TrueType font files and is used for subsetting the
+	// embedded font program.
+	ttfSubset *trueTypeSubset
 }
 
 // pdfCIDFontType0FromSkeleton returns a pdfFontSimple with its common fields initalized.
@@ -289,6 +293,33 @@ func (font *pdfFontSimple) addEncoding() error {
 	return nil
 }
 
+// addFontFile3Widths sets the character widths of `font` from the glyph widths of its embedded CFF
+// font program. It is used for fonts without a Widths array.
+func (font *pdfFontSimple) addFontFile3Widths() {
+	descriptor := font.fontDescriptor
+	encoder, ok := font.Encoder().(textencoding.SimpleEncoder)
+	if descriptor == nil || descriptor.fontFile3 == nil || !ok || encoder == nil {
+		return
+	}
+	cff := descriptor.fontFile3
+	if font.charWidths == nil {
+		font.charWidths = make(map[textencoding.CharCode]float64)
+	}
+	for _, code := range encoder.Charcodes() {
+		r, ok := encoder.CharcodeToRune(code)
+		if !ok {
+			continue
+		}
+		glyph, ok := textencoding.RuneToGlyph(r)
+		if !ok {
+			continue
+		}
+		if gid, ok := cff.GIDByName(glyph); ok && int(gid) < len(cff.Widths) {
+			font.charWidths[code] = cff.Widths[gid]
+		}
+	}
+}
+
 // getFontEncoding returns font encoding of `obj` the "Encoding" entry in a font dict.
 // Table 114 – Entries in an encoding dictionary (page 263)
 // 9.6.6.1 General (page 262)
@@ -391,9 +422,11 @@ func NewPdfFontFromTTFFile(filePath string) (*PdfFont, error) {
 	return NewPdfFontFromTTF(f)
 }
 
-// NewPdfFontFromTTF loads a TTF font and returns a PdfFont type that can be
+// NewPdfFontFromTTF loads a TTF or OTF font and returns a PdfFont type that can be
 // used in text styling functions.
 // Uses a WinAnsiTextEncoder and loads only character codes 32-255.
+// OpenType fonts with CFF outlines are represented by a Type1 font with the font program embedded
+// as a FontFile3 stream with subtype OpenType.
 func NewPdfFontFromTTF(r io.ReadSeeker) (*PdfFont, error) {
 	const minCode = textencoding.CharCode(32)
 	const maxCode = textencoding.CharCode(255)
@@ -409,10 +442,14 @@ func NewPdfFontFromTTF(r io.ReadSeeker) (*PdfFont, error) {
 		return nil, err
 	}
 
+	subtype := "TrueType"
+	if ttf.CFF {
+		subtype = "Type1"
+	}
 	truefont := &pdfFontSimple{
 		charWidths: make(map[textencoding.CharCode]float64),
 		fontCommon: fontCommon{
-			subtype: "TrueType",
+			subtype: subtype,
 		},
 	}
 
@@ -464,6 +501,42 @@ func NewPdfFontFromTTF(r io.ReadSeeker) (*PdfFont, error) {
 	// Use WinAnsiEncoding by default.
 	truefont.Encoding = core.MakeName("WinAnsiEncoding")
 
+	descriptor := newTTFFontDescriptor(&ttf)
+	stream, err := core.MakeStream(ttfBytes, core.NewFlateEncoder())
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to make stream: %v", err)
+		return nil, err
+	}
+	if ttf.CFF {
+		stream.PdfObjectDictionary.Set("Subtype", core.MakeName("OpenType"))
+		descriptor.FontFile3 = stream
+	} else {
+		stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(ttfBytes))))
+		descriptor.FontFile2 = stream
+	}
+
+	// Build Font.
+	truefont.fontDescriptor = descriptor
+	truefont.ttfSubset = &trueTypeSubset{
+		data:           ttfBytes,
+		postScriptName: ttf.PostScriptName,
+		fontFile:       stream,
+		cff:            ttf.CFF,
+		chars:          ttf.Chars,
+	}
+
+	font := &PdfFont{
+		context: truefont,
+	}
+
+	return font, nil
+}
+
+// newTTFFontDescriptor returns a font descriptor with the metrics of the TrueType or OpenType font
+// `ttf`, for a nonsymbolic font. The font program is not set.
+func newTTFFontDescriptor(ttf *fonts.TtfType) *PdfFontDescriptor {
+	k := 1000.0 / float64(ttf.UnitsPerEm)
+
 	descriptor := &PdfFontDescriptor{}
 	descriptor.FontName = core.MakeName(ttf.PostScriptName)
 	descriptor.Ascent = core.MakeFloat(k * float64(ttf.TypoAscender))
@@ -472,15 +545,9 @@ func NewPdfFontFromTTF(r io.ReadSeeker) (*PdfFont, error) {
 	descriptor.FontBBox = core.MakeArrayFromFloats([]float64{k * float64(ttf.Xmin),
 		k * float64(ttf.Ymin), k * float64(ttf.Xmax), k * float64(ttf.Ymax)})
 	descriptor.ItalicAngle = core.MakeFloat(float64(ttf.ItalicAngle))
-	descriptor.MissingWidth = core.MakeFloat(k * float64(ttf.Widths[0]))
-
-	stream, err := core.MakeStream(ttfBytes, core.NewFlateEncoder())
-	if err != nil {
-		common.Log.Debug("ERROR: Unable to make stream: %v", err)
-		return nil, err
+	if len(ttf.Widths) > 0 {
+		descriptor.MissingWidth = core.MakeFloat(k * float64(ttf.Widths[0]))
 	}
-	stream.PdfObjectDictionary.Set("Length1", core.MakeInteger(int64(len(ttfBytes))))
-	descriptor.FontFile2 = stream
 
 	if ttf.Bold {
 		descriptor.StemV = core.MakeInteger(120)
@@ -496,15 +563,7 @@ func NewPdfFontFromTTF(r io.ReadSeeker) (*PdfFont, error) {
 		flags |= fontFlagItalic
 	}
 	descriptor.Flags = core.MakeInteger(int64(flags))
-
-	// Build Font.
-	truefont.fontDescriptor = descriptor
-
-	font := &PdfFont{
-		context: truefont,
-	}
-
-	return font, nil
+	return descriptor
 }
 
 // updateStandard14Font fills the font.charWidths for standard 14 fonts.
@@ -0,0 +1,286 @@
+package model
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// trueTypeSubset holds the data needed for subsetting the TrueType or OpenType font program
+// embedded for a font created from a TrueType or OpenType font file.
+type trueTypeSubset struct {
+	data           []byte                // The complete font program.
+	postScriptName string                // The font name without subset tag.
+	fontFile       *core.PdfObjectStream // The embedded font program (FontFile2 or FontFile3).
+	cff            bool                  // The font program is an OpenType font with CFF outlines.
+	chars          map[rune]fonts.GID    // Rune to glyph index map of simple fonts.
+}
+
+// fontObjectsMu guards the building of the PDF objects of fonts set with PdfPageResources.SetFont.
+var fontObjectsMu sync.Mutex
+
+// pdfObject returns the PDF object of `font` used by PdfPageResources.SetFont. The object is built
+// by the first call and is not modified afterwards: the subset font objects are made by each
+// PdfWriter (see subsetObjects). This allows a font to be shared by documents written
+// concurrently.
+func (font *PdfFont) pdfObject() core.PdfObject {
+	fontObjectsMu.Lock()
+	defer fontObjectsMu.Unlock()
+	if font.obj == nil {
+		font.obj = font.ToPdfObject()
+	}
+	return font.obj
+}
+
+// subsetObjects returns the objects of `font` for a document in which the TrueType or OpenType font
+// program embedded for `font` is subset to the glyphs needed for drawing the text encoded with the
+// font so far. The returned map maps the objects built by pdfObject to the objects replacing them.
+// It is nil for fonts without a TrueType or OpenType font program to subset.
+//
+// The embedded font program of composite fonts created with NewCompositePdfFontFromTTFFile is
+// reduced to the glyphs of the runes encoded with the font's encoder. The W array and the
+// ToUnicode CMap of the font are reduced accordingly. Glyph indices are not changed, so the text
+// encoded with the font remains valid.
+// The embedded font program of simple fonts created with NewPdfFontFromTTF(File) is reduced to
+// the glyphs of the characters in its encoding.
+// The font names get a subset tag prefix, e.g. "ABCDEF+FreeSans".
+//
+// `font` and its objects are not modified. The runes encoded with the font are never
+// unregistered, so a font shared between documents is subset to the glyphs of the text of all of
+// them.
+func (font *PdfFont) subsetObjects() (map[core.PdfObject]core.PdfObject, error) {
+	switch t := font.context.(type) {
+	case *pdfFontType0:
+		return t.subsetObjects()
+	case *pdfFontSimple:
+		return t.subsetObjects()
+	}
+	return nil, nil
+}
+
+// subsetObjects implements PdfFont.subsetObjects for composite TrueType and OpenType fonts.
+func (font *pdfFontType0) subsetObjects() (map[core.PdfObject]core.PdfObject, error) {
+	if font.ttfSubset == nil || font.DescendantFont == nil {
+		return nil, nil
+	}
+	var runeToWidthMap map[rune]int
+	var widths, verticalMetrics core.PdfObject
+	var container *core.PdfIndirectObject
+	switch cidfont := font.DescendantFont.context.(type) {
+	case *pdfCIDFontType2:
+		runeToWidthMap, widths, verticalMetrics = cidfont.runeToWidthMap, cidfont.W, cidfont.W2
+		container = cidfont.container
+	case *pdfCIDFontType0:
+		runeToWidthMap, widths, verticalMetrics = cidfont.runeToWidthMap, cidfont.W, cidfont.W2
+		container = cidfont.container
+	default:
+		return nil, nil
+	}
+	encoder, ok := font.encoder.(textencoding.TrueTypeFontEncoder)
+	if !ok {
+		common.Log.Debug("ERROR: unexpected encoder for TrueType composite font: %T", font.encoder)
+		return nil, errors.New("unsupported encoder")
+	}
+
+	runeToGID := encoder.RegisteredRunes()
+	var shaped map[fonts.GID]rune
+	if font.shaping != nil {
+		shaped = font.shaping.shapedGlyphs()
+	}
+	basefont, fontFile, err := font.ttfSubset.subset(runeToGID, shaped)
+	if err != nil {
+		return nil, err
+	}
+	objs := map[core.PdfObject]core.PdfObject{
+		font.ttfSubset.fontFile: fontFile,
+	}
+	if err := replaceFontDict(objs, font.container, basefont); err != nil {
+		return nil, err
+	}
+	if err := replaceFontDict(objs, container, basefont); err != nil {
+		return nil, err
+	}
+
+	// Widths of the glyphs in the subset, and the runes they represent.
+	gidToRune := make(map[fonts.GID]rune, len(runeToGID))
+	for r, gid := range runeToGID {
+		if r0, ok := gidToRune[gid]; !ok || r < r0 {
+			gidToRune[gid] = r
+		}
+	}
+	gidWidths := make(map[fonts.GID]int, len(gidToRune))
+	codeToUnicode := make(map[cmap.CharCode]rune, len(gidToRune))
+	for gid, r := range gidToRune {
+		gidWidths[gid] = runeToWidthMap[r]
+		codeToUnicode[cmap.CharCode(gid)] = r
+	}
+	// The glyphs of shaped text that are not mapped from runes, e.g. ligatures and contextual forms.
+	for gid, r := range shaped {
+		if _, ok := gidWidths[gid]; !ok {
+			gidWidths[gid] = font.shaping.width(gid)
+			codeToUnicode[cmap.CharCode(gid)] = r
+		}
+	}
+	if widths != nil {
+		objs[widths] = core.MakeIndirectObject(makeSubsetWidthArr(gidWidths))
+	}
+	if font.verticalMetrics != nil && verticalMetrics != nil {
+		gids := make([]fonts.GID, 0, len(gidWidths))
+		for gid := range gidWidths {
+			gids = append(gids, gid)
+		}
+		objs[verticalMetrics] = core.MakeIndirectObject(font.verticalMetrics.makeW2(gids))
+	}
+	if font.toUnicode != nil {
+		toUnicode, err := core.MakeStream(cmap.NewToUnicodeCMap(codeToUnicode).Bytes(), nil)
+		if err != nil {
+			return nil, err
+		}
+		objs[font.toUnicode] = toUnicode
+	}
+	return objs, nil
+}
+
+// subsetObjects implements PdfFont.subsetObjects for simple TrueType and OpenType fonts.
+func (font *pdfFontSimple) subsetObjects() (map[core.PdfObject]core.PdfObject, error) {
+	if font.ttfSubset == nil || font.encoder == nil {
+		return nil, nil
+	}
+	first, errF := core.GetNumberAsInt64(font.FirstChar)
+	last, errL := core.GetNumberAsInt64(font.LastChar)
+	if errF != nil || errL != nil {
+		return nil, nil
+	}
+
+	runeToGID := make(map[rune]fonts.GID)
+	for code := first; code <= last; code++ {
+		r, ok := font.encoder.CharcodeToRune(textencoding.CharCode(code))
+		if !ok {
+			continue
+		}
+		if gid, ok := font.ttfSubset.chars[r]; ok {
+			runeToGID[r] = gid
+		}
+	}
+	basefont, fontFile, err := font.ttfSubset.subset(runeToGID, nil)
+	if err != nil {
+		return nil, err
+	}
+	objs := map[core.PdfObject]core.PdfObject{
+		font.ttfSubset.fontFile: fontFile,
+	}
+	if err := replaceFontDict(objs, font.container, basefont); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// replaceFontDict adds to `objs` a copy of the font dictionary `container` with the font name
+// `basefont`.
+func replaceFontDict(objs map[core.PdfObject]core.PdfObject, container *core.PdfIndirectObject,
+	basefont string) error {
+	if container == nil {
+		return errors.New("font object not built")
+	}
+	d, ok := core.GetDict(container.PdfObject)
+	if !ok {
+		common.Log.Debug("ERROR: font object is not a dictionary: %T", container.PdfObject)
+		return core.ErrTypeError
+	}
+	subset := core.MakeDict()
+	for _, key := range d.Keys() {
+		subset.Set(key, d.Get(key))
+	}
+	subset.Set("BaseFont", core.MakeName(basefont))
+	objs[container] = core.MakeIndirectObject(subset)
+	return nil
+}
+
+// subset returns the font program stream of the subset of the font program with the glyphs of
+// `runeToGID` and the glyphs `shaped` of shaped text, and the font name with the subset tag.
+func (s *trueTypeSubset) subset(runeToGID map[rune]fonts.GID, shaped map[fonts.GID]rune) (string, *core.PdfObjectStream, error) {
+	gidSet := make(map[fonts.GID]struct{}, len(runeToGID)+len(shaped))
+	for _, gid := range runeToGID {
+		gidSet[gid] = struct{}{}
+	}
+	for gid := range shaped {
+		gidSet[gid] = struct{}{}
+	}
+	gids := make([]fonts.GID, 0, len(gidSet))
+	for gid := range gidSet {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+	basefont := subsetTag(gids) + "+" + s.postScriptName
+
+	subsetFont := fonts.SubsetTrueType
+	if s.cff {
+		subsetFont = fonts.SubsetOpenType
+	}
+	data, err := subsetFont(s.data, gids, runeToGID)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to subset font %s: %v", s.postScriptName, err)
+		return "", nil, err
+	}
+	stream, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return "", nil, err
+	}
+	for _, key := range s.fontFile.Keys() {
+		switch key {
+		case "Length", "Length1", "Filter", "DecodeParms":
+		default:
+			stream.Set(key, s.fontFile.Get(key))
+		}
+	}
+	if !s.cff {
+		stream.Set("Length1", core.MakeInteger(int64(len(data))))
+	}
+	return basefont, stream, nil
+}
+
+// subsetTag returns a subset tag of six uppercase letters for the subset with glyphs `gids`.
+// The tag is derived from the glyphs, so that the same subset gets the same tag.
+func subsetTag(gids []fonts.GID) string {
+	h := fnv.New64a()
+	for _, gid := range gids {
+		h.Write([]byte{byte(gid >> 8), byte(gid)})
+	}
+	v := h.Sum64()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(v%26)
+		v /= 26
+	}
+	return string(tag)
+}
+
+// makeSubsetWidthArr returns a CID font W array for the glyph widths `gidWidths`, indexed by
+// glyph index (CID = GID).
+func makeSubsetWidthArr(gidWidths map[fonts.GID]int) *core.PdfObjectArray {
+	gids := make([]fonts.GID, 0, len(gidWidths))
+	for gid := range gidWidths {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	// Consecutive CIDs are listed in the format: c [w1 w2 ... wn].
+	arr := core.MakeArray()
+	for i := 0; i < len(gids); {
+		widths := core.MakeArray(core.MakeInteger(int64(gidWidths[gids[i]])))
+		j := i + 1
+		for ; j < len(gids) && gids[j] == gids[j-1]+1; j++ {
+			widths.Append(core.MakeInteger(int64(gidWidths[gids[j]])))
+		}
+		arr.Append(core.MakeInteger(int64(gids[i])), widths)
+		i = j
+	}
+	return arr
+}
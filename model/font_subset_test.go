@@ -0,0 +1,269 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+const (
+	subsetTestFont    = "./testdata/font/OpenSans-Regular.ttf"
+	subsetTestCFFFont = "../creator/testdata/CFFTest.otf"
+)
+
+// writeFontTestPDF writes a one page document showing `text` with `font` and returns the font
+// loaded from the written document, along with its encoded text.
+func writeFontTestPDF(t *testing.T, font *PdfFont, text string) (*PdfFont, []byte) {
+	encoded := font.Encoder().Encode(text)
+	data, err := writeFontTestPage(font, encoded)
+	require.NoError(t, err)
+	return loadFontTestPDF(t, data), encoded
+}
+
+// writeFontTestPage returns a one page document showing the text `encoded` with `font`.
+func writeFontTestPage(font *PdfFont, encoded []byte) ([]byte, error) {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	if err := page.Resources.SetFont("F1", font); err != nil {
+		return nil, err
+	}
+	content := fmt.Sprintf("BT /F1 12 Tf 50 700 Td %s Tj ET",
+		core.MakeHexString(string(encoded)).WriteString())
+	if err := page.SetContentStreams([]string{content}, nil); err != nil {
+		return nil, err
+	}
+
+	writer := NewPdfWriter()
+	if err := writer.AddPage(page); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writer.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadFontTestPDF returns the font F1 of the first page of the document `data`.
+func loadFontTestPDF(t *testing.T, data []byte) *PdfFont {
+	reader, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	obj, ok := page.Resources.GetFontByName("F1")
+	require.True(t, ok)
+	loaded, err := NewPdfFontFromPdfObject(obj)
+	require.NoError(t, err)
+	return loaded
+}
+
+// embeddedFontProgram returns the TrueType or OpenType font program embedded for `font`.
+func embeddedFontProgram(t *testing.T, font *PdfFont) []byte {
+	if type0, ok := font.context.(*pdfFontType0); ok {
+		font = type0.DescendantFont
+	}
+	descriptor, err := font.GetFontDescriptor()
+	require.NoError(t, err)
+	require.NotNil(t, descriptor)
+	if descriptor.FontFile3 != nil {
+		stream, ok := core.GetStream(descriptor.FontFile3)
+		require.True(t, ok)
+		subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+		require.Equal(t, "OpenType", subtype)
+		data, err := core.DecodeStream(stream)
+		require.NoError(t, err)
+		return data
+	}
+	stream, ok := core.GetStream(descriptor.FontFile2)
+	require.True(t, ok)
+	data, err := core.DecodeStream(stream)
+	require.NoError(t, err)
+	length1, ok := core.GetIntVal(stream.Get("Length1"))
+	require.True(t, ok)
+	require.Equal(t, len(data), length1)
+	return data
+}
+
+func TestSubsetCompositeFont(t *testing.T) {
+	ttf, err := fonts.TtfParseFile(subsetTestFont)
+	require.NoError(t, err)
+	font, err := NewCompositePdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+
+	text := "Hello, subset world!"
+	loaded, encoded := writeFontTestPDF(t, font, text)
+	assert.True(t, loaded.IsSubset(), loaded.BaseFont())
+	assert.Equal(t, "+"+ttf.PostScriptName, loaded.BaseFont()[6:])
+
+	// The text is decoded with the subset ToUnicode CMap.
+	decoded, _, numMisses := loaded.CharcodeBytesToUnicode(encoded)
+	assert.Equal(t, text, decoded)
+	assert.Equal(t, 0, numMisses)
+
+	// The W array contains the widths of the glyphs used.
+	for _, r := range text {
+		codes := loaded.BytesToCharcodes(font.Encoder().Encode(string(r)))
+		require.Len(t, codes, 1)
+		metrics, ok := loaded.GetCharMetrics(codes[0])
+		require.True(t, ok)
+		assert.Equal(t, float64(int(1000*float64(ttf.Widths[ttf.Chars[r]])/float64(ttf.UnitsPerEm))),
+			metrics.Wx, "%q", r)
+	}
+	descendant := loaded.context.(*pdfFontType0).DescendantFont.context.(*pdfCIDFontType2)
+	assert.Len(t, descendant.widths, len("Helo,subtwrd!")+1)
+
+	// The embedded font program only contains the glyphs used, with the original glyph indices.
+	data := embeddedFontProgram(t, loaded)
+	subset, err := fonts.TtfParse(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Len(t, subset.Chars, len("Helo,subtwrd!")+1)
+	for _, r := range text {
+		assert.Equal(t, ttf.Chars[r], subset.Chars[r], "%q", r)
+	}
+	assert.True(t, len(data) < 40000, "%d bytes", len(data))
+
+	// The font is subset again for the next document, with the glyphs of the new text.
+	text2 := "Quick jumps"
+	loaded2, encoded2 := writeFontTestPDF(t, font, text2)
+	assert.NotEqual(t, loaded.BaseFont(), loaded2.BaseFont())
+	decoded, _, _ = loaded2.CharcodeBytesToUnicode(append(encoded, encoded2...))
+	assert.Equal(t, text+text2, decoded)
+	subset, err = fonts.TtfParse(bytes.NewReader(embeddedFontProgram(t, loaded2)))
+	require.NoError(t, err)
+	for _, r := range text + text2 {
+		assert.Equal(t, ttf.Chars[r], subset.Chars[r], "%q", r)
+	}
+}
+
+func TestSubsetSimpleTrueTypeFont(t *testing.T) {
+	font, err := NewPdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+
+	loaded, encoded := writeFontTestPDF(t, font, "Simple text")
+	assert.True(t, loaded.IsSubset(), loaded.BaseFont())
+	decoded, _, _ := loaded.CharcodeBytesToUnicode(encoded)
+	assert.Equal(t, "Simple text", decoded)
+
+	// The subset contains the glyphs of the WinAnsi characters.
+	subset, err := fonts.TtfParse(bytes.NewReader(embeddedFontProgram(t, loaded)))
+	require.NoError(t, err)
+	for _, r := range "AZaz09é€" {
+		_, ok := subset.Chars[r]
+		assert.True(t, ok, "%q", r)
+	}
+	_, ok := subset.Chars['Ж']
+	assert.False(t, ok)
+}
+
+func TestSubsetOpenTypeCompositeFont(t *testing.T) {
+	font, err := NewCompositePdfFontFromTTFFile(subsetTestCFFFont)
+	require.NoError(t, err)
+
+	loaded, encoded := writeFontTestPDF(t, font, "01中")
+	assert.True(t, loaded.IsSubset(), loaded.BaseFont())
+	decoded, _, numMisses := loaded.CharcodeBytesToUnicode(encoded)
+	assert.Equal(t, "01中", decoded)
+	assert.Equal(t, 0, numMisses)
+
+	// OpenType fonts with CFF outlines are represented by a CIDFontType0 font.
+	descendant, ok := loaded.context.(*pdfFontType0).DescendantFont.context.(*pdfCIDFontType0)
+	require.True(t, ok)
+	assert.Equal(t, loaded.BaseFont(), descendant.basefont)
+	codes := loaded.BytesToCharcodes(encoded)
+	require.Len(t, codes, 3)
+	for i, w := range []float64{600, 400, 600} {
+		metrics, ok := loaded.GetCharMetrics(codes[i])
+		require.True(t, ok)
+		assert.Equal(t, w, metrics.Wx)
+	}
+
+	// The embedded font program only contains the glyphs used, and is loaded when reading.
+	subset, err := fonts.TtfParse(bytes.NewReader(embeddedFontProgram(t, loaded)))
+	require.NoError(t, err)
+	assert.True(t, subset.CFF)
+	assert.Len(t, subset.Chars, 3)
+	_, ok = subset.Chars['Q']
+	assert.False(t, ok)
+	require.NotNil(t, descendant.fontDescriptor.fontFile3)
+	assert.Equal(t, "CFFTest", descendant.fontDescriptor.fontFile3.Name)
+}
+
+func TestSubsetOpenTypeSimpleFont(t *testing.T) {
+	font, err := NewPdfFontFromTTFFile(subsetTestCFFFont)
+	require.NoError(t, err)
+
+	loaded, encoded := writeFontTestPDF(t, font, "10")
+	assert.Equal(t, "Type1", loaded.Subtype())
+	assert.True(t, loaded.IsSubset(), loaded.BaseFont())
+	decoded, _, _ := loaded.CharcodeBytesToUnicode(encoded)
+	assert.Equal(t, "10", decoded)
+	metrics, ok := loaded.GetCharMetrics('0')
+	require.True(t, ok)
+	assert.Equal(t, 600.0, metrics.Wx)
+
+	subset, err := fonts.TtfParse(bytes.NewReader(embeddedFontProgram(t, loaded)))
+	require.NoError(t, err)
+	assert.Equal(t, []fonts.GlyphName{".notdef", "zero", "one", "Q", "uni4E2D"}, subset.GlyphNames)
+	_, ok = subset.Chars['中']
+	assert.False(t, ok)
+}
+
+// TestSubsetSharedFont checks that a font shared by documents written concurrently is subset for
+// each of them without being modified. Run with -race.
+func TestSubsetSharedFont(t *testing.T) {
+	font, err := NewCompositePdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+	fontFile := font.context.(*pdfFontType0).ttfSubset.fontFile
+	fontFileData := fontFile.Stream
+
+	texts := []string{"Hello", "shared", "font", "written", "concurrently", "by", "many", "writers"}
+	docs := make([][]byte, len(texts))
+	encoded := make([][]byte, len(texts))
+	errs := make([]error, len(texts))
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				encoded[i] = font.Encoder().Encode(text)
+				font.GetRuneMetrics('H')
+				docs[i], errs[i] = writeFontTestPage(font, encoded[i])
+				if errs[i] != nil {
+					return
+				}
+			}
+		}(i, text)
+	}
+	wg.Wait()
+
+	for i, text := range texts {
+		require.NoError(t, errs[i])
+		loaded := loadFontTestPDF(t, docs[i])
+		assert.True(t, loaded.IsSubset(), loaded.BaseFont())
+		decoded, _, numMisses := loaded.CharcodeBytesToUnicode(encoded[i])
+		assert.Equal(t, text, decoded)
+		assert.Equal(t, 0, numMisses)
+		subset, err := fonts.TtfParse(bytes.NewReader(embeddedFontProgram(t, loaded)))
+		require.NoError(t, err)
+		for _, r := range text {
+			_, ok := subset.Chars[r]
+			assert.True(t, ok, "%q", r)
+		}
+	}
+
+	// The objects of the font are not modified by subsetting.
+	d, ok := core.GetDict(font.ToPdfObject())
+	require.True(t, ok)
+	basefont, _ := core.GetNameVal(d.Get("BaseFont"))
+	assert.Equal(t, font.BaseFont(), basefont)
+	assert.False(t, font.IsSubset(), font.BaseFont())
+	assert.Equal(t, fontFileData, fontFile.Stream)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -879,3 +880,197 @@ func TestNewFontFromFile(t *testing.T) {
 		t.Fatalf("Failed to load font from file. err=%v", err)
 	}
 }
+
+func TestFontHasRune(t *testing.T) {
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	require.True(t, helvetica.HasRune('A'))
+	require.True(t, helvetica.HasRune('é'))
+	require.False(t, helvetica.HasRune('ש'))
+
+	font, err := model.NewCompositePdfFontFromTTFFile("../creator/testdata/FreeSans.ttf")
+	require.NoError(t, err)
+	require.True(t, font.HasRune('A'))
+	require.True(t, font.HasRune('ש'))
+	require.False(t, font.HasRune('中'))
+
+	// The runes are not registered for subsetting.
+	ttEncoder, ok := font.Encoder().(textencoding.TrueTypeFontEncoder)
+	require.True(t, ok)
+	require.Empty(t, ttEncoder.RegisteredRunes())
+}
+
+// TestLoadType1CFont tests loading a simple font with a Type1C font program (FontFile3).
+func TestLoadType1CFont(t *testing.T) {
+	f, err := os.Open("./testdata/SampleSignedPDFDocument.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	obj, ok := page.Resources.GetFontByName("T1_0")
+	require.True(t, ok)
+
+	font, err := model.NewPdfFontFromPdfObject(obj)
+	require.NoError(t, err)
+	require.Equal(t, "UCYXRE+AdobeClean-Regular", font.BaseFont())
+	descriptor, err := font.GetFontDescriptor()
+	require.NoError(t, err)
+	require.Contains(t, descriptor.String(), "CFF{`UCYXRE+AdobeClean-Regular`")
+
+	// Without a Widths array, the widths are taken from the font program.
+	d, ok := core.GetDict(obj)
+	require.True(t, ok)
+	noWidths := core.MakeDict()
+	for _, key := range d.Keys() {
+		if key != "Widths" {
+			noWidths.Set(key, d.Get(key))
+		}
+	}
+	fontNoWidths, err := model.NewPdfFontFromPdfObject(noWidths)
+	require.NoError(t, err)
+
+	numWidths := 0
+	for code := textencoding.CharCode(0); code < 256; code++ {
+		metrics, ok := font.GetCharMetrics(code)
+		if !ok || metrics.Wx == 0 {
+			continue
+		}
+		metrics2, ok := fontNoWidths.GetCharMetrics(code)
+		require.True(t, ok, "code=%d", code)
+		require.InDelta(t, metrics.Wx, metrics2.Wx, 1, "code=%d", code)
+		numWidths++
+	}
+	require.NotZero(t, numWidths)
+}
+
+// TestLoadType3Font tests loading a Type 3 font with glyph names that are not in the glyph list,
+// as produced by TeX, and a ToUnicode CMap for text decoding.
+func TestLoadType3Font(t *testing.T) {
+	rawpdf := `
+10 0 obj
+<< /Type /Font /Subtype /Type3 /FontBBox [0 0 700 700] /FontMatrix [0.01 0 0 0.01 0 0]
+   /CharProcs 11 0 R /Encoding 12 0 R /FirstChar 1 /LastChar 2 /Widths [60 70]
+   /ToUnicode 15 0 R >>
+endobj
+11 0 obj
+<< /a1 13 0 R /a2 14 0 R >>
+endobj
+12 0 obj
+<< /Type /Encoding /Differences [1 /a1 /a2] >>
+endobj
+13 0 obj
+<< /Length 14 >>
+stream
+0 0 50 70 re f
+endstream
+endobj
+14 0 obj
+<< /Length 14 >>
+stream
+0 0 70 70 re f
+endstream
+endobj
+15 0 obj
+<< /Length 221 >>
+stream
+/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Adobe-Identity-UCS def
+1 begincodespacerange
+<00> <FF>
+endcodespacerange
+2 beginbfchar
+<01> <0041>
+<02> <0042>
+endbfchar
+endcmap
+end
+end
+endstream
+endobj
+`
+	objects, err := testutils.ParseIndirectObjects(rawpdf)
+	require.NoError(t, err)
+
+	font, err := model.NewPdfFontFromPdfObject(objects[10])
+	require.NoError(t, err)
+	require.Equal(t, "Type3", font.Subtype())
+	require.Equal(t, "", font.BaseFont())
+	require.Nil(t, font.FontDescriptor())
+	require.Equal(t, []float64{0.01, 0, 0, 0.01, 0, 0}, font.FontMatrix())
+
+	text, _, numMisses := font.CharcodeBytesToUnicode([]byte{1, 2, 1})
+	require.Equal(t, 0, numMisses)
+	require.Equal(t, "ABA", text)
+
+	for code, width := range map[textencoding.CharCode]float64{1: 600, 2: 700} {
+		metrics, ok := font.GetCharMetrics(code)
+		require.True(t, ok)
+		require.InDelta(t, width, metrics.Wx, 1e-9)
+	}
+
+	proc, ok := font.CharProc(2)
+	require.True(t, ok)
+	require.Equal(t, "0 0 70 70 re f", string(proc))
+	_, ok = font.CharProc(3)
+	require.False(t, ok)
+
+	// Check that the font dictionary is written back unchanged.
+	obj1 := core.FlattenObject(objects[10])
+	obj2 := core.FlattenObject(font.ToPdfObject())
+	require.True(t, core.EqualObjects(obj1, obj2), "obj1=%s\nobj2=%s", obj1, obj2)
+}
+
+// TestNewPdfFontType3 tests creating a Type 3 font from glyph definitions and loading it back.
+func TestNewPdfFontType3(t *testing.T) {
+	glyphs := []model.Type3Glyph{
+		{
+			Rune:    'A',
+			Width:   600,
+			BBox:    model.PdfRectangle{Llx: 0, Lly: 0, Urx: 500, Ury: 700},
+			Content: []byte("0 0 m 250 700 l 500 0 l f\n"),
+		},
+		{
+			Rune:    '★',
+			Width:   900,
+			BBox:    model.PdfRectangle{Llx: 50, Lly: -100, Urx: 850, Ury: 700},
+			Content: []byte("50 -100 800 800 re f\n"),
+		},
+	}
+	font, err := model.NewPdfFontType3(glyphs, nil, nil)
+	require.NoError(t, err)
+
+	// Write the font dict and load it back as a reader would.
+	font, err = model.NewPdfFontFromPdfObject(font.ToPdfObject())
+	require.NoError(t, err)
+	require.Equal(t, "Type3", font.Subtype())
+
+	dict, ok := core.GetDict(font.ToPdfObject())
+	require.True(t, ok)
+	arr, ok := core.GetArray(dict.Get("FontBBox"))
+	require.True(t, ok)
+	bbox, err := arr.ToFloat64Array()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, -100, 850, 700}, bbox)
+
+	encoded, numMisses := font.StringToCharcodeBytes("A★A")
+	require.Equal(t, 0, numMisses)
+	require.Equal(t, []byte{1, 2, 1}, encoded)
+
+	text, _, numMisses := font.CharcodeBytesToUnicode(encoded)
+	require.Equal(t, 0, numMisses)
+	require.Equal(t, "A★A", text)
+
+	metrics, ok := font.GetRuneMetrics('★')
+	require.True(t, ok)
+	require.InDelta(t, 900, metrics.Wx, 1e-9)
+
+	proc, ok := font.CharProc(1)
+	require.True(t, ok)
+	require.Equal(t, "600 0 0 0 500 700 d1\n0 0 m 250 700 l 500 0 l f\n", string(proc))
+
+	_, err = model.NewPdfFontType3(append(glyphs, glyphs[0]), nil, nil)
+	require.Error(t, err)
+}
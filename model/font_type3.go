@@ -0,0 +1,470 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// pdfFontType3 implements pdfFont
+var _ pdfFont = (*pdfFontType3)(nil)
+
+// pdfFontType3 represents a Type 3 font, whose glyphs are defined by PDF content streams instead of
+// a font program.
+//
+// 9.6.5 Type 3 Fonts (page 258)
+// Type 3 fonts differ from the other fonts supported by PDF. A Type 3 font dictionary defines the
+// font; font dictionaries for other fonts simply contain information about the font and refer to a
+// separate font program for the actual glyph descriptions. In Type 3 fonts, glyphs shall be defined
+// by streams of PDF graphics operators. These streams shall be associated with glyph names. A
+// separate encoding entry shall map character codes to the appropriate glyph names for the glyphs.
+type pdfFontType3 struct {
+	fontCommon
+	container *core.PdfIndirectObject
+
+	// charWidths are the glyph widths in glyph space units, as given by the /Widths array.
+	charWidths map[textencoding.CharCode]float64
+	// encoder is built from the /Encoding entry in the font dict.
+	encoder textencoding.SimpleEncoder
+	// codeToGlyph maps character codes to glyph names using the /Differences of the encoding.
+	codeToGlyph map[textencoding.CharCode]textencoding.GlyphName
+	// fontMatrix maps glyph space to text space.
+	fontMatrix [6]float64
+	// resources are the named resources used by the glyph descriptions.
+	resources *PdfPageResources
+
+	FontBBox   core.PdfObject
+	FontMatrix core.PdfObject
+	CharProcs  *core.PdfObjectDictionary
+	Encoding   core.PdfObject
+	FirstChar  core.PdfObject
+	LastChar   core.PdfObject
+	Widths     core.PdfObject
+	Resources  core.PdfObject
+}
+
+// baseFields returns the fields of `font` that are common to all PDF fonts.
+func (font *pdfFontType3) baseFields() *fontCommon {
+	return &font.fontCommon
+}
+
+// getFontDescriptor returns the font descriptor of `font`. It is optional for Type 3 fonts.
+func (font *pdfFontType3) getFontDescriptor() *PdfFontDescriptor {
+	return font.fontDescriptor
+}
+
+// Encoder returns the font's text encoder.
+func (font *pdfFontType3) Encoder() textencoding.TextEncoder {
+	if font.encoder == nil {
+		return nil
+	}
+	return font.encoder
+}
+
+// GetRuneMetrics returns the character metrics for the rune.
+// A bool flag is returned to indicate whether or not the entry was found.
+func (font *pdfFontType3) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
+	if font.encoder == nil {
+		return fonts.CharMetrics{}, false
+	}
+	code, ok := font.encoder.RuneToCharcode(r)
+	if !ok {
+		return fonts.CharMetrics{}, false
+	}
+	return font.GetCharMetrics(code)
+}
+
+// GetCharMetrics returns the character metrics for the specified character code. The glyph width
+// is mapped to text space by the font matrix and returned in thousandths of a text space unit, the
+// same units used for the metrics of the other font types.
+func (font *pdfFontType3) GetCharMetrics(code textencoding.CharCode) (fonts.CharMetrics, bool) {
+	width, ok := font.charWidths[code]
+	if !ok {
+		return fonts.CharMetrics{}, false
+	}
+	return fonts.CharMetrics{
+		Wx: width * font.fontMatrix[0] * 1000,
+		Wy: width * font.fontMatrix[1] * 1000,
+	}, true
+}
+
+// glyphName returns the name of the glyph selected by character code `code`.
+func (font *pdfFontType3) glyphName(code textencoding.CharCode) (textencoding.GlyphName, bool) {
+	if glyph, ok := font.codeToGlyph[code]; ok {
+		return glyph, true
+	}
+	if font.encoder == nil {
+		return "", false
+	}
+	r, ok := font.encoder.CharcodeToRune(code)
+	if !ok {
+		return "", false
+	}
+	return textencoding.RuneToGlyph(r)
+}
+
+// charProc returns the decoded glyph description for character code `code`.
+func (font *pdfFontType3) charProc(code textencoding.CharCode) ([]byte, bool) {
+	if font.CharProcs == nil {
+		return nil, false
+	}
+	glyph, ok := font.glyphName(code)
+	if !ok {
+		return nil, false
+	}
+	stream, ok := core.GetStream(font.CharProcs.Get(core.PdfObjectName(glyph)))
+	if !ok {
+		common.Log.Trace("No glyph procedure. code=%d glyph=%q", code, glyph)
+		return nil, false
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to decode glyph procedure %q: %v", glyph, err)
+		return nil, false
+	}
+	return data, true
+}
+
+// newPdfFontType3FromPdfObject creates a pdfFontType3 from dictionary `d`. Elements of `d` that
+// are already parsed are contained in `base`.
+func newPdfFontType3FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon) (*pdfFontType3, error) {
+	font := &pdfFontType3{
+		fontCommon: *base,
+		charWidths: make(map[textencoding.CharCode]float64),
+	}
+
+	font.FontBBox = d.Get("FontBBox")
+
+	obj := d.Get("FontMatrix")
+	if obj == nil {
+		common.Log.Debug("ERROR: Type3 font FontMatrix (Required) missing. font=%s", base)
+		return nil, ErrRequiredAttributeMissing
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() != 6 {
+		common.Log.Debug("ERROR: Invalid FontMatrix (%T) %s", obj, obj)
+		return nil, core.ErrTypeError
+	}
+	matrix, err := arr.ToFloat64Array()
+	if err != nil {
+		common.Log.Debug("ERROR: Invalid FontMatrix %s: %v", arr, err)
+		return nil, err
+	}
+	font.FontMatrix = obj
+	copy(font.fontMatrix[:], matrix)
+
+	charProcs, ok := core.GetDict(d.Get("CharProcs"))
+	if !ok {
+		common.Log.Debug("ERROR: Type3 font CharProcs (Required) missing. font=%s", base)
+		return nil, ErrRequiredAttributeMissing
+	}
+	font.CharProcs = charProcs
+
+	if obj := d.Get("Resources"); obj != nil {
+		font.Resources = obj
+		if dict, ok := core.GetDict(obj); ok {
+			resources, err := NewPdfPageResourcesFromDict(dict)
+			if err != nil {
+				common.Log.Debug("ERROR: Invalid Type3 font Resources: %v", err)
+				return nil, err
+			}
+			font.resources = resources
+		}
+	}
+
+	font.FirstChar = d.Get("FirstChar")
+	font.LastChar = d.Get("LastChar")
+	font.Widths = d.Get("Widths")
+	if font.FirstChar == nil {
+		font.FirstChar = core.MakeInteger(0)
+	}
+	firstChar, ok := core.GetIntVal(font.FirstChar)
+	if !ok {
+		common.Log.Debug("ERROR: Invalid FirstChar type (%T)", font.FirstChar)
+		return nil, core.ErrTypeError
+	}
+	if font.Widths != nil {
+		arr, ok := core.GetArray(font.Widths)
+		if !ok {
+			common.Log.Debug("ERROR: Widths attribute != array (%T)", font.Widths)
+			return nil, core.ErrTypeError
+		}
+		widths, err := arr.ToFloat64Array()
+		if err != nil {
+			common.Log.Debug("ERROR: converting widths to array")
+			return nil, err
+		}
+		for i, w := range widths {
+			font.charWidths[textencoding.CharCode(firstChar+i)] = w
+		}
+	}
+
+	font.Encoding = core.TraceToDirectObject(d.Get("Encoding"))
+	if err := font.addEncoding(); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+// addEncoding sets the encoder of `font` from its /Encoding dictionary. Type 3 fonts have no
+// built-in encoding, so when there is no /BaseEncoding only the codes in the /Differences array are
+// mapped.
+func (font *pdfFontType3) addEncoding() error {
+	var (
+		baseName    string
+		differences map[textencoding.CharCode]textencoding.GlyphName
+		err         error
+	)
+	switch encoding := font.Encoding.(type) {
+	case nil:
+		common.Log.Debug("Type3 font without Encoding. font=%s", font.baseFields())
+	case *core.PdfObjectName:
+		baseName = encoding.String()
+	case *core.PdfObjectDictionary:
+		if name, ok := core.GetName(encoding.Get("BaseEncoding")); ok {
+			baseName = name.String()
+		}
+		if diffObj := encoding.Get("Differences"); diffObj != nil {
+			diffList, ok := core.GetArray(diffObj)
+			if !ok {
+				common.Log.Debug("ERROR: Bad font encoding dict=%+v Differences=%T",
+					encoding, diffObj)
+				return core.ErrTypeError
+			}
+			differences, err = textencoding.FromFontDifferences(diffList)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		common.Log.Debug("ERROR: Encoding not a name or dict (%T) %s", font.Encoding, font.Encoding)
+		return core.ErrTypeError
+	}
+	font.codeToGlyph = differences
+
+	switch {
+	case baseName != "":
+		font.encoder, err = textencoding.NewSimpleTextEncoder(baseName, differences)
+	case len(differences) != 0:
+		font.encoder, err = textencoding.NewCustomSimpleTextEncoder(differences, nil)
+	default:
+		font.encoder, err = textencoding.NewSimpleTextEncoder("StandardEncoding", nil)
+	}
+	return err
+}
+
+// ToPdfObject converts the pdfFontType3 to its PDF representation for outputting.
+func (font *pdfFontType3) ToPdfObject() core.PdfObject {
+	if font.container == nil {
+		font.container = &core.PdfIndirectObject{}
+	}
+	d := font.baseFields().asPdfObjectDictionary("Type3")
+	if font.basefont == "" {
+		d.Remove("BaseFont")
+	}
+	font.container.PdfObject = d
+
+	if font.FontBBox != nil {
+		d.Set("FontBBox", font.FontBBox)
+	}
+	if font.FontMatrix != nil {
+		d.Set("FontMatrix", font.FontMatrix)
+	}
+	if font.CharProcs != nil {
+		d.Set("CharProcs", font.CharProcs)
+	}
+	if font.Encoding != nil {
+		d.Set("Encoding", font.Encoding)
+	}
+	if font.FirstChar != nil {
+		d.Set("FirstChar", font.FirstChar)
+	}
+	if font.LastChar != nil {
+		d.Set("LastChar", font.LastChar)
+	}
+	if font.Widths != nil {
+		d.Set("Widths", font.Widths)
+	}
+	if font.Resources != nil {
+		d.Set("Resources", font.Resources)
+	} else if font.resources != nil {
+		d.Set("Resources", font.resources.ToPdfObject())
+	}
+
+	return font.container
+}
+
+// Type3Glyph is a glyph description used for creating Type 3 fonts with NewPdfFontType3.
+type Type3Glyph struct {
+	// Rune is the character represented by the glyph.
+	Rune rune
+
+	// Width is the horizontal displacement of the glyph in glyph space units.
+	Width float64
+
+	// BBox is the bounding box of the glyph in glyph space units.
+	BBox PdfRectangle
+
+	// Content is the content stream that paints the glyph in glyph space. It should only contain
+	// path construction and painting operators, as the glyph is painted with the current fill and
+	// stroke colors. The leading d1 operator is added by NewPdfFontType3.
+	Content []byte
+}
+
+// NewPdfFontType3 creates a Type 3 font from the vector glyph descriptions in `glyphs`.
+// `fontMatrix` maps glyph space to text space. If it is nil, the customary [0.001 0 0 0.001 0 0]
+// is used so that glyph space has 1000 units per em. `resources` holds the named resources used by
+// the glyph descriptions and may be nil.
+// The glyphs are assigned consecutive character codes starting at 1 and a ToUnicode CMap is
+// generated from their runes, so text drawn with the font can be written and extracted like text
+// drawn with any other simple font.
+func NewPdfFontType3(glyphs []Type3Glyph, fontMatrix []float64, resources *PdfPageResources) (*PdfFont, error) {
+	if len(glyphs) == 0 {
+		return nil, errors.New("no glyphs")
+	}
+	if len(glyphs) > 255 {
+		common.Log.Debug("ERROR: Too many glyphs for a Type3 font (%d)", len(glyphs))
+		return nil, core.ErrRangeError
+	}
+	if fontMatrix == nil {
+		fontMatrix = []float64{0.001, 0, 0, 0.001, 0, 0}
+	}
+	if len(fontMatrix) != 6 {
+		common.Log.Debug("ERROR: Invalid font matrix %v", fontMatrix)
+		return nil, core.ErrRangeError
+	}
+
+	const firstChar = 1
+	font := &pdfFontType3{
+		fontCommon: fontCommon{
+			subtype: "Type3",
+		},
+		charWidths:  make(map[textencoding.CharCode]float64, len(glyphs)),
+		codeToGlyph: make(map[textencoding.CharCode]textencoding.GlyphName, len(glyphs)),
+		resources:   resources,
+	}
+	copy(font.fontMatrix[:], fontMatrix)
+
+	var (
+		bbox          PdfRectangle
+		widths        = make([]float64, len(glyphs))
+		charProcs     = core.MakeDict()
+		codeToUnicode = make(map[cmap.CharCode]rune, len(glyphs))
+		seen          = make(map[rune]struct{}, len(glyphs))
+	)
+	for i, g := range glyphs {
+		if _, ok := seen[g.Rune]; ok {
+			common.Log.Debug("ERROR: Duplicate glyph for rune %q", g.Rune)
+			return nil, fmt.Errorf("duplicate glyph for rune %q", g.Rune)
+		}
+		seen[g.Rune] = struct{}{}
+
+		code := textencoding.CharCode(firstChar + i)
+		glyph := type3GlyphName(g.Rune)
+		font.codeToGlyph[code] = glyph
+		font.charWidths[code] = g.Width
+		codeToUnicode[cmap.CharCode(code)] = g.Rune
+		widths[i] = g.Width
+
+		var content bytes.Buffer
+		for _, v := range []float64{g.Width, 0, g.BBox.Llx, g.BBox.Lly, g.BBox.Urx, g.BBox.Ury} {
+			content.WriteString(core.MakeFloat(v).WriteString())
+			content.WriteByte(' ')
+		}
+		content.WriteString("d1\n")
+		content.Write(g.Content)
+		stream, err := core.MakeStream(content.Bytes(), core.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+		charProcs.Set(core.PdfObjectName(glyph), stream)
+
+		if i == 0 {
+			bbox = g.BBox
+		} else {
+			bbox.Llx = math.Min(bbox.Llx, g.BBox.Llx)
+			bbox.Lly = math.Min(bbox.Lly, g.BBox.Lly)
+			bbox.Urx = math.Max(bbox.Urx, g.BBox.Urx)
+			bbox.Ury = math.Max(bbox.Ury, g.BBox.Ury)
+		}
+	}
+
+	encoder, err := textencoding.NewCustomSimpleTextEncoder(font.codeToGlyph, nil)
+	if err != nil {
+		return nil, err
+	}
+	font.encoder = encoder
+
+	differences := core.MakeArray(core.MakeInteger(firstChar))
+	for i := range glyphs {
+		glyph := font.codeToGlyph[textencoding.CharCode(firstChar+i)]
+		differences.Append(core.MakeName(string(glyph)))
+	}
+	encoding := core.MakeDict()
+	encoding.Set("Type", core.MakeName("Encoding"))
+	encoding.Set("Differences", differences)
+
+	font.toUnicodeCmap = cmap.NewToUnicodeCMap(codeToUnicode)
+	font.FontBBox = bbox.ToPdfObject()
+	font.FontMatrix = core.MakeArrayFromFloats(fontMatrix)
+	font.CharProcs = charProcs
+	font.Encoding = encoding
+	font.FirstChar = core.MakeInteger(firstChar)
+	font.LastChar = core.MakeInteger(int64(firstChar + len(glyphs) - 1))
+	font.Widths = core.MakeArrayFromFloats(widths)
+
+	return &PdfFont{context: font}, nil
+}
+
+// type3GlyphName returns the name of the glyph for rune `r` in Type 3 fonts created by
+// NewPdfFontType3. The name maps back to `r` so that the font's encoder can encode text.
+func type3GlyphName(r rune) textencoding.GlyphName {
+	if glyph, ok := textencoding.RuneToGlyph(r); ok {
+		if r2, ok := textencoding.GlyphToRune(glyph); ok && r2 == r {
+			return glyph
+		}
+	}
+	if r > 0xffff {
+		return textencoding.GlyphName(fmt.Sprintf("u%X", r))
+	}
+	return textencoding.GlyphName(fmt.Sprintf("uni%04X", r))
+}
+
+// FontMatrix returns the matrix that maps the glyph space of `font` to text space. This is the
+// /FontMatrix of Type 3 fonts and [0.001 0 0 0.001 0 0] for all other fonts.
+func (font *PdfFont) FontMatrix() []float64 {
+	if t, ok := font.context.(*pdfFontType3); ok {
+		m := t.fontMatrix
+		return m[:]
+	}
+	return []float64{0.001, 0, 0, 0.001, 0, 0}
+}
+
+// CharProc returns the decoded content stream that paints the glyph selected by character code
+// `code` in a Type 3 font. The bool return is false if `font` is not a Type 3 font or the glyph
+// procedure is missing.
+func (font *PdfFont) CharProc(code textencoding.CharCode) ([]byte, bool) {
+	t, ok := font.context.(*pdfFontType3)
+	if !ok {
+		return nil, false
+	}
+	return t.charProc(code)
+}
+
+// CharProcResources returns the resources used by the glyph procedures of a Type 3 font. It
+// returns nil if `font` is not a Type 3 font or has no /Resources, in which case the glyph
+// procedures use the resources of the page on which the font is used.
+func (font *PdfFont) CharProcResources() *PdfPageResources {
+	if t, ok := font.context.(*pdfFontType3); ok {
+		return t.resources
+	}
+	return nil
+}
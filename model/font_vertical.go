@@ -0,0 +1,244 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+/*
+   9.7.4.3 Glyph Metrics in CIDFonts (page 271)
+
+   In vertical writing mode the glyphs are positioned at their vertical origin, which is offset from
+   the horizontal origin of the glyph by the position vector v = (vx, vy). After drawing a glyph the
+   text position is moved by the displacement vector w1 = (0, w1y), w1y being negative as vertical
+   text advances downwards.
+
+   DW2  array  (Optional) An array of two numbers [vy w1y] specifying the default metrics for
+               vertical writing. The default vx is half of the horizontal width of the glyph.
+               Default value: [880 −1000].
+   W2   array  (Optional) The metrics of individual CIDs for vertical writing, in the formats
+                 c [w1y1 v1x v1y w1y2 v2x v2y ...]
+                 c_first c_last w1y v1x v1y
+*/
+
+// VerticalMetrics represents the metrics of a glyph in vertical writing mode, in glyph space
+// units (see section 9.7.4.3 "Glyph Metrics in CIDFonts" PDF32000_2008).
+type VerticalMetrics struct {
+	// W1y is the vertical displacement of the text position after drawing the glyph. It is
+	// negative, as vertical text advances downwards.
+	W1y float64
+	// Vx and Vy are the position vector of the glyph: the offset of the vertical origin of the
+	// glyph, at which it is drawn, from its horizontal origin.
+	Vx, Vy float64
+}
+
+// cidVerticalMetrics holds the vertical metrics of a CIDFont, parsed from its DW2 and W2 entries.
+type cidVerticalMetrics struct {
+	vy, w1y float64                                   // The default metrics (DW2).
+	metrics map[textencoding.CharCode]VerticalMetrics // The metrics of individual CIDs (W2).
+}
+
+// IsVertical returns true if `font` is a composite font used for vertical writing, i.e. its
+// CMap has writing mode 1 (e.g. the Identity-V CMap).
+func (font *PdfFont) IsVertical() bool {
+	type0, ok := font.context.(*pdfFontType0)
+	return ok && type0.vertical
+}
+
+// GetVerticalMetrics returns the vertical writing mode metrics of character code `code` of the
+// composite font `font`. The bool is false if `font` is not a composite font or its vertical
+// metrics are not known.
+func (font *PdfFont) GetVerticalMetrics(code textencoding.CharCode) (VerticalMetrics, bool) {
+	type0, ok := font.context.(*pdfFontType0)
+	if !ok || type0.DescendantFont == nil {
+		return VerticalMetrics{}, false
+	}
+	var vertical *cidVerticalMetrics
+	switch cidfont := type0.DescendantFont.context.(type) {
+	case *pdfCIDFontType0:
+		vertical = cidfont.vertical
+	case *pdfCIDFontType2:
+		vertical = cidfont.vertical
+	}
+	if vertical == nil {
+		return VerticalMetrics{}, false
+	}
+	if m, ok := vertical.metrics[code]; ok {
+		return m, true
+	}
+	metrics, _ := type0.DescendantFont.GetCharMetrics(code)
+	return VerticalMetrics{W1y: vertical.w1y, Vx: metrics.Wx / 2, Vy: vertical.vy}, true
+}
+
+// VerticalFont returns the variant of `font` for vertical writing: a composite font with the
+// Identity-V CMap, whose glyphs are the vertical forms of the glyphs of `font` (the "vert"
+// OpenType feature, e.g. rotated brackets) and whose vertical metrics are those of the "vmtx"
+// table of the font program. The variant is created once, subsequent calls return the same font.
+// `font` is returned if it is vertical already.
+//
+// Only composite fonts created with NewCompositePdfFontFromTTFFile have vertical variants.
+func (font *PdfFont) VerticalFont() (*PdfFont, error) {
+	if font.IsVertical() {
+		return font, nil
+	}
+	type0, ok := font.context.(*pdfFontType0)
+	if !ok || type0.ttfSubset == nil {
+		common.Log.Debug("ERROR: no vertical variant of font %s", font)
+		return nil, ErrFontNotSupported
+	}
+	if type0.verticalFont == nil {
+		vf, err := newCompositePdfFontFromTTF(type0.ttfSubset.data, true)
+		if err != nil {
+			return nil, err
+		}
+		type0.verticalFont = vf
+	}
+	return type0.verticalFont, nil
+}
+
+// isVerticalEncoding returns true if the Encoding entry `encoding` of a Type0 font is a CMap with
+// writing mode 1: a predefined vertical CMap or an embedded CMap with a WMode of 1.
+func isVerticalEncoding(encoding core.PdfObject) bool {
+	switch t := core.TraceToDirectObject(encoding).(type) {
+	case *core.PdfObjectName:
+		name := string(*t)
+		return name == "Identity-V" || cmap.IsPredefinedCMap(name) && strings.HasSuffix(name, "-V")
+	case *core.PdfObjectStream:
+		wmode, _ := core.GetIntVal(t.Get("WMode"))
+		return wmode == 1
+	}
+	return false
+}
+
+// parseCIDFontVerticalMetrics parses the DW2 and W2 entries of a CIDFont.
+func parseCIDFontVerticalMetrics(dw2, w2 core.PdfObject) (*cidVerticalMetrics, error) {
+	vertical := &cidVerticalMetrics{
+		vy:      880,
+		w1y:     -1000,
+		metrics: make(map[textencoding.CharCode]VerticalMetrics),
+	}
+	if arr, ok := core.GetArray(dw2); ok {
+		if vals, err := arr.ToFloat64Array(); err == nil && len(vals) == 2 {
+			vertical.vy, vertical.w1y = vals[0], vals[1]
+		} else {
+			common.Log.Debug("ERROR: Bad font DW2 array: %v", arr)
+		}
+	}
+
+	arr, ok := core.GetArray(w2)
+	if !ok {
+		return vertical, nil
+	}
+	for i := 0; i < arr.Len(); {
+		c, ok := core.GetIntVal(arr.Get(i))
+		if !ok || i+1 >= arr.Len() {
+			return vertical, fmt.Errorf("Bad font W2 obj0: i=%d %#v", i, arr.Get(i))
+		}
+		if metrics, ok := core.GetArray(arr.Get(i + 1)); ok {
+			vals, err := metrics.ToFloat64Array()
+			if err != nil || len(vals)%3 != 0 {
+				return vertical, fmt.Errorf("Bad font W2 array obj1: i=%d %#v", i+1, metrics)
+			}
+			for j := 0; j < len(vals); j += 3 {
+				vertical.metrics[textencoding.CharCode(c+j/3)] = VerticalMetrics{
+					W1y: vals[j], Vx: vals[j+1], Vy: vals[j+2],
+				}
+			}
+			i += 2
+			continue
+		}
+
+		if i+4 >= arr.Len() {
+			return vertical, fmt.Errorf("Bad font W2 array: i=%d len=%d", i, arr.Len())
+		}
+		last, ok := core.GetIntVal(arr.Get(i + 1))
+		if !ok {
+			return vertical, fmt.Errorf("Bad font W2 int obj1: i=%d %#v", i+1, arr.Get(i+1))
+		}
+		vals, err := core.MakeArray(arr.Elements()[i+2 : i+5]...).ToFloat64Array()
+		if err != nil {
+			return vertical, fmt.Errorf("Bad font W2 metrics: i=%d %v", i+2, err)
+		}
+		for code := c; code <= last; code++ {
+			vertical.metrics[textencoding.CharCode(code)] = VerticalMetrics{
+				W1y: vals[0], Vx: vals[1], Vy: vals[2],
+			}
+		}
+		i += 5
+	}
+	return vertical, nil
+}
+
+// ttfVerticalMetrics holds the metrics of a TrueType font from which the vertical metrics of a
+// vertical composite font are made.
+type ttfVerticalMetrics struct {
+	widths  []uint16 // The horizontal advances in font units, by glyph index.
+	heights []uint16 // The vertical advances in font units, by glyph index. Nil if unknown.
+	scale   float64  // The scale from font units to glyph space units.
+	vy      int      // The default vertical origin, in glyph space units.
+}
+
+// defaultMetrics returns the DW2 array: the vertical origin and a vertical advance of 1 em.
+func (m *ttfVerticalMetrics) defaultMetrics() *core.PdfObjectArray {
+	return core.MakeArray(core.MakeInteger(int64(m.vy)), core.MakeInteger(-1000))
+}
+
+// makeW2 returns the W2 array of the glyphs `gids`. Only the glyphs whose vertical advance
+// differs from the default advance of 1 em have entries, consecutive glyphs with the same
+// metrics share an entry.
+func (m *ttfVerticalMetrics) makeW2(gids []fonts.GID) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	if m.heights == nil {
+		return arr
+	}
+	sorted := make([]fonts.GID, len(gids))
+	copy(sorted, gids)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	metrics := func(gid fonts.GID) (w1y, vx int, ok bool) {
+		if int(gid) >= len(m.heights) || int(gid) >= len(m.widths) {
+			return 0, 0, false
+		}
+		w1y = -int(m.scale * float64(m.heights[gid]))
+		vx = int(m.scale*float64(m.widths[gid])) / 2
+		return w1y, vx, w1y != -1000
+	}
+	for i := 0; i < len(sorted); i++ {
+		w1y, vx, ok := metrics(sorted[i])
+		if !ok {
+			continue
+		}
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			w1y2, vx2, ok := metrics(sorted[j+1])
+			if !ok || w1y2 != w1y || vx2 != vx {
+				break
+			}
+			j++
+		}
+		arr.Append(core.MakeInteger(int64(sorted[i])), core.MakeInteger(int64(sorted[j])),
+			core.MakeInteger(int64(w1y)), core.MakeInteger(int64(vx)), core.MakeInteger(int64(m.vy)))
+		i = j
+	}
+	return arr
+}
+
+// verticalChars returns the rune to glyph index map `chars` with the glyphs replaced by their
+// vertical forms `substs`.
+func verticalChars(chars map[rune]fonts.GID, substs map[fonts.GID]fonts.GID) map[rune]fonts.GID {
+	vchars := make(map[rune]fonts.GID, len(chars))
+	for r, gid := range chars {
+		if vgid, ok := substs[gid]; ok {
+			gid = vgid
+		}
+		vchars[r] = gid
+	}
+	return vchars
+}
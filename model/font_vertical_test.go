@@ -0,0 +1,88 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+)
+
+func TestVerticalFont(t *testing.T) {
+	font, err := NewCompositePdfFontFromTTFFile(shapingTestFont)
+	require.NoError(t, err)
+	require.False(t, font.IsVertical())
+
+	vfont, err := font.VerticalFont()
+	require.NoError(t, err)
+	require.True(t, vfont.IsVertical())
+	dict, ok := core.GetDict(vfont.ToPdfObject())
+	require.True(t, ok)
+	assert.Equal(t, "Identity-V", dict.Get("Encoding").String())
+
+	// The vertical variant is created once.
+	vfont2, err := font.VerticalFont()
+	require.NoError(t, err)
+	assert.True(t, vfont == vfont2)
+	vfont3, err := vfont.VerticalFont()
+	require.NoError(t, err)
+	assert.True(t, vfont == vfont3)
+
+	// FreeSans has no vertical metrics: the glyphs have the default metrics.
+	code, ok := vfont.Encoder().RuneToCharcode('A')
+	require.True(t, ok)
+	vm, ok := vfont.GetVerticalMetrics(code)
+	require.True(t, ok)
+	metrics, ok := vfont.GetCharMetrics(code)
+	require.True(t, ok)
+	assert.Equal(t, -1000.0, vm.W1y)
+	assert.Equal(t, metrics.Wx/2, vm.Vx)
+
+	// The writing mode is kept when the font is written and loaded.
+	loaded, _ := writeFontTestPDF(t, vfont, "ABC")
+	assert.True(t, loaded.IsVertical())
+	vm2, ok := loaded.GetVerticalMetrics(code)
+	require.True(t, ok)
+	assert.Equal(t, vm, vm2)
+
+	// Simple fonts have no vertical variants.
+	simple, err := NewPdfFontFromTTFFile(shapingTestFont)
+	require.NoError(t, err)
+	_, err = simple.VerticalFont()
+	assert.Equal(t, ErrFontNotSupported, err)
+	_, ok = simple.GetVerticalMetrics(code)
+	assert.False(t, ok)
+}
+
+func TestParseCIDFontVerticalMetrics(t *testing.T) {
+	dw2 := core.MakeArray(core.MakeInteger(900), core.MakeInteger(-1100))
+	w2 := core.MakeArray(
+		core.MakeInteger(10), core.MakeArray(
+			core.MakeInteger(-500), core.MakeInteger(250), core.MakeInteger(880),
+			core.MakeInteger(-600), core.MakeInteger(300), core.MakeInteger(880)),
+		core.MakeInteger(20), core.MakeInteger(22),
+		core.MakeInteger(-800), core.MakeInteger(400), core.MakeInteger(700),
+	)
+	vertical, err := parseCIDFontVerticalMetrics(dw2, w2)
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, vertical.vy)
+	assert.Equal(t, -1100.0, vertical.w1y)
+	assert.Len(t, vertical.metrics, 5)
+	assert.Equal(t, VerticalMetrics{W1y: -500, Vx: 250, Vy: 880}, vertical.metrics[10])
+	assert.Equal(t, VerticalMetrics{W1y: -600, Vx: 300, Vy: 880}, vertical.metrics[11])
+	for code := textencoding.CharCode(20); code <= 22; code++ {
+		assert.Equal(t, VerticalMetrics{W1y: -800, Vx: 400, Vy: 700}, vertical.metrics[code])
+	}
+
+	// Default metrics.
+	vertical, err = parseCIDFontVerticalMetrics(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 880.0, vertical.vy)
+	assert.Equal(t, -1000.0, vertical.w1y)
+
+	// Truncated range.
+	_, err = parseCIDFontVerticalMetrics(nil, core.MakeArray(core.MakeInteger(1), core.MakeInteger(2)))
+	assert.Error(t, err)
+}
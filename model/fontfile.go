@@ -9,22 +9,24 @@
   *
   * 9.9 Embedded Font Programs (page 289)
   *
-  * TODO: Add Type1C support
+  * CFF font programs (FontFile3 streams of subtype Type1C, CIDFontType0C or OpenType) are parsed
+  * with the fonts package.
 */
 
 package model
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
-	"unicode"
 
 	"github.com/moolekkari/unipdf/common"
 	"github.com/moolekkari/unipdf/core"
 	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
 )
 
 // fontFile represents a font file.
@@ -34,6 +36,7 @@ type fontFile struct {
 	name    string
 	subtype string
 	encoder textencoding.SimpleEncoder
+	glyphs  *fonts.Type1Font // The glyph outlines of the font program.
 }
 
 // String returns a human readable description of `fontfile`.
@@ -65,12 +68,17 @@ func newFontFileFromPdfObject(obj core.PdfObject) (*fontFile, error) {
 	}
 
 	subtype, ok := core.GetNameVal(d.Get("Subtype"))
-	if !ok {
+	if ok {
 		fontfile.subtype = subtype
 		if subtype == "Type1C" {
-			// TODO: Add Type1C support
-			common.Log.Debug("Type1C fonts are currently not supported")
-			return nil, ErrType1CFontNotSupported
+			// Type1C font programs belong in FontFile3 streams, but some files have them in
+			// FontFile streams.
+			cff, err := fonts.ParseCFF(data)
+			if err != nil {
+				common.Log.Debug("ERROR: Unable to parse Type1C font program: %v", err)
+				return fontfile, nil
+			}
+			return newFontFileFromCFF(cff, subtype), nil
 		}
 	}
 
@@ -101,6 +109,59 @@ func newFontFileFromPdfObject(obj core.PdfObject) (*fontFile, error) {
 	return fontfile, nil
 }
 
+// newFontFileFromCFF returns a fontFile with the name and the built-in encoding of the CFF font
+// program `cff`, embedded in a font file of subtype `subtype`.
+func newFontFileFromCFF(cff *fonts.CFFFont, subtype string) *fontFile {
+	fontfile := &fontFile{name: cff.Name, subtype: subtype}
+	if len(cff.Encoding) > 0 {
+		encoder, err := textencoding.NewCustomSimpleTextEncoder(cff.Encoding, nil)
+		if err != nil {
+			common.Log.Debug("ERROR: CFF font %q has no usable encoding: %v", cff.Name, err)
+			return fontfile
+		}
+		fontfile.encoder = encoder
+	}
+	return fontfile
+}
+
+// loadFontFile3 loads the font program in the FontFile3 stream of `desc`. This is a CFF font
+// program, either bare (subtypes Type1C and CIDFontType0C) or in an OpenType font program (subtype
+// OpenType). OpenType font programs with TrueType outlines are loaded as FontFile2 font programs.
+func (desc *PdfFontDescriptor) loadFontFile3() error {
+	streamObj, ok := core.GetStream(desc.FontFile3)
+	if !ok {
+		common.Log.Debug("ERROR: FontFile3 must be a stream (%T)", desc.FontFile3)
+		return core.ErrTypeError
+	}
+	data, err := core.DecodeStream(streamObj)
+	if err != nil {
+		return err
+	}
+
+	subtype, _ := core.GetNameVal(streamObj.Get("Subtype"))
+	if subtype == "OpenType" && !bytes.HasPrefix(data, []byte("OTTO")) {
+		ttf, err := fonts.TtfParse(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if desc.fontFile2 == nil {
+			desc.fontFile2 = &ttf
+		}
+		return nil
+	}
+
+	cff, err := fonts.ParseCFF(data)
+	if err != nil {
+		return err
+	}
+	common.Log.Trace("fontFile3=%s", cff)
+	desc.fontFile3 = cff
+	if desc.fontFile == nil {
+		desc.fontFile = newFontFileFromCFF(cff, subtype)
+	}
+	return nil
+}
+
 // loadFromSegments loads a Type1Font object from two header-less .pfb segments.
 // Based on pdfbox
 func (fontfile *fontFile) loadFromSegments(segment1, segment2 []byte) error {
@@ -113,7 +174,14 @@ func (fontfile *fontFile) loadFromSegments(segment1, segment2 []byte) error {
 	if len(segment2) == 0 {
 		return nil
 	}
-	common.Log.Trace("fontfile=%s", fontfile)
+	// The font can be used without the glyph outlines, so a font program whose glyphs cannot be
+	// loaded is not an error.
+	glyphs, err := fonts.ParseType1(segment1, segment2)
+	if err != nil {
+		common.Log.Debug("ERROR: Unable to load the glyphs of Type 1 font %q: %v", fontfile.name, err)
+		return nil
+	}
+	fontfile.glyphs = glyphs
 	return nil
 }
 
@@ -238,36 +306,3 @@ func getEncodings(data string) (map[textencoding.CharCode]textencoding.GlyphName
 	common.Log.Trace("getEncodings: keyValues=%#v", keyValues)
 	return keyValues, nil
 }
-
-// decodeEexec returns the decoding of the eexec bytes `data`
-func decodeEexec(data []byte) []byte {
-	const c1 = 52845
-	const c2 = 22719
-
-	seed := 55665 // eexec key
-	// Run the seed through the encoder 4 times
-	for _, b := range data[:4] {
-		seed = (int(b)+seed)*c1 + c2
-	}
-	decoded := make([]byte, len(data)-4)
-	for i, b := range data[4:] {
-		decoded[i] = byte(int(b) ^ seed>>8)
-		seed = (int(b)+seed)*c1 + c2
-	}
-	return decoded
-}
-
-// isBinary returns true if `data` is binary. See Adobe Type 1 Font Format specification
-// 7.2 eexec encryption
-func isBinary(data []byte) bool {
-	if len(data) < 4 {
-		return true
-	}
-	for b := range data[:4] {
-		r := rune(b)
-		if !unicode.Is(unicode.ASCII_Hex_Digit, r) && !unicode.IsSpace(r) {
-			return true
-		}
-	}
-	return false
-}
@@ -36,6 +36,19 @@ type Image struct {
 	decode []float64 // [Dmin Dmax ... values for each color component]
 }
 
+// HasAlpha returns true if the image has an alpha channel.
+func (img *Image) HasAlpha() bool {
+	return img.hasAlpha
+}
+
+// SetAlpha sets the alpha channel of the image to `alpha`. The alpha data
+// contains one sample per pixel, stored with the same bits per component as
+// the image data. Passing nil removes the alpha channel.
+func (img *Image) SetAlpha(alpha []byte) {
+	img.alphaData = alpha
+	img.hasAlpha = alpha != nil
+}
+
 // AlphaMapFunc represents a alpha mapping function: byte -> byte. Can be used for
 // thresholding the alpha channel, i.e. setting all alpha values below threshold to transparent.
 type AlphaMapFunc func(alpha byte) byte
@@ -332,7 +345,7 @@ func (img *Image) Resample(targetBitsPerComponent int64) {
 	var data []byte
 	for i := int64(0); i < img.Height; i++ {
 		ind1 := i * img.Width * int64(img.ColorComponents)
-		ind2 := (i+1)*img.Width*int64(img.ColorComponents) - 1
+		ind2 := (i + 1) * img.Width * int64(img.ColorComponents)
 
 		resampled := sampling.ResampleUint32(samples[ind1:ind2], int(targetBitsPerComponent), 8)
 		for _, val := range resampled {
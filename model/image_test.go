@@ -65,6 +65,23 @@ func TestImageResampling(t *testing.T) {
 	}
 }
 
+// TestImageResamplingRows checks that the last sample of each row is resampled.
+func TestImageResamplingRows(t *testing.T) {
+	// Rows of 3x8bit: 00000000 00000000 11111111
+	// Downsample to 1bit: 001, padded to a byte per row.
+	// -> 00100000 = 32 decimal for each row.
+	img := Image{
+		Width:            3,
+		Height:           2,
+		BitsPerComponent: 8,
+		ColorComponents:  1,
+		Data:             []byte{0, 0, 255, 0, 0, 255},
+	}
+	img.Resample(1)
+	require.Equal(t, []byte{32, 32}, img.Data)
+	require.Equal(t, int64(1), img.BitsPerComponent)
+}
+
 func TestImageColorAt(t *testing.T) {
 	img := &Image{}
 	img.Data = []byte{
@@ -0,0 +1,543 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// PdfIncrementalWriter saves the changes made to a document as an incremental update, i.e. a new
+// revision appended to the original file. Unlike PdfAppender, which supports a fixed set of
+// operations, any object of the document can be changed: the models and the objects loaded by the
+// reader are changed directly, e.g. the annotations, the resources and the other attributes of the
+// pages, the outlines, the form fields and the entries of the catalog.
+// Only the objects which changed and the new objects are written. The original bytes are kept
+// unchanged, so that the existing signatures remain valid. The objects of other documents used by
+// the document are written as copies, the other documents are not changed.
+//
+// Example:
+//
+//	reader, err := model.NewPdfReader(f)
+//	w, err := model.NewPdfIncrementalWriter(reader)
+//	page, err := reader.GetPage(1)
+//	page.AddAnnotation(annotation)
+//	w.GetCatalog().Set("PageMode", core.MakeName("UseOutlines"))
+//	err = w.Write(out)
+type PdfIncrementalWriter struct {
+	reader *PdfReader
+
+	// Digests of the objects of the original document, for detecting the changes.
+	digests map[core.PdfObject][md5.Size]byte
+
+	outlineTree *PdfOutlineTreeNode
+	info        *PdfDocumentInfo
+	xmp         *XMPMetadata
+	written     bool
+}
+
+// NewPdfIncrementalWriter returns a new PdfIncrementalWriter for saving the changes made to the
+// document read by `reader`. The current state of the document is the reference for the changes:
+// the writer must be created before changing the document. The objects of the document are loaded
+// into memory. Encrypted documents and readers with a limited cache size are not supported.
+func NewPdfIncrementalWriter(reader *PdfReader) (*PdfIncrementalWriter, error) {
+	if reader.parser.GetCrypter() != nil {
+		return nil, errors.New("incremental updates of encrypted documents are not supported")
+	}
+	w := &PdfIncrementalWriter{
+		reader:      reader,
+		digests:     map[core.PdfObject][md5.Size]byte{},
+		outlineTree: reader.outlineTree,
+	}
+	// The models are converted to objects, so that only the changes made afterwards are detected.
+	w.updateObjects()
+	trailer, err := reader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := documentObjects(trailer)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		w.digests[obj] = objectDigest(obj)
+	}
+	return w, nil
+}
+
+// GetCatalog returns the catalog dictionary of the document, whose entries can be changed.
+func (w *PdfIncrementalWriter) GetCatalog() *core.PdfObjectDictionary {
+	return w.reader.catalog
+}
+
+// SetOutlineTree sets the outlines of the document to `outlineTree`. The outlines read from the
+// document, returned by PdfReader.GetOutlineTree, can also be changed directly.
+func (w *PdfIncrementalWriter) SetOutlineTree(outlineTree *PdfOutlineTreeNode) {
+	w.outlineTree = outlineTree
+}
+
+// SetDocumentInfo sets the document information dictionary of the document to `info`. The
+// metadata stream of the document, if any, is updated to be consistent with `info`.
+func (w *PdfIncrementalWriter) SetDocumentInfo(info *PdfDocumentInfo) {
+	w.info = info
+}
+
+// SetXMPMetadata sets the XMP metadata of the document, written as the metadata stream of the
+// document catalog.
+func (w *PdfIncrementalWriter) SetXMPMetadata(metadata *XMPMetadata) {
+	w.xmp = metadata
+}
+
+// updateObjects converts the models of the document to objects.
+func (w *PdfIncrementalWriter) updateObjects() {
+	r := w.reader
+	for _, page := range r.PageList {
+		page.ToPdfObject()
+	}
+	if w.outlineTree != nil {
+		r.catalog.Set("Outlines", w.outlineTree.ToPdfObject())
+	}
+	if r.AcroForm != nil {
+		r.catalog.Set("AcroForm", r.AcroForm.ToPdfObject())
+	}
+}
+
+// updateMetadata sets the document information dictionary and the metadata stream of the
+// document. Existing objects are kept with their object numbers.
+func (w *PdfIncrementalWriter) updateMetadata(trailer *core.PdfObjectDictionary) error {
+	r := w.reader
+	xmp := w.xmp
+	if w.info != nil {
+		infoDict := w.info.ToPdfObject()
+		if infoObj, ok := core.GetIndirect(core.ResolveReference(trailer.Get("Info"))); ok {
+			infoObj.PdfObject = infoDict
+		} else {
+			trailer.Set("Info", core.MakeIndirectObject(infoDict))
+		}
+		if xmp == nil {
+			// Keep the metadata stream of the document consistent with the document information.
+			var err error
+			if xmp, err = r.GetXMPMetadata(); err != nil {
+				common.Log.Debug("ERROR: unable to read XMP metadata: %v", err)
+				xmp = nil
+			}
+		}
+		if xmp != nil {
+			xmp.SetDocumentInfo(w.info)
+		}
+	}
+	if xmp == nil {
+		return nil
+	}
+	metadata, ok := xmp.ToPdfObject().(*core.PdfObjectStream)
+	if !ok {
+		return errors.New("invalid XMP metadata stream")
+	}
+	if stream, ok := core.GetStream(r.catalog.Get("Metadata")); ok && stream.GetParser() == r.parser {
+		stream.PdfObjectDictionary = metadata.PdfObjectDictionary
+		stream.Stream = metadata.Stream
+		return nil
+	}
+	r.catalog.Set("Metadata", metadata)
+	return nil
+}
+
+// documentObjects returns the indirect and stream objects used by the document with the trailer
+// `trailer`, in the order they are reached from the trailer.
+func documentObjects(trailer *core.PdfObjectDictionary) ([]core.PdfObject, error) {
+	var objects []core.PdfObject
+	visited := map[core.PdfObject]struct{}{}
+	var collect func(obj core.PdfObject) error
+	collect = func(obj core.PdfObject) error {
+		if ref, ok := obj.(*core.PdfObjectReference); ok {
+			if ref.GetParser() == nil {
+				return nil
+			}
+			resolved, err := ref.GetParser().LookupByReference(*ref)
+			if err != nil {
+				return err
+			}
+			obj = resolved
+		}
+		if _, ok := visited[obj]; ok {
+			return nil
+		}
+		visited[obj] = struct{}{}
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			objects = append(objects, t)
+			return collect(t.PdfObject)
+		case *core.PdfObjectStream:
+			if err := t.LoadData(); err != nil {
+				return err
+			}
+			objects = append(objects, t)
+			return collect(t.PdfObjectDictionary)
+		case *core.PdfObjectDictionary:
+			for _, key := range t.Keys() {
+				if err := collect(t.Get(key)); err != nil {
+					return err
+				}
+			}
+		case *core.PdfObjectArray:
+			for _, elem := range t.Elements() {
+				if err := collect(elem); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, key := range []core.PdfObjectName{"Root", "Info"} {
+		if err := collect(trailer.Get(key)); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// objectDigest returns the digest of the serialization of `obj`.
+func objectDigest(obj core.PdfObject) [md5.Size]byte {
+	h := md5.New()
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		io.WriteString(h, t.PdfObject.WriteString())
+	case *core.PdfObjectStream:
+		io.WriteString(h, t.PdfObjectDictionary.WriteString())
+		h.Write(t.Stream)
+	}
+	var digest [md5.Size]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// Write writes the original document followed by the incremental update with the changes to
+// `out`. The original document is written unchanged if nothing changed.
+// It can only be called once and further invocations will result in an error.
+func (w *PdfIncrementalWriter) Write(out io.Writer) error {
+	if w.written {
+		return errors.New("incremental writer write can only be invoked once")
+	}
+	w.written = true
+	r := w.reader
+	parser := r.parser
+
+	origTrailer, err := r.GetTrailer()
+	if err != nil {
+		return err
+	}
+	trailer := core.MakeDict()
+	trailer.Set("Root", origTrailer.Get("Root"))
+	trailer.SetIfNotNil("Info", origTrailer.Get("Info"))
+
+	w.updateObjects()
+	if err := w.updateMetadata(trailer); err != nil {
+		return err
+	}
+	if err := copyForeignObjects(trailer, parser); err != nil {
+		return err
+	}
+
+	// The objects of the document which changed keep their numbers, the new objects, including
+	// the objects from other documents, are numbered after the objects of the document.
+	objects, err := documentObjects(trailer)
+	if err != nil {
+		return err
+	}
+	size := 0
+	if s, ok := core.GetIntVal(origTrailer.Get("Size")); ok {
+		size = s
+	}
+	for _, objNum := range r.GetObjectNums() {
+		if objNum >= size {
+			size = objNum + 1
+		}
+	}
+	var changed []core.PdfObject
+	for _, obj := range objects {
+		if isDocumentObject(obj, parser) {
+			// The objects which were not used when the writer was created are unchanged.
+			if digest, ok := w.digests[obj]; !ok || digest == objectDigest(obj) {
+				continue
+			}
+		} else {
+			setObjectNumber(obj, int64(size))
+			size++
+		}
+		changed = append(changed, obj)
+	}
+
+	// Write the original document.
+	origSize, err := r.rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.rs.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(out, r.rs, origSize); err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	pw := NewPdfWriter()
+	pw.writer = bufio.NewWriter(out)
+	pw.writePos = origSize
+	pw.crossReferenceMap = map[int]crossReference{}
+	pw.writeString("\n")
+	for _, obj := range changed {
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			stream.Set("Length", core.MakeInteger(int64(len(stream.Stream))))
+		}
+		pw.writeObject(int(objectNumber(obj)), obj)
+	}
+
+	trailer.Set("ID", incrementalID(origTrailer.Get("ID"), fmt.Sprintf("%d %d", origSize, len(changed))))
+	trailer.Set("Prev", core.MakeInteger(parser.GetXrefOffset()))
+	xrefType := parser.GetXrefType()
+	if xrefType != nil && *xrefType == core.XrefTypeObjectStream {
+		err = writeIncrementalXrefStream(&pw, trailer, size)
+	} else {
+		err = writeIncrementalXrefTable(&pw, trailer, size)
+	}
+	if err != nil {
+		return err
+	}
+	return pw.writer.Flush()
+}
+
+// incrementalID returns the file identifier of an incremental update of a document with the file
+// identifier `id`: the first element is kept, the second one is set to a new identifier, made
+// from the current time and `seed`. Both are set to the new identifier if `id` is not valid.
+func incrementalID(id core.PdfObject, seed string) *core.PdfObjectArray {
+	digest := md5.Sum([]byte(fmt.Sprintf("%s %s", time.Now().String(), seed)))
+	newID := core.MakeHexString(string(digest[:]))
+	if arr, ok := core.GetArray(id); ok && arr.Len() == 2 {
+		if first, ok := core.GetString(arr.Get(0)); ok {
+			return core.MakeArray(core.MakeHexString(first.Str()), newID)
+		}
+	}
+	return core.MakeArray(newID, newID)
+}
+
+// copyForeignObjects replaces the indirect and stream objects of other documents used by the
+// document read by `parser`, with the trailer `trailer`, with copies. The copies are numbered in
+// the update, whereas the objects of the other documents keep their numbers.
+func copyForeignObjects(trailer *core.PdfObjectDictionary, parser *core.PdfParser) error {
+	isForeign := func(obj core.PdfObject) bool {
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			return t.GetParser() != nil && t.GetParser() != parser
+		case *core.PdfObjectStream:
+			return t.GetParser() != nil && t.GetParser() != parser
+		}
+		return false
+	}
+	copies := map[core.PdfObject]core.PdfObject{}
+	visited := map[core.PdfObject]struct{}{}
+
+	// replace returns the object to use instead of `obj`: a copy if `obj` belongs to another
+	// document, otherwise `obj`, whose entries are replaced. The direct objects of the other
+	// documents are copied with `foreign` set.
+	var replace func(obj core.PdfObject, foreign bool) (core.PdfObject, error)
+	replace = func(obj core.PdfObject, foreign bool) (core.PdfObject, error) {
+		if ref, ok := obj.(*core.PdfObjectReference); ok && ref.GetParser() != nil {
+			resolved, err := ref.GetParser().LookupByReference(*ref)
+			if err != nil {
+				return nil, err
+			}
+			if !isForeign(resolved) {
+				_, err := replace(resolved, false)
+				return obj, err
+			}
+			obj = resolved
+		}
+		if c, ok := copies[obj]; ok {
+			return c, nil
+		}
+
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			if isForeign(t) {
+				c := &core.PdfIndirectObject{PdfObjectReference: t.PdfObjectReference}
+				copies[t] = c
+				content, err := replace(t.PdfObject, true)
+				c.PdfObject = content
+				return c, err
+			}
+			if _, ok := visited[t]; !ok {
+				visited[t] = struct{}{}
+				content, err := replace(t.PdfObject, false)
+				if err != nil {
+					return nil, err
+				}
+				t.PdfObject = content
+			}
+		case *core.PdfObjectStream:
+			if isForeign(t) {
+				if err := t.LoadData(); err != nil {
+					return nil, err
+				}
+				c := &core.PdfObjectStream{PdfObjectReference: t.PdfObjectReference, Stream: t.Stream}
+				copies[t] = c
+				dict, err := replace(t.PdfObjectDictionary, true)
+				if err != nil {
+					return nil, err
+				}
+				c.PdfObjectDictionary = dict.(*core.PdfObjectDictionary)
+				return c, nil
+			}
+			if _, ok := visited[t]; !ok {
+				visited[t] = struct{}{}
+				if _, err := replace(t.PdfObjectDictionary, false); err != nil {
+					return nil, err
+				}
+			}
+		case *core.PdfObjectDictionary:
+			dict := t
+			if foreign {
+				dict = core.MakeDict()
+			} else if _, ok := visited[t]; ok {
+				return t, nil
+			}
+			visited[t] = struct{}{}
+			for _, key := range t.Keys() {
+				val, err := replace(t.Get(key), foreign)
+				if err != nil {
+					return nil, err
+				}
+				dict.Set(key, val)
+			}
+			return dict, nil
+		case *core.PdfObjectArray:
+			arr := t
+			if foreign {
+				arr = core.MakeArray()
+			} else if _, ok := visited[t]; ok {
+				return t, nil
+			}
+			visited[t] = struct{}{}
+			for i, elem := range t.Elements() {
+				val, err := replace(elem, foreign)
+				if err != nil {
+					return nil, err
+				}
+				if foreign {
+					arr.Append(val)
+				} else {
+					arr.Set(i, val)
+				}
+			}
+			return arr, nil
+		}
+		return obj, nil
+	}
+
+	for _, key := range []core.PdfObjectName{"Root", "Info"} {
+		obj := trailer.Get(key)
+		if obj == nil {
+			continue
+		}
+		val, err := replace(obj, false)
+		if err != nil {
+			return err
+		}
+		trailer.Set(key, val)
+	}
+	return nil
+}
+
+// isDocumentObject returns true if `obj` was read by `parser`.
+func isDocumentObject(obj core.PdfObject, parser *core.PdfParser) bool {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		return t.GetParser() == parser && t.ObjectNumber > 0
+	case *core.PdfObjectStream:
+		return t.GetParser() == parser && t.ObjectNumber > 0
+	}
+	return false
+}
+
+// incrementalXrefSections returns the sorted numbers of the objects written by `w`, grouped in
+// subsections of consecutive numbers.
+func incrementalXrefSections(w *PdfWriter) [][]int {
+	var nums []int
+	for num := range w.crossReferenceMap {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	var sections [][]int
+	for i, num := range nums {
+		if i == 0 || num != nums[i-1]+1 {
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], num)
+	}
+	return sections
+}
+
+// writeIncrementalXrefTable writes the cross-reference table and the trailer `trailer` of an
+// incremental update with the objects written by `w`, for a document with `size` objects.
+func writeIncrementalXrefTable(w *PdfWriter, trailer *core.PdfObjectDictionary, size int) error {
+	xrefOffset := w.writePos
+	w.writeString("xref\r\n")
+	for _, section := range incrementalXrefSections(w) {
+		w.writeString(fmt.Sprintf("%d %d\r\n", section[0], len(section)))
+		for _, num := range section {
+			ref := w.crossReferenceMap[num]
+			w.writeString(fmt.Sprintf("%.10d %.5d n\r\n", ref.Offset, ref.Generation))
+		}
+	}
+	trailer.Set("Size", core.MakeInteger(int64(size)))
+	w.writeString("trailer\n")
+	w.writeString(trailer.WriteString())
+	w.writeString("\n")
+	w.writeString(fmt.Sprintf("startxref\n%d\n", xrefOffset))
+	w.writeString("%%EOF\n")
+	return nil
+}
+
+// writeIncrementalXrefStream writes the cross-reference stream of an incremental update with the
+// objects written by `w` and the entries of `trailer`, for a document with `size` objects. The
+// cross-reference stream is numbered `size`.
+func writeIncrementalXrefStream(w *PdfWriter, trailer *core.PdfObjectDictionary, size int) error {
+	xrefOffset := w.writePos
+	w.crossReferenceMap[size] = crossReference{Type: 1, ObjectNumber: size, Offset: xrefOffset}
+
+	var data bytes.Buffer
+	index := core.MakeArray()
+	for _, section := range incrementalXrefSections(w) {
+		index.Append(core.MakeInteger(int64(section[0])), core.MakeInteger(int64(len(section))))
+		for _, num := range section {
+			ref := w.crossReferenceMap[num]
+			binary.Write(&data, binary.BigEndian, byte(1))
+			binary.Write(&data, binary.BigEndian, uint32(ref.Offset))
+			binary.Write(&data, binary.BigEndian, uint16(ref.Generation))
+		}
+	}
+	stream, err := core.MakeStream(data.Bytes(), core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	for _, key := range trailer.Keys() {
+		stream.Set(key, trailer.Get(key))
+	}
+	stream.Set("Type", core.MakeName("XRef"))
+	stream.Set("W", core.MakeArray(core.MakeInteger(1), core.MakeInteger(4), core.MakeInteger(2)))
+	stream.Set("Index", index)
+	stream.Set("Size", core.MakeInteger(int64(size+1)))
+	w.writeObject(size, stream)
+	w.writeString(fmt.Sprintf("startxref\n%d\n", xrefOffset))
+	w.writeString("%%EOF\n")
+	return nil
+}
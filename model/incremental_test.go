@@ -0,0 +1,236 @@
+package model_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/pdftest"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// incrementalUpdate reads the document `data`, applies `edit` and returns the document with the
+// incremental update.
+func incrementalUpdate(t *testing.T, data []byte, edit func(*model.PdfReader, *model.PdfIncrementalWriter)) []byte {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	w, err := model.NewPdfIncrementalWriter(reader)
+	require.NoError(t, err)
+	edit(reader, w)
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), data))
+	return buf.Bytes()
+}
+
+// addTestAnnotation adds a square annotation to the page `pageNum` of the document read by `reader`.
+func addTestAnnotation(t *testing.T, reader *model.PdfReader, pageNum int) {
+	page, err := reader.GetPage(pageNum)
+	require.NoError(t, err)
+	annot := model.NewPdfAnnotationSquare()
+	annot.Rect = core.MakeArrayFromFloats([]float64{10, 10, 50, 50})
+	page.AddAnnotation(annot.PdfAnnotation)
+}
+
+func TestIncrementalWriterNoop(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+
+	out := incrementalUpdate(t, data, func(*model.PdfReader, *model.PdfIncrementalWriter) {})
+	require.Equal(t, data, out)
+}
+
+func TestIncrementalWriterSigned(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfSignedPDFDocument)
+	require.NoError(t, err)
+
+	out := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		addTestAnnotation(t, reader, 1)
+		info, err := reader.GetDocumentInfo()
+		require.NoError(t, err)
+		info.Title = "Incremental"
+		w.SetDocumentInfo(info)
+		w.GetCatalog().Set("PageMode", core.MakeName("UseThumbs"))
+	})
+
+	outPath := tempFile("incremental_signed.pdf")
+	require.NoError(t, ioutil.WriteFile(outPath, out, 0644))
+	validateFile(t, outPath)
+
+	reader, err := model.NewPdfReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.NotEmpty(t, annots)
+	_, ok := annots[len(annots)-1].GetContext().(*model.PdfAnnotationSquare)
+	require.True(t, ok)
+
+	info, err := reader.GetDocumentInfo()
+	require.NoError(t, err)
+	require.Equal(t, "Incremental", info.Title)
+
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+	pageMode, ok := core.GetName(catalog.Get("PageMode"))
+	require.True(t, ok)
+	require.Equal(t, "UseThumbs", pageMode.String())
+}
+
+func TestIncrementalWriterRevisions(t *testing.T) {
+	for _, path := range []string{testPdfFile1, testPdfSignedPDFDocument} {
+		t.Run(path, func(t *testing.T) {
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			out := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+				addTestAnnotation(t, reader, 1)
+			})
+			out = incrementalUpdate(t, out, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+				addTestAnnotation(t, reader, 1)
+				w.GetCatalog().Set("Lang", core.MakeString("en"))
+			})
+
+			reader, err := model.NewPdfReader(bytes.NewReader(out))
+			require.NoError(t, err)
+			origReader, err := model.NewPdfReader(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			page, err := reader.GetPage(1)
+			require.NoError(t, err)
+			annots, err := page.GetAnnotations()
+			require.NoError(t, err)
+			origPage, err := origReader.GetPage(1)
+			require.NoError(t, err)
+			origAnnots, err := origPage.GetAnnotations()
+			require.NoError(t, err)
+			require.Len(t, annots, len(origAnnots)+2)
+
+			trailer, err := reader.GetTrailer()
+			require.NoError(t, err)
+			catalog, ok := core.GetDict(trailer.Get("Root"))
+			require.True(t, ok)
+			lang, ok := core.GetString(catalog.Get("Lang"))
+			require.True(t, ok)
+			require.Equal(t, "en", lang.Str())
+		})
+	}
+}
+
+// trailerIDs returns the elements of the file identifier of the document `data`.
+func trailerIDs(t *testing.T, data []byte) (string, string) {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	id, ok := core.GetArray(trailer.Get("ID"))
+	require.True(t, ok)
+	require.Equal(t, 2, id.Len())
+	id0, ok := core.GetString(id.Get(0))
+	require.True(t, ok)
+	id1, ok := core.GetString(id.Get(1))
+	require.True(t, ok)
+	return id0.Str(), id1.Str()
+}
+
+func TestIncrementalWriterID(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfSignedPDFDocument)
+	require.NoError(t, err)
+	id0, id1 := trailerIDs(t, data)
+
+	// The permanent identifier is kept, the changing identifier is replaced.
+	out := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		addTestAnnotation(t, reader, 1)
+	})
+	newID0, newID1 := trailerIDs(t, out)
+	require.Equal(t, id0, newID0)
+	require.NotEqual(t, id1, newID1)
+
+	// Documents without identifier get one.
+	data, err = ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	out = incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		addTestAnnotation(t, reader, 1)
+	})
+	newID0, newID1 = trailerIDs(t, out)
+	require.Len(t, newID0, 16)
+	require.Equal(t, newID0, newID1)
+}
+
+func TestIncrementalWriterGeneration(t *testing.T) {
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 2 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+	)
+	// Object 3 has generation 2.
+	offset := bytes.Index(data, []byte("3 0 obj"))
+	data = bytes.Replace(data, []byte("3 0 obj"), []byte("3 2 obj"), 1)
+	data = bytes.Replace(data, []byte(fmt.Sprintf("%.10d 00000 n", offset)),
+		[]byte(fmt.Sprintf("%.10d 00002 n", offset)), 1)
+
+	out := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		addTestAnnotation(t, reader, 1)
+	})
+	update := string(out[len(data):])
+	require.Contains(t, update, "\n3 2 obj\n")
+	offset = len(data) + strings.Index(update, "3 2 obj")
+	require.Contains(t, update, fmt.Sprintf("%.10d 00002 n", offset))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+}
+
+func TestIncrementalWriterForeignObjects(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	otherData, err := ioutil.ReadFile(testPdfSignedPDFDocument)
+	require.NoError(t, err)
+	other, err := model.NewPdfReader(bytes.NewReader(otherData))
+	require.NoError(t, err)
+	otherPage, err := other.GetPage(1)
+	require.NoError(t, err)
+	contents := otherPage.Contents
+	if arr, ok := core.GetArray(contents); ok {
+		contents = arr.Get(0)
+	}
+	stream, ok := core.GetStream(contents)
+	require.True(t, ok)
+	objNum := stream.ObjectNumber
+	streamData, err := core.DecodeStream(stream)
+	require.NoError(t, err)
+
+	// The objects of the other document are copied, they keep their numbers.
+	out := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		w.GetCatalog().Set("Foreign", core.MakeArray(stream))
+	})
+	require.Equal(t, objNum, stream.ObjectNumber)
+
+	reader, err := model.NewPdfReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(trailer.Get("Root"))
+	require.True(t, ok)
+	arr, ok := core.GetArray(catalog.Get("Foreign"))
+	require.True(t, ok)
+	copied, ok := core.GetStream(arr.Get(0))
+	require.True(t, ok)
+	copiedData, err := core.DecodeStream(copied)
+	require.NoError(t, err)
+	require.Equal(t, streamData, copiedData)
+}
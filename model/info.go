@@ -0,0 +1,246 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"unicode"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// Trapped values of the document information dictionary.
+const (
+	TrappedTrue    = "True"
+	TrappedFalse   = "False"
+	TrappedUnknown = "Unknown"
+)
+
+// PdfDocumentInfo represents the document information dictionary of a document (section 14.3.3
+// "Document Information Dictionary" PDF32000_2008). Empty strings and zero times are not set.
+// Besides the standard entries, the dictionary can contain custom entries with text string values.
+type PdfDocumentInfo struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string    // The application which created the original document.
+	Producer     string    // The application which converted the document to PDF.
+	CreationDate time.Time // The date and time the document was created.
+	ModDate      time.Time // The date and time the document was most recently modified.
+	Trapped      string    // TrappedTrue, TrappedFalse or TrappedUnknown.
+
+	custom map[string]string // Custom entries.
+}
+
+// documentInfoKeys are the keys of the standard entries of the document information dictionary.
+var documentInfoKeys = map[string]bool{
+	"Title": true, "Author": true, "Subject": true, "Keywords": true, "Creator": true,
+	"Producer": true, "CreationDate": true, "ModDate": true, "Trapped": true,
+}
+
+// newPdfDocumentInfoFromDict returns the document information of the information dictionary `dict`.
+// Invalid entries are skipped.
+func newPdfDocumentInfoFromDict(dict *core.PdfObjectDictionary) *PdfDocumentInfo {
+	info := &PdfDocumentInfo{}
+	for _, key := range dict.Keys() {
+		obj := dict.Get(key)
+		switch key {
+		case "CreationDate", "ModDate":
+			str, ok := core.GetString(obj)
+			if !ok {
+				common.Log.Debug("ERROR: invalid %s %T", key, obj)
+				continue
+			}
+			date, err := NewPdfDate(str.Str())
+			if err != nil {
+				common.Log.Debug("ERROR: invalid %s: %v", key, err)
+				continue
+			}
+			if key == "CreationDate" {
+				info.CreationDate = date.ToGoTime()
+			} else {
+				info.ModDate = date.ToGoTime()
+			}
+		case "Trapped":
+			// Some writers use booleans instead of names.
+			switch t := core.TraceToDirectObject(obj).(type) {
+			case *core.PdfObjectName:
+				info.Trapped = string(*t)
+			case *core.PdfObjectBool:
+				info.Trapped = TrappedFalse
+				if bool(*t) {
+					info.Trapped = TrappedTrue
+				}
+			default:
+				common.Log.Debug("ERROR: invalid Trapped %T", obj)
+			}
+		default:
+			str, ok := core.GetString(obj)
+			if !ok {
+				common.Log.Debug("ERROR: invalid document information entry %s %T", key, obj)
+				continue
+			}
+			info.set(string(key), str.Decoded())
+		}
+	}
+	return info
+}
+
+// set sets the entry `key` of `info` to `value`.
+func (info *PdfDocumentInfo) set(key, value string) {
+	switch key {
+	case "Title":
+		info.Title = value
+	case "Author":
+		info.Author = value
+	case "Subject":
+		info.Subject = value
+	case "Keywords":
+		info.Keywords = value
+	case "Creator":
+		info.Creator = value
+	case "Producer":
+		info.Producer = value
+	default:
+		if info.custom == nil {
+			info.custom = map[string]string{}
+		}
+		info.custom[key] = value
+	}
+}
+
+// Custom returns the value of the custom entry `key`.
+func (info *PdfDocumentInfo) Custom(key string) (string, bool) {
+	value, ok := info.custom[key]
+	return value, ok
+}
+
+// CustomKeys returns the sorted keys of the custom entries.
+func (info *PdfDocumentInfo) CustomKeys() []string {
+	keys := make([]string, 0, len(info.custom))
+	for key := range info.custom {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetCustom sets the custom entry `key` to `value`. An empty value removes the entry.
+// Returns an error if `key` is the key of a standard entry.
+func (info *PdfDocumentInfo) SetCustom(key, value string) error {
+	if key == "" {
+		return errors.New("empty key")
+	}
+	if documentInfoKeys[key] {
+		return fmt.Errorf("%s is a standard document information entry", key)
+	}
+	if value == "" {
+		delete(info.custom, key)
+		return nil
+	}
+	info.set(key, value)
+	return nil
+}
+
+// Copy returns a copy of `info`.
+func (info *PdfDocumentInfo) Copy() *PdfDocumentInfo {
+	c := *info
+	c.custom = nil
+	for key, value := range info.custom {
+		c.set(key, value)
+	}
+	return &c
+}
+
+// ToPdfObject returns the document information dictionary of `info`.
+func (info *PdfDocumentInfo) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	for _, entry := range []struct {
+		key   core.PdfObjectName
+		value string
+	}{
+		{"Title", info.Title},
+		{"Author", info.Author},
+		{"Subject", info.Subject},
+		{"Keywords", info.Keywords},
+		{"Creator", info.Creator},
+		{"Producer", info.Producer},
+	} {
+		if entry.value != "" {
+			dict.Set(entry.key, makeTextString(entry.value))
+		}
+	}
+	for _, entry := range []struct {
+		key   core.PdfObjectName
+		value time.Time
+	}{
+		{"CreationDate", info.CreationDate},
+		{"ModDate", info.ModDate},
+	} {
+		if entry.value.IsZero() {
+			continue
+		}
+		if date, err := NewPdfDateFromTime(entry.value); err == nil {
+			dict.Set(entry.key, date.ToPdfObject())
+		}
+	}
+	if info.Trapped != "" {
+		dict.Set("Trapped", core.MakeName(info.Trapped))
+	}
+	for _, key := range info.CustomKeys() {
+		dict.Set(core.PdfObjectName(key), makeTextString(info.custom[key]))
+	}
+	return dict
+}
+
+// makeTextString returns a text string object for `s`, encoded with PDFDocEncoding if it is ASCII
+// text and UTF-16BE otherwise.
+func makeTextString(s string) *core.PdfObjectString {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return core.MakeEncodedString(s, true)
+		}
+	}
+	return core.MakeEncodedString(s, false)
+}
+
+// GetDocumentInfo returns the document information dictionary of the document. The document
+// information is empty if the document has no information dictionary.
+func (r *PdfReader) GetDocumentInfo() (*PdfDocumentInfo, error) {
+	trailer, err := r.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	obj := trailer.Get("Info")
+	if obj == nil {
+		return &PdfDocumentInfo{}, nil
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: invalid Info %T", obj)
+		return nil, errors.New("invalid Info")
+	}
+	return newPdfDocumentInfoFromDict(dict), nil
+}
+
+// GetXMPMetadata returns the XMP metadata of the document, read from the Metadata stream of the
+// document catalog. Returns nil if the document has no metadata stream.
+func (r *PdfReader) GetXMPMetadata() (*XMPMetadata, error) {
+	obj := r.catalog.Get("Metadata")
+	if obj == nil {
+		return nil, nil
+	}
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		common.Log.Debug("ERROR: invalid Metadata %T", obj)
+		return nil, errors.New("invalid Metadata")
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	return ParseXMPMetadata(data)
+}
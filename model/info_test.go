@@ -0,0 +1,145 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestDocument returns a single page document with the document information `info` and XMP
+// metadata `xmp`.
+func writeTestDocument(t *testing.T, info *PdfDocumentInfo, xmp *XMPMetadata) []byte {
+	w := NewPdfWriter()
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, w.AddPage(page))
+	w.SetDocumentInfo(info)
+	if xmp != nil {
+		w.SetXMPMetadata(xmp)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	return buf.Bytes()
+}
+
+// assertSameInfo checks that `info` has the same values as `expected`.
+func assertSameInfo(t *testing.T, expected, info *PdfDocumentInfo) {
+	assert.True(t, expected.CreationDate.Equal(info.CreationDate), "CreationDate %v", info.CreationDate)
+	assert.True(t, expected.ModDate.Equal(info.ModDate), "ModDate %v", info.ModDate)
+	expected, info = expected.Copy(), info.Copy()
+	expected.CreationDate, expected.ModDate = time.Time{}, time.Time{}
+	info.CreationDate, info.ModDate = time.Time{}, time.Time{}
+	assert.Equal(t, expected, info)
+}
+
+func TestDocumentInfo(t *testing.T) {
+	info := &PdfDocumentInfo{
+		Title:        "Квартальный отчёт",
+		Author:       "Jane Doe",
+		Subject:      "Finance",
+		Keywords:     "report, Q3",
+		Creator:      "Report Service",
+		Producer:     "Moole PDF Engine",
+		CreationDate: time.Date(2019, 10, 1, 12, 30, 0, 0, time.FixedZone("", 2*3600)),
+		ModDate:      time.Date(2019, 10, 2, 8, 0, 0, 0, time.UTC),
+		Trapped:      TrappedFalse,
+	}
+	require.NoError(t, info.SetCustom("Department", "Accounting & Tax"))
+	require.Error(t, info.SetCustom("Title", "Custom title"))
+
+	data := writeTestDocument(t, info, NewXMPMetadata())
+	reader, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	read, err := reader.GetDocumentInfo()
+	require.NoError(t, err)
+	assertSameInfo(t, info, read)
+	assert.Equal(t, []string{"Department"}, read.CustomKeys())
+
+	// The XMP metadata is consistent with the document information.
+	xmp, err := reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.NotNil(t, xmp)
+	assertSameInfo(t, info, xmp.DocumentInfo())
+
+	// Documents written without XMP metadata have no metadata stream.
+	reader, err = NewPdfReader(bytes.NewReader(writeTestDocument(t, &PdfDocumentInfo{Title: "Plain"}, nil)))
+	require.NoError(t, err)
+	xmp, err = reader.GetXMPMetadata()
+	require.NoError(t, err)
+	assert.Nil(t, xmp)
+	read, err = reader.GetDocumentInfo()
+	require.NoError(t, err)
+	assert.Equal(t, &PdfDocumentInfo{Title: "Plain"}, read)
+}
+
+func TestXMPMetadata(t *testing.T) {
+	packet := `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/"
+ pdfaid:part="1" pdfaid:conformance="B"/>
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"
+ xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#">
+<dc:title><rdf:Alt><rdf:li xml:lang="de">Bericht</rdf:li><rdf:li xml:lang="x-default">Report</rdf:li></rdf:Alt></dc:title>
+<dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li><rdf:li>John Doe</rdf:li></rdf:Seq></dc:creator>
+<xmp:CreateDate>2019-10-01T12:30:00+02:00</xmp:CreateDate>
+<xmp:History><rdf:Seq><rdf:li rdf:parseType="Resource"><stEvt:action>created</stEvt:action></rdf:li></rdf:Seq></xmp:History>
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+	xmp, err := ParseXMPMetadata([]byte(packet))
+	require.NoError(t, err)
+	info := xmp.DocumentInfo()
+	assert.Equal(t, "Report", info.Title)
+	assert.Equal(t, "Jane Doe; John Doe", info.Author)
+	assert.True(t, time.Date(2019, 10, 1, 10, 30, 0, 0, time.UTC).Equal(info.CreationDate))
+	part, ok := xmp.Property("http://www.aiim.org/pdfa/ns/id/", "part")
+	assert.True(t, ok)
+	assert.Equal(t, "1", part)
+
+	info.Title = "New title"
+	xmp.SetDocumentInfo(info)
+	xmp, err = ParseXMPMetadata(xmp.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "New title", xmp.DocumentInfo().Title)
+	// Properties which are not changed are kept as they are.
+	data := string(xmp.Bytes())
+	assert.Contains(t, data, `<stEvt:action>created</stEvt:action>`)
+	assert.Contains(t, data, `<dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li><rdf:li>John Doe</rdf:li></rdf:Seq></dc:creator>`)
+	part, _ = xmp.Property("http://www.aiim.org/pdfa/ns/id/", "part")
+	assert.Equal(t, "1", part)
+
+	_, err = ParseXMPMetadata([]byte("<html></html>"))
+	assert.Error(t, err)
+}
+
+func TestAppenderDocumentInfo(t *testing.T) {
+	info := &PdfDocumentInfo{Title: "Original", Author: "Jane Doe"}
+	reader, err := NewPdfReader(bytes.NewReader(writeTestDocument(t, info, NewXMPMetadata())))
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	info, err = reader.GetDocumentInfo()
+	require.NoError(t, err)
+	info.Title = "Updated"
+	appender.SetDocumentInfo(info)
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	read, err := reader.GetDocumentInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "Updated", read.Title)
+	assert.Equal(t, "Jane Doe", read.Author)
+	xmp, err := reader.GetXMPMetadata()
+	require.NoError(t, err)
+	require.NotNil(t, xmp)
+	assert.Equal(t, "Updated", xmp.DocumentInfo().Title)
+}
@@ -0,0 +1,1032 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moolekkari/unipdf/internal/textencoding"
+)
+
+// CFFFont describes a CFF (Compact Font Format) font program, as embedded in PDF files with
+// FontFile3 streams of subtype Type1C or CIDFontType0C, or in the "CFF " table of OpenType fonts.
+// See Adobe Technical Note #5176 "The Compact Font Format Specification".
+type CFFFont struct {
+	// Name is the name of the font in the Name INDEX.
+	Name string
+	// IsCIDKeyed is true for CID-keyed fonts.
+	IsCIDKeyed bool
+	// GlyphNames lists the glyph names by glyph index. It is nil for CID-keyed fonts.
+	GlyphNames []GlyphName
+	// CIDs lists the CIDs of the glyphs by glyph index. It is nil for fonts that are not CID-keyed.
+	CIDs []uint16
+	// Encoding is the built-in encoding of fonts that are not CID-keyed.
+	Encoding map[textencoding.CharCode]GlyphName
+	// Widths lists the advance widths of the glyphs by glyph index, in 1/1000 of text space units.
+	Widths []float64
+	// FontBBox is the font bounding box in glyph space units.
+	FontBBox [4]float64
+
+	glyphs map[GlyphName]GID
+	cids   map[uint16]GID
+	data   *cffData
+}
+
+// GIDByName returns the glyph index of the glyph named `glyph`.
+func (cff *CFFFont) GIDByName(glyph GlyphName) (GID, bool) {
+	gid, ok := cff.glyphs[glyph]
+	return gid, ok
+}
+
+// GIDByCID returns the glyph index of the glyph with CID `cid` of a CID-keyed font.
+func (cff *CFFFont) GIDByCID(cid uint16) (GID, bool) {
+	gid, ok := cff.cids[cid]
+	return gid, ok
+}
+
+// String returns a human readable representation of `cff`.
+func (cff *CFFFont) String() string {
+	return fmt.Sprintf("CFF{%#q CIDKeyed=%t Glyphs=%d Encoding=%d}",
+		cff.Name, cff.IsCIDKeyed, len(cff.Widths), len(cff.Encoding))
+}
+
+// ParseCFF returns a CFFFont describing the CFF font program `data`. `data` is either a bare CFF
+// font program or an OpenType font with a "CFF " table.
+func ParseCFF(data []byte) (*CFFFont, error) {
+	data, err := cffTable(data)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseCFFData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cff := &CFFFont{
+		Name:       string(f.names[0]),
+		IsCIDKeyed: f.top.has(cffOpROS),
+		Widths:     make([]float64, len(f.charStrings)),
+		glyphs:     make(map[GlyphName]GID),
+		data:       f,
+	}
+	if bbox := f.top.get(cffOpFontBBox); len(bbox) == 4 {
+		copy(cff.FontBBox[:], bbox)
+	}
+	scale := 1.0 // The default FontMatrix is [0.001 0 0 0.001 0 0].
+	if matrix := f.top.get(cffOpFontMatrix); len(matrix) == 6 {
+		scale = 1000 * matrix[0]
+	}
+	for gid, cs := range f.charStrings {
+		private := f.private
+		if cff.IsCIDKeyed {
+			private = &cffPrivate{}
+			if fd := f.fdSelect[gid]; fd < len(f.fdPrivates) {
+				private = f.fdPrivates[fd]
+			}
+		}
+		cff.Widths[gid] = scale * f.charStringWidth(cs, private)
+	}
+
+	if cff.IsCIDKeyed {
+		cff.CIDs = f.charset
+		cff.cids = make(map[uint16]GID, len(f.charset))
+		for gid, cid := range f.charset {
+			if _, ok := cff.cids[cid]; !ok {
+				cff.cids[cid] = GID(gid)
+			}
+		}
+		return cff, nil
+	}
+	if f.charset != nil {
+		cff.GlyphNames = make([]GlyphName, len(f.charset))
+		for gid, sid := range f.charset {
+			glyph := GlyphName(f.sidString(sid))
+			cff.GlyphNames[gid] = glyph
+			if _, ok := cff.glyphs[glyph]; !ok {
+				cff.glyphs[glyph] = GID(gid)
+			}
+		}
+	}
+	cff.Encoding = make(map[textencoding.CharCode]GlyphName, len(f.encoding))
+	for code, gid := range f.encoding {
+		if int(gid) < len(cff.GlyphNames) {
+			cff.Encoding[code] = cff.GlyphNames[gid]
+		}
+	}
+	return cff, nil
+}
+
+// SubsetCFF returns a subset of the CFF font program `data` containing the glyphs `gids` and the
+// .notdef glyph. `data` is either a bare CFF font program or an OpenType font with a "CFF " table,
+// the subset is a bare CFF font program.
+// Glyph indices are preserved: the charstrings of the other glyphs are replaced with empty glyphs,
+// and the charset, encoding and FDSelect tables are not changed.
+func SubsetCFF(data []byte, gids []GID) ([]byte, error) {
+	data, err := cffTable(data)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseCFFData(data)
+	if err != nil {
+		return nil, err
+	}
+	charStrings := make([][]byte, len(f.charStrings))
+	for gid := range charStrings {
+		charStrings[gid] = cffEmptyGlyph
+	}
+	charStrings[0] = f.charStrings[0]
+	for _, gid := range gids {
+		if int(gid) < len(charStrings) {
+			charStrings[gid] = f.charStrings[gid]
+		}
+	}
+	return f.write(charStrings), nil
+}
+
+// SubsetOpenType returns a subset of the OpenType font program with CFF outlines `data`
+// containing the glyphs `gids` and the .notdef glyph. The glyph indices are preserved.
+// The "cmap" table of the subset maps the runes of `runeToGID` with glyphs in the subset.
+// Tables not needed for rendering the glyphs in PDF documents are dropped.
+func SubsetOpenType(data []byte, gids []GID, runeToGID map[rune]GID) ([]byte, error) {
+	tables, err := readTableDirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	if string(data[:4]) != "OTTO" {
+		return nil, errors.New("not an OpenType font with CFF outlines")
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "CFF "} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("required table missing: %q", tag)
+		}
+	}
+	head, maxp := tables["head"], tables["maxp"]
+	if len(head) < 54 || len(maxp) < 6 {
+		return nil, errors.New("invalid font tables")
+	}
+	cff, err := SubsetCFF(tables["CFF "], gids)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make([]bool, binary.BigEndian.Uint16(maxp[4:]))
+	for _, gid := range gids {
+		if int(gid) < len(keep) {
+			keep[gid] = true
+		}
+	}
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[8:], 0) // checkSumAdjustment, set below.
+	newTables := map[string][]byte{
+		"CFF ": cff,
+		"head": newHead,
+		"hhea": tables["hhea"],
+		"maxp": maxp,
+		"hmtx": tables["hmtx"],
+		"cmap": makeSubsetCmap(runeToGID, keep),
+	}
+	if post, ok := tables["post"]; ok && len(post) >= 32 {
+		// Glyph names are dropped (format 3).
+		newPost := append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(newPost, 0x00030000)
+		newTables["post"] = newPost
+	}
+	for _, tag := range []string{"OS/2", "name"} {
+		if table, ok := tables[tag]; ok {
+			newTables[tag] = table
+		}
+	}
+
+	font := writeTables(sfntVersionOpenType, newTables)
+	binary.BigEndian.PutUint32(font[headOffset(font)+8:], 0xB1B0AFBA-tableChecksum(font))
+	return font, nil
+}
+
+// cffTable returns the "CFF " table of `data` if it is an OpenType font, otherwise `data`.
+func cffTable(data []byte) ([]byte, error) {
+	if len(data) < 4 || string(data[:4]) != "OTTO" {
+		return data, nil
+	}
+	tables, err := readTableDirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	cff, ok := tables["CFF "]
+	if !ok {
+		return nil, errors.New("OpenType font has no CFF table")
+	}
+	return cff, nil
+}
+
+// DICT operators. Two byte operators (escape 12 followed by b1) are represented as 1200+b1.
+const (
+	cffOpFontBBox       = 5
+	cffOpCharset        = 15
+	cffOpEncoding       = 16
+	cffOpCharStrings    = 17
+	cffOpPrivate        = 18
+	cffOpSubrs          = 19
+	cffOpDefaultWidthX  = 20
+	cffOpNominalWidthX  = 21
+	cffOpCharstringType = 1206
+	cffOpFontMatrix     = 1207
+	cffOpROS            = 1230
+	cffOpFDArray        = 1236
+	cffOpFDSelect       = 1237
+)
+
+// cffEmptyGlyph is the charstring of the glyphs dropped from subsets: an endchar operator.
+var cffEmptyGlyph = []byte{14}
+
+// cffDictEntry is an operator with its operands in a CFF DICT.
+type cffDictEntry struct {
+	op       int
+	operands []float64
+	raw      []byte // The encoded operands and operator.
+}
+
+// cffDict is a CFF DICT. The entries are kept in order so that the DICT can be written unchanged.
+type cffDict []cffDictEntry
+
+// get returns the operands of operator `op` in `d`, nil if `d` has no entry for `op`.
+func (d cffDict) get(op int) []float64 {
+	for _, e := range d {
+		if e.op == op {
+			return e.operands
+		}
+	}
+	return nil
+}
+
+// has returns true if `d` has an entry for operator `op`.
+func (d cffDict) has(op int) bool {
+	for _, e := range d {
+		if e.op == op {
+			return true
+		}
+	}
+	return false
+}
+
+// getInt returns the integer operand of operator `op` in `d`, `def` if `d` has no entry for `op`.
+func (d cffDict) getInt(op, def int) int {
+	if operands := d.get(op); len(operands) > 0 {
+		return int(operands[0])
+	}
+	return def
+}
+
+// encode returns the encoded DICT `d`. The operands of the operators in `offsets` are replaced
+// by the values in `offsets`, encoded as 5 byte integers, so that the size of the encoded DICT
+// does not depend on the values.
+func (d cffDict) encode(offsets map[int][]int) []byte {
+	var b []byte
+	for _, e := range d {
+		values, ok := offsets[e.op]
+		if !ok {
+			b = append(b, e.raw...)
+			continue
+		}
+		for _, v := range values {
+			b = append(b, 29, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+		}
+		if e.op >= 1200 {
+			b = append(b, 12, byte(e.op-1200))
+		} else {
+			b = append(b, byte(e.op))
+		}
+	}
+	return b
+}
+
+// cffPrivate is a Private DICT with its local subroutines.
+type cffPrivate struct {
+	dict         cffDict
+	subrs        [][]byte
+	subrsRaw     []byte // The encoded local Subrs INDEX.
+	defaultWidth float64
+	nominalWidth float64
+}
+
+// cffData holds the structures of a CFF font program needed for reading and subsetting it.
+type cffData struct {
+	header      []byte
+	names       [][]byte
+	namesRaw    []byte
+	top         cffDict
+	strings     [][]byte
+	stringsRaw  []byte
+	gsubrs      [][]byte
+	gsubrsRaw   []byte
+	charStrings [][]byte
+
+	charset    []uint16 // SIDs, or CIDs for CID-keyed fonts, by glyph index. nil if predefined.
+	charsetRaw []byte   // The encoded custom charset.
+	encoding   map[textencoding.CharCode]GID
+	encodeRaw  []byte // The encoded custom encoding.
+
+	private *cffPrivate // The Private DICT of fonts that are not CID-keyed.
+
+	// CID-keyed fonts.
+	fdSelect    []int
+	fdSelectRaw []byte
+	fdArray     []cffDict
+	fdPrivates  []*cffPrivate
+}
+
+// parseCFFData parses the CFF font program `data`. Only the first font of font sets is parsed.
+func parseCFFData(data []byte) (*cffData, error) {
+	if len(data) < 4 {
+		return nil, errors.New("CFF data too short")
+	}
+	if data[0] != 1 {
+		return nil, fmt.Errorf("unsupported CFF version %d", data[0])
+	}
+	hdrSize := int(data[2])
+	if hdrSize < 4 || hdrSize > len(data) {
+		return nil, errors.New("invalid CFF header")
+	}
+	f := &cffData{header: data[:hdrSize]}
+
+	var err error
+	pos := hdrSize
+	var end int
+	if f.names, end, err = readCFFIndex(data, pos); err != nil {
+		return nil, err
+	}
+	f.namesRaw, pos = data[pos:end], end
+	topDicts, end, err := readCFFIndex(data, pos)
+	if err != nil {
+		return nil, err
+	}
+	pos = end
+	if f.strings, end, err = readCFFIndex(data, pos); err != nil {
+		return nil, err
+	}
+	f.stringsRaw, pos = data[pos:end], end
+	if f.gsubrs, end, err = readCFFIndex(data, pos); err != nil {
+		return nil, err
+	}
+	f.gsubrsRaw = data[pos:end]
+	if len(f.names) == 0 || len(topDicts) == 0 {
+		return nil, errors.New("CFF font set is empty")
+	}
+	if f.top, err = parseCFFDict(topDicts[0]); err != nil {
+		return nil, err
+	}
+	if t := f.top.getInt(cffOpCharstringType, 2); t != 2 {
+		return nil, fmt.Errorf("unsupported charstring type %d", t)
+	}
+
+	offset := f.top.getInt(cffOpCharStrings, 0)
+	if offset <= 0 {
+		return nil, errors.New("CFF font has no CharStrings")
+	}
+	if f.charStrings, _, err = readCFFIndex(data, offset); err != nil {
+		return nil, err
+	}
+	numGlyphs := len(f.charStrings)
+	if numGlyphs == 0 {
+		return nil, errors.New("CFF font has no glyphs")
+	}
+
+	if offset := f.top.getInt(cffOpCharset, 0); offset > 2 {
+		if f.charset, end, err = readCFFCharset(data, offset, numGlyphs); err != nil {
+			return nil, err
+		}
+		f.charsetRaw = data[offset:end]
+	} else if offset == 0 {
+		// ISOAdobe charset: the SIDs of the glyphs are the glyph indices.
+		n := numGlyphs
+		if n > 229 {
+			n = 229
+		}
+		f.charset = make([]uint16, n)
+		for gid := range f.charset {
+			f.charset[gid] = uint16(gid)
+		}
+	}
+
+	if f.top.has(cffOpROS) {
+		if err := f.parseCIDFont(data, numGlyphs); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	switch offset := f.top.getInt(cffOpEncoding, 0); offset {
+	case 0:
+		f.encoding = f.standardEncoding()
+	case 1:
+		// Expert encoding, not used for text extraction.
+	default:
+		if f.encoding, end, err = readCFFEncoding(data, offset, f.charset); err != nil {
+			return nil, err
+		}
+		f.encodeRaw = data[offset:end]
+	}
+
+	if f.private, err = readCFFPrivate(data, f.top.get(cffOpPrivate)); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseCIDFont parses the FDArray and FDSelect of CID-keyed font program `data`.
+func (f *cffData) parseCIDFont(data []byte, numGlyphs int) error {
+	offset := f.top.getInt(cffOpFDArray, 0)
+	if offset <= 0 {
+		return errors.New("CID-keyed font has no FDArray")
+	}
+	fdDicts, _, err := readCFFIndex(data, offset)
+	if err != nil {
+		return err
+	}
+	for _, fdDict := range fdDicts {
+		d, err := parseCFFDict(fdDict)
+		if err != nil {
+			return err
+		}
+		private, err := readCFFPrivate(data, d.get(cffOpPrivate))
+		if err != nil {
+			return err
+		}
+		f.fdArray = append(f.fdArray, d)
+		f.fdPrivates = append(f.fdPrivates, private)
+	}
+
+	offset = f.top.getInt(cffOpFDSelect, 0)
+	if offset <= 0 {
+		return errors.New("CID-keyed font has no FDSelect")
+	}
+	var end int
+	if f.fdSelect, end, err = readCFFFDSelect(data, offset, numGlyphs); err != nil {
+		return err
+	}
+	f.fdSelectRaw = data[offset:end]
+	return nil
+}
+
+// sidString returns the string with string identifier `sid`.
+func (f *cffData) sidString(sid uint16) string {
+	if int(sid) < len(cffStandardStrings) {
+		return cffStandardStrings[sid]
+	}
+	if i := int(sid) - len(cffStandardStrings); i < len(f.strings) {
+		return string(f.strings[i])
+	}
+	return ""
+}
+
+// standardEncoding returns the Standard Encoding code to glyph index map of the font.
+func (f *cffData) standardEncoding() map[textencoding.CharCode]GID {
+	glyphs := make(map[string]GID, len(f.charset))
+	for gid, sid := range f.charset {
+		glyphs[f.sidString(sid)] = GID(gid)
+	}
+	encoder := textencoding.NewStandardEncoder()
+	encoding := make(map[textencoding.CharCode]GID)
+	for _, code := range encoder.Charcodes() {
+		r, ok := encoder.CharcodeToRune(code)
+		if !ok {
+			continue
+		}
+		glyph, ok := textencoding.RuneToGlyph(r)
+		if !ok {
+			continue
+		}
+		if gid, ok := glyphs[string(glyph)]; ok && gid != 0 {
+			encoding[code] = gid
+		}
+	}
+	return encoding
+}
+
+// write returns the CFF font program of `f` with the charstrings `charStrings`.
+// The offset operands of the DICTs are encoded as 5 byte integers, so the sizes of the DICTs do
+// not depend on the offsets and the layout of the font program is computed before writing it.
+func (f *cffData) write(charStrings [][]byte) []byte {
+	privates := []*cffPrivate{f.private}
+	if f.top.has(cffOpROS) {
+		privates = f.fdPrivates
+	}
+	privateData := make([][]byte, len(privates))
+	privateSizes := make([]int, len(privates))
+	for i, p := range privates {
+		size := len(p.dict.encode(map[int][]int{cffOpSubrs: {0}}))
+		privateSizes[i] = size
+		privateData[i] = append(p.dict.encode(map[int][]int{cffOpSubrs: {size}}), p.subrsRaw...)
+	}
+
+	// Compute the layout with placeholder offsets.
+	// Predefined charsets and encodings are identified by their offset operand, which is kept.
+	topOffsets := map[int][]int{
+		cffOpCharStrings: {0},
+		cffOpPrivate:     {0, 0},
+		cffOpFDArray:     {0},
+		cffOpFDSelect:    {0},
+	}
+	if f.charsetRaw != nil {
+		topOffsets[cffOpCharset] = []int{0}
+	}
+	if f.encodeRaw != nil {
+		topOffsets[cffOpEncoding] = []int{0}
+	}
+	fdOffsets := make([]map[int][]int, len(f.fdArray))
+	for i := range fdOffsets {
+		fdOffsets[i] = map[int][]int{cffOpPrivate: {0, 0}}
+	}
+	encodeFDArray := func() []byte {
+		fdDicts := make([][]byte, len(f.fdArray))
+		for i, d := range f.fdArray {
+			fdDicts[i] = d.encode(fdOffsets[i])
+		}
+		return writeCFFIndex(fdDicts)
+	}
+	topIndexSize := len(writeCFFIndex([][]byte{f.top.encode(topOffsets)}))
+	charStringsIndex := writeCFFIndex(charStrings)
+
+	pos := len(f.header) + len(f.namesRaw) + topIndexSize + len(f.stringsRaw) + len(f.gsubrsRaw)
+	if f.charsetRaw != nil {
+		topOffsets[cffOpCharset] = []int{pos}
+		pos += len(f.charsetRaw)
+	}
+	if f.encodeRaw != nil {
+		topOffsets[cffOpEncoding] = []int{pos}
+		pos += len(f.encodeRaw)
+	}
+	if f.fdSelectRaw != nil {
+		topOffsets[cffOpFDSelect] = []int{pos}
+		pos += len(f.fdSelectRaw)
+	}
+	topOffsets[cffOpCharStrings] = []int{pos}
+	pos += len(charStringsIndex)
+	if f.fdArray != nil {
+		topOffsets[cffOpFDArray] = []int{pos}
+		pos += len(encodeFDArray())
+		for i := range f.fdArray {
+			fdOffsets[i][cffOpPrivate] = []int{privateSizes[i], pos}
+			pos += len(privateData[i])
+		}
+	} else {
+		topOffsets[cffOpPrivate] = []int{privateSizes[0], pos}
+	}
+
+	b := append([]byte(nil), f.header...)
+	b = append(b, f.namesRaw...)
+	b = append(b, writeCFFIndex([][]byte{f.top.encode(topOffsets)})...)
+	b = append(b, f.stringsRaw...)
+	b = append(b, f.gsubrsRaw...)
+	b = append(b, f.charsetRaw...)
+	b = append(b, f.encodeRaw...)
+	b = append(b, f.fdSelectRaw...)
+	b = append(b, charStringsIndex...)
+	if f.fdArray != nil {
+		b = append(b, encodeFDArray()...)
+	}
+	for _, p := range privateData {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// charStringWidth returns the advance width in glyph space units of the glyph with Type 2
+// charstring `cs`. The width is given by the optional first operand of the first stack clearing
+// operator of the charstring (Adobe Technical Note #5177, section 3.1).
+func (f *cffData) charStringWidth(cs []byte, private *cffPrivate) float64 {
+	var stack []float64
+	hasWidth, _ := scanCharStringWidth(cs, &stack, f.gsubrs, private.subrs, 0)
+	if hasWidth && len(stack) > 0 {
+		return private.nominalWidth + stack[0]
+	}
+	return private.defaultWidth
+}
+
+// scanCharStringWidth interprets charstring `cs` up to its first stack clearing operator, calling
+// the subroutines `gsubrs` and `subrs`, with operand stack `stack`. It returns true if the stack
+// has a width operand, and whether a stack clearing operator has been reached.
+func scanCharStringWidth(cs []byte, stack *[]float64, gsubrs, subrs [][]byte, depth int) (bool, bool) {
+	if depth > 10 {
+		return false, true
+	}
+	for pos := 0; pos < len(cs); {
+		b0 := cs[pos]
+		switch {
+		case b0 == 28:
+			if pos+3 > len(cs) {
+				return false, true
+			}
+			*stack = append(*stack, float64(int16(binary.BigEndian.Uint16(cs[pos+1:]))))
+			pos += 3
+		case b0 == 255:
+			if pos+5 > len(cs) {
+				return false, true
+			}
+			*stack = append(*stack, float64(int32(binary.BigEndian.Uint32(cs[pos+1:])))/65536)
+			pos += 5
+		case b0 >= 32:
+			v, n, err := readCFFInt(cs[pos:])
+			if err != nil {
+				return false, true
+			}
+			*stack = append(*stack, float64(v))
+			pos += n
+		default:
+			pos++
+			n := len(*stack)
+			switch b0 {
+			case 1, 3, 18, 23, 19, 20: // Stem hints and hint masks take an even number of operands.
+				return n%2 == 1, true
+			case 21: // rmoveto
+				return n > 2, true
+			case 4, 22: // hmoveto, vmoveto
+				return n > 1, true
+			case 14: // endchar
+				return n == 1 || n == 5, true
+			case 10, 29: // callsubr, callgsubr
+				if n == 0 {
+					return false, true
+				}
+				subroutines := subrs
+				if b0 == 29 {
+					subroutines = gsubrs
+				}
+				i := int((*stack)[n-1]) + cffSubrBias(len(subroutines))
+				*stack = (*stack)[:n-1]
+				if i < 0 || i >= len(subroutines) {
+					return false, true
+				}
+				if hasWidth, done := scanCharStringWidth(subroutines[i], stack, gsubrs, subrs,
+					depth+1); done {
+					return hasWidth, true
+				}
+			case 11: // return
+				return false, false
+			default:
+				return false, true
+			}
+		}
+	}
+	return false, false
+}
+
+// cffSubrBias returns the bias of subroutine numbers for a subroutine INDEX with `count` entries.
+func cffSubrBias(count int) int {
+	switch {
+	case count < 1240:
+		return 107
+	case count < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+// readCFFIndex returns the entries of the INDEX at `pos` in `data` and the position after it.
+func readCFFIndex(data []byte, pos int) ([][]byte, int, error) {
+	if pos < 0 || pos+2 > len(data) {
+		return nil, 0, errors.New("CFF INDEX out of range")
+	}
+	count := int(binary.BigEndian.Uint16(data[pos:]))
+	if count == 0 {
+		return nil, pos + 2, nil
+	}
+	if pos+3 > len(data) {
+		return nil, 0, errors.New("CFF INDEX out of range")
+	}
+	offSize := int(data[pos+2])
+	if offSize < 1 || offSize > 4 {
+		return nil, 0, fmt.Errorf("invalid CFF INDEX offset size %d", offSize)
+	}
+	offsets := pos + 3
+	base := offsets + (count+1)*offSize - 1
+	if base >= len(data) {
+		return nil, 0, errors.New("CFF INDEX out of range")
+	}
+	offset := func(i int) int {
+		v := 0
+		for _, b := range data[offsets+i*offSize : offsets+(i+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+	entries := make([][]byte, count)
+	start := offset(0)
+	for i := range entries {
+		end := offset(i + 1)
+		if start < 1 || end < start || base+end > len(data) {
+			return nil, 0, errors.New("invalid CFF INDEX offsets")
+		}
+		entries[i] = data[base+start : base+end]
+		start = end
+	}
+	return entries, base + start, nil
+}
+
+// writeCFFIndex returns the INDEX with entries `entries`.
+func writeCFFIndex(entries [][]byte) []byte {
+	if len(entries) == 0 {
+		return []byte{0, 0}
+	}
+	last := 1
+	for _, e := range entries {
+		last += len(e)
+	}
+	offSize := 1
+	for last >= 1<<uint(8*offSize) {
+		offSize++
+	}
+
+	b := make([]byte, 3, 3+(len(entries)+1)*offSize+last-1)
+	binary.BigEndian.PutUint16(b, uint16(len(entries)))
+	b[2] = byte(offSize)
+	putOffset := func(v int) {
+		for i := offSize - 1; i >= 0; i-- {
+			b = append(b, byte(v>>uint(8*i)))
+		}
+	}
+	offset := 1
+	putOffset(offset)
+	for _, e := range entries {
+		offset += len(e)
+		putOffset(offset)
+	}
+	for _, e := range entries {
+		b = append(b, e...)
+	}
+	return b
+}
+
+// parseCFFDict parses the DICT `data`.
+func parseCFFDict(data []byte) (cffDict, error) {
+	var d cffDict
+	var operands []float64
+	start := 0
+	for pos := 0; pos < len(data); {
+		b0 := data[pos]
+		switch {
+		case b0 <= 21:
+			op := int(b0)
+			pos++
+			if b0 == 12 {
+				if pos >= len(data) {
+					return nil, errors.New("truncated CFF DICT")
+				}
+				op = 1200 + int(data[pos])
+				pos++
+			}
+			d = append(d, cffDictEntry{op: op, operands: operands, raw: data[start:pos]})
+			operands = nil
+			start = pos
+		case b0 == 30:
+			v, n, err := readCFFReal(data[pos+1:])
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, v)
+			pos += 1 + n
+		default:
+			v, n, err := readCFFInt(data[pos:])
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, float64(v))
+			pos += n
+		}
+	}
+	return d, nil
+}
+
+// readCFFInt returns the integer operand at the start of `data` and its encoded length.
+func readCFFInt(data []byte) (int, int, error) {
+	b0 := int(data[0])
+	need := 1
+	switch {
+	case b0 == 28:
+		need = 3
+	case b0 == 29:
+		need = 5
+	case b0 >= 247 && b0 <= 254:
+		need = 2
+	case b0 < 32 || b0 == 255:
+		return 0, 0, fmt.Errorf("invalid CFF operand 0x%02x", b0)
+	}
+	if len(data) < need {
+		return 0, 0, errors.New("truncated CFF operand")
+	}
+	switch {
+	case b0 == 28:
+		return int(int16(binary.BigEndian.Uint16(data[1:]))), 3, nil
+	case b0 == 29:
+		return int(int32(binary.BigEndian.Uint32(data[1:]))), 5, nil
+	case b0 <= 246:
+		return b0 - 139, 1, nil
+	case b0 <= 250:
+		return (b0-247)*256 + int(data[1]) + 108, 2, nil
+	}
+	return -(b0-251)*256 - int(data[1]) - 108, 2, nil
+}
+
+// readCFFReal returns the real number operand encoded as nibbles at the start of `data` and its
+// encoded length.
+func readCFFReal(data []byte) (float64, int, error) {
+	var sb strings.Builder
+	for i, b := range data {
+		for _, nibble := range []byte{b >> 4, b & 0x0f} {
+			switch {
+			case nibble <= 9:
+				sb.WriteByte('0' + nibble)
+			case nibble == 0xa:
+				sb.WriteByte('.')
+			case nibble == 0xb:
+				sb.WriteByte('E')
+			case nibble == 0xc:
+				sb.WriteString("E-")
+			case nibble == 0xe:
+				sb.WriteByte('-')
+			case nibble == 0xf:
+				v, err := strconv.ParseFloat(sb.String(), 64)
+				if err != nil {
+					return 0, 0, fmt.Errorf("invalid CFF real %q", sb.String())
+				}
+				return v, i + 1, nil
+			default:
+				return 0, 0, errors.New("invalid CFF real")
+			}
+		}
+	}
+	return 0, 0, errors.New("truncated CFF real")
+}
+
+// readCFFCharset returns the SIDs (or CIDs) by glyph index of the charset at `pos` in `data` and
+// the position after it.
+func readCFFCharset(data []byte, pos, numGlyphs int) ([]uint16, int, error) {
+	errRange := errors.New("CFF charset out of range")
+	if pos >= len(data) {
+		return nil, 0, errRange
+	}
+	charset := make([]uint16, 1, numGlyphs)
+	format := data[pos]
+	pos++
+	switch format {
+	case 0:
+		if pos+2*(numGlyphs-1) > len(data) {
+			return nil, 0, errRange
+		}
+		for gid := 1; gid < numGlyphs; gid++ {
+			charset = append(charset, binary.BigEndian.Uint16(data[pos:]))
+			pos += 2
+		}
+	case 1, 2:
+		for len(charset) < numGlyphs {
+			size := 2 + int(format)
+			if pos+size > len(data) {
+				return nil, 0, errRange
+			}
+			first := int(binary.BigEndian.Uint16(data[pos:]))
+			nLeft := int(data[pos+2])
+			if format == 2 {
+				nLeft = int(binary.BigEndian.Uint16(data[pos+2:]))
+			}
+			pos += size
+			for i := 0; i <= nLeft && len(charset) < numGlyphs; i++ {
+				charset = append(charset, uint16(first+i))
+			}
+		}
+	default:
+		return nil, 0, fmt.Errorf("invalid CFF charset format %d", format)
+	}
+	return charset, pos, nil
+}
+
+// readCFFEncoding returns the code to glyph index map of the encoding at `pos` in `data` and the
+// position after it. `charset` is used for resolving the supplementary encodings.
+func readCFFEncoding(data []byte, pos int, charset []uint16) (map[textencoding.CharCode]GID, int, error) {
+	errRange := errors.New("CFF encoding out of range")
+	if pos+2 > len(data) {
+		return nil, 0, errRange
+	}
+	encoding := make(map[textencoding.CharCode]GID)
+	format := data[pos]
+	n := int(data[pos+1])
+	pos += 2
+	switch format & 0x7f {
+	case 0:
+		if pos+n > len(data) {
+			return nil, 0, errRange
+		}
+		for i := 0; i < n; i++ {
+			encoding[textencoding.CharCode(data[pos+i])] = GID(i + 1)
+		}
+		pos += n
+	case 1:
+		if pos+2*n > len(data) {
+			return nil, 0, errRange
+		}
+		gid := 1
+		for i := 0; i < n; i++ {
+			first, nLeft := int(data[pos]), int(data[pos+1])
+			for code := first; code <= first+nLeft && code < 256; code++ {
+				encoding[textencoding.CharCode(code)] = GID(gid)
+				gid++
+			}
+			pos += 2
+		}
+	default:
+		return nil, 0, fmt.Errorf("invalid CFF encoding format %d", format)
+	}
+
+	if format&0x80 != 0 {
+		if pos >= len(data) {
+			return nil, 0, errRange
+		}
+		nSups := int(data[pos])
+		pos++
+		if pos+3*nSups > len(data) {
+			return nil, 0, errRange
+		}
+		for i := 0; i < nSups; i++ {
+			code := textencoding.CharCode(data[pos])
+			sid := binary.BigEndian.Uint16(data[pos+1:])
+			for gid, s := range charset {
+				if s == sid {
+					encoding[code] = GID(gid)
+					break
+				}
+			}
+			pos += 3
+		}
+	}
+	return encoding, pos, nil
+}
+
+// readCFFFDSelect returns the Font DICT indices by glyph index of the FDSelect at `pos` in `data`
+// and the position after it.
+func readCFFFDSelect(data []byte, pos, numGlyphs int) ([]int, int, error) {
+	errRange := errors.New("CFF FDSelect out of range")
+	if pos >= len(data) {
+		return nil, 0, errRange
+	}
+	fds := make([]int, numGlyphs)
+	switch format := data[pos]; format {
+	case 0:
+		if pos+1+numGlyphs > len(data) {
+			return nil, 0, errRange
+		}
+		for gid := range fds {
+			fds[gid] = int(data[pos+1+gid])
+		}
+		return fds, pos + 1 + numGlyphs, nil
+	case 3:
+		if pos+3 > len(data) {
+			return nil, 0, errRange
+		}
+		nRanges := int(binary.BigEndian.Uint16(data[pos+1:]))
+		end := pos + 3 + 3*nRanges + 2
+		if end > len(data) {
+			return nil, 0, errRange
+		}
+		for i := 0; i < nRanges; i++ {
+			r := data[pos+3+3*i:]
+			first := int(binary.BigEndian.Uint16(r))
+			next := int(binary.BigEndian.Uint16(r[3:]))
+			for gid := first; gid < next && gid < numGlyphs; gid++ {
+				fds[gid] = int(r[2])
+			}
+		}
+		return fds, end, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid CFF FDSelect format %d", format)
+	}
+}
+
+// readCFFPrivate returns the Private DICT with the size and offset operands `operands` in `data`.
+func readCFFPrivate(data []byte, operands []float64) (*cffPrivate, error) {
+	private := &cffPrivate{}
+	if len(operands) != 2 {
+		return private, nil
+	}
+	size, offset := int(operands[0]), int(operands[1])
+	if size < 0 || offset < 0 || offset+size > len(data) {
+		return nil, errors.New("CFF Private DICT out of range")
+	}
+	var err error
+	if private.dict, err = parseCFFDict(data[offset : offset+size]); err != nil {
+		return nil, err
+	}
+	if operands := private.dict.get(cffOpDefaultWidthX); len(operands) > 0 {
+		private.defaultWidth = operands[0]
+	}
+	if operands := private.dict.get(cffOpNominalWidthX); len(operands) > 0 {
+		private.nominalWidth = operands[0]
+	}
+	if subrs := private.dict.getInt(cffOpSubrs, 0); subrs > 0 {
+		var end int
+		if private.subrs, end, err = readCFFIndex(data, offset+subrs); err != nil {
+			return nil, err
+		}
+		private.subrsRaw = data[offset+subrs : end]
+	}
+	return private, nil
+}
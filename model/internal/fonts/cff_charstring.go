@@ -0,0 +1,425 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/moolekkari/unipdf/internal/textencoding"
+)
+
+// maxSubrDepth limits the nesting of charstring subroutine calls.
+const maxSubrDepth = 10
+
+// Outline returns the outline of glyph `gid`, interpreting its Type 2 charstring.
+func (cff *CFFFont) Outline(gid GID) (GlyphPath, error) {
+	f := cff.data
+	if int(gid) >= len(f.charStrings) {
+		return nil, fmt.Errorf("invalid glyph index %d", gid)
+	}
+	matrix := [6]float64{0.001, 0, 0, 0.001, 0, 0}
+	if m := f.top.get(cffOpFontMatrix); len(m) == 6 {
+		copy(matrix[:], m)
+	}
+	private := f.private
+	if cff.IsCIDKeyed {
+		private = &cffPrivate{}
+		if fd := f.fdSelect[gid]; fd < len(f.fdPrivates) {
+			private = f.fdPrivates[fd]
+			if m := f.fdArray[fd].get(cffOpFontMatrix); len(m) == 6 {
+				// The FontMatrix of the Font DICT is applied before the FontMatrix of the font.
+				matrix = concatMatrices([6]float64{m[0], m[1], m[2], m[3], m[4], m[5]}, matrix)
+			}
+		}
+	}
+
+	cs := &type2Charstring{
+		b:      newPathBuilder(matrix),
+		gsubrs: f.gsubrs,
+		subrs:  private.subrs,
+		seac:   cff.standardGlyph,
+	}
+	if _, err := cs.run(f.charStrings[gid], 0); err != nil {
+		return nil, err
+	}
+	return cs.b.glyphPath(), nil
+}
+
+// standardGlyph returns the charstring of the glyph with Standard Encoding code `code`, for the
+// components of accented glyphs.
+func (cff *CFFFont) standardGlyph(code int) ([]byte, bool) {
+	r, ok := textencoding.NewStandardEncoder().CharcodeToRune(textencoding.CharCode(code))
+	if !ok {
+		return nil, false
+	}
+	glyph, ok := textencoding.RuneToGlyph(r)
+	if !ok {
+		return nil, false
+	}
+	gid, ok := cff.GIDByName(glyph)
+	if !ok {
+		return nil, false
+	}
+	return cff.data.charStrings[gid], true
+}
+
+// concatMatrices returns the matrix applying `m1` and then `m2`.
+func concatMatrices(m1, m2 [6]float64) [6]float64 {
+	return [6]float64{
+		m1[0]*m2[0] + m1[1]*m2[2], m1[0]*m2[1] + m1[1]*m2[3],
+		m1[2]*m2[0] + m1[3]*m2[2], m1[2]*m2[1] + m1[3]*m2[3],
+		m1[4]*m2[0] + m1[5]*m2[2] + m2[4], m1[4]*m2[1] + m1[5]*m2[3] + m2[5],
+	}
+}
+
+// type2Charstring is an interpreter of Type 2 charstrings (Adobe Technical Note #5177).
+type type2Charstring struct {
+	b             *pathBuilder
+	gsubrs, subrs [][]byte
+	seac          func(code int) ([]byte, bool) // Returns the charstring of a Standard Encoding code.
+
+	stack    []float64
+	numStems int
+	hasWidth bool // True if the optional width operand has been removed from the stack.
+}
+
+// run interprets the charstring `cs`, called as a subroutine at depth `depth`. It returns true if
+// the end of the glyph (endchar) has been reached.
+func (t *type2Charstring) run(cs []byte, depth int) (bool, error) {
+	if depth > maxSubrDepth {
+		return false, errors.New("charstring subroutines nested too deeply")
+	}
+	b := t.b
+	for pos := 0; pos < len(cs); {
+		b0 := cs[pos]
+		switch {
+		case b0 == 28:
+			if pos+3 > len(cs) {
+				return false, errors.New("truncated charstring")
+			}
+			t.stack = append(t.stack, float64(int16(binary.BigEndian.Uint16(cs[pos+1:]))))
+			pos += 3
+			continue
+		case b0 == 255:
+			if pos+5 > len(cs) {
+				return false, errors.New("truncated charstring")
+			}
+			t.stack = append(t.stack, float64(int32(binary.BigEndian.Uint32(cs[pos+1:])))/65536)
+			pos += 5
+			continue
+		case b0 >= 32:
+			v, n, err := readCFFInt(cs[pos:])
+			if err != nil {
+				return false, err
+			}
+			t.stack = append(t.stack, float64(v))
+			pos += n
+			continue
+		}
+
+		pos++
+		op := int(b0)
+		if b0 == 12 {
+			if pos >= len(cs) {
+				return false, errors.New("truncated charstring")
+			}
+			op = 1200 + int(cs[pos])
+			pos++
+		}
+		s := t.stack
+		switch op {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+			s = t.width(len(s)%2 == 1)
+			t.numStems += len(s) / 2
+		case 19, 20: // hintmask, cntrmask
+			// The operands are the values of an implied vstemhm operator.
+			s = t.width(len(s)%2 == 1)
+			t.numStems += len(s) / 2
+			pos += (t.numStems + 7) / 8
+		case 21: // rmoveto
+			s = t.width(len(s) > 2)
+			if len(s) < 2 {
+				return false, errors.New("rmoveto: stack underflow")
+			}
+			b.moveTo(b.x+s[0], b.y+s[1])
+		case 22: // hmoveto
+			s = t.width(len(s) > 1)
+			if len(s) < 1 {
+				return false, errors.New("hmoveto: stack underflow")
+			}
+			b.moveTo(b.x+s[0], b.y)
+		case 4: // vmoveto
+			s = t.width(len(s) > 1)
+			if len(s) < 1 {
+				return false, errors.New("vmoveto: stack underflow")
+			}
+			b.moveTo(b.x, b.y+s[0])
+		case 5: // rlineto
+			for i := 0; i+1 < len(s); i += 2 {
+				b.lineTo(b.x+s[i], b.y+s[i+1])
+			}
+		case 6, 7: // hlineto, vlineto
+			horizontal := op == 6
+			for _, v := range s {
+				if horizontal {
+					b.lineTo(b.x+v, b.y)
+				} else {
+					b.lineTo(b.x, b.y+v)
+				}
+				horizontal = !horizontal
+			}
+		case 8: // rrcurveto
+			for i := 0; i+5 < len(s); i += 6 {
+				t.curve(s[i], s[i+1], s[i+2], s[i+3], s[i+4], s[i+5])
+			}
+		case 24: // rcurveline
+			i := 0
+			for ; i+5 < len(s)-2; i += 6 {
+				t.curve(s[i], s[i+1], s[i+2], s[i+3], s[i+4], s[i+5])
+			}
+			if i+1 < len(s) {
+				b.lineTo(b.x+s[i], b.y+s[i+1])
+			}
+		case 25: // rlinecurve
+			i := 0
+			for ; i+1 < len(s)-6; i += 2 {
+				b.lineTo(b.x+s[i], b.y+s[i+1])
+			}
+			if i+5 < len(s) {
+				t.curve(s[i], s[i+1], s[i+2], s[i+3], s[i+4], s[i+5])
+			}
+		case 26: // vvcurveto
+			dx1 := 0.0
+			if len(s)%2 == 1 {
+				dx1, s = s[0], s[1:]
+			}
+			for i := 0; i+3 < len(s); i += 4 {
+				t.curve(dx1, s[i], s[i+1], s[i+2], 0, s[i+3])
+				dx1 = 0
+			}
+		case 27: // hhcurveto
+			dy1 := 0.0
+			if len(s)%2 == 1 {
+				dy1, s = s[0], s[1:]
+			}
+			for i := 0; i+3 < len(s); i += 4 {
+				t.curve(s[i], dy1, s[i+1], s[i+2], s[i+3], 0)
+				dy1 = 0
+			}
+		case 30, 31: // vhcurveto, hvcurveto
+			horizontal := op == 31
+			for i := 0; i+3 < len(s); i += 4 {
+				last := 0.0
+				if len(s)-i == 5 {
+					last = s[i+4]
+				}
+				if horizontal {
+					t.curve(s[i], 0, s[i+1], s[i+2], last, s[i+3])
+				} else {
+					t.curve(0, s[i], s[i+1], s[i+2], s[i+3], last)
+				}
+				horizontal = !horizontal
+			}
+		case 1235: // flex
+			if len(s) < 13 {
+				return false, errors.New("flex: stack underflow")
+			}
+			t.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+			t.curve(s[6], s[7], s[8], s[9], s[10], s[11])
+		case 1234: // hflex
+			if len(s) < 7 {
+				return false, errors.New("hflex: stack underflow")
+			}
+			t.curve(s[0], 0, s[1], s[2], s[3], 0)
+			t.curve(s[4], 0, s[5], -s[2], s[6], 0)
+		case 1236: // hflex1
+			if len(s) < 9 {
+				return false, errors.New("hflex1: stack underflow")
+			}
+			t.curve(s[0], s[1], s[2], s[3], s[4], 0)
+			t.curve(s[5], 0, s[6], s[7], s[8], -(s[1] + s[3] + s[7]))
+		case 1237: // flex1
+			if len(s) < 11 {
+				return false, errors.New("flex1: stack underflow")
+			}
+			dx, dy := 0.0, 0.0
+			for i := 0; i < 10; i += 2 {
+				dx += s[i]
+				dy += s[i+1]
+			}
+			dx6, dy6 := s[10], -dy
+			if math.Abs(dx) <= math.Abs(dy) {
+				dx6, dy6 = -dx, s[10]
+			}
+			t.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+			t.curve(s[6], s[7], s[8], s[9], dx6, dy6)
+		case 10, 29: // callsubr, callgsubr
+			if len(s) == 0 {
+				return false, errors.New("callsubr: stack underflow")
+			}
+			subrs := t.subrs
+			if op == 29 {
+				subrs = t.gsubrs
+			}
+			i := int(s[len(s)-1]) + cffSubrBias(len(subrs))
+			t.stack = s[:len(s)-1]
+			if i < 0 || i >= len(subrs) {
+				return false, fmt.Errorf("invalid subroutine %d", i)
+			}
+			done, err := t.run(subrs[i], depth+1)
+			if done || err != nil {
+				return done, err
+			}
+			continue
+		case 11: // return
+			return false, nil
+		case 14: // endchar
+			s = t.width(len(s) == 1 || len(s) == 5)
+			if len(s) == 4 {
+				// Accented character, as the seac operator of Type 1 charstrings.
+				return true, t.accented(s[0], s[1], int(s[2]), int(s[3]), depth)
+			}
+			return true, nil
+		default:
+			if !t.arithmetic(op) {
+				return false, fmt.Errorf("unsupported charstring operator %d", op)
+			}
+			continue
+		}
+		t.stack = t.stack[:0]
+	}
+	return false, nil
+}
+
+// width removes the width operand from the bottom of the stack if `hasWidth` is true and the
+// width has not been removed yet. It returns the remaining operands.
+func (t *type2Charstring) width(hasWidth bool) []float64 {
+	if !t.hasWidth {
+		t.hasWidth = true
+		if hasWidth && len(t.stack) > 0 {
+			t.stack = t.stack[1:]
+		}
+	}
+	return t.stack
+}
+
+// curve appends a curve to the glyph path, with points relative to the preceding point.
+func (t *type2Charstring) curve(dx1, dy1, dx2, dy2, dx3, dy3 float64) {
+	b := t.b
+	x1, y1 := b.x+dx1, b.y+dy1
+	x2, y2 := x1+dx2, y1+dy2
+	b.curveTo(x1, y1, x2, y2, x2+dx3, y2+dy3)
+}
+
+// accented draws the base glyph with Standard Encoding code `bchar` and the accent glyph with code
+// `achar` offset by `adx`,`ady`.
+func (t *type2Charstring) accented(adx, ady float64, bchar, achar, depth int) error {
+	base, ok := t.seac(bchar)
+	if !ok {
+		return fmt.Errorf("no base glyph for code %d", bchar)
+	}
+	accent, ok := t.seac(achar)
+	if !ok {
+		return fmt.Errorf("no accent glyph for code %d", achar)
+	}
+	b := t.b
+	for i, cs := range [][]byte{base, accent} {
+		b.closePath()
+		b.x, b.y = 0, 0
+		if i == 1 {
+			b.dx, b.dy = adx, ady
+		}
+		t.stack = t.stack[:0]
+		t.numStems = 0
+		t.hasWidth = false
+		if _, err := t.run(cs, depth+1); err != nil {
+			return err
+		}
+	}
+	b.closePath()
+	b.dx, b.dy = 0, 0
+	return nil
+}
+
+// arithmetic performs the arithmetic or stack operator `op`. It returns false if `op` is not
+// supported.
+func (t *type2Charstring) arithmetic(op int) bool {
+	s := t.stack
+	n := len(s)
+	switch op {
+	case 1209: // abs
+		if n > 0 {
+			s[n-1] = math.Abs(s[n-1])
+		}
+	case 1210, 1211, 1212, 1224: // add, sub, div, mul
+		if n < 2 {
+			return true
+		}
+		a, b := s[n-2], s[n-1]
+		switch op {
+		case 1210:
+			a += b
+		case 1211:
+			a -= b
+		case 1212:
+			if b != 0 {
+				a /= b
+			}
+		case 1224:
+			a *= b
+		}
+		t.stack = append(s[:n-2], a)
+	case 1214: // neg
+		if n > 0 {
+			s[n-1] = -s[n-1]
+		}
+	case 1218: // drop
+		if n > 0 {
+			t.stack = s[:n-1]
+		}
+	case 1226: // sqrt
+		if n > 0 {
+			s[n-1] = math.Sqrt(math.Abs(s[n-1]))
+		}
+	case 1227: // dup
+		if n > 0 {
+			t.stack = append(s, s[n-1])
+		}
+	case 1228: // exch
+		if n > 1 {
+			s[n-2], s[n-1] = s[n-1], s[n-2]
+		}
+	case 1229: // index
+		if n > 0 {
+			i := int(s[n-1])
+			if i < 0 {
+				i = 0
+			}
+			s = s[:n-1]
+			if i < len(s) {
+				t.stack = append(s, s[len(s)-1-i])
+			} else {
+				t.stack = s
+			}
+		}
+	case 1230: // roll
+		if n < 2 {
+			return true
+		}
+		num, j := int(s[n-2]), int(s[n-1])
+		s = s[:n-2]
+		if num > 0 && num <= len(s) {
+			items := s[len(s)-num:]
+			j = ((j % num) + num) % num
+			rolled := append(append([]float64(nil), items[num-j:]...), items[:num-j]...)
+			copy(items, rolled)
+		}
+		t.stack = s
+	case 0, 2, 9, 13, 15, 16, 17: // Reserved operators.
+		t.stack = s[:0]
+	default:
+		return false
+	}
+	return true
+}
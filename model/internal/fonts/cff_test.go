@@ -0,0 +1,233 @@
+package fonts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// cffTestFont is an OpenType font with CFF outlines and the glyphs .notdef, zero, one, Q and
+// uni4E2D.
+const cffTestFont = "CFFTest.otf"
+
+func TestParseCFF(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(fontDir, cffTestFont))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ft, err := TtfParse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ft.CFF || ft.PostScriptName != "CFFTest" {
+		t.Errorf("CFF=%t PostScriptName=%q", ft.CFF, ft.PostScriptName)
+	}
+	if gid := ft.Chars['中']; gid != 4 || ft.GlyphNames[gid] != "uni4E2D" {
+		t.Errorf("gid=%d glyphs=%v", gid, ft.GlyphNames)
+	}
+
+	cff, err := ParseCFF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cff.Name != "CFFTest" || cff.IsCIDKeyed {
+		t.Errorf("%s", cff)
+	}
+	glyphs := []GlyphName{".notdef", "zero", "one", "Q", "uni4E2D"}
+	if !reflect.DeepEqual(cff.GlyphNames, glyphs) {
+		t.Errorf("glyphs=%v", cff.GlyphNames)
+	}
+	if gid, ok := cff.GIDByName("Q"); !ok || gid != 3 {
+		t.Errorf("Q: %d %t", gid, ok)
+	}
+	// The widths of the glyphs are the same as in the "hmtx" table.
+	for gid, w := range cff.Widths {
+		if int(w) != int(ft.Widths[gid]) {
+			t.Errorf("gid=%d: %g != %d", gid, w, ft.Widths[gid])
+		}
+	}
+	if cff.Widths[1] != 600 || cff.Widths[2] != 400 || cff.Widths[3] != 1000 {
+		t.Errorf("widths=%v", cff.Widths)
+	}
+}
+
+func TestSubsetOpenType(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(fontDir, cffTestFont))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft, err := TtfParse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runeToGID := map[rune]GID{'0': ft.Chars['0'], 'Q': ft.Chars['Q']}
+	subset, err := SubsetOpenType(data, []GID{ft.Chars['0'], ft.Chars['Q']}, runeToGID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := tableChecksum(subset); sum != 0xB1B0AFBA {
+		t.Errorf("bad font checksum 0x%08x", sum)
+	}
+
+	st, err := TtfParse(bytes.NewReader(subset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.CFF || len(st.Chars) != 2 || st.Chars['Q'] != ft.Chars['Q'] {
+		t.Errorf("CFF=%t chars=%v", st.CFF, st.Chars)
+	}
+	cff, err := ParseCFF(subset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cff.GlyphNames) != 5 || cff.GlyphNames[3] != "Q" {
+		t.Errorf("glyphs=%v", cff.GlyphNames)
+	}
+
+	// The outlines of the glyphs in the subset are the same as in the original font, the other
+	// glyphs are empty.
+	orig, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := sfnt.Parse(subset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf sfnt.Buffer
+	ppem := fixed.I(1000)
+	for gid := 0; gid < 5; gid++ {
+		segments, err := orig.LoadGlyph(&buf, sfnt.GlyphIndex(gid), ppem, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := append(sfnt.Segments(nil), segments...)
+		got, err := font.LoadGlyph(&buf, sfnt.GlyphIndex(gid), ppem, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gid == 2 || gid == 4 {
+			if len(got) != 0 {
+				t.Errorf("gid=%d: %d segments", gid, len(got))
+			}
+			continue
+		}
+		if len(want) == 0 || !reflect.DeepEqual(want, got) {
+			t.Errorf("gid=%d: outlines differ", gid)
+		}
+	}
+}
+
+// makeCIDKeyedCFF returns a CID-keyed CFF font program with the glyphs of `charStrings`, the CIDs
+// `cids` and a Private DICT with defaultWidthX 300 and nominalWidthX 500.
+func makeCIDKeyedCFF(charStrings [][]byte, cids []uint16) []byte {
+	offset := func(v int) []byte {
+		return []byte{29, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	}
+	topDict := func(charset, fdSelect, charStrings, fdArray int) []byte {
+		var b []byte
+		b = append(b, offset(391)...) // "Adobe"
+		b = append(b, offset(392)...) // "Identity"
+		b = append(b, 139, 12, 30)    // Supplement 0, ROS.
+		b = append(append(b, offset(charset)...), 15)
+		b = append(append(b, offset(charStrings)...), 17)
+		b = append(append(b, offset(fdArray)...), 12, 36)
+		b = append(append(b, offset(fdSelect)...), 12, 37)
+		return b
+	}
+	private := []byte{28, 1, 44, 20, 28, 1, 244, 21} // defaultWidthX 300, nominalWidthX 500.
+
+	var charset []byte
+	charset = append(charset, 0)
+	for _, cid := range cids[1:] {
+		charset = append(charset, byte(cid>>8), byte(cid))
+	}
+	n := len(charStrings)
+	fdSelect := []byte{3, 0, 1, 0, 0, 0, byte(n >> 8), byte(n)}
+
+	head := []byte{1, 0, 4, 4}
+	head = append(head, writeCFFIndex([][]byte{[]byte("CIDTest")})...)
+	pos := len(head) + len(writeCFFIndex([][]byte{topDict(0, 0, 0, 0)}))
+	strs := writeCFFIndex([][]byte{[]byte("Adobe"), []byte("Identity")})
+	pos += len(strs) + 2
+	charsetPos := pos
+	fdSelectPos := charsetPos + len(charset)
+	charStringsPos := fdSelectPos + len(fdSelect)
+	fdArrayPos := charStringsPos + len(writeCFFIndex(charStrings))
+	fontDict := append(append(offset(len(private)), offset(0)...), 18)
+	privatePos := fdArrayPos + len(writeCFFIndex([][]byte{fontDict}))
+	fontDict = append(append(offset(len(private)), offset(privatePos)...), 18)
+
+	b := append(head, writeCFFIndex([][]byte{topDict(charsetPos, fdSelectPos, charStringsPos,
+		fdArrayPos)})...)
+	b = append(b, strs...)
+	b = append(b, 0, 0) // Global Subr INDEX.
+	b = append(b, charset...)
+	b = append(b, fdSelect...)
+	b = append(b, writeCFFIndex(charStrings)...)
+	b = append(b, writeCFFIndex([][]byte{fontDict})...)
+	return append(b, private...)
+}
+
+func TestSubsetCIDKeyedCFF(t *testing.T) {
+	// Glyphs with the default width, a width of 500+50 and a width of 500-20 with a hint.
+	charStrings := [][]byte{{14}, {139 + 50, 14}, {139 - 20, 139, 139 + 10, 1, 14}}
+	cids := []uint16{0, 100, 200}
+	data := makeCIDKeyedCFF(charStrings, cids)
+
+	cff, err := ParseCFF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cff.Name != "CIDTest" || !cff.IsCIDKeyed || cff.GlyphNames != nil {
+		t.Errorf("%s", cff)
+	}
+	if !reflect.DeepEqual(cff.CIDs, cids) {
+		t.Errorf("cids=%v", cff.CIDs)
+	}
+	if want := []float64{300, 550, 480}; !reflect.DeepEqual(cff.Widths, want) {
+		t.Errorf("widths=%v", cff.Widths)
+	}
+
+	subset, err := SubsetCFF(data, []GID{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parseCFFData(subset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.charStrings, [][]byte{{14}, {14}, charStrings[2]}) {
+		t.Errorf("charstrings=%v", f.charStrings)
+	}
+	if !reflect.DeepEqual(f.charset, cids) || len(f.fdPrivates) != 1 ||
+		f.fdPrivates[0].nominalWidth != 500 {
+		t.Errorf("charset=%v", f.charset)
+	}
+}
+
+func TestReadCFFCharset(t *testing.T) {
+	cases := []struct {
+		data []byte
+		want []uint16
+	}{
+		{[]byte{0, 0, 5, 1, 0}, []uint16{0, 5, 256}},
+		{[]byte{1, 0, 5, 1, 1, 0, 0}, []uint16{0, 5, 6, 256}},
+		{[]byte{2, 0, 5, 0, 2}, []uint16{0, 5, 6, 7}},
+	}
+	for _, c := range cases {
+		charset, end, err := readCFFCharset(c.data, 0, len(c.want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(charset, c.want) || end != len(c.data) {
+			t.Errorf("format %d: charset=%v end=%d", c.data[0], charset, end)
+		}
+	}
+}
@@ -0,0 +1,717 @@
+package fonts
+
+import (
+	"encoding/binary"
+)
+
+// OpenType layout tables (GSUB, GPOS and GDEF).
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2
+
+// GSUB lookup types.
+const (
+	gsubSingle       = 1
+	gsubMultiple     = 2
+	gsubAlternate    = 3
+	gsubLigature     = 4
+	gsubContext      = 5
+	gsubChain        = 6
+	gsubExtension    = 7
+	gsubReverseChain = 8
+)
+
+// GPOS lookup types.
+const (
+	gposSingle     = 1
+	gposPair       = 2
+	gposCursive    = 3
+	gposMarkToBase = 4
+	gposMarkToLig  = 5
+	gposMarkToMark = 6
+	gposContext    = 7
+	gposChain      = 8
+	gposExtension  = 9
+)
+
+// Lookup flags.
+const (
+	lookupIgnoreBaseGlyphs    = 0x0002
+	lookupIgnoreLigatures     = 0x0004
+	lookupIgnoreMarks         = 0x0008
+	lookupUseMarkFilteringSet = 0x0010
+	lookupMarkAttachmentType  = 0xFF00
+)
+
+// GDEF glyph classes.
+const (
+	glyphClassBase      = 1
+	glyphClassLigature  = 2
+	glyphClassMark      = 3
+	glyphClassComponent = 4
+)
+
+// maxLookupNesting is the maximum nesting depth of lookups applied by contextual lookups.
+const maxLookupNesting = 8
+
+// otU16 returns the big-endian uint16 at offset `off` of `b`, or 0 if it is out of range.
+func otU16(b []byte, off int) uint16 {
+	if off < 0 || off+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[off:])
+}
+
+// otU32 returns the big-endian uint32 at offset `off` of `b`, or 0 if it is out of range.
+func otU32(b []byte, off int) uint32 {
+	if off < 0 || off+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[off:])
+}
+
+// otSub returns the part of `b` starting at offset `off`, or nil if `off` is zero or out of range.
+func otSub(b []byte, off int) []byte {
+	if off <= 0 || off >= len(b) {
+		return nil
+	}
+	return b[off:]
+}
+
+// otCoverage maps the glyphs of a coverage table to their coverage indexes.
+type otCoverage map[GID]int
+
+// parseCoverage parses the coverage table `b`.
+func parseCoverage(b []byte) otCoverage {
+	cov := make(otCoverage)
+	switch otU16(b, 0) {
+	case 1:
+		n := int(otU16(b, 2))
+		for i := 0; i < n; i++ {
+			cov[GID(otU16(b, 4+2*i))] = i
+		}
+	case 2:
+		n := int(otU16(b, 2))
+		for i := 0; i < n; i++ {
+			off := 4 + 6*i
+			start, end, index := int(otU16(b, off)), int(otU16(b, off+2)), int(otU16(b, off+4))
+			for g := start; g <= end; g++ {
+				cov[GID(g)] = index + g - start
+			}
+		}
+	}
+	return cov
+}
+
+// otClassDef maps glyphs to their classes as defined by a class definition table. Glyphs not in
+// the map have class 0.
+type otClassDef map[GID]uint16
+
+// parseClassDef parses the class definition table `b`.
+func parseClassDef(b []byte) otClassDef {
+	cd := make(otClassDef)
+	switch otU16(b, 0) {
+	case 1:
+		start, n := int(otU16(b, 2)), int(otU16(b, 4))
+		for i := 0; i < n; i++ {
+			cd[GID(start+i)] = otU16(b, 6+2*i)
+		}
+	case 2:
+		n := int(otU16(b, 2))
+		for i := 0; i < n; i++ {
+			off := 4 + 6*i
+			start, end, class := int(otU16(b, off)), int(otU16(b, off+2)), otU16(b, off+4)
+			for g := start; g <= end; g++ {
+				cd[GID(g)] = class
+			}
+		}
+	}
+	return cd
+}
+
+// otGDEF holds the glyph definitions of a GDEF table.
+type otGDEF struct {
+	glyphClasses      otClassDef   // Glyph classes, nil if the font has no GDEF glyph classes.
+	markAttachClasses otClassDef   // Mark attachment classes.
+	markGlyphSets     []otCoverage // Mark glyph sets used for mark filtering.
+}
+
+// parseGDEF parses the GDEF table `b`.
+func parseGDEF(b []byte) otGDEF {
+	var gdef otGDEF
+	if len(b) < 12 {
+		return gdef
+	}
+	if sub := otSub(b, int(otU16(b, 4))); sub != nil {
+		gdef.glyphClasses = parseClassDef(sub)
+	}
+	if sub := otSub(b, int(otU16(b, 10))); sub != nil {
+		gdef.markAttachClasses = parseClassDef(sub)
+	}
+	if otU16(b, 2) >= 2 {
+		if sets := otSub(b, int(otU16(b, 12))); sets != nil {
+			n := int(otU16(sets, 2))
+			for i := 0; i < n; i++ {
+				gdef.markGlyphSets = append(gdef.markGlyphSets,
+					parseCoverage(otSub(sets, int(otU32(sets, 4+4*i)))))
+			}
+		}
+	}
+	return gdef
+}
+
+// otLangSys is a language system of a script: the features used for the language.
+type otLangSys struct {
+	required int   // Index of the required feature, -1 if none.
+	features []int // Feature indexes.
+}
+
+// otFeature is a feature of a GSUB or GPOS table.
+type otFeature struct {
+	tag     string
+	lookups []int // Lookup indexes.
+}
+
+// otLookup is a lookup of a GSUB or GPOS table.
+type otLookup struct {
+	kind      uint16        // Lookup type, extension lookups are resolved to the extended type.
+	flag      uint16        // Lookup flags.
+	markSet   int           // Mark filtering set index, if the flags use a mark filtering set.
+	subtables []interface{} // Parsed subtables of the type given by `kind`.
+}
+
+// otTable is a parsed GSUB or GPOS table.
+type otTable struct {
+	gpos     bool
+	scripts  map[string]otLangSys // Default language systems by script tag.
+	features []otFeature
+	lookups  []*otLookup
+}
+
+// parseOTTable parses the GSUB (`gpos` false) or GPOS (`gpos` true) table `b`.
+func parseOTTable(b []byte, gpos bool) *otTable {
+	if len(b) < 10 {
+		return nil
+	}
+	t := &otTable{gpos: gpos, scripts: make(map[string]otLangSys)}
+
+	scripts := otSub(b, int(otU16(b, 4)))
+	for i, n := 0, int(otU16(scripts, 0)); i < n; i++ {
+		rec := 2 + 6*i
+		if rec+6 > len(scripts) {
+			break
+		}
+		tag := string(scripts[rec : rec+4])
+		script := otSub(scripts, int(otU16(scripts, rec+4)))
+		langSys := otSub(script, int(otU16(script, 0)))
+		if langSys == nil {
+			// Use the first language system if there is no default.
+			if otU16(script, 2) == 0 {
+				continue
+			}
+			langSys = otSub(script, int(otU16(script, 8)))
+		}
+		ls := otLangSys{required: -1}
+		if req := otU16(langSys, 2); req != 0xFFFF {
+			ls.required = int(req)
+		}
+		for k, m := 0, int(otU16(langSys, 4)); k < m; k++ {
+			ls.features = append(ls.features, int(otU16(langSys, 6+2*k)))
+		}
+		t.scripts[tag] = ls
+	}
+
+	features := otSub(b, int(otU16(b, 6)))
+	for i, n := 0, int(otU16(features, 0)); i < n; i++ {
+		rec := 2 + 6*i
+		if rec+6 > len(features) {
+			break
+		}
+		f := otFeature{tag: string(features[rec : rec+4])}
+		feature := otSub(features, int(otU16(features, rec+4)))
+		for k, m := 0, int(otU16(feature, 2)); k < m; k++ {
+			f.lookups = append(f.lookups, int(otU16(feature, 4+2*k)))
+		}
+		t.features = append(t.features, f)
+	}
+
+	lookups := otSub(b, int(otU16(b, 8)))
+	for i, n := 0, int(otU16(lookups, 0)); i < n; i++ {
+		t.lookups = append(t.lookups, t.parseLookup(otSub(lookups, int(otU16(lookups, 2+2*i)))))
+	}
+	return t
+}
+
+// parseLookup parses the lookup table `b`.
+func (t *otTable) parseLookup(b []byte) *otLookup {
+	lk := &otLookup{kind: otU16(b, 0), flag: otU16(b, 2)}
+	n := int(otU16(b, 4))
+	if lk.flag&lookupUseMarkFilteringSet != 0 {
+		lk.markSet = int(otU16(b, 6+2*n))
+	}
+	extension := uint16(gsubExtension)
+	if t.gpos {
+		extension = gposExtension
+	}
+	isExtension := lk.kind == extension
+	for i := 0; i < n; i++ {
+		sub := otSub(b, int(otU16(b, 6+2*i)))
+		kind := lk.kind
+		if isExtension {
+			kind = otU16(sub, 2)
+			sub = otSub(sub, int(otU32(sub, 4)))
+			if kind == extension {
+				continue
+			}
+			lk.kind = kind
+		}
+		var st interface{}
+		if t.gpos {
+			st = parseGPOSSubtable(kind, sub)
+		} else {
+			st = parseGSUBSubtable(kind, sub)
+		}
+		if st != nil {
+			lk.subtables = append(lk.subtables, st)
+		}
+	}
+	return lk
+}
+
+// otSingleSubst is a single substitution subtable (GSUB type 1).
+type otSingleSubst struct {
+	cov    otCoverage
+	delta  int   // Format 1: the delta added to the glyph index.
+	substs []GID // Format 2: the substitutes by coverage index.
+}
+
+// otMultipleSubst is a multiple substitution subtable (GSUB type 2).
+type otMultipleSubst struct {
+	cov  otCoverage
+	seqs [][]GID // The substitute sequences by coverage index.
+}
+
+// otLigature is a ligature of a ligature substitution subtable.
+type otLigature struct {
+	glyph      GID   // The ligature glyph.
+	components []GID // The components following the first component.
+}
+
+// otLigatureSubst is a ligature substitution subtable (GSUB type 4).
+type otLigatureSubst struct {
+	cov  otCoverage
+	sets [][]otLigature // The ligatures starting with the covered glyphs, by coverage index.
+}
+
+// parseGSUBSubtable parses the GSUB subtable `b` of type `kind`. It returns nil for unsupported
+// subtables.
+func parseGSUBSubtable(kind uint16, b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	format := otU16(b, 0)
+	switch kind {
+	case gsubSingle:
+		st := &otSingleSubst{cov: parseCoverage(otSub(b, int(otU16(b, 2))))}
+		switch format {
+		case 1:
+			st.delta = int(int16(otU16(b, 4)))
+		case 2:
+			for i, n := 0, int(otU16(b, 4)); i < n; i++ {
+				st.substs = append(st.substs, GID(otU16(b, 6+2*i)))
+			}
+		default:
+			return nil
+		}
+		return st
+	case gsubMultiple:
+		st := &otMultipleSubst{cov: parseCoverage(otSub(b, int(otU16(b, 2))))}
+		for i, n := 0, int(otU16(b, 4)); i < n; i++ {
+			seq := otSub(b, int(otU16(b, 6+2*i)))
+			var glyphs []GID
+			for k, m := 0, int(otU16(seq, 0)); k < m; k++ {
+				glyphs = append(glyphs, GID(otU16(seq, 2+2*k)))
+			}
+			st.seqs = append(st.seqs, glyphs)
+		}
+		return st
+	case gsubLigature:
+		st := &otLigatureSubst{cov: parseCoverage(otSub(b, int(otU16(b, 2))))}
+		for i, n := 0, int(otU16(b, 4)); i < n; i++ {
+			set := otSub(b, int(otU16(b, 6+2*i)))
+			var ligs []otLigature
+			for k, m := 0, int(otU16(set, 0)); k < m; k++ {
+				lig := otSub(set, int(otU16(set, 2+2*k)))
+				l := otLigature{glyph: GID(otU16(lig, 0))}
+				for c, nc := 1, int(otU16(lig, 2)); c < nc; c++ {
+					l.components = append(l.components, GID(otU16(lig, 2+2*c)))
+				}
+				ligs = append(ligs, l)
+			}
+			st.sets = append(st.sets, ligs)
+		}
+		return st
+	case gsubContext:
+		return parseContext(b)
+	case gsubChain:
+		return parseChainContext(b)
+	}
+	// Alternate substitutions are only used by features that are not applied, reverse chaining
+	// substitutions are not supported.
+	return nil
+}
+
+// otLookupRecord is a lookup applied at a position of the input sequence matched by a contextual
+// lookup.
+type otLookupRecord struct {
+	seqIndex    int
+	lookupIndex int
+}
+
+// otRule is a rule of a contextual lookup subtable in format 1 (glyphs) or 2 (classes).
+type otRule struct {
+	backtrack []uint16 // The backtrack sequence, in reverse logical order.
+	input     []uint16 // The input sequence following the first glyph.
+	lookahead []uint16
+	records   []otLookupRecord
+}
+
+// otContext is a contextual or chained contextual lookup subtable (GSUB types 5 and 6, GPOS types
+// 7 and 8).
+type otContext struct {
+	format int
+	cov    otCoverage // Formats 1 and 2: the coverage of the first input glyph.
+
+	// Format 1: the rules by coverage index. Format 2: the rules by class of the first input glyph.
+	rules [][]otRule
+	// Format 2: the class definitions of the backtrack, input and lookahead sequences.
+	backtrackClasses, inputClasses, lookaheadClasses otClassDef
+
+	// Format 3: the coverages of the sequences, and the lookups to apply.
+	backtrackCov, inputCov, lookaheadCov []otCoverage
+	records                              []otLookupRecord
+}
+
+// parseLookupRecords parses `n` lookup records at the start of `b`.
+func parseLookupRecords(b []byte, n int) []otLookupRecord {
+	records := make([]otLookupRecord, 0, n)
+	for i := 0; i < n; i++ {
+		records = append(records, otLookupRecord{
+			seqIndex:    int(otU16(b, 4*i)),
+			lookupIndex: int(otU16(b, 4*i+2)),
+		})
+	}
+	return records
+}
+
+// parseU16s returns `n` uint16 values at the start of `b`.
+func parseU16s(b []byte, n int) []uint16 {
+	vals := make([]uint16, 0, n)
+	for i := 0; i < n; i++ {
+		vals = append(vals, otU16(b, 2*i))
+	}
+	return vals
+}
+
+// parseCoverages parses `n` coverage tables with offsets relative to `base` listed at the start of
+// `b`.
+func parseCoverages(base, b []byte, n int) []otCoverage {
+	covs := make([]otCoverage, 0, n)
+	for i := 0; i < n; i++ {
+		covs = append(covs, parseCoverage(otSub(base, int(otU16(b, 2*i)))))
+	}
+	return covs
+}
+
+// parseContext parses the contextual lookup subtable `b`.
+func parseContext(b []byte) *otContext {
+	st := &otContext{format: int(otU16(b, 0))}
+	parseRuleSets := func(off int) {
+		for i, n := 0, int(otU16(b, off)); i < n; i++ {
+			set := otSub(b, int(otU16(b, off+2+2*i)))
+			var rules []otRule
+			for k, m := 0, int(otU16(set, 0)); k < m; k++ {
+				rule := otSub(set, int(otU16(set, 2+2*k)))
+				glyphCount, recordCount := int(otU16(rule, 0)), int(otU16(rule, 2))
+				if glyphCount == 0 {
+					continue
+				}
+				rules = append(rules, otRule{
+					input:   parseU16s(otSub(rule, 4), glyphCount-1),
+					records: parseLookupRecords(otSub(rule, 4+2*(glyphCount-1)), recordCount),
+				})
+			}
+			st.rules = append(st.rules, rules)
+		}
+	}
+	switch st.format {
+	case 1:
+		st.cov = parseCoverage(otSub(b, int(otU16(b, 2))))
+		parseRuleSets(4)
+	case 2:
+		st.cov = parseCoverage(otSub(b, int(otU16(b, 2))))
+		st.inputClasses = parseClassDef(otSub(b, int(otU16(b, 4))))
+		parseRuleSets(6)
+	case 3:
+		glyphCount, recordCount := int(otU16(b, 2)), int(otU16(b, 4))
+		st.inputCov = parseCoverages(b, otSub(b, 6), glyphCount)
+		st.records = parseLookupRecords(otSub(b, 6+2*glyphCount), recordCount)
+	default:
+		return nil
+	}
+	return st
+}
+
+// parseChainContext parses the chained contextual lookup subtable `b`.
+func parseChainContext(b []byte) *otContext {
+	st := &otContext{format: int(otU16(b, 0))}
+	parseRuleSets := func(off int) {
+		for i, n := 0, int(otU16(b, off)); i < n; i++ {
+			set := otSub(b, int(otU16(b, off+2+2*i)))
+			var rules []otRule
+			for k, m := 0, int(otU16(set, 0)); k < m; k++ {
+				rule := otSub(set, int(otU16(set, 2+2*k)))
+				var r otRule
+				pos := 0
+				n := int(otU16(rule, pos))
+				r.backtrack = parseU16s(otSub(rule, pos+2), n)
+				pos += 2 + 2*n
+				n = int(otU16(rule, pos))
+				if n == 0 {
+					continue
+				}
+				r.input = parseU16s(otSub(rule, pos+2), n-1)
+				pos += 2 + 2*(n-1)
+				n = int(otU16(rule, pos))
+				r.lookahead = parseU16s(otSub(rule, pos+2), n)
+				pos += 2 + 2*n
+				n = int(otU16(rule, pos))
+				r.records = parseLookupRecords(otSub(rule, pos+2), n)
+				rules = append(rules, r)
+			}
+			st.rules = append(st.rules, rules)
+		}
+	}
+	switch st.format {
+	case 1:
+		st.cov = parseCoverage(otSub(b, int(otU16(b, 2))))
+		parseRuleSets(4)
+	case 2:
+		st.cov = parseCoverage(otSub(b, int(otU16(b, 2))))
+		st.backtrackClasses = parseClassDef(otSub(b, int(otU16(b, 4))))
+		st.inputClasses = parseClassDef(otSub(b, int(otU16(b, 6))))
+		st.lookaheadClasses = parseClassDef(otSub(b, int(otU16(b, 8))))
+		parseRuleSets(10)
+	case 3:
+		pos := 2
+		n := int(otU16(b, pos))
+		st.backtrackCov = parseCoverages(b, otSub(b, pos+2), n)
+		pos += 2 + 2*n
+		n = int(otU16(b, pos))
+		st.inputCov = parseCoverages(b, otSub(b, pos+2), n)
+		pos += 2 + 2*n
+		n = int(otU16(b, pos))
+		st.lookaheadCov = parseCoverages(b, otSub(b, pos+2), n)
+		pos += 2 + 2*n
+		st.records = parseLookupRecords(otSub(b, pos+2), int(otU16(b, pos)))
+	default:
+		return nil
+	}
+	return st
+}
+
+// otValue is a GPOS value record, in font units.
+type otValue struct {
+	xPlacement, yPlacement, xAdvance, yAdvance int
+}
+
+// valueRecordSize returns the size in bytes of value records in format `format`.
+func valueRecordSize(format uint16) int {
+	size := 0
+	for ; format != 0; format >>= 1 {
+		size += 2 * int(format&1)
+	}
+	return size
+}
+
+// parseValueRecord parses the value record in format `format` at the start of `b`. Device tables
+// are ignored.
+func parseValueRecord(b []byte, format uint16) otValue {
+	var v otValue
+	pos := 0
+	read := func(bit uint16) int {
+		if format&bit == 0 {
+			return 0
+		}
+		val := int(int16(otU16(b, pos)))
+		pos += 2
+		return val
+	}
+	v.xPlacement = read(0x0001)
+	v.yPlacement = read(0x0002)
+	v.xAdvance = read(0x0004)
+	v.yAdvance = read(0x0008)
+	return v
+}
+
+// otAnchor is an anchor point of a GPOS table, in font units.
+type otAnchor struct {
+	x, y int
+}
+
+// parseAnchor parses the anchor table `b`. It returns nil if `b` is nil.
+func parseAnchor(b []byte) *otAnchor {
+	if b == nil {
+		return nil
+	}
+	return &otAnchor{x: int(int16(otU16(b, 2))), y: int(int16(otU16(b, 4)))}
+}
+
+// otSinglePos is a single adjustment positioning subtable (GPOS type 1).
+type otSinglePos struct {
+	cov    otCoverage
+	value  otValue   // Format 1: the value of all covered glyphs.
+	values []otValue // Format 2: the values by coverage index.
+}
+
+// otPairPos is a pair adjustment positioning subtable (GPOS type 2).
+type otPairPos struct {
+	format         int
+	cov            otCoverage
+	hasSecondValue bool // The values of the second glyphs are not empty.
+
+	// Format 1: the values of the pairs by coverage index of the first glyph and second glyph.
+	pairs []map[GID][2]otValue
+	// Format 2: the values of the pairs by classes of the first and second glyphs.
+	classes1, classes2 otClassDef
+	classValues        [][][2]otValue
+}
+
+// otMarkRecord is a mark of a mark attachment subtable.
+type otMarkRecord struct {
+	class  int
+	anchor *otAnchor
+}
+
+// otMarkAttach is a mark-to-base, mark-to-ligature or mark-to-mark attachment positioning
+// subtable (GPOS types 4, 5 and 6).
+type otMarkAttach struct {
+	kind    uint16 // The lookup type.
+	markCov otCoverage
+	baseCov otCoverage // The coverage of the base glyphs, ligatures or preceding marks.
+	marks   []otMarkRecord
+	// The anchors of the base glyphs by coverage index, component (one for bases and marks), and
+	// mark class.
+	bases [][][]*otAnchor
+}
+
+// parseGPOSSubtable parses the GPOS subtable `b` of type `kind`. It returns nil for unsupported
+// subtables.
+func parseGPOSSubtable(kind uint16, b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	format := otU16(b, 0)
+	switch kind {
+	case gposSingle:
+		st := &otSinglePos{cov: parseCoverage(otSub(b, int(otU16(b, 2))))}
+		valueFormat := otU16(b, 4)
+		switch format {
+		case 1:
+			st.value = parseValueRecord(otSub(b, 6), valueFormat)
+		case 2:
+			size := valueRecordSize(valueFormat)
+			for i, n := 0, int(otU16(b, 6)); i < n; i++ {
+				st.values = append(st.values, parseValueRecord(otSub(b, 8+size*i), valueFormat))
+			}
+		default:
+			return nil
+		}
+		return st
+	case gposPair:
+		st := &otPairPos{format: int(format), cov: parseCoverage(otSub(b, int(otU16(b, 2))))}
+		format1, format2 := otU16(b, 4), otU16(b, 6)
+		size1, size2 := valueRecordSize(format1), valueRecordSize(format2)
+		st.hasSecondValue = format2 != 0
+		switch format {
+		case 1:
+			for i, n := 0, int(otU16(b, 8)); i < n; i++ {
+				set := otSub(b, int(otU16(b, 10+2*i)))
+				pairs := make(map[GID][2]otValue)
+				for k, m := 0, int(otU16(set, 0)); k < m; k++ {
+					rec := 2 + k*(2+size1+size2)
+					if rec+2+size1+size2 > len(set) {
+						break
+					}
+					pairs[GID(otU16(set, rec))] = [2]otValue{
+						parseValueRecord(set[rec+2:], format1),
+						parseValueRecord(set[rec+2+size1:], format2),
+					}
+				}
+				st.pairs = append(st.pairs, pairs)
+			}
+		case 2:
+			st.classes1 = parseClassDef(otSub(b, int(otU16(b, 8))))
+			st.classes2 = parseClassDef(otSub(b, int(otU16(b, 10))))
+			n1, n2 := int(otU16(b, 12)), int(otU16(b, 14))
+			if 16+n1*n2*(size1+size2) > len(b) {
+				return nil
+			}
+			pos := 16
+			for i := 0; i < n1; i++ {
+				row := make([][2]otValue, n2)
+				for k := 0; k < n2; k++ {
+					row[k] = [2]otValue{
+						parseValueRecord(b[pos:], format1),
+						parseValueRecord(b[pos+size1:], format2),
+					}
+					pos += size1 + size2
+				}
+				st.classValues = append(st.classValues, row)
+			}
+		default:
+			return nil
+		}
+		return st
+	case gposMarkToBase, gposMarkToLig, gposMarkToMark:
+		st := &otMarkAttach{
+			kind:    kind,
+			markCov: parseCoverage(otSub(b, int(otU16(b, 2)))),
+			baseCov: parseCoverage(otSub(b, int(otU16(b, 4)))),
+		}
+		classCount := int(otU16(b, 6))
+		marks := otSub(b, int(otU16(b, 8)))
+		for i, n := 0, int(otU16(marks, 0)); i < n; i++ {
+			st.marks = append(st.marks, otMarkRecord{
+				class:  int(otU16(marks, 2+4*i)),
+				anchor: parseAnchor(otSub(marks, int(otU16(marks, 4+4*i)))),
+			})
+		}
+		// parseAnchors parses the anchor records of a base, ligature component or mark.
+		parseAnchors := func(base []byte, pos int) []*otAnchor {
+			anchors := make([]*otAnchor, classCount)
+			for c := range anchors {
+				anchors[c] = parseAnchor(otSub(base, int(otU16(base, pos+2*c))))
+			}
+			return anchors
+		}
+		bases := otSub(b, int(otU16(b, 10)))
+		for i, n := 0, int(otU16(bases, 0)); i < n; i++ {
+			if kind != gposMarkToLig {
+				st.bases = append(st.bases, [][]*otAnchor{parseAnchors(bases, 2+2*classCount*i)})
+				continue
+			}
+			lig := otSub(bases, int(otU16(bases, 2+2*i)))
+			var components [][]*otAnchor
+			for c, nc := 0, int(otU16(lig, 0)); c < nc; c++ {
+				components = append(components, parseAnchors(lig, 2+2*classCount*c))
+			}
+			st.bases = append(st.bases, components)
+		}
+		return st
+	case gposContext:
+		return parseContext(b)
+	case gposChain:
+		return parseChainContext(b)
+	}
+	// Cursive attachments are not supported.
+	return nil
+}
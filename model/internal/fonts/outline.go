@@ -0,0 +1,372 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// GlyphPath is the outline of a glyph: a sequence of closed subpaths with coordinates in glyph
+// space units, where 1000 units are 1 text space unit.
+type GlyphPath []PathSegment
+
+// PathSegment is a segment of a glyph outline.
+type PathSegment struct {
+	// Op is the PDF path construction operator of the segment: "m", "l", "c" or "h".
+	Op string
+	// Points are the x,y coordinates of the operands of Op: one point for "m" and "l", three for
+	// "c" and none for "h".
+	Points [][2]float64
+}
+
+// pathBuilder builds a GlyphPath from glyph outlines given in font units.
+type pathBuilder struct {
+	path   GlyphPath
+	matrix [6]float64 // Transforms font units to glyph space units.
+	open   bool       // True if the current subpath has not been closed.
+	x, y   float64    // The current point in font units.
+	dx, dy float64    // The offset of the points, used for the accents of accented glyphs.
+}
+
+// newPathBuilder returns a pathBuilder transforming font units to glyph space units with the font
+// matrix `fontMatrix`, which maps font units to text space units.
+func newPathBuilder(fontMatrix [6]float64) *pathBuilder {
+	b := &pathBuilder{}
+	for i, v := range fontMatrix {
+		b.matrix[i] = 1000 * v
+	}
+	return b
+}
+
+// point returns the point `x`,`y` in font units transformed to glyph space units.
+func (b *pathBuilder) point(x, y float64) [2]float64 {
+	m := b.matrix
+	x, y = x+b.dx, y+b.dy
+	return [2]float64{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+}
+
+// moveTo starts a new subpath at `x`,`y`, closing the current subpath.
+func (b *pathBuilder) moveTo(x, y float64) {
+	b.closePath()
+	b.x, b.y = x, y
+	b.path = append(b.path, PathSegment{Op: "m", Points: [][2]float64{b.point(x, y)}})
+	b.open = true
+}
+
+// lineTo appends a line to `x`,`y` to the current subpath.
+func (b *pathBuilder) lineTo(x, y float64) {
+	if !b.open {
+		b.moveTo(b.x, b.y)
+	}
+	b.x, b.y = x, y
+	b.path = append(b.path, PathSegment{Op: "l", Points: [][2]float64{b.point(x, y)}})
+}
+
+// curveTo appends a cubic Bézier curve to `x3`,`y3` with control points `x1`,`y1` and `x2`,`y2`
+// to the current subpath.
+func (b *pathBuilder) curveTo(x1, y1, x2, y2, x3, y3 float64) {
+	if !b.open {
+		b.moveTo(b.x, b.y)
+	}
+	b.x, b.y = x3, y3
+	b.path = append(b.path, PathSegment{Op: "c", Points: [][2]float64{
+		b.point(x1, y1), b.point(x2, y2), b.point(x3, y3),
+	}})
+}
+
+// quadTo appends a quadratic Bézier curve to `x2`,`y2` with control point `x1`,`y1` to the current
+// subpath, as the equivalent cubic curve.
+func (b *pathBuilder) quadTo(x1, y1, x2, y2 float64) {
+	x0, y0 := b.x, b.y
+	b.curveTo(x0+2.0/3.0*(x1-x0), y0+2.0/3.0*(y1-y0), x2+2.0/3.0*(x1-x2), y2+2.0/3.0*(y1-y2), x2, y2)
+}
+
+// closePath closes the current subpath, if any.
+func (b *pathBuilder) closePath() {
+	if b.open {
+		b.path = append(b.path, PathSegment{Op: "h"})
+		b.open = false
+	}
+}
+
+// glyphPath returns the path built by `b`, with the last subpath closed.
+func (b *pathBuilder) glyphPath() GlyphPath {
+	b.closePath()
+	return b.path
+}
+
+// Simple glyph flags (section "glyf" of the TrueType reference manual).
+const (
+	glyphOnCurve    = 0x01
+	glyphXShort     = 0x02
+	glyphYShort     = 0x04
+	glyphRepeat     = 0x08
+	glyphXSameOrPos = 0x10
+	glyphYSameOrPos = 0x20
+)
+
+// Composite glyph flags, in addition to those used for subsetting.
+const compositeArgsAreXYValues = 0x0002
+
+// maxCompositeDepth limits the nesting of composite glyphs.
+const maxCompositeDepth = 8
+
+// TrueTypeOutlines gives access to the glyph outlines of a TrueType font program.
+type TrueTypeOutlines struct {
+	f          *glyphTables
+	unitsPerEm float64
+}
+
+// ttPoint is a point of a TrueType glyph contour.
+type ttPoint struct {
+	x, y    float64
+	onCurve bool
+}
+
+// ParseTrueTypeOutlines returns the glyph outlines of the TrueType font program `data`.
+func ParseTrueTypeOutlines(data []byte) (*TrueTypeOutlines, error) {
+	f, err := readGlyphTables(data)
+	if err != nil {
+		return nil, err
+	}
+	unitsPerEm := binary.BigEndian.Uint16(f.tables["head"][18:])
+	if unitsPerEm == 0 {
+		return nil, errors.New("invalid unitsPerEm")
+	}
+	return &TrueTypeOutlines{f: f, unitsPerEm: float64(unitsPerEm)}, nil
+}
+
+// Outline returns the outline of glyph `gid`. The quadratic curves of the glyph are converted to
+// cubic curves.
+func (t *TrueTypeOutlines) Outline(gid GID) (GlyphPath, error) {
+	contours, err := t.contours(gid, 0)
+	if err != nil {
+		return nil, err
+	}
+	b := newPathBuilder([6]float64{1 / t.unitsPerEm, 0, 0, 1 / t.unitsPerEm, 0, 0})
+	for _, contour := range contours {
+		addTrueTypeContour(b, contour)
+	}
+	return b.glyphPath(), nil
+}
+
+// contours returns the contours of glyph `gid`, nested in composite glyphs at depth `depth`.
+func (t *TrueTypeOutlines) contours(gid GID, depth int) ([][]ttPoint, error) {
+	if int(gid) >= t.f.numGlyphs {
+		return nil, fmt.Errorf("invalid glyph index %d", gid)
+	}
+	glyph := t.f.glyph(gid)
+	if len(glyph) == 0 {
+		return nil, nil
+	}
+	if len(glyph) < 10 {
+		return nil, fmt.Errorf("glyph %d too short", gid)
+	}
+	numContours := int(int16(binary.BigEndian.Uint16(glyph)))
+	if numContours >= 0 {
+		return simpleGlyphContours(glyph, numContours)
+	}
+	if depth >= maxCompositeDepth {
+		return nil, fmt.Errorf("glyph %d nested too deeply", gid)
+	}
+
+	var contours [][]ttPoint
+	for pos := 10; ; {
+		if pos+4 > len(glyph) {
+			return nil, fmt.Errorf("glyph %d truncated", gid)
+		}
+		flags := binary.BigEndian.Uint16(glyph[pos:])
+		component := GID(binary.BigEndian.Uint16(glyph[pos+2:]))
+		pos += 4
+
+		var dx, dy float64
+		if flags&compositeArgsAreWords != 0 {
+			if pos+4 > len(glyph) {
+				return nil, fmt.Errorf("glyph %d truncated", gid)
+			}
+			dx = float64(int16(binary.BigEndian.Uint16(glyph[pos:])))
+			dy = float64(int16(binary.BigEndian.Uint16(glyph[pos+2:])))
+			pos += 4
+		} else {
+			if pos+2 > len(glyph) {
+				return nil, fmt.Errorf("glyph %d truncated", gid)
+			}
+			dx, dy = float64(int8(glyph[pos])), float64(int8(glyph[pos+1]))
+			pos += 2
+		}
+		if flags&compositeArgsAreXYValues == 0 {
+			// The component is positioned by matching points, which is rarely used.
+			dx, dy = 0, 0
+		}
+
+		a, b, c, d := 1.0, 0.0, 0.0, 1.0
+		f2dot14 := func(i int) float64 {
+			return float64(int16(binary.BigEndian.Uint16(glyph[pos+2*i:]))) / 16384
+		}
+		switch {
+		case flags&compositeHaveScale != 0 && pos+2 <= len(glyph):
+			a = f2dot14(0)
+			d = a
+			pos += 2
+		case flags&compositeHaveXYScale != 0 && pos+4 <= len(glyph):
+			a, d = f2dot14(0), f2dot14(1)
+			pos += 4
+		case flags&compositeHaveTwoByTwo != 0 && pos+8 <= len(glyph):
+			a, b, c, d = f2dot14(0), f2dot14(1), f2dot14(2), f2dot14(3)
+			pos += 8
+		}
+
+		componentContours, err := t.contours(component, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, contour := range componentContours {
+			for i, p := range contour {
+				contour[i].x = a*p.x + c*p.y + dx
+				contour[i].y = b*p.x + d*p.y + dy
+			}
+			contours = append(contours, contour)
+		}
+		if flags&compositeMoreComponents == 0 {
+			break
+		}
+	}
+	return contours, nil
+}
+
+// simpleGlyphContours returns the `numContours` contours of the simple glyph with data `glyph`.
+func simpleGlyphContours(glyph []byte, numContours int) ([][]ttPoint, error) {
+	errTruncated := errors.New("glyph truncated")
+	pos := 10
+	if pos+2*numContours+2 > len(glyph) {
+		return nil, errTruncated
+	}
+	endPts := make([]int, numContours)
+	numPoints := 0
+	for i := range endPts {
+		endPts[i] = int(binary.BigEndian.Uint16(glyph[pos+2*i:]))
+		if endPts[i] < numPoints-1 {
+			return nil, errors.New("invalid contour end points")
+		}
+		numPoints = endPts[i] + 1
+	}
+	pos += 2 * numContours
+	pos += 2 + int(binary.BigEndian.Uint16(glyph[pos:])) // Skip the instructions.
+
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if pos >= len(glyph) {
+			return nil, errTruncated
+		}
+		flag := glyph[pos]
+		pos++
+		flags = append(flags, flag)
+		if flag&glyphRepeat != 0 {
+			if pos >= len(glyph) {
+				return nil, errTruncated
+			}
+			for n := int(glyph[pos]); n > 0 && len(flags) < numPoints; n-- {
+				flags = append(flags, flag)
+			}
+			pos++
+		}
+	}
+
+	points := make([]ttPoint, numPoints)
+	readCoords := func(short, sameOrPos byte, set func(p *ttPoint, v float64)) error {
+		v := 0
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if pos >= len(glyph) {
+					return errTruncated
+				}
+				if flag&sameOrPos != 0 {
+					v += int(glyph[pos])
+				} else {
+					v -= int(glyph[pos])
+				}
+				pos++
+			case flag&sameOrPos == 0:
+				if pos+2 > len(glyph) {
+					return errTruncated
+				}
+				v += int(int16(binary.BigEndian.Uint16(glyph[pos:])))
+				pos += 2
+			}
+			set(&points[i], float64(v))
+		}
+		return nil
+	}
+	if err := readCoords(glyphXShort, glyphXSameOrPos, func(p *ttPoint, v float64) { p.x = v }); err != nil {
+		return nil, err
+	}
+	if err := readCoords(glyphYShort, glyphYSameOrPos, func(p *ttPoint, v float64) { p.y = v }); err != nil {
+		return nil, err
+	}
+	for i, flag := range flags {
+		points[i].onCurve = flag&glyphOnCurve != 0
+	}
+
+	contours := make([][]ttPoint, numContours)
+	start := 0
+	for i, end := range endPts {
+		contours[i] = points[start : end+1]
+		start = end + 1
+	}
+	return contours, nil
+}
+
+// addTrueTypeContour adds the TrueType glyph contour `contour`, made of quadratic curves, to `b`.
+// Two consecutive off-curve points have an implied on-curve point midway between them.
+func addTrueTypeContour(b *pathBuilder, contour []ttPoint) {
+	n := len(contour)
+	if n == 0 {
+		return
+	}
+	// Start the contour at an on-curve point.
+	first := -1
+	for i, p := range contour {
+		if p.onCurve {
+			first = i
+			break
+		}
+	}
+	var start ttPoint
+	if first < 0 {
+		p, q := contour[0], contour[1%n]
+		start = ttPoint{x: (p.x + q.x) / 2, y: (p.y + q.y) / 2, onCurve: true}
+		first = 1
+	} else {
+		start = contour[first]
+		first++
+	}
+	b.moveTo(start.x, start.y)
+
+	var control *ttPoint
+	for i := 0; i < n; i++ {
+		p := contour[(first+i)%n]
+		if i == n-1 && p == start {
+			break
+		}
+		switch {
+		case p.onCurve && control == nil:
+			b.lineTo(p.x, p.y)
+		case p.onCurve:
+			b.quadTo(control.x, control.y, p.x, p.y)
+			control = nil
+		case control != nil:
+			mx, my := (control.x+p.x)/2, (control.y+p.y)/2
+			b.quadTo(control.x, control.y, mx, my)
+			p := p
+			control = &p
+		default:
+			p := p
+			control = &p
+		}
+	}
+	if control != nil {
+		b.quadTo(control.x, control.y, start.x, start.y)
+	}
+	b.closePath()
+}
@@ -0,0 +1,235 @@
+package fonts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// outlinePoints returns the sorted end points of the segments of `path`, in font units of a font
+// with `unitsPerEm` units per em.
+func outlinePoints(path GlyphPath, unitsPerEm float64) [][2]int {
+	var points [][2]int
+	for _, seg := range path {
+		if len(seg.Points) == 0 {
+			continue
+		}
+		p := seg.Points[len(seg.Points)-1]
+		points = append(points, [2]int{
+			int(math.Round(p[0] * unitsPerEm / 1000)),
+			int(math.Round(p[1] * unitsPerEm / 1000)),
+		})
+	}
+	return sortedPoints(points)
+}
+
+// sfntPoints returns the sorted end points of the segments of the outline of glyph `gid` of the
+// font `data`, as loaded by the sfnt package.
+func sfntPoints(t *testing.T, data []byte, gid GID) [][2]int {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unitsPerEm := f.UnitsPerEm()
+	segments, err := f.LoadGlyph(nil, sfnt.GlyphIndex(gid), fixed.I(int(unitsPerEm)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var points [][2]int
+	for _, seg := range segments {
+		n := map[sfnt.SegmentOp]int{
+			sfnt.SegmentOpMoveTo: 1, sfnt.SegmentOpLineTo: 1, sfnt.SegmentOpQuadTo: 2, sfnt.SegmentOpCubeTo: 3,
+		}[seg.Op]
+		p := seg.Args[n-1]
+		// The y axis of the sfnt package points down.
+		points = append(points, [2]int{p.X.Round(), -p.Y.Round()})
+	}
+	return sortedPoints(points)
+}
+
+// samePoints returns true if `a` and `b` have the same number of points and the points of `a` are
+// within 1 unit of points of `b`, allowing for the rounding of fractional coordinates.
+func samePoints(a, b [][2]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, p := range a {
+		found := false
+		for _, q := range b {
+			if math.Abs(float64(p[0]-q[0])) <= 1 && math.Abs(float64(p[1]-q[1])) <= 1 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedPoints returns the distinct points of `points`, sorted.
+func sortedPoints(points [][2]int) [][2]int {
+	seen := make(map[[2]int]bool)
+	var distinct [][2]int
+	for _, p := range points {
+		if !seen[p] {
+			seen[p] = true
+			distinct = append(distinct, p)
+		}
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		if distinct[i][0] != distinct[j][0] {
+			return distinct[i][0] < distinct[j][0]
+		}
+		return distinct[i][1] < distinct[j][1]
+	})
+	return distinct
+}
+
+func TestTrueTypeOutlines(t *testing.T) {
+	for _, c := range casesTTFParse {
+		t.Run(c.path, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join(fontDir, c.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ft, err := TtfParse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			outlines, err := ParseTrueTypeOutlines(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range testRunes {
+				gid := ft.Chars[r]
+				path, err := outlines.Outline(gid)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(path) == 0 || path[0].Op != "m" || path[len(path)-1].Op != "h" {
+					t.Fatalf("rune %q: invalid path %v", r, path)
+				}
+				got := outlinePoints(path, float64(ft.UnitsPerEm))
+				if want := sfntPoints(t, data, gid); !samePoints(got, want) {
+					t.Errorf("rune %q: points %v, want %v", r, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCFFOutline(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(fontDir, cffTestFont))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cff, err := ParseCFF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for gid := range cff.Widths {
+		path, err := cff.Outline(GID(gid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := outlinePoints(path, 1000)
+		if want := sfntPoints(t, data, GID(gid)); !samePoints(got, want) {
+			t.Errorf("glyph %d: points %v, want %v", gid, got, want)
+		}
+	}
+}
+
+// encryptType1 returns `data` encrypted with the Type 1 encryption key `r`, preceded by 4 bytes.
+func encryptType1(data []byte, r int) []byte {
+	var encrypted []byte
+	for _, p := range append([]byte{1, 2, 3, 4}, data...) {
+		c := p ^ byte(r>>8)
+		r = ((int(c)+r)*52845 + 22719) & 0xffff
+		encrypted = append(encrypted, c)
+	}
+	return encrypted
+}
+
+// type1Number returns the Type 1 charstring encoding of `v`.
+func type1Number(v int) []byte {
+	switch {
+	case v >= -107 && v <= 107:
+		return []byte{byte(v + 139)}
+	case v >= 108 && v <= 1131:
+		return []byte{byte((v-108)/256 + 247), byte((v - 108) % 256)}
+	}
+	return []byte{255, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func TestType1Outline(t *testing.T) {
+	// The glyph A is a 100 unit square with a left sidebearing of 50 units. Its right side is drawn
+	// by a subroutine.
+	var cs []byte
+	for _, item := range [][]int{
+		{50, 500}, {13}, // hsbw
+		{0, 0}, {21}, // rmoveto
+		{100, 0}, {5}, // rlineto
+		{0}, {10}, // callsubr
+		{9}, {14}, // closepath endchar
+	} {
+		if len(item) == 1 && item[0] != 0 {
+			cs = append(cs, byte(item[0]))
+			continue
+		}
+		for _, v := range item {
+			cs = append(cs, type1Number(v)...)
+		}
+	}
+	subr := append(append(type1Number(0), type1Number(100)...), 5, 11) // rlineto return
+	notdef := append(append(type1Number(0), type1Number(500)...), 13, 14)
+
+	var private bytes.Buffer
+	private.WriteString("dup /Private 8 dict dup begin /lenIV 4 def\n/Subrs 1 array\n")
+	writeEntry := func(prefix string, data []byte, suffix string) {
+		encrypted := encryptType1(data, 4330)
+		private.WriteString(prefix + " " + strconv.Itoa(len(encrypted)) + " RD ")
+		private.Write(encrypted)
+		private.WriteString(" " + suffix + "\n")
+	}
+	writeEntry("dup 0", subr, "NP")
+	private.WriteString("ND\n2 index /CharStrings 2 dict dup begin\n")
+	writeEntry("/.notdef", notdef, "ND")
+	writeEntry("/A", cs, "ND")
+	private.WriteString("end\nend\nmark currentfile closefile\n")
+
+	segment1 := []byte("%!PS-AdobeFont-1.0: Test\n/FontMatrix [0.002 0 0 0.002 0 0] readonly def\n" +
+		"currentfile eexec\n")
+	font, err := ParseType1(segment1, encryptType1(private.Bytes(), 55665))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !font.HasGlyph("A") || font.HasGlyph("B") {
+		t.Errorf("HasGlyph A=%t B=%t", font.HasGlyph("A"), font.HasGlyph("B"))
+	}
+	path, err := font.Outline("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GlyphPath{
+		{Op: "m", Points: [][2]float64{{100, 0}}},
+		{Op: "l", Points: [][2]float64{{300, 0}}},
+		{Op: "l", Points: [][2]float64{{300, 200}}},
+		{Op: "h"},
+	}
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path %v, want %v", path, want)
+	}
+	if path, err := font.Outline(".notdef"); err != nil || len(path) != 0 {
+		t.Errorf(".notdef: path %v err=%v", path, err)
+	}
+}
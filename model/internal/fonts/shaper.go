@@ -0,0 +1,756 @@
+package fonts
+
+import (
+	"sort"
+	"unicode"
+)
+
+// Shaper shapes text with the OpenType layout tables of a TrueType or OpenType font. It maps the
+// runes of the text to glyphs with the glyph substitutions (GSUB) of the font features for the
+// script of the text, e.g. contextual forms, ligatures and conjuncts, and positions the glyphs
+// with the glyph positioning (GPOS) features, e.g. kerning and mark positioning.
+//
+// Arabic, Hebrew, Devanagari and Thai text is shaped with the script specific features and
+// reordering. Other scripts are shaped with the common features.
+type Shaper struct {
+	chars  map[rune]GID
+	widths []uint16
+	gdef   otGDEF
+	gsub   *otTable
+	gpos   *otTable
+}
+
+// ShapedGlyph is a glyph of shaped text.
+type ShapedGlyph struct {
+	// GID is the glyph index.
+	GID GID
+	// Cluster is the index of the first rune of the text represented by the glyph. Glyphs
+	// representing the same runes, e.g. a ligature and its marks, have the same cluster.
+	Cluster int
+	// XAdvance is the horizontal advance of the glyph, in font units.
+	XAdvance int
+	// XOffset and YOffset are the offsets of the glyph from its pen position, in font units.
+	XOffset, YOffset int
+}
+
+// NewShaper returns a Shaper for the TrueType or OpenType font `ttf` with font program `data`.
+// Fonts without layout tables can be shaped: their glyphs are the glyphs of the runes, in visual
+// order.
+func NewShaper(ttf *TtfType, data []byte) (*Shaper, error) {
+	tables, err := readTableDirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	s := &Shaper{
+		chars:  ttf.Chars,
+		widths: ttf.Widths,
+		gdef:   parseGDEF(tables["GDEF"]),
+		gsub:   parseOTTable(tables["GSUB"], false),
+		gpos:   parseOTTable(tables["GPOS"], true),
+	}
+	return s, nil
+}
+
+// glyphInfo is a glyph of the text being shaped.
+type glyphInfo struct {
+	gid     GID
+	r       rune   // The rune the glyph was mapped from, 0 for glyphs from substitutions.
+	cluster int    // The index of the first rune represented by the glyph.
+	mask    uint32 // The features applied to the glyph.
+	class   uint16 // GDEF glyph class.
+
+	ligID   int // Ligatures and the marks within them have the same ligature id.
+	ligComp int // The component of the ligature that a mark belongs to, 1 based.
+
+	xAdvance, xOffset, yOffset int
+	attach                     int // The index of the glyph a mark is attached to, -1 if none.
+	attachDx, attachDy         int // The offset of the mark from the glyph it is attached to.
+}
+
+// Feature masks. maskGlobal is set for all glyphs, the other masks select the glyphs for the
+// script specific features.
+const (
+	maskGlobal uint32 = 1 << iota
+	maskIsol
+	maskFina
+	maskMedi
+	maskInit
+	maskRphf
+	maskHalf
+	maskBlwf
+	maskPstf
+)
+
+// shapeBuffer holds the glyphs of a run of text in a single script while it is shaped.
+type shapeBuffer struct {
+	s         *Shaper
+	glyphs    []glyphInfo
+	nextLigID int
+}
+
+// Shape shapes the run of text `text` with direction `rtl`, i.e. text that is laid out in a
+// single direction. It returns the glyphs in visual order, from left to right.
+func (s *Shaper) Shape(text []rune, rtl bool) []ShapedGlyph {
+	runs := splitScripts(text)
+	if rtl {
+		// The glyphs of each run are in visual order, the runs are in logical order.
+		for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+			runs[i], runs[j] = runs[j], runs[i]
+		}
+	}
+	var shaped []ShapedGlyph
+	for _, run := range runs {
+		sh := scriptShapers[run.script]
+		shaped = append(shaped, s.shapeRun(sh, text, run.start, run.end, rtl)...)
+	}
+	return shaped
+}
+
+// shapeRun shapes the run text[start:end] of script `sh` and returns its glyphs in visual order,
+// from right to left if `rtl` is true.
+func (s *Shaper) shapeRun(sh *scriptShaper, text []rune, start, end int, rtl bool) []ShapedGlyph {
+	b := &shapeBuffer{s: s}
+	for i := start; i < end; i++ {
+		b.glyphs = append(b.glyphs, glyphInfo{r: text[i], cluster: i, mask: maskGlobal, attach: -1})
+	}
+	if sh.prepare != nil {
+		sh.prepare(b)
+	}
+	for i := range b.glyphs {
+		g := &b.glyphs[i]
+		g.gid = s.chars[g.r]
+		b.setClass(g)
+	}
+
+	if s.gsub != nil {
+		b.applyFeatures(s.gsub, sh.tags, sh.gsubStages, sh.mask)
+	}
+	for i := range b.glyphs {
+		g := &b.glyphs[i]
+		g.xAdvance = s.advance(g.gid)
+	}
+	if s.gpos != nil {
+		b.applyFeatures(s.gpos, sh.tags, gposStages, sh.mask)
+	}
+	if sh.zeroMarks {
+		for i := range b.glyphs {
+			if g := &b.glyphs[i]; g.class == glyphClassMark {
+				g.xAdvance = 0
+			}
+		}
+	}
+
+	// Order the glyphs visually. The attachments refer to the visual positions.
+	glyphs := b.glyphs
+	n := len(glyphs)
+	if rtl {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			glyphs[i], glyphs[j] = glyphs[j], glyphs[i]
+		}
+		for i := range glyphs {
+			if glyphs[i].attach >= 0 {
+				glyphs[i].attach = n - 1 - glyphs[i].attach
+			}
+		}
+	}
+	pen := make([]int, n)
+	for i := 1; i < n; i++ {
+		pen[i] = pen[i-1] + glyphs[i-1].xAdvance
+	}
+	// Marks are attached to preceding glyphs, in logical order.
+	for k := 0; k < n; k++ {
+		i := k
+		if rtl {
+			i = n - 1 - k
+		}
+		g := &glyphs[i]
+		if a := g.attach; a >= 0 && a < n {
+			g.xOffset = pen[a] + glyphs[a].xOffset + g.attachDx - pen[i]
+			g.yOffset = glyphs[a].yOffset + g.attachDy
+		}
+	}
+
+	shaped := make([]ShapedGlyph, n)
+	for i, g := range glyphs {
+		shaped[i] = ShapedGlyph{
+			GID:      g.gid,
+			Cluster:  g.cluster,
+			XAdvance: g.xAdvance,
+			XOffset:  g.xOffset,
+			YOffset:  g.yOffset,
+		}
+	}
+	return shaped
+}
+
+// advance returns the advance width of glyph `gid` in font units.
+func (s *Shaper) advance(gid GID) int {
+	if int(gid) < len(s.widths) {
+		return int(s.widths[gid])
+	}
+	return 0
+}
+
+// hasGlyph returns true if the font has a glyph for rune `r`.
+func (s *Shaper) hasGlyph(r rune) bool {
+	gid, ok := s.chars[r]
+	return ok && gid != 0
+}
+
+// VerticalSubstitutions returns the vertical forms of the glyphs of the font, as substituted by
+// the single substitutions of the "vert" and "vrt2" GSUB features, e.g. the rotated forms of
+// brackets and the forms of small kana for vertical writing. The substitutions of all scripts
+// are returned, as the glyphs of vertical text are not shaped in runs of a single script.
+func (s *Shaper) VerticalSubstitutions() map[GID]GID {
+	substs := make(map[GID]GID)
+	if s.gsub == nil {
+		return substs
+	}
+	for _, f := range s.gsub.features {
+		if f.tag != "vert" && f.tag != "vrt2" {
+			continue
+		}
+		for _, li := range f.lookups {
+			if li >= len(s.gsub.lookups) {
+				continue
+			}
+			for _, st := range s.gsub.lookups[li].subtables {
+				single, ok := st.(*otSingleSubst)
+				if !ok {
+					continue
+				}
+				for gid, idx := range single.cov {
+					if _, ok := substs[gid]; ok {
+						continue
+					}
+					if single.substs == nil {
+						substs[gid] = GID(int(gid) + single.delta)
+					} else if idx < len(single.substs) {
+						substs[gid] = single.substs[idx]
+					}
+				}
+			}
+		}
+	}
+	return substs
+}
+
+// hasFeature returns true if the GSUB table has feature `tag` for the first script of `tags`
+// present in the table.
+func (s *Shaper) hasFeature(tags []string, tag string) bool {
+	if s.gsub == nil {
+		return false
+	}
+	ls, ok := s.gsub.langSys(tags)
+	if !ok {
+		return false
+	}
+	for _, fi := range ls.features {
+		if fi < len(s.gsub.features) && s.gsub.features[fi].tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// setClass sets the glyph class of `g`. Fonts without GDEF glyph classes get the classes from the
+// Unicode general category of the glyphs' runes.
+func (b *shapeBuffer) setClass(g *glyphInfo) {
+	if cd := b.s.gdef.glyphClasses; cd != nil {
+		g.class = cd[g.gid]
+		return
+	}
+	if g.r != 0 {
+		g.class = glyphClassBase
+		if unicode.In(g.r, unicode.Mn, unicode.Me) {
+			g.class = glyphClassMark
+		}
+	}
+}
+
+// langSys returns the language system for the first script of `tags` present in the table.
+func (t *otTable) langSys(tags []string) (otLangSys, bool) {
+	for _, tag := range tags {
+		if ls, ok := t.scripts[tag]; ok {
+			return ls, true
+		}
+	}
+	for _, tag := range []string{"DFLT", "dflt", "latn"} {
+		if ls, ok := t.scripts[tag]; ok {
+			return ls, true
+		}
+	}
+	return otLangSys{}, false
+}
+
+// applyFeatures applies the lookups of the features of table `t` in `stages` for the first script
+// of `tags` present in the table. The lookups of the features of a stage are applied in the
+// order of the lookup list to the glyphs with a mask given by `mask` for the feature.
+func (b *shapeBuffer) applyFeatures(t *otTable, tags []string, stages [][]string,
+	mask func(tag string) uint32) {
+	ls, ok := t.langSys(tags)
+	if !ok {
+		return
+	}
+	for s, stage := range stages {
+		lookups := make(map[int]uint32)
+		add := func(fi int, m uint32) {
+			if fi < 0 || fi >= len(t.features) {
+				return
+			}
+			for _, li := range t.features[fi].lookups {
+				lookups[li] |= m
+			}
+		}
+		if s == 0 && ls.required >= 0 {
+			add(ls.required, maskGlobal)
+		}
+		for _, fi := range ls.features {
+			if fi >= len(t.features) {
+				continue
+			}
+			tag := t.features[fi].tag
+			for _, f := range stage {
+				if f == tag {
+					add(fi, mask(tag))
+				}
+			}
+		}
+		indexes := make([]int, 0, len(lookups))
+		for li := range lookups {
+			indexes = append(indexes, li)
+		}
+		sort.Ints(indexes)
+		for _, li := range indexes {
+			if li < len(t.lookups) {
+				b.applyLookup(t, t.lookups[li], lookups[li])
+			}
+		}
+	}
+}
+
+// ignored returns true if glyph `g` is skipped by lookup `lk`.
+func (b *shapeBuffer) ignored(lk *otLookup, g *glyphInfo) bool {
+	switch g.class {
+	case glyphClassBase:
+		return lk.flag&lookupIgnoreBaseGlyphs != 0
+	case glyphClassLigature:
+		return lk.flag&lookupIgnoreLigatures != 0
+	case glyphClassMark:
+		if lk.flag&lookupIgnoreMarks != 0 {
+			return true
+		}
+		gdef := &b.s.gdef
+		if lk.flag&lookupUseMarkFilteringSet != 0 {
+			if lk.markSet < len(gdef.markGlyphSets) {
+				_, ok := gdef.markGlyphSets[lk.markSet][g.gid]
+				return !ok
+			}
+			return false
+		}
+		if class := lk.flag & lookupMarkAttachmentType; class != 0 {
+			return class>>8 != gdef.markAttachClasses[g.gid]
+		}
+	}
+	return false
+}
+
+// next returns the index of the first glyph after `i` that is not skipped by lookup `lk`, or -1.
+func (b *shapeBuffer) next(lk *otLookup, i int) int {
+	for j := i + 1; j < len(b.glyphs); j++ {
+		if !b.ignored(lk, &b.glyphs[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// prev returns the index of the last glyph before `i` that is not skipped by lookup `lk`, or -1.
+func (b *shapeBuffer) prev(lk *otLookup, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if !b.ignored(lk, &b.glyphs[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// applyLookup applies lookup `lk` of table `t` to the glyphs with a mask matching `mask`.
+func (b *shapeBuffer) applyLookup(t *otTable, lk *otLookup, mask uint32) {
+	for i := 0; i < len(b.glyphs); {
+		if b.glyphs[i].mask&mask == 0 || b.ignored(lk, &b.glyphs[i]) {
+			i++
+			continue
+		}
+		n := len(b.glyphs)
+		next, ok := b.applyAt(t, lk, i, 0)
+		if !ok || (next <= i && len(b.glyphs) >= n) {
+			next = i + 1
+		}
+		i = next
+	}
+}
+
+// applyAt applies the first matching subtable of lookup `lk` of table `t` at glyph `i`. It
+// returns the index of the glyph following the glyphs processed and true if a subtable was
+// applied.
+func (b *shapeBuffer) applyAt(t *otTable, lk *otLookup, i, depth int) (int, bool) {
+	for _, st := range lk.subtables {
+		switch st := st.(type) {
+		case *otSingleSubst:
+			if b.applySingleSubst(st, i) {
+				return i + 1, true
+			}
+		case *otMultipleSubst:
+			if next, ok := b.applyMultipleSubst(st, i); ok {
+				return next, true
+			}
+		case *otLigatureSubst:
+			if b.applyLigatureSubst(lk, st, i) {
+				return i + 1, true
+			}
+		case *otContext:
+			if next, ok := b.applyContext(t, lk, st, i, depth); ok {
+				return next, true
+			}
+		case *otSinglePos:
+			if b.applySinglePos(st, i) {
+				return i + 1, true
+			}
+		case *otPairPos:
+			if next, ok := b.applyPairPos(lk, st, i); ok {
+				return next, true
+			}
+		case *otMarkAttach:
+			if b.applyMarkAttach(lk, st, i) {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// applySingleSubst applies single substitution `st` at glyph `i`.
+func (b *shapeBuffer) applySingleSubst(st *otSingleSubst, i int) bool {
+	g := &b.glyphs[i]
+	idx, ok := st.cov[g.gid]
+	if !ok {
+		return false
+	}
+	if st.substs == nil {
+		g.gid = GID(int(g.gid) + st.delta)
+	} else if idx < len(st.substs) {
+		g.gid = st.substs[idx]
+	} else {
+		return false
+	}
+	b.setClass(g)
+	return true
+}
+
+// applyMultipleSubst applies multiple substitution `st` at glyph `i`.
+func (b *shapeBuffer) applyMultipleSubst(st *otMultipleSubst, i int) (int, bool) {
+	g := b.glyphs[i]
+	idx, ok := st.cov[g.gid]
+	if !ok || idx >= len(st.seqs) {
+		return 0, false
+	}
+	seq := st.seqs[idx]
+	glyphs := make([]glyphInfo, 0, len(b.glyphs)+len(seq)-1)
+	glyphs = append(glyphs, b.glyphs[:i]...)
+	for k, gid := range seq {
+		ng := g
+		ng.gid = gid
+		if k > 0 {
+			ng.r = 0
+		}
+		b.setClass(&ng)
+		glyphs = append(glyphs, ng)
+	}
+	glyphs = append(glyphs, b.glyphs[i+1:]...)
+	b.glyphs = glyphs
+	return i + len(seq), true
+}
+
+// applyLigatureSubst applies ligature substitution `st` of lookup `lk` at glyph `i`.
+func (b *shapeBuffer) applyLigatureSubst(lk *otLookup, st *otLigatureSubst, i int) bool {
+	idx, ok := st.cov[b.glyphs[i].gid]
+	if !ok || idx >= len(st.sets) {
+		return false
+	}
+	for _, lig := range st.sets[idx] {
+		positions := []int{i}
+		j := i
+		for _, c := range lig.components {
+			if j = b.next(lk, j); j < 0 || b.glyphs[j].gid != c {
+				break
+			}
+			positions = append(positions, j)
+		}
+		if len(positions) != len(lig.components)+1 {
+			continue
+		}
+
+		b.nextLigID++
+		id := b.nextLigID
+		last := positions[len(positions)-1]
+		comp := 1
+		removed := make(map[int]bool, len(positions))
+		for k := i + 1; k <= last; k++ {
+			if comp < len(positions) && positions[comp] == k {
+				removed[k] = true
+				if b.glyphs[k].cluster < b.glyphs[i].cluster {
+					b.glyphs[i].cluster = b.glyphs[k].cluster
+				}
+				comp++
+				continue
+			}
+			// Skipped marks belong to the preceding component.
+			b.glyphs[k].ligID = id
+			b.glyphs[k].ligComp = comp
+		}
+		g := &b.glyphs[i]
+		g.gid = lig.glyph
+		g.r = 0
+		g.ligID = id
+		g.ligComp = 0
+		if b.s.gdef.glyphClasses != nil {
+			b.setClass(g)
+		} else {
+			g.class = glyphClassLigature
+		}
+
+		glyphs := b.glyphs[:i+1]
+		for k := i + 1; k < len(b.glyphs); k++ {
+			if !removed[k] {
+				glyphs = append(glyphs, b.glyphs[k])
+			}
+		}
+		b.glyphs = glyphs
+		return true
+	}
+	return false
+}
+
+// applyContext applies contextual lookup `st` of lookup `lk` of table `t` at glyph `i`.
+func (b *shapeBuffer) applyContext(t *otTable, lk *otLookup, st *otContext, i, depth int) (int, bool) {
+	gid := b.glyphs[i].gid
+	var positions []int
+	var records []otLookupRecord
+	switch st.format {
+	case 1, 2:
+		idx, ok := st.cov[gid]
+		if !ok {
+			return 0, false
+		}
+		var backtrack, input, lookahead otClassDef
+		if st.format == 2 {
+			backtrack, input, lookahead = st.backtrackClasses, st.inputClasses, st.lookaheadClasses
+			idx = int(input[gid])
+		}
+		if idx >= len(st.rules) {
+			return 0, false
+		}
+		// matcher returns a function matching glyphs with the values of a rule's sequence.
+		matcher := func(classes otClassDef, values []uint16) func(k int, gid GID) bool {
+			return func(k int, gid GID) bool {
+				if st.format == 1 {
+					return uint16(gid) == values[k]
+				}
+				return classes[gid] == values[k]
+			}
+		}
+		for _, rule := range st.rules[idx] {
+			positions = b.matchContext(lk, i,
+				len(rule.backtrack), matcher(backtrack, rule.backtrack),
+				len(rule.input), matcher(input, rule.input),
+				len(rule.lookahead), matcher(lookahead, rule.lookahead))
+			if positions != nil {
+				records = rule.records
+				break
+			}
+		}
+	case 3:
+		if len(st.inputCov) == 0 {
+			return 0, false
+		}
+		if _, ok := st.inputCov[0][gid]; !ok {
+			return 0, false
+		}
+		matcher := func(covs []otCoverage) func(k int, gid GID) bool {
+			return func(k int, gid GID) bool {
+				_, ok := covs[k][gid]
+				return ok
+			}
+		}
+		positions = b.matchContext(lk, i,
+			len(st.backtrackCov), matcher(st.backtrackCov),
+			len(st.inputCov)-1, matcher(st.inputCov[1:]),
+			len(st.lookaheadCov), matcher(st.lookaheadCov))
+		records = st.records
+	}
+	if positions == nil {
+		return 0, false
+	}
+
+	if depth < maxLookupNesting {
+		for _, rec := range records {
+			if rec.seqIndex >= len(positions) || rec.lookupIndex >= len(t.lookups) {
+				continue
+			}
+			p := positions[rec.seqIndex]
+			if p < 0 || p >= len(b.glyphs) {
+				continue
+			}
+			nested := t.lookups[rec.lookupIndex]
+			if b.ignored(nested, &b.glyphs[p]) {
+				continue
+			}
+			n := len(b.glyphs)
+			b.applyAt(t, nested, p, depth+1)
+			if delta := len(b.glyphs) - n; delta != 0 {
+				for k := rec.seqIndex + 1; k < len(positions); k++ {
+					positions[k] += delta
+				}
+			}
+		}
+	}
+	next := positions[len(positions)-1] + 1
+	if next > len(b.glyphs) {
+		next = len(b.glyphs)
+	}
+	return next, true
+}
+
+// matchContext matches the glyphs around glyph `i` with a contextual rule: the `nb` glyphs of the
+// backtrack sequence before `i`, the `ni` input glyphs following `i` and the `nl` lookahead
+// glyphs following the input. The match functions are called with the index in the sequence and
+// the glyph. It returns the positions of the input glyphs, including `i`, or nil if the rule does
+// not match.
+func (b *shapeBuffer) matchContext(lk *otLookup, i int,
+	nb int, backtrack func(int, GID) bool,
+	ni int, input func(int, GID) bool,
+	nl int, lookahead func(int, GID) bool) []int {
+	positions := []int{i}
+	j := i
+	for k := 0; k < ni; k++ {
+		if j = b.next(lk, j); j < 0 || !input(k, b.glyphs[j].gid) {
+			return nil
+		}
+		positions = append(positions, j)
+	}
+	for k := 0; k < nl; k++ {
+		if j = b.next(lk, j); j < 0 || !lookahead(k, b.glyphs[j].gid) {
+			return nil
+		}
+	}
+	j = i
+	for k := 0; k < nb; k++ {
+		if j = b.prev(lk, j); j < 0 || !backtrack(k, b.glyphs[j].gid) {
+			return nil
+		}
+	}
+	return positions
+}
+
+// adjust adds the values of value record `v` to the position of glyph `g`.
+func (g *glyphInfo) adjust(v otValue) {
+	g.xOffset += v.xPlacement
+	g.yOffset += v.yPlacement
+	g.xAdvance += v.xAdvance
+}
+
+// applySinglePos applies single adjustment `st` at glyph `i`.
+func (b *shapeBuffer) applySinglePos(st *otSinglePos, i int) bool {
+	g := &b.glyphs[i]
+	idx, ok := st.cov[g.gid]
+	if !ok {
+		return false
+	}
+	if st.values == nil {
+		g.adjust(st.value)
+	} else if idx < len(st.values) {
+		g.adjust(st.values[idx])
+	}
+	return true
+}
+
+// applyPairPos applies pair adjustment `st` of lookup `lk` at glyph `i` and the following glyph.
+func (b *shapeBuffer) applyPairPos(lk *otLookup, st *otPairPos, i int) (int, bool) {
+	idx, ok := st.cov[b.glyphs[i].gid]
+	if !ok {
+		return 0, false
+	}
+	j := b.next(lk, i)
+	if j < 0 {
+		return 0, false
+	}
+	var values [2]otValue
+	switch st.format {
+	case 1:
+		if idx >= len(st.pairs) {
+			return 0, false
+		}
+		if values, ok = st.pairs[idx][b.glyphs[j].gid]; !ok {
+			return 0, false
+		}
+	case 2:
+		c1, c2 := int(st.classes1[b.glyphs[i].gid]), int(st.classes2[b.glyphs[j].gid])
+		if c1 >= len(st.classValues) || c2 >= len(st.classValues[c1]) {
+			return 0, false
+		}
+		values = st.classValues[c1][c2]
+	}
+	b.glyphs[i].adjust(values[0])
+	b.glyphs[j].adjust(values[1])
+	if st.hasSecondValue {
+		return j + 1, true
+	}
+	return j, true
+}
+
+// applyMarkAttach applies mark attachment `st` of lookup `lk` to the mark glyph `i`.
+func (b *shapeBuffer) applyMarkAttach(lk *otLookup, st *otMarkAttach, i int) bool {
+	g := &b.glyphs[i]
+	idx, ok := st.markCov[g.gid]
+	if !ok || idx >= len(st.marks) {
+		return false
+	}
+	mark := st.marks[idx]
+
+	j := -1
+	if st.kind == gposMarkToMark {
+		j = b.prev(lk, i)
+		if j < 0 || b.glyphs[j].class != glyphClassMark {
+			return false
+		}
+	} else {
+		for j = i - 1; j >= 0 && b.glyphs[j].class == glyphClassMark; j-- {
+		}
+		if j < 0 {
+			return false
+		}
+	}
+	base := &b.glyphs[j]
+	bidx, ok := st.baseCov[base.gid]
+	if !ok || bidx >= len(st.bases) || len(st.bases[bidx]) == 0 {
+		return false
+	}
+	components := st.bases[bidx]
+	comp := 0
+	if st.kind == gposMarkToLig {
+		comp = len(components) - 1
+		if g.ligID == base.ligID && g.ligComp > 0 && g.ligComp <= len(components) {
+			comp = g.ligComp - 1
+		}
+	}
+	anchors := components[comp]
+	if mark.class >= len(anchors) || anchors[mark.class] == nil || mark.anchor == nil {
+		return false
+	}
+	anchor := anchors[mark.class]
+	g.attach = j
+	g.attachDx = anchor.x - mark.anchor.x
+	g.attachDy = anchor.y - mark.anchor.y
+	return true
+}
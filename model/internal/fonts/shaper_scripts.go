@@ -0,0 +1,582 @@
+package fonts
+
+import (
+	"unicode"
+)
+
+// scriptShaper describes how text of a script is shaped.
+type scriptShaper struct {
+	tags       []string            // OpenType script tags, in order of preference.
+	gsubStages [][]string          // GSUB features applied in stages.
+	mask       func(string) uint32 // Mask of the glyphs a feature is applied to.
+	prepare    func(*shapeBuffer)  // Script specific processing of the runes, may be nil.
+	zeroMarks  bool                // Marks have zero advance widths.
+}
+
+// Scripts with specific shaping.
+const (
+	scriptCommon = iota // Runes that take the script of the surrounding text.
+	scriptDefault
+	scriptArabic
+	scriptHebrew
+	scriptDevanagari
+	scriptThai
+)
+
+// Feature stages of the common features.
+var (
+	commonGSUBStage = []string{"ccmp", "locl", "rlig", "calt", "clig", "liga", "rclt"}
+	gposStages      = [][]string{{"kern", "mark", "mkmk", "dist", "abvm", "blwm"}}
+)
+
+// OpenType script tags of the scripts with specific shaping, in order of preference.
+var (
+	arabicTags     = []string{"arab"}
+	devanagariTags = []string{"dev2", "deva"}
+)
+
+// globalMask returns the mask of features applied to all glyphs.
+func globalMask(string) uint32 {
+	return maskGlobal
+}
+
+// scriptShapers are the shapers by script.
+var scriptShapers = map[int]*scriptShaper{
+	scriptDefault: {
+		tags:       []string{"latn"},
+		gsubStages: [][]string{commonGSUBStage},
+		mask:       globalMask,
+		zeroMarks:  true,
+	},
+	scriptHebrew: {
+		tags:       []string{"hebr"},
+		gsubStages: [][]string{commonGSUBStage},
+		mask:       globalMask,
+		zeroMarks:  true,
+	},
+	scriptThai: {
+		tags:       []string{"thai"},
+		gsubStages: [][]string{commonGSUBStage},
+		mask:       globalMask,
+		prepare:    prepareThai,
+		zeroMarks:  true,
+	},
+	scriptArabic: {
+		tags: arabicTags,
+		gsubStages: [][]string{
+			{"ccmp", "locl"},
+			{"isol"}, {"fina"}, {"fin2"}, {"fin3"}, {"medi"}, {"med2"}, {"init"},
+			{"rlig"}, {"calt"},
+			{"clig", "liga", "rclt", "mset"},
+		},
+		mask:      arabicMask,
+		prepare:   prepareArabic,
+		zeroMarks: true,
+	},
+	scriptDevanagari: {
+		tags: devanagariTags,
+		gsubStages: [][]string{
+			{"locl", "ccmp"},
+			{"nukt"}, {"akhn"}, {"rphf"}, {"rkrf"}, {"pref"}, {"blwf"}, {"abvf"}, {"half"},
+			{"pstf"}, {"vatu"}, {"cjct"},
+			{"init", "pres", "abvs", "blws", "psts", "haln"},
+			{"calt", "clig", "liga", "rclt"},
+		},
+		mask:    indicMask,
+		prepare: prepareIndic,
+	},
+}
+
+// runeScript returns the script of rune `r`.
+func runeScript(r rune) int {
+	switch {
+	case r < 0x80:
+		if unicode.IsLetter(r) {
+			return scriptDefault
+		}
+		return scriptCommon
+	case unicode.Is(unicode.Inherited, r) || unicode.Is(unicode.Common, r):
+		return scriptCommon
+	case unicode.Is(unicode.Arabic, r):
+		return scriptArabic
+	case unicode.Is(unicode.Hebrew, r):
+		return scriptHebrew
+	case unicode.Is(unicode.Devanagari, r):
+		return scriptDevanagari
+	case unicode.Is(unicode.Thai, r):
+		return scriptThai
+	}
+	return scriptDefault
+}
+
+// scriptRun is a part of a text in a single script.
+type scriptRun struct {
+	start, end int
+	script     int
+}
+
+// splitScripts splits `text` in runs of a single script. Runes common to several scripts, e.g.
+// spaces, punctuation and combining marks, belong to the run of the preceding text.
+func splitScripts(text []rune) []scriptRun {
+	var runs []scriptRun
+	for i, r := range text {
+		script := runeScript(r)
+		if len(runs) == 0 {
+			runs = append(runs, scriptRun{start: i, end: i + 1, script: script})
+			continue
+		}
+		last := &runs[len(runs)-1]
+		switch {
+		case script == scriptCommon || script == last.script:
+			last.end = i + 1
+		case last.script == scriptCommon:
+			last.end = i + 1
+			last.script = script
+		default:
+			runs = append(runs, scriptRun{start: i, end: i + 1, script: script})
+		}
+	}
+	for i := range runs {
+		if runs[i].script == scriptCommon {
+			runs[i].script = scriptDefault
+		}
+	}
+	return runs
+}
+
+// Arabic joining types.
+const (
+	joinNone        = iota // U: non-joining.
+	joinRight              // R: joins with the preceding character only.
+	joinDual               // D: joins with the preceding and following characters.
+	joinCausing            // C: joins with both sides, e.g. tatweel.
+	joinTransparent        // T: marks do not affect joining.
+)
+
+// arabicRightJoining lists the runes of the Arabic block with right joining (R) type.
+var arabicRightJoining = []struct{ lo, hi rune }{
+	{0x0622, 0x0625}, {0x0627, 0x0627}, {0x0629, 0x0629}, {0x062F, 0x0632}, {0x0648, 0x0648},
+	{0x0671, 0x0673}, {0x0675, 0x0677}, {0x0688, 0x0699}, {0x06C0, 0x06C0}, {0x06C3, 0x06CB},
+	{0x06CD, 0x06CD}, {0x06CF, 0x06CF}, {0x06D2, 0x06D3}, {0x06D5, 0x06D5}, {0x06EE, 0x06EF},
+	{0x0759, 0x075B}, {0x076B, 0x076C}, {0x0771, 0x0771}, {0x0773, 0x0774}, {0x0778, 0x0779},
+	{0x08AA, 0x08AC}, {0x08AE, 0x08AE}, {0x08B1, 0x08B2}, {0x08B9, 0x08B9},
+}
+
+// arabicJoiningType returns the joining type of rune `r` (ArabicShaping.txt).
+func arabicJoiningType(r rune) int {
+	switch {
+	case r == 0x200D || r == 0x0640 || r == 0x07FA:
+		return joinCausing
+	case r == 0x200C:
+		return joinNone
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return joinTransparent
+	}
+	for _, rng := range arabicRightJoining {
+		if r >= rng.lo && r <= rng.hi {
+			return joinRight
+		}
+	}
+	switch {
+	case r == 0x0621 || r == 0x0674 || r == 0x06D5 || r == 0x06FD || r == 0x06FE || r == 0x08AD:
+		return joinNone
+	case r >= 0x0620 && r <= 0x064A, r >= 0x066E && r <= 0x06D3, r >= 0x06FA && r <= 0x06FF,
+		r >= 0x0750 && r <= 0x077F, r >= 0x08A0 && r <= 0x08BD:
+		return joinDual
+	}
+	return joinNone
+}
+
+// arabicMask returns the mask of the glyphs that the Arabic feature `tag` is applied to.
+func arabicMask(tag string) uint32 {
+	switch tag {
+	case "isol":
+		return maskIsol
+	case "fina", "fin2", "fin3":
+		return maskFina
+	case "medi", "med2":
+		return maskMedi
+	case "init":
+		return maskInit
+	}
+	return maskGlobal
+}
+
+// Arabic joining forms, the indexes of the forms in arabicForms.
+const (
+	formIsol = iota
+	formFina
+	formInit
+	formMedi
+	formNone
+)
+
+// prepareArabic determines the joining forms of the Arabic letters and sets the masks of the
+// positional features. Fonts without positional features are shaped with the Arabic
+// Presentation Forms of the letters, if the font has glyphs for them.
+func prepareArabic(b *shapeBuffer) {
+	glyphs := b.glyphs
+	forms := make([]int, len(glyphs))
+	prev := -1 // The previous non-transparent letter.
+	for i := range glyphs {
+		forms[i] = formNone
+		jt := arabicJoiningType(glyphs[i].r)
+		if jt == joinTransparent {
+			continue
+		}
+		if jt != joinNone {
+			forms[i] = formIsol
+		}
+		if prev >= 0 {
+			pt := arabicJoiningType(glyphs[prev].r)
+			if (pt == joinDual || pt == joinCausing) &&
+				(jt == joinDual || jt == joinRight || jt == joinCausing) {
+				// The previous letter joins with this one.
+				switch forms[prev] {
+				case formIsol:
+					forms[prev] = formInit
+				case formFina:
+					forms[prev] = formMedi
+				}
+				forms[i] = formFina
+			}
+		}
+		prev = i
+	}
+	masks := [...]uint32{maskIsol, maskFina, maskInit, maskMedi}
+	for i := range glyphs {
+		if forms[i] != formNone && arabicJoiningType(glyphs[i].r) != joinCausing {
+			glyphs[i].mask |= masks[forms[i]]
+		}
+	}
+
+	if b.s.hasFeature(arabicTags, "init") || b.s.hasFeature(arabicTags, "fina") ||
+		b.s.hasFeature(arabicTags, "medi") {
+		return
+	}
+	b.glyphs = arabicFallback(b.s, glyphs, forms)
+}
+
+// arabicForms maps the Arabic letters to their isolated, final, initial and medial presentation
+// forms, 0 if a form does not exist.
+var arabicForms = make(map[rune][4]rune)
+
+// arabicLamAlef are the isolated and final forms of the ligatures of lam with the alef letters.
+var arabicLamAlef = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+func init() {
+	// The presentation forms of the letters U+0621-U+064A are consecutive in the Arabic
+	// Presentation Forms-B block, starting with U+FE80.
+	form := rune(0xFE80)
+	for r := rune(0x0621); r <= 0x064A; r++ {
+		if r >= 0x063B && r <= 0x0640 {
+			continue
+		}
+		n := 4
+		switch {
+		case r == 0x0621:
+			n = 1
+		case r == 0x0649 || arabicJoiningType(r) == joinRight:
+			n = 2
+		}
+		var forms [4]rune
+		for k := 0; k < n; k++ {
+			forms[k] = form + rune(k)
+		}
+		arabicForms[r] = forms
+		form += rune(n)
+	}
+}
+
+// arabicFallback substitutes the Arabic letters of `glyphs` with their presentation forms
+// `forms`, for fonts without positional features.
+func arabicFallback(s *Shaper, glyphs []glyphInfo, forms []int) []glyphInfo {
+	out := make([]glyphInfo, 0, len(glyphs))
+	for i := 0; i < len(glyphs); i++ {
+		g := glyphs[i]
+		if forms[i] == formNone {
+			out = append(out, g)
+			continue
+		}
+		if g.r == 0x0644 && (forms[i] == formInit || forms[i] == formMedi) {
+			// Lam followed by alef.
+			if j := i + 1; j < len(glyphs) {
+				if lig, ok := arabicLamAlef[glyphs[j].r]; ok {
+					r := lig[0]
+					if forms[i] == formMedi {
+						r = lig[1]
+					}
+					if s.hasGlyph(r) {
+						g.r = r
+						out = append(out, g)
+						i = j
+						continue
+					}
+				}
+			}
+		}
+		if f := arabicForms[g.r][forms[i]]; f != 0 && s.hasGlyph(f) {
+			g.r = f
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// prepareThai decomposes sara am into nikhahit and sara aa. Nikhahit is moved before the tone
+// marks preceding sara am.
+func prepareThai(b *shapeBuffer) {
+	const (
+		saraAm    = 0x0E33
+		nikhahit  = 0x0E4D
+		saraAa    = 0x0E32
+		toneFirst = 0x0E48
+		toneLast  = 0x0E4B
+	)
+	if !b.s.hasGlyph(nikhahit) || !b.s.hasGlyph(saraAa) {
+		return
+	}
+	var out []glyphInfo
+	for _, g := range b.glyphs {
+		if g.r != saraAm {
+			out = append(out, g)
+			continue
+		}
+		k := len(out)
+		for k > 0 && out[k-1].r >= toneFirst && out[k-1].r <= toneLast {
+			k--
+		}
+		nk := g
+		nk.r = nikhahit
+		out = append(out, glyphInfo{})
+		copy(out[k+1:], out[k:])
+		out[k] = nk
+		aa := g
+		aa.r = saraAa
+		out = append(out, aa)
+	}
+	b.glyphs = out
+}
+
+// Indic character categories.
+const (
+	indicOther = iota
+	indicConsonant
+	indicVowel
+	indicNukta
+	indicHalant
+	indicMatra
+	indicPreMatra
+	indicPostMatra
+	indicModifier
+	indicZWJ
+	indicZWNJ
+)
+
+// Devanagari letters used for reordering.
+const (
+	devaRa     = 0x0930
+	devaHalant = 0x094D
+)
+
+// indicCategory returns the Indic category of Devanagari rune `r`.
+func indicCategory(r rune) int {
+	switch {
+	case r >= 0x0915 && r <= 0x0939, r >= 0x0958 && r <= 0x095F, r >= 0x0978 && r <= 0x097F,
+		r == 0x25CC, r == 0x00A0:
+		return indicConsonant
+	case r >= 0x0904 && r <= 0x0914, r >= 0x0960 && r <= 0x0961, r >= 0x0972 && r <= 0x0977:
+		return indicVowel
+	case r == 0x093C:
+		return indicNukta
+	case r == devaHalant:
+		return indicHalant
+	case r == 0x093F || r == 0x094E:
+		return indicPreMatra
+	case r == 0x093E || r == 0x0940 || (r >= 0x0949 && r <= 0x094C) || r == 0x094F:
+		return indicPostMatra
+	case r == 0x093A || r == 0x093B || (r >= 0x0941 && r <= 0x0948) || (r >= 0x0955 && r <= 0x0957) ||
+		r == 0x0962 || r == 0x0963:
+		return indicMatra
+	case r >= 0x0900 && r <= 0x0903, r >= 0x0951 && r <= 0x0954:
+		return indicModifier
+	case r == 0x200D:
+		return indicZWJ
+	case r == 0x200C:
+		return indicZWNJ
+	}
+	return indicOther
+}
+
+// indicMask returns the mask of the glyphs that the Indic feature `tag` is applied to.
+func indicMask(tag string) uint32 {
+	switch tag {
+	case "rphf":
+		return maskRphf
+	case "half":
+		return maskHalf
+	case "blwf", "abvf", "pref":
+		return maskBlwf
+	case "pstf":
+		return maskPstf
+	case "init":
+		return maskInit
+	}
+	return maskGlobal
+}
+
+// prepareIndic splits Devanagari text in syllables and reorders the syllables for the GSUB
+// features: the pre-base matras are moved to the start of the syllable and the reph (ra +
+// halant at the start of a syllable) after the base consonant. The masks of the glyphs select
+// the half forms before the base consonant and the below-base and post-base forms after it.
+func prepareIndic(b *shapeBuffer) {
+	glyphs := b.glyphs
+	cat := func(i int) int {
+		if i >= len(glyphs) {
+			return -1
+		}
+		return indicCategory(glyphs[i].r)
+	}
+	hasReph := b.s.hasFeature(devanagariTags, "rphf")
+
+	var out []glyphInfo
+	for i := 0; i < len(glyphs); {
+		start := i
+		switch cat(i) {
+		case indicConsonant:
+			// Consonants joined by halants, the last consonant is followed by an optional
+			// halant, matras and modifiers.
+			for {
+				i++
+				if cat(i) == indicNukta {
+					i++
+				}
+				if cat(i) != indicHalant {
+					break
+				}
+				j := i + 1
+				if c := cat(j); c == indicZWJ || c == indicZWNJ {
+					j++
+				}
+				if cat(j) != indicConsonant {
+					break
+				}
+				i = j
+			}
+		case indicVowel:
+			i++
+			if cat(i) == indicNukta {
+				i++
+			}
+		default:
+			i++
+		}
+		for {
+			c := cat(i)
+			if c == indicHalant || c == indicNukta || c == indicMatra || c == indicPreMatra ||
+				c == indicPostMatra || c == indicModifier || c == indicZWJ || c == indicZWNJ {
+				i++
+				continue
+			}
+			break
+		}
+		syllable := glyphs[start:i]
+		for k := range syllable {
+			syllable[k].cluster = glyphs[start].cluster
+		}
+		if cat(start) == indicConsonant {
+			syllable = reorderIndicSyllable(syllable, hasReph)
+		}
+		out = append(out, syllable...)
+	}
+	b.glyphs = out
+}
+
+// reorderIndicSyllable reorders the Devanagari consonant syllable `syllable` and sets the masks
+// of its glyphs. The reph is formed if `hasReph` is true.
+func reorderIndicSyllable(syllable []glyphInfo, hasReph bool) []glyphInfo {
+	n := len(syllable)
+	cat := func(i int) int {
+		return indicCategory(syllable[i].r)
+	}
+	var consonants []int
+	for i := range syllable {
+		if cat(i) == indicConsonant {
+			consonants = append(consonants, i)
+		}
+	}
+
+	// Ra + halant at the start of a syllable with more consonants is a reph.
+	reph := hasReph && len(consonants) > 1 && syllable[0].r == devaRa && n > 2 &&
+		syllable[1].r == devaHalant && cat(2) != indicZWJ
+	first := 0
+	if reph {
+		first = 2
+		consonants = consonants[1:]
+	}
+	// The base consonant is the last consonant, unless it is ra following a halant, which takes
+	// its below-base form.
+	base := consonants[len(consonants)-1]
+	if len(consonants) > 1 && syllable[base].r == devaRa && syllable[base-1].r == devaHalant {
+		base = consonants[len(consonants)-2]
+	}
+
+	// The end of the consonant part of the syllable, where matras and modifiers start.
+	end := base + 1
+	for end < n {
+		c := cat(end)
+		if c != indicNukta && c != indicHalant && c != indicConsonant && c != indicZWJ &&
+			c != indicZWNJ {
+			break
+		}
+		end++
+	}
+
+	var out, preMatras []glyphInfo
+	for i := end; i < n; i++ {
+		if cat(i) == indicPreMatra {
+			preMatras = append(preMatras, syllable[i])
+		}
+	}
+	out = append(out, preMatras...)
+	for i := first; i < end; i++ {
+		g := syllable[i]
+		switch {
+		case i < base:
+			g.mask |= maskHalf
+		case i > base:
+			g.mask |= maskBlwf | maskPstf
+		}
+		out = append(out, g)
+	}
+	rephDone := !reph
+	for i := end; i < n; i++ {
+		c := cat(i)
+		if c == indicPreMatra {
+			continue
+		}
+		if !rephDone && (c == indicPostMatra || c == indicModifier) {
+			out = appendReph(out, syllable)
+			rephDone = true
+		}
+		out = append(out, syllable[i])
+	}
+	if !rephDone {
+		out = appendReph(out, syllable)
+	}
+	return out
+}
+
+// appendReph appends the reph glyphs (ra + halant) at the start of `syllable` to `out`.
+func appendReph(out, syllable []glyphInfo) []glyphInfo {
+	for _, g := range syllable[:2] {
+		g.mask |= maskRphf
+		out = append(out, g)
+	}
+	return out
+}
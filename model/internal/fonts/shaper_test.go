@@ -0,0 +1,72 @@
+package fonts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// shaperFontPath is a font with OpenType layout tables for Devanagari and Hebrew.
+const shaperFontPath = "../../../creator/testdata/FreeSans.ttf"
+
+func TestShaper(t *testing.T) {
+	data, err := ioutil.ReadFile(shaperFontPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttf, err := TtfParse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper, err := NewShaper(&ttf, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		text   string
+		rtl    bool
+		glyphs []string
+	}{
+		// Kerned Latin text is not substituted.
+		{"AVA", false, []string{"A", "V", "A"}},
+		// Conjunct ligature.
+		{"क्ष", false, []string{"uni0915_uni094D_uni0937.akhn"}},
+		// Half form of a dead consonant.
+		{"नमस्ते", false, []string{"uni0928", "uni092E", "uni0938_uni094D.half", "uni0924", "uni0947"}},
+		// Reph is moved after the base consonant.
+		{"र्म", false, []string{"uni092E", "uni0930_uni094D.rphf"}},
+		// Pre-base matra is moved before the syllable.
+		{"र्कि", false, []string{"uni093F", "uni0915", "uni0930_uni094D.rphf"}},
+		// Right-to-left text is returned in visual order.
+		{"של", true, []string{"afii57676", "afii57689"}},
+	}
+	for _, c := range cases {
+		t.Run(c.text, func(t *testing.T) {
+			shaped := shaper.Shape([]rune(c.text), c.rtl)
+			var names []string
+			for _, g := range shaped {
+				names = append(names, string(ttf.GlyphNames[g.GID]))
+			}
+			if len(names) != len(c.glyphs) {
+				t.Fatalf("glyphs %v, expected %v", names, c.glyphs)
+			}
+			for i := range names {
+				if names[i] != c.glyphs[i] {
+					t.Fatalf("glyphs %v, expected %v", names, c.glyphs)
+				}
+			}
+		})
+	}
+
+	// Kerning reduces the advance of the first A.
+	shaped := shaper.Shape([]rune("AVA"), false)
+	if shaped[0].XAdvance >= shaped[2].XAdvance {
+		t.Errorf("AVA not kerned: %+v", shaped)
+	}
+	// The vowel sign of a conjunct belongs to its cluster.
+	shaped = shaper.Shape([]rune("नमस्ते"), false)
+	if c := shaped[len(shaped)-1].Cluster; c != 2 {
+		t.Errorf("cluster of vowel sign %d", c)
+	}
+}
@@ -0,0 +1,112 @@
+package fonts
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// TrueTypeHash returns a hash of the font-wide tables of the TrueType font program `data`, which
+// SubsetTrueType copies from the original font. Subsets of the same font have the same hash, and
+// the glyphs with the same index in them are the same glyphs.
+func TrueTypeHash(data []byte) (string, error) {
+	f, err := readGlyphTables(data)
+	if err != nil {
+		return "", err
+	}
+	h := md5.New()
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment.
+	binary.BigEndian.PutUint16(head[50:], 0) // indexToLocFormat.
+	h.Write(head)
+	hhea := append([]byte(nil), f.tables["hhea"]...)
+	binary.BigEndian.PutUint16(hhea[34:], 0) // numberOfHMetrics.
+	h.Write(hhea)
+	for _, tag := range ttfSubsetTables {
+		h.Write([]byte(tag))
+		h.Write(f.tables[tag])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MergeTrueType returns a TrueType font program with the glyphs of the TrueType font programs
+// `programs`, which are subsets of the same font with the glyph indices of the font (see
+// TrueTypeHash). The "cmap" table maps the runes of the "cmap" tables of `programs`.
+// An error is returned if glyphs with the same index or the glyphs of the same rune differ.
+func MergeTrueType(programs [][]byte) ([]byte, error) {
+	if len(programs) == 0 {
+		return nil, fmt.Errorf("no font programs")
+	}
+	var hash string
+	var fonts []*glyphTables
+	numGlyphs := 0
+	for i, data := range programs {
+		h, err := TrueTypeHash(data)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			hash = h
+		} else if h != hash {
+			return nil, fmt.Errorf("font program %d is not a subset of the same font", i)
+		}
+		f, err := readGlyphTables(data)
+		if err != nil {
+			return nil, err
+		}
+		fonts = append(fonts, f)
+		if f.numGlyphs > numGlyphs {
+			numGlyphs = f.numGlyphs
+		}
+	}
+
+	// A glyph is in a subset if it has outlines or an advance width, e.g. the space glyph.
+	keep := make([]bool, numGlyphs)
+	glyphs := make([][]byte, numGlyphs)
+	hmetrics := make([][2]uint16, numGlyphs)
+	keep[0] = true
+	for _, f := range fonts {
+		for gid := 0; gid < f.numGlyphs; gid++ {
+			glyph := f.glyph(GID(gid))
+			advance, lsb, err := horizontalMetrics(f.tables["hmtx"], f.numHMetrics, gid)
+			if err != nil {
+				return nil, err
+			}
+			if len(glyph) == 0 && advance == 0 {
+				continue
+			}
+			metrics := [2]uint16{advance, lsb}
+			if keep[gid] && glyphs[gid] != nil {
+				if !bytes.Equal(glyph, glyphs[gid]) || metrics != hmetrics[gid] {
+					return nil, fmt.Errorf("glyph %d differs", gid)
+				}
+				continue
+			}
+			keep[gid] = true
+			glyphs[gid] = glyph
+			hmetrics[gid] = metrics
+		}
+	}
+
+	runeToGID := make(map[rune]GID)
+	for _, data := range programs {
+		ttf, err := TtfParse(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		for r, gid := range ttf.Chars {
+			if gid0, ok := runeToGID[r]; ok && gid0 != gid {
+				return nil, fmt.Errorf("rune %+q maps to glyphs %d and %d", r, gid0, gid)
+			}
+			if int(gid) >= len(keep) {
+				return nil, fmt.Errorf("rune %+q maps to invalid glyph %d", r, gid)
+			}
+			// Empty glyphs without advance widths are in the subsets they are mapped in.
+			keep[gid] = true
+			runeToGID[r] = gid
+		}
+	}
+	return buildTrueType(fonts[0].tables, keep, glyphs, hmetrics, runeToGID), nil
+}
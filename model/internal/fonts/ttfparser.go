@@ -33,6 +33,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"sort"
@@ -97,6 +98,9 @@ type TtfType struct {
 	CapHeight              int16
 	// Widths is a list of glyph widths indexed by GID.
 	Widths []uint16
+	// Heights is a list of glyph vertical advances indexed by GID, from the "vmtx" table. It is
+	// nil for fonts without vertical metrics.
+	Heights []uint16
 
 	// Chars maps rune values (unicode) to GIDs (the indexes in GlyphNames). i.e. GlyphNames[Chars[r]] is
 	// the glyph corresponding to rune r.
@@ -105,8 +109,12 @@ type TtfType struct {
 	//				 not the case right now, but make sure to update it once we support those tables
 	// TODO(dennwc,peterwilliams97): it should map char codes to GIDs
 	Chars map[rune]GID
-	// GlyphNames is a list of glyphs from the "post" section of the TrueType file.
+	// GlyphNames is a list of glyphs from the "post" section of the TrueType file, or from the
+	// charset of the CFF font program of OpenType fonts with CFF outlines.
 	GlyphNames []GlyphName
+	// CFF is true for OpenType fonts with CFF (PostScript) outlines. Their glyphs are described by
+	// the font program in the "CFF " table instead of the "glyf" table.
+	CFF bool
 }
 
 // MakeToUnicode returns a ToUnicode CMap based on the encoding of `ttf`.
@@ -207,11 +215,7 @@ func (t *ttfParser) Parse() (TtfType, error) {
 	if err != nil {
 		return TtfType{}, err
 	}
-	if version == "OTTO" {
-		// See https://docs.microsoft.com/en-us/typography/opentype/spec/otff
-		return TtfType{}, errors.New("fonts based on PostScript outlines are not supported")
-	}
-	if version != "\x00\x01\x00\x00" && version != "true" {
+	if version != "\x00\x01\x00\x00" && version != "true" && version != "OTTO" {
 		// This is not an error. In the font_test.go example axes.txt we see version "true".
 		common.Log.Debug("Unrecognized TrueType file format. version=%q", version)
 	}
@@ -235,6 +239,13 @@ func (t *ttfParser) Parse() (TtfType, error) {
 	if err = t.ParseComponents(); err != nil {
 		return TtfType{}, err
 	}
+	if version == "OTTO" {
+		// See https://docs.microsoft.com/en-us/typography/opentype/spec/otff
+		t.rec.CFF = true
+		if err = t.ParseCFF(); err != nil {
+			return TtfType{}, err
+		}
+	}
 	return t.rec, nil
 }
 
@@ -301,6 +312,13 @@ func (t *ttfParser) ParseComponents() error {
 			return err
 		}
 	}
+	if _, ok := t.tables["vhea"]; ok {
+		if _, ok := t.tables["vmtx"]; ok {
+			if err := t.ParseVmtx(); err != nil {
+				return err
+			}
+		}
+	}
 
 	return nil
 }
@@ -366,6 +384,38 @@ func (t *ttfParser) ParseHmtx() error {
 	return nil
 }
 
+// ParseVmtx parses the Vertical Metrics table in a TrueType, using the number of metrics of the
+// Vertical Header table.
+func (t *ttfParser) ParseVmtx() error {
+	if err := t.Seek("vhea"); err != nil {
+		return err
+	}
+	t.Skip(4 + 15*2)
+	numOfLongVerMetrics := t.ReadUShort()
+	if numOfLongVerMetrics == 0 {
+		return nil
+	}
+
+	if err := t.Seek("vmtx"); err != nil {
+		return err
+	}
+	t.rec.Heights = make([]uint16, 0, t.numGlyphs)
+	for j := uint16(0); j < numOfLongVerMetrics && j < t.numGlyphs; j++ {
+		t.rec.Heights = append(t.rec.Heights, t.ReadUShort())
+		t.Skip(2) // tsb
+	}
+	if len(t.rec.Heights) == 0 {
+		t.rec.Heights = nil
+		return nil
+	}
+	lastHeight := t.rec.Heights[len(t.rec.Heights)-1]
+	for j := uint16(len(t.rec.Heights)); j < t.numGlyphs; j++ {
+		t.rec.Heights = append(t.rec.Heights, lastHeight)
+	}
+
+	return nil
+}
+
 // parseCmapSubtable31 parses information from an (3,1) subtable (Windows Unicode).
 func (t *ttfParser) parseCmapSubtable31(offset31 int64) error {
 	startCount := make([]rune, 0, 8)
@@ -788,6 +838,29 @@ var macGlyphNames = []GlyphName{
 	"ccaron", "dcroat",
 }
 
+// ParseCFF reads the "CFF " table of an OpenType font with CFF outlines and sets t.rec.GlyphNames
+// from the charset of the CFF font program, if the "post" table has no glyph names.
+func (t *ttfParser) ParseCFF() error {
+	if err := t.Seek("CFF "); err != nil {
+		return err
+	}
+	if len(t.rec.GlyphNames) > 0 {
+		return nil
+	}
+	data, err := ioutil.ReadAll(t.f)
+	if err != nil {
+		return err
+	}
+	cff, err := ParseCFF(data)
+	if err != nil {
+		// The glyph names are optional, the font can be used without them.
+		common.Log.Debug("ERROR: unable to parse CFF table: %v", err)
+		return nil
+	}
+	t.rec.GlyphNames = cff.GlyphNames
+	return nil
+}
+
 // Seek moves the file pointer to the table named `tag`.
 func (t *ttfParser) Seek(tag string) error {
 	ofs, ok := t.tables[tag]
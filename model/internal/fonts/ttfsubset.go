@@ -0,0 +1,479 @@
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Composite glyph flags (section "glyf" of the TrueType reference manual).
+const (
+	compositeArgsAreWords   = 0x0001
+	compositeHaveScale      = 0x0008
+	compositeMoreComponents = 0x0020
+	compositeHaveXYScale    = 0x0040
+	compositeHaveTwoByTwo   = 0x0080
+)
+
+// ttfSubsetTables are the tables copied unchanged from the original font to a subset font.
+var ttfSubsetTables = []string{"OS/2", "cvt ", "fpgm", "name", "prep"}
+
+// SubsetTrueType returns a subset of the TrueType font program `data` containing the glyphs
+// `gids`, the .notdef glyph and the glyphs referenced by composite glyphs.
+// Glyph indices are preserved: a glyph has the same index in the subset as in the original font,
+// so character codes mapped to glyphs by index (e.g. by an Identity CIDToGIDMap) remain valid.
+// The "cmap" table of the subset maps the runes of `runeToGID` with glyphs in the subset.
+// Tables not needed for rendering the glyphs in PDF documents are dropped.
+func SubsetTrueType(data []byte, gids []GID, runeToGID map[rune]GID) ([]byte, error) {
+	f, err := readGlyphTables(data)
+	if err != nil {
+		return nil, err
+	}
+	numGlyphs := f.numGlyphs
+
+	// Determine the glyphs to keep, including the components of composite glyphs.
+	keep := make([]bool, numGlyphs)
+	queue := []GID{0}
+	for _, gid := range gids {
+		if int(gid) < numGlyphs {
+			queue = append(queue, gid)
+		}
+	}
+	for len(queue) > 0 {
+		gid := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if keep[gid] {
+			continue
+		}
+		keep[gid] = true
+		for _, c := range glyphComponents(f.glyph(gid)) {
+			if int(c) < numGlyphs && !keep[c] {
+				queue = append(queue, c)
+			}
+		}
+	}
+	glyphs := make([][]byte, numGlyphs)
+	hmetrics := make([][2]uint16, numGlyphs)
+	for gid := range keep {
+		if !keep[gid] {
+			continue
+		}
+		glyphs[gid] = f.glyph(GID(gid))
+		advance, lsb, err := horizontalMetrics(f.tables["hmtx"], f.numHMetrics, gid)
+		if err != nil {
+			return nil, err
+		}
+		hmetrics[gid] = [2]uint16{advance, lsb}
+	}
+	return buildTrueType(f.tables, keep, glyphs, hmetrics, runeToGID), nil
+}
+
+// glyphTables are the tables of a TrueType font program with the glyph data.
+type glyphTables struct {
+	tables      map[string][]byte
+	loca        []int
+	numGlyphs   int
+	numHMetrics int
+}
+
+// readGlyphTables returns the tables of the TrueType font program `data`.
+func readGlyphTables(data []byte) (*glyphTables, error) {
+	tables, err := readTableDirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("required table missing: %q", tag)
+		}
+	}
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("invalid font tables")
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numGlyphs == 0 || numHMetrics == 0 || numHMetrics > numGlyphs {
+		return nil, errors.New("invalid number of glyphs")
+	}
+	longLoca := binary.BigEndian.Uint16(head[50:]) != 0
+	loca, err := readLoca(tables["loca"], numGlyphs, longLoca, len(tables["glyf"]))
+	if err != nil {
+		return nil, err
+	}
+	return &glyphTables{
+		tables:      tables,
+		loca:        loca,
+		numGlyphs:   numGlyphs,
+		numHMetrics: numHMetrics,
+	}, nil
+}
+
+// glyph returns the data of glyph `gid` in the "glyf" table.
+func (f *glyphTables) glyph(gid GID) []byte {
+	return f.tables["glyf"][f.loca[gid]:f.loca[gid+1]]
+}
+
+// buildTrueType returns a TrueType font program with the glyphs `glyphs` and horizontal metrics
+// `hmetrics` of the glyphs in `keep`, indexed by glyph index, and the font-wide tables of `tables`.
+// Glyphs not in `keep` are empty. The "cmap" table maps the runes of `runeToGID` with glyphs in
+// `keep`.
+func buildTrueType(tables map[string][]byte, keep []bool, glyphs [][]byte, hmetrics [][2]uint16,
+	runeToGID map[rune]GID) []byte {
+	newNumGlyphs := 0
+	for gid := range keep {
+		if keep[gid] {
+			newNumGlyphs = gid + 1
+		}
+	}
+
+	// Rebuild the glyph data, keeping the indices of the glyphs. Dropped glyphs are empty.
+	// The font program always uses long (32 bit) offsets in the "loca" table.
+	var newGlyf []byte
+	newLoca := make([]byte, 4*(newNumGlyphs+1))
+	newHmtx := make([]byte, 4*newNumGlyphs)
+	for gid := 0; gid < newNumGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(len(newGlyf)))
+		if !keep[gid] {
+			continue
+		}
+		newGlyf = append(newGlyf, glyphs[gid]...)
+		for len(newGlyf)%4 != 0 {
+			newGlyf = append(newGlyf, 0)
+		}
+		binary.BigEndian.PutUint16(newHmtx[4*gid:], hmetrics[gid][0])
+		binary.BigEndian.PutUint16(newHmtx[4*gid+2:], hmetrics[gid][1])
+	}
+	binary.BigEndian.PutUint32(newLoca[4*newNumGlyphs:], uint32(len(newGlyf)))
+	if len(newGlyf) == 0 {
+		// Some font consumers do not accept an empty "glyf" table.
+		newGlyf = make([]byte, 4)
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[8:], 0) // checkSumAdjustment, set below.
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+	newHhea := append([]byte(nil), hhea...)
+	binary.BigEndian.PutUint16(newHhea[34:], uint16(newNumGlyphs))
+	newMaxp := append([]byte(nil), maxp...)
+	binary.BigEndian.PutUint16(newMaxp[4:], uint16(newNumGlyphs))
+
+	newTables := map[string][]byte{
+		"head": newHead,
+		"hhea": newHhea,
+		"maxp": newMaxp,
+		"hmtx": newHmtx,
+		"loca": newLoca,
+		"glyf": newGlyf,
+		"cmap": makeSubsetCmap(runeToGID, keep),
+	}
+	if post, ok := tables["post"]; ok && len(post) >= 32 {
+		// Glyph names are dropped (format 3).
+		newPost := append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(newPost, 0x00030000)
+		newTables["post"] = newPost
+	}
+	for _, tag := range ttfSubsetTables {
+		if table, ok := tables[tag]; ok {
+			newTables[tag] = table
+		}
+	}
+
+	font := writeTables(sfntVersionTrueType, newTables)
+	binary.BigEndian.PutUint32(font[headOffset(font)+8:], 0xB1B0AFBA-tableChecksum(font))
+	return font
+}
+
+// readTableDirectory returns the tables of the TrueType or OpenType font program `data` by tag.
+func readTableDirectory(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font data too short")
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true", "OTTO":
+	case "ttcf":
+		return nil, errors.New("font collections are not supported")
+	default:
+		return nil, fmt.Errorf("unrecognized font format %q", data[:4])
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*numTables {
+		return nil, errors.New("font table directory too short")
+	}
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		entry := data[12+16*i:]
+		tag := string(entry[:4])
+		offset := int64(binary.BigEndian.Uint32(entry[8:]))
+		length := int64(binary.BigEndian.Uint32(entry[12:]))
+		if offset+length > int64(len(data)) {
+			return nil, fmt.Errorf("table %q out of range", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// readLoca returns the `numGlyphs`+1 glyph offsets in the "loca" table `loca`.
+func readLoca(loca []byte, numGlyphs int, long bool, glyfLen int) ([]int, error) {
+	offsets := make([]int, numGlyphs+1)
+	size := 2
+	if long {
+		size = 4
+	}
+	if len(loca) < size*(numGlyphs+1) {
+		return nil, errors.New("loca table too short")
+	}
+	for i := range offsets {
+		if long {
+			offsets[i] = int(binary.BigEndian.Uint32(loca[4*i:]))
+		} else {
+			offsets[i] = 2 * int(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+		if offsets[i] > glyfLen || (i > 0 && offsets[i] < offsets[i-1]) {
+			return nil, errors.New("invalid loca table")
+		}
+	}
+	return offsets, nil
+}
+
+// glyphComponents returns the glyph indices of the components of the glyph with data `glyph`.
+// It returns nil for simple glyphs.
+func glyphComponents(glyph []byte) []GID {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+	var components []GID
+	for pos := 10; pos+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[pos:])
+		components = append(components, GID(binary.BigEndian.Uint16(glyph[pos+2:])))
+		pos += 4
+		if flags&compositeArgsAreWords != 0 {
+			pos += 4
+		} else {
+			pos += 2
+		}
+		switch {
+		case flags&compositeHaveScale != 0:
+			pos += 2
+		case flags&compositeHaveXYScale != 0:
+			pos += 4
+		case flags&compositeHaveTwoByTwo != 0:
+			pos += 8
+		}
+		if flags&compositeMoreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// horizontalMetrics returns the advance width and left side bearing of glyph `gid` in the "hmtx"
+// table `hmtx` with `numHMetrics` long horizontal metrics.
+func horizontalMetrics(hmtx []byte, numHMetrics, gid int) (uint16, uint16, error) {
+	if gid < numHMetrics {
+		if len(hmtx) < 4*gid+4 {
+			return 0, 0, errors.New("hmtx table too short")
+		}
+		return binary.BigEndian.Uint16(hmtx[4*gid:]), binary.BigEndian.Uint16(hmtx[4*gid+2:]), nil
+	}
+	pos := 4*numHMetrics + 2*(gid-numHMetrics)
+	if len(hmtx) < pos+2 {
+		return 0, 0, errors.New("hmtx table too short")
+	}
+	return binary.BigEndian.Uint16(hmtx[4*numHMetrics-4:]), binary.BigEndian.Uint16(hmtx[pos:]), nil
+}
+
+// makeSubsetCmap returns a "cmap" table mapping the runes of `runeToGID` with glyphs in `keep`.
+// The table has a Windows Unicode BMP (3,1) format 4 subtable, if the mappings of the BMP runes fit
+// in one, and a Windows Unicode full repertoire (3,10) format 12 subtable if there are runes
+// outside the BMP or the format 4 subtable is too large.
+func makeSubsetCmap(runeToGID map[rune]GID, keep []bool) []byte {
+	var runes []rune
+	for r, gid := range runeToGID {
+		if r >= 0 && r <= 0x10FFFF && int(gid) < len(keep) && keep[gid] {
+			runes = append(runes, r)
+		}
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	type subtable struct {
+		encodingID uint16
+		data       []byte
+	}
+	var subtables []subtable
+	format4, ok := makeCmapFormat4(runes, runeToGID)
+	if ok {
+		subtables = append(subtables, subtable{1, format4})
+	}
+	if !ok || (len(runes) > 0 && runes[len(runes)-1] >= 0xFFFF) {
+		subtables = append(subtables, subtable{10, makeCmapFormat12(runes, runeToGID)})
+	}
+
+	cmap := make([]byte, 4+8*len(subtables))
+	binary.BigEndian.PutUint16(cmap[2:], uint16(len(subtables)))
+	for i, st := range subtables {
+		binary.BigEndian.PutUint16(cmap[4+8*i:], 3)
+		binary.BigEndian.PutUint16(cmap[4+8*i+2:], st.encodingID)
+	}
+	for i, st := range subtables {
+		binary.BigEndian.PutUint32(cmap[4+8*i+4:], uint32(len(cmap)))
+		cmap = append(cmap, st.data...)
+	}
+	return cmap
+}
+
+// makeCmapFormat4 returns a format 4 cmap subtable for the BMP runes of the sorted `runes`.
+// The returned bool is false if the subtable is too large for the format.
+func makeCmapFormat4(runes []rune, runeToGID map[rune]GID) ([]byte, bool) {
+	type segment struct {
+		start, end uint16
+		delta      uint16
+	}
+	var segments []segment
+	for _, r := range runes {
+		if r >= 0xFFFF {
+			break
+		}
+		code := uint16(r)
+		delta := uint16(runeToGID[r]) - code
+		if n := len(segments); n > 0 && segments[n-1].end+1 == code && segments[n-1].delta == delta {
+			segments[n-1].end = code
+			continue
+		}
+		segments = append(segments, segment{start: code, end: code, delta: delta})
+	}
+	// The last segment must map 0xFFFF.
+	segments = append(segments, segment{start: 0xFFFF, end: 0xFFFF, delta: 1})
+
+	segCount := len(segments)
+	length := 16 + 8*segCount
+	if length > 0xFFFF {
+		return nil, false
+	}
+	searchRange, entrySelector := 2, 0
+	for searchRange*2 <= 2*segCount {
+		searchRange *= 2
+		entrySelector++
+	}
+
+	b := make([]byte, length)
+	binary.BigEndian.PutUint16(b[0:], 4)
+	binary.BigEndian.PutUint16(b[2:], uint16(length))
+	binary.BigEndian.PutUint16(b[6:], uint16(2*segCount))
+	binary.BigEndian.PutUint16(b[8:], uint16(searchRange))
+	binary.BigEndian.PutUint16(b[10:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(b[12:], uint16(2*segCount-searchRange))
+	endCodes := b[14:]
+	startCodes := b[16+2*segCount:]
+	deltas := b[16+4*segCount:]
+	// The idRangeOffset array is all zeros.
+	for i, s := range segments {
+		binary.BigEndian.PutUint16(endCodes[2*i:], s.end)
+		binary.BigEndian.PutUint16(startCodes[2*i:], s.start)
+		binary.BigEndian.PutUint16(deltas[2*i:], s.delta)
+	}
+	return b, true
+}
+
+// makeCmapFormat12 returns a format 12 cmap subtable for the sorted `runes`.
+func makeCmapFormat12(runes []rune, runeToGID map[rune]GID) []byte {
+	type group struct {
+		start, end rune
+		gid        GID
+	}
+	var groups []group
+	for _, r := range runes {
+		gid := runeToGID[r]
+		if n := len(groups); n > 0 {
+			g := &groups[n-1]
+			if g.end+1 == r && g.gid+GID(r-g.start) == gid {
+				g.end = r
+				continue
+			}
+		}
+		groups = append(groups, group{start: r, end: r, gid: gid})
+	}
+
+	b := make([]byte, 16+12*len(groups))
+	binary.BigEndian.PutUint16(b[0:], 12)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(b)))
+	binary.BigEndian.PutUint32(b[12:], uint32(len(groups)))
+	for i, g := range groups {
+		binary.BigEndian.PutUint32(b[16+12*i:], uint32(g.start))
+		binary.BigEndian.PutUint32(b[16+12*i+4:], uint32(g.end))
+		binary.BigEndian.PutUint32(b[16+12*i+8:], uint32(g.gid))
+	}
+	return b
+}
+
+// Versions of the font programs written by writeTables.
+const (
+	sfntVersionTrueType = 0x00010000 // TrueType outlines.
+	sfntVersionOpenType = 0x4F54544F // CFF outlines ("OTTO").
+)
+
+// writeTables returns a TrueType or OpenType font program of version `sfntVersion` with the
+// tables `tables`.
+func writeTables(sfntVersion uint32, tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	font := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(font[0:], sfntVersion)
+	binary.BigEndian.PutUint16(font[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(font[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(font[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(font[10:], uint16(16*numTables-searchRange))
+	for i, tag := range tags {
+		table := tables[tag]
+		entry := font[12+16*i:]
+		copy(entry, tag)
+		binary.BigEndian.PutUint32(entry[4:], tableChecksum(table))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(font)))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table)))
+		font = append(font, table...)
+		for len(font)%4 != 0 {
+			font = append(font, 0)
+		}
+	}
+	return font
+}
+
+// headOffset returns the offset of the "head" table in the font program `font` written by
+// writeTables.
+func headOffset(font []byte) int {
+	numTables := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < numTables; i++ {
+		entry := font[12+16*i:]
+		if string(entry[:4]) == "head" {
+			return int(binary.BigEndian.Uint32(entry[8:]))
+		}
+	}
+	return 0
+}
+
+// tableChecksum returns the checksum of `data`, the sum of its big endian 32 bit words.
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,152 @@
+package fonts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubsetTrueType(t *testing.T) {
+	for _, c := range casesTTFParse {
+		t.Run(c.path, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join(fontDir, c.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ft, err := TtfParse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runeToGID := make(map[rune]GID)
+			var gids []GID
+			for _, r := range testRunes {
+				runeToGID[r] = ft.Chars[r]
+				gids = append(gids, ft.Chars[r])
+			}
+			subset, err := SubsetTrueType(data, gids, runeToGID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(subset) >= len(data)/2 {
+				t.Errorf("subset not smaller: %d >= %d/2", len(subset), len(data))
+			}
+			if sum := tableChecksum(subset); sum != 0xB1B0AFBA {
+				t.Errorf("bad font checksum 0x%08x", sum)
+			}
+
+			st, err := TtfParse(bytes.NewReader(subset))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.PostScriptName != c.name || st.UnitsPerEm != ft.UnitsPerEm {
+				t.Errorf("%q %d", st.PostScriptName, st.UnitsPerEm)
+			}
+			if len(st.Chars) != len(testRunes) {
+				t.Errorf("%d runes in subset cmap", len(st.Chars))
+			}
+			for _, r := range testRunes {
+				// Glyph indices are preserved.
+				if gid := st.Chars[r]; gid != ft.Chars[r] {
+					t.Errorf("%q: %d != %d", r, gid, ft.Chars[r])
+				}
+				if w := st.Widths[st.Chars[r]]; int(w) != c.widths[r] {
+					t.Errorf("%q: %d != %d", r, w, c.widths[r])
+				}
+			}
+
+			// The subset glyph data is the same as the original glyph data.
+			tables, err := readTableDirectory(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subsetTables, err := readTableDirectory(subset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loca, err := readLoca(tables["loca"], len(ft.Widths),
+				tables["head"][51] != 0, len(tables["glyf"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			subsetLoca, err := readLoca(subsetTables["loca"], int(st.Chars['ё'])+1, true,
+				len(subsetTables["glyf"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range testRunes {
+				gid := ft.Chars[r]
+				glyph := tables["glyf"][loca[gid]:loca[gid+1]]
+				subsetGlyph := subsetTables["glyf"][subsetLoca[gid]:subsetLoca[gid+1]]
+				if !bytes.Equal(glyph, subsetGlyph[:len(glyph)]) {
+					t.Errorf("%q: glyph data differs", r)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeTrueType(t *testing.T) {
+	c := casesTTFParse[0]
+	data, err := ioutil.ReadFile(filepath.Join(fontDir, c.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft, err := TtfParse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One subset for each of the test runes.
+	var subsets [][]byte
+	for _, r := range testRunes {
+		gid := ft.Chars[r]
+		subset, err := SubsetTrueType(data, []GID{gid}, map[rune]GID{r: gid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		subsets = append(subsets, subset)
+	}
+	hash, err := TrueTypeHash(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subset := range subsets {
+		if h, err := TrueTypeHash(subset); err != nil || h != hash {
+			t.Fatalf("subset hash %q != %q: %v", h, hash, err)
+		}
+	}
+
+	merged, err := MergeTrueType(subsets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := tableChecksum(merged); sum != 0xB1B0AFBA {
+		t.Errorf("bad font checksum 0x%08x", sum)
+	}
+	mt, err := TtfParse(bytes.NewReader(merged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mt.Chars) != len(testRunes) {
+		t.Errorf("%d runes in merged cmap", len(mt.Chars))
+	}
+	for _, r := range testRunes {
+		if gid := mt.Chars[r]; gid != ft.Chars[r] {
+			t.Errorf("%q: %d != %d", r, gid, ft.Chars[r])
+		}
+		if w := mt.Widths[mt.Chars[r]]; int(w) != c.widths[r] {
+			t.Errorf("%q: %d != %d", r, w, c.widths[r])
+		}
+	}
+
+	// Subsets of different fonts are not merged.
+	other, err := ioutil.ReadFile(filepath.Join(fontDir, casesTTFParse[1].path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MergeTrueType([][]byte{subsets[0], other}); err == nil {
+		t.Error("subsets of different fonts merged")
+	}
+}
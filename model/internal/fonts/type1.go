@@ -0,0 +1,473 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/moolekkari/unipdf/internal/textencoding"
+)
+
+// Type1Font describes the glyphs of a Type 1 font program, as embedded in PDF files with FontFile
+// streams. See Adobe "Type 1 Font Format".
+type Type1Font struct {
+	// FontMatrix maps the glyph coordinates of the font program to text space units.
+	FontMatrix [6]float64
+
+	subrs       [][]byte
+	charStrings map[GlyphName][]byte
+}
+
+var (
+	reType1FontMatrix = regexp.MustCompile(`/FontMatrix\s*[\[{]([^\]}]*)[\]}]`)
+	reType1LenIV      = regexp.MustCompile(`/lenIV\s+(-?\d+)`)
+)
+
+// ParseType1 returns the glyphs of the Type 1 font program with the cleartext portion `segment1`
+// and the eexec encrypted portion `segment2`.
+func ParseType1(segment1, segment2 []byte) (*Type1Font, error) {
+	font := &Type1Font{
+		FontMatrix:  [6]float64{0.001, 0, 0, 0.001, 0, 0},
+		charStrings: make(map[GlyphName][]byte),
+	}
+	if m := reType1FontMatrix.FindSubmatch(segment1); m != nil {
+		fields := strings.Fields(string(m[1]))
+		if len(fields) == 6 {
+			for i, field := range fields {
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid FontMatrix: %v", err)
+				}
+				font.FontMatrix[i] = v
+			}
+		}
+	}
+
+	if !isBinary(segment2) {
+		decoded, err := hex.DecodeString(strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, string(segment2)))
+		if err != nil {
+			return nil, err
+		}
+		segment2 = decoded
+	}
+	if len(segment2) < 4 {
+		return nil, errors.New("eexec section too short")
+	}
+	private := decodeEexec(segment2)
+
+	lenIV := 4
+	if m := reType1LenIV.FindSubmatch(private); m != nil {
+		lenIV, _ = strconv.Atoi(string(m[1]))
+	}
+	decrypt := func(cs []byte) []byte {
+		if lenIV < 0 {
+			return cs
+		}
+		cs = decryptType1(cs, 4330)
+		if lenIV > len(cs) {
+			return nil
+		}
+		return cs[lenIV:]
+	}
+
+	if i := bytes.Index(private, []byte("/Subrs")); i >= 0 {
+		s := &type1Scanner{data: private, pos: i}
+		s.token()
+		count, _ := strconv.Atoi(s.token())
+		if count < 0 || count > len(private) {
+			return nil, errors.New("invalid number of Subrs")
+		}
+		font.subrs = make([][]byte, count)
+		s.token() // array
+		for s.peek() == "dup" {
+			s.token()
+			index, err1 := strconv.Atoi(s.token())
+			n, err2 := strconv.Atoi(s.token())
+			s.token() // RD or -|
+			data, ok := s.binary(n)
+			if err1 != nil || err2 != nil || !ok {
+				return nil, errors.New("invalid Subrs entry")
+			}
+			if index >= 0 && index < count {
+				font.subrs[index] = decrypt(data)
+			}
+			s.skip("NP", "|", "noaccess", "put")
+		}
+	}
+
+	i := bytes.Index(private, []byte("/CharStrings"))
+	if i < 0 {
+		return nil, errors.New("no CharStrings")
+	}
+	s := &type1Scanner{data: private, pos: i}
+	s.token()
+	s.skip("dict", "dup", "begin")
+	for s.peek() != "end" {
+		tok := s.token()
+		if tok == "" {
+			break
+		}
+		if !strings.HasPrefix(tok, "/") {
+			// Skip the size of the dictionary and other tokens preceding the charstrings.
+			continue
+		}
+		n, err := strconv.Atoi(s.token())
+		s.token() // RD or -|
+		data, ok := s.binary(n)
+		if err != nil || !ok {
+			return nil, fmt.Errorf("invalid charstring %s", tok)
+		}
+		font.charStrings[GlyphName(tok[1:])] = decrypt(data)
+		s.skip("ND", "|-", "noaccess", "def")
+	}
+	if len(font.charStrings) == 0 {
+		return nil, errors.New("no charstrings")
+	}
+	return font, nil
+}
+
+// HasGlyph returns true if the font has a glyph named `glyph`.
+func (f *Type1Font) HasGlyph(glyph GlyphName) bool {
+	_, ok := f.charStrings[glyph]
+	return ok
+}
+
+// Outline returns the outline of the glyph named `glyph`, interpreting its Type 1 charstring.
+func (f *Type1Font) Outline(glyph GlyphName) (GlyphPath, error) {
+	cs, ok := f.charStrings[glyph]
+	if !ok {
+		return nil, fmt.Errorf("no glyph %q", glyph)
+	}
+	t := &type1Charstring{font: f, b: newPathBuilder(f.FontMatrix)}
+	if _, err := t.run(cs, 0); err != nil {
+		return nil, err
+	}
+	return t.b.glyphPath(), nil
+}
+
+// type1Scanner splits the decrypted private part of a Type 1 font program in tokens.
+type type1Scanner struct {
+	data []byte
+	pos  int
+}
+
+// token returns the next token, or an empty string at the end of the data.
+func (s *type1Scanner) token() string {
+	for s.pos < len(s.data) && isType1Space(s.data[s.pos]) {
+		s.pos++
+	}
+	start := s.pos
+	for s.pos < len(s.data) && !isType1Space(s.data[s.pos]) {
+		s.pos++
+	}
+	return string(s.data[start:s.pos])
+}
+
+// peek returns the next token without consuming it.
+func (s *type1Scanner) peek() string {
+	pos := s.pos
+	tok := s.token()
+	s.pos = pos
+	return tok
+}
+
+// skip consumes the following tokens which are in `tokens`.
+func (s *type1Scanner) skip(tokens ...string) {
+	for {
+		tok := s.peek()
+		found := false
+		for _, t := range tokens {
+			found = found || tok == t
+		}
+		if !found {
+			return
+		}
+		s.token()
+	}
+}
+
+// binary returns the `n` bytes following the single space after the current token.
+func (s *type1Scanner) binary(n int) ([]byte, bool) {
+	start := s.pos + 1
+	if n < 0 || start+n > len(s.data) {
+		return nil, false
+	}
+	s.pos = start + n
+	return s.data[start:s.pos], true
+}
+
+// isType1Space returns true if `b` is a PostScript white-space character.
+func isType1Space(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+// decodeEexec returns the decoding of the eexec bytes `data`.
+func decodeEexec(data []byte) []byte {
+	return decryptType1(data, 55665)[4:]
+}
+
+// decryptType1 returns `data` decrypted with the Type 1 encryption key `r`: 55665 for the eexec
+// encrypted portion of the font program, 4330 for charstrings.
+func decryptType1(data []byte, r int) []byte {
+	const c1 = 52845
+	const c2 = 22719
+	decoded := make([]byte, len(data))
+	for i, b := range data {
+		decoded[i] = b ^ byte(r>>8)
+		r = ((int(b)+r)*c1 + c2) & 0xffff
+	}
+	return decoded
+}
+
+// isBinary returns true if `data` is binary. See Adobe Type 1 Font Format specification
+// 7.2 eexec encryption
+func isBinary(data []byte) bool {
+	if len(data) < 4 {
+		return true
+	}
+	for _, b := range data[:4] {
+		r := rune(b)
+		if !unicode.Is(unicode.ASCII_Hex_Digit, r) && !unicode.IsSpace(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// type1Charstring is an interpreter of Type 1 charstrings (Adobe "Type 1 Font Format", chapter 6).
+type type1Charstring struct {
+	font *Type1Font
+	b    *pathBuilder
+
+	stack   []float64
+	psStack []float64 // The results of OtherSubrs, transferred to the stack by pop.
+	sbx     float64   // The x coordinate of the left sidebearing point.
+	flex    bool      // True between the start and the end of a flex sequence.
+	flexPts [][2]float64
+}
+
+// run interprets the charstring `cs`, called as a subroutine at depth `depth`. It returns true if
+// the end of the glyph (endchar or seac) has been reached.
+func (t *type1Charstring) run(cs []byte, depth int) (bool, error) {
+	if depth > maxSubrDepth {
+		return false, errors.New("charstring subroutines nested too deeply")
+	}
+	b := t.b
+	for pos := 0; pos < len(cs); {
+		b0 := cs[pos]
+		switch {
+		case b0 == 255:
+			if pos+5 > len(cs) {
+				return false, errors.New("truncated charstring")
+			}
+			t.stack = append(t.stack, float64(int32(binary.BigEndian.Uint32(cs[pos+1:]))))
+			pos += 5
+			continue
+		case b0 >= 32:
+			v, n, err := readCFFInt(cs[pos:])
+			if err != nil {
+				return false, err
+			}
+			t.stack = append(t.stack, float64(v))
+			pos += n
+			continue
+		}
+
+		pos++
+		op := int(b0)
+		if b0 == 12 {
+			if pos >= len(cs) {
+				return false, errors.New("truncated charstring")
+			}
+			op = 1200 + int(cs[pos])
+			pos++
+		}
+		s := t.stack
+		if n := type1Operands[op]; len(s) < n {
+			return false, fmt.Errorf("charstring operator %d: stack underflow", op)
+		}
+		switch op {
+		case 13: // hsbw
+			t.sbx = s[0]
+			b.x, b.y = s[0], 0
+		case 1207: // sbw
+			t.sbx = s[0]
+			b.x, b.y = s[0], s[1]
+		case 21: // rmoveto
+			t.moveTo(b.x+s[0], b.y+s[1])
+		case 22: // hmoveto
+			t.moveTo(b.x+s[0], b.y)
+		case 4: // vmoveto
+			t.moveTo(b.x, b.y+s[0])
+		case 5: // rlineto
+			b.lineTo(b.x+s[0], b.y+s[1])
+		case 6: // hlineto
+			b.lineTo(b.x+s[0], b.y)
+		case 7: // vlineto
+			b.lineTo(b.x, b.y+s[0])
+		case 8: // rrcurveto
+			t.curve(s[0], s[1], s[2], s[3], s[4], s[5])
+		case 30: // vhcurveto
+			t.curve(0, s[0], s[1], s[2], s[3], 0)
+		case 31: // hvcurveto
+			t.curve(s[0], 0, s[1], s[2], 0, s[3])
+		case 9: // closepath
+			b.closePath()
+		case 10: // callsubr
+			i := int(s[len(s)-1])
+			t.stack = s[:len(s)-1]
+			if i < 0 || i >= len(t.font.subrs) {
+				return false, fmt.Errorf("invalid subroutine %d", i)
+			}
+			done, err := t.run(t.font.subrs[i], depth+1)
+			if done || err != nil {
+				return done, err
+			}
+			continue
+		case 11: // return
+			return false, nil
+		case 14: // endchar
+			b.closePath()
+			return true, nil
+		case 1206: // seac
+			return true, t.accented(s[0], s[1], s[2], int(s[3]), int(s[4]), depth)
+		case 1212: // div
+			n := len(s)
+			t.stack = append(s[:n-2], s[n-2]/nonZero(s[n-1]))
+			continue
+		case 1216: // callothersubr
+			t.callOtherSubr()
+			continue
+		case 1217: // pop
+			if n := len(t.psStack); n > 0 {
+				t.stack = append(t.stack, t.psStack[n-1])
+				t.psStack = t.psStack[:n-1]
+			}
+			continue
+		case 1233: // setcurrentpoint
+			b.x, b.y = s[0], s[1]
+		case 1, 3, 1200, 1201, 1202: // hstem, vstem, dotsection, vstem3, hstem3
+		default:
+			return false, fmt.Errorf("unsupported charstring operator %d", op)
+		}
+		t.stack = t.stack[:0]
+	}
+	return false, nil
+}
+
+// type1Operands are the numbers of operands of the Type 1 charstring operators.
+var type1Operands = map[int]int{
+	1: 2, 3: 2, 4: 1, 5: 2, 6: 1, 7: 1, 8: 6, 10: 1, 13: 2, 21: 2, 22: 1, 30: 4, 31: 4,
+	1201: 6, 1202: 6, 1206: 5, 1207: 4, 1212: 2, 1216: 2, 1233: 2,
+}
+
+// nonZero returns `v`, or 1 if `v` is 0.
+func nonZero(v float64) float64 {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
+
+// moveTo moves the current point to `x`,`y`. In flex sequences the point is recorded instead.
+func (t *type1Charstring) moveTo(x, y float64) {
+	if t.flex {
+		t.b.x, t.b.y = x, y
+		t.flexPts = append(t.flexPts, [2]float64{x, y})
+		return
+	}
+	t.b.moveTo(x, y)
+}
+
+// curve appends a curve to the glyph path, with points relative to the preceding point.
+func (t *type1Charstring) curve(dx1, dy1, dx2, dy2, dx3, dy3 float64) {
+	b := t.b
+	x1, y1 := b.x+dx1, b.y+dy1
+	x2, y2 := x1+dx2, y1+dy2
+	b.curveTo(x1, y1, x2, y2, x2+dx3, y2+dy3)
+}
+
+// callOtherSubr performs the callothersubr operator. The flex (0, 1 and 2) and hint replacement
+// (3) OtherSubrs of the standard Type 1 fonts are supported, the arguments of other OtherSubrs
+// are returned unchanged.
+func (t *type1Charstring) callOtherSubr() {
+	s := t.stack
+	n := len(s)
+	index, numArgs := int(s[n-1]), int(s[n-2])
+	s = s[:n-2]
+	if numArgs < 0 || numArgs > len(s) {
+		numArgs = len(s)
+	}
+	args := s[len(s)-numArgs:]
+	t.stack = s[:len(s)-numArgs]
+
+	t.psStack = t.psStack[:0]
+	switch index {
+	case 0: // End of flex: flexheight x y.
+		t.flex = false
+		if pts := t.flexPts; len(pts) == 7 {
+			// The first point is the reference point of the flex, the curves start at the current
+			// point before the flex.
+			t.b.curveTo(pts[1][0], pts[1][1], pts[2][0], pts[2][1], pts[3][0], pts[3][1])
+			t.b.curveTo(pts[4][0], pts[4][1], pts[5][0], pts[5][1], pts[6][0], pts[6][1])
+		}
+		if len(args) == 3 {
+			// pop pop setcurrentpoint sets the current point to x y.
+			t.psStack = append(t.psStack, args[2], args[1])
+		}
+	case 1: // Start of flex.
+		t.flex = true
+		t.flexPts = t.flexPts[:0]
+	case 2: // Flex point, recorded by the preceding moveto.
+	default:
+		for i := len(args) - 1; i >= 0; i-- {
+			t.psStack = append(t.psStack, args[i])
+		}
+	}
+}
+
+// accented draws the base glyph with Standard Encoding code `bchar` and the accent glyph with code
+// `achar`, whose left sidebearing is `asb`, offset by `adx`,`ady`.
+func (t *type1Charstring) accented(asb, adx, ady float64, bchar, achar, depth int) error {
+	standard := textencoding.NewStandardEncoder()
+	glyphs := make([][]byte, 2)
+	for i, code := range []int{bchar, achar} {
+		r, ok := standard.CharcodeToRune(textencoding.CharCode(code))
+		if !ok {
+			return fmt.Errorf("no Standard Encoding glyph for code %d", code)
+		}
+		glyph, ok := textencoding.RuneToGlyph(r)
+		if !ok {
+			return fmt.Errorf("no glyph name for %+q", r)
+		}
+		if glyphs[i], ok = t.font.charStrings[glyph]; !ok {
+			return fmt.Errorf("no glyph %q", glyph)
+		}
+	}
+	b := t.b
+	dx := adx - asb + t.sbx
+	for i, cs := range glyphs {
+		b.closePath()
+		if i == 1 {
+			b.dx, b.dy = dx, ady
+		}
+		t.stack = t.stack[:0]
+		if _, err := t.run(cs, depth+1); err != nil {
+			return err
+		}
+	}
+	b.closePath()
+	b.dx, b.dy = 0, 0
+	return nil
+}
@@ -0,0 +1,806 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/jbig2/reader"
+	"github.com/moolekkari/unipdf/internal/jbig2/writer"
+)
+
+// Linearized files (Annex F "Linearized PDF" PDF32000_2008) are laid out as follows:
+//  1. Header.
+//  2. Linearization parameter dictionary.
+//  3. First-page cross-reference table and trailer.
+//  4. Document catalog and encryption dictionary.
+//  5. Primary hint stream.
+//  6. First page section: the first page and the objects it uses.
+//  7. Remaining pages: each page with the objects only it uses.
+//  8. Shared objects: the objects used by several of the remaining pages.
+//  9. Other objects.
+//  10. Main cross-reference table and trailer.
+// The objects of parts 2 to 6 are numbered after the other objects, so that the first-page
+// cross-reference table is a single section.
+
+// SetLinearized sets whether the output PDF is linearized, for viewing the first page before the
+// whole file is loaded, e.g. when served over HTTP with range requests (Fast Web View).
+// Linearized output is written with cross-reference tables and does not support object streams.
+func (w *PdfWriter) SetLinearized(linearized bool) {
+	w.linearized = linearized
+}
+
+// linearizedLayout is the order and numbering of the objects of a linearized file.
+type linearizedLayout struct {
+	documentObjects  []core.PdfObject   // Part 4.
+	firstPageObjects []core.PdfObject   // Part 6, starting with the page object.
+	pageObjects      [][]core.PdfObject // Part 7, for each remaining page, starting with the page object.
+	sharedObjects    []core.PdfObject   // Part 8.
+	otherObjects     []core.PdfObject   // Part 9.
+
+	// References of the remaining pages to the objects of parts 6 and 8, as indexes of the objects
+	// in the concatenation of the parts.
+	sharedRefs [][]int
+
+	linearizationNum int64 // The object number of the linearization parameter dictionary.
+	hintNum          int64 // The object number of the primary hint stream.
+}
+
+// layoutLinearized returns the layout of the objects of `w` for linearized output.
+func (w *PdfWriter) layoutLinearized() (*linearizedLayout, error) {
+	catalog, ok := core.GetDict(w.root)
+	if !ok {
+		return nil, errors.New("invalid catalog")
+	}
+	pagesObj, ok := core.GetIndirect(catalog.Get("Pages"))
+	if !ok {
+		return nil, errors.New("invalid Pages")
+	}
+	pagesDict, ok := core.GetDict(pagesObj)
+	if !ok {
+		return nil, errors.New("invalid Pages")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok || kids.Len() == 0 {
+		return nil, errors.New("no pages")
+	}
+	pages := kids.Elements()
+
+	// The traversal of the objects of a page stops at the other pages and the document level
+	// objects.
+	stop := map[core.PdfObject]bool{pagesObj: true, w.root: true, w.infoObj: true}
+	for _, page := range pages {
+		if _, ok := page.(*core.PdfIndirectObject); !ok || !w.hasObject(page) {
+			return nil, errors.New("invalid page object")
+		}
+		stop[page] = true
+	}
+
+	layout := &linearizedLayout{documentObjects: []core.PdfObject{w.root}}
+	assigned := map[core.PdfObject]bool{w.root: true}
+	if w.encryptObj != nil {
+		layout.documentObjects = append(layout.documentObjects, w.encryptObj)
+		assigned[w.encryptObj] = true
+	}
+
+	reachable := func(page core.PdfObject) []core.PdfObject {
+		delete(stop, page)
+		defer func() { stop[page] = true }()
+		return w.reachableObjects(page, stop)
+	}
+	for _, obj := range reachable(pages[0]) {
+		if !assigned[obj] {
+			layout.firstPageObjects = append(layout.firstPageObjects, obj)
+			assigned[obj] = true
+		}
+	}
+
+	// Objects used by several of the remaining pages are shared.
+	pageUses := make([][]core.PdfObject, len(pages)-1)
+	numPages := map[core.PdfObject]int{}
+	for i, page := range pages[1:] {
+		pageUses[i] = reachable(page)
+		for _, obj := range pageUses[i] {
+			numPages[obj]++
+		}
+	}
+	var shared []core.PdfObject
+	for _, objs := range pageUses {
+		var pageObjects []core.PdfObject
+		for _, obj := range objs {
+			if assigned[obj] {
+				continue
+			}
+			assigned[obj] = true
+			if numPages[obj] > 1 {
+				shared = append(shared, obj)
+			} else {
+				pageObjects = append(pageObjects, obj)
+			}
+		}
+		layout.pageObjects = append(layout.pageObjects, pageObjects)
+	}
+	layout.sharedObjects = shared
+
+	index := map[core.PdfObject]int{}
+	for i, obj := range append(append([]core.PdfObject{}, layout.firstPageObjects...), shared...) {
+		index[obj] = i
+	}
+	for _, objs := range pageUses {
+		var refs []int
+		for _, obj := range objs {
+			if i, ok := index[obj]; ok {
+				refs = append(refs, i)
+			}
+		}
+		layout.sharedRefs = append(layout.sharedRefs, refs)
+	}
+
+	for _, obj := range w.objects {
+		if !assigned[obj] {
+			layout.otherObjects = append(layout.otherObjects, obj)
+			assigned[obj] = true
+		}
+	}
+
+	// Number the objects of the main cross-reference table, then those of the first-page table.
+	num := int64(0)
+	number := func(objs []core.PdfObject) {
+		for _, obj := range objs {
+			num++
+			setObjectNumber(obj, num)
+		}
+	}
+	for _, objs := range layout.pageObjects {
+		number(objs)
+	}
+	number(layout.sharedObjects)
+	number(layout.otherObjects)
+	num++
+	layout.linearizationNum = num
+	number(layout.documentObjects)
+	number(layout.firstPageObjects)
+	layout.hintNum = num + 1
+	return layout, nil
+}
+
+// reachableObjects returns the objects of `w` used by `obj`, in depth-first order, starting with
+// `obj`. Parent entries are not followed and the objects in `stop` are not entered.
+func (w *PdfWriter) reachableObjects(obj core.PdfObject, stop map[core.PdfObject]bool) []core.PdfObject {
+	var objs []core.PdfObject
+	visited := map[core.PdfObject]bool{}
+	var visit func(obj core.PdfObject)
+	visit = func(obj core.PdfObject) {
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			if visited[t] || stop[t] {
+				return
+			}
+			visited[t] = true
+			if w.hasObject(t) {
+				objs = append(objs, t)
+			}
+			visit(t.PdfObject)
+		case *core.PdfObjectStream:
+			if visited[t] || stop[t] {
+				return
+			}
+			visited[t] = true
+			if w.hasObject(t) {
+				objs = append(objs, t)
+			}
+			visit(t.PdfObjectDictionary)
+		case *core.PdfObjectDictionary:
+			for _, key := range t.Keys() {
+				if key != "Parent" {
+					visit(t.Get(key))
+				}
+			}
+		case *core.PdfObjectArray:
+			for _, elem := range t.Elements() {
+				visit(elem)
+			}
+		}
+	}
+	visit(obj)
+	return objs
+}
+
+// setObjectNumber sets the object number of the indirect or stream object `obj` to `num`.
+func setObjectNumber(obj core.PdfObject, num int64) {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		t.ObjectNumber, t.GenerationNumber = num, 0
+	case *core.PdfObjectStream:
+		t.ObjectNumber, t.GenerationNumber = num, 0
+	}
+}
+
+// objectBytes returns the serialization of the object `obj` with number `num`.
+func (w *PdfWriter) objectBytes(num int64, obj core.PdfObject) []byte {
+	var buf bytes.Buffer
+	out, pos := w.writer, w.writePos
+	w.writer = bufio.NewWriter(&buf)
+	w.writeObject(int(num), obj)
+	w.writer.Flush()
+	w.writer, w.writePos = out, pos
+	return buf.Bytes()
+}
+
+// linearizedPart is a part of a linearized file made of objects.
+type linearizedPart struct {
+	nums []int64  // The object numbers.
+	data [][]byte // The serialized objects.
+}
+
+// add adds the serialized objects `objs` to `p`.
+func (p *linearizedPart) add(w *PdfWriter, objs ...core.PdfObject) {
+	for _, obj := range objs {
+		num := objectNumber(obj)
+		p.nums = append(p.nums, num)
+		p.data = append(p.data, w.objectBytes(num, obj))
+	}
+}
+
+// size returns the length of `p` in bytes.
+func (p *linearizedPart) size() int64 {
+	var n int64
+	for _, data := range p.data {
+		n += int64(len(data))
+	}
+	return n
+}
+
+// objectNumber returns the object number of the indirect or stream object `obj`.
+func objectNumber(obj core.PdfObject) int64 {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		return t.ObjectNumber
+	case *core.PdfObjectStream:
+		return t.ObjectNumber
+	}
+	return 0
+}
+
+// writeLinearized writes the objects of `w` as a linearized file to `out`.
+func (w *PdfWriter) writeLinearized(out io.Writer) error {
+	if w.appendMode {
+		return errors.New("linearized output is not supported for incremental updates")
+	}
+	if w.useCrossReferenceStream != nil && *w.useCrossReferenceStream {
+		return errors.New("linearized output does not support cross-reference streams")
+	}
+	for _, obj := range w.objects {
+		if _, ok := obj.(*core.PdfObjectStreams); ok {
+			return errors.New("linearized output does not support object streams")
+		}
+	}
+	layout, err := w.layoutLinearized()
+	if err != nil {
+		return err
+	}
+	if w.crypter != nil {
+		for _, obj := range w.objects {
+			if obj == w.encryptObj {
+				continue
+			}
+			if err := w.crypter.Encrypt(obj, objectNumber(obj), 0); err != nil {
+				common.Log.Debug("ERROR: Failed encrypting (%s)", err)
+				return err
+			}
+		}
+	}
+	if w.ids == nil {
+		id := md5.Sum([]byte(fmt.Sprintf("%s %d", time.Now().String(), len(w.objects))))
+		w.ids = core.MakeArray(core.MakeHexString(string(id[:])), core.MakeHexString(string(id[:])))
+	}
+
+	w.crossReferenceMap = map[int]crossReference{}
+	var document, firstPage, shared, other linearizedPart
+	document.add(w, layout.documentObjects...)
+	firstPage.add(w, layout.firstPageObjects...)
+	pages := make([]linearizedPart, len(layout.pageObjects))
+	for i, objs := range layout.pageObjects {
+		pages[i].add(w, objs...)
+	}
+	shared.add(w, layout.sharedObjects...)
+	other.add(w, layout.otherObjects...)
+
+	header := fmt.Sprintf("%%PDF-%d.%d\n%%âãÏÓ\n", w.majorVersion, w.minorVersion)
+	numPages := len(layout.pageObjects) + 1
+	firstPageNum := objectNumber(layout.firstPageObjects[0])
+	linearizationDict := func(length, hintOffset, hintLength, end, mainXrefOffset int64) []byte {
+		return []byte(fmt.Sprintf("%d 0 obj\n<< /Linearized 1 /L %10d /H [ %10d %10d ] /O %d /E %10d /N %d /T %10d >>\nendobj\n",
+			layout.linearizationNum, length, hintOffset, hintLength, firstPageNum, end, numPages, mainXrefOffset))
+	}
+	firstXrefSize := layout.hintNum - layout.linearizationNum + 1
+	firstTrailer := func(prev int64) string {
+		trailer := fmt.Sprintf("<< /Size %d /Root %d 0 R /Info %d 0 R /ID %s /Prev %10d",
+			layout.hintNum+1, objectNumber(w.root), objectNumber(w.infoObj), w.ids.WriteString(), prev)
+		if w.encryptObj != nil {
+			trailer += fmt.Sprintf(" /Encrypt %d 0 R", w.encryptObj.ObjectNumber)
+		}
+		return "trailer\n" + trailer + " >>\nstartxref\n0\n%%EOF\n"
+	}
+	firstXrefHeader := fmt.Sprintf("xref\n%d %d\n", layout.linearizationNum, firstXrefSize)
+
+	// Offsets of the parts, computed without the hint stream as for the hint tables.
+	linearizationOffset := int64(len(header))
+	firstXrefOffset := linearizationOffset + int64(len(linearizationDict(0, 0, 0, 0, 0)))
+	documentOffset := firstXrefOffset + int64(len(firstXrefHeader)) + 20*firstXrefSize + int64(len(firstTrailer(0)))
+	hintOffset := documentOffset + document.size()
+	firstPageOffset := hintOffset
+	pageOffsets := make([]int64, len(pages))
+	offset := firstPageOffset + firstPage.size()
+	for i := range pages {
+		pageOffsets[i] = offset
+		offset += pages[i].size()
+	}
+	sharedOffset := offset
+	otherOffset := sharedOffset + shared.size()
+	mainXrefOffset := otherOffset + other.size()
+
+	hint, err := w.hintStream(layout, firstPageOffset, &firstPage, pages, sharedOffset, &shared)
+	if err != nil {
+		return err
+	}
+	// The hint tables depend on the sizes of the encrypted objects, so the hint stream is
+	// encrypted after the other objects.
+	if w.crypter != nil {
+		if err := w.crypter.Encrypt(hint, layout.hintNum, 0); err != nil {
+			common.Log.Debug("ERROR: Failed encrypting (%s)", err)
+			return err
+		}
+	}
+	hintData := w.objectBytes(layout.hintNum, hint)
+	hintLength := int64(len(hintData))
+
+	// Cross-reference tables, with the offsets shifted by the hint stream.
+	offsets := map[int64]int64{layout.linearizationNum: linearizationOffset, layout.hintNum: hintOffset}
+	setOffsets := func(p *linearizedPart, offset int64) {
+		for i, num := range p.nums {
+			offsets[num] = offset
+			offset += int64(len(p.data[i]))
+		}
+	}
+	setOffsets(&document, documentOffset)
+	setOffsets(&firstPage, firstPageOffset+hintLength)
+	for i := range pages {
+		setOffsets(&pages[i], pageOffsets[i]+hintLength)
+	}
+	setOffsets(&shared, sharedOffset+hintLength)
+	setOffsets(&other, otherOffset+hintLength)
+	mainXrefOffset += hintLength
+
+	var firstXref bytes.Buffer
+	firstXref.WriteString(firstXrefHeader)
+	for num := layout.linearizationNum; num <= layout.hintNum; num++ {
+		firstXref.WriteString(fmt.Sprintf("%.10d %.5d n\r\n", offsets[num], 0))
+	}
+	firstXref.WriteString(firstTrailer(mainXrefOffset))
+
+	var mainXref bytes.Buffer
+	mainXrefHeader := fmt.Sprintf("xref\n0 %d\n", layout.linearizationNum)
+	mainXref.WriteString(mainXrefHeader)
+	mainXref.WriteString(fmt.Sprintf("%.10d %.5d f\r\n", 0, 65535))
+	for num := int64(1); num < layout.linearizationNum; num++ {
+		mainXref.WriteString(fmt.Sprintf("%.10d %.5d n\r\n", offsets[num], 0))
+	}
+	mainXref.WriteString(fmt.Sprintf("trailer\n<< /Size %d >>\nstartxref\n%d\n%%%%EOF\n",
+		layout.linearizationNum, firstXrefOffset))
+
+	length := mainXrefOffset + int64(mainXref.Len())
+	end := firstPageOffset + hintLength + firstPage.size()
+	// The main cross-reference table is located by the white-space preceding its first entry.
+	firstEntryOffset := mainXrefOffset + int64(len(mainXrefHeader)) - 1
+
+	w.writer = bufio.NewWriter(out)
+	w.writePos = 0
+	w.writeString(header)
+	w.writeBytes(linearizationDict(length, hintOffset, hintLength, end, firstEntryOffset))
+	w.writeBytes(firstXref.Bytes())
+	for _, data := range document.data {
+		w.writeBytes(data)
+	}
+	w.writeBytes(hintData)
+	parts := []*linearizedPart{&firstPage}
+	for i := range pages {
+		parts = append(parts, &pages[i])
+	}
+	parts = append(parts, &shared, &other)
+	for _, p := range parts {
+		for _, data := range p.data {
+			w.writeBytes(data)
+		}
+	}
+	w.writeBytes(mainXref.Bytes())
+	if w.writePos != length {
+		common.Log.Debug("ERROR: linearized file length %d, expected %d", w.writePos, length)
+		return errors.New("invalid linearized file layout")
+	}
+	return w.writer.Flush()
+}
+
+// hintStream returns the primary hint stream of the linearized layout `layout`, with the page
+// offset and shared object hint tables (section F.4 "Hint Tables" PDF32000_2008). The offsets of
+// the parts are given without the hint stream.
+func (w *PdfWriter) hintStream(layout *linearizedLayout, firstPageOffset int64, firstPage *linearizedPart,
+	pages []linearizedPart, sharedOffset int64, shared *linearizedPart) (*core.PdfObjectStream, error) {
+	// Page offset hint table. The content stream offsets and lengths are not used by viewers and
+	// are given as the page lengths, as written by Acrobat.
+	numObjects := []uint64{uint64(len(firstPage.nums))}
+	lengths := []uint64{uint64(firstPage.size())}
+	for i := range pages {
+		numObjects = append(numObjects, uint64(len(pages[i].nums)))
+		lengths = append(lengths, uint64(pages[i].size()))
+	}
+	refs := append([][]int{nil}, layout.sharedRefs...)
+	numRefs := make([]uint64, len(refs))
+	var maxRef uint64
+	for i, pageRefs := range refs {
+		numRefs[i] = uint64(len(pageRefs))
+		for _, ref := range pageRefs {
+			if uint64(ref) > maxRef {
+				maxRef = uint64(ref)
+			}
+		}
+	}
+	minObjects, objectBits := deltaRange(numObjects)
+	minLength, lengthBits := deltaRange(lengths)
+	_, refBits := deltaRange(append(numRefs, 0))
+
+	b := writer.BufferedMSB()
+	for _, item := range []struct {
+		value uint64
+		nbits int
+	}{
+		{minObjects, 32}, {uint64(firstPageOffset), 32}, {uint64(objectBits), 16},
+		{minLength, 32}, {uint64(lengthBits), 16},
+		{0, 32}, {0, 16}, // Content stream offsets.
+		{minLength, 32}, {uint64(lengthBits), 16}, // Content stream lengths.
+		{uint64(refBits), 16}, {uint64(bitLength(maxRef)), 16},
+		{0, 16}, {1, 16}, // Fractional positions of the shared object references.
+	} {
+		b.WriteBits(item.value, item.nbits)
+	}
+	writeItem := func(values []uint64, min uint64, nbits int) {
+		for _, v := range values {
+			b.WriteBits(v-min, nbits)
+		}
+		b.FinishByte()
+	}
+	writeItem(numObjects, minObjects, objectBits)
+	writeItem(lengths, minLength, lengthBits)
+	writeItem(numRefs, 0, refBits)
+	var ids []uint64
+	for _, pageRefs := range refs {
+		for _, ref := range pageRefs {
+			ids = append(ids, uint64(ref))
+		}
+	}
+	writeItem(ids, 0, bitLength(maxRef))
+	writeItem(make([]uint64, len(lengths)), 0, 0) // Content stream offsets.
+	writeItem(lengths, minLength, lengthBits)     // Content stream lengths.
+	sharedTableOffset := len(b.Data())
+
+	// Shared object hint table, with a group for each object of the first page and shared
+	// objects sections.
+	var groupLengths []uint64
+	for _, part := range []*linearizedPart{firstPage, shared} {
+		for _, data := range part.data {
+			groupLengths = append(groupLengths, uint64(len(data)))
+		}
+	}
+	minGroupLength, groupLengthBits := deltaRange(groupLengths)
+	var firstSharedNum, firstSharedOffset uint64
+	if len(shared.nums) > 0 {
+		firstSharedNum, firstSharedOffset = uint64(shared.nums[0]), uint64(sharedOffset)
+	}
+	for _, item := range []struct {
+		value uint64
+		nbits int
+	}{
+		{firstSharedNum, 32}, {firstSharedOffset, 32},
+		{uint64(len(firstPage.nums)), 32}, {uint64(len(groupLengths)), 32},
+		{0, 16}, // Groups of single objects.
+		{minGroupLength, 32}, {uint64(groupLengthBits), 16},
+	} {
+		b.WriteBits(item.value, item.nbits)
+	}
+	writeItem(groupLengths, minGroupLength, groupLengthBits)
+	writeItem(make([]uint64, len(groupLengths)), 0, 1) // No MD5 signatures.
+	writeItem(make([]uint64, len(groupLengths)), 0, 0) // Number of objects minus 1.
+
+	stream, err := core.MakeStream(b.Data(), core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	stream.ObjectNumber = layout.hintNum
+	stream.Set("S", core.MakeInteger(int64(sharedTableOffset)))
+	return stream, nil
+}
+
+// deltaRange returns the least value of `values` and the number of bits needed for the
+// differences between the values and the least value.
+func deltaRange(values []uint64) (uint64, int) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, bitLength(max - min)
+}
+
+// bitLength returns the number of bits needed for representing `v`.
+func bitLength(v uint64) int {
+	return bits.Len64(v)
+}
+
+// LinearizationReport is the result of checking whether a document is linearized.
+type LinearizationReport struct {
+	// Linearized is true if the document has a linearization parameter dictionary.
+	Linearized bool
+
+	// Problems are the reasons why a document with a linearization parameter dictionary is not
+	// properly linearized, e.g. because it was updated incrementally after linearization.
+	Problems []string
+}
+
+// IsValid returns true if the document is properly linearized.
+func (report *LinearizationReport) IsValid() bool {
+	return report.Linearized && len(report.Problems) == 0
+}
+
+// addProblem adds a problem to `report`.
+func (report *LinearizationReport) addProblem(format string, args ...interface{}) {
+	report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+}
+
+// reFirstObject matches the start of the first object of a file.
+var reFirstObject = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+
+// reXrefEntry matches the first entry of a cross-reference table.
+var reXrefEntry = regexp.MustCompile(`^\s*\d{10} \d{5} [fn]`)
+
+// CheckLinearization checks whether the document is properly linearized: the first object of the
+// file is a linearization parameter dictionary whose parameters match the document, the objects
+// of the first page precede the end of the first page section and the pages are located where the
+// page offset hint table places them.
+func (r *PdfReader) CheckLinearization() (*LinearizationReport, error) {
+	report := &LinearizationReport{}
+	fileSize, err := r.rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	start, err := r.parser.ReadBytesAt(0, minInt64(fileSize, 1024))
+	if err != nil {
+		return nil, err
+	}
+	match := reFirstObject.FindSubmatch(start)
+	if match == nil {
+		return report, nil
+	}
+	num, _ := strconv.Atoi(string(match[1]))
+	obj, err := r.parser.LookupByNumber(num)
+	if err != nil {
+		return report, nil
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok || dict.Get("Linearized") == nil {
+		return report, nil
+	}
+	report.Linearized = true
+
+	param := func(key core.PdfObjectName) int64 {
+		v, ok := core.GetIntVal(dict.Get(key))
+		if !ok {
+			report.addProblem("missing or invalid %s", key)
+		}
+		return int64(v)
+	}
+	length, end, numPages, firstPageNum, mainXref := param("L"), param("E"), param("N"), param("O"), param("T")
+	hint, ok := core.GetArray(dict.Get("H"))
+	var hintOffset, hintLength int64
+	if ok && hint.Len() >= 2 {
+		offsets, err := hint.ToInt64Slice()
+		if err != nil {
+			report.addProblem("invalid H")
+		} else {
+			hintOffset, hintLength = offsets[0], offsets[1]
+		}
+	} else {
+		report.addProblem("missing or invalid H")
+	}
+	if len(report.Problems) > 0 {
+		return report, nil
+	}
+
+	if length != fileSize {
+		report.addProblem("file length %d differs from L %d, the file was changed after linearization", fileSize, length)
+	}
+	if int(numPages) != len(r.pageList) {
+		report.addProblem("N %d differs from the number of pages %d", numPages, len(r.pageList))
+	}
+	if len(r.pageList) > 0 && r.pageList[0].ObjectNumber != firstPageNum {
+		report.addProblem("O %d is not the first page object %d", firstPageNum, r.pageList[0].ObjectNumber)
+	}
+	if data, err := r.parser.ReadBytesAt(mainXref, minInt64(fileSize-mainXref, 24)); err != nil ||
+		!reXrefEntry.Match(data) {
+		report.addProblem("T %d is not the location of the main cross-reference table", mainXref)
+	}
+
+	// The objects of the first page are located in the first page section.
+	xrefs := r.parser.GetXrefTable()
+	objectOffset := func(num int64) (int64, bool) {
+		xref, ok := xrefs.ObjectMap[int(num)]
+		if ok && xref.XType == core.XrefTypeObjectStream {
+			xref, ok = xrefs.ObjectMap[xref.OsObjNumber]
+		}
+		return xref.Offset, ok && xref.XType == core.XrefTypeTableEntry
+	}
+	if len(r.pageList) > 0 {
+		for _, num := range r.pageObjectNumbers(r.pageList[0]) {
+			if offset, ok := objectOffset(num); ok && offset >= end {
+				report.addProblem("object %d of the first page is located after the first page section", num)
+				break
+			}
+		}
+	}
+
+	r.checkPageOffsetHints(report, hintOffset, hintLength, objectOffset)
+	return report, nil
+}
+
+// IsLinearized returns true if the document is properly linearized. See CheckLinearization.
+func (r *PdfReader) IsLinearized() (bool, error) {
+	report, err := r.CheckLinearization()
+	if err != nil {
+		return false, err
+	}
+	return report.IsValid(), nil
+}
+
+// checkPageOffsetHints checks that the page objects are located where the page offset hint table of
+// the hint stream at `hintOffset` places them, adding the problems found to `report`.
+func (r *PdfReader) checkPageOffsetHints(report *LinearizationReport, hintOffset, hintLength int64,
+	objectOffset func(num int64) (int64, bool)) {
+	var stream *core.PdfObjectStream
+	for num, xref := range r.parser.GetXrefTable().ObjectMap {
+		if xref.XType == core.XrefTypeTableEntry && xref.Offset == hintOffset {
+			obj, err := r.parser.LookupByNumber(num)
+			if err == nil {
+				stream, _ = core.GetStream(obj)
+			}
+			break
+		}
+	}
+	if stream == nil {
+		report.addProblem("no hint stream at offset %d", hintOffset)
+		return
+	}
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		report.addProblem("invalid hint stream: %v", err)
+		return
+	}
+
+	br := reader.New(data)
+	readBits := func(n int) uint64 {
+		if n == 0 || err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = br.ReadBits(byte(n))
+		return v
+	}
+	header := make([]uint64, 13)
+	for i, n := range []int{32, 32, 16, 32, 16, 32, 16, 32, 16, 16, 16, 16, 16} {
+		header[i] = readBits(n)
+	}
+	objectBits, lengthBits := int(header[2]), int(header[4])
+	if objectBits > 32 || lengthBits > 32 {
+		report.addProblem("invalid page offset hint table")
+		return
+	}
+	numPages := len(r.pageList)
+	for i := 0; i < numPages; i++ {
+		readBits(objectBits)
+	}
+	br.Align()
+	offset := int64(header[1])
+	for i := 0; i < numPages && err == nil; i++ {
+		// Hint table offsets do not include the hint stream.
+		expected := offset
+		if expected >= hintOffset {
+			expected += hintLength
+		}
+		if actual, ok := objectOffset(r.pageList[i].ObjectNumber); !ok || actual != expected {
+			report.addProblem("page %d is located at %d, the hint table gives %d", i+1, actual, expected)
+			return
+		}
+		offset += int64(header[3] + readBits(lengthBits))
+	}
+	if err != nil {
+		report.addProblem("invalid page offset hint table: %v", err)
+	}
+}
+
+// pageObjectNumbers returns the numbers of the objects used by the page object `page`, without
+// following Parent entries and entering other pages.
+func (r *PdfReader) pageObjectNumbers(page *core.PdfIndirectObject) []int64 {
+	pages := map[int64]bool{}
+	for _, p := range r.pageList {
+		pages[p.ObjectNumber] = true
+	}
+	var nums []int64
+	visited := map[int64]bool{}
+	var visit func(obj core.PdfObject, depth int)
+	visit = func(obj core.PdfObject, depth int) {
+		if depth > 100 {
+			return
+		}
+		var num int64
+		switch t := obj.(type) {
+		case *core.PdfObjectReference:
+			num = t.ObjectNumber
+		case *core.PdfIndirectObject:
+			num = t.ObjectNumber
+		case *core.PdfObjectStream:
+			num = t.ObjectNumber
+		}
+		if num != 0 {
+			if visited[num] || pages[num] && num != page.ObjectNumber {
+				return
+			}
+			visited[num] = true
+			nums = append(nums, num)
+			resolved, err := r.parser.LookupByNumber(int(num))
+			if err != nil {
+				return
+			}
+			obj = resolved
+		}
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			visit(t.PdfObject, depth+1)
+		case *core.PdfObjectStream:
+			visit(t.PdfObjectDictionary, depth+1)
+		case *core.PdfObjectDictionary:
+			for _, key := range t.Keys() {
+				if key != "Parent" {
+					visit(t.Get(key), depth+1)
+				}
+			}
+		case *core.PdfObjectArray:
+			for _, elem := range t.Elements() {
+				visit(elem, depth+1)
+			}
+		}
+	}
+	visit(page, 0)
+	return nums
+}
+
+// minInt64 returns the lesser of `a` and `b`.
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
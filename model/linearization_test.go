@@ -0,0 +1,139 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+)
+
+// makeLinearizationTestWriter returns a writer of a document with `numPages` pages. The pages after
+// the first page share a font, and each page has its own content stream.
+func makeLinearizationTestWriter(t *testing.T, numPages int) *PdfWriter {
+	helvetica := NewStandard14FontMustCompile(HelveticaName)
+	courier := NewStandard14FontMustCompile(CourierName)
+	writer := NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+		font := courier
+		if i > 0 {
+			font = helvetica
+		}
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		require.NoError(t, page.SetContentStreams([]string{
+			fmt.Sprintf("BT /F1 12 Tf 50 700 Td (Page %d) Tj ET", i+1),
+		}, nil))
+		require.NoError(t, writer.AddPage(page))
+	}
+	return &writer
+}
+
+func TestWriteLinearized(t *testing.T) {
+	for _, numPages := range []int{1, 4} {
+		writer := makeLinearizationTestWriter(t, numPages)
+		writer.SetLinearized(true)
+		var buf bytes.Buffer
+		require.NoError(t, writer.Write(&buf))
+		require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.3\n")))
+
+		reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		report, err := reader.CheckLinearization()
+		require.NoError(t, err)
+		assert.True(t, report.Linearized)
+		assert.Empty(t, report.Problems)
+		assert.True(t, report.IsValid())
+
+		n, err := reader.GetNumPages()
+		require.NoError(t, err)
+		require.Equal(t, numPages, n)
+		for i := 1; i <= numPages; i++ {
+			page, err := reader.GetPage(i)
+			require.NoError(t, err)
+			content, err := page.GetAllContentStreams()
+			require.NoError(t, err)
+			assert.Contains(t, content, fmt.Sprintf("(Page %d)", i))
+		}
+	}
+}
+
+func TestWriteLinearizedEncrypted(t *testing.T) {
+	for _, algorithm := range []EncryptionAlgorithm{RC4128bit, AES128bit} {
+		writer := makeLinearizationTestWriter(t, 3)
+		writer.SetLinearized(true)
+		require.NoError(t, writer.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: algorithm}))
+		var buf bytes.Buffer
+		require.NoError(t, writer.Write(&buf))
+
+		reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		ok, err := reader.Decrypt([]byte("owner"))
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// The hint stream is encrypted like the other streams.
+		report, err := reader.CheckLinearization()
+		require.NoError(t, err)
+		assert.True(t, report.Linearized)
+		assert.Empty(t, report.Problems, "algorithm %d", algorithm)
+
+		page, err := reader.GetPage(3)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		assert.Contains(t, content, "(Page 3)")
+	}
+}
+
+func TestCheckLinearization(t *testing.T) {
+	// A document which is not linearized.
+	var buf bytes.Buffer
+	require.NoError(t, makeLinearizationTestWriter(t, 2).Write(&buf))
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	linearized, err := reader.IsLinearized()
+	require.NoError(t, err)
+	assert.False(t, linearized)
+
+	// A linearized document changed by an incremental update is not properly linearized.
+	buf.Reset()
+	writer := makeLinearizationTestWriter(t, 2)
+	writer.SetLinearized(true)
+	require.NoError(t, writer.Write(&buf))
+	reader, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(reader)
+	require.NoError(t, err)
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	appender.AddPages(page)
+	var updated bytes.Buffer
+	require.NoError(t, appender.Write(&updated))
+
+	reader, err = NewPdfReader(bytes.NewReader(updated.Bytes()))
+	require.NoError(t, err)
+	report, err := reader.CheckLinearization()
+	require.NoError(t, err)
+	assert.True(t, report.Linearized)
+	assert.False(t, report.IsValid())
+	assert.NotEmpty(t, report.Problems)
+
+	// Linearized output does not support object streams.
+	writer = makeLinearizationTestWriter(t, 1)
+	writer.SetLinearized(true)
+	writer.SetOptimizer(&objectStreamsOptimizer{})
+	assert.Error(t, writer.Write(&buf))
+}
+
+// objectStreamsOptimizer adds an object stream to the objects.
+type objectStreamsOptimizer struct{}
+
+// Optimize implements the Optimizer interface.
+func (o *objectStreamsOptimizer) Optimize(objects []core.PdfObject) ([]core.PdfObject, error) {
+	return append(objects, &core.PdfObjectStreams{}), nil
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"sync"
+
 	"github.com/moolekkari/unipdf/core"
 )
 
@@ -23,6 +25,7 @@ type PdfModel interface {
 // object is used by two higher level objects. (Example PDF Widgets owned by both Page Annotations,
 // and the interactive form - AcroForm).
 type modelManager struct {
+	mu             sync.RWMutex
 	primitiveCache map[PdfModel]core.PdfObject
 	modelCache     map[core.PdfObject]PdfModel
 }
@@ -37,12 +40,16 @@ func newModelManager() *modelManager {
 
 // Register registers (caches) a model to primitive object relationship.
 func (mm *modelManager) Register(primitive core.PdfObject, model PdfModel) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
 	mm.primitiveCache[model] = primitive
 	mm.modelCache[primitive] = model
 }
 
 // GetPrimitiveFromModel returns the primitive object corresponding to the input `model`.
 func (mm *modelManager) GetPrimitiveFromModel(model PdfModel) core.PdfObject {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
 	primitive, has := mm.primitiveCache[model]
 	if !has {
 		return nil
@@ -52,6 +59,8 @@ func (mm *modelManager) GetPrimitiveFromModel(model PdfModel) core.PdfObject {
 
 // GetModelFromPrimitive returns the model corresponding to the `primitive` PdfObject.
 func (mm *modelManager) GetModelFromPrimitive(primitive core.PdfObject) PdfModel {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
 	model, has := mm.modelCache[primitive]
 	if !has {
 		return nil
@@ -0,0 +1,866 @@
+package optimize
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/cmap"
+	"github.com/moolekkari/unipdf/internal/textencoding"
+	"github.com/moolekkari/unipdf/model"
+	"github.com/moolekkari/unipdf/model/internal/fonts"
+)
+
+// CombineDuplicateFonts combines fonts with the same font program, e.g. the subsets of a font in
+// merged documents, into one font with the glyphs of all of them. Unlike CombineDuplicateStreams,
+// which only combines identical streams, the font programs of the fonts need not be identical.
+// Composite TrueType fonts with the Identity-H encoding and simple TrueType fonts are combined. The
+// character codes of simple fonts whose encodings conflict are remapped in the content streams of
+// the pages and form XObjects that use them.
+// It implements interface model.Optimizer.
+type CombineDuplicateFonts struct {
+}
+
+// duplicateFont is a font that can be combined with other fonts with the same font program.
+type duplicateFont struct {
+	obj        *core.PdfIndirectObject
+	dict       *core.PdfObjectDictionary
+	cidFont    *core.PdfObjectDictionary // The descendant font of composite fonts.
+	descriptor *core.PdfObjectDictionary
+	fontFile   *core.PdfObjectStream
+	program    []byte // The decoded font program.
+}
+
+// combinedFont is the result of combining a group of fonts into the first font of the group.
+type combinedFont struct {
+	// The character codes of the fonts of the group that are changed in the combined font.
+	remaps map[*core.PdfIndirectObject]map[byte]byte
+	// apply changes the first font of the group to the combined font and returns the new objects
+	// it references. The changes are prepared when the fonts are combined, so that it cannot fail.
+	apply func() []core.PdfObject
+}
+
+// flateData is data compressed with the Flate filter, for setting the data of a stream.
+type flateData struct {
+	data    []byte // The uncompressed data.
+	encoded []byte
+	dict    *core.PdfObjectDictionary
+}
+
+// simpleGlyph is a glyph of a simple font, selected by the rune of its character code.
+type simpleGlyph struct {
+	r     rune
+	width float64
+}
+
+// Optimize optimizes PDF objects to decrease PDF size.
+func (c *CombineDuplicateFonts) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfObject, err error) {
+	var keys []string
+	groups := make(map[string][]*duplicateFont)
+	for _, obj := range objects {
+		font, key, ok := newDuplicateFont(obj)
+		if !ok {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], font)
+	}
+
+	replaceTable := make(map[core.PdfObject]core.PdfObject)
+	var newObjects []core.PdfObject
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		var combined *combinedFont
+		if group[0].cidFont != nil {
+			combined, err = combineCompositeFonts(group)
+		} else {
+			combined, err = combineSimpleFonts(group)
+		}
+		var contents map[*core.PdfObjectStream][]byte
+		if err == nil && len(combined.remaps) > 0 {
+			contents, err = remapContentStreams(objects, combined.remaps)
+		}
+		if err != nil {
+			common.Log.Debug("ERROR: Unable to combine fonts %s: %v", key, err)
+			continue
+		}
+
+		// Nothing is changed until all the changes of the group have been prepared.
+		for stream, encoded := range contents {
+			stream.Stream = encoded
+			stream.Set("Length", core.MakeInteger(int64(len(encoded))))
+		}
+		fontObjects := combined.apply()
+		for _, font := range group[1:] {
+			replaceTable[font.obj] = group[0].obj
+		}
+		newObjects = append(newObjects, fontObjects...)
+	}
+	if len(replaceTable) == 0 {
+		return objects, nil
+	}
+
+	toDelete := unreferencedObjects(objects, replaceTable)
+	optimizedObjects = make([]core.PdfObject, 0, len(objects)-len(toDelete)+len(newObjects))
+	for _, obj := range objects {
+		if _, found := toDelete[obj]; found {
+			continue
+		}
+		optimizedObjects = append(optimizedObjects, obj)
+	}
+	optimizedObjects = append(optimizedObjects, newObjects...)
+	replaceObjectsInPlace(optimizedObjects, replaceTable)
+	return optimizedObjects, nil
+}
+
+// newDuplicateFont returns the font of `obj` if it is a font that can be combined with other fonts,
+// and the key of the fonts it can be combined with.
+func newDuplicateFont(obj core.PdfObject) (*duplicateFont, string, bool) {
+	ind, ok := obj.(*core.PdfIndirectObject)
+	if !ok {
+		return nil, "", false
+	}
+	dict, ok := core.GetDict(ind.PdfObject)
+	if !ok {
+		return nil, "", false
+	}
+	if typ, _ := core.GetNameVal(dict.Get("Type")); typ != "Font" {
+		return nil, "", false
+	}
+	font := &duplicateFont{obj: ind, dict: dict}
+	basefont, _ := core.GetNameVal(dict.Get("BaseFont"))
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	switch subtype {
+	case "TrueType":
+		font.descriptor, ok = core.GetDict(dict.Get("FontDescriptor"))
+		if !ok || dict.Get("ToUnicode") != nil {
+			return nil, "", false
+		}
+		// The glyphs of symbolic fonts are not selected by the runes of their encodings.
+		if flags, _ := core.GetIntVal(font.descriptor.Get("Flags")); flags&4 != 0 {
+			return nil, "", false
+		}
+	case "Type0":
+		if encoding, _ := core.GetNameVal(dict.Get("Encoding")); encoding != "Identity-H" {
+			return nil, "", false
+		}
+		descendants, ok := core.GetArray(dict.Get("DescendantFonts"))
+		if !ok || descendants.Len() != 1 {
+			return nil, "", false
+		}
+		font.cidFont, ok = core.GetDict(descendants.Get(0))
+		if !ok {
+			return nil, "", false
+		}
+		if subtype, _ := core.GetNameVal(font.cidFont.Get("Subtype")); subtype != "CIDFontType2" {
+			return nil, "", false
+		}
+		if m := font.cidFont.Get("CIDToGIDMap"); m != nil {
+			if name, _ := core.GetNameVal(m); name != "Identity" {
+				return nil, "", false
+			}
+		}
+		font.descriptor, ok = core.GetDict(font.cidFont.Get("FontDescriptor"))
+		if !ok {
+			return nil, "", false
+		}
+		basefont, _ = core.GetNameVal(font.cidFont.Get("BaseFont"))
+	default:
+		return nil, "", false
+	}
+
+	font.fontFile, ok = core.GetStream(font.descriptor.Get("FontFile2"))
+	if !ok {
+		return nil, "", false
+	}
+	program, err := core.DecodeStream(font.fontFile)
+	if err != nil {
+		return nil, "", false
+	}
+	hash, err := fonts.TrueTypeHash(program)
+	if err != nil {
+		return nil, "", false
+	}
+	font.program = program
+	return font, fmt.Sprintf("%s:%s:%s", subtype, stripSubsetTag(basefont), hash), true
+}
+
+// combineCompositeFonts combines the composite fonts `group` into the first font of the group.
+// The character codes of the fonts are the glyph indices of the font program, so they are not
+// changed.
+func combineCompositeFonts(group []*duplicateFont) (*combinedFont, error) {
+	program, err := mergeFontPrograms(group)
+	if err != nil {
+		return nil, err
+	}
+	dw := 1000.0
+	if v, err := core.GetNumberAsFloat(group[0].cidFont.Get("DW")); err == nil {
+		dw = v
+	}
+	widths := make(map[int]float64)
+	codeToUnicode := make(map[cmap.CharCode]rune)
+	for _, font := range group {
+		fontDW := 1000.0
+		if v, err := core.GetNumberAsFloat(font.cidFont.Get("DW")); err == nil {
+			fontDW = v
+		}
+		if fontDW != dw {
+			return nil, errors.New("different default widths")
+		}
+		fontWidths, err := parseCIDWidths(font.cidFont.Get("W"))
+		if err != nil {
+			return nil, err
+		}
+		for cid, w := range fontWidths {
+			if w0, ok := widths[cid]; ok && w0 != w {
+				return nil, fmt.Errorf("different widths of CID %d", cid)
+			}
+			widths[cid] = w
+		}
+
+		toUnicode, ok := core.GetStream(font.dict.Get("ToUnicode"))
+		if !ok {
+			continue
+		}
+		data, err := core.DecodeStream(toUnicode)
+		if err != nil {
+			return nil, err
+		}
+		cm, err := cmap.LoadCmapFromData(data, false)
+		if err != nil {
+			return nil, err
+		}
+		for code, r := range cm.CharcodeToUnicodeMap() {
+			if r0, ok := codeToUnicode[code]; ok && r0 != r {
+				return nil, fmt.Errorf("different runes of code %d", code)
+			}
+			codeToUnicode[code] = r
+		}
+	}
+
+	fontFile, err := newFlateData(program)
+	if err != nil {
+		return nil, err
+	}
+	target := group[0]
+	var cmapData *flateData
+	var newToUnicode *core.PdfObjectStream
+	if len(codeToUnicode) > 0 {
+		data := cmap.NewToUnicodeCMap(codeToUnicode).Bytes()
+		if _, ok := core.GetStream(target.dict.Get("ToUnicode")); ok {
+			cmapData, err = newFlateData(data)
+		} else {
+			newToUnicode, err = core.MakeStream(data, core.NewFlateEncoder())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	apply := func() []core.PdfObject {
+		setFontProgram(target, fontFile)
+		if w, ok := target.cidFont.Get("W").(*core.PdfIndirectObject); ok {
+			w.PdfObject = makeCIDWidthArr(widths)
+		} else {
+			target.cidFont.Set("W", makeCIDWidthArr(widths))
+		}
+		// The CIDs in the CIDSet are those of the font program of the first font.
+		target.descriptor.Remove("CIDSet")
+		if cmapData != nil {
+			toUnicode, _ := core.GetStream(target.dict.Get("ToUnicode"))
+			cmapData.set(toUnicode)
+		}
+		if newToUnicode != nil {
+			target.dict.Set("ToUnicode", newToUnicode)
+			return []core.PdfObject{newToUnicode}
+		}
+		return nil
+	}
+	return &combinedFont{apply: apply}, nil
+}
+
+// combineSimpleFonts combines the simple fonts `group` into the first font of the group. The
+// character codes of the fonts that map to other glyphs in the first font are changed to unused
+// codes.
+func combineSimpleFonts(group []*duplicateFont) (*combinedFont, error) {
+	program, err := mergeFontPrograms(group)
+	if err != nil {
+		return nil, err
+	}
+
+	remaps := make(map[*core.PdfIndirectObject]map[byte]byte)
+	codeGlyphs := make(map[byte]simpleGlyph)
+	glyphCodes := make(map[simpleGlyph]byte)
+	var targetEncoder textencoding.TextEncoder
+	targetWidths := make(map[byte]float64)
+	for i, font := range group {
+		pdfFont, err := model.NewPdfFontFromPdfObject(font.obj)
+		if err != nil {
+			return nil, err
+		}
+		encoder := pdfFont.Encoder()
+		if encoder == nil {
+			return nil, errors.New("no encoding")
+		}
+		ttf, err := fonts.TtfParse(bytes.NewReader(font.program))
+		if err != nil {
+			return nil, err
+		}
+		first, _ := core.GetIntVal(font.dict.Get("FirstChar"))
+		widthsArr, ok := core.GetArray(font.dict.Get("Widths"))
+		if !ok {
+			return nil, errors.New("no widths")
+		}
+		widths, err := widthsArr.ToFloat64Array()
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			targetEncoder = encoder
+			for j, w := range widths {
+				if code := first + j; code >= 0 && code <= 255 {
+					targetWidths[byte(code)] = w
+				}
+			}
+		}
+
+		// The glyphs of the codes are selected by the runes of the encoding.
+		remap := make(map[byte]byte)
+		for j, w := range widths {
+			code := first + j
+			if code < 0 || code > 255 {
+				continue
+			}
+			r, ok := encoder.CharcodeToRune(textencoding.CharCode(code))
+			if !ok {
+				continue
+			}
+			if gid, ok := ttf.Chars[r]; !ok || gid == 0 {
+				continue
+			}
+			g := simpleGlyph{r, w}
+			newCode, ok := glyphCodes[g]
+			if !ok {
+				if _, used := codeGlyphs[byte(code)]; !used {
+					newCode = byte(code)
+				} else if newCode, ok = unusedCode(codeGlyphs); !ok {
+					return nil, errors.New("too many glyphs")
+				}
+				codeGlyphs[newCode] = g
+				glyphCodes[g] = newCode
+			}
+			if newCode != byte(code) {
+				remap[byte(code)] = newCode
+			}
+		}
+		if len(remap) > 0 {
+			remaps[font.obj] = remap
+		}
+	}
+
+	// The encoding of the first font is kept if it maps the codes to the same runes.
+	var differences []core.PdfObject
+	sameEncoding := true
+	winAnsi := textencoding.NewWinAnsiEncoder()
+	codes := make([]int, 0, len(codeGlyphs))
+	for code := range codeGlyphs {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		g := codeGlyphs[byte(code)]
+		targetWidths[byte(code)] = g.width
+		if r, ok := targetEncoder.CharcodeToRune(textencoding.CharCode(code)); !ok || r != g.r {
+			sameEncoding = false
+		}
+		if r, ok := winAnsi.CharcodeToRune(textencoding.CharCode(code)); ok && r == g.r {
+			continue
+		}
+		name, ok := textencoding.RuneToGlyph(g.r)
+		if !ok {
+			return nil, fmt.Errorf("no glyph name for rune %+q", g.r)
+		}
+		differences = append(differences, core.MakeInteger(int64(code)), core.MakeName(string(name)))
+	}
+
+	first, last := 255, 0
+	for code := range targetWidths {
+		if int(code) < first {
+			first = int(code)
+		}
+		if int(code) > last {
+			last = int(code)
+		}
+	}
+	widths := make([]float64, last-first+1)
+	for code, w := range targetWidths {
+		widths[int(code)-first] = w
+	}
+
+	fontFile, err := newFlateData(program)
+	if err != nil {
+		return nil, err
+	}
+	target := group[0]
+	apply := func() []core.PdfObject {
+		setFontProgram(target, fontFile)
+		if !sameEncoding {
+			encoding := core.MakeDict()
+			encoding.Set("Type", core.MakeName("Encoding"))
+			encoding.Set("BaseEncoding", core.MakeName("WinAnsiEncoding"))
+			if len(differences) > 0 {
+				encoding.Set("Differences", core.MakeArray(differences...))
+			}
+			target.dict.Set("Encoding", encoding)
+		}
+		target.dict.Set("FirstChar", core.MakeInteger(int64(first)))
+		target.dict.Set("LastChar", core.MakeInteger(int64(last)))
+		if w, ok := target.dict.Get("Widths").(*core.PdfIndirectObject); ok {
+			w.PdfObject = core.MakeArrayFromFloats(widths)
+		} else {
+			target.dict.Set("Widths", core.MakeArrayFromFloats(widths))
+		}
+		// The glyph names in the CharSet are those of the font program of the first font.
+		target.descriptor.Remove("CharSet")
+		return nil
+	}
+	return &combinedFont{remaps: remaps, apply: apply}, nil
+}
+
+// unusedCode returns an unused character code of the simple font with the glyphs `codeGlyphs`,
+// preferring printable codes.
+func unusedCode(codeGlyphs map[byte]simpleGlyph) (byte, bool) {
+	for i := 0; i < 255; i++ {
+		code := byte(0x21 + i) // 0x21, ..., 0xff, 0x00, ..., 0x1f
+		if _, used := codeGlyphs[code]; !used && code != 0 {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// mergeFontPrograms returns a font program with the glyphs of the font programs of the fonts
+// `group`.
+func mergeFontPrograms(group []*duplicateFont) ([]byte, error) {
+	var programs [][]byte
+	seen := make(map[*core.PdfObjectStream]struct{})
+	for _, font := range group {
+		if _, ok := seen[font.fontFile]; ok {
+			continue
+		}
+		seen[font.fontFile] = struct{}{}
+		programs = append(programs, font.program)
+	}
+	return fonts.MergeTrueType(programs)
+}
+
+// setFontProgram sets the font program of `font` to `program` and changes the subset tag of the
+// font names of `font` to a tag derived from `program`.
+func setFontProgram(font *duplicateFont, program *flateData) {
+	program.set(font.fontFile)
+	font.fontFile.Set("Length1", core.MakeInteger(int64(len(program.data))))
+	setSubsetTag(font, program.data)
+}
+
+// newFlateData returns `data` compressed with the Flate filter.
+func newFlateData(data []byte) (*flateData, error) {
+	encoder := core.NewFlateEncoder()
+	encoded, err := encoder.EncodeBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &flateData{data: data, encoded: encoded, dict: encoder.MakeStreamDict()}, nil
+}
+
+// set sets the data of `stream` to `d`.
+func (d *flateData) set(stream *core.PdfObjectStream) {
+	stream.Remove("DecodeParms")
+	stream.PdfObjectDictionary.Merge(d.dict)
+	stream.Stream = d.encoded
+	stream.Set("Length", core.MakeInteger(int64(len(d.encoded))))
+}
+
+// setSubsetTag sets the subset tag of the font names of `font` to a tag derived from the font
+// program `program`.
+func setSubsetTag(font *duplicateFont, program []byte) {
+	sum := md5.Sum(program)
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	dicts := []*core.PdfObjectDictionary{font.dict, font.descriptor}
+	if font.cidFont != nil {
+		dicts = append(dicts, font.cidFont)
+	}
+	for _, d := range dicts {
+		key := core.PdfObjectName("BaseFont")
+		if d == font.descriptor {
+			key = "FontName"
+		}
+		if name, ok := core.GetNameVal(d.Get(key)); ok {
+			d.Set(key, core.MakeName(string(tag)+"+"+stripSubsetTag(name)))
+		}
+	}
+}
+
+// stripSubsetTag returns the font name `name` without its subset tag, if it has one.
+func stripSubsetTag(name string) string {
+	if len(name) < 7 || name[6] != '+' {
+		return name
+	}
+	for _, c := range name[:6] {
+		if c < 'A' || c > 'Z' {
+			return name
+		}
+	}
+	return name[7:]
+}
+
+// parseCIDWidths returns the widths of the CIDs in the CID font W array `obj`.
+func parseCIDWidths(obj core.PdfObject) (map[int]float64, error) {
+	widths := make(map[int]float64)
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return widths, nil
+	}
+	elements := arr.Elements()
+	for i := 0; i < len(elements); {
+		first, ok := core.GetIntVal(elements[i])
+		if !ok || i+1 >= len(elements) {
+			return nil, errors.New("invalid W array")
+		}
+		if ws, ok := core.GetArray(elements[i+1]); ok {
+			values, err := ws.ToFloat64Array()
+			if err != nil {
+				return nil, err
+			}
+			for j, w := range values {
+				widths[first+j] = w
+			}
+			i += 2
+			continue
+		}
+		last, ok := core.GetIntVal(elements[i+1])
+		if !ok || i+2 >= len(elements) {
+			return nil, errors.New("invalid W array")
+		}
+		w, err := core.GetNumberAsFloat(elements[i+2])
+		if err != nil {
+			return nil, err
+		}
+		for cid := first; cid <= last; cid++ {
+			widths[cid] = w
+		}
+		i += 3
+	}
+	return widths, nil
+}
+
+// makeCIDWidthArr returns a CID font W array for the CID widths `widths`.
+func makeCIDWidthArr(widths map[int]float64) *core.PdfObjectArray {
+	cids := make([]int, 0, len(widths))
+	for cid := range widths {
+		cids = append(cids, cid)
+	}
+	sort.Ints(cids)
+
+	// Consecutive CIDs are listed in the format: c [w1 w2 ... wn].
+	arr := core.MakeArray()
+	for i := 0; i < len(cids); {
+		ws := core.MakeArray()
+		j := i
+		for ; j < len(cids) && (j == i || cids[j] == cids[j-1]+1); j++ {
+			if w := widths[cids[j]]; w == math.Trunc(w) {
+				ws.Append(core.MakeInteger(int64(w)))
+			} else {
+				ws.Append(core.MakeFloat(w))
+			}
+		}
+		arr.Append(core.MakeInteger(int64(cids[i])), ws)
+		i = j
+	}
+	return arr
+}
+
+// remapContentStreams returns the data of the content streams of the pages and form XObjects of
+// `objects` with the character codes of the fonts of `remaps` changed, encoded with the filters
+// of the streams. The streams are not changed. Form XObjects without resources use the resources
+// of the content streams that paint them. An error is returned if any content stream cannot be
+// parsed, or if such a form XObject is painted with different fonts.
+func remapContentStreams(objects []core.PdfObject, remaps map[*core.PdfIndirectObject]map[byte]byte) (map[*core.PdfObjectStream][]byte, error) {
+	contents := make(map[*core.PdfObjectStream][]byte)
+	processed := make(map[*core.PdfObjectStream]struct{})
+	// The resources used by the form XObjects without resources.
+	inherited := make(map[*core.PdfObjectStream]*core.PdfObjectDictionary)
+
+	var remapStreams func(streams []*core.PdfObjectStream, resources *core.PdfObjectDictionary) error
+	remapStreams = func(streams []*core.PdfObjectStream, resources *core.PdfObjectDictionary) error {
+		fontRemaps := resourceFontRemaps(resources, remaps)
+		if len(fontRemaps) > 0 {
+			if err := remapContents(streams, fontRemaps, contents, processed); err != nil {
+				return err
+			}
+		}
+		xobjects, ok := core.GetDict(resources.Get("XObject"))
+		if !ok {
+			return nil
+		}
+		for _, name := range xobjects.Keys() {
+			form, ok := core.GetStream(xobjects.Get(name))
+			if !ok || form.Get("Resources") != nil {
+				continue
+			}
+			if subtype, _ := core.GetNameVal(form.Get("Subtype")); subtype != "Form" {
+				continue
+			}
+			if res, ok := inherited[form]; ok {
+				if res != resources && (len(fontRemaps) > 0 || len(resourceFontRemaps(res, remaps)) > 0) {
+					return errors.New("form XObject without resources painted with different fonts")
+				}
+				continue
+			}
+			inherited[form] = resources
+			if err := remapStreams([]*core.PdfObjectStream{form}, resources); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, obj := range objects {
+		var resources core.PdfObject
+		var streams []*core.PdfObjectStream
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			resources = stream.Get("Resources")
+			streams = []*core.PdfObjectStream{stream}
+		} else if dict, ok := core.GetDict(obj); ok {
+			if typ, _ := core.GetNameVal(dict.Get("Type")); typ != "Page" {
+				continue
+			}
+			// Resources are inherited from the page tree.
+			for d := dict; d != nil && resources == nil; d, _ = core.GetDict(d.Get("Parent")) {
+				resources = d.Get("Resources")
+			}
+			if stream, ok := core.GetStream(dict.Get("Contents")); ok {
+				streams = append(streams, stream)
+			} else if arr, ok := core.GetArray(dict.Get("Contents")); ok {
+				for _, elem := range arr.Elements() {
+					if stream, ok := core.GetStream(elem); ok {
+						streams = append(streams, stream)
+					}
+				}
+			}
+		}
+		resDict, ok := core.GetDict(resources)
+		if !ok {
+			continue
+		}
+		if err := remapStreams(streams, resDict); err != nil {
+			return nil, err
+		}
+	}
+
+	encodedContents := make(map[*core.PdfObjectStream][]byte, len(contents))
+	for stream, data := range contents {
+		encoder, err := core.NewEncoderFromStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encoder.EncodeBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		encodedContents[stream] = encoded
+	}
+	return encodedContents, nil
+}
+
+// resourceFontRemaps returns the changes of the character codes of the fonts of the resources
+// `resources`, by resource name, for the changes `remaps` of the character codes of fonts.
+func resourceFontRemaps(resources *core.PdfObjectDictionary, remaps map[*core.PdfIndirectObject]map[byte]byte) map[core.PdfObjectName]map[byte]byte {
+	fontDict, ok := core.GetDict(resources.Get("Font"))
+	if !ok {
+		return nil
+	}
+	fontRemaps := make(map[core.PdfObjectName]map[byte]byte)
+	for _, name := range fontDict.Keys() {
+		if ind, ok := core.GetIndirect(fontDict.Get(name)); ok && remaps[ind] != nil {
+			fontRemaps[name] = remaps[ind]
+		}
+	}
+	return fontRemaps
+}
+
+// remapContents changes the character codes of the strings shown with the fonts of `fontRemaps`,
+// by resource name, in the content streams `streams` that are not in `processed`. The changed
+// content streams are added to `contents` and the content streams to `processed`.
+func remapContents(streams []*core.PdfObjectStream, fontRemaps map[core.PdfObjectName]map[byte]byte,
+	contents map[*core.PdfObjectStream][]byte, processed map[*core.PdfObjectStream]struct{}) error {
+	// The font is part of the graphics state, which is saved and restored by the q and Q
+	// operators, and is kept from one content stream of a page to the next.
+	var font core.PdfObjectName
+	var fontStack []core.PdfObjectName
+	remapString := func(obj core.PdfObject) (core.PdfObject, bool) {
+		remap := fontRemaps[font]
+		str, ok := core.GetString(obj)
+		if !ok || remap == nil {
+			return obj, false
+		}
+		data := str.Bytes()
+		changed := false
+		for i, b := range data {
+			if code, ok := remap[b]; ok {
+				data[i] = code
+				changed = true
+			}
+		}
+		if !changed {
+			return obj, false
+		}
+		if strings.HasPrefix(str.WriteString(), "<") {
+			return core.MakeHexString(string(data)), true
+		}
+		return core.MakeStringFromBytes(data), true
+	}
+
+	for _, stream := range streams {
+		if _, ok := processed[stream]; ok {
+			continue
+		}
+		processed[stream] = struct{}{}
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return err
+		}
+		ops, err := contentstream.NewContentStreamParser(string(data)).Parse()
+		if err != nil {
+			return err
+		}
+		changed := false
+		for _, op := range *ops {
+			switch op.Operand {
+			case "q":
+				fontStack = append(fontStack, font)
+			case "Q":
+				if len(fontStack) > 0 {
+					font = fontStack[len(fontStack)-1]
+					fontStack = fontStack[:len(fontStack)-1]
+				}
+			case "Tf":
+				if len(op.Params) == 2 {
+					if name, ok := core.GetName(op.Params[0]); ok {
+						font = *name
+					}
+				}
+			case "Tj", "'", "\"":
+				if len(op.Params) == 0 {
+					continue
+				}
+				last := len(op.Params) - 1
+				var ok bool
+				if op.Params[last], ok = remapString(op.Params[last]); ok {
+					changed = true
+				}
+			case "TJ":
+				if len(op.Params) == 0 {
+					continue
+				}
+				arr, ok := core.GetArray(op.Params[0])
+				if !ok {
+					continue
+				}
+				for i, elem := range arr.Elements() {
+					if obj, ok := remapString(elem); ok {
+						arr.Set(i, obj)
+						changed = true
+					}
+				}
+			}
+		}
+		if changed {
+			contents[stream] = ops.Bytes()
+		}
+	}
+	return nil
+}
+
+// unreferencedObjects returns the objects of `objects` that are replaced in `replaceTable` and
+// the objects only referenced by them.
+func unreferencedObjects(objects []core.PdfObject, replaceTable map[core.PdfObject]core.PdfObject) map[core.PdfObject]struct{} {
+	unreferenced := make(map[core.PdfObject]struct{})
+	var collect func(obj core.PdfObject)
+	collect = func(obj core.PdfObject) {
+		for _, ref := range objectReferences(obj) {
+			if _, ok := unreferenced[ref]; !ok {
+				unreferenced[ref] = struct{}{}
+				collect(ref)
+			}
+		}
+	}
+	for obj := range replaceTable {
+		unreferenced[obj] = struct{}{}
+		collect(obj)
+	}
+
+	// Objects that are referenced by other objects are kept.
+	for changed := true; changed; {
+		changed = false
+		for _, obj := range objects {
+			if _, ok := unreferenced[obj]; ok {
+				continue
+			}
+			for _, ref := range objectReferences(obj) {
+				if _, replaced := replaceTable[ref]; replaced {
+					continue
+				}
+				if _, ok := unreferenced[ref]; ok {
+					delete(unreferenced, ref)
+					changed = true
+				}
+			}
+		}
+	}
+	return unreferenced
+}
+
+// objectReferences returns the indirect objects and streams that are referenced by the indirect
+// object or stream `obj`.
+func objectReferences(obj core.PdfObject) []core.PdfObject {
+	var refs []core.PdfObject
+	var walk func(obj core.PdfObject)
+	walk = func(obj core.PdfObject) {
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject, *core.PdfObjectStream:
+			refs = append(refs, t)
+		case *core.PdfObjectArray:
+			for _, elem := range t.Elements() {
+				walk(elem)
+			}
+		case *core.PdfObjectDictionary:
+			for _, key := range t.Keys() {
+				walk(t.Get(key))
+			}
+		}
+	}
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		walk(t.PdfObject)
+	case *core.PdfObjectStream:
+		walk(t.PdfObjectDictionary)
+	case *core.PdfObjectStreams:
+		refs = append(refs, t.Elements()...)
+	}
+	return refs
+}
@@ -0,0 +1,218 @@
+package optimize_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/extractor"
+	"github.com/moolekkari/unipdf/model"
+	"github.com/moolekkari/unipdf/model/optimize"
+)
+
+const (
+	compositeTestFont = "../../creator/testdata/FreeSans.ttf"
+	simpleTestFont    = "../testdata/font/OpenSans-Regular.ttf"
+)
+
+// writeTestPDF writes the pages `pages` with the optimizer `optimizer` and returns the document.
+func writeTestPDF(t *testing.T, pages []*model.PdfPage, optimizer model.Optimizer) []byte {
+	writer := model.NewPdfWriter()
+	if optimizer != nil {
+		writer.SetOptimizer(optimizer)
+	}
+	for _, page := range pages {
+		require.NoError(t, writer.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+	return buf.Bytes()
+}
+
+// pageTexts returns the texts of the pages of document `data`.
+func pageTexts(t *testing.T, data []byte) []string {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	var texts []string
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		ex, err := extractor.New(page)
+		require.NoError(t, err)
+		text, err := ex.ExtractText()
+		require.NoError(t, err)
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+func TestCombineDuplicateFontsComposite(t *testing.T) {
+	// Each page shows text with its own subset of the same font.
+	makePages := func() []*model.PdfPage {
+		var pages []*model.PdfPage
+		for _, text := range []string{"Hello", "World"} {
+			font, err := model.NewCompositePdfFontFromTTFFile(compositeTestFont)
+			require.NoError(t, err)
+			page := model.NewPdfPage()
+			page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+			require.NoError(t, page.Resources.SetFont("F1", font))
+			encoded := core.MakeHexString(string(font.Encoder().Encode(text))).WriteString()
+			require.NoError(t, page.SetContentStreams([]string{"BT /F1 12 Tf 50 700 Td " + encoded + " Tj ET"}, nil))
+			pages = append(pages, page)
+		}
+		return pages
+	}
+	original := writeTestPDF(t, makePages(), nil)
+	optimized := writeTestPDF(t, makePages(), optimize.New(optimize.Options{CombineDuplicateFonts: true}))
+	assert.Less(t, len(optimized), len(original))
+	assert.Equal(t, []string{"Hello", "World"}, pageTexts(t, optimized))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(optimized))
+	require.NoError(t, err)
+	inventory, err := reader.GetFontInventory()
+	require.NoError(t, err)
+	var entries []*model.FontInventoryEntry
+	for _, entry := range inventory {
+		if len(entry.Charcodes) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.True(t, entry.Subset)
+	assert.Equal(t, []int{1, 2}, entry.Pages)
+	assert.Equal(t, []rune("HWdelor"), entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+}
+
+// simpleFontTestPages returns two pages showing "Az" and "A" with their own simple font F1 made
+// from the same font program. Code 65 of the font of the second page shows "z".
+func simpleFontTestPages(t *testing.T) []*model.PdfPage {
+	var pages []*model.PdfPage
+	for _, text := range []string{"Az", "A"} {
+		font, err := model.NewPdfFontFromTTFFile(simpleTestFont)
+		require.NoError(t, err)
+		page := model.NewPdfPage()
+		page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		require.NoError(t, page.SetContentStreams([]string{"BT /F1 12 Tf 50 700 Td (" + text + ") Tj ET"}, nil))
+		pages = append(pages, page)
+	}
+	reader, err := model.NewPdfReader(bytes.NewReader(writeTestPDF(t, pages, nil)))
+	require.NoError(t, err)
+
+	pages = nil
+	for i := 1; i <= 2; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		pages = append(pages, page)
+	}
+	obj, ok := pages[1].Resources.GetFontByName("F1")
+	require.True(t, ok)
+	dict, ok := core.GetDict(obj)
+	require.True(t, ok)
+	encoding := core.MakeDict()
+	encoding.Set("BaseEncoding", core.MakeName("WinAnsiEncoding"))
+	encoding.Set("Differences", core.MakeArray(core.MakeInteger(65), core.MakeName("z")))
+	dict.Set("Encoding", encoding)
+	return pages
+}
+
+// pageFonts returns the font objects F1 of the pages of document `data`.
+func pageFonts(t *testing.T, data []byte) []core.PdfObject {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	return readerPageFonts(t, reader)
+}
+
+// readerPageFonts returns the font objects F1 of the pages of `reader`.
+func readerPageFonts(t *testing.T, reader *model.PdfReader) []core.PdfObject {
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	var fonts []core.PdfObject
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		obj, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		fonts = append(fonts, obj)
+	}
+	return fonts
+}
+
+// addTextForm makes the pages `pages` paint a form XObject without resources that shows "A" with
+// the font F1 of the resources of the page.
+func addTextForm(t *testing.T, pages ...*model.PdfPage) {
+	form, err := core.MakeStream([]byte("BT /F1 12 Tf 50 600 Td (A) Tj ET"), nil)
+	require.NoError(t, err)
+	form.Set("Type", core.MakeName("XObject"))
+	form.Set("Subtype", core.MakeName("Form"))
+	form.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 612, 792}))
+	for _, page := range pages {
+		require.NoError(t, page.Resources.SetXObjectByName("Fm1", form))
+		contents, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		require.NoError(t, page.SetContentStreams([]string{contents + "\n/Fm1 Do"}, nil))
+	}
+}
+
+func TestCombineDuplicateFontsSimple(t *testing.T) {
+	// Code 65 shows "z" on page 2, so it is changed to another code when the fonts are combined.
+	pages := simpleFontTestPages(t)
+	optimized := writeTestPDF(t, pages, optimize.New(optimize.Options{CombineDuplicateFonts: true}))
+	assert.Equal(t, []string{"Az", "z"}, pageTexts(t, optimized))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(optimized))
+	require.NoError(t, err)
+	fonts := readerPageFonts(t, reader)
+	assert.Same(t, fonts[0], fonts[1])
+
+	inventory, err := reader.GetFontInventory()
+	require.NoError(t, err)
+	var entry *model.FontInventoryEntry
+	for _, e := range inventory {
+		if e.Object == fonts[0] {
+			entry = e
+		}
+	}
+	require.NotNil(t, entry)
+	assert.Equal(t, []int{1, 2}, entry.Pages)
+	assert.Len(t, entry.Charcodes, 3)
+	assert.Equal(t, []rune("Az"), entry.Runes)
+	assert.Empty(t, entry.MissingGlyphs)
+}
+
+func TestCombineDuplicateFontsInheritedResources(t *testing.T) {
+	// The form uses the font of page 2, whose codes are changed.
+	pages := simpleFontTestPages(t)
+	addTextForm(t, pages[1])
+	expected := pageTexts(t, writeTestPDF(t, pages, nil))
+	assert.Contains(t, expected[1], "z")
+
+	pages = simpleFontTestPages(t)
+	addTextForm(t, pages[1])
+	optimized := writeTestPDF(t, pages, optimize.New(optimize.Options{CombineDuplicateFonts: true}))
+	assert.Equal(t, expected, pageTexts(t, optimized))
+	fonts := pageFonts(t, optimized)
+	assert.Same(t, fonts[0], fonts[1])
+}
+
+func TestCombineDuplicateFontsConflictingForm(t *testing.T) {
+	// The form is painted with the fonts of both pages, so its codes cannot be changed for the font
+	// of page 2 only. The fonts are not combined and nothing is changed.
+	pages := simpleFontTestPages(t)
+	addTextForm(t, pages...)
+	expected := pageTexts(t, writeTestPDF(t, pages, nil))
+
+	pages = simpleFontTestPages(t)
+	addTextForm(t, pages...)
+	optimized := writeTestPDF(t, pages, optimize.New(optimize.Options{CombineDuplicateFonts: true}))
+	assert.Equal(t, expected, pageTexts(t, optimized))
+	fonts := pageFonts(t, optimized)
+	assert.NotSame(t, fonts[0], fonts[1])
+}
@@ -17,6 +17,9 @@ func New(options Options) *Chain {
 		imageOptimizer.ImageQuality = options.ImageQuality
 		chain.Append(imageOptimizer)
 	}
+	if options.CombineDuplicateFonts {
+		chain.Append(new(CombineDuplicateFonts))
+	}
 	if options.CombineDuplicateDirectObjects {
 		chain.Append(new(CombineDuplicateDirectObjects))
 	}
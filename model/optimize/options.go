@@ -3,6 +3,7 @@ package optimize
 // Options describes PDF optimization parameters.
 type Options struct {
 	CombineDuplicateStreams         bool
+	CombineDuplicateFonts           bool
 	CombineDuplicateDirectObjects   bool
 	ImageUpperPPI                   float64
 	ImageQuality                    int
@@ -294,11 +294,11 @@ func newPdfTilingPatternFromDictionary(dict *core.PdfObjectDictionary) (*PdfTili
 		common.Log.Debug("Resources missing")
 		return nil, ErrRequiredAttributeMissing
 	}
-	dict, ok = core.TraceToDirectObject(obj).(*core.PdfObjectDictionary)
+	resDict, ok := core.TraceToDirectObject(obj).(*core.PdfObjectDictionary)
 	if !ok {
 		return nil, fmt.Errorf("invalid resource dictionary (%T)", obj)
 	}
-	resources, err := NewPdfPageResourcesFromDict(dict)
+	resources, err := NewPdfPageResourcesFromDict(resDict)
 	if err != nil {
 		return nil, err
 	}
@@ -13,6 +13,13 @@ import (
 
 // PdfReader represents a PDF file reader. It is a frontend to the lower level parsing mechanism and provides
 // a higher level access to work with PDF structure and information, such as the page structure etc.
+//
+// Once created and, for encrypted documents, decrypted, a PdfReader can be used from multiple goroutines
+// for processing different pages concurrently, e.g. with GetPage, PdfPage.GetAllContentStreams and the
+// text and image extraction of package extractor. A PdfPage, and the objects it shares with other pages,
+// e.g. fonts, must not be modified while other goroutines use them, and ReleasePage must not be called
+// for a page used by other goroutines. Methods changing the reader, e.g. Decrypt, are not safe for
+// concurrent use.
 type PdfReader struct {
 	parser         *core.PdfParser
 	root           core.PdfObject
@@ -78,34 +85,7 @@ func NewPdfReader(rs io.ReadSeeker) (*PdfReader, error) {
 // Note that it may make sense to use the lazy-load reader when processing only parts of files,
 // rather than loading entire file into memory. Example: splitting a few pages from a large PDF file.
 func NewPdfReaderLazy(rs io.ReadSeeker) (*PdfReader, error) {
-	pdfReader := &PdfReader{
-		rs:           rs,
-		traversed:    map[core.PdfObject]struct{}{},
-		modelManager: newModelManager(),
-		isLazy:       true,
-	}
-
-	// Create the parser, loads the cross reference table and trailer.
-	parser, err := core.NewParser(rs)
-	if err != nil {
-		return nil, err
-	}
-	pdfReader.parser = parser
-
-	isEncrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return nil, err
-	}
-
-	// Load pdf doc structure if not encrypted.
-	if !isEncrypted {
-		err = pdfReader.loadStructure()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return pdfReader, nil
+	return NewPdfReaderLazyWithOptions(rs, nil)
 }
 
 // PdfVersion returns version of the PDF file.
@@ -667,17 +647,15 @@ func (r *PdfReader) GetNumPages() (int, error) {
 // Resolves a reference, returning the object and indicates whether or not
 // it was cached.
 func (r *PdfReader) resolveReference(ref *core.PdfObjectReference) (core.PdfObject, bool, error) {
-	cachedObj, isCached := r.parser.ObjCache[int(ref.ObjectNumber)]
+	isCached := r.parser.IsCached(int(ref.ObjectNumber))
 	if !isCached {
 		common.Log.Trace("Reader Lookup ref: %s", ref)
-		obj, err := r.parser.LookupByReference(*ref)
-		if err != nil {
-			return nil, false, err
-		}
-		r.parser.ObjCache[int(ref.ObjectNumber)] = obj
-		return obj, false, nil
 	}
-	return cachedObj, true, nil
+	obj, err := r.parser.LookupByReference(*ref)
+	if err != nil {
+		return nil, false, err
+	}
+	return obj, isCached, nil
 }
 
 /*
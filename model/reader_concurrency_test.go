@@ -0,0 +1,98 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+)
+
+// The pages of a reader are processed concurrently. Run with -race.
+func TestPdfReaderConcurrentPages(t *testing.T) {
+	const numPages = 16
+	font, err := NewCompositePdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+	writer := NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		encoded := font.Encoder().Encode(fmt.Sprintf("Page %d", i+1))
+		require.NoError(t, page.SetContentStreams([]string{
+			fmt.Sprintf("BT /F1 12 Tf 50 700 Td %s Tj ET", core.MakeHexString(string(encoded)).WriteString()),
+		}, core.NewFlateEncoder()))
+		require.NoError(t, writer.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	newReaders := map[string]func() (*PdfReader, error){
+		"full": func() (*PdfReader, error) {
+			return NewPdfReader(bytes.NewReader(buf.Bytes()))
+		},
+		"lazy": func() (*PdfReader, error) {
+			return NewPdfReaderLazy(bytes.NewReader(buf.Bytes()))
+		},
+		"bounded": func() (*PdfReader, error) {
+			return NewPdfReaderLazyWithOptions(bytes.NewReader(buf.Bytes()), &LazyReaderOptions{
+				CacheSize:   2048,
+				LazyStreams: true,
+			})
+		},
+	}
+	for name, newReader := range newReaders {
+		t.Run(name, func(t *testing.T) {
+			reader, err := newReader()
+			require.NoError(t, err)
+
+			var wg sync.WaitGroup
+			errs := make([]error, numPages)
+			texts := make([]string, numPages)
+			for i := 0; i < numPages; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					encoded := font.Encoder().Encode(fmt.Sprintf("Page %d", i+1))
+					texts[i], errs[i] = decodePageText(reader, i+1, encoded)
+				}(i)
+			}
+			wg.Wait()
+			for i := 0; i < numPages; i++ {
+				require.NoError(t, errs[i], "page %d", i+1)
+				assert.Equal(t, fmt.Sprintf("Page %d", i+1), texts[i])
+			}
+		})
+	}
+}
+
+// decodePageText checks the content stream and the font F1 of page `pageNum` of `reader`, and
+// returns the text of the page decoded with the font, given the encoded text `encoded`.
+func decodePageText(reader *PdfReader, pageNum int, encoded []byte) (string, error) {
+	page, err := reader.GetPage(pageNum)
+	if err != nil {
+		return "", err
+	}
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(content, core.MakeHexString(string(encoded)).WriteString()) {
+		return "", fmt.Errorf("missing text on page %d", pageNum)
+	}
+	obj, ok := page.Resources.GetFontByName("F1")
+	if !ok {
+		return "", fmt.Errorf("missing font on page %d", pageNum)
+	}
+	font, err := NewPdfFontFromPdfObject(obj)
+	if err != nil {
+		return "", err
+	}
+	text, _, _ := font.CharcodeBytesToUnicode(encoded)
+	return text, nil
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// LazyReaderOptions define the memory use of a lazy-loading PdfReader.
+type LazyReaderOptions struct {
+	// CacheSize limits the estimated size of the objects kept in memory by the parser, in bytes.
+	// The least recently used objects are evicted when the limit is exceeded, and parsed again
+	// from the input when needed. A value of 0 means no limit.
+	CacheSize int64
+
+	// LazyStreams defers reading the data of the streams from the input until the data is needed,
+	// e.g. when decoding a content stream or an image. Ignored for encrypted documents.
+	LazyStreams bool
+}
+
+// NewPdfReaderLazyWithOptions creates a new PdfReader for `rs` in lazy-loading mode (see
+// NewPdfReaderLazy), with the memory use bounded as specified by `opts`. As the objects are parsed
+// again when needed, `rs` must remain available while the reader is used.
+// This allows processing very large files, e.g. multi-gigabyte scanned documents, page by page with
+// bounded memory, in combination with ReleasePage.
+func NewPdfReaderLazyWithOptions(rs io.ReadSeeker, opts *LazyReaderOptions) (*PdfReader, error) {
+	if opts == nil {
+		opts = &LazyReaderOptions{}
+	}
+	pdfReader := &PdfReader{
+		rs:           rs,
+		traversed:    map[core.PdfObject]struct{}{},
+		modelManager: newModelManager(),
+		isLazy:       true,
+	}
+
+	// Create the parser, loads the cross reference table and trailer.
+	parser, err := core.NewParser(rs)
+	if err != nil {
+		return nil, err
+	}
+	pdfReader.parser = parser
+	parser.SetCacheLimit(opts.CacheSize)
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load pdf doc structure if not encrypted.
+	if !isEncrypted {
+		parser.SetLazyStreams(opts.LazyStreams)
+		err = pdfReader.loadStructure()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pdfReader, nil
+}
+
+// ReleasePage releases the objects used by page `pageNumber` which were loaded since the reader was
+// created, i.e. its content streams, resources and annotations, and the data of the streams read
+// with LazyReaderOptions.LazyStreams. The objects are parsed again if needed, e.g. when the page is
+// processed again. The PdfPage returned by GetPage for the page before the release must not be used
+// afterwards; GetPage returns a new PdfPage.
+// Intended for readers created by NewPdfReaderLazy or NewPdfReaderLazyWithOptions: objects loaded
+// with the document structure, and the objects shared with other pages which are still referenced,
+// stay in memory.
+func (r *PdfReader) ReleasePage(pageNumber int) error {
+	if pageNumber < 1 || pageNumber > len(r.pageList) {
+		return fmt.Errorf("invalid page number %d", pageNumber)
+	}
+	idx := pageNumber - 1
+	node := r.pageList[idx]
+	pageDict, ok := core.GetDict(node)
+	if !ok {
+		return errors.New("page object should be a dictionary")
+	}
+
+	objs := map[int]core.PdfObject{}
+	r.collectLoadedObjects(pageDict, objs, map[core.PdfObject]struct{}{})
+	for objNum, obj := range objs {
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			// Not an error: the data is only released if read lazily.
+			stream.ReleaseData()
+		}
+		r.parser.ReleaseObject(objNum)
+	}
+	common.Log.Trace("Page %d: released %d objects", pageNumber, len(objs))
+
+	page, err := r.newPdfPageFromDict(pageDict)
+	if err != nil {
+		return err
+	}
+	page.setContainer(node)
+	r.PageList[idx] = page
+	return nil
+}
+
+// collectLoadedObjects adds the loaded indirect objects used by `obj` to `objs` by number,
+// without following the Parent entries and the references to the page tree, e.g. from annotations.
+func (r *PdfReader) collectLoadedObjects(obj core.PdfObject, objs map[int]core.PdfObject,
+	visited map[core.PdfObject]struct{}) {
+	if _, ok := visited[obj]; ok {
+		return
+	}
+	visited[obj] = struct{}{}
+
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		objNum := int(t.ObjectNumber)
+		if !r.parser.IsCached(objNum) {
+			return
+		}
+		resolved, err := r.parser.LookupByNumber(objNum)
+		if err != nil {
+			return
+		}
+		if r.isPageTreeNode(resolved) {
+			return
+		}
+		objs[objNum] = resolved
+		r.collectLoadedObjects(resolved, objs, visited)
+	case *core.PdfIndirectObject:
+		if r.isPageTreeNode(t) {
+			return
+		}
+		if t.ObjectNumber > 0 && r.parser.IsCached(int(t.ObjectNumber)) {
+			objs[int(t.ObjectNumber)] = t
+		}
+		r.collectLoadedObjects(t.PdfObject, objs, visited)
+	case *core.PdfObjectStream:
+		if t.ObjectNumber > 0 && r.parser.IsCached(int(t.ObjectNumber)) {
+			objs[int(t.ObjectNumber)] = t
+		}
+		r.collectLoadedObjects(t.PdfObjectDictionary, objs, visited)
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			if key != "Parent" {
+				r.collectLoadedObjects(t.Get(key), objs, visited)
+			}
+		}
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			r.collectLoadedObjects(elem, objs, visited)
+		}
+	}
+}
+
+// isPageTreeNode returns true if `obj` is a Page or Pages dictionary.
+func (r *PdfReader) isPageTreeNode(obj core.PdfObject) bool {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return false
+	}
+	name, ok := core.GetName(dict.Get("Type"))
+	return ok && (*name == "Page" || *name == "Pages")
+}
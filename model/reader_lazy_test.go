@@ -0,0 +1,66 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPdfReaderLazyWithOptions(t *testing.T) {
+	const numPages = 8
+	writer := NewPdfWriter()
+	padding := strings.Repeat("% padding\n", 200)
+	for i := 0; i < numPages; i++ {
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+		require.NoError(t, page.SetContentStreams([]string{
+			fmt.Sprintf("%sBT 50 700 Td (Page %d) Tj ET", padding, i+1),
+		}, nil))
+		require.NoError(t, writer.AddPage(page))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err := NewPdfReaderLazyWithOptions(bytes.NewReader(buf.Bytes()), &LazyReaderOptions{
+		CacheSize:   4096,
+		LazyStreams: true,
+	})
+	require.NoError(t, err)
+	n, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, numPages, n)
+
+	// The pages are processed twice, as the objects are parsed again once released or evicted.
+	for pass := 0; pass < 2; pass++ {
+		for i := 1; i <= numPages; i++ {
+			page, err := reader.GetPage(i)
+			require.NoError(t, err)
+			content, err := page.GetAllContentStreams()
+			require.NoError(t, err)
+			assert.Contains(t, content, fmt.Sprintf("(Page %d)", i))
+			require.NoError(t, reader.ReleasePage(i))
+		}
+	}
+	assert.Error(t, reader.ReleasePage(numPages+1))
+
+	// A reader with lazy streams can be written out.
+	writer = NewPdfWriter()
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		require.NoError(t, writer.AddPage(page))
+	}
+	var out bytes.Buffer
+	require.NoError(t, writer.Write(&out))
+	reader, err = NewPdfReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	page, err := reader.GetPage(numPages)
+	require.NoError(t, err)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	assert.Contains(t, content, fmt.Sprintf("(Page %d)", numPages))
+}
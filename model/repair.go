@@ -0,0 +1,116 @@
+package model
+
+import (
+	"errors"
+	"io"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// NewPdfReaderWithRecovery returns a new PdfReader for a damaged document `rs`, parsed by
+// core.NewParserWithRecovery: the cross-reference table, the stream lengths, the trailer, the
+// document catalog and the page tree are repaired as needed. The repairs made are returned by
+// GetRepairLog. Loads entire document structure into memory, as NewPdfReader.
+func NewPdfReaderWithRecovery(rs io.ReadSeeker) (*PdfReader, error) {
+	pdfReader := &PdfReader{
+		rs:           rs,
+		traversed:    map[core.PdfObject]struct{}{},
+		modelManager: newModelManager(),
+		isLazy:       false,
+	}
+
+	parser, err := core.NewParserWithRecovery(rs)
+	if err != nil {
+		return nil, err
+	}
+	pdfReader.parser = parser
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load pdf doc structure if not encrypted.
+	if !isEncrypted {
+		err = pdfReader.loadStructure()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pdfReader, nil
+}
+
+// GetRepairLog returns the log of the repairs made by a reader created by
+// NewPdfReaderWithRecovery, or nil for other readers.
+func (r *PdfReader) GetRepairLog() *core.RepairLog {
+	return r.parser.GetRepairLog()
+}
+
+// RepairAndRewrite repairs the damaged document `rs` and writes the repaired document to `out`.
+// The pages, forms, outlines, optional content, named destinations, document information and XMP
+// metadata of the document are written; the metadata which cannot be read is dropped. Encrypted
+// documents are repaired if the user password is empty and written without encryption.
+// Returns the log of the repairs made.
+func RepairAndRewrite(rs io.ReadSeeker, out io.Writer) (*core.RepairLog, error) {
+	reader, err := NewPdfReaderWithRecovery(rs)
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted, err := reader.IsEncrypted(); err != nil {
+		return nil, err
+	} else if isEncrypted {
+		if ok, err := reader.Decrypt([]byte("")); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, errors.New("unable to repair encrypted document with user password")
+		}
+	}
+
+	w := NewPdfWriter()
+	version := reader.PdfVersion()
+	w.SetVersion(version.Major, version.Minor)
+	for _, page := range reader.PageList {
+		if err := w.AddPage(page); err != nil {
+			return nil, err
+		}
+	}
+	if reader.AcroForm != nil {
+		if err := w.SetForms(reader.AcroForm); err != nil {
+			return nil, err
+		}
+	}
+	if outlineTree := reader.GetOutlineTree(); outlineTree != nil {
+		w.AddOutlineTree(outlineTree)
+	}
+	if ocProperties, err := reader.GetOCProperties(); err != nil {
+		common.Log.Debug("ERROR: unable to read OCProperties: %v", err)
+	} else if ocProperties != nil {
+		if err := w.SetOCProperties(ocProperties); err != nil {
+			return nil, err
+		}
+	}
+	if names, err := reader.GetNamedDestinations(); err != nil {
+		common.Log.Debug("ERROR: unable to read Names: %v", err)
+	} else if names != nil {
+		if err := w.SetNamedDestinations(names); err != nil {
+			return nil, err
+		}
+	}
+	if info, err := reader.GetDocumentInfo(); err != nil {
+		common.Log.Debug("ERROR: unable to read document information: %v", err)
+	} else {
+		w.SetDocumentInfo(info)
+	}
+	if xmp, err := reader.GetXMPMetadata(); err != nil {
+		common.Log.Debug("ERROR: unable to read XMP metadata: %v", err)
+	} else if xmp != nil {
+		w.SetXMPMetadata(xmp)
+	}
+
+	if err := w.Write(out); err != nil {
+		return nil, err
+	}
+	return reader.GetRepairLog(), nil
+}
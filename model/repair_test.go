@@ -0,0 +1,68 @@
+package model_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/internal/pdftest"
+	"github.com/moolekkari/unipdf/model"
+)
+
+func TestRepairAndRewrite(t *testing.T) {
+	// Page tree without Count, stream with invalid Length, truncated cross-reference table.
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << >> /Contents 4 0 R >>",
+		"<< /Length 100 >>\nstream\nBT ET\nendstream",
+	)
+	data = data[:bytes.Index(data, []byte("xref"))+20]
+
+	_, err := model.NewPdfReader(bytes.NewReader(data))
+	require.Error(t, err)
+	reader, err := model.NewPdfReaderWithRecovery(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, reader.PageList, 1)
+	require.NotEmpty(t, reader.GetRepairLog().Entries())
+
+	var buf bytes.Buffer
+	log, err := model.RepairAndRewrite(bytes.NewReader(data), &buf)
+	require.NoError(t, err)
+	require.NotEmpty(t, log.Entries())
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 1, numPages)
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(contents, "BT ET"), contents)
+
+	report, err := reader.Validate()
+	require.NoError(t, err)
+	require.True(t, report.IsValid(), report.Issues)
+}
+
+func TestRepairAndRewriteValid(t *testing.T) {
+	f, err := os.Open(testPdfFile1)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var buf bytes.Buffer
+	log, err := model.RepairAndRewrite(f, &buf)
+	require.NoError(t, err)
+	require.Empty(t, log.Entries())
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 1, numPages)
+}
@@ -24,6 +24,9 @@ type PdfPageResources struct {
 
 	// Loaded objects.
 	colorspace *PdfPageResourcesColorspaces
+
+	// Fonts set with SetFont, by font object.
+	fonts map[core.PdfObject]*PdfFont
 }
 
 // NewPdfPageResources returns a new PdfPageResources object.
@@ -276,6 +279,52 @@ func (r *PdfPageResources) SetFontByName(keyName core.PdfObjectName, obj core.Pd
 	return nil
 }
 
+// SetFont sets the font specified by keyName to `font`.
+// Unlike fonts set with SetFontByName, the TrueType font programs of fonts set with SetFont are
+// subset to the glyphs used in the document when the page is written with PdfWriter.
+// Subsetting does not modify `font`, which can be shared by documents written concurrently.
+func (r *PdfPageResources) SetFont(keyName core.PdfObjectName, font *PdfFont) error {
+	obj := font.pdfObject()
+	if err := r.SetFontByName(keyName, obj); err != nil {
+		return err
+	}
+	if r.fonts == nil {
+		r.fonts = make(map[core.PdfObject]*PdfFont)
+	}
+	r.fonts[obj] = font
+	return nil
+}
+
+// GetFont returns the font specified by keyName if it has been set with SetFont.
+// The bool flag is false for fonts that have not been set with SetFont. GetFontByName and
+// NewPdfFontFromPdfObject can be used for loading these.
+func (r *PdfPageResources) GetFont(keyName core.PdfObjectName) (*PdfFont, bool) {
+	obj, has := r.GetFontByName(keyName)
+	if !has {
+		return nil, false
+	}
+	font, has := r.fonts[obj]
+	return font, has
+}
+
+// setFonts returns the fonts of the resources that have been set with SetFont.
+func (r *PdfPageResources) setFonts() []*PdfFont {
+	if len(r.fonts) == 0 {
+		return nil
+	}
+	fontDict, has := core.TraceToDirectObject(r.Font).(*core.PdfObjectDictionary)
+	if !has {
+		return nil
+	}
+	var fonts []*PdfFont
+	for _, key := range fontDict.Keys() {
+		if font, has := r.fonts[fontDict.Get(key)]; has {
+			fonts = append(fonts, font)
+		}
+	}
+	return fonts
+}
+
 // GetColorspaceByName returns the colorspace with the specified name from the page resources.
 func (r *PdfPageResources) GetColorspaceByName(keyName core.PdfObjectName) (PdfColorspace, bool) {
 	colorspace, err := r.GetColorspaces()
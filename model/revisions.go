@@ -0,0 +1,133 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// GetRevisions returns the revisions of the document, from the original document to the latest
+// incremental update. The document read by the reader is the latest revision.
+func (r *PdfReader) GetRevisions() ([]core.Revision, error) {
+	return r.parser.GetRevisions()
+}
+
+// revisionData returns the document at revision `revision`, where 0 is the original document.
+func (r *PdfReader) revisionData(revision int) ([]byte, error) {
+	revisions, err := r.GetRevisions()
+	if err != nil {
+		return nil, err
+	}
+	if revision < 0 || revision >= len(revisions) {
+		common.Log.Debug("ERROR: invalid revision %d (%d revisions)", revision, len(revisions))
+		return nil, errors.New("revision out of range")
+	}
+	return r.parser.ReadBytesAt(0, revisions[revision].Length)
+}
+
+// GetRevision returns a reader of the document at revision `revision`, where 0 is the original
+// document and len(GetRevisions())-1 the latest revision. The returned reader is independent from
+// `r`; if the document is encrypted, it must be decrypted.
+func (r *PdfReader) GetRevision(revision int) (*PdfReader, error) {
+	data, err := r.revisionData(revision)
+	if err != nil {
+		return nil, err
+	}
+	return NewPdfReader(bytes.NewReader(data))
+}
+
+// WriteRevision writes the document at revision `revision` to `out`, i.e. the document truncated
+// to the revision with the later incremental updates removed. The revisions are numbered as for
+// GetRevision.
+func (r *PdfReader) WriteRevision(revision int, out io.Writer) error {
+	data, err := r.revisionData(revision)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// RevisionDiff represents the differences between the objects of two revisions of a document.
+// The objects are identified by their object numbers.
+type RevisionDiff struct {
+	Added    []int // Objects of the newer revision which are not in the older revision.
+	Modified []int // Objects of both revisions which differ.
+	Removed  []int // Objects of the older revision which are not in the newer revision.
+}
+
+// IsEmpty returns true if the revisions have the same objects.
+func (d *RevisionDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Modified) == 0 && len(d.Removed) == 0
+}
+
+// DiffRevisions returns the differences between the objects of the revisions of the same document
+// read by `older` and `newer`, e.g. the objects changed after a revision was signed.
+// The objects are compared by content, an object rewritten without changes is not modified.
+func DiffRevisions(older, newer *PdfReader) (*RevisionDiff, error) {
+	oldObjects, err := revisionObjects(older)
+	if err != nil {
+		return nil, err
+	}
+	newObjects, err := revisionObjects(newer)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RevisionDiff{}
+	for objNum, newObj := range newObjects {
+		oldObj, ok := oldObjects[objNum]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, objNum)
+		case objectDigest(oldObj) != objectDigest(newObj):
+			diff.Modified = append(diff.Modified, objNum)
+		}
+	}
+	for objNum := range oldObjects {
+		if _, ok := newObjects[objNum]; !ok {
+			diff.Removed = append(diff.Removed, objNum)
+		}
+	}
+	sort.Ints(diff.Added)
+	sort.Ints(diff.Modified)
+	sort.Ints(diff.Removed)
+	return diff, nil
+}
+
+// revisionObjects returns the objects of the document read by `r` by object number. The free
+// objects and the objects which cannot be read are skipped.
+func revisionObjects(r *PdfReader) (map[int]core.PdfObject, error) {
+	if isEncrypted, err := r.IsEncrypted(); err != nil {
+		return nil, err
+	} else if isEncrypted && !r.parser.IsAuthenticated() {
+		return nil, errors.New("revision is encrypted")
+	}
+
+	objects := map[int]core.PdfObject{}
+	for _, objNum := range r.GetObjectNums() {
+		obj, err := r.GetIndirectObjectByNumber(objNum)
+		if err != nil {
+			common.Log.Debug("ERROR: unable to read object %d: %v", objNum, err)
+			continue
+		}
+		switch t := obj.(type) {
+		case *core.PdfObjectStream:
+			if err := t.LoadData(); err != nil {
+				return nil, err
+			}
+		case *core.PdfIndirectObject:
+			if _, ok := t.PdfObject.(*core.PdfObjectNull); ok {
+				continue
+			}
+		default:
+			continue
+		}
+		objects[objNum] = obj
+	}
+	return objects, nil
+}
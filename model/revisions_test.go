@@ -0,0 +1,108 @@
+package model_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/model"
+)
+
+func TestReaderRevisions(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	update1 := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		addTestAnnotation(t, reader, 1)
+	})
+	update2 := incrementalUpdate(t, update1, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		w.GetCatalog().Set("Lang", core.MakeString("en"))
+	})
+
+	reader, err := model.NewPdfReader(bytes.NewReader(update2))
+	require.NoError(t, err)
+	revisions, err := reader.GetRevisions()
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	for i, expected := range [][]byte{data, update1, update2} {
+		require.Equal(t, int64(len(expected)), revisions[i].Length)
+
+		var buf bytes.Buffer
+		require.NoError(t, reader.WriteRevision(i, &buf))
+		require.Equal(t, expected, buf.Bytes())
+	}
+	_, err = reader.GetRevision(3)
+	require.Error(t, err)
+
+	readRevision := func(revision int) *model.PdfReader {
+		r, err := reader.GetRevision(revision)
+		require.NoError(t, err)
+		return r
+	}
+	rev0, rev1, rev2 := readRevision(0), readRevision(1), readRevision(2)
+
+	page, err := rev0.GetPage(1)
+	require.NoError(t, err)
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Empty(t, annots)
+
+	// The first update adds the annotation to the page.
+	diff, err := model.DiffRevisions(rev0, rev1)
+	require.NoError(t, err)
+	page, err = rev1.GetPage(1)
+	require.NoError(t, err)
+	annots, err = page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+	annotObj, ok := annots[0].GetContainingPdfObject().(*core.PdfIndirectObject)
+	require.True(t, ok)
+	require.Equal(t, []int{int(annotObj.ObjectNumber)}, diff.Added)
+	require.Equal(t, []int{int(page.GetContainingPdfObject().(*core.PdfIndirectObject).ObjectNumber)},
+		diff.Modified)
+	require.Empty(t, diff.Removed)
+
+	// The second update changes the catalog only.
+	diff, err = model.DiffRevisions(rev1, rev2)
+	require.NoError(t, err)
+	trailer, err := rev2.GetTrailer()
+	require.NoError(t, err)
+	root, ok := trailer.Get("Root").(*core.PdfObjectReference)
+	require.True(t, ok)
+	require.Empty(t, diff.Added)
+	require.Equal(t, []int{int(root.ObjectNumber)}, diff.Modified)
+
+	diff, err = model.DiffRevisions(rev2, reader)
+	require.NoError(t, err)
+	require.True(t, diff.IsEmpty())
+}
+
+func TestReaderRevisionsSigned(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfSignedPDFDocument)
+	require.NoError(t, err)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	signedRevisions, err := reader.GetRevisions()
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), signedRevisions[len(signedRevisions)-1].Length)
+
+	out := incrementalUpdate(t, data, func(reader *model.PdfReader, w *model.PdfIncrementalWriter) {
+		addTestAnnotation(t, reader, 1)
+	})
+	reader, err = model.NewPdfReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	revisions, err := reader.GetRevisions()
+	require.NoError(t, err)
+	require.Len(t, revisions, len(signedRevisions)+1)
+
+	// The signed revision is the document before the update.
+	signed, err := reader.GetRevision(len(signedRevisions) - 1)
+	require.NoError(t, err)
+	diff, err := model.DiffRevisions(signed, reader)
+	require.NoError(t, err)
+	require.Len(t, diff.Added, 2) // The annotation and the cross-reference stream.
+	require.NotEmpty(t, diff.Modified)
+	require.Empty(t, diff.Removed)
+}
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// PdfStreamWriter writes a PDF document page by page. Unlike PdfWriter, which keeps all the objects
+// in memory until Write, the pages and the objects they use are written to the output as the pages
+// are added, so that very large documents can be generated with bounded memory.
+// Shared resources, e.g. images and fonts, are written once. The fonts set with
+// PdfPageResources.SetFont, whose font programs are subset with the glyphs used by all the pages,
+// and the document level objects are written by Close.
+// The page dictionaries and the data of the streams, e.g. content streams and images, are released
+// once written, and the objects of a page must not be modified once the page is added. Pages must not refer to pages
+// added later. Optimizers and encryption are not supported.
+type PdfStreamWriter struct {
+	w PdfWriter
+
+	started    bool
+	closed     bool
+	numObjects int64 // The number of object numbers assigned.
+
+	// Objects written by Close, in the order they were numbered.
+	deferred    []core.PdfObject
+	deferredMap map[core.PdfObject]struct{}
+
+	// Objects referenced as Parent before being added, numbered in advance.
+	reserved map[core.PdfObject]struct{}
+}
+
+// NewPdfStreamWriter returns a new PdfStreamWriter writing to `out`.
+func NewPdfStreamWriter(out io.Writer) *PdfStreamWriter {
+	s := &PdfStreamWriter{
+		w:           NewPdfWriter(),
+		deferredMap: map[core.PdfObject]struct{}{},
+		reserved:    map[core.PdfObject]struct{}{},
+	}
+	s.w.writer = bufio.NewWriter(out)
+	s.w.crossReferenceMap = map[int]crossReference{0: {Type: 0, ObjectNumber: 0, Generation: 0xFFFF}}
+	for _, obj := range []core.PdfObject{s.w.infoObj, s.w.root, s.w.pages} {
+		s.deferredMap[obj] = struct{}{}
+	}
+	return s
+}
+
+// SetVersion sets the PDF version of the output file. The version in the file header is the
+// version set when the first page is added.
+func (s *PdfStreamWriter) SetVersion(majorVersion, minorVersion int) {
+	s.w.SetVersion(majorVersion, minorVersion)
+}
+
+// GetDocumentInfo returns the document information of the output PDF.
+func (s *PdfStreamWriter) GetDocumentInfo() *PdfDocumentInfo {
+	return s.w.GetDocumentInfo()
+}
+
+// SetDocumentInfo sets the document information dictionary of the output PDF to `info`.
+func (s *PdfStreamWriter) SetDocumentInfo(info *PdfDocumentInfo) {
+	s.w.SetDocumentInfo(info)
+}
+
+// SetXMPMetadata sets the XMP metadata of the output PDF.
+func (s *PdfStreamWriter) SetXMPMetadata(metadata *XMPMetadata) {
+	s.w.SetXMPMetadata(metadata)
+}
+
+// AddOutlineTree adds outlines to the output PDF. The outlines are written by Close.
+func (s *PdfStreamWriter) AddOutlineTree(outlineTree *PdfOutlineTreeNode) {
+	s.w.AddOutlineTree(outlineTree)
+}
+
+// SetForms sets the AcroForm of the output PDF. The form is written by Close.
+func (s *PdfStreamWriter) SetForms(form *PdfAcroForm) error {
+	return s.w.SetForms(form)
+}
+
+// NumPages returns the number of pages added.
+func (s *PdfStreamWriter) NumPages() int {
+	pagesDict, ok := core.GetDict(s.w.pages)
+	if !ok {
+		return 0
+	}
+	count, _ := core.GetIntVal(pagesDict.Get("Count"))
+	return count
+}
+
+// AddPage adds `page` to the output PDF and writes it with the objects it uses which were not
+// written yet.
+func (s *PdfStreamWriter) AddPage(page *PdfPage) error {
+	if s.closed {
+		return errors.New("stream writer closed")
+	}
+	w := &s.w
+	numFonts := len(w.fonts)
+	if err := w.AddPage(page); err != nil {
+		return err
+	}
+	// The fonts to be subset are completed by Close.
+	for _, font := range w.fonts[numFonts:] {
+		s.deferObjects(font.pdfObject())
+	}
+
+	pagesDict, ok := core.GetDict(w.pages)
+	if !ok {
+		return errors.New("invalid Pages obj (not a dict)")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok {
+		return errors.New("invalid Pages Kids obj (not an array)")
+	}
+	pageObj, ok := core.GetIndirect(kids.Get(kids.Len() - 1))
+	if !ok {
+		return errors.New("page should be an indirect object")
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	// Release the page dictionary. The object is kept, so that references to the page from the
+	// pages added later are written with its number.
+	pageObj.PdfObject = core.MakeNull()
+	return nil
+}
+
+// deferObjects marks the objects used by `obj` to be written by Close.
+func (s *PdfStreamWriter) deferObjects(obj core.PdfObject) {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		if _, ok := s.deferredMap[t]; ok {
+			return
+		}
+		s.deferredMap[t] = struct{}{}
+		s.deferObjects(t.PdfObject)
+	case *core.PdfObjectStream:
+		if _, ok := s.deferredMap[t]; ok {
+			return
+		}
+		s.deferredMap[t] = struct{}{}
+		s.deferObjects(t.PdfObjectDictionary)
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			if key != "Parent" {
+				s.deferObjects(t.Get(key))
+			}
+		}
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			s.deferObjects(elem)
+		}
+	}
+}
+
+// flush numbers the objects added since the last flush and writes those that are not deferred.
+func (s *PdfStreamWriter) flush() error {
+	w := &s.w
+	if !s.started {
+		w.writeString(fmt.Sprintf("%%PDF-%d.%d\n", w.majorVersion, w.minorVersion))
+		w.writeString("%âãÏÓ\n")
+		s.started = true
+	}
+
+	number := func(obj core.PdfObject) error {
+		switch obj.(type) {
+		case *core.PdfIndirectObject, *core.PdfObjectStream:
+		default:
+			common.Log.Debug("ERROR: Unsupported type in writer objects: %T", obj)
+			return ErrTypeCheck
+		}
+		if _, ok := s.reserved[obj]; ok {
+			delete(s.reserved, obj)
+			return nil
+		}
+		s.numObjects++
+		setObjectNumber(obj, s.numObjects)
+		return nil
+	}
+	for _, obj := range w.objects {
+		if err := number(obj); err != nil {
+			return err
+		}
+	}
+	// Objects referenced as Parent which are added later, e.g. form fields, need their numbers for
+	// writing the references.
+	for obj := range w.pendingObjects {
+		if _, ok := s.reserved[obj]; ok || w.hasObject(obj) {
+			continue
+		}
+		if err := number(obj); err != nil {
+			return err
+		}
+		s.reserved[obj] = struct{}{}
+	}
+
+	for _, obj := range w.objects {
+		if _, ok := s.deferredMap[obj]; ok {
+			s.deferred = append(s.deferred, obj)
+			continue
+		}
+		w.writeObject(int(objectNumber(obj)), obj)
+		// The written objects are kept in objectsMap, so that the shared ones are written once,
+		// but the stream data is not needed anymore.
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			stream.Stream = nil
+		}
+	}
+	w.objects = nil
+	w.traversed = map[core.PdfObject]struct{}{}
+	return nil
+}
+
+// Close writes the deferred objects, the document level objects and the cross-reference table, and
+// flushes the output. The output is a complete PDF file once Close returns.
+func (s *PdfStreamWriter) Close() error {
+	if s.closed {
+		return errors.New("stream writer closed")
+	}
+	s.closed = true
+	w := &s.w
+	if err := w.addDocumentObjects(); err != nil {
+		return err
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	for _, obj := range s.deferred {
+		num := int(objectNumber(obj))
+		if subset, ok := w.fontSubsets[obj]; ok {
+			obj = subset
+		}
+		w.writeObject(num, obj)
+	}
+
+	// Objects reserved but never added are free.
+	xrefOffset := w.writePos
+	w.writeString("xref\r\n")
+	w.writeString(fmt.Sprintf("%d %d\r\n", 0, s.numObjects+1))
+	for num := 0; num <= int(s.numObjects); num++ {
+		ref, has := w.crossReferenceMap[num]
+		if has && ref.Type == 1 {
+			w.writeString(fmt.Sprintf("%.10d %.5d n\r\n", ref.Offset, 0))
+		} else {
+			w.writeString(fmt.Sprintf("%.10d %.5d f\r\n", 0, 65535))
+		}
+	}
+	trailer := core.MakeDict()
+	trailer.Set("Info", w.infoObj)
+	trailer.Set("Root", w.root)
+	trailer.Set("Size", core.MakeInteger(s.numObjects+1))
+	w.writeString("trailer\n")
+	w.writeString(trailer.WriteString())
+	w.writeString("\n")
+	w.writeString(fmt.Sprintf("startxref\n%d\n", xrefOffset))
+	w.writeString("%%EOF\n")
+	return w.writer.Flush()
+}
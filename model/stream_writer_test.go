@@ -0,0 +1,140 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+)
+
+func TestPdfStreamWriter(t *testing.T) {
+	const numPages = 20
+	font, err := NewCompositePdfFontFromTTFFile(subsetTestFont)
+	require.NoError(t, err)
+	helvetica := NewStandard14FontMustCompile(HelveticaName)
+
+	var buf bytes.Buffer
+	s := NewPdfStreamWriter(&buf)
+	info := s.GetDocumentInfo()
+	info.Title = "Statements"
+	s.SetDocumentInfo(info)
+	// Large content streams, so that the pages go through the output buffer as they are added.
+	padding := strings.Repeat("% padding\n", 500)
+	for i := 0; i < numPages; i++ {
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+		require.NoError(t, page.Resources.SetFont("F1", font))
+		require.NoError(t, page.Resources.SetFont("F2", helvetica))
+		encoded := font.Encoder().Encode(fmt.Sprintf("Page %d", i+1))
+		content := fmt.Sprintf("%sBT /F1 12 Tf 50 700 Td %s Tj /F2 10 Tf (Total) Tj ET",
+			padding, core.MakeHexString(string(encoded)).WriteString())
+		require.NoError(t, page.SetContentStreams([]string{content}, nil))
+		size := buf.Len()
+		require.NoError(t, s.AddPage(page))
+		if i > 0 {
+			assert.Greater(t, buf.Len(), size, "page %d", i+1)
+		}
+
+		// The page objects are released once written.
+		assert.Empty(t, s.w.objects)
+		pageObj, ok := core.GetIndirect(page.GetContainingPdfObject())
+		require.True(t, ok)
+		assert.True(t, core.IsNullObject(pageObj.PdfObject))
+	}
+	assert.Equal(t, numPages, s.NumPages())
+	require.NoError(t, s.Close())
+	require.Error(t, s.AddPage(NewPdfPage()))
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	n, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, numPages, n)
+	docInfo, err := reader.GetDocumentInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "Statements", docInfo.Title)
+
+	// The shared fonts are written once, and the composite font is subset with the glyphs of all
+	// the pages.
+	fontObjs := map[core.PdfObject]bool{}
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		obj, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		fontObjs[obj] = true
+	}
+	require.Len(t, fontObjs, 1)
+	page, err := reader.GetPage(numPages)
+	require.NoError(t, err)
+	obj, _ := page.Resources.GetFontByName("F1")
+	loaded, err := NewPdfFontFromPdfObject(obj)
+	require.NoError(t, err)
+	assert.True(t, loaded.IsSubset(), loaded.BaseFont())
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	encoded := font.Encoder().Encode(fmt.Sprintf("Page %d", numPages))
+	assert.Contains(t, content, core.MakeHexString(string(encoded)).WriteString())
+	decoded, _, numMisses := loaded.CharcodeBytesToUnicode(encoded)
+	assert.Equal(t, fmt.Sprintf("Page %d", numPages), decoded)
+	assert.Equal(t, 0, numMisses)
+}
+
+// makeStreamWriterTestImage returns an image XObject of `size`x`size` gray pixels of value `gray`.
+func makeStreamWriterTestImage(t *testing.T, size int, gray byte) *core.PdfObjectStream {
+	stream, err := core.MakeStream(bytes.Repeat([]byte{gray}, size*size), core.NewRawEncoder())
+	require.NoError(t, err)
+	stream.Set("Type", core.MakeName("XObject"))
+	stream.Set("Subtype", core.MakeName("Image"))
+	stream.Set("Width", core.MakeInteger(int64(size)))
+	stream.Set("Height", core.MakeInteger(int64(size)))
+	stream.Set("ColorSpace", core.MakeName("DeviceGray"))
+	stream.Set("BitsPerComponent", core.MakeInteger(8))
+	return stream
+}
+
+func TestPdfStreamWriterImages(t *testing.T) {
+	const numPages = 10
+	const size = 100
+	logo := makeStreamWriterTestImage(t, size, 0)
+
+	var buf bytes.Buffer
+	s := NewPdfStreamWriter(&buf)
+	for i := 0; i < numPages; i++ {
+		img := makeStreamWriterTestImage(t, size, byte(i+1))
+		page := NewPdfPage()
+		page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+		require.NoError(t, page.Resources.SetXObjectByName("Logo", logo))
+		require.NoError(t, page.Resources.SetXObjectByName("Im1", img))
+		require.NoError(t, page.SetContentStreams([]string{"q 100 0 0 100 0 0 cm /Logo Do /Im1 Do Q"}, nil))
+		require.NoError(t, s.AddPage(page))
+
+		// The data of the written images is released.
+		assert.Nil(t, img.Stream, "page %d", i+1)
+		assert.Nil(t, logo.Stream, "page %d", i+1)
+	}
+	require.NoError(t, s.Close())
+
+	reader, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	logos := map[core.PdfObject]bool{}
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		obj, xtype := page.Resources.GetXObjectByName("Logo")
+		require.Equal(t, XObjectTypeImage, xtype)
+		logos[obj] = true
+		obj, xtype = page.Resources.GetXObjectByName("Im1")
+		require.Equal(t, XObjectTypeImage, xtype)
+		data, err := core.DecodeStream(obj)
+		require.NoError(t, err)
+		require.Equal(t, bytes.Repeat([]byte{byte(i)}, size*size), data, "page %d", i)
+	}
+	// The shared image is written once.
+	require.Len(t, logos, 1)
+}
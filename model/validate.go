@@ -0,0 +1,254 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/moolekkari/unipdf/core"
+)
+
+// requiredKeys lists the required entries of the dictionaries by Type, for the entries which are
+// not inheritable.
+var requiredKeys = map[core.PdfObjectName][]core.PdfObjectName{
+	"Catalog":        {"Pages"},
+	"Pages":          {"Kids", "Count"},
+	"Page":           {"Parent"},
+	"Font":           {"Subtype"},
+	"FontDescriptor": {"FontName", "Flags", "ItalicAngle"},
+	"Annot":          {"Subtype", "Rect"},
+	"XObject":        {"Subtype"},
+	"ObjStm":         {"N", "First"},
+	"XRef":           {"Size", "W"},
+	"Action":         {"S"},
+	"Sig":            {"Filter", "Contents"},
+}
+
+// requiredSubtypeKeys lists the required entries of the dictionaries by Type and Subtype, in
+// addition to the entries of requiredKeys.
+var requiredSubtypeKeys = map[core.PdfObjectName]map[core.PdfObjectName][]core.PdfObjectName{
+	"Font": {
+		"Type0":        {"BaseFont", "Encoding", "DescendantFonts"},
+		"Type1":        {"BaseFont"},
+		"MMType1":      {"BaseFont"},
+		"TrueType":     {"BaseFont"},
+		"Type3":        {"FontBBox", "FontMatrix", "CharProcs", "Encoding", "FirstChar", "LastChar", "Widths"},
+		"CIDFontType0": {"BaseFont", "CIDSystemInfo", "FontDescriptor"},
+		"CIDFontType2": {"BaseFont", "CIDSystemInfo", "FontDescriptor"},
+	},
+	"XObject": {
+		"Image": {"Width", "Height"},
+		"Form":  {"BBox"},
+	},
+}
+
+// Validate checks the document against the syntax rules of the PDF specification (ISO 32000-1):
+// the cross-reference entries, the stream lengths and the references are checked by
+// core.PdfParser.Validate, and the required entries of the dictionaries and the integrity of the
+// page tree are checked in addition. The document is parsed again, as the reader repairs some
+// issues when loading the document. Encrypted documents can be validated if the user password is
+// empty.
+// The issues found are returned in the report; an error is returned only if the document cannot
+// be read.
+func (r *PdfReader) Validate() (*core.ValidationReport, error) {
+	parser, err := core.NewParser(r.rs)
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted, err := parser.IsEncrypted(); err != nil {
+		return nil, err
+	} else if isEncrypted {
+		if ok, err := parser.Decrypt([]byte("")); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, errors.New("unable to validate encrypted document with user password")
+		}
+	}
+
+	report, err := parser.Validate()
+	if err != nil {
+		return nil, err
+	}
+	for _, objNum := range parser.GetObjectNums() {
+		obj, err := parser.LookupByNumber(objNum)
+		if err != nil {
+			// Reported by the syntax validation.
+			continue
+		}
+		validateRequiredKeys(report, int64(objNum), obj)
+	}
+	validatePageTree(report, parser)
+	return report, nil
+}
+
+// validateRequiredKeys checks that the dictionary of object `objNum` `obj` has the required
+// entries for its type.
+func validateRequiredKeys(report *core.ValidationReport, objNum int64, obj core.PdfObject) {
+	var dict *core.PdfObjectDictionary
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		dict, _ = t.PdfObject.(*core.PdfObjectDictionary)
+	case *core.PdfObjectStream:
+		dict = t.PdfObjectDictionary
+	}
+	if dict == nil {
+		return
+	}
+	typ, ok := core.GetName(dict.Get("Type"))
+	if !ok {
+		return
+	}
+	keys := append([]core.PdfObjectName(nil), requiredKeys[*typ]...)
+	if subtype, ok := core.GetName(dict.Get("Subtype")); ok {
+		keys = append(keys, requiredSubtypeKeys[*typ][*subtype]...)
+		if *typ == "XObject" && *subtype == "Image" && !isImageMaskOrJPX(dict) {
+			keys = append(keys, "BitsPerComponent")
+		}
+	}
+	for _, key := range keys {
+		if dict.Get(key) == nil {
+			report.Add(core.ValidationError, core.ValidationCodeMissingKey, objNum, "%s dictionary without %s",
+				*typ, key)
+		}
+	}
+}
+
+// isImageMaskOrJPX returns true if the image dictionary `dict` is an image mask or JPX encoded,
+// in which cases BitsPerComponent is optional.
+func isImageMaskOrJPX(dict *core.PdfObjectDictionary) bool {
+	if mask, ok := core.GetBoolVal(dict.Get("ImageMask")); ok && mask {
+		return true
+	}
+	filter := core.TraceToDirectObject(dict.Get("Filter"))
+	if arr, ok := filter.(*core.PdfObjectArray); ok && arr.Len() > 0 {
+		filter = core.TraceToDirectObject(arr.Get(arr.Len() - 1))
+	}
+	name, ok := core.GetName(filter)
+	return ok && *name == core.StreamEncodingFilterNameJPX
+}
+
+// validatePageTree checks the integrity of the page tree of the document parsed by `parser`: the
+// types of the nodes, the Parent entries of the nodes and the Count entries of the intermediate
+// nodes. The inheritable required entries of the pages are checked as well.
+func validatePageTree(report *core.ValidationReport, parser *core.PdfParser) {
+	catalog, ok := core.GetDict(parser.GetTrailer().Get("Root"))
+	if !ok {
+		// Reported by the syntax validation.
+		return
+	}
+	root, ok := catalog.Get("Pages").(*core.PdfObjectReference)
+	if !ok {
+		report.Add(core.ValidationError, core.ValidationCodePageTree, 0, "Pages is not an indirect reference")
+		return
+	}
+	v := pageTreeValidator{parser: parser, report: report, visited: map[int64]struct{}{}}
+	if node, ok := v.node(root); ok && node.Get("Parent") != nil {
+		report.Add(core.ValidationWarning, core.ValidationCodePageTree, node.ObjectNumber,
+			"root node with Parent")
+	}
+	v.validate(root, nil, nil)
+}
+
+// pageTreeValidator checks the nodes of a page tree.
+type pageTreeValidator struct {
+	parser  *core.PdfParser
+	report  *core.ValidationReport
+	visited map[int64]struct{}
+}
+
+// pageTreeNode is a node of the page tree.
+type pageTreeNode struct {
+	*core.PdfObjectDictionary
+	ObjectNumber int64
+}
+
+// node returns the page tree node referred to by `ref`.
+func (v *pageTreeValidator) node(ref *core.PdfObjectReference) (pageTreeNode, bool) {
+	obj, err := v.parser.LookupByNumber(int(ref.ObjectNumber))
+	if err != nil {
+		return pageTreeNode{}, false
+	}
+	ind, ok := obj.(*core.PdfIndirectObject)
+	if !ok {
+		return pageTreeNode{}, false
+	}
+	dict, ok := ind.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		return pageTreeNode{}, false
+	}
+	return pageTreeNode{PdfObjectDictionary: dict, ObjectNumber: ref.ObjectNumber}, true
+}
+
+// validate checks the node referred to by `ref` with parent `parent`, whose inherited entries
+// are `inherited`. Returns the number of pages of the node.
+func (v *pageTreeValidator) validate(ref *core.PdfObjectReference, parent *core.PdfObjectReference,
+	inherited map[core.PdfObjectName]bool) int {
+	objNum := ref.ObjectNumber
+	if parent != nil {
+		objNum = parent.ObjectNumber
+	}
+	if _, ok := v.visited[ref.ObjectNumber]; ok {
+		v.report.Add(core.ValidationError, core.ValidationCodePageTree, objNum,
+			"node %d referred to more than once", ref.ObjectNumber)
+		return 0
+	}
+	v.visited[ref.ObjectNumber] = struct{}{}
+
+	node, ok := v.node(ref)
+	if !ok {
+		v.report.Add(core.ValidationError, core.ValidationCodePageTree, objNum,
+			"node %d is not a dictionary object", ref.ObjectNumber)
+		return 0
+	}
+	objNum = node.ObjectNumber
+	if parent != nil {
+		if p, ok := node.Get("Parent").(*core.PdfObjectReference); !ok || p.ObjectNumber != parent.ObjectNumber {
+			v.report.Add(core.ValidationError, core.ValidationCodePageTree, objNum,
+				"Parent does not refer to parent node %d", parent.ObjectNumber)
+		}
+	}
+
+	nodeInherited := map[core.PdfObjectName]bool{}
+	for key := range inherited {
+		nodeInherited[key] = true
+	}
+	for _, key := range []core.PdfObjectName{"Resources", "MediaBox"} {
+		if node.Get(key) != nil {
+			nodeInherited[key] = true
+		}
+	}
+
+	typ, _ := core.GetName(node.Get("Type"))
+	switch {
+	case typ != nil && *typ == "Page":
+		if !nodeInherited["MediaBox"] {
+			v.report.Add(core.ValidationError, core.ValidationCodeMissingKey, objNum, "page without MediaBox")
+		}
+		if !nodeInherited["Resources"] {
+			v.report.Add(core.ValidationWarning, core.ValidationCodeMissingKey, objNum, "page without Resources")
+		}
+		return 1
+	case typ != nil && *typ == "Pages":
+	default:
+		v.report.Add(core.ValidationError, core.ValidationCodePageTree, objNum, "node without type Pages or Page")
+		return 0
+	}
+
+	kids, ok := core.GetArray(node.Get("Kids"))
+	if !ok {
+		// Missing Kids entry reported as a missing required entry.
+		return 0
+	}
+	count := 0
+	for _, kid := range kids.Elements() {
+		kidRef, ok := kid.(*core.PdfObjectReference)
+		if !ok {
+			v.report.Add(core.ValidationError, core.ValidationCodePageTree, objNum,
+				"Kids entry is not an indirect reference")
+			continue
+		}
+		count += v.validate(kidRef, ref, nodeInherited)
+	}
+	if n, ok := core.GetIntVal(node.Get("Count")); ok && n != count {
+		v.report.Add(core.ValidationError, core.ValidationCodePageTree, objNum, "Count %d, %d pages", n, count)
+	}
+	return count
+}
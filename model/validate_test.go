@@ -0,0 +1,59 @@
+package model_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/pdftest"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// issueCodes returns the codes of the issues of `report` by object number.
+func issueCodes(report *core.ValidationReport) map[int64][]string {
+	codes := map[int64][]string{}
+	for _, issue := range report.Issues {
+		codes[issue.ObjectNumber] = append(codes[issue.ObjectNumber], issue.Code)
+	}
+	return codes
+}
+
+func TestReaderValidate(t *testing.T) {
+	for _, path := range []string{testPdfFile1, testPdfSignedPDFDocument, testPdfAcroFormFile1} {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		reader, err := model.NewPdfReader(f)
+		require.NoError(t, err)
+
+		report, err := reader.Validate()
+		require.NoError(t, err)
+		require.Empty(t, report.Issues, path)
+	}
+}
+
+func TestReaderValidateStructure(t *testing.T) {
+	data := pdftest.Build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 3 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 3 0 R >>",
+		"<< /Type /Font /Subtype /Type1 >>",
+	)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	report, err := reader.Validate()
+	require.NoError(t, err)
+	// Object 2: Count 3 with 2 pages. Object 4: Parent is not the parent node and Resources is not
+	// inherited. Object 5: Font without BaseFont.
+	require.Equal(t, map[int64][]string{
+		2: {core.ValidationCodePageTree},
+		4: {core.ValidationCodePageTree, core.ValidationCodeMissingKey},
+		5: {core.ValidationCodeMissingKey},
+	}, issueCodes(report))
+	require.Len(t, report.Errors(), 3)
+}
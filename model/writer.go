@@ -45,6 +45,8 @@ func getPdfAuthor() string {
 }
 
 // SetPdfAuthor sets the Author attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfAuthor(author string) {
 	pdfAuthor = author
 }
@@ -54,6 +56,8 @@ func getPdfCreationDate() time.Time {
 }
 
 // SetPdfCreationDate sets the CreationDate attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfCreationDate(creationDate time.Time) {
 	pdfCreationDate = creationDate
 }
@@ -68,6 +72,8 @@ func getPdfCreator() string {
 }
 
 // SetPdfCreator sets the Creator attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfCreator(creator string) {
 	pdfCreator = creator
 }
@@ -77,6 +83,8 @@ func getPdfKeywords() string {
 }
 
 // SetPdfKeywords sets the Keywords attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfKeywords(keywords ...string) {
 	pdfKeywords = keywords
 }
@@ -86,11 +94,15 @@ func getPdfModifiedDate() time.Time {
 }
 
 // SetPdfModifiedDate sets the ModDate attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfModifiedDate(modifiedDate time.Time) {
-	pdfCreationDate = modifiedDate
+	pdfModifiedDate = modifiedDate
 }
 
 // SetPdfProducer sets the Producer attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfProducer(producer string) {
 	pdfProducer = producer
 }
@@ -104,6 +116,8 @@ func getPdfProducer() string {
 }
 
 // SetPdfSubject sets the Subject attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfSubject(subject string) {
 	pdfSubject = subject
 }
@@ -113,10 +127,26 @@ func getPdfTitle() string {
 }
 
 // SetPdfTitle sets the Title attribute of the output PDF.
+//
+// Deprecated: The attribute is shared by all writers. Use PdfWriter.SetDocumentInfo instead.
 func SetPdfTitle(title string) {
 	pdfTitle = title
 }
 
+// defaultDocumentInfo returns the document information set with the package level functions.
+func defaultDocumentInfo() *PdfDocumentInfo {
+	return &PdfDocumentInfo{
+		Title:        getPdfTitle(),
+		Author:       getPdfAuthor(),
+		Subject:      getPdfSubject(),
+		Keywords:     getPdfKeywords(),
+		Creator:      getPdfCreator(),
+		Producer:     getPdfProducer(),
+		CreationDate: getPdfCreationDate(),
+		ModDate:      getPdfModifiedDate(),
+	}
+}
+
 // PdfWriter handles outputing PDF content.
 type PdfWriter struct {
 	root        *core.PdfIndirectObject
@@ -131,16 +161,29 @@ type PdfWriter struct {
 	fields      []core.PdfObject
 	infoObj     *core.PdfIndirectObject
 
+	// Document metadata.
+	info *PdfDocumentInfo
+	xmp  *XMPMetadata
+
 	// Encryption
 	crypter     *core.PdfCrypt
 	encryptDict *core.PdfObjectDictionary
 	encryptObj  *core.PdfIndirectObject
 	ids         *core.PdfObjectArray
 
+	// Encryption parameters, for replacing the algorithm on writing.
+	encryptUserPass  []byte
+	encryptOwnerPass []byte
+	encryptOptions   EncryptOptions
+
 	// PDF version
 	majorVersion int
 	minorVersion int
 
+	// Version enforced on writing, see SetTargetVersion.
+	targetVersion *core.Version
+	targetOptions TargetVersionOptions
+
 	// Force whether or not to use cross reference streams.
 	// Otherwise is used/not used depending on the PDF version (1.5 and above).
 	useCrossReferenceStream *bool
@@ -168,6 +211,15 @@ type PdfWriter struct {
 
 	// Cache of objects traversed while resolving references.
 	traversed map[core.PdfObject]struct{}
+
+	// Write the objects in the order and with the hint tables of linearized files.
+	linearized bool
+
+	// Fonts set with PdfPageResources.SetFont in the resources of the pages, subset on writing.
+	fonts    []*PdfFont
+	fontsMap map[*PdfFont]struct{}
+	// The subset font objects, written instead of the objects of the fonts they replace.
+	fontSubsets map[core.PdfObject]core.PdfObject
 }
 
 // NewPdfWriter initializes a new PdfWriter.
@@ -178,6 +230,7 @@ func NewPdfWriter() PdfWriter {
 	w.objects = []core.PdfObject{}
 	w.pendingObjects = map[core.PdfObject][]*core.PdfObjectDictionary{}
 	w.traversed = map[core.PdfObject]struct{}{}
+	w.fontsMap = map[*PdfFont]struct{}{}
 
 	// PDF Version. Can be changed if using more advanced features in PDF.
 	// By default it is set to 1.3.
@@ -185,35 +238,8 @@ func NewPdfWriter() PdfWriter {
 	w.minorVersion = 3
 
 	// Creation info.
-	infoDict := core.MakeDict()
-	metadata := []struct {
-		key   core.PdfObjectName
-		value string
-	}{
-		{"Producer", getPdfProducer()},
-		{"Creator", getPdfCreator()},
-		{"Author", getPdfAuthor()},
-		{"Subject", getPdfSubject()},
-		{"Title", getPdfTitle()},
-		{"Keywords", getPdfKeywords()},
-	}
-	for _, tuple := range metadata {
-		if tuple.value != "" {
-			infoDict.Set(tuple.key, core.MakeString(tuple.value))
-		}
-	}
-
-	// Set creation and modified dates.
-	if creationDate := getPdfCreationDate(); !creationDate.IsZero() {
-		if cd, err := NewPdfDateFromTime(creationDate); err == nil {
-			infoDict.Set("CreationDate", cd.ToPdfObject())
-		}
-	}
-	if modifiedDate := getPdfModifiedDate(); !modifiedDate.IsZero() {
-		if md, err := NewPdfDateFromTime(modifiedDate); err == nil {
-			infoDict.Set("ModDate", md.ToPdfObject())
-		}
-	}
+	w.info = defaultDocumentInfo()
+	infoDict := w.info.ToPdfObject()
 
 	infoObj := core.PdfIndirectObject{}
 	infoObj.PdfObject = infoDict
@@ -274,6 +300,9 @@ func copyObject(obj core.PdfObject, objectToObjectCopyMap map[core.PdfObject]cor
 		}
 		return newObj
 	case *core.PdfObjectStream:
+		if err := t.LoadData(); err != nil {
+			common.Log.Debug("ERROR: %v - copying stream without data", err)
+		}
 		newObj := &core.PdfObjectStream{
 			Stream:             t.Stream,
 			PdfObjectReference: t.PdfObjectReference,
@@ -347,6 +376,19 @@ func copyObject(obj core.PdfObject, objectToObjectCopyMap map[core.PdfObject]cor
 // copyObjects makes objects copy and set as working.
 func (w *PdfWriter) copyObjects() {
 	objectToObjectCopyMap := make(map[core.PdfObject]core.PdfObject)
+	// The subset font objects are new objects of this writer, used as the copies of the objects
+	// of the fonts. Only the objects they refer to need to be copied.
+	for obj, subset := range w.fontSubsets {
+		objectToObjectCopyMap[obj] = subset
+	}
+	for _, subset := range w.fontSubsets {
+		switch t := subset.(type) {
+		case *core.PdfIndirectObject:
+			t.PdfObject = copyObject(t.PdfObject, objectToObjectCopyMap)
+		case *core.PdfObjectStream:
+			t.PdfObjectDictionary = copyObject(t.PdfObjectDictionary, objectToObjectCopyMap).(*core.PdfObjectDictionary)
+		}
+	}
 	objects := make([]core.PdfObject, len(w.objects))
 	objectsMap := make(map[core.PdfObject]struct{}, len(w.objects))
 	for i, obj := range w.objects {
@@ -377,6 +419,23 @@ func (w *PdfWriter) copyObjects() {
 	}
 }
 
+// subsetFonts subsets the TrueType font programs of the fonts set with PdfPageResources.SetFont in
+// the resources of the pages. The subset font objects are written instead of the objects of the
+// fonts, which are not modified. Fonts that cannot be subset are embedded completely.
+func (w *PdfWriter) subsetFonts() {
+	w.fontSubsets = map[core.PdfObject]core.PdfObject{}
+	for _, font := range w.fonts {
+		objs, err := font.subsetObjects()
+		if err != nil {
+			common.Log.Debug("ERROR: unable to subset font %s: %v", font.BaseFont(), err)
+			continue
+		}
+		for obj, subset := range objs {
+			w.fontSubsets[obj] = subset
+		}
+	}
+}
+
 // SetVersion sets the PDF version of the output file.
 func (w *PdfWriter) SetVersion(majorVersion, minorVersion int) {
 	w.majorVersion = majorVersion
@@ -409,6 +468,40 @@ func (w *PdfWriter) SetNamedDestinations(names core.PdfObject) error {
 	return w.addObjects(names)
 }
 
+// GetDocumentInfo returns the document information of the output PDF. It is initialized with the
+// values set with the package level functions SetPdfAuthor, SetPdfTitle, etc.
+func (w *PdfWriter) GetDocumentInfo() *PdfDocumentInfo {
+	return w.info
+}
+
+// SetDocumentInfo sets the document information dictionary of the output PDF to `info`.
+func (w *PdfWriter) SetDocumentInfo(info *PdfDocumentInfo) {
+	if info == nil {
+		info = &PdfDocumentInfo{}
+	}
+	w.info = info
+}
+
+// SetXMPMetadata sets the XMP metadata of the output PDF, written as the metadata stream of the
+// document catalog. The properties corresponding to the entries of the document information
+// dictionary are set to the document information on writing.
+func (w *PdfWriter) SetXMPMetadata(metadata *XMPMetadata) {
+	w.xmp = metadata
+}
+
+// writeMetadata sets the document information dictionary and the metadata stream of the output
+// PDF for writing.
+func (w *PdfWriter) writeMetadata() error {
+	w.infoObj.PdfObject = w.info.ToPdfObject()
+	if w.xmp == nil {
+		return nil
+	}
+	w.xmp.SetDocumentInfo(w.info)
+	metadata := w.xmp.ToPdfObject()
+	w.catalog.Set("Metadata", metadata)
+	return w.addObjects(metadata)
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (w *PdfWriter) SetOptimizer(optimizer Optimizer) {
 	w.optimizer = optimizer
@@ -433,6 +526,12 @@ func (w *PdfWriter) addObject(obj core.PdfObject) bool {
 		if err != nil {
 			common.Log.Debug("ERROR: %v - skipping", err)
 		}
+		// Streams read with lazy stream data are written with their data.
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			if err := stream.LoadData(); err != nil {
+				common.Log.Debug("ERROR: %v - skipping", err)
+			}
+		}
 
 		w.objects = append(w.objects, obj)
 		w.objectsMap[obj] = struct{}{}
@@ -532,6 +631,14 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 // AddPage adds a page to the PDF file. The new page should be an indirect object.
 func (w *PdfWriter) AddPage(page *PdfPage) error {
 	procPage(page)
+	if page.Resources != nil {
+		for _, font := range page.Resources.setFonts() {
+			if _, has := w.fontsMap[font]; !has {
+				w.fonts = append(w.fonts, font)
+				w.fontsMap[font] = struct{}{}
+			}
+		}
+	}
 	obj := page.ToPdfObject()
 
 	common.Log.Trace("==========")
@@ -701,7 +808,7 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 
 	if pobj, isIndirect := obj.(*core.PdfIndirectObject); isIndirect {
 		w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: pobj.GenerationNumber}
-		outStr := fmt.Sprintf("%d 0 obj\n", num)
+		outStr := fmt.Sprintf("%d %d obj\n", num, pobj.GenerationNumber)
 		if sDict, ok := pobj.PdfObject.(*pdfSignDictionary); ok {
 			sDict.fileOffset = w.writePos + int64(len(outStr))
 		}
@@ -719,7 +826,7 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 	// Still need to make sure is encrypted.
 	if pobj, isStream := obj.(*core.PdfObjectStream); isStream {
 		w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: pobj.GenerationNumber}
-		outStr := fmt.Sprintf("%d 0 obj\n", num)
+		outStr := fmt.Sprintf("%d %d obj\n", num, pobj.GenerationNumber)
 		outStr += pobj.PdfObjectDictionary.WriteString()
 		outStr += "\nstream\n"
 		w.writeString(outStr)
@@ -730,7 +837,7 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 
 	if ostreams, isObjStreams := obj.(*core.PdfObjectStreams); isObjStreams {
 		w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: ostreams.GenerationNumber}
-		outStr := fmt.Sprintf("%d 0 obj\n", num)
+		outStr := fmt.Sprintf("%d %d obj\n", num, ostreams.GenerationNumber)
 		var offsets []string
 		var objData string
 		var offset int64
@@ -871,6 +978,9 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 		return err
 	}
 	w.crypter = crypter
+	w.encryptUserPass = userPass
+	w.encryptOwnerPass = ownerPass
+	w.encryptOptions = EncryptOptions{Permissions: perm, Algorithm: algo}
 	if info.Major != 0 {
 		w.SetVersion(info.Major, info.Minor)
 	}
@@ -906,9 +1016,14 @@ func (w *PdfWriter) writeBytes(bb []byte) error {
 	return nil
 }
 
-// Write writes out the PDF.
-func (w *PdfWriter) Write(writer io.Writer) error {
-	common.Log.Trace("Write()")
+// addDocumentObjects adds the document level objects for writing: the subset fonts, the metadata,
+// the outlines and the forms. Pending objects which were never added are replaced with null.
+func (w *PdfWriter) addDocumentObjects() error {
+	w.subsetFonts()
+	if err := w.writeMetadata(); err != nil {
+		return err
+	}
+
 	// Outlines.
 	if w.outlineTree != nil {
 		common.Log.Trace("OutlineTree: %+v", w.outlineTree)
@@ -951,6 +1066,18 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	}
 	// Set version in the catalog.
 	w.catalog.Set("Version", core.MakeName(fmt.Sprintf("%d.%d", w.majorVersion, w.minorVersion)))
+	return nil
+}
+
+// Write writes out the PDF.
+func (w *PdfWriter) Write(writer io.Writer) error {
+	common.Log.Trace("Write()")
+	if err := w.applyTargetVersion(); err != nil {
+		return err
+	}
+	if err := w.addDocumentObjects(); err != nil {
+		return err
+	}
 
 	// Make a copy of objects prior to optimizing as this can alter the objects.
 	// TODO: Copying wastes memory. Might be worth making user responsible for handling properly.
@@ -969,6 +1096,13 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 		w.objectsMap = objMap
 	}
+	if err := w.convertToTargetVersion(); err != nil {
+		return err
+	}
+
+	if w.linearized {
+		return w.writeLinearized(writer)
+	}
 
 	w.writePos = w.writeOffset
 	w.writer = bufio.NewWriter(writer)
@@ -0,0 +1,279 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// TargetVersionOptions represents the options for writing an output PDF of a target version.
+type TargetVersionOptions struct {
+	// Strict makes Write return an error instead of converting the features which cannot be
+	// converted without loss to the target version: the transparency, which is removed, and the
+	// encryption algorithm, which is replaced with a weaker one.
+	Strict bool
+}
+
+// SetTargetVersion sets the version of the output PDF to `majorVersion`.`minorVersion`. Unlike
+// SetVersion, the version is enforced by Write, which converts the features requiring a later
+// version: object streams and cross-reference streams are not used before PDF 1.5, and the
+// encryption algorithm is replaced with the strongest algorithm of the target version, RC4 before
+// PDF 1.6 and AES-128 before PDF 2.0; encryption is not supported before PDF 1.4. The transparency
+// is removed before PDF 1.4: the soft masks, the constant opacity, the blend modes and the
+// transparency groups. For PDF 2.0, the deprecated entries are removed (the ProcSet entries of the
+// resources, the Name and OPI entries of the fonts and XObjects and the NeedAppearances entry of
+// the form) and the encryption algorithm is replaced with AES-256.
+// The lossy conversions can be turned into errors with `options`. Other features are written as
+// they are.
+func (w *PdfWriter) SetTargetVersion(majorVersion, minorVersion int, options *TargetVersionOptions) {
+	w.SetVersion(majorVersion, minorVersion)
+	w.targetVersion = &core.Version{Major: majorVersion, Minor: minorVersion}
+	w.targetOptions = TargetVersionOptions{}
+	if options != nil {
+		w.targetOptions = *options
+	}
+}
+
+// versionBefore returns true if `version` is before `majorVersion`.`minorVersion`.
+func versionBefore(version core.Version, majorVersion, minorVersion int) bool {
+	return version.Major < majorVersion || version.Major == majorVersion && version.Minor < minorVersion
+}
+
+// applyTargetVersion sets the version of the output PDF to the target version, if set, and
+// replaces the encryption algorithm if not supported by the target version.
+func (w *PdfWriter) applyTargetVersion() error {
+	target := w.targetVersion
+	if target == nil {
+		return nil
+	}
+	if w.crypter != nil {
+		if err := w.applyTargetEncryption(*target); err != nil {
+			return err
+		}
+	}
+	// Encrypt sets the version required by the encryption algorithm.
+	w.SetVersion(target.Major, target.Minor)
+	return nil
+}
+
+// applyTargetEncryption replaces the encryption algorithm of the output PDF with an algorithm of
+// the `target` version.
+func (w *PdfWriter) applyTargetEncryption(target core.Version) error {
+	algorithm := w.encryptOptions.Algorithm
+	switch {
+	case versionBefore(target, 1, 4):
+		common.Log.Debug("ERROR: encryption requires PDF 1.4, target version %s", target)
+		return fmt.Errorf("encryption not supported by PDF %s", target)
+	case !versionBefore(target, 2, 0):
+		// RC4 and AES-128 are deprecated in PDF 2.0.
+		algorithm = AES256bit
+	case versionBefore(target, 1, 6) && algorithm != RC4128bit:
+		algorithm = RC4128bit
+	case algorithm == AES256bit:
+		algorithm = AES128bit
+	}
+	if algorithm == w.encryptOptions.Algorithm {
+		return nil
+	}
+	if algorithm < w.encryptOptions.Algorithm && w.targetOptions.Strict {
+		common.Log.Debug("ERROR: encryption algorithm %d not supported by PDF %s", w.encryptOptions.Algorithm,
+			target)
+		return fmt.Errorf("encryption algorithm not supported by PDF %s", target)
+	}
+
+	common.Log.Debug("Replacing encryption algorithm %d with %d for PDF %s", w.encryptOptions.Algorithm,
+		algorithm, target)
+	w.removeObject(w.encryptObj)
+	return w.Encrypt(w.encryptUserPass, w.encryptOwnerPass, &EncryptOptions{
+		Permissions: w.encryptOptions.Permissions,
+		Algorithm:   algorithm,
+	})
+}
+
+// removeObject removes `obj` from the objects to write.
+func (w *PdfWriter) removeObject(obj core.PdfObject) {
+	if !w.hasObject(obj) {
+		return
+	}
+	objects := w.objects[:0]
+	for _, o := range w.objects {
+		if o != obj {
+			objects = append(objects, o)
+		}
+	}
+	w.objects = objects
+	delete(w.objectsMap, obj)
+}
+
+// convertToTargetVersion converts the objects to write to the target version, if set. The objects
+// must be copies of the objects added to the writer.
+func (w *PdfWriter) convertToTargetVersion() error {
+	target := w.targetVersion
+	if target == nil {
+		return nil
+	}
+
+	if versionBefore(*target, 1, 5) {
+		// The objects of the object streams are written as indirect objects.
+		for _, obj := range append([]core.PdfObject(nil), w.objects...) {
+			if _, ok := obj.(*core.PdfObjectStreams); ok {
+				common.Log.Debug("Object streams not supported by PDF %s: not used", target)
+				w.removeObject(obj)
+			}
+		}
+	}
+
+	var convert func(dict *core.PdfObjectDictionary, isStream bool) error
+	switch {
+	case versionBefore(*target, 1, 4):
+		if root, ok := w.root.PdfObject.(*core.PdfObjectDictionary); ok {
+			root.Remove("Version")
+		}
+		convert = func(dict *core.PdfObjectDictionary, isStream bool) error {
+			return w.removeTransparency(dict, *target)
+		}
+	case !versionBefore(*target, 2, 0):
+		convert = func(dict *core.PdfObjectDictionary, isStream bool) error {
+			removeDeprecatedKeys(dict, isStream)
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	visited := map[core.PdfObject]struct{}{}
+	for _, obj := range w.objects {
+		if err := walkDicts(obj, visited, convert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkDicts calls `fn` for the dictionaries of `obj` and of the objects it contains. The
+// dictionaries of the streams are called with isStream set.
+func walkDicts(obj core.PdfObject, visited map[core.PdfObject]struct{},
+	fn func(dict *core.PdfObjectDictionary, isStream bool) error) error {
+	if _, ok := visited[obj]; ok {
+		return nil
+	}
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		visited[obj] = struct{}{}
+		return walkDicts(t.PdfObject, visited, fn)
+	case *core.PdfObjectStream:
+		visited[obj] = struct{}{}
+		visited[t.PdfObjectDictionary] = struct{}{}
+		if err := fn(t.PdfObjectDictionary, true); err != nil {
+			return err
+		}
+		return walkDictEntries(t.PdfObjectDictionary, visited, fn)
+	case *core.PdfObjectDictionary:
+		visited[obj] = struct{}{}
+		if err := fn(t, false); err != nil {
+			return err
+		}
+		return walkDictEntries(t, visited, fn)
+	case *core.PdfObjectArray:
+		visited[obj] = struct{}{}
+		for _, elem := range t.Elements() {
+			if err := walkDicts(elem, visited, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkDictEntries calls walkDicts for the entries of `dict`.
+func walkDictEntries(dict *core.PdfObjectDictionary, visited map[core.PdfObject]struct{},
+	fn func(dict *core.PdfObjectDictionary, isStream bool) error) error {
+	for _, key := range dict.Keys() {
+		if err := walkDicts(dict.Get(key), visited, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTransparency removes the transparency entries of `dict`, introduced in PDF 1.4, for the
+// `target` version. Returns an error instead in strict mode.
+func (w *PdfWriter) removeTransparency(dict *core.PdfObjectDictionary, target core.Version) error {
+	var keys []core.PdfObjectName
+	if smask := dict.Get("SMask"); smask != nil {
+		if name, ok := core.GetName(smask); !ok || *name != "None" {
+			keys = append(keys, "SMask")
+		}
+	}
+	if n, ok := core.GetIntVal(dict.Get("SMaskInData")); ok && n != 0 {
+		keys = append(keys, "SMaskInData")
+	}
+	for _, key := range []core.PdfObjectName{"CA", "ca"} {
+		// The CA entry of the appearance characteristics of widget annotations is a caption.
+		if alpha, err := core.GetNumberAsFloat(dict.Get(key)); err == nil && alpha < 1 {
+			keys = append(keys, key)
+		}
+	}
+	if bm := dict.Get("BM"); bm != nil && !isNormalBlendMode(bm) {
+		keys = append(keys, "BM")
+	}
+	if group, ok := core.GetDict(dict.Get("Group")); ok {
+		if s, ok := core.GetName(group.Get("S")); ok && *s == "Transparency" {
+			keys = append(keys, "Group")
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if w.targetOptions.Strict {
+		common.Log.Debug("ERROR: transparency entry %s not supported by PDF %s", keys[0], target)
+		return fmt.Errorf("transparency not supported by PDF %s", target)
+	}
+	for _, key := range keys {
+		common.Log.Debug("Transparency not supported by PDF %s: %s removed", target, key)
+		dict.Remove(key)
+	}
+	return nil
+}
+
+// isNormalBlendMode returns true if the blend mode `bm`, a name or an array of names, is Normal.
+func isNormalBlendMode(bm core.PdfObject) bool {
+	if arr, ok := core.GetArray(bm); ok {
+		for _, elem := range arr.Elements() {
+			if !isNormalBlendMode(elem) {
+				return false
+			}
+		}
+		return true
+	}
+	name, ok := core.GetName(bm)
+	return ok && (*name == "Normal" || *name == "Compatible")
+}
+
+// removeDeprecatedKeys removes the entries of `dict` deprecated in PDF 2.0.
+func removeDeprecatedKeys(dict *core.PdfObjectDictionary, isStream bool) {
+	var keys []core.PdfObjectName
+	if dict.Get("ProcSet") != nil {
+		keys = append(keys, "ProcSet")
+	}
+	typ, _ := core.GetName(dict.Get("Type"))
+	subtype, _ := core.GetName(dict.Get("Subtype"))
+	isFont := typ != nil && *typ == "Font"
+	isXObject := isStream && (typ != nil && *typ == "XObject" ||
+		subtype != nil && (*subtype == "Image" || *subtype == "Form"))
+	if (isFont || isXObject) && dict.Get("Name") != nil {
+		keys = append(keys, "Name")
+	}
+	if isXObject && dict.Get("OPI") != nil {
+		keys = append(keys, "OPI")
+	}
+	if dict.Get("Fields") != nil && dict.Get("NeedAppearances") != nil {
+		keys = append(keys, "NeedAppearances")
+	}
+	for _, key := range keys {
+		common.Log.Debug("Deprecated in PDF 2.0: %s removed", key)
+		dict.Remove(key)
+	}
+}
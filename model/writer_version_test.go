@@ -0,0 +1,170 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+)
+
+// newTargetVersionTestPage returns a page with transparency and deprecated entries.
+func newTargetVersionTestPage() *PdfPage {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 612, Ury: 792}
+	page.Resources = NewPdfPageResources()
+	page.Resources.ProcSet = core.MakeArray(core.MakeName("PDF"), core.MakeName("Text"))
+	gs := core.MakeDict()
+	gs.Set("Type", core.MakeName("ExtGState"))
+	gs.Set("ca", core.MakeFloat(0.5))
+	gs.Set("BM", core.MakeName("Multiply"))
+	page.Resources.ExtGState = core.MakeDict()
+	page.Resources.ExtGState.(*core.PdfObjectDictionary).Set("GS0", gs)
+	font := core.MakeDict()
+	font.Set("Type", core.MakeName("Font"))
+	font.Set("Subtype", core.MakeName("Type1"))
+	font.Set("Name", core.MakeName("F1"))
+	font.Set("BaseFont", core.MakeName("Helvetica"))
+	page.Resources.Font = core.MakeDict()
+	page.Resources.Font.(*core.PdfObjectDictionary).Set("F1", core.MakeIndirectObject(font))
+	group := core.MakeDict()
+	group.Set("S", core.MakeName("Transparency"))
+	page.Group = group
+	return page
+}
+
+// writeTargetVersionTestFile writes a file with the test page, set up with `setup`.
+func writeTargetVersionTestFile(t *testing.T, setup func(w *PdfWriter)) ([]byte, error) {
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(newTargetVersionTestPage()))
+	setup(&w)
+	var buf bytes.Buffer
+	err := w.Write(&buf)
+	return buf.Bytes(), err
+}
+
+// readTargetVersionTestFile reads `data`, encrypted with password `password` if set, and returns
+// the reader and the page dictionary.
+func readTargetVersionTestFile(t *testing.T, data []byte, password string) (*PdfReader, *core.PdfObjectDictionary) {
+	reader, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	if password != "" {
+		ok, err := reader.Decrypt([]byte(password))
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	page, err := reader.GetPage(1)
+	require.NoError(t, err)
+	return reader, page.GetPageDict()
+}
+
+// resourceDict returns the resource `name` of category `category` of the page `pageDict`.
+func resourceDict(pageDict *core.PdfObjectDictionary, category, name core.PdfObjectName) *core.PdfObjectDictionary {
+	resources, _ := core.GetDict(pageDict.Get("Resources"))
+	dicts, _ := core.GetDict(resources.Get(category))
+	dict, _ := core.GetDict(dicts.Get(name))
+	return dict
+}
+
+func TestWriteTargetVersionObjectStreams(t *testing.T) {
+	// Without target version, the object streams require PDF 1.5.
+	data, err := writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		w.SetVersion(1, 4)
+		w.SetOptimizer(&objectStreamsOptimizer{})
+	})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.5")))
+	require.Contains(t, string(data), "/ObjStm")
+
+	data, err = writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		w.SetTargetVersion(1, 4, nil)
+		w.SetOptimizer(&objectStreamsOptimizer{})
+	})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4")))
+	require.NotContains(t, string(data), "/ObjStm")
+	require.NotContains(t, string(data), "/XRef")
+
+	reader, pageDict := readTargetVersionTestFile(t, data, "")
+	require.Equal(t, core.Version{Major: 1, Minor: 4}, reader.PdfVersion())
+	// Transparency is supported by PDF 1.4.
+	require.NotNil(t, pageDict.Get("Group"))
+	require.NotNil(t, resourceDict(pageDict, "ExtGState", "GS0").Get("ca"))
+}
+
+func TestWriteTargetVersionTransparency(t *testing.T) {
+	data, err := writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		w.SetTargetVersion(1, 3, nil)
+	})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.3")))
+
+	_, pageDict := readTargetVersionTestFile(t, data, "")
+	require.Nil(t, pageDict.Get("Group"))
+	gs := resourceDict(pageDict, "ExtGState", "GS0")
+	require.Nil(t, gs.Get("ca"))
+	require.Nil(t, gs.Get("BM"))
+	require.NotNil(t, gs.Get("Type"))
+
+	_, err = writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		w.SetTargetVersion(1, 3, &TargetVersionOptions{Strict: true})
+	})
+	require.EqualError(t, err, "transparency not supported by PDF 1.3")
+}
+
+func TestWriteTargetVersionDeprecated(t *testing.T) {
+	data, err := writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		w.SetTargetVersion(2, 0, nil)
+	})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-2.0")))
+
+	_, pageDict := readTargetVersionTestFile(t, data, "")
+	resources, _ := core.GetDict(pageDict.Get("Resources"))
+	require.Nil(t, resources.Get("ProcSet"))
+	font := resourceDict(pageDict, "Font", "F1")
+	require.Nil(t, font.Get("Name"))
+	require.NotNil(t, font.Get("BaseFont"))
+	require.NotNil(t, pageDict.Get("Group"))
+}
+
+func TestWriteTargetVersionEncryption(t *testing.T) {
+	for _, tc := range []struct {
+		major, minor int
+		algorithm    EncryptionAlgorithm
+		v            int // V entry of the encryption dictionary.
+	}{
+		{1, 7, AES256bit, 4},
+		{1, 5, AES128bit, 2},
+		{1, 7, RC4128bit, 2},
+		{2, 0, RC4128bit, 5},
+	} {
+		data, err := writeTargetVersionTestFile(t, func(w *PdfWriter) {
+			require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: tc.algorithm}))
+			w.SetTargetVersion(tc.major, tc.minor, nil)
+		})
+		require.NoError(t, err)
+
+		reader, _ := readTargetVersionTestFile(t, data, "owner")
+		require.Equal(t, core.Version{Major: tc.major, Minor: tc.minor}, reader.PdfVersion())
+		trailer, err := reader.GetTrailer()
+		require.NoError(t, err)
+		encrypt, ok := core.GetDict(trailer.Get("Encrypt"))
+		require.True(t, ok)
+		v, _ := core.GetIntVal(encrypt.Get("V"))
+		require.Equal(t, tc.v, v, "%d.%d", tc.major, tc.minor)
+	}
+
+	_, err := writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: AES256bit}))
+		w.SetTargetVersion(1, 6, &TargetVersionOptions{Strict: true})
+	})
+	require.EqualError(t, err, "encryption algorithm not supported by PDF 1.6")
+
+	_, err = writeTargetVersionTestFile(t, func(w *PdfWriter) {
+		require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), nil))
+		w.SetTargetVersion(1, 3, nil)
+	})
+	require.EqualError(t, err, "encryption not supported by PDF 1.3")
+}
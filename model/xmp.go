@@ -0,0 +1,504 @@
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+)
+
+// XMP namespaces of the properties corresponding to the entries of the document information
+// dictionary.
+const (
+	XMPNamespaceDublinCore = "http://purl.org/dc/elements/1.1/"
+	XMPNamespaceXMP        = "http://ns.adobe.com/xap/1.0/"
+	XMPNamespacePDF        = "http://ns.adobe.com/pdf/1.3/"
+	XMPNamespacePDFX       = "http://ns.adobe.com/pdfx/1.3/" // Custom document information entries.
+)
+
+const (
+	xmpNamespaceRDF  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNamespaceMeta = "adobe:ns:meta/"
+	xmlNamespace     = "http://www.w3.org/XML/1998/namespace"
+)
+
+// xmpPrefixes are the prefixes used for the namespaces of properties set by XMPMetadata.
+var xmpPrefixes = map[string]string{
+	XMPNamespaceDublinCore: "dc",
+	XMPNamespaceXMP:        "xmp",
+	XMPNamespacePDF:        "pdf",
+	XMPNamespacePDFX:       "pdfx",
+}
+
+// xmpDateLayouts are the layouts of the date values of XMP properties, from the most to the least
+// precise.
+var xmpDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// XMPMetadata is an XMP metadata packet, as contained in the metadata stream of a document
+// (section 14.3.2 "Metadata Streams" PDF32000_2008).
+// The top level properties of the packet are kept in order. Properties which are not changed are
+// written as they were read.
+type XMPMetadata struct {
+	properties []*xmpProperty
+	namespaces map[string]string // The namespace URIs of the prefixes declared in the packet.
+}
+
+// xmpValueType is the type of the value of an XMP property.
+type xmpValueType int
+
+const (
+	xmpText    xmpValueType = iota // A simple text value.
+	xmpLangAlt                     // A language alternative, with the text as x-default value.
+	xmpSeq                         // An ordered array of text values.
+)
+
+// xmpProperty is a top level property of an XMP packet.
+type xmpProperty struct {
+	ns, name  string
+	value     string   // The text value of simple and language alternative properties.
+	items     []string // The items of array properties.
+	raw       []byte   // The XML of the property as read, nil for properties set by XMPMetadata.
+	valueType xmpValueType
+}
+
+// text returns the text value of `prop`. The items of arrays are separated by semicolons.
+func (prop *xmpProperty) text() string {
+	if prop.items != nil {
+		return strings.Join(prop.items, "; ")
+	}
+	return prop.value
+}
+
+// NewXMPMetadata returns an empty XMP metadata packet.
+func NewXMPMetadata() *XMPMetadata {
+	return &XMPMetadata{namespaces: map[string]string{}}
+}
+
+// ParseXMPMetadata parses the XMP metadata packet `data`.
+func ParseXMPMetadata(data []byte) (*XMPMetadata, error) {
+	m := NewXMPMetadata()
+	d := xml.NewDecoder(bytes.NewReader(data))
+	foundRDF := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			common.Log.Debug("ERROR: invalid XMP packet: %v", err)
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		m.addNamespaces(start)
+		switch {
+		case start.Name.Space == xmpNamespaceRDF && start.Name.Local == "RDF":
+			foundRDF = true
+		case start.Name.Space == xmpNamespaceRDF && start.Name.Local == "Description":
+			if err := m.parseDescription(d, data, start); err != nil {
+				common.Log.Debug("ERROR: invalid XMP packet: %v", err)
+				return nil, err
+			}
+		}
+	}
+	if !foundRDF {
+		return nil, errors.New("XMP packet without rdf:RDF element")
+	}
+	return m, nil
+}
+
+// addNamespaces records the namespace declarations of `start`.
+func (m *XMPMetadata) addNamespaces(start xml.StartElement) {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			if _, ok := m.namespaces[attr.Name.Local]; !ok {
+				m.namespaces[attr.Name.Local] = attr.Value
+			}
+		}
+	}
+}
+
+// parseDescription adds the properties of the rdf:Description element starting with `start`,
+// read by `d` from `data`.
+func (m *XMPMetadata) parseDescription(d *xml.Decoder, data []byte, start xml.StartElement) error {
+	// Simple properties can be written as attributes.
+	for _, attr := range start.Attr {
+		switch attr.Name.Space {
+		case "", "xmlns", xmpNamespaceRDF, xmlNamespace:
+			continue
+		}
+		m.properties = append(m.properties, &xmpProperty{
+			ns: attr.Name.Space, name: attr.Name.Local, value: attr.Value,
+		})
+	}
+
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			m.addNamespaces(t)
+			node, err := parseXMLNode(d, t)
+			if err != nil {
+				return err
+			}
+			prop := node.property()
+			prop.raw = data[offset:d.InputOffset()]
+			m.properties = append(m.properties, prop)
+		}
+	}
+}
+
+// xmlNode is an XML element.
+type xmlNode struct {
+	start    xml.StartElement
+	children []*xmlNode
+	text     string
+}
+
+// parseXMLNode returns the element starting with `start`, read by `d`.
+func parseXMLNode(d *xml.Decoder, start xml.StartElement) (*xmlNode, error) {
+	node := &xmlNode{start: start.Copy()}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := parseXMLNode(d, t)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		case xml.CharData:
+			node.text += string(t)
+		case xml.EndElement:
+			return node, nil
+		}
+	}
+}
+
+// attr returns the value of the attribute `name` of `node`.
+func (node *xmlNode) attr(space, local string) (string, bool) {
+	for _, attr := range node.start.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// property returns the XMP property of the property element `node`.
+func (node *xmlNode) property() *xmpProperty {
+	prop := &xmpProperty{ns: node.start.Name.Space, name: node.start.Name.Local}
+	if len(node.children) == 0 {
+		if resource, ok := node.attr(xmpNamespaceRDF, "resource"); ok {
+			prop.value = resource
+		} else {
+			prop.value = strings.TrimSpace(node.text)
+		}
+		return prop
+	}
+
+	// Arrays. Structures and qualified values have no text value.
+	container := node.children[0]
+	if container.start.Name.Space != xmpNamespaceRDF {
+		return prop
+	}
+	switch container.start.Name.Local {
+	case "Alt":
+		prop.valueType = xmpLangAlt
+		for _, li := range container.children {
+			if lang, _ := li.attr(xmlNamespace, "lang"); lang == "x-default" || prop.value == "" {
+				prop.value = strings.TrimSpace(li.text)
+			}
+		}
+	case "Seq", "Bag":
+		prop.valueType = xmpSeq
+		prop.items = []string{}
+		for _, li := range container.children {
+			prop.items = append(prop.items, strings.TrimSpace(li.text))
+		}
+	}
+	return prop
+}
+
+// find returns the index of the property `name` in namespace `ns`, or -1 if there is none.
+func (m *XMPMetadata) find(ns, name string) int {
+	for i, prop := range m.properties {
+		if prop.ns == ns && prop.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Property returns the text value of the top level property `name` in namespace `ns`. The items of
+// arrays are separated by semicolons and language alternatives have their default value.
+func (m *XMPMetadata) Property(ns, name string) (string, bool) {
+	i := m.find(ns, name)
+	if i < 0 {
+		return "", false
+	}
+	return m.properties[i].text(), true
+}
+
+// SetProperty sets the top level property `name` in namespace `ns` to the simple text value
+// `value`. An empty value removes the property.
+func (m *XMPMetadata) SetProperty(ns, name, value string) {
+	m.set(&xmpProperty{ns: ns, name: name, value: value})
+}
+
+// set replaces the property of `m` with the namespace and name of `prop` by `prop`, or removes it
+// if `prop` has no value.
+func (m *XMPMetadata) set(prop *xmpProperty) {
+	i := m.find(prop.ns, prop.name)
+	switch {
+	case prop.value == "" && len(prop.items) == 0:
+		if i >= 0 {
+			m.properties = append(m.properties[:i], m.properties[i+1:]...)
+		}
+	case i >= 0:
+		if old := m.properties[i]; old.raw != nil && old.valueType == prop.valueType &&
+			old.value == prop.value && equalStrings(old.items, prop.items) {
+			// Unchanged.
+			return
+		}
+		m.properties[i] = prop
+	default:
+		m.properties = append(m.properties, prop)
+	}
+}
+
+// equalStrings returns true if `a` and `b` have the same elements.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DocumentInfo returns the document information of the properties of `m` corresponding to the
+// entries of the document information dictionary (section 14.3.3 "Document Information
+// Dictionary" PDF32000_2008). Custom entries are read from properties in the XMPNamespacePDFX
+// namespace.
+func (m *XMPMetadata) DocumentInfo() *PdfDocumentInfo {
+	info := &PdfDocumentInfo{}
+	info.Title, _ = m.Property(XMPNamespaceDublinCore, "title")
+	info.Author, _ = m.Property(XMPNamespaceDublinCore, "creator")
+	info.Subject, _ = m.Property(XMPNamespaceDublinCore, "description")
+	info.Keywords, _ = m.Property(XMPNamespacePDF, "Keywords")
+	info.Creator, _ = m.Property(XMPNamespaceXMP, "CreatorTool")
+	info.Producer, _ = m.Property(XMPNamespacePDF, "Producer")
+	info.Trapped, _ = m.Property(XMPNamespacePDF, "Trapped")
+	if value, ok := m.Property(XMPNamespaceXMP, "CreateDate"); ok {
+		info.CreationDate = parseXMPDate(value)
+	}
+	if value, ok := m.Property(XMPNamespaceXMP, "ModifyDate"); ok {
+		info.ModDate = parseXMPDate(value)
+	}
+	for _, prop := range m.properties {
+		if prop.ns == XMPNamespacePDFX && !documentInfoKeys[prop.name] {
+			info.set(prop.name, prop.text())
+		}
+	}
+	return info
+}
+
+// SetDocumentInfo sets the properties of `m` corresponding to the entries of the document
+// information dictionary to the values of `info`, so that the metadata is consistent with the
+// document information dictionary. Properties of entries which are not set are removed.
+// Custom entries whose keys are not valid XML names are skipped.
+func (m *XMPMetadata) SetDocumentInfo(info *PdfDocumentInfo) {
+	m.set(&xmpProperty{ns: XMPNamespaceDublinCore, name: "title", value: info.Title, valueType: xmpLangAlt})
+	// Multiple authors are separated by semicolons.
+	var authors []string
+	for _, author := range strings.Split(info.Author, ";") {
+		if author = strings.TrimSpace(author); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	m.set(&xmpProperty{ns: XMPNamespaceDublinCore, name: "creator", items: authors, valueType: xmpSeq})
+	m.set(&xmpProperty{ns: XMPNamespaceDublinCore, name: "description", value: info.Subject, valueType: xmpLangAlt})
+	m.SetProperty(XMPNamespacePDF, "Keywords", info.Keywords)
+	m.SetProperty(XMPNamespaceXMP, "CreatorTool", info.Creator)
+	m.SetProperty(XMPNamespacePDF, "Producer", info.Producer)
+	m.SetProperty(XMPNamespacePDF, "Trapped", info.Trapped)
+	m.SetProperty(XMPNamespaceXMP, "CreateDate", formatXMPDate(info.CreationDate))
+	m.SetProperty(XMPNamespaceXMP, "ModifyDate", formatXMPDate(info.ModDate))
+
+	var custom []*xmpProperty
+	for _, prop := range m.properties {
+		if prop.ns == XMPNamespacePDFX {
+			if _, ok := info.custom[prop.name]; !ok {
+				custom = append(custom, prop)
+			}
+		}
+	}
+	for _, prop := range custom {
+		m.SetProperty(prop.ns, prop.name, "")
+	}
+	for _, key := range info.CustomKeys() {
+		if !isXMLName(key) {
+			common.Log.Debug("ERROR: document information key %q is not a valid XMP property name", key)
+			continue
+		}
+		m.SetProperty(XMPNamespacePDFX, key, info.custom[key])
+	}
+}
+
+// isXMLName returns true if `name` is a valid XML element name without a prefix.
+func isXMLName(name string) bool {
+	for i, r := range name {
+		letter := r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7f
+		if !letter && (i == 0 || !(r == '-' || r == '.' || r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return name != "" && !strings.HasPrefix(strings.ToLower(name), "xml")
+}
+
+// parseXMPDate returns the time of the XMP date value `value`, or the zero time if it is invalid.
+func parseXMPDate(value string) time.Time {
+	for _, layout := range xmpDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	common.Log.Debug("ERROR: invalid XMP date %q", value)
+	return time.Time{}
+}
+
+// formatXMPDate returns the XMP date value of `t`, or an empty string for the zero time.
+func formatXMPDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Bytes returns the XMP packet of `m`.
+func (m *XMPMetadata) Bytes() []byte {
+	// Declare the namespaces of the packet which was read, as its properties are written as read,
+	// and prefixes for the namespaces of the other properties.
+	prefixes := map[string]string{}
+	declared := map[string]string{}
+	for prefix, ns := range m.namespaces {
+		if prefix != "x" && prefix != "rdf" {
+			declared[prefix] = ns
+			prefixes[ns] = prefix
+		}
+	}
+	for _, prop := range m.properties {
+		if prop.raw != nil || prefixes[prop.ns] != "" {
+			continue
+		}
+		prefix := xmpPrefixes[prop.ns]
+		for i := 1; prefix == "" || declared[prefix] != ""; i++ {
+			prefix = fmt.Sprintf("ns%d", i)
+		}
+		declared[prefix] = prop.ns
+		prefixes[prop.ns] = prefix
+	}
+	var keys []string
+	for prefix := range declared {
+		keys = append(keys, prefix)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"" + xmpNamespaceMeta + "\">\n")
+	buf.WriteString(" <rdf:RDF xmlns:rdf=\"" + xmpNamespaceRDF + "\">\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\"")
+	for _, prefix := range keys {
+		attr := "xmlns"
+		if prefix != "" {
+			attr += ":" + prefix
+		}
+		buf.WriteString("\n    " + attr + "=\"" + escapeXML(declared[prefix]) + "\"")
+	}
+	buf.WriteString(">\n")
+	for _, prop := range m.properties {
+		buf.WriteString("   ")
+		if prop.raw != nil {
+			buf.Write(prop.raw)
+		} else {
+			prop.write(&buf, prefixes[prop.ns])
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString(" </rdf:RDF>\n")
+	buf.WriteString("</x:xmpmeta>\n")
+	buf.WriteString("<?xpacket end=\"w\"?>")
+	return buf.Bytes()
+}
+
+// write writes the XML of `prop` to `buf`, with `prefix` for the namespace of the property.
+func (prop *xmpProperty) write(buf *bytes.Buffer, prefix string) {
+	name := prefix + ":" + prop.name
+	buf.WriteString("<" + name + ">")
+	switch prop.valueType {
+	case xmpLangAlt:
+		buf.WriteString("<rdf:Alt><rdf:li xml:lang=\"x-default\">" + escapeXML(prop.value) + "</rdf:li></rdf:Alt>")
+	case xmpSeq:
+		buf.WriteString("<rdf:Seq>")
+		for _, item := range prop.items {
+			buf.WriteString("<rdf:li>" + escapeXML(item) + "</rdf:li>")
+		}
+		buf.WriteString("</rdf:Seq>")
+	default:
+		buf.WriteString(escapeXML(prop.value))
+	}
+	buf.WriteString("</" + name + ">")
+}
+
+// escapeXML returns `s` escaped for XML character data and attribute values.
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// ToPdfObject returns a metadata stream containing the XMP packet of `m`.
+func (m *XMPMetadata) ToPdfObject() core.PdfObject {
+	// Metadata streams are not compressed, so that they can be read by applications which do not
+	// parse PDF.
+	stream, err := core.MakeStream(m.Bytes(), nil)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to create metadata stream: %v", err)
+		return core.MakeNull()
+	}
+	stream.Set("Type", core.MakeName("Metadata"))
+	stream.Set("Subtype", core.MakeName("XML"))
+	return stream
+}
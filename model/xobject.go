@@ -46,6 +46,9 @@ func NewXObjectForm() *XObjectForm {
 func NewXObjectFormFromStream(stream *core.PdfObjectStream) (*XObjectForm, error) {
 	form := &XObjectForm{}
 	form.primitive = stream
+	if err := stream.LoadData(); err != nil {
+		return nil, err
+	}
 
 	dict := *(stream.PdfObjectDictionary)
 
@@ -370,6 +373,9 @@ func toGray(matte *core.PdfObjectArray) (float64, error) {
 func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, error) {
 	img := &XObjectImage{}
 	img.primitive = stream
+	if err := stream.LoadData(); err != nil {
+		return nil, err
+	}
 
 	dict := *(stream.PdfObjectDictionary)
 
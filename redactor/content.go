@@ -0,0 +1,532 @@
+package redactor
+
+import (
+	"math"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// maxFormDepth is the maximum nesting depth of form XObjects which are redacted.
+const maxFormDepth = 20
+
+// clipExtent is the half-size of the rectangle used for clipping out the redaction regions.
+// It exceeds the maximum page size of 14,400 units.
+const clipExtent = 30000
+
+// graphicsState is the part of the graphics state tracked while redacting a content stream.
+type graphicsState struct {
+	ctm transform.Matrix
+
+	contentutil.TextState
+}
+
+// subpath is a subpath of the path being constructed.
+type subpath struct {
+	ops    []*contentstream.ContentStreamOperation
+	points [][2]float64 // Points of the subpath (including control points) in page coordinates.
+}
+
+// bbox returns the bounding box of `sp` in page coordinates.
+func (sp *subpath) bbox() model.PdfRectangle {
+	bbox := model.PdfRectangle{Llx: sp.points[0][0], Lly: sp.points[0][1], Urx: sp.points[0][0], Ury: sp.points[0][1]}
+	for _, p := range sp.points[1:] {
+		bbox = model.PdfRectangle{
+			Llx: math.Min(bbox.Llx, p[0]), Lly: math.Min(bbox.Lly, p[1]),
+			Urx: math.Max(bbox.Urx, p[0]), Ury: math.Max(bbox.Ury, p[1]),
+		}
+	}
+	return bbox
+}
+
+// contentRedactor redacts a single content stream.
+type contentRedactor struct {
+	r         *redactor
+	resources *resourceEditor
+	depth     int
+
+	gs    graphicsState
+	stack []graphicsState
+	text  contentutil.TextMatrices // The text matrices of the current text object.
+
+	// Path under construction.
+	subpaths []*subpath
+	clipOp   *contentstream.ContentStreamOperation
+
+	out     contentstream.ContentStreamOperations
+	changed bool
+}
+
+// redactContent redacts the content stream `contents` with resources `resources` and current
+// transformation matrix `ctm`, starting with the graphics state `gs` (the default graphics state
+// if nil). Resources needed by the redacted content are added to `resources`. `depth` is the form
+// XObject nesting depth.
+// Returns the redacted content stream operations and whether any content was redacted.
+func (r *redactor) redactContent(contents string, resources *resourceEditor, ctm transform.Matrix,
+	gs *graphicsState, depth int) (*contentstream.ContentStreamOperations, bool, error) {
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, false, err
+	}
+
+	c := &contentRedactor{
+		r:         r,
+		resources: resources,
+		depth:     depth,
+		text:      contentutil.NewTextMatrices(),
+	}
+	if gs != nil {
+		c.gs = *gs
+	} else {
+		c.gs = graphicsState{TextState: contentutil.NewTextState()}
+	}
+	c.gs.ctm = ctm
+
+	for _, op := range *ops {
+		if err := c.process(op); err != nil {
+			return nil, false, err
+		}
+	}
+	// Keep any incomplete path as is.
+	c.flushPath()
+
+	return c.out.WrapIfNeeded(), c.changed, nil
+}
+
+// emit appends `ops` to the output.
+func (c *contentRedactor) emit(ops ...*contentstream.ContentStreamOperation) {
+	for _, op := range ops {
+		if op.Operand != "Do" || len(op.Params) != 1 {
+			continue
+		}
+		if name, ok := core.GetName(op.Params[0]); ok {
+			c.resources.useXObject(*name)
+		}
+	}
+	c.out = append(c.out, ops...)
+}
+
+// process redacts the content stream operation `op`.
+func (c *contentRedactor) process(op *contentstream.ContentStreamOperation) error {
+	switch op.Operand {
+	case "m", "l", "c", "v", "y", "h", "re":
+		c.addPathOp(op)
+		return nil
+	case "W", "W*":
+		c.clipOp = op
+		return nil
+	case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+		c.paintPath(op)
+		return nil
+	}
+
+	// Any other operator ends the path.
+	c.flushPath()
+
+	switch op.Operand {
+	case "q":
+		c.stack = append(c.stack, c.gs)
+	case "Q":
+		if len(c.stack) > 0 {
+			c.gs = c.stack[len(c.stack)-1]
+			c.stack = c.stack[:len(c.stack)-1]
+		}
+	case "cm":
+		m, err := contentutil.OperandMatrix(op)
+		if err != nil {
+			return err
+		}
+		c.gs.ctm = c.gs.ctm.Mult(m)
+	case "Tj", "TJ", "'", "\"":
+		return c.showText(op)
+	case "sh":
+		c.emitClipped([]*contentstream.ContentStreamOperation{op})
+		return nil
+	case "BI":
+		c.showInlineImage(op)
+		return nil
+	case "Do":
+		return c.showXObject(op)
+	default:
+		if _, err := contentutil.ProcessTextOp(op, &c.gs.TextState, &c.text, c.resources.resources, c.r.fonts); err != nil {
+			return err
+		}
+	}
+
+	c.emit(op)
+	return nil
+}
+
+// addPathOp adds the path construction operation `op` to the current path.
+func (c *contentRedactor) addPathOp(op *contentstream.ContentStreamOperation) {
+	vals, err := core.GetNumbersAsFloat(op.Params)
+	if err != nil {
+		common.Log.Debug("ERROR: invalid path operation %s: %v", op.Operand, err)
+		vals = nil
+	}
+
+	var points [][2]float64
+	addPoint := func(x, y float64) {
+		px, py := c.gs.ctm.TransformPoint(x, y)
+		points = append(points, [2]float64{px, py})
+	}
+	if op.Operand == "re" && len(vals) == 4 {
+		x, y, w, h := vals[0], vals[1], vals[2], vals[3]
+		addPoint(x, y)
+		addPoint(x+w, y)
+		addPoint(x+w, y+h)
+		addPoint(x, y+h)
+	} else {
+		for i := 0; i+1 < len(vals); i += 2 {
+			addPoint(vals[i], vals[i+1])
+		}
+	}
+
+	if op.Operand == "m" || op.Operand == "re" || len(c.subpaths) == 0 {
+		c.subpaths = append(c.subpaths, &subpath{})
+	}
+	sp := c.subpaths[len(c.subpaths)-1]
+	sp.ops = append(sp.ops, op)
+	sp.points = append(sp.points, points...)
+	if op.Operand == "re" {
+		// The current point after re is the start of the rectangle.
+		c.subpaths = append(c.subpaths, &subpath{})
+	}
+}
+
+// pathOps returns the operations of the subpaths `subpaths`.
+func pathOps(subpaths []*subpath) []*contentstream.ContentStreamOperation {
+	var ops []*contentstream.ContentStreamOperation
+	for _, sp := range subpaths {
+		ops = append(ops, sp.ops...)
+	}
+	return ops
+}
+
+// flushPath outputs the current path unchanged. It is used for paths that are not terminated
+// by a path painting operator.
+func (c *contentRedactor) flushPath() {
+	c.emit(pathOps(c.subpaths)...)
+	if c.clipOp != nil {
+		c.emit(c.clipOp)
+	}
+	c.subpaths = nil
+	c.clipOp = nil
+}
+
+// paintPath paints the current path with the path painting operation `op`. Subpaths with
+// points inside the redaction regions are removed and subpaths that pass over the regions are
+// clipped so that the regions are not painted.
+func (c *contentRedactor) paintPath(op *contentstream.ContentStreamOperation) {
+	subpaths, clipOp := c.subpaths, c.clipOp
+	c.subpaths = nil
+	c.clipOp = nil
+
+	if op.Operand == "n" {
+		c.emit(pathOps(subpaths)...)
+		if clipOp != nil {
+			c.emit(clipOp)
+		}
+		c.emit(op)
+		return
+	}
+
+	var kept []*subpath
+	numRemoved := 0
+	clipped := false
+	for _, sp := range subpaths {
+		if len(sp.points) == 0 {
+			kept = append(kept, sp)
+			continue
+		}
+		if !c.r.overlaps(sp.bbox()) {
+			kept = append(kept, sp)
+			continue
+		}
+		inside := false
+		for _, p := range sp.points {
+			if c.r.contains(p[0], p[1]) {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			numRemoved++
+			continue
+		}
+		kept = append(kept, sp)
+		clipped = true
+	}
+
+	if numRemoved == 0 && !clipped {
+		c.emit(pathOps(subpaths)...)
+		if clipOp != nil {
+			c.emit(clipOp)
+		}
+		c.emit(op)
+		return
+	}
+
+	c.changed = true
+	c.r.result.NumPaths += numRemoved
+	if clipped {
+		c.r.result.NumPaths++
+	}
+
+	paintOps := append(pathOps(kept), op)
+	hasPoints := false
+	for _, sp := range kept {
+		if len(sp.points) > 0 {
+			hasPoints = true
+			break
+		}
+	}
+	if hasPoints {
+		if clipped {
+			c.emitClipped(paintOps)
+		} else {
+			c.emit(paintOps...)
+		}
+	}
+
+	// The clipping path takes effect after the path is painted, so it can be set separately.
+	if clipOp != nil {
+		c.emit(pathOps(subpaths)...)
+		c.emit(clipOp, &contentstream.ContentStreamOperation{Operand: "n"})
+	}
+}
+
+// emitClipped outputs the painting operations `ops`, clipped so that they are not painted inside
+// the redaction regions.
+func (c *contentRedactor) emitClipped(ops []*contentstream.ContentStreamOperation) {
+	inv, ok := c.gs.ctm.Inverse()
+	if !ok {
+		// Nothing is painted with a degenerate transformation.
+		return
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	cc.Add_cm(inv[0], inv[1], inv[3], inv[4], inv[6], inv[7])
+	for _, region := range c.r.regions {
+		cc.Add_re(-clipExtent, -clipExtent, 2*clipExtent, 2*clipExtent)
+		cc.Add_re(region.Llx, region.Lly, region.Urx-region.Llx, region.Ury-region.Lly)
+		cc.Add_W_starred()
+		cc.Add_n()
+	}
+	m := c.gs.ctm
+	cc.Add_cm(m[0], m[1], m[3], m[4], m[6], m[7])
+	c.emit(*cc.Operations()...)
+	c.emit(ops...)
+	c.emit(&contentstream.ContentStreamOperation{Operand: "Q"})
+	c.changed = true
+}
+
+// showInlineImage outputs the inline image operation `op` unless it overlaps the redaction regions.
+func (c *contentRedactor) showInlineImage(op *contentstream.ContentStreamOperation) {
+	llx, lly, urx, ury := c.gs.ctm.TransformBBox(0, 0, 1, 1)
+	bbox := model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: ury}
+	if intersectionArea(bbox, c.regionsBBox(bbox)) <= 0 {
+		c.emit(op)
+		return
+	}
+	c.changed = true
+	c.r.result.NumImages++
+}
+
+// regionsBBox returns the bounding box of the part of `bbox` covered by the regions, or an
+// empty rectangle if `bbox` does not intersect any region.
+func (c *contentRedactor) regionsBBox(bbox model.PdfRectangle) model.PdfRectangle {
+	var covered model.PdfRectangle
+	found := false
+	for _, region := range c.r.regions {
+		if intersectionArea(region, bbox) <= 0 {
+			continue
+		}
+		if !found {
+			covered = region
+			found = true
+			continue
+		}
+		covered = model.PdfRectangle{
+			Llx: math.Min(covered.Llx, region.Llx), Lly: math.Min(covered.Lly, region.Lly),
+			Urx: math.Max(covered.Urx, region.Urx), Ury: math.Max(covered.Ury, region.Ury),
+		}
+	}
+	return covered
+}
+
+// showXObject redacts the XObject painting operation `op`.
+func (c *contentRedactor) showXObject(op *contentstream.ContentStreamOperation) error {
+	if len(op.Params) != 1 || c.resources.resources == nil {
+		c.emit(op)
+		return nil
+	}
+	name, ok := core.GetName(op.Params[0])
+	if !ok {
+		c.emit(op)
+		return nil
+	}
+
+	stream, xtype := c.resources.resources.GetXObjectByName(*name)
+	switch xtype {
+	case model.XObjectTypeImage:
+		return c.showImage(op, *name, stream)
+	case model.XObjectTypeForm:
+		return c.showForm(op, *name, stream)
+	}
+	c.emit(op)
+	return nil
+}
+
+// showImage redacts the image XObject `stream` named `name` painted by `op`.
+func (c *contentRedactor) showImage(op *contentstream.ContentStreamOperation, name core.PdfObjectName,
+	stream *core.PdfObjectStream) error {
+	llx, lly, urx, ury := c.gs.ctm.TransformBBox(0, 0, 1, 1)
+	bbox := model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: ury}
+	if intersectionArea(bbox, c.regionsBBox(bbox)) <= 0 {
+		c.emit(op)
+		return nil
+	}
+
+	if !c.r.covers(bbox) {
+		redacted, err := c.r.redactImage(stream, c.gs.ctm)
+		if err != nil {
+			return err
+		}
+		if redacted == stream {
+			c.emit(op)
+			return nil
+		}
+		if redacted != nil {
+			name, err := c.resources.addXObject(redacted)
+			if err != nil {
+				return err
+			}
+			c.emit(&contentstream.ContentStreamOperation{Operand: "Do", Params: []core.PdfObject{&name}})
+		}
+	}
+
+	c.resources.replaceXObject(name)
+	c.changed = true
+	c.r.result.NumImages++
+	return nil
+}
+
+// showForm redacts the form XObject `stream` named `name` painted by `op`.
+func (c *contentRedactor) showForm(op *contentstream.ContentStreamOperation, name core.PdfObjectName,
+	stream *core.PdfObjectStream) error {
+	xform, err := model.NewXObjectFormFromStream(stream)
+	if err != nil {
+		return err
+	}
+	ctm := c.gs.ctm.Mult(contentutil.FormMatrix(xform))
+	if arr, ok := core.GetArray(xform.BBox); ok {
+		if bbox, err := model.NewPdfRectangle(*arr); err == nil {
+			llx, lly, urx, ury := ctm.TransformBBox(bbox.Llx, bbox.Lly, bbox.Urx, bbox.Ury)
+			if !c.r.overlaps(model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: ury}) {
+				c.emit(op)
+				c.useFormXObjects(xform, c.depth+1)
+				return nil
+			}
+		}
+	}
+	if c.depth >= maxFormDepth {
+		common.Log.Debug("ERROR: form XObjects nested too deeply. Removing %s", op.Params[0])
+		c.resources.replaceXObject(name)
+		c.changed = true
+		return nil
+	}
+
+	content, err := xform.GetContentStream()
+	if err != nil {
+		return err
+	}
+	resources := c.resources
+	if xform.Resources != nil {
+		resources = &resourceEditor{resources: xform.Resources}
+	}
+	// Forms without resources use the resources of the page, which are edited along with the page.
+	gs := c.gs
+	ops, changed, err := c.r.redactContent(string(content), resources, ctm, &gs, c.depth+1)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		c.emit(op)
+		return nil
+	}
+	if resources != c.resources {
+		if err := resources.removeReplaced(); err != nil {
+			return err
+		}
+	}
+
+	redacted, err := core.MakeStream(ops.Bytes(), core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	for _, key := range stream.PdfObjectDictionary.Keys() {
+		switch key {
+		case "Filter", "DecodeParms", "Length", "Metadata", "PieceInfo":
+			continue
+		}
+		redacted.PdfObjectDictionary.Set(key, stream.PdfObjectDictionary.Get(key))
+	}
+	if resources != c.resources && resources.modified {
+		redacted.PdfObjectDictionary.Set("Resources", resources.resources.ToPdfObject())
+	}
+
+	redactedName, err := c.resources.addXObject(redacted)
+	if err != nil {
+		return err
+	}
+	c.emit(&contentstream.ContentStreamOperation{Operand: "Do", Params: []core.PdfObject{&redactedName}})
+	c.resources.replaceXObject(name)
+	c.changed = true
+	return nil
+}
+
+// useFormXObjects records the XObjects painted by the form XObject `xform`, which is kept as is.
+// Only forms without resources of their own paint XObjects of the resources being edited. `depth`
+// is the form XObject nesting depth.
+func (c *contentRedactor) useFormXObjects(xform *model.XObjectForm, depth int) {
+	if xform.Resources != nil || depth > maxFormDepth {
+		return
+	}
+	content, err := xform.GetContentStream()
+	if err == nil {
+		var ops *contentstream.ContentStreamOperations
+		if ops, err = contentstream.NewContentStreamParser(string(content)).Parse(); err == nil {
+			for _, op := range *ops {
+				if op.Operand != "Do" || len(op.Params) != 1 {
+					continue
+				}
+				name, ok := core.GetName(op.Params[0])
+				if !ok {
+					continue
+				}
+				c.resources.useXObject(*name)
+				stream, xtype := c.resources.resources.GetXObjectByName(*name)
+				if xtype != model.XObjectTypeForm {
+					continue
+				}
+				if nested, err := model.NewXObjectFormFromStream(stream); err == nil {
+					c.useFormXObjects(nested, depth+1)
+				}
+			}
+			return
+		}
+	}
+	// Keep all the XObjects if the ones painted by the form are unknown.
+	common.Log.Debug("ERROR: unable to parse form content: %v", err)
+	if dict, ok := core.GetDict(c.resources.resources.XObject); ok {
+		for _, name := range dict.Keys() {
+			c.resources.useXObject(name)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+// Package redactor provides functionality for applying redactions to PDF pages.
+// Applying a redaction permanently removes the content covered by the redaction annotations
+// of a page (text glyphs, image pixels and vector graphics) from its content streams, along
+// with the underlying annotations, and paints the overlay appearance of the redactions.
+// Redaction annotations can also be created for the matches of regular expressions in the
+// text of a document, e.g. for redacting all the email addresses in a document.
+package redactor
@@ -0,0 +1,112 @@
+package redactor
+
+import (
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// redactImage returns a copy of the image XObject `stream`, painted with the current
+// transformation matrix `ctm`, with the pixels inside the redaction regions cleared.
+// The soft mask of the image is redacted in the same way.
+// Returns `stream` if no pixels are inside the regions and nil if the image cannot be decoded,
+// in which case the whole image has to be removed.
+func (r *redactor) redactImage(stream *core.PdfObjectStream, ctm transform.Matrix) (*core.PdfObjectStream, error) {
+	dict := stream.PdfObjectDictionary
+	width, okW := core.GetIntVal(dict.Get("Width"))
+	height, okH := core.GetIntVal(dict.Get("Height"))
+	if !okW || !okH || width <= 0 || height <= 0 {
+		common.Log.Debug("ERROR: invalid image dimensions")
+		return nil, nil
+	}
+
+	bpc, components := 1, 1
+	if isMask, _ := core.GetBoolVal(dict.Get("ImageMask")); !isMask {
+		var ok bool
+		bpc, ok = core.GetIntVal(dict.Get("BitsPerComponent"))
+		if !ok {
+			common.Log.Debug("ERROR: missing image BitsPerComponent")
+			return nil, nil
+		}
+		cs, err := model.NewPdfColorspaceFromPdfObject(dict.Get("ColorSpace"))
+		if err != nil {
+			common.Log.Debug("ERROR: unsupported image colorspace: %v", err)
+			return nil, nil
+		}
+		components = cs.GetNumComponents()
+	}
+
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		common.Log.Debug("ERROR: unable to decode image: %v", err)
+		return nil, nil
+	}
+	// The data of unencoded streams are not copied when decoded, and the original image may be
+	// painted elsewhere.
+	data = append([]byte(nil), data...)
+	pixelBits := bpc * components
+	rowBytes := (width*pixelBits + 7) / 8
+	if len(data) < rowBytes*height {
+		common.Log.Debug("ERROR: image data too short (%d < %d)", len(data), rowBytes*height)
+		return nil, nil
+	}
+
+	// The image space maps to the unit square, with the first row at the top.
+	cleared := false
+	for j := 0; j < height; j++ {
+		row := data[j*rowBytes : (j+1)*rowBytes]
+		v := 1 - (float64(j)+0.5)/float64(height)
+		for i := 0; i < width; i++ {
+			u := (float64(i) + 0.5) / float64(width)
+			if x, y := ctm.TransformPoint(u, v); r.contains(x, y) {
+				clearBits(row, i*pixelBits, pixelBits)
+				cleared = true
+			}
+		}
+	}
+
+	smask, hasSMask := core.GetStream(dict.Get("SMask"))
+	var redactedSMask *core.PdfObjectStream
+	if hasSMask {
+		redactedSMask, err = r.redactImage(smask, ctm)
+		if err != nil {
+			return nil, err
+		}
+		if redactedSMask == nil {
+			return nil, nil
+		}
+	}
+	if !cleared && redactedSMask == smask {
+		return stream, nil
+	}
+
+	redacted, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range dict.Keys() {
+		switch key {
+		case "Filter", "DecodeParms", "Length", "Metadata", "SMask":
+			continue
+		}
+		redacted.PdfObjectDictionary.Set(key, dict.Get(key))
+	}
+	if hasSMask {
+		redacted.PdfObjectDictionary.Set("SMask", redactedSMask)
+	}
+	return redacted, nil
+}
+
+// clearBits clears the `n` bits of `data` starting at bit `start`.
+func clearBits(data []byte, start, n int) {
+	for bit := start; bit < start+n; {
+		if bit%8 == 0 && start+n-bit >= 8 {
+			data[bit/8] = 0
+			bit += 8
+			continue
+		}
+		data[bit/8] &^= 0x80 >> uint(bit%8)
+		bit++
+	}
+}
@@ -0,0 +1,211 @@
+package redactor
+
+import (
+	"math"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// defaultOverlayFontSize is the maximum font size of overlay text when the default appearance
+// of a redaction annotation does not specify one.
+const defaultOverlayFontSize = 10
+
+// overlay returns a content stream painting the overlay appearances of the redaction annotations
+// `redacts`. Resources needed by the overlays are added to `resources`.
+// The overlay of an annotation is its RO form XObject if present. Otherwise, the redacted regions
+// are filled with its interior color IC and its OverlayText is drawn inside its Rect.
+func (r *redactor) overlay(redacts []*model.PdfAnnotationRedact, resources *resourceEditor) (string, error) {
+	cc := contentstream.NewContentCreator()
+	var fontName core.PdfObjectName
+	var font *model.PdfFont
+
+	for _, redact := range redacts {
+		rect := annotationRect(redact.PdfAnnotation)
+
+		if stream, ok := core.GetStream(redact.RO); ok {
+			if rect == nil {
+				continue
+			}
+			xform, err := model.NewXObjectFormFromStream(stream)
+			if err != nil {
+				return "", err
+			}
+			arr, ok := core.GetArray(xform.BBox)
+			if !ok {
+				continue
+			}
+			bbox, err := model.NewPdfRectangle(*arr)
+			if err != nil || bbox.Width() == 0 || bbox.Height() == 0 {
+				common.Log.Debug("ERROR: invalid overlay BBox")
+				continue
+			}
+			*bbox = normalizeRect(*bbox)
+			name, err := resources.addXObject(stream)
+			if err != nil {
+				return "", err
+			}
+			sx := rect.Width() / bbox.Width()
+			sy := rect.Height() / bbox.Height()
+			cc.Add_q().
+				Add_cm(sx, 0, 0, sy, rect.Llx-bbox.Llx*sx, rect.Lly-bbox.Lly*sy).
+				Add_Do(name).
+				Add_Q()
+			continue
+		}
+
+		if arr, ok := core.GetArray(redact.IC); ok {
+			color, err := arr.ToFloat64Array()
+			if err != nil {
+				common.Log.Debug("ERROR: invalid redaction IC: %v", err)
+			}
+			if addFillColor(cc, color) {
+				for _, region := range redactRegions(redact) {
+					cc.Add_re(region.Llx, region.Lly, region.Urx-region.Llx, region.Ury-region.Lly)
+				}
+				cc.Add_f().Add_Q()
+			}
+		}
+
+		text, ok := core.GetString(redact.OverlayText)
+		if !ok || text.Decoded() == "" || rect == nil {
+			continue
+		}
+		if font == nil {
+			var err error
+			font, err = model.NewStandard14Font(model.HelveticaName)
+			if err != nil {
+				return "", err
+			}
+			fontName, err = resources.addFont(font.ToPdfObject())
+			if err != nil {
+				return "", err
+			}
+		}
+		repeat, _ := core.GetBoolVal(redact.Repeat)
+		align, _ := core.GetIntVal(redact.Q)
+		addOverlayText(cc, font, fontName, text.Decoded(), *rect, redact.DA, align, repeat)
+	}
+
+	if len(*cc.Operations()) == 0 {
+		return "", nil
+	}
+	return cc.String(), nil
+}
+
+// addFillColor adds a q operation followed by the setting of the nonstroking color with
+// components `color` to `cc`. Returns false if `color` is not a valid DeviceGray, DeviceRGB or
+// DeviceCMYK color, in which case nothing is added.
+func addFillColor(cc *contentstream.ContentCreator, color []float64) bool {
+	switch len(color) {
+	case 1:
+		cc.Add_q().Add_g(color[0])
+	case 3:
+		cc.Add_q().Add_rg(color[0], color[1], color[2])
+	case 4:
+		cc.Add_q().Add_k(color[0], color[1], color[2], color[3])
+	default:
+		return false
+	}
+	return true
+}
+
+// addOverlayText adds the drawing of the overlay text `text` inside `rect` to `cc`, with the
+// font size and color specified by the default appearance string `da`, if any, and horizontal
+// alignment `align` (0: left, 1: centered, 2: right). If `repeat` is true, the text is repeated
+// to fill `rect`.
+func addOverlayText(cc *contentstream.ContentCreator, font *model.PdfFont, fontName core.PdfObjectName,
+	text string, rect model.PdfRectangle, da core.PdfObject, align int, repeat bool) {
+	fontSize, color := parseDefaultAppearance(da)
+
+	textWidth := func(s string, size float64) float64 {
+		w := 0.0
+		for _, r := range s {
+			if m, ok := font.GetRuneMetrics(r); ok {
+				w += m.Wx
+			}
+		}
+		return w * size / 1000
+	}
+
+	if fontSize <= 0 {
+		// Auto size: fit the text in the rectangle.
+		fontSize = math.Min(defaultOverlayFontSize, rect.Height()*0.8)
+		if w := textWidth(text, fontSize); w > rect.Width() && w > 0 {
+			fontSize *= rect.Width() / w
+		}
+	}
+	if fontSize <= 0 {
+		return
+	}
+
+	lines := []string{text}
+	if repeat {
+		line := text
+		for textWidth(line+" "+text, fontSize) <= rect.Width() {
+			line += " " + text
+		}
+		numLines := int(rect.Height() / (fontSize * 1.2))
+		lines = nil
+		for i := 0; i < numLines; i++ {
+			lines = append(lines, line)
+		}
+	}
+
+	cc.Add_q()
+	cc.Add_re(rect.Llx, rect.Lly, rect.Width(), rect.Height()).Add_W().Add_n()
+	if !addFillColor(cc, color) {
+		cc.Add_q().Add_g(0)
+	}
+	cc.Add_BT().Add_Tf(fontName, fontSize)
+
+	lineHeight := fontSize * 1.2
+	top := rect.Lly + (rect.Height()+lineHeight*float64(len(lines)))/2
+	for i, line := range lines {
+		x := rect.Llx
+		switch align {
+		case 1:
+			x += (rect.Width() - textWidth(line, fontSize)) / 2
+		case 2:
+			x += rect.Width() - textWidth(line, fontSize)
+		}
+		y := top - lineHeight*float64(i+1) + (lineHeight-fontSize)/2 + 0.2*fontSize
+
+		encoded, _ := font.StringToCharcodeBytes(line)
+		cc.Add_Tm(1, 0, 0, 1, x, y)
+		cc.Add_Tj(*core.MakeStringFromBytes(encoded))
+	}
+	cc.Add_ET().Add_Q().Add_Q()
+}
+
+// parseDefaultAppearance returns the font size and nonstroking color specified by the default
+// appearance string `da`. The font size is 0 if not specified.
+func parseDefaultAppearance(da core.PdfObject) (float64, []float64) {
+	str, ok := core.GetString(da)
+	if !ok {
+		return 0, nil
+	}
+	ops, err := contentstream.NewContentStreamParser(str.Str()).Parse()
+	if err != nil {
+		common.Log.Debug("ERROR: invalid default appearance: %v", err)
+		return 0, nil
+	}
+
+	var fontSize float64
+	var color []float64
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tf":
+			if len(op.Params) == 2 {
+				fontSize, _ = core.GetNumberAsFloat(op.Params[1])
+			}
+		case "g", "rg", "k":
+			if vals, err := core.GetNumbersAsFloat(op.Params); err == nil {
+				color = vals
+			}
+		}
+	}
+	return fontSize, color
+}
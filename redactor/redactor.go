@@ -0,0 +1,246 @@
+package redactor
+
+import (
+	"errors"
+	"math"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// PageRedactions contains information about the content removed from a page by ApplyRedactions.
+type PageRedactions struct {
+	// Regions are the redacted areas of the page, in page coordinates.
+	Regions []model.PdfRectangle
+
+	// RemovedText contains the text of the removed glyphs, one entry per text showing operation.
+	RemovedText []string
+
+	// NumGlyphs, NumPaths and NumImages are the number of removed glyphs, the number of removed
+	// or clipped subpaths and the number of removed or modified images respectively.
+	NumGlyphs int
+	NumPaths  int
+	NumImages int
+
+	// RemovedAnnotations contains the annotations which were removed from the page as they
+	// overlapped the redacted areas. The redaction annotations themselves are not included.
+	// Widget annotations in this list are still referenced by the AcroForm fields of the document.
+	RemovedAnnotations []*model.PdfAnnotation
+}
+
+// ApplyRedactions applies the redaction annotations of `page`. The text glyphs, image pixels and
+// vector paths covered by the redaction annotations are removed from the page contents, as are
+// the annotations that overlap the redacted areas and the page metadata. The overlay appearance
+// of each redaction annotation is painted over the redacted area and the redaction annotations
+// are removed from the page.
+// The redacted areas are specified by the QuadPoints of the annotations or, if the QuadPoints
+// are missing, by their Rect.
+// Redacted images and form XObjects are replaced by redacted copies in the page resources. The
+// originals are removed from the resources unless they are still painted elsewhere on the page.
+// Returns information about the removed content. Nothing is changed if the page contains no
+// redaction annotations.
+func ApplyRedactions(page *model.PdfPage) (*PageRedactions, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+
+	annotations, err := page.GetAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PageRedactions{}
+	var redacts []*model.PdfAnnotationRedact
+	for _, annotation := range annotations {
+		redact, ok := annotation.GetContext().(*model.PdfAnnotationRedact)
+		if !ok {
+			continue
+		}
+		redacts = append(redacts, redact)
+		result.Regions = append(result.Regions, redactRegions(redact)...)
+	}
+	if len(redacts) == 0 {
+		return result, nil
+	}
+
+	r := &redactor{
+		regions: result.Regions,
+		result:  result,
+		fonts:   contentutil.FontCache{},
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+	resources := &resourceEditor{resources: page.Resources}
+	redacted, _, err := r.redactContent(contents, resources, transform.IdentityMatrix(), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay, err := r.overlay(redacts, resources)
+	if err != nil {
+		return nil, err
+	}
+	if err := page.SetContentStreams([]string{redacted.String() + overlay}, core.NewFlateEncoder()); err != nil {
+		return nil, err
+	}
+	if err := resources.removeReplaced(); err != nil {
+		return nil, err
+	}
+	if resources.modified {
+		page.Resources = resources.resources
+	}
+
+	page.SetAnnotations(r.redactAnnotations(annotations))
+	page.Metadata = nil
+	page.PieceInfo = nil
+	page.Thumb = nil
+
+	return result, nil
+}
+
+// redactor applies a set of redaction regions to the contents of a page.
+type redactor struct {
+	regions []model.PdfRectangle
+	result  *PageRedactions
+
+	// Cache of the fonts loaded from the font objects of the page resources.
+	fonts contentutil.FontCache
+}
+
+// redactRegions returns the areas redacted by `redact`.
+func redactRegions(redact *model.PdfAnnotationRedact) []model.PdfRectangle {
+	var regions []model.PdfRectangle
+	if arr, ok := core.GetArray(redact.QuadPoints); ok {
+		points, err := arr.ToFloat64Array()
+		if err != nil {
+			common.Log.Debug("ERROR: invalid redaction QuadPoints: %v", err)
+		}
+		for i := 0; i+8 <= len(points); i += 8 {
+			region := model.PdfRectangle{
+				Llx: points[i], Lly: points[i+1],
+				Urx: points[i], Ury: points[i+1],
+			}
+			for j := i + 2; j < i+8; j += 2 {
+				region.Llx = math.Min(region.Llx, points[j])
+				region.Lly = math.Min(region.Lly, points[j+1])
+				region.Urx = math.Max(region.Urx, points[j])
+				region.Ury = math.Max(region.Ury, points[j+1])
+			}
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) > 0 {
+		return regions
+	}
+
+	if rect := annotationRect(redact.PdfAnnotation); rect != nil {
+		regions = append(regions, *rect)
+	}
+	return regions
+}
+
+// annotationRect returns the normalized Rect of `annotation` or nil if it is missing or invalid.
+func annotationRect(annotation *model.PdfAnnotation) *model.PdfRectangle {
+	arr, ok := core.GetArray(annotation.Rect)
+	if !ok {
+		return nil
+	}
+	rect, err := model.NewPdfRectangle(*arr)
+	if err != nil {
+		common.Log.Debug("ERROR: invalid annotation Rect: %v", err)
+		return nil
+	}
+	norm := normalizeRect(*rect)
+	return &norm
+}
+
+// overlaps returns true if `bbox` overlaps any of the redaction regions.
+func (r *redactor) overlaps(bbox model.PdfRectangle) bool {
+	for _, region := range r.regions {
+		if rectsOverlap(region, bbox) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the point `x`,`y` is inside any of the redaction regions.
+func (r *redactor) contains(x, y float64) bool {
+	for _, region := range r.regions {
+		if rectContains(region, x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns true if `bbox` is entirely inside one of the redaction regions.
+func (r *redactor) covers(bbox model.PdfRectangle) bool {
+	for _, region := range r.regions {
+		if rectContainsRect(region, bbox) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAnnotations returns the annotations from `annotations` that remain on the page after the
+// redaction, i.e. all annotations except the redaction annotations, the annotations overlapping
+// the redacted areas and the popups of removed annotations.
+func (r *redactor) redactAnnotations(annotations []*model.PdfAnnotation) []*model.PdfAnnotation {
+	removed := map[core.PdfObject]struct{}{}
+	for _, annotation := range annotations {
+		switch annotation.GetContext().(type) {
+		case *model.PdfAnnotationRedact:
+			removed[annotation.GetContainingPdfObject()] = struct{}{}
+		case *model.PdfAnnotationPopup:
+			// Popups are removed along with their parent annotation.
+		default:
+			if rect := annotationRect(annotation); rect != nil && r.overlaps(*rect) {
+				removed[annotation.GetContainingPdfObject()] = struct{}{}
+				r.result.RemovedAnnotations = append(r.result.RemovedAnnotations, annotation)
+			}
+		}
+	}
+
+	var remaining []*model.PdfAnnotation
+	for _, annotation := range annotations {
+		if _, ok := removed[annotation.GetContainingPdfObject()]; ok {
+			continue
+		}
+		if popup, ok := annotation.GetContext().(*model.PdfAnnotationPopup); ok && isRemovedObject(popup.Parent, removed) {
+			r.result.RemovedAnnotations = append(r.result.RemovedAnnotations, annotation)
+			continue
+		}
+		remaining = append(remaining, annotation)
+	}
+	return remaining
+}
+
+// isRemovedObject returns true if `obj` is, or refers to, one of the objects in `removed`.
+func isRemovedObject(obj core.PdfObject, removed map[core.PdfObject]struct{}) bool {
+	if obj == nil {
+		return false
+	}
+	if _, ok := removed[obj]; ok {
+		return true
+	}
+	ref, ok := obj.(*core.PdfObjectReference)
+	if !ok {
+		return false
+	}
+	for removedObj := range removed {
+		ind, ok := removedObj.(*core.PdfIndirectObject)
+		if ok && ind.ObjectNumber != 0 && ind.ObjectNumber == ref.ObjectNumber &&
+			ind.GenerationNumber == ref.GenerationNumber {
+			return true
+		}
+	}
+	return false
+}
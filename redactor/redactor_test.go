@@ -0,0 +1,297 @@
+package redactor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/extractor"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// textWidth returns the width of `text` drawn with `font` at size `fontSize`.
+func textWidth(t *testing.T, font *model.PdfFont, text string, fontSize float64) float64 {
+	w := 0.0
+	for _, r := range text {
+		m, ok := font.GetRuneMetrics(r)
+		require.True(t, ok)
+		w += m.Wx
+	}
+	return w * fontSize / 1000
+}
+
+// makeTestPage returns a page with text, an image and a rectangle, along with the font used
+// for the text.
+func makeTestPage(t *testing.T) (*model.PdfPage, *model.PdfFont) {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte{0xff}, 10*10)
+	img, err := core.MakeStream(data, core.NewFlateEncoder())
+	require.NoError(t, err)
+	img.Set("Type", core.MakeName("XObject"))
+	img.Set("Subtype", core.MakeName("Image"))
+	img.Set("Width", core.MakeInteger(10))
+	img.Set("Height", core.MakeInteger(10))
+	img.Set("ColorSpace", core.MakeName("DeviceGray"))
+	img.Set("BitsPerComponent", core.MakeInteger(8))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	require.NoError(t, page.Resources.SetXObjectByName("Im1", img))
+
+	content := strings.Join([]string{
+		"BT /F1 12 Tf 50 700 Td (Public SECRET text) Tj ET",
+		"q 100 0 0 100 300 300 cm /Im1 Do Q",
+		"0 0 1 rg 50 100 50 50 re f",
+		"0 1 0 rg 200 100 50 50 re f",
+	}, "\n")
+	require.NoError(t, page.SetContentStreams([]string{content}, nil))
+	return page, font
+}
+
+func TestApplyRedactions(t *testing.T) {
+	page, font := makeTestPage(t)
+
+	// Regions covering the word SECRET, the left half of the image and the blue rectangle.
+	x0 := 50 + textWidth(t, font, "Public ", 12)
+	x1 := x0 + textWidth(t, font, "SECRET", 12)
+	regions := []model.PdfRectangle{
+		{Llx: x0, Lly: 697, Urx: x1, Ury: 710},
+		{Llx: 290, Lly: 290, Urx: 350, Ury: 410},
+		{Llx: 40, Lly: 90, Urx: 110, Ury: 160},
+	}
+	var quads []float64
+	for _, r := range regions {
+		quads = append(quads, r.Llx, r.Ury, r.Urx, r.Ury, r.Llx, r.Lly, r.Urx, r.Lly)
+	}
+	redact := model.NewPdfAnnotationRedact()
+	redact.Rect = core.MakeArrayFromFloats([]float64{40, 90, 350, 710})
+	redact.QuadPoints = core.MakeArrayFromFloats(quads)
+	redact.IC = core.MakeArrayFromFloats([]float64{0, 0, 0})
+	page.AddAnnotation(redact.PdfAnnotation)
+
+	covered := model.NewPdfAnnotationSquare()
+	covered.Rect = core.MakeArrayFromFloats([]float64{60, 110, 90, 140})
+	page.AddAnnotation(covered.PdfAnnotation)
+	uncovered := model.NewPdfAnnotationSquare()
+	uncovered.Rect = core.MakeArrayFromFloats([]float64{400, 500, 450, 550})
+	page.AddAnnotation(uncovered.PdfAnnotation)
+
+	result, err := ApplyRedactions(page)
+	require.NoError(t, err)
+	require.Equal(t, regions, result.Regions)
+	require.Equal(t, []string{"SECRET"}, result.RemovedText)
+	require.Equal(t, 6, result.NumGlyphs)
+	require.Equal(t, 1, result.NumPaths)
+	require.Equal(t, 1, result.NumImages)
+	require.Len(t, result.RemovedAnnotations, 1)
+	require.Equal(t, covered.PdfAnnotation, result.RemovedAnnotations[0])
+
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Equal(t, []*model.PdfAnnotation{uncovered.PdfAnnotation}, annotations)
+
+	// The redacted page is written and read back to check that the output is valid.
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+
+	e, err := extractor.New(page)
+	require.NoError(t, err)
+	text, err := e.ExtractText()
+	require.NoError(t, err)
+	require.Contains(t, text, "Public")
+	require.Contains(t, text, "text")
+	require.NotContains(t, text, "SECRET")
+
+	// The remaining text keeps its position.
+	pageText, _, _, err := e.ExtractPageText()
+	require.NoError(t, err)
+	found := false
+	for _, mark := range pageText.Marks().Elements() {
+		if mark.Text == "t" && mark.BBox.Llx > x1 {
+			require.InDelta(t, x1+textWidth(t, font, " ", 12), mark.BBox.Llx, 0.1)
+			found = true
+			break
+		}
+	}
+	require.True(t, found)
+
+	contents, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.NotContains(t, contents, "50 100 50 50 re")
+	require.Contains(t, contents, "200 100 50 50 re")
+
+	// The left half of the image is cleared.
+	images, err := e.ExtractPageImages(nil)
+	require.NoError(t, err)
+	require.Len(t, images.Images, 1)
+	img := images.Images[0].Image
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			expected := byte(0xff)
+			if x < 5 {
+				expected = 0
+			}
+			for c := 0; c < img.ColorComponents; c++ {
+				i := (y*10+x)*img.ColorComponents + c
+				require.Equal(t, expected, img.Data[i], fmt.Sprintf("x=%d y=%d", x, y))
+			}
+		}
+	}
+}
+
+func TestApplyRedactionsTJ(t *testing.T) {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	content := "BT /F1 10 Tf 2 Tc 12 TL 100 600 Td [(AB) -500 (CD)] TJ (EF) ' ET"
+	require.NoError(t, page.SetContentStreams([]string{content}, nil))
+
+	// Redact "B" and "C".
+	wA := textWidth(t, font, "A", 10) + 2
+	wB := textWidth(t, font, "B", 10) + 2
+	x0 := 100 + wA + 0.5
+	x1 := 100 + wA + wB + 5 + textWidth(t, font, "C", 10) - 0.5
+
+	redact := model.NewPdfAnnotationRedact()
+	redact.Rect = core.MakeArrayFromFloats([]float64{x0, 598, x1, 610})
+	page.AddAnnotation(redact.PdfAnnotation)
+
+	result, err := ApplyRedactions(page)
+	require.NoError(t, err)
+	require.Equal(t, []string{"BC"}, result.RemovedText)
+
+	e, err := extractor.New(page)
+	require.NoError(t, err)
+	pageText, _, _, err := e.ExtractPageText()
+	require.NoError(t, err)
+
+	var text string
+	for _, mark := range pageText.Marks().Elements() {
+		text += mark.Text
+		if mark.Text == "D" {
+			// D keeps its position.
+			expected := x1 + 0.5 + 2
+			require.InDelta(t, expected, mark.BBox.Llx, 0.01)
+		}
+	}
+	require.Equal(t, "ADEF", strings.Join(strings.Fields(text), ""))
+}
+
+// makeXObjectTestPage returns a page with a 10x10 white image Im1, drawn at 300,300 with size 100,
+// and the form XObjects `forms`, all stored unencoded so that their data can be found in the
+// written file.
+func makeXObjectTestPage(t *testing.T, content string, forms map[core.PdfObjectName]*core.PdfObjectStream) *model.PdfPage {
+	img, err := core.MakeStream(bytes.Repeat([]byte{0xff}, 10*10), nil)
+	require.NoError(t, err)
+	img.Set("Type", core.MakeName("XObject"))
+	img.Set("Subtype", core.MakeName("Image"))
+	img.Set("Width", core.MakeInteger(10))
+	img.Set("Height", core.MakeInteger(10))
+	img.Set("ColorSpace", core.MakeName("DeviceGray"))
+	img.Set("BitsPerComponent", core.MakeInteger(8))
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+	require.NoError(t, page.Resources.SetXObjectByName("Im1", img))
+	for name, form := range forms {
+		require.NoError(t, page.Resources.SetXObjectByName(name, form))
+	}
+	require.NoError(t, page.SetContentStreams([]string{content}, nil))
+	return page
+}
+
+// makeTestForm returns an unencoded form XObject with content `content` and resources `resources`.
+func makeTestForm(t *testing.T, content string, resources core.PdfObject) *core.PdfObjectStream {
+	form, err := core.MakeStream([]byte(content), nil)
+	require.NoError(t, err)
+	form.Set("Type", core.MakeName("XObject"))
+	form.Set("Subtype", core.MakeName("Form"))
+	form.Set("BBox", core.MakeArrayFromFloats([]float64{0, 0, 200, 100}))
+	if resources != nil {
+		form.Set("Resources", resources)
+	}
+	return form
+}
+
+// redactAndWrite redacts `rects` of `page` and returns the written document, along with the names
+// of the XObjects of the page read back from it.
+func redactAndWrite(t *testing.T, page *model.PdfPage, rects ...[]float64) ([]byte, []core.PdfObjectName) {
+	for _, rect := range rects {
+		redact := model.NewPdfAnnotationRedact()
+		redact.Rect = core.MakeArrayFromFloats(rect)
+		page.AddAnnotation(redact.PdfAnnotation)
+	}
+	_, err := ApplyRedactions(page)
+	require.NoError(t, err)
+
+	writer := model.NewPdfWriter()
+	require.NoError(t, writer.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = reader.GetPage(1)
+	require.NoError(t, err)
+	xobjects, ok := core.GetDict(page.Resources.XObject)
+	require.True(t, ok)
+	return buf.Bytes(), xobjects.Keys()
+}
+
+func TestApplyRedactionsXObjectsRemoved(t *testing.T) {
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	require.NoError(t, err)
+	fonts := core.MakeDict()
+	fonts.Set("F1", font.ToPdfObject())
+	formResources := core.MakeDict()
+	formResources.Set("Font", fonts)
+	form := makeTestForm(t, "BT /F1 12 Tf 0 10 Td (FORMSECRET) Tj ET", formResources)
+
+	// The form without resources uses the font of the page.
+	inherited := makeTestForm(t, "BT /F1 12 Tf 0 10 Td (PAGESECRET) Tj ET", nil)
+
+	page := makeXObjectTestPage(t, "q 100 0 0 100 300 300 cm /Im1 Do Q\nq 1 0 0 1 50 500 cm /Fm1 Do Q\n"+
+		"q 1 0 0 1 50 600 cm /Fm2 Do Q",
+		map[core.PdfObjectName]*core.PdfObjectStream{"Fm1": form, "Fm2": inherited})
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	data, names := redactAndWrite(t, page, []float64{290, 290, 350, 410}, []float64{40, 500, 200, 530},
+		[]float64{40, 600, 200, 630})
+
+	// Only the redacted copies are written.
+	require.NotContains(t, string(data), "FORMSECRET")
+	require.NotContains(t, string(data), "PAGESECRET")
+	require.False(t, bytes.Contains(data, bytes.Repeat([]byte{0xff}, 10*10)))
+	require.Len(t, names, 3)
+	for _, name := range []core.PdfObjectName{"Im1", "Fm1", "Fm2"} {
+		require.NotContains(t, names, name)
+	}
+}
+
+func TestApplyRedactionsXObjectsKept(t *testing.T) {
+	// The form without resources paints Im1 outside the redacted region.
+	form := makeTestForm(t, "q 100 0 0 100 0 0 cm /Im1 Do Q", nil)
+	page := makeXObjectTestPage(t, "q 100 0 0 100 300 300 cm /Im1 Do Q\nq 1 0 0 1 50 500 cm /Fm2 Do Q",
+		map[core.PdfObjectName]*core.PdfObjectStream{"Fm2": form})
+	data, names := redactAndWrite(t, page, []float64{290, 290, 350, 410})
+
+	require.True(t, bytes.Contains(data, bytes.Repeat([]byte{0xff}, 10*10)))
+	require.Len(t, names, 3)
+	require.Contains(t, names, core.PdfObjectName("Im1"))
+	require.Contains(t, names, core.PdfObjectName("Fm2"))
+}
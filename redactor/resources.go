@@ -0,0 +1,123 @@
+package redactor
+
+import (
+	"fmt"
+
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// resourceEditor adds resources to a resource dictionary without modifying the original, which
+// may be shared with other pages or forms. The resources are copied on the first modification.
+// The XObjects replaced by redacted copies are removed once no content uses them, so that the
+// unredacted originals are not written.
+type resourceEditor struct {
+	resources *model.PdfPageResources
+	modified  bool
+
+	used     map[core.PdfObjectName]struct{} // XObjects painted by the redacted content.
+	replaced map[core.PdfObjectName]struct{} // XObjects replaced or removed by the redaction.
+}
+
+// useXObject records that the XObject `name` is painted by the redacted content.
+func (e *resourceEditor) useXObject(name core.PdfObjectName) {
+	if e.used == nil {
+		e.used = map[core.PdfObjectName]struct{}{}
+	}
+	e.used[name] = struct{}{}
+}
+
+// replaceXObject records that the XObject `name` is no longer painted where it was redacted.
+func (e *resourceEditor) replaceXObject(name core.PdfObjectName) {
+	if e.replaced == nil {
+		e.replaced = map[core.PdfObjectName]struct{}{}
+	}
+	e.replaced[name] = struct{}{}
+}
+
+// removeReplaced removes the replaced XObjects that are not painted by the redacted content from
+// the resources.
+func (e *resourceEditor) removeReplaced() error {
+	for name := range e.replaced {
+		if _, ok := e.used[name]; ok {
+			continue
+		}
+		if err := e.copyResources(); err != nil {
+			return err
+		}
+		if dict, ok := core.GetDict(e.resources.XObject); ok {
+			dict.Remove(name)
+		}
+	}
+	e.replaced = nil
+	return nil
+}
+
+// copyResources makes a copy of the resources being edited, if not already done.
+func (e *resourceEditor) copyResources() error {
+	if e.modified {
+		return nil
+	}
+	e.modified = true
+
+	if e.resources == nil {
+		e.resources = model.NewPdfPageResources()
+		return nil
+	}
+	dict, ok := core.GetDict(e.resources.ToPdfObject())
+	if !ok {
+		return fmt.Errorf("invalid resources: %T", e.resources.ToPdfObject())
+	}
+	resources, err := model.NewPdfPageResourcesFromDict(core.MakeDict().Merge(dict))
+	if err != nil {
+		return err
+	}
+	resources.XObject = copyDict(resources.XObject)
+	resources.Font = copyDict(resources.Font)
+	e.resources = resources
+	return nil
+}
+
+// addXObject adds the XObject `stream` to the resources and returns its name.
+func (e *resourceEditor) addXObject(stream *core.PdfObjectStream) (core.PdfObjectName, error) {
+	if err := e.copyResources(); err != nil {
+		return "", err
+	}
+	name := e.resources.GenerateXObjectName()
+	if err := e.resources.SetXObjectByName(name, stream); err != nil {
+		return "", err
+	}
+	e.useXObject(name)
+	return name, nil
+}
+
+// addFont adds the font object `font` to the resources and returns its name.
+func (e *resourceEditor) addFont(font core.PdfObject) (core.PdfObjectName, error) {
+	if err := e.copyResources(); err != nil {
+		return "", err
+	}
+	num := 1
+	name := core.PdfObjectName(fmt.Sprintf("RedactFont%d", num))
+	for e.resources.HasFontByName(name) {
+		num++
+		name = core.PdfObjectName(fmt.Sprintf("RedactFont%d", num))
+	}
+	if err := e.resources.SetFontByName(name, font); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// copyDict returns a shallow copy of the dictionary `obj`, or nil if `obj` is not a dictionary.
+func copyDict(obj core.PdfObject) core.PdfObject {
+	if obj == nil {
+		return nil
+	}
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: resource not a dictionary (%T)", obj)
+		return nil
+	}
+	return core.MakeDict().Merge(dict)
+}
@@ -0,0 +1,244 @@
+package redactor
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/extractor"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// SearchOptions contains options for searching and redacting text with SearchAndRedact.
+type SearchOptions struct {
+	// FirstPage and LastPage specify the (1-based, inclusive) range of pages to search.
+	// Zero values default to the first and last page of the document.
+	FirstPage int
+	LastPage  int
+
+	// Apply enables applying the redaction annotations created for the matches, which removes
+	// the matched text from the pages. If false, the annotations are only added to the pages,
+	// e.g. for reviewing them before applying the redactions with ApplyRedactions.
+	Apply bool
+
+	// FillColor is the interior color (IC) of the created redaction annotations, as DeviceRGB
+	// components. Defaults to black.
+	FillColor []float64
+
+	// OverlayText is the overlay text of the created redaction annotations, if any.
+	OverlayText string
+}
+
+// SearchReport is the report of the redactions made by SearchAndRedact.
+type SearchReport struct {
+	// Pages contains the reports of the pages with matches, in page order.
+	Pages []*PageSearchReport
+}
+
+// NumMatches returns the total number of matches in `report`.
+func (report *SearchReport) NumMatches() int {
+	n := 0
+	for _, page := range report.Pages {
+		n += len(page.Matches)
+	}
+	return n
+}
+
+// PageSearchReport is the report of the redactions made on a page by SearchAndRedact.
+type PageSearchReport struct {
+	// PageNum is the (1-based) number of the page.
+	PageNum int
+
+	// Matches contains the text matched on the page.
+	Matches []*SearchMatch
+
+	// Redactions contains the content removed from the page. It is nil if the redactions were
+	// not applied.
+	Redactions *PageRedactions
+}
+
+// SearchMatch represents a match of a search pattern in the text of a page.
+type SearchMatch struct {
+	// Pattern is the regular expression that matched.
+	Pattern string
+
+	// Text is the matched text.
+	Text string
+
+	// Regions are the areas of the page covered by the matched text, one per line of text.
+	Regions []model.PdfRectangle
+
+	// Annotation is the redaction annotation created for the match.
+	Annotation *model.PdfAnnotationRedact
+}
+
+// SearchAndRedact searches the text of the pages of `reader` for matches of `patterns` and adds
+// a redaction annotation covering each match to the pages. If `options`.Apply is true, the
+// redactions are then applied with ApplyRedactions.
+// The pages of `reader` are modified in place. The modified pages can be obtained with
+// reader.GetPage and written out with a PdfWriter.
+// Returns a report of the matches and of the content removed from each page. A nil `options` is
+// treated as the default options.
+func SearchAndRedact(reader *model.PdfReader, patterns []*regexp.Regexp, options *SearchOptions) (*SearchReport, error) {
+	if reader == nil {
+		return nil, errors.New("reader not specified")
+	}
+	if options == nil {
+		options = &SearchOptions{}
+	}
+
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	first, last := options.FirstPage, options.LastPage
+	if first <= 0 {
+		first = 1
+	}
+	if last <= 0 || last > numPages {
+		last = numPages
+	}
+	if first > last {
+		return nil, errors.New("invalid page range")
+	}
+
+	report := &SearchReport{}
+	for pageNum := first; pageNum <= last; pageNum++ {
+		page, err := reader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		pageReport, err := SearchAndRedactPage(page, patterns, options)
+		if err != nil {
+			return nil, err
+		}
+		if len(pageReport.Matches) == 0 {
+			continue
+		}
+		pageReport.PageNum = pageNum
+		report.Pages = append(report.Pages, pageReport)
+	}
+	return report, nil
+}
+
+// SearchAndRedactPage is like SearchAndRedact but operates on a single page. The page range of
+// `options` is ignored and the PageNum of the returned report is not set.
+func SearchAndRedactPage(page *model.PdfPage, patterns []*regexp.Regexp, options *SearchOptions) (*PageSearchReport, error) {
+	if page == nil {
+		return nil, errors.New("page not specified")
+	}
+	if options == nil {
+		options = &SearchOptions{}
+	}
+
+	e, err := extractor.New(page)
+	if err != nil {
+		return nil, err
+	}
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		return nil, err
+	}
+	text := pageText.Text()
+	marks := pageText.Marks().Elements()
+
+	report := &PageSearchReport{}
+	for _, pattern := range patterns {
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			regions := matchRegions(marks, loc[0], loc[1])
+			if len(regions) == 0 {
+				continue
+			}
+			match := &SearchMatch{
+				Pattern:    pattern.String(),
+				Text:       text[loc[0]:loc[1]],
+				Regions:    regions,
+				Annotation: newRedactAnnotation(regions, options),
+			}
+			page.AddAnnotation(match.Annotation.PdfAnnotation)
+			report.Matches = append(report.Matches, match)
+		}
+	}
+
+	if options.Apply && len(report.Matches) > 0 {
+		report.Redactions, err = ApplyRedactions(page)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// matchRegions returns the areas covered by the text marks in `marks` with offsets in the range
+// `start` to `end`, one area per line of text.
+func matchRegions(marks []extractor.TextMark, start, end int) []model.PdfRectangle {
+	var regions []model.PdfRectangle
+	newLine := true
+	for _, mark := range marks {
+		if mark.Offset < start || mark.Offset >= end {
+			continue
+		}
+		if mark.Meta {
+			// Line breaks inserted in the extracted text separate the lines of a match.
+			if strings.Contains(mark.Text, "\n") {
+				newLine = true
+			}
+			continue
+		}
+		if strings.TrimSpace(mark.Text) == "" {
+			continue
+		}
+
+		bbox := normalizeRect(mark.BBox)
+		if !newLine {
+			// Marks that do not vertically overlap the current line start a new line.
+			last := &regions[len(regions)-1]
+			overlap := math.Min(last.Ury, bbox.Ury) - math.Max(last.Lly, bbox.Lly)
+			if overlap < 0.5*math.Min(last.Height(), bbox.Height()) {
+				newLine = true
+			} else {
+				last.Llx = math.Min(last.Llx, bbox.Llx)
+				last.Lly = math.Min(last.Lly, bbox.Lly)
+				last.Urx = math.Max(last.Urx, bbox.Urx)
+				last.Ury = math.Max(last.Ury, bbox.Ury)
+			}
+		}
+		if newLine {
+			regions = append(regions, bbox)
+			newLine = false
+		}
+	}
+	return regions
+}
+
+// newRedactAnnotation returns a redaction annotation covering `regions`.
+func newRedactAnnotation(regions []model.PdfRectangle, options *SearchOptions) *model.PdfAnnotationRedact {
+	rect := regions[0]
+	var quads []float64
+	for _, r := range regions {
+		rect.Llx = math.Min(rect.Llx, r.Llx)
+		rect.Lly = math.Min(rect.Lly, r.Lly)
+		rect.Urx = math.Max(rect.Urx, r.Urx)
+		rect.Ury = math.Max(rect.Ury, r.Ury)
+		quads = append(quads, r.Llx, r.Ury, r.Urx, r.Ury, r.Llx, r.Lly, r.Urx, r.Lly)
+	}
+
+	color := options.FillColor
+	if len(color) == 0 {
+		color = []float64{0, 0, 0}
+	}
+
+	annotation := model.NewPdfAnnotationRedact()
+	annotation.Rect = core.MakeArrayFromFloats([]float64{rect.Llx, rect.Lly, rect.Urx, rect.Ury})
+	annotation.QuadPoints = core.MakeArrayFromFloats(quads)
+	annotation.IC = core.MakeArrayFromFloats(color)
+	if options.OverlayText != "" {
+		annotation.OverlayText = core.MakeString(options.OverlayText)
+	}
+	return annotation
+}
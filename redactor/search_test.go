@@ -0,0 +1,97 @@
+package redactor
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moolekkari/unipdf/creator"
+	"github.com/moolekkari/unipdf/extractor"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// TestSearchAndRedact tests redacting the matches of regular expressions in a document.
+func TestSearchAndRedact(t *testing.T) {
+	pageTexts := []string{
+		"Name: John Doe, SSN: 123-45-6789",
+		"Nothing to redact on this page.",
+		"Contact john.doe@example.com or jane@example.org about 987-65-4321.",
+	}
+
+	c := creator.New()
+	for _, text := range pageTexts {
+		c.NewPage()
+		require.NoError(t, c.Draw(c.NewParagraph(text)))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+
+	reader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	ssn := regexp.MustCompile(`\d{3}-\d{2}-\d{4}`)
+	email := regexp.MustCompile(`[\w.]+@[\w.]+\.[a-z]+`)
+	report, err := SearchAndRedact(reader, []*regexp.Regexp{ssn, email}, &SearchOptions{Apply: true})
+	require.NoError(t, err)
+
+	require.Len(t, report.Pages, 2)
+	assert.Equal(t, 4, report.NumMatches())
+	assert.Equal(t, 1, report.Pages[0].PageNum)
+	assert.Equal(t, 3, report.Pages[1].PageNum)
+
+	var matched []string
+	for _, page := range report.Pages {
+		require.NotNil(t, page.Redactions)
+		for _, match := range page.Matches {
+			matched = append(matched, match.Text)
+			assert.Len(t, match.Regions, 1)
+			assert.NotNil(t, match.Annotation)
+		}
+	}
+	assert.Equal(t, []string{"123-45-6789", "987-65-4321", "john.doe@example.com", "jane@example.org"}, matched)
+	assert.Equal(t, []string{"123-45-6789"}, report.Pages[0].Redactions.RemovedText)
+
+	// Write out the redacted document and check that the matches were removed.
+	writer := model.NewPdfWriter()
+	for i := range pageTexts {
+		page, err := reader.GetPage(i + 1)
+		require.NoError(t, err)
+		require.NoError(t, writer.AddPage(page))
+	}
+	buf.Reset()
+	require.NoError(t, writer.Write(&buf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	for i := range pageTexts {
+		page, err := reader.GetPage(i + 1)
+		require.NoError(t, err)
+		annotations, err := page.GetAnnotations()
+		require.NoError(t, err)
+		assert.Empty(t, annotations)
+
+		e, err := extractor.New(page)
+		require.NoError(t, err)
+		text, err := e.ExtractText()
+		require.NoError(t, err)
+		assert.False(t, ssn.MatchString(text), text)
+		assert.False(t, email.MatchString(text), text)
+	}
+
+	// Annotations only.
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	report, err = SearchAndRedact(reader, []*regexp.Regexp{regexp.MustCompile("Nothing")}, nil)
+	require.NoError(t, err)
+	require.Len(t, report.Pages, 1)
+	assert.Nil(t, report.Pages[0].Redactions)
+	page, err := reader.GetPage(2)
+	require.NoError(t, err)
+	annotations, err := page.GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	assert.IsType(t, &model.PdfAnnotationRedact{}, annotations[0].GetContext())
+}
@@ -0,0 +1,119 @@
+package redactor
+
+import (
+	"github.com/moolekkari/unipdf/common"
+	"github.com/moolekkari/unipdf/contentstream"
+	"github.com/moolekkari/unipdf/core"
+	"github.com/moolekkari/unipdf/internal/contentutil"
+	"github.com/moolekkari/unipdf/internal/transform"
+	"github.com/moolekkari/unipdf/model"
+)
+
+// showText redacts the text showing operation `op`. The glyphs inside the redaction regions are
+// removed and replaced by the equivalent displacement in a TJ operation.
+func (c *contentRedactor) showText(op *contentstream.ContentStreamOperation) error {
+	items, err := contentutil.TextShowItems(op, &c.gs.TextState, &c.text)
+	if err != nil {
+		return err
+	}
+	// The move to the next line and the spacing set by ' and " are kept.
+	var pre []*contentstream.ContentStreamOperation
+	switch op.Operand {
+	case "'":
+		pre = []*contentstream.ContentStreamOperation{{Operand: "T*"}}
+	case "\"":
+		pre = []*contentstream.ContentStreamOperation{
+			{Operand: "Tw", Params: op.Params[:1]},
+			{Operand: "Tc", Params: op.Params[1:2]},
+			{Operand: "T*"},
+		}
+	}
+
+	redacted, changed := c.redactText(items)
+	if !changed {
+		c.emit(op)
+		return nil
+	}
+	c.changed = true
+	c.emit(pre...)
+	c.emit(&contentstream.ContentStreamOperation{
+		Operand: "TJ",
+		Params:  []core.PdfObject{core.MakeArray(redacted...)},
+	})
+	return nil
+}
+
+// redactText shows the TJ array elements `items`, updating the text matrix. Returns the TJ array
+// elements with the glyphs inside the redaction regions removed and whether any were removed.
+func (c *contentRedactor) redactText(items []core.PdfObject) ([]core.PdfObject, bool) {
+	font := c.gs.Font
+	if c.gs.FontName == "" {
+		common.Log.Debug("ERROR: no font set for text showing operation")
+	}
+	ascent, descent := contentutil.FontExtent(font)
+	tfs := c.gs.Tfs
+
+	var out []core.PdfObject
+	var run []byte
+	var removed []byte
+	adjust := 0.0
+	changed := false
+
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if adjust != 0 {
+			out = append(out, core.MakeFloat(adjust))
+			adjust = 0
+		}
+		out = append(out, contentutil.MakeString(font, run))
+		run = nil
+	}
+
+	c.text.Tm = contentutil.LayoutGlyphs(c.gs.TextState, c.text.Tm, items,
+		func(g contentutil.Glyph, m transform.Matrix, advance float64) {
+			llx, lly, urx, ury := c.gs.ctm.Mult(m).TransformBBox(0, descent, g.Width, ascent)
+			bbox := model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: ury}
+			if c.r.removesGlyph(bbox) && tfs != 0 {
+				flush()
+				adjust -= advance * 1000 / tfs
+				removed = append(removed, g.Data...)
+				c.r.result.NumGlyphs++
+				changed = true
+			} else {
+				run = append(run, g.Data...)
+			}
+		},
+		func(num float64) {
+			flush()
+			adjust += num
+		})
+	flush()
+	if adjust != 0 {
+		out = append(out, core.MakeFloat(adjust))
+	}
+
+	if len(removed) > 0 {
+		c.r.result.RemovedText = append(c.r.result.RemovedText, contentutil.DecodeText(font, removed))
+	}
+	return out, changed
+}
+
+// removesGlyph returns true if the glyph with bounding box `bbox` is to be removed, i.e. if its
+// center is inside a redaction region or if a significant part of it is covered by a region.
+func (r *redactor) removesGlyph(bbox model.PdfRectangle) bool {
+	if r.contains((bbox.Llx+bbox.Urx)/2, (bbox.Lly+bbox.Ury)/2) {
+		return true
+	}
+	area := rectArea(bbox)
+	if area <= 0 {
+		return false
+	}
+	for _, region := range r.regions {
+		if intersectionArea(region, bbox) >= minOverlapRatio*area {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package redactor
+
+import (
+	"math"
+
+	"github.com/moolekkari/unipdf/model"
+)
+
+// minOverlapRatio is the minimum fraction of the area of a glyph that needs to be covered by a
+// redaction region for the glyph to be removed.
+const minOverlapRatio = 0.2
+
+// normalizeRect returns `rect` with its corners ordered so that Llx <= Urx and Lly <= Ury.
+func normalizeRect(rect model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(rect.Llx, rect.Urx),
+		Lly: math.Min(rect.Lly, rect.Ury),
+		Urx: math.Max(rect.Llx, rect.Urx),
+		Ury: math.Max(rect.Lly, rect.Ury),
+	}
+}
+
+// rectArea returns the area of `rect`.
+func rectArea(rect model.PdfRectangle) float64 {
+	return math.Max(rect.Urx-rect.Llx, 0) * math.Max(rect.Ury-rect.Lly, 0)
+}
+
+// intersectionArea returns the area of the intersection of `a` and `b`.
+func intersectionArea(a, b model.PdfRectangle) float64 {
+	w := math.Min(a.Urx, b.Urx) - math.Max(a.Llx, b.Llx)
+	h := math.Min(a.Ury, b.Ury) - math.Max(a.Lly, b.Lly)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
+
+// rectsOverlap returns true if `a` and `b` overlap. Unlike intersectionArea, degenerate
+// rectangles such as the bounding boxes of horizontal or vertical lines are handled.
+func rectsOverlap(a, b model.PdfRectangle) bool {
+	return a.Llx <= b.Urx && b.Llx <= a.Urx && a.Lly <= b.Ury && b.Lly <= a.Ury
+}
+
+// rectContains returns true if the point `x`,`y` is inside `rect`.
+func rectContains(rect model.PdfRectangle, x, y float64) bool {
+	return rect.Llx <= x && x <= rect.Urx && rect.Lly <= y && y <= rect.Ury
+}
+
+// rectContainsRect returns true if `inner` is entirely inside `outer`.
+func rectContainsRect(outer, inner model.PdfRectangle) bool {
+	return outer.Llx <= inner.Llx && inner.Urx <= outer.Urx &&
+		outer.Lly <= inner.Lly && inner.Ury <= outer.Ury
+}
@@ -119,6 +119,32 @@ func (tf *TextFont) GetCharMetrics(code textencoding.CharCode) (float64, float64
 	return metrics.Wx, metrics.Wy, ok && metrics.Wx != 0
 }
 
+// IsVertical returns true if the encapsulated PDF font is used for vertical
+// writing.
+func (tf *TextFont) IsVertical() bool {
+	if tf.origFont != nil {
+		return tf.origFont.IsVertical()
+	}
+
+	return tf.Font.IsVertical()
+}
+
+// GetVerticalMetrics returns the vertical writing mode metrics of the
+// specified character code. Default metrics are returned for character codes
+// whose metrics are not known.
+func (tf *TextFont) GetVerticalMetrics(code textencoding.CharCode) model.VerticalMetrics {
+	font := tf.Font
+	if tf.origFont != nil {
+		font = tf.origFont
+	}
+	if metrics, ok := font.GetVerticalMetrics(code); ok {
+		return metrics
+	}
+
+	wx, _, _ := tf.GetCharMetrics(code)
+	return model.VerticalMetrics{W1y: -1000, Vx: wx / 2, Vy: 880}
+}
+
 // GetRuneMetrics returns the metrics of the specified rune. The character
 // metrics are calculated by the internal PDF font.
 func (tf *TextFont) GetRuneMetrics(r rune) (float64, float64, bool) {
@@ -1,6 +1,7 @@
 package context
 
 import (
+	"github.com/moolekkari/unipdf/internal/textencoding"
 	"github.com/moolekkari/unipdf/internal/transform"
 )
 
@@ -71,6 +72,11 @@ func (ts *TextState) ProcTStar() {
 // See section 9.4.3 "Text Showing Operators" and
 // Table 209 (pp. 258-259 PDF32000_2008).
 func (ts *TextState) ProcTj(data []byte, ctx Context) {
+	if ts.Tf.IsVertical() {
+		ts.procTjVertical(data, ctx)
+		return
+	}
+
 	tfs := ts.Tf.Size
 	th := ts.Th / 100.0
 	stateMatrix := transform.NewMatrix(tfs*th, 0, 0, tfs, 0, ts.Ts)
@@ -113,6 +119,42 @@ func (ts *TextState) ProcTj(data []byte, ctx Context) {
 	}
 }
 
+// procTjVertical processes a `Tj` operation for a font used for vertical
+// writing. The glyphs are drawn at their vertical origin, below the text
+// position, which advances downwards. Word spacing does not apply, as the
+// character codes of vertical fonts are not single-byte codes.
+//
+// See section 9.7.4.3 "Glyph Metrics in CIDFonts" (pp. 271-272 PDF32000_2008).
+func (ts *TextState) procTjVertical(data []byte, ctx Context) {
+	tfs := ts.Tf.Size
+	th := ts.Th / 100.0
+	stateMatrix := transform.NewMatrix(tfs*th, 0, 0, tfs, 0, ts.Ts)
+
+	for _, code := range ts.Tf.BytesToCharcodes(data) {
+		metrics := ts.Tf.GetVerticalMetrics(code)
+
+		// Calculate text rendering matrix. The glyph is offset from the
+		// text position by its position vector.
+		tm := ts.Tm.Clone()
+		ts.Tm = transform.TranslationMatrix(-metrics.Vx*0.001*tfs, metrics.Vy*0.001*tfs).Mult(tm)
+		ts.Tm.Concat(stateMatrix)
+
+		// Draw glyph.
+		runes := ts.Tf.CharcodesToUnicode([]textencoding.CharCode{code})
+		if len(runes) > 0 && runes[0] != '\x00' {
+			x, y := ts.Tm.Transform(0, 0)
+			ctx.Scale(1, -1)
+			ctx.DrawString(string(runes), x, y)
+			ctx.Scale(1, -1)
+		}
+
+		// Generate new text matrix. The text matrix is kept in the flipped
+		// coordinate system of the rendering context.
+		ty := metrics.W1y*0.001*tfs + ts.Tc
+		ts.Tm = transform.TranslationMatrix(0, -ty).Mult(tm)
+	}
+}
+
 // ProcQ processes a `'` operation, which advances the text state to a new line
 // and then displays a text string.
 //
@@ -2,6 +2,7 @@ package render
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/adrg/sysfont"
 
@@ -880,7 +881,10 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 				common.Log.Debug("' string: %s", string(charcodes))
 
-				textState.ProcQ(charcodes, ctx)
+				textState.ProcTStar()
+				if err := r.showText(ctx, charcodes, gs, resources); err != nil {
+					return err
+				}
 			// Move to the next line and show text string.
 			case `''`:
 				if len(op.Params) != 3 {
@@ -902,7 +906,12 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return errType
 				}
 
-				textState.ProcDQ(charcodes, aw, ac, ctx)
+				textState.Tw = aw
+				textState.Tc = ac
+				textState.ProcTStar()
+				if err := r.showText(ctx, charcodes, gs, resources); err != nil {
+					return err
+				}
 			// Show text string.
 			case "Tj":
 				if len(op.Params) != 1 {
@@ -915,7 +924,9 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 				}
 				common.Log.Debug("Tj string: `%s`", string(charcodes))
 
-				textState.ProcTj(charcodes, ctx)
+				if err := r.showText(ctx, charcodes, gs, resources); err != nil {
+					return err
+				}
 			// Show array of text strings.
 			case "TJ":
 				if len(op.Params) != 1 {
@@ -933,11 +944,21 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					switch t := obj.(type) {
 					case *core.PdfObjectString:
 						if t != nil {
-							textState.ProcTj(t.Bytes(), ctx)
+							if err := r.showText(ctx, t.Bytes(), gs, resources); err != nil {
+								return err
+							}
 						}
 					case *core.PdfObjectFloat, *core.PdfObjectInteger:
 						val, err := core.GetNumberAsFloat(t)
-						if err == nil {
+						if err != nil {
+							break
+						}
+						// The adjustments of vertical text move the text
+						// position downwards, in the flipped coordinate system
+						// of the text state.
+						if textState.Tf != nil && textState.Tf.IsVertical() {
+							textState.Translate(0, val*0.001*textState.Tf.Size)
+						} else {
 							textState.Translate(-val*0.001*textState.Tf.Size, 0)
 						}
 					}
@@ -985,6 +1006,12 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return err
 				}
 
+				// Type 3 glyphs are painted by their glyph procedures.
+				if pdfFont.Subtype() == "Type3" {
+					textState.ProcTf(&context.TextFont{Font: pdfFont, Size: fontSize})
+					break
+				}
+
 				baseFont := pdfFont.BaseFont()
 				if baseFont == "" {
 					baseFont = fontName.String()
@@ -1046,6 +1073,14 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 			// Marked content operators
 			//
 
+			//
+			// Type 3 font operators
+			//
+
+			// Set glyph width and bounding box. The glyph procedures are
+			// painted like any other content stream, so these are ignored.
+			case "d0", "d1":
+
 			// Begin a marked-content sequence.
 			case "BMC", "BDC":
 			// End a marked-content sequence.
@@ -1064,3 +1099,94 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 
 	return nil
 }
+
+// showText displays the string `data` using the current font of the text
+// state of `ctx`. Glyphs of Type 3 fonts are painted by executing their glyph
+// procedures, glyphs of other fonts are drawn by the text state.
+func (r renderer) showText(ctx context.Context, data []byte, gs contentstream.GraphicsState,
+	resources *model.PdfPageResources) error {
+	textState := ctx.TextState()
+	if textState.Tf == nil {
+		common.Log.Debug("ERROR: no font set for text")
+		return errors.New("no font set")
+	}
+
+	font := textState.Tf.Font
+	if font.Subtype() != "Type3" {
+		textState.ProcTj(data, ctx)
+		return nil
+	}
+
+	if glyphResources := font.CharProcResources(); glyphResources != nil {
+		resources = glyphResources
+	}
+
+	// The glyph procedures are processed as separate content streams, so
+	// the current colors are passed on to them.
+	colorOps := colorOperators(gs)
+
+	fm := font.FontMatrix()
+	fontMatrix := transform.NewMatrix(fm[0], fm[1], fm[2], fm[3], fm[4], fm[5])
+	tfs := textState.Tf.Size
+	th := textState.Th / 100.0
+	stateMatrix := transform.NewMatrix(tfs*th, 0, 0, tfs, 0, textState.Ts)
+
+	for _, code := range font.BytesToCharcodes(data) {
+		// The text state keeps the text matrix in the flipped coordinate
+		// system of the rendering context.
+		tm := textState.Tm
+		textMatrix := transform.NewMatrix(tm[0], tm[1], tm[3], tm[4], tm[6], -tm[7])
+
+		// Paint the glyph in glyph space (see section 9.6.5 "Type 3 Fonts").
+		if content, ok := font.CharProc(code); ok {
+			glyphMatrix := textMatrix.Mult(stateMatrix.Mult(fontMatrix))
+
+			ctx.Push()
+			ctx.SetMatrix(ctx.Matrix().Mult(glyphMatrix))
+			err := r.renderContentStream(ctx, colorOps+string(content), resources)
+			ctx.Pop()
+			if err != nil {
+				common.Log.Debug("ERROR: could not render glyph %d: %v", code, err)
+			}
+		}
+
+		// Calculate word spacing.
+		tw := 0.0
+		if code == 32 {
+			tw = textState.Tw
+		}
+
+		var w float64
+		if metrics, ok := font.GetCharMetrics(code); ok {
+			w = metrics.Wx * 0.001 * tfs
+		}
+
+		// Advance the text matrix.
+		tx := (w + textState.Tc + tw) * th
+		textMatrix = textMatrix.Mult(transform.TranslationMatrix(tx, 0))
+		textState.Tm = transform.NewMatrix(
+			textMatrix[0], textMatrix[1],
+			textMatrix[3], textMatrix[4],
+			textMatrix[6], -textMatrix[7],
+		)
+	}
+
+	return nil
+}
+
+// colorOperators returns content stream operators which set the stroking and
+// non-stroking colors of `gs` as RGB colors.
+func colorOperators(gs contentstream.GraphicsState) string {
+	var ops string
+	if color, err := gs.ColorspaceNonStroking.ColorToRGB(gs.ColorNonStroking); err == nil {
+		if rgb, ok := color.(*model.PdfColorDeviceRGB); ok {
+			ops += fmt.Sprintf("%f %f %f rg\n", rgb.R(), rgb.G(), rgb.B())
+		}
+	}
+	if color, err := gs.ColorspaceStroking.ColorToRGB(gs.ColorStroking); err == nil {
+		if rgb, ok := color.(*model.PdfColorDeviceRGB); ok {
+			ops += fmt.Sprintf("%f %f %f RG\n", rgb.R(), rgb.G(), rgb.B())
+		}
+	}
+	return ops
+}